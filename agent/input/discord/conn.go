@@ -0,0 +1,220 @@
+package discord
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/micro/go-micro/v2/agent/input"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+// message received from a channel, dm or slash command
+type message struct {
+	channelID string
+	userID    string
+	content   string
+	// set for slash commands which are answered by editing the deferred response
+	interaction *discordgo.Interaction
+}
+
+type discordConn struct {
+	master *discordInput
+	exit   chan struct{}
+	recv   chan *message
+
+	sync.Mutex
+}
+
+func newConn(master *discordInput) *discordConn {
+	conn := &discordConn{
+		master: master,
+		exit:   make(chan struct{}),
+		recv:   make(chan *message),
+	}
+
+	conn.master.session.AddHandler(func(s *discordgo.Session, m *discordgo.MessageCreate) {
+		if m.Author == nil || m.Author.ID == master.botID || m.Author.Bot {
+			return
+		}
+
+		var roles []string
+		if m.Member != nil {
+			roles = m.Member.Roles
+		}
+
+		if !master.allowed(m.ChannelID, m.GuildID, m.Author.ID, roles) {
+			return
+		}
+
+		content := m.Content
+
+		// dms don't need the prefix
+		if len(m.GuildID) > 0 {
+			var valid bool
+			content, valid = conn.master.prefixfn(m.Content)
+			if !valid {
+				return
+			}
+		}
+
+		conn.deliver(&message{
+			channelID: m.ChannelID,
+			userID:    m.Author.ID,
+			content:   content,
+		})
+	})
+
+	conn.master.session.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		if i.Type != discordgo.InteractionApplicationCommand {
+			return
+		}
+
+		data := i.ApplicationCommandData()
+		if data.Name != master.command {
+			return
+		}
+
+		var userID string
+		var roles []string
+		switch {
+		case i.Member != nil && i.Member.User != nil:
+			userID = i.Member.User.ID
+			roles = i.Member.Roles
+		case i.User != nil:
+			userID = i.User.ID
+		}
+
+		if !master.allowed(i.ChannelID, i.GuildID, userID, roles) {
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: &discordgo.InteractionResponseData{
+					Content: "You're not allowed to use this command",
+					Flags:   uint64(discordgo.MessageFlagsEphemeral),
+				},
+			})
+			return
+		}
+
+		var args []string
+		for _, o := range data.Options {
+			args = append(args, o.StringValue())
+		}
+
+		// commands may take longer than the three seconds discord waits for a response
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		})
+		if err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Error("[bot][loop][interaction]", err)
+			}
+			return
+		}
+
+		conn.deliver(&message{
+			channelID:   i.ChannelID,
+			userID:      userID,
+			content:     strings.Join(args, " "),
+			interaction: i.Interaction,
+		})
+	})
+
+	return conn
+}
+
+func (dc *discordConn) deliver(m *message) {
+	select {
+	case <-dc.exit:
+	case dc.recv <- m:
+	}
+}
+
+func (dc *discordConn) Recv(event *input.Event) error {
+	for {
+		select {
+		case <-dc.exit:
+			return errors.New("connection closed")
+		case msg := <-dc.recv:
+
+			event.From = msg.channelID + ":" + msg.userID
+			event.To = dc.master.botID
+			event.Type = input.TextEvent
+			event.Data = []byte(msg.content)
+			event.Meta = nil
+			if msg.interaction != nil {
+				event.Meta = map[string]interface{}{
+					"interaction": msg.interaction,
+				}
+			}
+			return nil
+		}
+	}
+}
+
+func ChunkString(s string, chunkSize int) []string {
+	var chunks []string
+	runes := []rune(s)
+
+	if len(runes) == 0 {
+		return []string{s}
+	}
+
+	for i := 0; i < len(runes); i += chunkSize {
+		nn := i + chunkSize
+		if nn > len(runes) {
+			nn = len(runes)
+		}
+		chunks = append(chunks, string(runes[i:nn]))
+	}
+	return chunks
+}
+
+func (dc *discordConn) Send(e *input.Event) error {
+	fields := strings.Split(e.To, ":")
+
+	var interaction *discordgo.Interaction
+	if e.Meta != nil {
+		interaction, _ = e.Meta["interaction"].(*discordgo.Interaction)
+	}
+
+	for i, chunk := range ChunkString(string(e.Data), 2000) {
+		var err error
+
+		switch {
+		case interaction != nil && i == 0:
+			// replace the deferred "thinking" response
+			_, err = dc.master.session.InteractionResponseEdit(dc.master.botID, interaction, &discordgo.WebhookEdit{
+				Content: chunk,
+			})
+		case interaction != nil:
+			_, err = dc.master.session.FollowupMessageCreate(dc.master.botID, interaction, true, &discordgo.WebhookParams{
+				Content: chunk,
+			})
+		default:
+			_, err = dc.master.session.ChannelMessageSend(fields[0], chunk)
+		}
+
+		if err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Error("[bot][loop][send]", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (dc *discordConn) Close() error {
+	if err := dc.master.session.Close(); err != nil {
+		return err
+	}
+
+	select {
+	case <-dc.exit:
+		return nil
+	default:
+		close(dc.exit)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+// Package discord is a discord input for the micro bot supporting slash commands
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/micro/cli/v2"
+	"github.com/micro/go-micro/v2/agent/input"
+)
+
+func init() {
+	input.Inputs["discord"] = newInput()
+}
+
+func newInput() *discordInput {
+	return &discordInput{}
+}
+
+type discordInput struct {
+	token    string
+	prefix   string
+	prefixfn func(string) (string, bool)
+	botID    string
+
+	// slash command registered, empty disables it
+	command string
+	// guild the command is registered in, empty registers it globally
+	guild string
+
+	// permission filters, empty allows everyone
+	whitelist []string
+	roles     []string
+	channels  []string
+	dm        bool
+
+	session *discordgo.Session
+
+	sync.Mutex
+	running bool
+	exit    chan struct{}
+}
+
+func (d *discordInput) Flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "discord_token",
+			EnvVars: []string{"MICRO_DISCORD_TOKEN"},
+			Usage:   "Discord bot token",
+		},
+		&cli.StringFlag{
+			Name:    "discord_whitelist",
+			EnvVars: []string{"MICRO_DISCORD_WHITELIST"},
+			Usage:   "Discord user ids allowed to use the bot (seperated by ,)",
+		},
+		&cli.StringFlag{
+			Name:    "discord_roles",
+			EnvVars: []string{"MICRO_DISCORD_ROLES"},
+			Usage:   "Discord role ids allowed to use the bot in guilds (seperated by ,)",
+		},
+		&cli.StringFlag{
+			Name:    "discord_channels",
+			EnvVars: []string{"MICRO_DISCORD_CHANNELS"},
+			Usage:   "Discord channel ids the bot listens in (seperated by ,)",
+		},
+		&cli.BoolFlag{
+			Name:    "discord_dm",
+			EnvVars: []string{"MICRO_DISCORD_DM"},
+			Usage:   "Allow direct messages to the bot",
+			Value:   true,
+		},
+		&cli.StringFlag{
+			Name:    "discord_prefix",
+			Usage:   "Discord Prefix",
+			EnvVars: []string{"MICRO_DISCORD_PREFIX"},
+			Value:   "Micro ",
+		},
+		&cli.StringFlag{
+			Name:    "discord_command",
+			Usage:   "Name of the slash command to register, empty disables it",
+			EnvVars: []string{"MICRO_DISCORD_COMMAND"},
+			Value:   "micro",
+		},
+		&cli.StringFlag{
+			Name:    "discord_guild",
+			Usage:   "Guild id to register the slash command in, global commands can take an hour to appear",
+			EnvVars: []string{"MICRO_DISCORD_GUILD"},
+		},
+	}
+}
+
+func split(s string) []string {
+	var vals []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
+
+func (d *discordInput) Init(ctx *cli.Context) error {
+	token := ctx.String("discord_token")
+
+	if len(token) == 0 {
+		return errors.New("require token")
+	}
+
+	d.token = token
+	d.prefix = ctx.String("discord_prefix")
+	d.command = ctx.String("discord_command")
+	d.guild = ctx.String("discord_guild")
+	d.whitelist = split(ctx.String("discord_whitelist"))
+	d.roles = split(ctx.String("discord_roles"))
+	d.channels = split(ctx.String("discord_channels"))
+	d.dm = ctx.Bool("discord_dm")
+
+	return nil
+}
+
+func contains(vals []string, v string) bool {
+	for _, s := range vals {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed returns true if the user may use the bot in the channel, dms have no guild
+func (d *discordInput) allowed(channelID, guildID, userID string, roles []string) bool {
+	if len(d.whitelist) > 0 && !contains(d.whitelist, userID) {
+		return false
+	}
+
+	if len(guildID) == 0 {
+		return d.dm
+	}
+
+	if len(d.channels) > 0 && !contains(d.channels, channelID) {
+		return false
+	}
+
+	if len(d.roles) == 0 {
+		return true
+	}
+
+	for _, r := range roles {
+		if contains(d.roles, r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// register creates the slash command taking the bot command as an argument
+func (d *discordInput) register(appID string) error {
+	if len(d.command) == 0 {
+		return nil
+	}
+
+	_, err := d.session.ApplicationCommandCreate(appID, d.guild, &discordgo.ApplicationCommand{
+		Name:        d.command,
+		Description: "Run a micro bot command",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "command",
+				Description: "The command e.g help",
+				Required:    true,
+			},
+		},
+	})
+
+	return err
+}
+
+func (d *discordInput) Start() error {
+	if len(d.token) == 0 {
+		return errors.New("missing discord configuration")
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	if d.running {
+		return nil
+	}
+
+	var err error
+	d.session, err = discordgo.New("Bot " + d.token)
+	if err != nil {
+		return err
+	}
+
+	d.session.Identify.Intents = discordgo.IntentsGuildMessages |
+		discordgo.IntentsDirectMessages |
+		discordgo.IntentsMessageContent
+
+	u, err := d.session.User("@me")
+	if err != nil {
+		return err
+	}
+
+	d.botID = u.ID
+	d.prefixfn = CheckPrefixFactory(fmt.Sprintf("<@%s> ", d.botID), fmt.Sprintf("<@!%s> ", d.botID), d.prefix)
+
+	// the bot user id is the application id
+	if err := d.register(d.botID); err != nil {
+		return fmt.Errorf("registering slash command: %v", err)
+	}
+
+	d.exit = make(chan struct{})
+	d.running = true
+
+	return nil
+}
+
+func (d *discordInput) Stream() (input.Conn, error) {
+	d.Lock()
+	defer d.Unlock()
+	if !d.running {
+		return nil, errors.New("not running")
+	}
+
+	//Fire-n-forget close just in case...
+	d.session.Close()
+
+	conn := newConn(d)
+	if err := d.session.Open(); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (d *discordInput) Stop() error {
+	d.Lock()
+	defer d.Unlock()
+
+	if !d.running {
+		return nil
+	}
+
+	close(d.exit)
+	d.running = false
+	return nil
+}
+
+func (d *discordInput) String() string {
+	return "discord"
+}
+
+// CheckPrefixFactory Creates a prefix checking function and stuff.
+func CheckPrefixFactory(prefixes ...string) func(string) (string, bool) {
+	return func(content string) (string, bool) {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(content, prefix) {
+				return strings.TrimPrefix(content, prefix), true
+			}
+		}
+		return "", false
+	}
+}
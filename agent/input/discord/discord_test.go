@@ -0,0 +1,43 @@
+package discord
+
+import (
+	"testing"
+)
+
+func TestAllowed(t *testing.T) {
+	d := &discordInput{
+		whitelist: []string{"alice", "bob"},
+		roles:     []string{"ops"},
+		channels:  []string{"general"},
+	}
+
+	testData := []struct {
+		channel string
+		guild   string
+		user    string
+		roles   []string
+		dm      bool
+		allowed bool
+	}{
+		{"general", "guild", "alice", []string{"ops"}, false, true},
+		{"general", "guild", "carol", []string{"ops"}, false, false},
+		{"random", "guild", "alice", []string{"ops"}, false, false},
+		{"general", "guild", "bob", []string{"dev"}, false, false},
+		{"dm", "", "alice", nil, true, true},
+		{"dm", "", "alice", nil, false, false},
+	}
+
+	for _, v := range testData {
+		d.dm = v.dm
+		if ok := d.allowed(v.channel, v.guild, v.user, v.roles); ok != v.allowed {
+			t.Errorf("%+v: expected allowed %v got %v", v, v.allowed, ok)
+		}
+	}
+}
+
+func TestChunkString(t *testing.T) {
+	chunks := ChunkString("hello world", 5)
+	if len(chunks) != 3 || chunks[0] != "hello" || chunks[2] != "d" {
+		t.Fatalf("Unexpected chunks %v", chunks)
+	}
+}
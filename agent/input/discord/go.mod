@@ -0,0 +1,9 @@
+module github.com/micro/go-plugins/agent/input/discord/v2
+
+go 1.13
+
+require (
+	github.com/bwmarrin/discordgo v0.24.0
+	github.com/micro/cli/v2 v2.1.2
+	github.com/micro/go-micro/v2 v2.9.1
+)
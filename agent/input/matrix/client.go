@@ -0,0 +1,201 @@
+package matrix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// client is a minimal matrix client-server api client
+type client struct {
+	homeserver string
+	token      string
+	http       *http.Client
+
+	txn  int64
+	mtx  sync.Mutex
+	send *limiter
+}
+
+// Error returned by the homeserver
+type Error struct {
+	Status     int    `json:"-"`
+	Code       string `json:"errcode"`
+	Message    string `json:"error"`
+	RetryAfter int64  `json:"retry_after_ms"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("matrix: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+type event struct {
+	Type     string          `json:"type"`
+	EventID  string          `json:"event_id"`
+	Sender   string          `json:"sender"`
+	StateKey *string         `json:"state_key"`
+	Content  json.RawMessage `json:"content"`
+}
+
+type syncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []event `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+		Invite map[string]json.RawMessage `json:"invite"`
+	} `json:"rooms"`
+}
+
+// limiter allows one call per interval
+type limiter struct {
+	interval time.Duration
+
+	sync.Mutex
+	next time.Time
+}
+
+func (l *limiter) wait() {
+	if l == nil || l.interval <= 0 {
+		return
+	}
+
+	l.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.Unlock()
+
+	time.Sleep(delay)
+}
+
+func newClient(homeserver, token string, interval time.Duration) *client {
+	return &client{
+		homeserver: strings.TrimSuffix(homeserver, "/"),
+		token:      token,
+		http:       &http.Client{Timeout: 60 * time.Second},
+		send:       &limiter{interval: interval},
+		txn:        time.Now().UnixNano(),
+	}
+}
+
+func (c *client) do(method, path string, query url.Values, in, out interface{}) error {
+	u := c.homeserver + "/_matrix/client/r0" + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return err
+	}
+	if len(c.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rsp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		e := &Error{Status: rsp.StatusCode}
+		json.NewDecoder(rsp.Body).Decode(e)
+		return e
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(rsp.Body).Decode(out)
+}
+
+// login exchanges a password for an access token
+func (c *client) login(user, password string) error {
+	var rsp struct {
+		AccessToken string `json:"access_token"`
+	}
+	err := c.do("POST", "/login", nil, map[string]interface{}{
+		"type":       "m.login.password",
+		"identifier": map[string]string{"type": "m.id.user", "user": user},
+		"password":   password,
+	}, &rsp)
+	if err != nil {
+		return err
+	}
+	c.token = rsp.AccessToken
+	return nil
+}
+
+func (c *client) whoami() (string, error) {
+	var rsp struct {
+		UserID string `json:"user_id"`
+	}
+	err := c.do("GET", "/account/whoami", nil, nil, &rsp)
+	return rsp.UserID, err
+}
+
+func (c *client) join(room string) error {
+	return c.do("POST", "/join/"+url.PathEscape(room), nil, struct{}{}, nil)
+}
+
+func (c *client) sync(since string, timeout time.Duration) (*syncResponse, error) {
+	q := url.Values{}
+	q.Set("timeout", strconv.FormatInt(int64(timeout/time.Millisecond), 10))
+	if len(since) > 0 {
+		q.Set("since", since)
+	}
+	var rsp syncResponse
+	err := c.do("GET", "/sync", q, nil, &rsp)
+	return &rsp, err
+}
+
+// sendMessage sends a message to the room, waiting for the rate limit
+func (c *client) sendMessage(room string, content interface{}) error {
+	c.mtx.Lock()
+	c.txn++
+	txn := strconv.FormatInt(c.txn, 10)
+	c.mtx.Unlock()
+
+	path := fmt.Sprintf("/rooms/%s/send/m.room.message/%s", url.PathEscape(room), txn)
+
+	for i := 0; i < 3; i++ {
+		c.send.wait()
+
+		err := c.do("PUT", path, nil, content, nil)
+		e, ok := err.(*Error)
+		if !ok || e.Code != "M_LIMIT_EXCEEDED" {
+			return err
+		}
+
+		// the same txn id makes retries idempotent
+		time.Sleep(time.Duration(e.RetryAfter) * time.Millisecond)
+	}
+
+	return fmt.Errorf("matrix: rate limited sending to %s", room)
+}
@@ -0,0 +1,70 @@
+package matrix
+
+import (
+	"errors"
+	"html"
+	"sync"
+
+	"github.com/micro/go-micro/v2/agent/input"
+)
+
+type matrixConn struct {
+	master *matrixInput
+	exit   chan struct{}
+	recv   chan *input.Event
+
+	once sync.Once
+}
+
+func newConn(master *matrixInput) *matrixConn {
+	return &matrixConn{
+		master: master,
+		exit:   make(chan struct{}),
+		recv:   make(chan *input.Event),
+	}
+}
+
+func (c *matrixConn) deliver(ev *input.Event) {
+	select {
+	case <-c.exit:
+	case c.recv <- ev:
+	}
+}
+
+func (c *matrixConn) Recv(event *input.Event) error {
+	select {
+	case <-c.exit:
+		return errors.New("connection closed")
+	case ev := <-c.recv:
+		*event = *ev
+		return nil
+	}
+}
+
+func (c *matrixConn) Send(event *input.Event) error {
+	if event.Meta == nil {
+		return errors.New("missing room to reply to")
+	}
+
+	room, ok := event.Meta["room"].(string)
+	if !ok || len(room) == 0 {
+		return errors.New("missing room to reply to")
+	}
+
+	text := string(event.Data)
+
+	// notices aren't answered by other bots
+	return c.master.client.sendMessage(room, &message{
+		Type:          "m.notice",
+		Body:          text,
+		Format:        "org.matrix.custom.html",
+		FormattedBody: "<pre><code>" + html.EscapeString(text) + "</code></pre>",
+	})
+}
+
+func (c *matrixConn) Close() error {
+	c.once.Do(func() {
+		close(c.exit)
+	})
+	return nil
+}
@@ -0,0 +1,8 @@
+module github.com/micro/go-plugins/agent/input/matrix/v2
+
+go 1.13
+
+require (
+	github.com/micro/cli/v2 v2.1.2
+	github.com/micro/go-micro/v2 v2.9.1
+)
@@ -0,0 +1,363 @@
+// Package matrix is a matrix input for the micro bot using the client-server api
+package matrix
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micro/cli/v2"
+	"github.com/micro/go-micro/v2/agent/input"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+var (
+	// SyncTimeout is how long the homeserver holds a sync request open
+	SyncTimeout = 30 * time.Second
+)
+
+type matrixInput struct {
+	homeserver string
+	user       string
+	password   string
+	token      string
+
+	rooms     []string
+	autojoin  bool
+	prefix    string
+	whitelist []string
+	interval  time.Duration
+
+	client *client
+	userID string
+
+	sync.Mutex
+	running bool
+	exit    chan struct{}
+	conn    *matrixConn
+	// encrypted rooms we've already warned about
+	encrypted map[string]bool
+}
+
+type message struct {
+	Type          string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+}
+
+func init() {
+	input.Inputs["matrix"] = &matrixInput{}
+}
+
+func (m *matrixInput) Flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "matrix_homeserver",
+			EnvVars: []string{"MICRO_MATRIX_HOMESERVER"},
+			Usage:   "Matrix homeserver url",
+			Value:   "https://matrix.org",
+		},
+		&cli.StringFlag{
+			Name:    "matrix_user",
+			EnvVars: []string{"MICRO_MATRIX_USER"},
+			Usage:   "Matrix user to login as",
+		},
+		&cli.StringFlag{
+			Name:    "matrix_password",
+			EnvVars: []string{"MICRO_MATRIX_PASSWORD"},
+			Usage:   "Matrix password to login with",
+		},
+		&cli.StringFlag{
+			Name:    "matrix_token",
+			EnvVars: []string{"MICRO_MATRIX_TOKEN"},
+			Usage:   "Matrix access token, used instead of a password",
+		},
+		&cli.StringFlag{
+			Name:    "matrix_rooms",
+			EnvVars: []string{"MICRO_MATRIX_ROOMS"},
+			Usage:   "Room ids or aliases to join on start (comma-separated values)",
+		},
+		&cli.BoolFlag{
+			Name:    "matrix_autojoin",
+			EnvVars: []string{"MICRO_MATRIX_AUTOJOIN"},
+			Usage:   "Join rooms the bot is invited to",
+			Value:   true,
+		},
+		&cli.StringFlag{
+			Name:    "matrix_prefix",
+			EnvVars: []string{"MICRO_MATRIX_PREFIX"},
+			Usage:   "Prefix of commands sent to the bot",
+			Value:   "!micro",
+		},
+		&cli.StringFlag{
+			Name:    "matrix_whitelist",
+			EnvVars: []string{"MICRO_MATRIX_WHITELIST"},
+			Usage:   "Matrix user ids allowed to use the bot (comma-separated values)",
+		},
+		&cli.DurationFlag{
+			Name:    "matrix_send_interval",
+			EnvVars: []string{"MICRO_MATRIX_SEND_INTERVAL"},
+			Usage:   "Minimum time between messages sent by the bot",
+			Value:   time.Second,
+		},
+	}
+}
+
+func (m *matrixInput) Init(ctx *cli.Context) error {
+	m.homeserver = ctx.String("matrix_homeserver")
+	m.user = ctx.String("matrix_user")
+	m.password = ctx.String("matrix_password")
+	m.token = ctx.String("matrix_token")
+	m.autojoin = ctx.Bool("matrix_autojoin")
+	m.prefix = strings.TrimSpace(ctx.String("matrix_prefix"))
+	m.interval = ctx.Duration("matrix_send_interval")
+
+	if rooms := ctx.String("matrix_rooms"); len(rooms) > 0 {
+		m.rooms = strings.Split(rooms, ",")
+	}
+	if whitelist := ctx.String("matrix_whitelist"); len(whitelist) > 0 {
+		m.whitelist = strings.Split(whitelist, ",")
+	}
+
+	if len(m.homeserver) == 0 {
+		return errors.New("missing matrix homeserver")
+	}
+	if len(m.token) == 0 && (len(m.user) == 0 || len(m.password) == 0) {
+		return errors.New("missing matrix token or user and password")
+	}
+
+	return nil
+}
+
+func (m *matrixInput) allowed(sender string) bool {
+	if sender == m.userID {
+		return false
+	}
+	if len(m.whitelist) == 0 {
+		return true
+	}
+	for _, id := range m.whitelist {
+		if sender == id {
+			return true
+		}
+	}
+	return false
+}
+
+// command returns the command in a message body addressed to the bot
+func (m *matrixInput) command(body string) (string, bool) {
+	body = strings.TrimSpace(body)
+
+	// !micro help
+	if len(m.prefix) > 0 && strings.HasPrefix(body, m.prefix) {
+		rest := body[len(m.prefix):]
+		if len(rest) == 0 || rest[0] == ' ' {
+			return strings.TrimSpace(rest), true
+		}
+	}
+
+	// @micro:example.com: help or micro: help
+	names := []string{m.userID}
+	if i := strings.Index(m.userID, ":"); i > 1 {
+		names = append(names, m.userID[1:i])
+	}
+
+	for _, name := range names {
+		if len(name) == 0 || !strings.HasPrefix(body, name) {
+			continue
+		}
+		rest := strings.TrimSpace(body[len(name):])
+		if strings.HasPrefix(rest, ":") || strings.HasPrefix(rest, ",") {
+			return strings.TrimSpace(rest[1:]), true
+		}
+	}
+
+	return "", false
+}
+
+func (m *matrixInput) current() *matrixConn {
+	m.Lock()
+	defer m.Unlock()
+	return m.conn
+}
+
+// handle processes the rooms in a sync response
+func (m *matrixInput) handle(rsp *syncResponse) {
+	for room := range rsp.Rooms.Invite {
+		if !m.autojoin {
+			continue
+		}
+		if err := m.client.join(room); err != nil && logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Errorf("[bot][matrix] error joining %s: %v", room, err)
+		}
+	}
+
+	for room, joined := range rsp.Rooms.Join {
+		for _, ev := range joined.Timeline.Events {
+			switch ev.Type {
+			case "m.room.encrypted":
+				m.warnEncrypted(room)
+			case "m.room.message":
+				m.handleMessage(room, ev)
+			}
+		}
+	}
+}
+
+func (m *matrixInput) handleMessage(room string, ev event) {
+	if !m.allowed(ev.Sender) {
+		return
+	}
+
+	var msg message
+	if err := json.Unmarshal(ev.Content, &msg); err != nil || msg.Type != "m.text" {
+		return
+	}
+
+	cmd, ok := m.command(msg.Body)
+	if !ok || len(cmd) == 0 {
+		return
+	}
+
+	if conn := m.current(); conn != nil {
+		conn.deliver(&input.Event{
+			Type: input.TextEvent,
+			From: room + ":" + ev.Sender,
+			To:   m.userID,
+			Data: []byte(cmd),
+			Meta: map[string]interface{}{
+				"room":  room,
+				"event": ev.EventID,
+			},
+		})
+	}
+}
+
+// warnEncrypted logs once per room that encrypted messages can't be read
+func (m *matrixInput) warnEncrypted(room string) {
+	m.Lock()
+	warned := m.encrypted[room]
+	m.encrypted[room] = true
+	m.Unlock()
+
+	if !warned && logger.V(logger.WarnLevel, logger.DefaultLogger) {
+		logger.Warnf("[bot][matrix] ignoring encrypted messages in %s, disable encryption in the room to use the bot", room)
+	}
+}
+
+func (m *matrixInput) run(since string, exit chan struct{}) {
+	backoff := time.Second
+
+	for {
+		select {
+		case <-exit:
+			return
+		default:
+		}
+
+		rsp, err := m.client.sync(since, SyncTimeout)
+		if err != nil {
+			if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Error("[bot][matrix] sync error: ", err)
+			}
+			select {
+			case <-exit:
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < time.Minute {
+				backoff *= 2
+			}
+			continue
+		}
+
+		backoff = time.Second
+		since = rsp.NextBatch
+		m.handle(rsp)
+	}
+}
+
+func (m *matrixInput) Start() error {
+	m.Lock()
+	defer m.Unlock()
+
+	if m.running {
+		return nil
+	}
+
+	c := newClient(m.homeserver, m.token, m.interval)
+
+	if len(m.token) == 0 {
+		if err := c.login(m.user, m.password); err != nil {
+			return err
+		}
+	}
+
+	userID, err := c.whoami()
+	if err != nil {
+		return err
+	}
+
+	for _, room := range m.rooms {
+		if err := c.join(strings.TrimSpace(room)); err != nil {
+			return err
+		}
+	}
+
+	// skip messages sent before we started
+	rsp, err := c.sync("", 0)
+	if err != nil {
+		return err
+	}
+
+	m.client = c
+	m.userID = userID
+	m.encrypted = make(map[string]bool)
+	m.exit = make(chan struct{})
+	m.running = true
+
+	go m.run(rsp.NextBatch, m.exit)
+
+	return nil
+}
+
+func (m *matrixInput) Stream() (input.Conn, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if !m.running {
+		return nil, errors.New("not running")
+	}
+
+	if m.conn != nil {
+		m.conn.Close()
+	}
+
+	m.conn = newConn(m)
+	return m.conn, nil
+}
+
+func (m *matrixInput) Stop() error {
+	m.Lock()
+	defer m.Unlock()
+
+	if !m.running {
+		return nil
+	}
+
+	if m.conn != nil {
+		m.conn.Close()
+		m.conn = nil
+	}
+
+	close(m.exit)
+	m.running = false
+	return nil
+}
+
+func (m *matrixInput) String() string {
+	return "matrix"
+}
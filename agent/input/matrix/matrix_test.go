@@ -0,0 +1,161 @@
+package matrix
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/agent/input"
+)
+
+func TestCommand(t *testing.T) {
+	m := &matrixInput{prefix: "!micro", userID: "@micro:example.com"}
+
+	testData := []struct {
+		body    string
+		command string
+		ok      bool
+	}{
+		{"!micro help", "help", true},
+		{"  !micro   call go.micro.srv.greeter Say.Hello ", "call go.micro.srv.greeter Say.Hello", true},
+		{"!microscope", "", false},
+		{"@micro:example.com: help", "help", true},
+		{"micro, help", "help", true},
+		{"hello micro", "", false},
+	}
+
+	for _, d := range testData {
+		cmd, ok := m.command(d.body)
+		if ok != d.ok || cmd != d.command {
+			t.Fatalf("Expected %q %v for %q got %q %v", d.command, d.ok, d.body, cmd, ok)
+		}
+	}
+}
+
+type homeserver struct {
+	sync.Mutex
+	joined []string
+	sent   []message
+	// rate limited the first send
+	limited bool
+}
+
+func (h *homeserver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.Lock()
+	defer h.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/_matrix/client/r0")
+
+	switch {
+	case path == "/account/whoami":
+		w.Write([]byte(`{"user_id":"@micro:example.com"}`))
+	case strings.HasPrefix(path, "/join/"):
+		h.joined = append(h.joined, strings.TrimPrefix(path, "/join/"))
+		w.Write([]byte(`{}`))
+	case strings.HasPrefix(path, "/rooms/"):
+		if !h.limited {
+			h.limited = true
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"errcode":"M_LIMIT_EXCEEDED","retry_after_ms":10}`))
+			return
+		}
+		var msg message
+		json.NewDecoder(r.Body).Decode(&msg)
+		h.sent = append(h.sent, msg)
+		w.Write([]byte(`{}`))
+	case path == "/sync":
+		switch r.URL.Query().Get("since") {
+		case "":
+			// history is skipped
+			w.Write([]byte(`{"next_batch":"s1","rooms":{"join":{"!old:example.com":{"timeline":{"events":[
+				{"type":"m.room.message","sender":"@user:example.com","content":{"msgtype":"m.text","body":"!micro stale"}}
+			]}}}}}`))
+		case "s1":
+			w.Write([]byte(`{"next_batch":"s2","rooms":{
+				"invite":{"!invited:example.com":{}},
+				"join":{"!room:example.com":{"timeline":{"events":[
+					{"type":"m.room.message","sender":"@micro:example.com","content":{"msgtype":"m.text","body":"!micro self"}},
+					{"type":"m.room.encrypted","sender":"@user:example.com","content":{}},
+					{"type":"m.room.message","sender":"@user:example.com","content":{"msgtype":"m.text","body":"hello"}},
+					{"type":"m.room.message","sender":"@user:example.com","event_id":"$1","content":{"msgtype":"m.text","body":"!micro help"}}
+				]}}}}}`))
+		default:
+			h.Unlock()
+			time.Sleep(50 * time.Millisecond)
+			h.Lock()
+			w.Write([]byte(`{"next_batch":"s2"}`))
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestInput(t *testing.T) {
+	hs := &homeserver{}
+	srv := httptest.NewServer(hs)
+	defer srv.Close()
+
+	m := &matrixInput{
+		homeserver: srv.URL,
+		token:      "token",
+		rooms:      []string{"#micro:example.com"},
+		autojoin:   true,
+		prefix:     "!micro",
+		interval:   time.Millisecond,
+	}
+
+	// hold the connection before syncing starts
+	m.running = true
+	conn, err := m.Stream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.running = false
+
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+
+	var ev input.Event
+	if err := conn.Recv(&ev); err != nil {
+		t.Fatal(err)
+	}
+	if string(ev.Data) != "help" {
+		t.Fatalf("Expected help got %q", ev.Data)
+	}
+	if ev.From != "!room:example.com:@user:example.com" {
+		t.Fatalf("Unexpected sender %s", ev.From)
+	}
+
+	err = conn.Send(&input.Event{
+		Type: input.TextEvent,
+		Data: []byte("a <reply>"),
+		Meta: ev.Meta,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hs.Lock()
+	defer hs.Unlock()
+
+	if len(hs.joined) != 2 || hs.joined[0] != "#micro:example.com" || hs.joined[1] != "!invited:example.com" {
+		t.Fatalf("Unexpected joins %v", hs.joined)
+	}
+	if !hs.limited || len(hs.sent) != 1 {
+		t.Fatalf("Expected 1 message got %d", len(hs.sent))
+	}
+	if msg := hs.sent[0]; msg.Type != "m.notice" || msg.Body != "a <reply>" || msg.FormattedBody != "<pre><code>a &lt;reply&gt;</code></pre>" {
+		t.Fatalf("Unexpected message %+v", msg)
+	}
+}
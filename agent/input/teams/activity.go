@@ -0,0 +1,74 @@
+package teams
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Activity is the subset of a bot framework activity used by the bot
+type Activity struct {
+	Type         string        `json:"type"`
+	ID           string        `json:"id,omitempty"`
+	ServiceURL   string        `json:"serviceUrl,omitempty"`
+	ChannelID    string        `json:"channelId,omitempty"`
+	From         *Account      `json:"from,omitempty"`
+	Recipient    *Account      `json:"recipient,omitempty"`
+	Conversation *Conversation `json:"conversation,omitempty"`
+	ReplyToID    string        `json:"replyToId,omitempty"`
+	Text         string        `json:"text,omitempty"`
+	TextFormat   string        `json:"textFormat,omitempty"`
+	Attachments  []Attachment  `json:"attachments,omitempty"`
+}
+
+// Account is a user or bot
+type Account struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// Conversation the activity belongs to
+type Conversation struct {
+	ID string `json:"id"`
+}
+
+// Attachment of a card
+type Attachment struct {
+	ContentType string      `json:"contentType"`
+	Content     interface{} `json:"content"`
+}
+
+var mention = regexp.MustCompile(`<at>[^<]*</at>`)
+
+// text returns the activity's text without mentions of the bot
+func (a *Activity) text() string {
+	return strings.TrimSpace(mention.ReplaceAllString(a.Text, ""))
+}
+
+// reply returns a reply to the activity, formatted as an adaptive card if cards is true
+func reply(text string, cards bool) *Activity {
+	if !cards {
+		return &Activity{
+			Type:       "message",
+			Text:       text,
+			TextFormat: "markdown",
+		}
+	}
+
+	return &Activity{
+		Type: "message",
+		Attachments: []Attachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: map[string]interface{}{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.2",
+				"body": []map[string]interface{}{{
+					"type":     "TextBlock",
+					"text":     text,
+					"wrap":     true,
+					"fontType": "Monospace",
+				}},
+			},
+		}},
+	}
+}
@@ -0,0 +1,211 @@
+package teams
+
+import (
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	// KeysURL of the keys signing bot framework tokens
+	KeysURL = "https://login.botframework.com/v1/.well-known/keys"
+	// TokenURL issuing tokens to call the bot framework
+	TokenURL = "https://login.microsoftonline.com/botframework.com/oauth2/v2.0/token"
+	// Issuer of bot framework tokens
+	Issuer = "https://api.botframework.com"
+
+	client = &http.Client{Timeout: 10 * time.Second}
+)
+
+// verifier validates tokens sent by the bot framework
+type verifier struct {
+	appID string
+
+	sync.RWMutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+}
+
+func (v *verifier) refresh() error {
+	rsp, err := client.Get(KeysURL)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(rsp.Body).Decode(&set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	v.Lock()
+	v.keys = keys
+	v.fetched = time.Now()
+	v.Unlock()
+
+	return nil
+}
+
+func (v *verifier) key(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	v.RLock()
+	key, ok := v.keys[kid]
+	stale := time.Since(v.fetched) > 24*time.Hour
+	v.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+
+	// keys are rotated, unknown keys refetch at most once a minute
+	v.RLock()
+	recent := time.Since(v.fetched) < time.Minute
+	v.RUnlock()
+	if !ok && recent {
+		return nil, errors.New("unknown signing key")
+	}
+
+	if err := v.refresh(); err != nil && !ok {
+		return nil, err
+	}
+
+	v.RLock()
+	key, ok = v.keys[kid]
+	v.RUnlock()
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	return key, nil
+}
+
+// verify checks the bearer token of a request from the bot framework for the service url
+func (v *verifier) verify(r *http.Request, serviceURL string) error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return errors.New("missing bearer token")
+	}
+
+	claims := jwt.MapClaims{}
+	parser := &jwt.Parser{ValidMethods: []string{"RS256"}}
+	if _, err := parser.ParseWithClaims(strings.TrimPrefix(auth, "Bearer "), claims, v.key); err != nil {
+		return err
+	}
+
+	if !claims.VerifyIssuer(Issuer, true) {
+		return errors.New("invalid issuer")
+	}
+	if !claims.VerifyAudience(v.appID, true) {
+		return errors.New("invalid audience")
+	}
+	if _, ok := claims["exp"]; !ok {
+		return errors.New("token has no expiry")
+	}
+	// replies are sent to the service url so it must be the one in the token
+	if s, ok := claims["serviceurl"].(string); ok && s != serviceURL {
+		return errors.New("invalid service url")
+	}
+
+	return nil
+}
+
+// verifyHMAC checks the signature of an outgoing webhook request
+func verifyHMAC(r *http.Request, body []byte, secret []byte) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "HMAC ") {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "HMAC "))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+// credentials fetches and caches the token used to send activities
+type credentials struct {
+	appID       string
+	appPassword string
+
+	sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (c *credentials) get() (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.token) > 0 && time.Now().Before(c.expires) {
+		return c.token, nil
+	}
+
+	rsp, err := client.PostForm(TokenURL, url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.appID},
+		"client_secret": {c.appPassword},
+		"scope":         {"https://api.botframework.com/.default"},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching token: %s", rsp.Status)
+	}
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(rsp.Body).Decode(&tok); err != nil {
+		return "", err
+	}
+
+	c.token = tok.AccessToken
+	// refresh a little early so the token doesn't expire in flight
+	c.expires = time.Now().Add(time.Duration(tok.ExpiresIn)*time.Second - time.Minute)
+
+	return c.token, nil
+}
@@ -0,0 +1,151 @@
+package teams
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/micro/go-micro/v2/agent/input"
+)
+
+// message received from teams
+type message struct {
+	activity *Activity
+	// set for outgoing webhooks which are answered synchronously
+	reply chan *Activity
+}
+
+type teamsConn struct {
+	master *teamsInput
+	exit   chan struct{}
+	recv   chan *message
+
+	once sync.Once
+}
+
+func newConn(master *teamsInput) *teamsConn {
+	return &teamsConn{
+		master: master,
+		exit:   make(chan struct{}),
+		recv:   make(chan *message),
+	}
+}
+
+func (c *teamsConn) deliver(m *message) {
+	select {
+	case <-c.exit:
+	case c.recv <- m:
+	}
+}
+
+func (c *teamsConn) Recv(event *input.Event) error {
+	select {
+	case <-c.exit:
+		return errors.New("connection closed")
+	case m := <-c.recv:
+		a := m.activity
+
+		var from string
+		if a.From != nil {
+			from = a.From.ID
+		}
+		var conversation string
+		if a.Conversation != nil {
+			conversation = a.Conversation.ID
+		}
+
+		event.Type = input.TextEvent
+		event.From = conversation + ":" + from
+		if a.Recipient != nil {
+			event.To = a.Recipient.ID
+		}
+		event.Data = []byte(a.text())
+		event.Meta = map[string]interface{}{
+			"activity": a,
+		}
+		if m.reply != nil {
+			event.Meta["reply"] = m.reply
+		}
+		return nil
+	}
+}
+
+func (c *teamsConn) Send(event *input.Event) error {
+	if event.Meta == nil {
+		return errors.New("missing activity to reply to")
+	}
+
+	out := reply(string(event.Data), c.master.cards)
+
+	// outgoing webhook replies go in the response
+	if ch, ok := event.Meta["reply"].(chan *Activity); ok {
+		select {
+		case ch <- out:
+		default:
+		}
+		return nil
+	}
+
+	a, ok := event.Meta["activity"].(*Activity)
+	if !ok || a.Conversation == nil {
+		return errors.New("missing activity to reply to")
+	}
+
+	out.From = a.Recipient
+	out.Recipient = a.From
+	out.Conversation = a.Conversation
+	out.ReplyToID = a.ID
+
+	return c.master.sendActivity(a.ServiceURL, a.Conversation.ID, a.ID, out)
+}
+
+// sendActivity replies to an activity through the bot framework connector
+func (t *teamsInput) sendActivity(serviceURL, conversation, replyTo string, a *Activity) error {
+	if t.creds == nil {
+		return errors.New("missing bot framework credentials")
+	}
+
+	token, err := t.creds.get()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	u := fmt.Sprintf("%s/v3/conversations/%s/activities/%s",
+		strings.TrimSuffix(serviceURL, "/"), url.PathEscape(conversation), url.PathEscape(replyTo))
+
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	rsp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode >= 300 {
+		return fmt.Errorf("sending activity: %s", rsp.Status)
+	}
+
+	return nil
+}
+
+func (c *teamsConn) Close() error {
+	c.once.Do(func() {
+		close(c.exit)
+	})
+	return nil
+}
@@ -0,0 +1,273 @@
+// Package teams is a microsoft teams input for the micro bot using the bot framework or outgoing webhooks
+package teams
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micro/cli/v2"
+	"github.com/micro/go-micro/v2/agent/input"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+var (
+	// WebhookTimeout is how long teams waits for an outgoing webhook's response
+	WebhookTimeout = 5 * time.Second
+)
+
+type teamsInput struct {
+	address string
+	path    string
+	cards   bool
+
+	// bot framework
+	appID    string
+	verifier *verifier
+	creds    *credentials
+
+	// outgoing webhook
+	secret []byte
+
+	whitelist []string
+
+	sync.Mutex
+	running  bool
+	listener net.Listener
+	conn     *teamsConn
+}
+
+func init() {
+	input.Inputs["teams"] = &teamsInput{}
+}
+
+func (t *teamsInput) Flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "teams_app_id",
+			EnvVars: []string{"MICRO_TEAMS_APP_ID"},
+			Usage:   "Bot framework app id",
+		},
+		&cli.StringFlag{
+			Name:    "teams_app_password",
+			EnvVars: []string{"MICRO_TEAMS_APP_PASSWORD"},
+			Usage:   "Bot framework app password",
+		},
+		&cli.StringFlag{
+			Name:    "teams_webhook_secret",
+			EnvVars: []string{"MICRO_TEAMS_WEBHOOK_SECRET"},
+			Usage:   "Security token of a teams outgoing webhook",
+		},
+		&cli.StringFlag{
+			Name:    "teams_address",
+			EnvVars: []string{"MICRO_TEAMS_ADDRESS"},
+			Usage:   "Address to receive activities on",
+			Value:   ":3978",
+		},
+		&cli.StringFlag{
+			Name:    "teams_path",
+			EnvVars: []string{"MICRO_TEAMS_PATH"},
+			Usage:   "Path to receive activities on",
+			Value:   "/api/messages",
+		},
+		&cli.BoolFlag{
+			Name:    "teams_cards",
+			EnvVars: []string{"MICRO_TEAMS_CARDS"},
+			Usage:   "Reply with adaptive cards rather than markdown",
+			Value:   true,
+		},
+		&cli.StringFlag{
+			Name:    "teams_whitelist",
+			EnvVars: []string{"MICRO_TEAMS_WHITELIST"},
+			Usage:   "Teams user ids allowed to use the bot (comma-separated values)",
+		},
+	}
+}
+
+func (t *teamsInput) Init(ctx *cli.Context) error {
+	t.appID = ctx.String("teams_app_id")
+	t.address = ctx.String("teams_address")
+	t.path = ctx.String("teams_path")
+	t.cards = ctx.Bool("teams_cards")
+
+	if whitelist := ctx.String("teams_whitelist"); len(whitelist) > 0 {
+		t.whitelist = strings.Split(whitelist, ",")
+	}
+
+	if secret := ctx.String("teams_webhook_secret"); len(secret) > 0 {
+		b, err := base64.StdEncoding.DecodeString(secret)
+		if err != nil {
+			return errors.New("webhook secret must be base64")
+		}
+		t.secret = b
+	}
+
+	if len(t.appID) > 0 {
+		t.verifier = &verifier{appID: t.appID}
+		t.creds = &credentials{
+			appID:       t.appID,
+			appPassword: ctx.String("teams_app_password"),
+		}
+	}
+
+	if t.verifier == nil && len(t.secret) == 0 {
+		return errors.New("missing teams app id or webhook secret")
+	}
+
+	return nil
+}
+
+func (t *teamsInput) allowed(a *Activity) bool {
+	if len(t.whitelist) == 0 {
+		return true
+	}
+	if a.From == nil {
+		return false
+	}
+	for _, id := range t.whitelist {
+		if a.From.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *teamsInput) current() *teamsConn {
+	t.Lock()
+	defer t.Unlock()
+	return t.conn
+}
+
+// ServeHTTP receives activities from the bot framework and outgoing webhooks
+func (t *teamsInput) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != t.path {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var a Activity
+	if err := json.Unmarshal(body, &a); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	webhook := strings.HasPrefix(r.Header.Get("Authorization"), "HMAC ")
+
+	switch {
+	case webhook && len(t.secret) > 0:
+		if !verifyHMAC(r, body, t.secret) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+	case !webhook && t.verifier != nil:
+		if err := t.verifier.verify(r, a.ServiceURL); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	default:
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	conn := t.current()
+
+	if a.Type != "message" || !t.allowed(&a) || conn == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if !webhook {
+		// replies are sent through the bot framework
+		w.WriteHeader(http.StatusAccepted)
+		conn.deliver(&message{activity: &a})
+		return
+	}
+
+	// outgoing webhooks are answered in the response
+	rsp := make(chan *Activity, 1)
+	conn.deliver(&message{activity: &a, reply: rsp})
+
+	var out *Activity
+	select {
+	case out = <-rsp:
+	case <-time.After(WebhookTimeout):
+		out = reply("Still working on it...", false)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+func (t *teamsInput) Start() error {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.running {
+		return nil
+	}
+
+	l, err := net.Listen("tcp", t.address)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := http.Serve(l, t); err != nil && logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Error("[bot][teams] server error: ", err)
+		}
+	}()
+
+	t.listener = l
+	t.running = true
+
+	return nil
+}
+
+func (t *teamsInput) Stream() (input.Conn, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	if !t.running {
+		return nil, errors.New("not running")
+	}
+
+	if t.conn != nil {
+		t.conn.Close()
+	}
+
+	t.conn = newConn(t)
+	return t.conn, nil
+}
+
+func (t *teamsInput) Stop() error {
+	t.Lock()
+	defer t.Unlock()
+
+	if !t.running {
+		return nil
+	}
+
+	if t.conn != nil {
+		t.conn.Close()
+		t.conn = nil
+	}
+
+	t.running = false
+	return t.listener.Close()
+}
+
+func (t *teamsInput) String() string {
+	return "teams"
+}
@@ -0,0 +1,84 @@
+package teams
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/micro/go-micro/v2/agent/input"
+)
+
+func TestWebhook(t *testing.T) {
+	secret := []byte("secret")
+
+	ti := &teamsInput{
+		path:   "/api/messages",
+		secret: secret,
+		cards:  false,
+	}
+	ti.conn = newConn(ti)
+
+	// echo the command back
+	go func() {
+		var ev input.Event
+		if err := ti.conn.Recv(&ev); err != nil {
+			return
+		}
+		ti.conn.Send(&input.Event{
+			Meta: ev.Meta,
+			From: ev.To,
+			To:   ev.From,
+			Type: input.TextEvent,
+			Data: append([]byte("echo "), ev.Data...),
+		})
+	}()
+
+	body, _ := json.Marshal(&Activity{
+		Type:         "message",
+		Text:         "<at>Micro</at> help",
+		From:         &Account{ID: "user"},
+		Conversation: &Conversation{ID: "conversation"},
+	})
+
+	sign := func(b []byte) string {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(b)
+		return "HMAC " + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	r := httptest.NewRequest("POST", "/api/messages", bytes.NewReader(body))
+	r.Header.Set("Authorization", sign([]byte("tampered")))
+	w := httptest.NewRecorder()
+	ti.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for a bad signature got %d", w.Code)
+	}
+
+	r = httptest.NewRequest("POST", "/api/messages", bytes.NewReader(body))
+	r.Header.Set("Authorization", sign(body))
+	w = httptest.NewRecorder()
+	ti.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 got %d", w.Code)
+	}
+
+	var rsp Activity
+	if err := json.Unmarshal(w.Body.Bytes(), &rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Text != "echo help" {
+		t.Fatalf("Expected echo help got %q", rsp.Text)
+	}
+}
+
+func TestReply(t *testing.T) {
+	a := reply("hello", true)
+	if len(a.Attachments) != 1 || a.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("Expected an adaptive card got %+v", a)
+	}
+}
@@ -0,0 +1,135 @@
+package telegram
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/micro/go-micro/v2/agent/input"
+	"github.com/micro/go-micro/v2/logger"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+type telegramConn struct {
+	input *telegramInput
+
+	exit chan struct{}
+	once sync.Once
+}
+
+func newConn(input *telegramInput) *telegramConn {
+	return &telegramConn{
+		input: input,
+		exit:  make(chan struct{}),
+	}
+}
+
+func (tc *telegramConn) Close() error {
+	tc.once.Do(func() {
+		close(tc.exit)
+	})
+	return nil
+}
+
+func (tc *telegramConn) allowed(user *tgbotapi.User) bool {
+	if len(tc.input.whitelist) == 0 {
+		return true
+	}
+	return user != nil && contains(tc.input.whitelist, user.UserName)
+}
+
+func (tc *telegramConn) Recv(event *input.Event) error {
+	if event == nil {
+		return errors.New("event cannot be nil")
+	}
+
+	for {
+		var update tgbotapi.Update
+
+		select {
+		case <-tc.exit:
+			return errors.New("connection closed")
+		case <-tc.input.exit:
+			return errors.New("input stopped")
+		case update = <-tc.input.updates:
+		}
+
+		var msg *tgbotapi.Message
+		var from *tgbotapi.User
+		var text string
+
+		switch {
+		case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+			// inline keyboard button pressed
+			q := update.CallbackQuery
+			tc.input.api.AnswerCallbackQuery(tgbotapi.NewCallback(q.ID, ""))
+
+			msg, from, text = q.Message, q.From, strings.TrimSpace(q.Data)
+			if !tc.allowed(from) || len(text) == 0 {
+				continue
+			}
+		case update.Message != nil:
+			msg, from = update.Message, update.Message.From
+			if !tc.allowed(from) {
+				continue
+			}
+			cmd, ok := tc.input.command(msg)
+			if !ok {
+				continue
+			}
+			text = cmd
+		default:
+			continue
+		}
+
+		if event.Meta == nil {
+			event.Meta = make(map[string]interface{})
+		}
+
+		event.Type = input.TextEvent
+		if from != nil {
+			event.From = from.UserName
+		}
+		event.To = tc.input.api.Self.UserName
+		event.Data = []byte(text)
+		event.Meta["chatId"] = msg.Chat.ID
+		event.Meta["chatType"] = msg.Chat.Type
+		event.Meta["messageId"] = msg.MessageID
+
+		return nil
+	}
+}
+
+func (tc *telegramConn) Send(event *input.Event) error {
+	messageText := strings.TrimSpace(string(event.Data))
+
+	chatId, ok := event.Meta["chatId"].(int64)
+	if !ok {
+		return errors.New("missing chat to reply to")
+	}
+	chatType, _ := event.Meta["chatType"].(string)
+
+	msgConfig := tgbotapi.NewMessage(chatId, messageText)
+	msgConfig.ParseMode = tgbotapi.ModeHTML
+
+	if ChatType(chatType) == Group || ChatType(chatType) == Supergroup {
+		msgConfig.ReplyToMessageID, _ = event.Meta["messageId"].(int)
+	}
+
+	if markup := keyboardMarkup(tc.input.keyboard); markup != nil {
+		msgConfig.ReplyMarkup = markup
+	}
+
+	_, err := tc.input.api.Send(msgConfig)
+
+	if err != nil {
+		// probably it could be because of nested HTML tags -- telegram doesn't allow nested tags
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Error("[telegram][Send] error:", err)
+		}
+		msgConfig.Text = "This bot couldn't send the response (Internal error)"
+		tc.input.api.Send(msgConfig)
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+module github.com/micro/go-plugins/agent/input/telegram/v2
+
+go 1.13
+
+require (
+	github.com/micro/cli/v2 v2.1.2
+	github.com/micro/go-micro/v2 v2.9.1
+	gopkg.in/telegram-bot-api.v4 v4.6.4
+)
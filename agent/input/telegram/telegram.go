@@ -0,0 +1,358 @@
+// Package telegram is a telegram input for the micro bot supporting webhooks and inline keyboards
+package telegram
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/micro/cli/v2"
+	"github.com/micro/go-micro/v2/agent/input"
+	"github.com/micro/go-micro/v2/logger"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+type telegramInput struct {
+	sync.Mutex
+
+	debug     bool
+	token     string
+	whitelist []string
+
+	// webhook mode, polling is used if the url is empty
+	webhookURL     string
+	webhookAddress string
+	webhookSecret  string
+
+	// commands shown as inline keyboard buttons on replies
+	keyboard []string
+	// commands allowed in groups, empty allows all
+	groupCommands []string
+
+	api      *tgbotapi.BotAPI
+	updates  chan tgbotapi.Update
+	listener net.Listener
+	running  bool
+	exit     chan struct{}
+}
+
+type ChatType string
+
+const (
+	Private    ChatType = "private"
+	Group      ChatType = "group"
+	Supergroup ChatType = "supergroup"
+)
+
+func init() {
+	input.Inputs["telegram"] = &telegramInput{}
+}
+
+func (ti *telegramInput) Flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:    "telegram_debug",
+			EnvVars: []string{"MICRO_TELEGRAM_DEBUG"},
+			Usage:   "Telegram debug output",
+		},
+		&cli.StringFlag{
+			Name:    "telegram_token",
+			EnvVars: []string{"MICRO_TELEGRAM_TOKEN"},
+			Usage:   "Telegram token",
+		},
+		&cli.StringFlag{
+			Name:    "telegram_whitelist",
+			EnvVars: []string{"MICRO_TELEGRAM_WHITELIST"},
+			Usage:   "Telegram bot's users (comma-separated values)",
+		},
+		&cli.StringFlag{
+			Name:    "telegram_webhook_url",
+			EnvVars: []string{"MICRO_TELEGRAM_WEBHOOK_URL"},
+			Usage:   "Public url telegram sends updates to, polling is used if empty",
+		},
+		&cli.StringFlag{
+			Name:    "telegram_webhook_address",
+			EnvVars: []string{"MICRO_TELEGRAM_WEBHOOK_ADDRESS"},
+			Usage:   "Address to receive webhook updates on",
+			Value:   ":8443",
+		},
+		&cli.StringFlag{
+			Name:    "telegram_webhook_secret",
+			EnvVars: []string{"MICRO_TELEGRAM_WEBHOOK_SECRET"},
+			Usage:   "Secret token telegram sends with webhook updates",
+		},
+		&cli.StringFlag{
+			Name:    "telegram_keyboard",
+			EnvVars: []string{"MICRO_TELEGRAM_KEYBOARD"},
+			Usage:   "Commands shown as inline keyboard buttons on replies (comma-separated values)",
+		},
+		&cli.StringFlag{
+			Name:    "telegram_group_commands",
+			EnvVars: []string{"MICRO_TELEGRAM_GROUP_COMMANDS"},
+			Usage:   "Commands allowed in groups, all are allowed if empty (comma-separated values)",
+		},
+	}
+}
+
+func split(s string) []string {
+	var vals []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
+
+func contains(vals []string, v string) bool {
+	for _, s := range vals {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (ti *telegramInput) Init(ctx *cli.Context) error {
+	ti.debug = ctx.Bool("telegram_debug")
+	ti.token = ctx.String("telegram_token")
+	ti.whitelist = split(ctx.String("telegram_whitelist"))
+	ti.webhookURL = ctx.String("telegram_webhook_url")
+	ti.webhookAddress = ctx.String("telegram_webhook_address")
+	ti.webhookSecret = ctx.String("telegram_webhook_secret")
+	ti.keyboard = split(ctx.String("telegram_keyboard"))
+	ti.groupCommands = split(ctx.String("telegram_group_commands"))
+
+	if len(ti.token) == 0 {
+		return errors.New("missing telegram token")
+	}
+
+	for _, cmd := range ti.keyboard {
+		// callback data is limited to 64 bytes
+		if len(cmd) > 64 {
+			return errors.New("keyboard commands must be at most 64 bytes")
+		}
+	}
+
+	return nil
+}
+
+// command returns the bot command in a message, in groups only commands
+// addressed to the bot are accepted
+func (ti *telegramInput) command(msg *tgbotapi.Message) (string, bool) {
+	text := strings.TrimSpace(msg.Text)
+	self := ti.api.Self.UserName
+	group := msg.Chat != nil && !msg.Chat.IsPrivate()
+
+	var cmd, args string
+
+	switch {
+	case msg.IsCommand():
+		// /list@otherbot is for another bot
+		if at := msg.CommandWithAt(); strings.Contains(at, "@") && !strings.EqualFold(at[strings.Index(at, "@")+1:], self) {
+			return "", false
+		}
+		cmd = msg.Command()
+		args = strings.TrimSpace(msg.CommandArguments())
+	case strings.HasPrefix(strings.ToLower(text), "@"+strings.ToLower(self)):
+		// @bot list services
+		fields := strings.Fields(text[len(self)+1:])
+		if len(fields) == 0 {
+			return "", false
+		}
+		cmd = fields[0]
+		args = strings.Join(fields[1:], " ")
+	case group:
+		// other messages in groups aren't for the bot
+		return "", false
+	default:
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			return "", false
+		}
+		cmd = fields[0]
+		args = strings.Join(fields[1:], " ")
+	}
+
+	if group && len(ti.groupCommands) > 0 && !contains(ti.groupCommands, cmd) {
+		return "", false
+	}
+
+	if len(args) > 0 {
+		return cmd + " " + args, true
+	}
+	return cmd, true
+}
+
+// keyboardMarkup returns the inline keyboard for replies, three buttons a row
+func keyboardMarkup(commands []string) *tgbotapi.InlineKeyboardMarkup {
+	if len(commands) == 0 {
+		return nil
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+
+	for _, cmd := range commands {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(cmd, cmd))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	markup := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &markup
+}
+
+// ServeHTTP receives webhook updates
+func (ti *telegramInput) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if len(ti.webhookSecret) > 0 && r.Header.Get("X-Telegram-Bot-Api-Secret-Token") != ti.webhookSecret {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&update); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// telegram resends updates which aren't acknowledged
+	select {
+	case ti.updates <- update:
+	case <-ti.exit:
+	}
+}
+
+// setWebhook registers the webhook, the secret token isn't supported by WebhookConfig
+func (ti *telegramInput) setWebhook() error {
+	v := url.Values{}
+	v.Set("url", ti.webhookURL)
+	v.Set("allowed_updates", `["message","callback_query"]`)
+	if len(ti.webhookSecret) > 0 {
+		v.Set("secret_token", ti.webhookSecret)
+	}
+	_, err := ti.api.MakeRequest("setWebhook", v)
+	return err
+}
+
+func (ti *telegramInput) Stream() (input.Conn, error) {
+	ti.Lock()
+	defer ti.Unlock()
+
+	if !ti.running {
+		return nil, errors.New("not running")
+	}
+
+	return newConn(ti), nil
+}
+
+func (ti *telegramInput) Start() error {
+	ti.Lock()
+	defer ti.Unlock()
+
+	if ti.running {
+		return nil
+	}
+
+	api, err := tgbotapi.NewBotAPI(ti.token)
+	if err != nil {
+		return err
+	}
+
+	ti.api = api
+
+	api.Debug = ti.debug
+
+	ti.exit = make(chan struct{})
+	ti.updates = make(chan tgbotapi.Update, api.Buffer)
+
+	if len(ti.webhookURL) > 0 {
+		l, err := net.Listen("tcp", ti.webhookAddress)
+		if err != nil {
+			return err
+		}
+
+		if err := ti.setWebhook(); err != nil {
+			l.Close()
+			return err
+		}
+
+		go func() {
+			if err := http.Serve(l, ti); err != nil && logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+				logger.Error("[telegram] webhook server error: ", err)
+			}
+		}()
+
+		ti.listener = l
+	} else {
+		// updates can't be polled while a webhook is set
+		if _, err := api.RemoveWebhook(); err != nil {
+			return err
+		}
+
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 60
+		updates, err := api.GetUpdatesChan(u)
+		if err != nil {
+			return err
+		}
+
+		go func(exit chan struct{}) {
+			for {
+				select {
+				case <-exit:
+					return
+				case update := <-updates:
+					select {
+					case ti.updates <- update:
+					case <-exit:
+						return
+					}
+				}
+			}
+		}(ti.exit)
+	}
+
+	ti.running = true
+
+	return nil
+}
+
+func (ti *telegramInput) Stop() error {
+	ti.Lock()
+	defer ti.Unlock()
+
+	if !ti.running {
+		return nil
+	}
+
+	close(ti.exit)
+	ti.running = false
+
+	if ti.listener != nil {
+		err := ti.listener.Close()
+		ti.listener = nil
+		return err
+	}
+
+	ti.api.StopReceivingUpdates()
+	return nil
+}
+
+func (p *telegramInput) String() string {
+	return "telegram"
+}
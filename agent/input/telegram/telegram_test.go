@@ -0,0 +1,162 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/micro/go-micro/v2/agent/input"
+	tgbotapi "gopkg.in/telegram-bot-api.v4"
+)
+
+func message(chat, text string) *tgbotapi.Message {
+	msg := &tgbotapi.Message{
+		MessageID: 1,
+		From:      &tgbotapi.User{UserName: "user"},
+		Chat:      &tgbotapi.Chat{ID: 1, Type: chat},
+		Text:      text,
+	}
+	if strings.HasPrefix(text, "/") {
+		n := strings.Index(text+" ", " ")
+		msg.Entities = &[]tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: n}}
+	}
+	return msg
+}
+
+func TestCommand(t *testing.T) {
+	ti := &telegramInput{
+		api:           &tgbotapi.BotAPI{Self: tgbotapi.User{UserName: "microbot"}},
+		groupCommands: []string{"help", "list"},
+	}
+
+	testData := []struct {
+		chat    string
+		text    string
+		command string
+		ok      bool
+	}{
+		{"private", "list services", "list services", true},
+		{"private", "/list services", "list services", true},
+		{"group", "list services", "", false},
+		{"group", "/list@microbot services", "list services", true},
+		{"group", "/list@otherbot services", "", false},
+		{"supergroup", "@microbot help", "help", true},
+		{"group", "/call greeter Say.Hello", "", false},
+		{"private", "/call greeter Say.Hello", "call greeter Say.Hello", true},
+	}
+
+	for _, d := range testData {
+		cmd, ok := ti.command(message(d.chat, d.text))
+		if ok != d.ok || cmd != d.command {
+			t.Fatalf("Expected %q %v for %s %q got %q %v", d.command, d.ok, d.chat, d.text, cmd, ok)
+		}
+	}
+}
+
+// rewrite sends api requests to the test server
+type rewrite struct {
+	target *url.URL
+}
+
+func (rw rewrite) RoundTrip(r *http.Request) (*http.Response, error) {
+	r.URL.Scheme = rw.target.Scheme
+	r.URL.Host = rw.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestWebhook(t *testing.T) {
+	var mtx sync.Mutex
+	calls := map[string]url.Values{}
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mtx.Lock()
+		calls[r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]] = r.PostForm
+		mtx.Unlock()
+		w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer api.Close()
+
+	target, _ := url.Parse(api.URL)
+	client := &http.Client{Transport: rewrite{target}}
+
+	ti := &telegramInput{
+		api:           &tgbotapi.BotAPI{Token: "token", Client: client, Self: tgbotapi.User{UserName: "microbot"}},
+		webhookSecret: "secret",
+		keyboard:      []string{"help", "list services"},
+		updates:       make(chan tgbotapi.Update, 10),
+		exit:          make(chan struct{}),
+	}
+	conn := newConn(ti)
+
+	post := func(secret string, update tgbotapi.Update) int {
+		b, _ := json.Marshal(update)
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(b))
+		r.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+		w := httptest.NewRecorder()
+		ti.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := post("wrong", tgbotapi.Update{}); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 got %d", code)
+	}
+
+	// ignored in groups then accepted when addressed to the bot
+	post("secret", tgbotapi.Update{UpdateID: 1, Message: message("group", "help")})
+	post("secret", tgbotapi.Update{UpdateID: 2, Message: message("group", "/help@microbot")})
+
+	var ev input.Event
+	if err := conn.Recv(&ev); err != nil {
+		t.Fatal(err)
+	}
+	if string(ev.Data) != "help" || ev.From != "user" {
+		t.Fatalf("Unexpected event %s %q", ev.From, ev.Data)
+	}
+
+	if err := conn.Send(&input.Event{Data: []byte("reply"), Meta: ev.Meta}); err != nil {
+		t.Fatal(err)
+	}
+
+	mtx.Lock()
+	sent := calls["sendMessage"]
+	mtx.Unlock()
+	if sent.Get("text") != "reply" || sent.Get("reply_to_message_id") != "1" {
+		t.Fatalf("Unexpected message %v", sent)
+	}
+	var markup tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(sent.Get("reply_markup")), &markup); err != nil {
+		t.Fatal(err)
+	}
+	if len(markup.InlineKeyboard) != 1 || len(markup.InlineKeyboard[0]) != 2 || *markup.InlineKeyboard[0][1].CallbackData != "list services" {
+		t.Fatalf("Unexpected keyboard %+v", markup)
+	}
+
+	// pressing a button runs its command
+	post("secret", tgbotapi.Update{UpdateID: 3, CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      "query",
+		From:    &tgbotapi.User{UserName: "user"},
+		Message: message("group", "reply"),
+		Data:    "list services",
+	}})
+
+	ev = input.Event{}
+	if err := conn.Recv(&ev); err != nil {
+		t.Fatal(err)
+	}
+	if string(ev.Data) != "list services" {
+		t.Fatalf("Expected list services got %q", ev.Data)
+	}
+
+	mtx.Lock()
+	answered := calls["answerCallbackQuery"]
+	mtx.Unlock()
+	if answered.Get("callback_query_id") != "query" {
+		t.Fatalf("Expected the callback query to be answered got %v", answered)
+	}
+}
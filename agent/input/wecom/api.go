@@ -0,0 +1,124 @@
+package wecom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var (
+	// APIURL is the wecom server api
+	APIURL = "https://qyapi.weixin.qq.com/cgi-bin"
+
+	client = &http.Client{Timeout: 10 * time.Second}
+)
+
+type apiResponse struct {
+	ErrCode     int    `json:"errcode"`
+	ErrMsg      string `json:"errmsg"`
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (r *apiResponse) err() error {
+	if r.ErrCode != 0 {
+		return fmt.Errorf("wecom error %d: %s", r.ErrCode, r.ErrMsg)
+	}
+	return nil
+}
+
+// credentials caches the app's access token
+type credentials struct {
+	corpID string
+	secret string
+
+	sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+func (c *credentials) get() (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.token) > 0 && time.Now().Before(c.expiry) {
+		return c.token, nil
+	}
+
+	q := url.Values{}
+	q.Set("corpid", c.corpID)
+	q.Set("corpsecret", c.secret)
+
+	rsp, err := client.Get(APIURL + "/gettoken?" + q.Encode())
+	if err != nil {
+		return "", err
+	}
+	defer rsp.Body.Close()
+
+	var r apiResponse
+	if err := json.NewDecoder(rsp.Body).Decode(&r); err != nil {
+		return "", err
+	}
+	if err := r.err(); err != nil {
+		return "", err
+	}
+
+	c.token = r.AccessToken
+	// refresh a minute early
+	c.expiry = time.Now().Add(time.Duration(r.ExpiresIn)*time.Second - time.Minute)
+
+	return c.token, nil
+}
+
+// invalidate forgets the token after the server rejects it
+func (c *credentials) invalidate() {
+	c.Lock()
+	c.token = ""
+	c.Unlock()
+}
+
+// sendMarkdown sends a markdown message to a user
+func (w *wecomInput) sendMarkdown(user, content string) error {
+	b, err := json.Marshal(map[string]interface{}{
+		"touser":   user,
+		"msgtype":  "markdown",
+		"agentid":  w.agentID,
+		"markdown": map[string]string{"content": content},
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		token, err := w.creds.get()
+		if err != nil {
+			return err
+		}
+
+		rsp, err := client.Post(APIURL+"/message/send?access_token="+url.QueryEscape(token), "application/json", bytes.NewReader(b))
+		if err != nil {
+			return err
+		}
+
+		var r apiResponse
+		err = json.NewDecoder(rsp.Body).Decode(&r)
+		rsp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		// expired or invalid access token
+		if r.ErrCode == 42001 || r.ErrCode == 40014 {
+			w.creds.invalidate()
+			continue
+		}
+
+		return r.err()
+	}
+
+	return fmt.Errorf("wecom: access token rejected")
+}
@@ -0,0 +1,68 @@
+package wecom
+
+import (
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/micro/go-micro/v2/agent/input"
+)
+
+type wecomConn struct {
+	master *wecomInput
+	exit   chan struct{}
+	recv   chan *Message
+
+	once sync.Once
+}
+
+func newConn(master *wecomInput) *wecomConn {
+	return &wecomConn{
+		master: master,
+		exit:   make(chan struct{}),
+		recv:   make(chan *Message),
+	}
+}
+
+func (c *wecomConn) deliver(m *Message) {
+	select {
+	case <-c.exit:
+	case c.recv <- m:
+	}
+}
+
+func (c *wecomConn) Recv(event *input.Event) error {
+	select {
+	case <-c.exit:
+		return errors.New("connection closed")
+	case m := <-c.recv:
+		event.Type = input.TextEvent
+		event.From = m.FromUserName
+		event.To = m.ToUserName
+		event.Data = []byte(strings.TrimSpace(m.Content))
+		event.Meta = map[string]interface{}{
+			"user": m.FromUserName,
+		}
+		return nil
+	}
+}
+
+func (c *wecomConn) Send(event *input.Event) error {
+	if event.Meta == nil {
+		return errors.New("missing user to reply to")
+	}
+
+	user, ok := event.Meta["user"].(string)
+	if !ok || len(user) == 0 {
+		return errors.New("missing user to reply to")
+	}
+
+	return c.master.sendMarkdown(user, string(event.Data))
+}
+
+func (c *wecomConn) Close() error {
+	c.once.Do(func() {
+		close(c.exit)
+	})
+	return nil
+}
@@ -0,0 +1,110 @@
+package wecom
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// crypter verifies and decrypts callback messages as described in
+// https://work.weixin.qq.com/api/doc/90000/90139/90968
+type crypter struct {
+	token  string
+	key    []byte
+	corpID string
+}
+
+func newCrypter(token, encodingAESKey, corpID string) (*crypter, error) {
+	key, err := base64.StdEncoding.DecodeString(encodingAESKey + "=")
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("encoding aes key must be 43 characters of base64")
+	}
+	return &crypter{token: token, key: key, corpID: corpID}, nil
+}
+
+// signature of a callback
+func (c *crypter) signature(timestamp, nonce, encrypted string) string {
+	parts := []string{c.token, timestamp, nonce, encrypted}
+	sort.Strings(parts)
+	sum := sha1.Sum([]byte(strings.Join(parts, "")))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *crypter) verify(signature, timestamp, nonce, encrypted string) bool {
+	return c.signature(timestamp, nonce, encrypted) == signature
+}
+
+// decrypt returns the message in an encrypted payload
+func (c *crypter) decrypt(encrypted string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 || len(b)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid ciphertext")
+	}
+
+	block, err := aes.NewCipher(c.key)
+	if err != nil {
+		return nil, err
+	}
+
+	plain := make([]byte, len(b))
+	cipher.NewCBCDecrypter(block, c.key[:aes.BlockSize]).CryptBlocks(plain, b)
+
+	// pkcs7 padded to 32 bytes
+	pad := int(plain[len(plain)-1])
+	if pad < 1 || pad > 32 || pad > len(plain) {
+		return nil, errors.New("invalid padding")
+	}
+	plain = plain[:len(plain)-pad]
+
+	// random(16) + length(4) + message + corp id
+	if len(plain) < 20 {
+		return nil, errors.New("invalid message")
+	}
+	n := int(binary.BigEndian.Uint32(plain[16:20]))
+	if n > len(plain)-20 {
+		return nil, errors.New("invalid message length")
+	}
+
+	msg := plain[20 : 20+n]
+	if string(plain[20+n:]) != c.corpID {
+		return nil, errors.New("message for another corp")
+	}
+
+	return msg, nil
+}
+
+// encrypt a message with the given random prefix
+func (c *crypter) encrypt(random, msg []byte) (string, error) {
+	if len(random) != 16 {
+		return "", errors.New("random must be 16 bytes")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(random)
+	binary.Write(&buf, binary.BigEndian, uint32(len(msg)))
+	buf.Write(msg)
+	buf.WriteString(c.corpID)
+
+	pad := 32 - buf.Len()%32
+	buf.Write(bytes.Repeat([]byte{byte(pad)}, pad))
+
+	block, err := aes.NewCipher(c.key)
+	if err != nil {
+		return "", err
+	}
+
+	b := buf.Bytes()
+	cipher.NewCBCEncrypter(block, c.key[:aes.BlockSize]).CryptBlocks(b, b)
+
+	return base64.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,280 @@
+// Package wecom is a wecom (wechat work) input for the micro bot using an app's callback url
+package wecom
+
+import (
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/micro/cli/v2"
+	"github.com/micro/go-micro/v2/agent/input"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+type wecomInput struct {
+	address   string
+	path      string
+	agentID   int
+	whitelist []string
+
+	crypter *crypter
+	creds   *credentials
+
+	sync.Mutex
+	running  bool
+	listener net.Listener
+	conn     *wecomConn
+}
+
+// envelope posted to the callback url
+type envelope struct {
+	ToUserName string `xml:"ToUserName"`
+	AgentID    string `xml:"AgentID"`
+	Encrypt    string `xml:"Encrypt"`
+}
+
+// Message received by the app
+type Message struct {
+	ToUserName   string `xml:"ToUserName"`
+	FromUserName string `xml:"FromUserName"`
+	CreateTime   int64  `xml:"CreateTime"`
+	MsgType      string `xml:"MsgType"`
+	Content      string `xml:"Content"`
+	MsgID        string `xml:"MsgId"`
+	AgentID      int    `xml:"AgentID"`
+}
+
+func init() {
+	input.Inputs["wecom"] = &wecomInput{}
+}
+
+func (w *wecomInput) Flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "wecom_corp_id",
+			EnvVars: []string{"MICRO_WECOM_CORP_ID"},
+			Usage:   "WeCom corp id",
+		},
+		&cli.IntFlag{
+			Name:    "wecom_agent_id",
+			EnvVars: []string{"MICRO_WECOM_AGENT_ID"},
+			Usage:   "WeCom app agent id",
+		},
+		&cli.StringFlag{
+			Name:    "wecom_secret",
+			EnvVars: []string{"MICRO_WECOM_SECRET"},
+			Usage:   "WeCom app secret",
+		},
+		&cli.StringFlag{
+			Name:    "wecom_token",
+			EnvVars: []string{"MICRO_WECOM_TOKEN"},
+			Usage:   "Token of the app's callback url",
+		},
+		&cli.StringFlag{
+			Name:    "wecom_aes_key",
+			EnvVars: []string{"MICRO_WECOM_AES_KEY"},
+			Usage:   "EncodingAESKey of the app's callback url",
+		},
+		&cli.StringFlag{
+			Name:    "wecom_address",
+			EnvVars: []string{"MICRO_WECOM_ADDRESS"},
+			Usage:   "Address to receive callbacks on",
+			Value:   ":8090",
+		},
+		&cli.StringFlag{
+			Name:    "wecom_path",
+			EnvVars: []string{"MICRO_WECOM_PATH"},
+			Usage:   "Path to receive callbacks on",
+			Value:   "/wecom",
+		},
+		&cli.StringFlag{
+			Name:    "wecom_whitelist",
+			EnvVars: []string{"MICRO_WECOM_WHITELIST"},
+			Usage:   "WeCom user ids allowed to use the bot (comma-separated values)",
+		},
+	}
+}
+
+func (w *wecomInput) Init(ctx *cli.Context) error {
+	corpID := ctx.String("wecom_corp_id")
+	secret := ctx.String("wecom_secret")
+
+	w.agentID = ctx.Int("wecom_agent_id")
+	w.address = ctx.String("wecom_address")
+	w.path = ctx.String("wecom_path")
+
+	if whitelist := ctx.String("wecom_whitelist"); len(whitelist) > 0 {
+		w.whitelist = strings.Split(whitelist, ",")
+	}
+
+	if len(corpID) == 0 || len(secret) == 0 || w.agentID == 0 {
+		return errors.New("missing wecom corp id, agent id or secret")
+	}
+
+	c, err := newCrypter(ctx.String("wecom_token"), ctx.String("wecom_aes_key"), corpID)
+	if err != nil {
+		return err
+	}
+	if len(c.token) == 0 {
+		return errors.New("missing wecom callback token")
+	}
+
+	w.crypter = c
+	w.creds = &credentials{corpID: corpID, secret: secret}
+
+	return nil
+}
+
+func (w *wecomInput) allowed(user string) bool {
+	if len(w.whitelist) == 0 {
+		return true
+	}
+	for _, id := range w.whitelist {
+		if user == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *wecomInput) current() *wecomConn {
+	w.Lock()
+	defer w.Unlock()
+	return w.conn
+}
+
+// ServeHTTP verifies the callback url and receives messages
+func (w *wecomInput) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != w.path {
+		http.NotFound(rw, r)
+		return
+	}
+
+	q := r.URL.Query()
+	signature := q.Get("msg_signature")
+	timestamp := q.Get("timestamp")
+	nonce := q.Get("nonce")
+
+	switch r.Method {
+	case http.MethodGet:
+		// url verification echoes the decrypted echostr
+		echo := q.Get("echostr")
+		if !w.crypter.verify(signature, timestamp, nonce, echo) {
+			http.Error(rw, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		b, err := w.crypter.decrypt(echo)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rw.Write(b)
+	case http.MethodPost:
+		body, err := ioutil.ReadAll(http.MaxBytesReader(rw, r.Body, 1<<20))
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var env envelope
+		if err := xml.Unmarshal(body, &env); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !w.crypter.verify(signature, timestamp, nonce, env.Encrypt) {
+			http.Error(rw, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		b, err := w.crypter.decrypt(env.Encrypt)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var msg Message
+		if err := xml.Unmarshal(b, &msg); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// reply straight away, wecom retries requests taking over 5 seconds
+		rw.WriteHeader(http.StatusOK)
+
+		if msg.MsgType != "text" || !w.allowed(msg.FromUserName) {
+			return
+		}
+
+		if conn := w.current(); conn != nil {
+			go conn.deliver(&msg)
+		}
+	default:
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (w *wecomInput) Start() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.running {
+		return nil
+	}
+
+	l, err := net.Listen("tcp", w.address)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := http.Serve(l, w); err != nil && logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Error("[bot][wecom] server error: ", err)
+		}
+	}()
+
+	w.listener = l
+	w.running = true
+
+	return nil
+}
+
+func (w *wecomInput) Stream() (input.Conn, error) {
+	w.Lock()
+	defer w.Unlock()
+
+	if !w.running {
+		return nil, errors.New("not running")
+	}
+
+	if w.conn != nil {
+		w.conn.Close()
+	}
+
+	w.conn = newConn(w)
+	return w.conn, nil
+}
+
+func (w *wecomInput) Stop() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if !w.running {
+		return nil
+	}
+
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	w.running = false
+	return w.listener.Close()
+}
+
+func (w *wecomInput) String() string {
+	return "wecom"
+}
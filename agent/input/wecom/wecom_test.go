@@ -0,0 +1,129 @@
+package wecom
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/micro/go-micro/v2/agent/input"
+)
+
+const aesKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+
+func TestCrypt(t *testing.T) {
+	c, err := newCrypter("token", aesKey, "corp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc, err := c.encrypt([]byte("0123456789abcdef"), []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := c.decrypt(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello" {
+		t.Fatalf("Expected hello got %q", b)
+	}
+
+	other, _ := newCrypter("token", aesKey, "other")
+	if _, err := other.decrypt(enc); err == nil {
+		t.Fatal("Expected error decrypting another corp's message")
+	}
+
+	if !c.verify(c.signature("1", "2", enc), "1", "2", enc) || c.verify(c.signature("1", "2", enc), "1", "3", enc) {
+		t.Fatal("Unexpected signature verification")
+	}
+
+	if _, err := newCrypter("token", "short", "corp"); err == nil {
+		t.Fatal("Expected error for an invalid aes key")
+	}
+}
+
+func TestCallback(t *testing.T) {
+	var sent map[string]interface{}
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gettoken":
+			w.Write([]byte(`{"errcode":0,"access_token":"token","expires_in":7200}`))
+		case "/message/send":
+			if r.URL.Query().Get("access_token") != "token" {
+				w.Write([]byte(`{"errcode":40014,"errmsg":"invalid access_token"}`))
+				return
+			}
+			json.NewDecoder(r.Body).Decode(&sent)
+			w.Write([]byte(`{"errcode":0}`))
+		}
+	}))
+	defer api.Close()
+
+	defer func(u string) { APIURL = u }(APIURL)
+	APIURL = api.URL
+
+	c, _ := newCrypter("token", aesKey, "corp")
+	w := &wecomInput{
+		path:    "/wecom",
+		agentID: 1000002,
+		crypter: c,
+		creds:   &credentials{corpID: "corp", secret: "secret"},
+	}
+	w.conn = newConn(w)
+
+	query := func(enc string) string {
+		q := url.Values{}
+		q.Set("timestamp", "1600000000")
+		q.Set("nonce", "nonce")
+		q.Set("msg_signature", c.signature("1600000000", "nonce", enc))
+		return q.Encode()
+	}
+
+	// url verification
+	echo, _ := c.encrypt([]byte("0123456789abcdef"), []byte("12345"))
+	rsp := httptest.NewRecorder()
+	w.ServeHTTP(rsp, httptest.NewRequest("GET", "/wecom?"+query(echo)+"&echostr="+url.QueryEscape(echo), nil))
+	if rsp.Code != http.StatusOK || rsp.Body.String() != "12345" {
+		t.Fatalf("Unexpected verification response %d %q", rsp.Code, rsp.Body.String())
+	}
+
+	msg := `<xml><ToUserName>corp</ToUserName><FromUserName>user</FromUserName><MsgType>text</MsgType><Content> help </Content><AgentID>1000002</AgentID></xml>`
+	enc, _ := c.encrypt([]byte("0123456789abcdef"), []byte(msg))
+	body := fmt.Sprintf(`<xml><ToUserName>corp</ToUserName><AgentID>1000002</AgentID><Encrypt>%s</Encrypt></xml>`, enc)
+
+	rsp = httptest.NewRecorder()
+	w.ServeHTTP(rsp, httptest.NewRequest("POST", "/wecom?"+query("tampered"), strings.NewReader(body)))
+	if rsp.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 got %d", rsp.Code)
+	}
+
+	rsp = httptest.NewRecorder()
+	w.ServeHTTP(rsp, httptest.NewRequest("POST", "/wecom?"+query(enc), strings.NewReader(body)))
+	if rsp.Code != http.StatusOK {
+		t.Fatalf("Expected 200 got %d", rsp.Code)
+	}
+
+	var ev input.Event
+	if err := w.conn.Recv(&ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.From != "user" || string(ev.Data) != "help" {
+		t.Fatalf("Unexpected event %s %q", ev.From, ev.Data)
+	}
+
+	if err := w.conn.Send(&input.Event{Data: []byte("**ok**"), Meta: ev.Meta}); err != nil {
+		t.Fatal(err)
+	}
+	if sent["touser"] != "user" || sent["msgtype"] != "markdown" || sent["agentid"].(float64) != 1000002 {
+		t.Fatalf("Unexpected message %v", sent)
+	}
+	if md := sent["markdown"].(map[string]interface{}); md["content"] != "**ok**" {
+		t.Fatalf("Unexpected content %v", md)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/micro/go-micro/v2/config/cmd"
 	log "github.com/micro/go-micro/v2/logger"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -243,6 +244,23 @@ func NewBroker(opts ...broker.Option) broker.Broker {
 	// retrieve client opts
 	cOpts, _ := options.Context.Value(clientOptionKey{}).([]option.ClientOption)
 
+	if ep, ok := options.Context.Value(endpointKey{}).(string); ok && len(ep) > 0 {
+		cOpts = append(cOpts, option.WithEndpoint(ep))
+	}
+
+	// the emulator doesn't need credentials, so connect without them
+	emulator, _ := options.Context.Value(emulatorKey{}).(string)
+	if len(emulator) == 0 {
+		emulator = os.Getenv("PUBSUB_EMULATOR_HOST")
+	}
+	if len(emulator) > 0 {
+		conn, err := grpc.Dial(emulator, grpc.WithInsecure())
+		if err != nil {
+			panic(err.Error())
+		}
+		cOpts = append(cOpts, option.WithGRPCConn(conn), option.WithoutAuthentication())
+	}
+
 	// create pubsub client
 	c, err := pubsub.NewClient(context.Background(), prjID, cOpts...)
 	if err != nil {
@@ -0,0 +1,54 @@
+package googlepubsub
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/micro/go-micro/v2/broker"
+)
+
+func TestEmulator(t *testing.T) {
+	srv := pstest.NewServer()
+	defer srv.Close()
+
+	testEmulator(t, NewBroker(ProjectID("test"), Emulator(srv.Addr)))
+
+	os.Setenv("PUBSUB_EMULATOR_HOST", srv.Addr)
+	defer os.Unsetenv("PUBSUB_EMULATOR_HOST")
+
+	testEmulator(t, NewBroker(ProjectID("test")))
+}
+
+func testEmulator(t *testing.T, b broker.Broker) {
+	defer b.Disconnect()
+
+	// subscribing needs the topic to exist
+	if err := b.Publish("foo", &broker.Message{Body: []byte("create")}); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := make(chan *broker.Message, 10)
+	s, err := b.Subscribe("foo", func(e broker.Event) error {
+		msgs <- e.Message()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+
+	if err := b.Publish("foo", &broker.Message{Header: map[string]string{"id": "1"}, Body: []byte("bar")}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case m := <-msgs:
+		if string(m.Body) != "bar" || m.Header["id"] != "1" {
+			t.Fatalf("unexpected message %v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected message from the emulator")
+	}
+}
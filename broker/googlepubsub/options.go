@@ -20,6 +20,10 @@ type createSubscription struct{}
 
 type deleteSubscription struct{}
 
+type endpointKey struct{}
+
+type emulatorKey struct{}
+
 // ClientOption is a broker Option which allows google pubsub client options to be
 // set for the client
 func ClientOption(c ...option.ClientOption) broker.Option {
@@ -41,6 +45,28 @@ func ProjectID(id string) broker.Option {
 	}
 }
 
+// Endpoint overrides the pubsub service endpoint e.g a regional endpoint
+// or a private service connect address
+func Endpoint(addr string) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, endpointKey{}, addr)
+	}
+}
+
+// Emulator connects to the pubsub emulator at the address without credentials,
+// like setting the PUBSUB_EMULATOR_HOST env var which is used otherwise
+func Emulator(addr string) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, emulatorKey{}, addr)
+	}
+}
+
 // CreateSubscription prevents the creation of the subscription if it not exists
 func CreateSubscription(b bool) broker.Option {
 	return func(o *broker.Options) {
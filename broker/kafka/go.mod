@@ -3,7 +3,11 @@ module github.com/micro/go-plugins/broker/kafka/v2
 go 1.13
 
 require (
-	github.com/Shopify/sarama v1.25.0
+	github.com/Shopify/sarama v1.38.1
 	github.com/google/uuid v1.1.1
 	github.com/micro/go-micro/v2 v2.9.1
+	github.com/xdg/scram v0.0.0-20180814205039-7eeb5667e42c
+	github.com/xdg/stringprep v1.0.0 // indirect
 )
+
+replace google.golang.org/grpc => google.golang.org/grpc v1.26.0
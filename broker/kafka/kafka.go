@@ -3,7 +3,10 @@ package kafka
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/google/uuid"
@@ -21,15 +24,23 @@ type kBroker struct {
 
 	sc []sarama.Client
 
+	// transactional producer, started by the first transaction
+	txn *txnProducer
+
 	connected bool
 	scMutex   sync.Mutex
 	opts      broker.Options
 }
 
 type subscriber struct {
+	*partitions
+
 	cg   sarama.ConsumerGroup
 	t    string
 	opts broker.SubscribeOptions
+
+	once sync.Once
+	exit chan bool
 }
 
 type publication struct {
@@ -71,6 +82,9 @@ func (s *subscriber) Topic() string {
 }
 
 func (s *subscriber) Unsubscribe() error {
+	s.once.Do(func() {
+		close(s.exit)
+	})
 	return s.cg.Close()
 }
 
@@ -93,7 +107,10 @@ func (k *kBroker) Connect() error {
 	}
 	k.scMutex.Unlock()
 
-	pconfig := k.getBrokerConfig()
+	pconfig, err := k.getBrokerConfig()
+	if err != nil {
+		return err
+	}
 	// For implementation reasons, the SyncProducer requires
 	// `Producer.Return.Errors` and `Producer.Return.Successes`
 	// to be set to true in its configuration.
@@ -127,6 +144,12 @@ func (k *kBroker) Disconnect() error {
 		client.Close()
 	}
 	k.sc = nil
+	if k.txn != nil {
+		k.txn.Lock()
+		k.txn.reset()
+		k.txn.Unlock()
+		k.txn = nil
+	}
 	k.p.Close()
 	if err := k.c.Close(); err != nil {
 		return err
@@ -150,6 +173,17 @@ func (k *kBroker) Init(opts ...broker.Option) error {
 		cAddrs = []string{"127.0.0.1:9092"}
 	}
 	k.addrs = cAddrs
+
+	if _, err := k.getClusterConfig(); err != nil {
+		return err
+	}
+	c, err := k.getBrokerConfig()
+	if err != nil {
+		return err
+	}
+	if k.idempotent() {
+		return validateIdempotent(c)
+	}
 	return nil
 }
 
@@ -158,19 +192,51 @@ func (k *kBroker) Options() broker.Options {
 }
 
 func (k *kBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
-	b, err := k.opts.Codec.Marshal(msg)
+	m, err := k.producerMessage(topic, msg, opts...)
 	if err != nil {
 		return err
 	}
-	_, _, err = k.p.SendMessage(&sarama.ProducerMessage{
+	// record headers need version 0.11, the headers are encoded in the value anyway
+	if !k.c.Config().Version.IsAtLeast(sarama.V0_11_0_0) {
+		m.Headers = nil
+	}
+	_, _, err = k.p.SendMessage(m)
+	return err
+}
+
+// producerMessage returns the message written by a publish of the broker or a
+// transaction, the message headers are also sent as record headers
+func (k *kBroker) producerMessage(topic string, msg *broker.Message, opts ...broker.PublishOption) (*sarama.ProducerMessage, error) {
+	options := broker.PublishOptions{
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	b, err := k.opts.Codec.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &sarama.ProducerMessage{
 		Topic: topic,
 		Value: sarama.ByteEncoder(b),
-	})
-	return err
+	}
+	if key, ok := options.Context.Value(publishKeyKey{}).([]byte); ok {
+		m.Key = sarama.ByteEncoder(key)
+	}
+	for hk, hv := range msg.Header {
+		m.Headers = append(m.Headers, sarama.RecordHeader{Key: []byte(hk), Value: []byte(hv)})
+	}
+	return m, nil
 }
 
 func (k *kBroker) getSaramaClusterClient(topic string) (sarama.Client, error) {
-	config := k.getClusterConfig()
+	config, err := k.getClusterConfig()
+	if err != nil {
+		return nil, err
+	}
 	cs, err := sarama.NewClient(k.addrs, config)
 	if err != nil {
 		return nil, err
@@ -189,6 +255,10 @@ func (k *kBroker) Subscribe(topic string, handler broker.Handler, opts ...broker
 	for _, o := range opts {
 		o(&opt)
 	}
+	t, err := newTopics(topic, opt)
+	if err != nil {
+		return nil, err
+	}
 	// we need to create a new client per consumer
 	c, err := k.getSaramaClusterClient(topic)
 	if err != nil {
@@ -203,18 +273,42 @@ func (k *kBroker) Subscribe(topic string, handler broker.Handler, opts ...broker
 		subopts: opt,
 		kopts:   k.opts,
 		cg:      cg,
+		parts:   newPartitions(),
 	}
-	ctx := context.Background()
-	topics := []string{topic}
+	sub := &subscriber{partitions: h.parts, cg: cg, opts: opt, t: topic, exit: make(chan bool)}
 	go func() {
 		for {
 			select {
+			case <-sub.exit:
+				return
 			case err := <-cg.Errors():
 				if err != nil {
 					log.Errorf("consumer error:", err)
 				}
 			default:
-				err := cg.Consume(ctx, topics, h)
+				topics, err := t.list(c)
+				if err == nil && len(topics) == 0 {
+					// wait for a topic matching the regex to be created
+					select {
+					case <-sub.exit:
+						return
+					case <-time.After(t.refresh):
+					}
+					continue
+				}
+				if err != nil {
+					log.Error(err)
+					time.Sleep(time.Second)
+					continue
+				}
+
+				// a new session is started when the matching topics change
+				ctx, cancel := context.WithCancel(context.Background())
+				go t.watch(ctx, c, topics, cancel)
+
+				err = cg.Consume(ctx, topics, h)
+				cancel()
+
 				switch err {
 				case sarama.ErrClosedConsumerGroup:
 					return
@@ -226,7 +320,7 @@ func (k *kBroker) Subscribe(topic string, handler broker.Handler, opts ...broker
 			}
 		}
 	}()
-	return &subscriber{cg: cg, opts: opt, t: topic}, nil
+	return sub, nil
 }
 
 func (k *kBroker) String() string {
@@ -261,23 +355,121 @@ func NewBroker(opts ...broker.Option) broker.Broker {
 	}
 }
 
-func (k *kBroker) getBrokerConfig() *sarama.Config {
+// setAuth enables the SASL authentication and TLS of the broker options in the config
+func (k *kBroker) setAuth(c *sarama.Config) error {
+	tc, err := k.tlsConfig()
+	if err != nil {
+		return err
+	}
+	if tc != nil {
+		c.Net.TLS.Enable = true
+		c.Net.TLS.Config = tc
+	}
+
+	m, hasMechanism := k.opts.Context.Value(saslMechanismKey{}).(sarama.SASLMechanism)
+	cred, hasCredentials := k.opts.Context.Value(saslCredentialsKey{}).(saslCredentials)
+	if !hasMechanism && !hasCredentials {
+		return nil
+	}
+	if !hasMechanism {
+		m = sarama.SASLTypePlaintext
+	}
+
+	c.Net.SASL.Enable = true
+	c.Net.SASL.Mechanism = m
+	if hasCredentials {
+		c.Net.SASL.User = cred.user
+		c.Net.SASL.Password = cred.password
+	}
+
+	switch m {
+	case sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512:
+		if c.Net.SASL.SCRAMClientGeneratorFunc == nil {
+			c.Net.SASL.SCRAMClientGeneratorFunc = SCRAMClientGenerator(m)
+		}
+	}
+	return nil
+}
+
+func (k *kBroker) idempotent() bool {
+	v, _ := k.opts.Context.Value(idempotentKey{}).(bool)
+	return v
+}
+
+// setIdempotent enables the idempotent producer in the config if it's set in
+// the broker options, the settings it limits are only changed in the default config
+func (k *kBroker) setIdempotent(c *sarama.Config, defaults bool) {
+	if !k.idempotent() {
+		return
+	}
+
+	c.Producer.Idempotent = true
+	c.Producer.RequiredAcks = sarama.WaitForAll
+
+	if defaults {
+		c.Net.MaxOpenRequests = 1
+		if !c.Version.IsAtLeast(sarama.V0_11_0_0) {
+			c.Version = sarama.V0_11_0_0
+		}
+	}
+}
+
+// validateIdempotent returns an error if the config is incompatible with the idempotent producer
+func validateIdempotent(c *sarama.Config) error {
+	switch {
+	case !c.Version.IsAtLeast(sarama.V0_11_0_0):
+		return fmt.Errorf("kafka: idempotent producer requires version 0.11 or later, got %s", c.Version)
+	case c.Net.MaxOpenRequests != 1:
+		return fmt.Errorf("kafka: idempotent producer requires Net.MaxOpenRequests 1, got %d", c.Net.MaxOpenRequests)
+	case c.Producer.Retry.Max < 1:
+		return fmt.Errorf("kafka: idempotent producer requires Producer.Retry.Max of at least 1, got %d", c.Producer.Retry.Max)
+	case c.Producer.RequiredAcks != sarama.WaitForAll:
+		return errors.New("kafka: idempotent producer requires Producer.RequiredAcks WaitForAll")
+	}
+	return nil
+}
+
+// copyConfig returns a copy of the config for the broker to change, the
+// default configs are shared by all brokers and the ones passed in are the caller's
+func copyConfig(c *sarama.Config) *sarama.Config {
+	cp := *c
+	return &cp
+}
+
+func (k *kBroker) getBrokerConfig() (*sarama.Config, error) {
 	if c, ok := k.opts.Context.Value(brokerConfigKey{}).(*sarama.Config); ok {
-		return c
+		c = copyConfig(c)
+		if err := k.setAuth(c); err != nil {
+			return nil, err
+		}
+		k.setIdempotent(c, false)
+		return c, nil
 	}
-	return DefaultBrokerConfig
+	c := copyConfig(DefaultBrokerConfig)
+	if err := k.setAuth(c); err != nil {
+		return nil, err
+	}
+	k.setIdempotent(c, true)
+	return c, nil
 }
 
-func (k *kBroker) getClusterConfig() *sarama.Config {
+func (k *kBroker) getClusterConfig() (*sarama.Config, error) {
 	if c, ok := k.opts.Context.Value(clusterConfigKey{}).(*sarama.Config); ok {
-		return c
+		c = copyConfig(c)
+		if err := k.setAuth(c); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	clusterConfig := copyConfig(DefaultClusterConfig)
+	if err := k.setAuth(clusterConfig); err != nil {
+		return nil, err
 	}
-	clusterConfig := DefaultClusterConfig
 	// the oldest supported version is V0_10_2_0
 	if !clusterConfig.Version.IsAtLeast(sarama.V0_10_2_0) {
 		clusterConfig.Version = sarama.V0_10_2_0
 	}
 	clusterConfig.Consumer.Return.Errors = true
 	clusterConfig.Consumer.Offsets.Initial = sarama.OffsetNewest
-	return clusterConfig
+	return clusterConfig, nil
 }
@@ -0,0 +1,756 @@
+package kafka
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/codec/json"
+	mls "github.com/micro/go-micro/v2/util/tls"
+	"github.com/xdg/scram"
+)
+
+type testSession struct {
+	sarama.ConsumerGroupSession
+	ctx    context.Context
+	claims map[string][]int32
+	marked chan *sarama.ConsumerMessage
+}
+
+func (s *testSession) Claims() map[string][]int32 {
+	return s.claims
+}
+
+func (s *testSession) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.marked <- msg
+}
+
+type testClaim struct {
+	sarama.ConsumerGroupClaim
+	topic     string
+	partition int32
+	messages  chan *sarama.ConsumerMessage
+}
+
+func (c *testClaim) Topic() string {
+	return c.topic
+}
+
+func (c *testClaim) Partition() int32 {
+	return c.partition
+}
+
+func (c *testClaim) Messages() <-chan *sarama.ConsumerMessage {
+	return c.messages
+}
+
+func TestPartitions(t *testing.T) {
+	foo0, foo1, foo2 := TopicPartition{"foo", 0}, TopicPartition{"foo", 1}, TopicPartition{"foo", 2}
+
+	p := newPartitions()
+	p.assign([]TopicPartition{foo0, foo1, foo2})
+
+	p.Pause(foo1)
+	if paused := p.Paused(); !reflect.DeepEqual(paused, []TopicPartition{foo1}) {
+		t.Fatalf("expected partition 1 paused got %v", paused)
+	}
+
+	p.Pause()
+	if paused := p.Paused(); !reflect.DeepEqual(paused, []TopicPartition{foo0, foo1, foo2}) {
+		t.Fatalf("expected all partitions paused got %v", paused)
+	}
+
+	p.Resume(foo0)
+	if paused := p.Paused(); !reflect.DeepEqual(paused, []TopicPartition{foo1, foo2}) {
+		t.Fatalf("expected partitions 1 and 2 paused got %v", paused)
+	}
+
+	p.Resume()
+	if paused := p.Paused(); len(paused) != 0 {
+		t.Fatalf("expected no partitions paused got %v", paused)
+	}
+
+	if parts := p.Partitions(); !reflect.DeepEqual(parts, []TopicPartition{foo0, foo1, foo2}) {
+		t.Fatalf("expected partitions 0, 1 and 2 got %v", parts)
+	}
+}
+
+func TestPause(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sess := &testSession{
+		ctx:    ctx,
+		claims: map[string][]int32{"test": {0}},
+		marked: make(chan *sarama.ConsumerMessage, 10),
+	}
+	claim := &testClaim{topic: "test", messages: make(chan *sarama.ConsumerMessage, 10)}
+
+	h := &consumerGroupHandler{
+		handler: func(broker.Event) error { return nil },
+		subopts: broker.SubscribeOptions{AutoAck: true},
+		kopts:   broker.Options{Codec: json.Marshaler{}},
+		parts:   newPartitions(),
+	}
+	if err := h.Setup(sess); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := &subscriber{partitions: h.parts}
+	var _ Pauser = sub
+
+	sub.Pause(TopicPartition{"test", 0})
+
+	done := make(chan error)
+	go func() {
+		done <- h.ConsumeClaim(sess, claim)
+	}()
+
+	claim.messages <- &sarama.ConsumerMessage{Topic: "test", Value: []byte(`{"body": "MQ=="}`)}
+
+	select {
+	case <-sess.marked:
+		t.Fatal("message consumed while paused")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	sub.Resume(TopicPartition{"test", 0})
+
+	select {
+	case msg := <-sess.marked:
+		if string(msg.Value) != `{"body": "MQ=="}` {
+			t.Fatalf("unexpected message %s", msg.Value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not consumed after resuming")
+	}
+
+	// ending the session while paused returns from the claim
+	sub.Pause()
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("claim not released when the session ended")
+	}
+}
+
+type testClient struct {
+	sarama.Client
+	sync.Mutex
+	topics []string
+}
+
+func (c *testClient) RefreshMetadata(...string) error {
+	return nil
+}
+
+func (c *testClient) Topics() ([]string, error) {
+	c.Lock()
+	defer c.Unlock()
+	return c.topics, nil
+}
+
+func TestTopics(t *testing.T) {
+	c := &testClient{topics: []string{"__consumer_offsets", "orders.created", "orders.paid", "payments", "orders"}}
+
+	testData := []struct {
+		topic    string
+		opts     []broker.SubscribeOption
+		expected []string
+	}{
+		{
+			topic:    "orders",
+			expected: []string{"orders"},
+		},
+		{
+			topic:    "orders",
+			opts:     []broker.SubscribeOption{SubscribeTopics("payments", "refunds")},
+			expected: []string{"orders", "payments", "refunds"},
+		},
+		{
+			topic:    `orders\..*`,
+			opts:     []broker.SubscribeOption{SubscribeRegex()},
+			expected: []string{"orders.created", "orders.paid"},
+		},
+		{
+			topic:    `pay.*`,
+			opts:     []broker.SubscribeOption{SubscribeRegex(), SubscribeTopics("refunds")},
+			expected: []string{"payments", "refunds"},
+		},
+		{
+			topic:    `.*`,
+			opts:     []broker.SubscribeOption{SubscribeRegex()},
+			expected: []string{"orders", "orders.created", "orders.paid", "payments"},
+		},
+	}
+
+	for _, d := range testData {
+		var opts broker.SubscribeOptions
+		for _, o := range d.opts {
+			o(&opts)
+		}
+
+		tp, err := newTopics(d.topic, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		topics, err := tp.list(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(topics, d.expected) {
+			t.Fatalf("expected %v for %s got %v", d.expected, d.topic, topics)
+		}
+	}
+
+	var opts broker.SubscribeOptions
+	SubscribeRegex()(&opts)
+	if _, err := newTopics("orders(", opts); err == nil {
+		t.Fatal("expected invalid regex to fail")
+	}
+}
+
+func TestTopicsWatch(t *testing.T) {
+	c := &testClient{topics: []string{"orders.created"}}
+
+	opts := broker.SubscribeOptions{}
+	SubscribeRegex()(&opts)
+	TopicRefresh(10 * time.Millisecond)(&opts)
+
+	tp, err := newTopics(`orders\..*`, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := tp.list(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tp.watch(ctx, c, current, cancel)
+
+	select {
+	case <-ctx.Done():
+		t.Fatal("session cancelled without the topics changing")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.Lock()
+	c.topics = append(c.topics, "orders.paid")
+	c.Unlock()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the session to be cancelled for the new topic")
+	}
+}
+
+func TestMultiTopicPartitions(t *testing.T) {
+	h := &consumerGroupHandler{parts: newPartitions()}
+	sess := &testSession{
+		ctx:    context.Background(),
+		claims: map[string][]int32{"orders": {0, 1}, "payments": {1, 2}},
+	}
+	if err := h.Setup(sess); err != nil {
+		t.Fatal(err)
+	}
+	parts := []TopicPartition{{"orders", 0}, {"orders", 1}, {"payments", 1}, {"payments", 2}}
+	if p := h.parts.Partitions(); !reflect.DeepEqual(p, parts) {
+		t.Fatalf("expected partitions of all topics got %v", p)
+	}
+}
+
+func TestPauseTopics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sess := &testSession{
+		ctx:    ctx,
+		claims: map[string][]int32{"orders": {1}, "payments": {1}},
+		marked: make(chan *sarama.ConsumerMessage, 10),
+	}
+
+	h := &consumerGroupHandler{
+		handler: func(broker.Event) error { return nil },
+		subopts: broker.SubscribeOptions{AutoAck: true},
+		kopts:   broker.Options{Codec: json.Marshaler{}},
+		parts:   newPartitions(),
+	}
+	if err := h.Setup(sess); err != nil {
+		t.Fatal(err)
+	}
+
+	orders := &testClaim{topic: "orders", partition: 1, messages: make(chan *sarama.ConsumerMessage, 10)}
+	payments := &testClaim{topic: "payments", partition: 1, messages: make(chan *sarama.ConsumerMessage, 10)}
+	for _, c := range []*testClaim{orders, payments} {
+		go h.ConsumeClaim(sess, c)
+	}
+
+	// pausing partition 1 of orders keeps consuming partition 1 of payments
+	h.parts.Pause(TopicPartition{"orders", 1})
+	if paused := h.parts.Paused(); !reflect.DeepEqual(paused, []TopicPartition{{"orders", 1}}) {
+		t.Fatalf("expected partition 1 of orders paused got %v", paused)
+	}
+
+	orders.messages <- &sarama.ConsumerMessage{Topic: "orders", Partition: 1, Value: []byte(`{"body": "MQ=="}`)}
+	payments.messages <- &sarama.ConsumerMessage{Topic: "payments", Partition: 1, Value: []byte(`{"body": "Mg=="}`)}
+
+	select {
+	case msg := <-sess.marked:
+		if msg.Topic != "payments" {
+			t.Fatalf("message of %s consumed while paused", msg.Topic)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message of payments not consumed")
+	}
+
+	select {
+	case msg := <-sess.marked:
+		t.Fatalf("message of %s consumed while paused", msg.Topic)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	h.parts.Resume(TopicPartition{"orders", 1})
+
+	select {
+	case msg := <-sess.marked:
+		if msg.Topic != "orders" {
+			t.Fatalf("unexpected message of %s", msg.Topic)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message of orders not consumed after resuming")
+	}
+}
+
+func TestSASL(t *testing.T) {
+	k := NewBroker(SASLScramSHA256("user", "secret"), BrokerConfig(sarama.NewConfig())).(*kBroker)
+
+	c, err := k.getBrokerConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Net.SASL.Enable || c.Net.SASL.Mechanism != sarama.SASLTypeSCRAMSHA256 {
+		t.Fatalf("Expected SCRAM-SHA-256 authentication, got %v %s", c.Net.SASL.Enable, c.Net.SASL.Mechanism)
+	}
+	if c.Net.SASL.User != "user" || c.Net.SASL.Password != "secret" {
+		t.Fatalf("Expected the credentials to be set, got %s %s", c.Net.SASL.User, c.Net.SASL.Password)
+	}
+	if c.Net.SASL.SCRAMClientGeneratorFunc == nil {
+		t.Fatal("Expected the SCRAM client to be set")
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	k = NewBroker(SASLCredentials("user", "secret"), ClusterConfig(sarama.NewConfig())).(*kBroker)
+	if c, _ := k.getClusterConfig(); !c.Net.SASL.Enable || c.Net.SASL.Mechanism != sarama.SASLTypePlaintext {
+		t.Fatalf("Expected PLAIN authentication, got %v %s", c.Net.SASL.Enable, c.Net.SASL.Mechanism)
+	}
+
+	k = NewBroker(BrokerConfig(sarama.NewConfig())).(*kBroker)
+	if c, _ := k.getBrokerConfig(); c.Net.SASL.Enable {
+		t.Fatal("Expected SASL to be disabled without options")
+	}
+}
+
+func TestSASLDefaultConfig(t *testing.T) {
+	a := NewBroker(SASLCredentials("user", "secret")).(*kBroker)
+	b := NewBroker().(*kBroker)
+
+	for _, get := range []func() (*sarama.Config, error){a.getBrokerConfig, a.getClusterConfig} {
+		c, err := get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !c.Net.SASL.Enable || c.Net.SASL.User != "user" {
+			t.Fatalf("Expected PLAIN authentication, got %v %s", c.Net.SASL.Enable, c.Net.SASL.User)
+		}
+	}
+	for _, get := range []func() (*sarama.Config, error){b.getBrokerConfig, b.getClusterConfig} {
+		c, err := get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Net.SASL.Enable || len(c.Net.SASL.User) > 0 {
+			t.Fatal("Expected the credentials of another broker not to be set")
+		}
+	}
+	if DefaultBrokerConfig.Net.SASL.Enable || DefaultClusterConfig.Net.SASL.Enable {
+		t.Fatal("Expected the default configs not to be changed")
+	}
+}
+
+func TestSCRAMClient(t *testing.T) {
+	for _, m := range []sarama.SASLMechanism{sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512} {
+		h := SHA512
+		if m == sarama.SASLTypeSCRAMSHA256 {
+			h = SHA256
+		}
+
+		sc, err := h.NewClient("user", "secret", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		creds := sc.GetStoredCredentials(scram.KeyFactors{Salt: "salt", Iters: 4096})
+
+		srv, err := h.NewServer(func(string) (scram.StoredCredentials, error) {
+			return creds, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		conv := srv.NewConversation()
+
+		c := SCRAMClientGenerator(m)()
+		if err := c.Begin("user", "secret", ""); err != nil {
+			t.Fatal(err)
+		}
+
+		var challenge string
+		for !c.Done() {
+			rsp, err := c.Step(challenge)
+			if err != nil {
+				t.Fatalf("%s: %v", m, err)
+			}
+			if c.Done() {
+				break
+			}
+			challenge, err = conv.Step(rsp)
+			if err != nil {
+				t.Fatalf("%s: %v", m, err)
+			}
+		}
+
+		if !conv.Valid() {
+			t.Fatalf("%s: Expected the server to authenticate the client", m)
+		}
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	k := NewBroker(Idempotent())
+	if err := k.Init(); err != nil {
+		t.Fatal(err)
+	}
+	c, err := k.(*kBroker).getBrokerConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Producer.Idempotent || c.Producer.RequiredAcks != sarama.WaitForAll || c.Net.MaxOpenRequests != 1 {
+		t.Fatalf("Expected the idempotent producer to be enabled, got %+v", c.Producer)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_0_0_0
+	if err := NewBroker(Idempotent(), BrokerConfig(config)).Init(); err == nil {
+		t.Fatal("Expected an error for Net.MaxOpenRequests 5")
+	}
+
+	config.Net.MaxOpenRequests = 1
+	config.Producer.Retry.Max = 0
+	if err := NewBroker(Idempotent(), BrokerConfig(config)).Init(); err == nil {
+		t.Fatal("Expected an error for Producer.Retry.Max 0")
+	}
+
+	config.Producer.Retry.Max = 3
+	if err := NewBroker(Idempotent(), BrokerConfig(config)).Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewBroker(BrokerConfig(sarama.NewConfig())).Init(); err != nil {
+		t.Fatalf("Expected no validation without Idempotent, got %v", err)
+	}
+}
+
+func TestIdempotentDefaultConfig(t *testing.T) {
+	a := NewBroker(Idempotent()).(*kBroker)
+	b := NewBroker().(*kBroker)
+
+	ac, err := a.getBrokerConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, err := b.getBrokerConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ac.Producer.Idempotent || ac.Net.MaxOpenRequests != 1 {
+		t.Fatalf("Expected the idempotent producer to be enabled, got %+v", ac.Producer)
+	}
+	if bc.Producer.Idempotent || bc.Net.MaxOpenRequests == 1 || bc.Producer.RequiredAcks == sarama.WaitForAll {
+		t.Fatalf("Expected the producer without Idempotent to keep the defaults, got %+v", bc.Producer)
+	}
+	if DefaultBrokerConfig.Producer.Idempotent || DefaultBrokerConfig.Net.MaxOpenRequests == 1 {
+		t.Fatal("Expected the default broker config not to be changed")
+	}
+}
+
+func TestProducerMessage(t *testing.T) {
+	k := NewBroker().(*kBroker)
+
+	m, err := k.producerMessage("foo", &broker.Message{
+		Header: map[string]string{"Content-Type": "application/json"},
+		Body:   []byte("body"),
+	}, PublishKey([]byte("key")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Topic != "foo" || !reflect.DeepEqual(m.Key, sarama.ByteEncoder("key")) {
+		t.Fatalf("Expected the message to foo with the key, got %s %v", m.Topic, m.Key)
+	}
+	headers := []sarama.RecordHeader{{Key: []byte("Content-Type"), Value: []byte("application/json")}}
+	if !reflect.DeepEqual(m.Headers, headers) {
+		t.Fatalf("Expected the record headers %v, got %v", headers, m.Headers)
+	}
+
+	if m, _ := k.producerMessage("foo", &broker.Message{Body: []byte("body")}); m.Key != nil || len(m.Headers) > 0 {
+		t.Fatalf("Expected no key or headers, got %v %v", m.Key, m.Headers)
+	}
+}
+
+func TestTransaction(t *testing.T) {
+	mb := sarama.NewMockBroker(t, 1)
+	defer mb.Close()
+
+	produce := sarama.NewMockProduceResponse(t).SetVersion(3)
+	handlers := map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(mb.Addr(), mb.BrokerID()).
+			SetController(mb.BrokerID()).
+			SetLeader("foo", 0, mb.BrokerID()).
+			SetLeader("bar", 0, mb.BrokerID()),
+		// the mock coordinator response is version 0, transactions need 1
+		"FindCoordinatorRequest": sarama.NewMockWrapper(&sarama.FindCoordinatorResponse{
+			Version:     1,
+			Coordinator: sarama.NewBroker(mb.Addr()),
+		}),
+		"InitProducerIDRequest": sarama.NewMockWrapper(&sarama.InitProducerIDResponse{
+			ProducerID:    1000,
+			ProducerEpoch: 1,
+		}),
+		"AddPartitionsToTxnRequest": sarama.NewMockWrapper(&sarama.AddPartitionsToTxnResponse{
+			Errors: map[string][]*sarama.PartitionError{
+				"foo": {{Partition: 0}},
+				"bar": {{Partition: 0}},
+			},
+		}),
+		"ProduceRequest": produce,
+		"EndTxnRequest":  sarama.NewMockWrapper(&sarama.EndTxnResponse{}),
+	}
+	mb.SetHandlerByMap(handlers)
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V0_11_0_0
+	config.Producer.Retry.Backoff = 0
+
+	if _, err := NewBroker(BrokerConfig(config)).(Transactional).BeginTransaction(); err != ErrNoTransactionalID {
+		t.Fatalf("Expected ErrNoTransactionalID, got %v", err)
+	}
+
+	b := NewBroker(broker.Addrs(mb.Addr()), BrokerConfig(config), TransactionalID("txn"))
+	if _, err := b.(Transactional).BeginTransaction(); err == nil {
+		t.Fatal("Expected an error before the broker is connected")
+	}
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	results := func() []bool {
+		var res []bool
+		for _, r := range mb.History() {
+			if req, ok := r.Request.(*sarama.EndTxnRequest); ok {
+				res = append(res, req.TransactionResult)
+			}
+		}
+		return res
+	}
+
+	txn, err := b.(Transactional).BeginTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.(Transactional).BeginTransaction(); err != ErrTransactionInProgress {
+		t.Fatalf("Expected ErrTransactionInProgress, got %v", err)
+	}
+
+	for _, topic := range []string{"foo", "bar", "foo"} {
+		if err := txn.Publish(topic, &broker.Message{Body: []byte("body")}, PublishKey([]byte("key"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Commit(); err != ErrTransactionDone {
+		t.Fatalf("Expected ErrTransactionDone, got %v", err)
+	}
+	if res := results(); !reflect.DeepEqual(res, []bool{true}) {
+		t.Fatalf("Expected the transaction to be committed, got %v", res)
+	}
+
+	// a failed publish aborts the transaction
+	produce.SetError("bar", 0, sarama.ErrNotEnoughReplicas)
+
+	txn, err = b.(Transactional).BeginTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Publish("foo", &broker.Message{Body: []byte("body")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Publish("bar", &broker.Message{Body: []byte("body")}); err == nil {
+		t.Fatal("Expected the publish to fail")
+	}
+	if err := txn.Commit(); err != ErrTransactionAborted {
+		t.Fatalf("Expected ErrTransactionAborted, got %v", err)
+	}
+	if res := results(); !reflect.DeepEqual(res, []bool{true, false}) {
+		t.Fatalf("Expected the transaction to be aborted, got %v", res)
+	}
+
+	txn, err = b.(Transactional).BeginTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Abort(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a producer fenced by another one with the transactional id is replaced
+	handlers["EndTxnRequest"] = sarama.NewMockWrapper(&sarama.EndTxnResponse{Err: sarama.ErrProducerFenced})
+	mb.SetHandlerByMap(handlers)
+
+	txn, err = b.(Transactional).BeginTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Publish("foo", &broker.Message{Body: []byte("body")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Commit(); err == nil {
+		t.Fatal("Expected the commit of the fenced producer to fail")
+	}
+	if p := b.(*kBroker).txn; p.p != nil {
+		t.Fatal("Expected the fenced producer to be closed")
+	}
+}
+
+// writeCert writes a self signed certificate and its key as PEM files to the dir
+func writeCert(t *testing.T, dir string) (string, string) {
+	cert, err := mls.Certificate("kafka")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key})
+	if err := ioutil.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kafka")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := writeCert(t, dir)
+
+	base := &tls.Config{MinVersion: tls.VersionTLS12}
+	k := NewBroker(
+		WithTLSConfig(base),
+		WithTLSCertFile(certFile, keyFile),
+		WithTLSCAFile(certFile),
+		WithTLSServerName("kafka.internal"),
+		BrokerConfig(sarama.NewConfig()),
+		ClusterConfig(sarama.NewConfig()),
+	).(*kBroker)
+	if err := k.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, get := range []func() (*sarama.Config, error){k.getBrokerConfig, k.getClusterConfig} {
+		c, err := get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tc := c.Net.TLS.Config
+		if !c.Net.TLS.Enable || tc == nil {
+			t.Fatal("Expected TLS to be enabled")
+		}
+		if tc.MinVersion != tls.VersionTLS12 || len(tc.Certificates) != 1 || tc.RootCAs == nil || tc.ServerName != "kafka.internal" {
+			t.Fatalf("Expected the TLS options to be set, got %+v", tc)
+		}
+		if tc == base || len(base.Certificates) > 0 {
+			t.Fatal("Expected the TLS config passed in not to be changed")
+		}
+	}
+
+	config := sarama.NewConfig()
+	k = NewBroker(WithTLSInsecureSkipVerify(), BrokerConfig(config), ClusterConfig(config)).(*kBroker)
+	for _, get := range []func() (*sarama.Config, error){k.getBrokerConfig, k.getClusterConfig} {
+		if c, _ := get(); c == config || !c.Net.TLS.Enable {
+			t.Fatal("Expected TLS to be enabled on a copy of the config")
+		}
+	}
+	if config.Net.TLS.Enable || config.Net.TLS.Config != nil {
+		t.Fatal("Expected the config passed in not to be changed")
+	}
+	if DefaultBrokerConfig.Net.TLS.Enable || DefaultClusterConfig.Net.TLS.Enable {
+		t.Fatal("Expected the default configs not to be changed")
+	}
+
+	k = NewBroker(WithTLSInsecureSkipVerify(), BrokerConfig(sarama.NewConfig())).(*kBroker)
+	if c, _ := k.getBrokerConfig(); !c.Net.TLS.Enable || !c.Net.TLS.Config.InsecureSkipVerify {
+		t.Fatal("Expected TLS without verifying certificates")
+	}
+
+	if err := NewBroker(WithTLSCAFile(filepath.Join(dir, "missing.pem"))).Init(); err == nil {
+		t.Fatal("Expected an error for a missing CA file")
+	}
+	if err := NewBroker(WithTLSCertFile(keyFile, certFile)).Init(); err == nil {
+		t.Fatal("Expected an error for an invalid certificate")
+	}
+
+	k = NewBroker(BrokerConfig(sarama.NewConfig())).(*kBroker)
+	if c, _ := k.getBrokerConfig(); c.Net.TLS.Enable {
+		t.Fatal("Expected TLS to be disabled without options")
+	}
+}
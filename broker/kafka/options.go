@@ -2,6 +2,8 @@ package kafka
 
 import (
 	"context"
+	"crypto/tls"
+	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/micro/go-micro/v2/broker"
@@ -24,6 +26,127 @@ func ClusterConfig(c *sarama.Config) broker.Option {
 	return setBrokerOption(clusterConfigKey{}, c)
 }
 
+type saslMechanismKey struct{}
+type saslCredentialsKey struct{}
+
+type saslCredentials struct {
+	user     string
+	password string
+}
+
+// SASLMechanism enables SASL authentication with the mechanism, one of
+// sarama.SASLTypePlaintext, sarama.SASLTypeSCRAMSHA256 or sarama.SASLTypeSCRAMSHA512.
+// The client of SCRAM mechanisms is set unless the config has one.
+func SASLMechanism(m sarama.SASLMechanism) broker.Option {
+	return setBrokerOption(saslMechanismKey{}, m)
+}
+
+// SASLCredentials sets the user name and password of SASL authentication, it's
+// enabled with the PLAIN mechanism unless SASLMechanism is set
+func SASLCredentials(user, password string) broker.Option {
+	return setBrokerOption(saslCredentialsKey{}, saslCredentials{user: user, password: password})
+}
+
+// SASLPlain enables SASL/PLAIN authentication
+func SASLPlain(user, password string) broker.Option {
+	return func(o *broker.Options) {
+		SASLMechanism(sarama.SASLTypePlaintext)(o)
+		SASLCredentials(user, password)(o)
+	}
+}
+
+// SASLScramSHA256 enables SASL/SCRAM-SHA-256 authentication
+func SASLScramSHA256(user, password string) broker.Option {
+	return func(o *broker.Options) {
+		SASLMechanism(sarama.SASLTypeSCRAMSHA256)(o)
+		SASLCredentials(user, password)(o)
+	}
+}
+
+// SASLScramSHA512 enables SASL/SCRAM-SHA-512 authentication
+func SASLScramSHA512(user, password string) broker.Option {
+	return func(o *broker.Options) {
+		SASLMechanism(sarama.SASLTypeSCRAMSHA512)(o)
+		SASLCredentials(user, password)(o)
+	}
+}
+
+type tlsConfigKey struct{}
+type tlsCertFileKey struct{}
+type tlsCAFileKey struct{}
+type tlsInsecureSkipVerifyKey struct{}
+type tlsServerNameKey struct{}
+
+type tlsCertFile struct {
+	cert string
+	key  string
+}
+
+// WithTLSConfig enables TLS with the config for the producer and the consumer
+// group clients, it's used instead of the TLSConfig of the broker options. The
+// other TLS options change a copy of it.
+func WithTLSConfig(c *tls.Config) broker.Option {
+	return setBrokerOption(tlsConfigKey{}, c)
+}
+
+// WithTLSCertFile enables mutual TLS with the PEM encoded certificate and key
+// files, they're read when the broker connects
+func WithTLSCertFile(certFile, keyFile string) broker.Option {
+	return setBrokerOption(tlsCertFileKey{}, tlsCertFile{cert: certFile, key: keyFile})
+}
+
+// WithTLSCAFile enables TLS verifying the certificates of the kafka brokers with
+// the PEM encoded CA certificates of the file instead of the system's
+func WithTLSCAFile(caFile string) broker.Option {
+	return setBrokerOption(tlsCAFileKey{}, caFile)
+}
+
+// WithTLSInsecureSkipVerify enables TLS without verifying the certificates of the kafka brokers
+func WithTLSInsecureSkipVerify() broker.Option {
+	return setBrokerOption(tlsInsecureSkipVerifyKey{}, true)
+}
+
+// WithTLSServerName enables TLS verifying the certificates of the kafka brokers
+// for the server name instead of their address, which is also sent as SNI
+func WithTLSServerName(name string) broker.Option {
+	return setBrokerOption(tlsServerNameKey{}, name)
+}
+
+type idempotentKey struct{}
+
+// Idempotent enables the idempotent producer so retried publishes are written
+// once per partition. It sets Producer.Idempotent and RequiredAcks WaitForAll,
+// and on the default config limits Net.MaxOpenRequests to 1 and raises the
+// version to 0.11. A config set with BrokerConfig must already have those and
+// Producer.Retry.Max of at least 1, Init returns an error otherwise.
+func Idempotent() broker.Option {
+	return setBrokerOption(idempotentKey{}, true)
+}
+
+type transactionalIDKey struct{}
+type transactionTimeoutKey struct{}
+
+// TransactionalID sets the transactional id of the producer, it's required by
+// BeginTransaction and must be the same across restarts of a producer so its
+// unfinished transactions are aborted
+func TransactionalID(id string) broker.Option {
+	return setBrokerOption(transactionalIDKey{}, id)
+}
+
+// TransactionTimeout sets how long a transaction may be in progress before the
+// coordinator aborts it, the default is DefaultTransactionTimeout
+func TransactionTimeout(d time.Duration) broker.Option {
+	return setBrokerOption(transactionTimeoutKey{}, d)
+}
+
+type publishKeyKey struct{}
+
+// PublishKey sets the key of the message, the default partitioner writes
+// messages with the same key to the same partition
+func PublishKey(key []byte) broker.PublishOption {
+	return setPublishOption(publishKeyKey{}, key)
+}
+
 type subscribeContextKey struct{}
 
 // SubscribeContext set the context for broker.SubscribeOption
@@ -33,6 +156,31 @@ func SubscribeContext(ctx context.Context) broker.SubscribeOption {
 
 type subscribeConfigKey struct{}
 
+type subscribeTopicsKey struct{}
+
+type subscribeRegexKey struct{}
+
+type topicRefreshKey struct{}
+
+// SubscribeTopics consumes the topics as well as the subscribed topic in one
+// consumer group. Publication.Topic returns the topic a message was sent to.
+func SubscribeTopics(topics ...string) broker.SubscribeOption {
+	return setSubscribeOption(subscribeTopicsKey{}, topics)
+}
+
+// SubscribeRegex treats the subscribed topic as a regular expression and consumes
+// every topic it matches in one consumer group e.g Subscribe("orders\\..*", ...).
+// The whole topic name must match. Topics created later are consumed once found.
+func SubscribeRegex() broker.SubscribeOption {
+	return setSubscribeOption(subscribeRegexKey{}, true)
+}
+
+// TopicRefresh sets how often the topics matching SubscribeRegex are looked up,
+// the default is DefaultTopicRefresh
+func TopicRefresh(d time.Duration) broker.SubscribeOption {
+	return setSubscribeOption(topicRefreshKey{}, d)
+}
+
 func SubscribeConfig(c *sarama.Config) broker.SubscribeOption {
 	return setSubscribeOption(subscribeConfigKey{}, c)
 }
@@ -44,12 +192,43 @@ type consumerGroupHandler struct {
 	kopts   broker.Options
 	cg      sarama.ConsumerGroup
 	sess    sarama.ConsumerGroupSession
+	parts   *partitions
+}
+
+func (h *consumerGroupHandler) Setup(sess sarama.ConsumerGroupSession) error {
+	var parts []TopicPartition
+	for topic, claimed := range sess.Claims() {
+		for _, p := range claimed {
+			parts = append(parts, TopicPartition{Topic: topic, Partition: p})
+		}
+	}
+	h.parts.assign(parts)
+	return nil
+}
+
+func (h *consumerGroupHandler) Cleanup(_ sarama.ConsumerGroupSession) error {
+	h.parts.assign(nil)
+	return nil
 }
 
-func (*consumerGroupHandler) Setup(_ sarama.ConsumerGroupSession) error   { return nil }
-func (*consumerGroupHandler) Cleanup(_ sarama.ConsumerGroupSession) error { return nil }
 func (h *consumerGroupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	for msg := range claim.Messages() {
+	for {
+		// stop reading while paused, unread messages are fetched again after a rebalance
+		if !h.parts.wait(sess.Context(), TopicPartition{Topic: claim.Topic(), Partition: claim.Partition()}) {
+			return nil
+		}
+
+		var msg *sarama.ConsumerMessage
+		select {
+		case m, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			msg = m
+		case <-sess.Context().Done():
+			return nil
+		}
+
 		var m broker.Message
 		p := &publication{m: &m, t: msg.Topic, km: msg, cg: h.cg, sess: sess}
 		eh := h.kopts.ErrorHandler
@@ -77,5 +256,4 @@ func (h *consumerGroupHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, cl
 			}
 		}
 	}
-	return nil
 }
@@ -0,0 +1,152 @@
+package kafka
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// TopicPartition is a partition of one of the topics consumed by a subscriber
+type TopicPartition struct {
+	Topic     string
+	Partition int32
+}
+
+// Pauser is implemented by kafka subscribers. Pausing stops consuming the
+// partitions without leaving the consumer group, so applications can apply
+// backpressure while a downstream dependency is degraded. Paused partitions
+// stay paused across rebalances until they're resumed.
+type Pauser interface {
+	// Pause stops consuming the partitions, or all partitions if none are given
+	Pause(partitions ...TopicPartition)
+	// Resume resumes consuming the partitions, or all partitions if none are given
+	Resume(partitions ...TopicPartition)
+	// Paused returns the paused partitions assigned to the subscriber
+	Paused() []TopicPartition
+	// Partitions returns the partitions assigned to the subscriber
+	Partitions() []TopicPartition
+}
+
+// partitions tracks the assigned and paused partitions of a subscriber
+type partitions struct {
+	sync.Mutex
+	// all partitions are paused
+	all      bool
+	paused   map[TopicPartition]bool
+	assigned map[TopicPartition]bool
+	// closed to wake claims waiting to be resumed
+	resumed chan struct{}
+}
+
+func newPartitions() *partitions {
+	return &partitions{
+		paused:   make(map[TopicPartition]bool),
+		assigned: make(map[TopicPartition]bool),
+		resumed:  make(chan struct{}),
+	}
+}
+
+func sorted(m map[TopicPartition]bool) []TopicPartition {
+	var parts []TopicPartition
+	for p, ok := range m {
+		if ok {
+			parts = append(parts, p)
+		}
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		if parts[i].Topic != parts[j].Topic {
+			return parts[i].Topic < parts[j].Topic
+		}
+		return parts[i].Partition < parts[j].Partition
+	})
+	return parts
+}
+
+func (p *partitions) assign(parts []TopicPartition) {
+	p.Lock()
+	defer p.Unlock()
+	p.assigned = make(map[TopicPartition]bool, len(parts))
+	for _, part := range parts {
+		p.assigned[part] = true
+	}
+}
+
+func (p *partitions) Pause(parts ...TopicPartition) {
+	p.Lock()
+	defer p.Unlock()
+
+	if len(parts) == 0 {
+		p.all = true
+		return
+	}
+
+	for _, part := range parts {
+		p.paused[part] = true
+	}
+}
+
+func (p *partitions) Resume(parts ...TopicPartition) {
+	p.Lock()
+	defer p.Unlock()
+
+	if len(parts) == 0 {
+		p.all = false
+		p.paused = make(map[TopicPartition]bool)
+	} else {
+		// keep the other assigned partitions paused
+		if p.all {
+			p.all = false
+			for part := range p.assigned {
+				p.paused[part] = true
+			}
+		}
+		for _, part := range parts {
+			delete(p.paused, part)
+		}
+	}
+
+	close(p.resumed)
+	p.resumed = make(chan struct{})
+}
+
+func (p *partitions) Paused() []TopicPartition {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.all {
+		return sorted(p.assigned)
+	}
+
+	paused := make(map[TopicPartition]bool)
+	for part := range p.paused {
+		paused[part] = p.assigned[part]
+	}
+	return sorted(paused)
+}
+
+func (p *partitions) Partitions() []TopicPartition {
+	p.Lock()
+	defer p.Unlock()
+	return sorted(p.assigned)
+}
+
+// wait blocks while the partition is paused. It returns false if the
+// context is done, such as when the session ends for a rebalance.
+func (p *partitions) wait(ctx context.Context, part TopicPartition) bool {
+	for {
+		p.Lock()
+		paused := p.all || p.paused[part]
+		resumed := p.resumed
+		p.Unlock()
+
+		if !paused {
+			return true
+		}
+
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package kafka
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+
+	"github.com/Shopify/sarama"
+	"github.com/xdg/scram"
+)
+
+var (
+	// SHA256 is the hash of SCRAM-SHA-256 authentication
+	SHA256 scram.HashGeneratorFcn = sha256.New
+	// SHA512 is the hash of SCRAM-SHA-512 authentication
+	SHA512 scram.HashGeneratorFcn = sha512.New
+)
+
+// SCRAMClient is the sarama.SCRAMClient of SCRAM authentication, sarama
+// doesn't have one
+type SCRAMClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+// Begin starts the conversation with the user name and password
+func (x *SCRAMClient) Begin(userName, password, authzID string) error {
+	c, err := x.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	x.Client = c
+	x.ClientConversation = c.NewConversation()
+	return nil
+}
+
+// Step returns the response to the challenge of the server
+func (x *SCRAMClient) Step(challenge string) (string, error) {
+	return x.ClientConversation.Step(challenge)
+}
+
+// Done returns whether the conversation is over
+func (x *SCRAMClient) Done() bool {
+	return x.ClientConversation.Done()
+}
+
+// SCRAMClientGenerator returns the Net.SASL.SCRAMClientGeneratorFunc of the
+// sarama config for SCRAM-SHA-256 or SCRAM-SHA-512 authentication, it's set by
+// the SASL options
+func SCRAMClientGenerator(m sarama.SASLMechanism) func() sarama.SCRAMClient {
+	h := SHA512
+	if m == sarama.SASLTypeSCRAMSHA256 {
+		h = SHA256
+	}
+	return func() sarama.SCRAMClient {
+		return &SCRAMClient{HashGeneratorFcn: h}
+	}
+}
@@ -0,0 +1,67 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// tlsConfig returns the TLS config of the broker options, it's nil if none of
+// them enable TLS
+func (k *kBroker) tlsConfig() (*tls.Config, error) {
+	ctx := k.opts.Context
+
+	config, _ := ctx.Value(tlsConfigKey{}).(*tls.Config)
+	if config == nil {
+		config = k.opts.TLSConfig
+	}
+
+	certFile, hasCert := ctx.Value(tlsCertFileKey{}).(tlsCertFile)
+	caFile, hasCA := ctx.Value(tlsCAFileKey{}).(string)
+	insecure, _ := ctx.Value(tlsInsecureSkipVerifyKey{}).(bool)
+	serverName, hasServerName := ctx.Value(tlsServerNameKey{}).(string)
+
+	if !hasCert && !hasCA && !insecure && !hasServerName {
+		if config == nil && k.opts.Secure {
+			return &tls.Config{}, nil
+		}
+		return config, nil
+	}
+
+	// the options change a copy so the config passed in isn't
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+
+	if hasCert {
+		cert, err := tls.LoadX509KeyPair(certFile.cert, certFile.key)
+		if err != nil {
+			return nil, fmt.Errorf("kafka: loading the TLS certificate: %v", err)
+		}
+		config.Certificates = append(config.Certificates, cert)
+	}
+
+	if hasCA {
+		b, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("kafka: reading the TLS CA file: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("kafka: no certificates in the TLS CA file %s", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if insecure {
+		config.InsecureSkipVerify = true
+	}
+	if hasServerName {
+		config.ServerName = serverName
+	}
+
+	return config, nil
+}
@@ -0,0 +1,133 @@
+package kafka
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/micro/go-micro/v2/broker"
+	log "github.com/micro/go-micro/v2/logger"
+)
+
+var (
+	// DefaultTopicRefresh is how often topics matching a regex subscription are looked up
+	DefaultTopicRefresh = time.Minute
+)
+
+// topics are the topics consumed by a subscriber
+type topics struct {
+	names   []string
+	re      *regexp.Regexp
+	refresh time.Duration
+}
+
+// newTopics returns the topics of a subscription to the topic with the options
+func newTopics(topic string, opts broker.SubscribeOptions) (*topics, error) {
+	t := &topics{
+		refresh: DefaultTopicRefresh,
+	}
+
+	var regex bool
+	if opts.Context != nil {
+		if v, ok := opts.Context.Value(subscribeTopicsKey{}).([]string); ok {
+			t.names = append(t.names, v...)
+		}
+		if v, ok := opts.Context.Value(subscribeRegexKey{}).(bool); ok {
+			regex = v
+		}
+		if v, ok := opts.Context.Value(topicRefreshKey{}).(time.Duration); ok && v > 0 {
+			t.refresh = v
+		}
+	}
+
+	if !regex {
+		t.names = append([]string{topic}, t.names...)
+		return t, nil
+	}
+
+	// the whole topic name must match, as with the java client
+	re, err := regexp.Compile("^(?:" + topic + ")$")
+	if err != nil {
+		return nil, err
+	}
+	t.re = re
+
+	return t, nil
+}
+
+// list returns the topics to consume, looking up those matching the regex
+func (t *topics) list(c sarama.Client) ([]string, error) {
+	if t.re == nil {
+		return t.names, nil
+	}
+
+	if err := c.RefreshMetadata(); err != nil {
+		return nil, err
+	}
+	all, err := c.Topics()
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]bool)
+	for _, name := range t.names {
+		set[name] = true
+	}
+	for _, name := range all {
+		// skip internal topics such as __consumer_offsets
+		if strings.HasPrefix(name, "__") {
+			continue
+		}
+		if t.re.MatchString(name) {
+			set[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// watch calls cancel when the topics matching the regex change from the
+// current topics, so they're consumed in a new session. It returns when
+// the context is done.
+func (t *topics) watch(ctx context.Context, c sarama.Client, current []string, cancel func()) {
+	if t.re == nil {
+		return
+	}
+
+	tick := time.NewTicker(t.refresh)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+
+		names, err := t.list(c)
+		if err != nil {
+			if log.V(log.ErrorLevel, log.DefaultLogger) {
+				log.Errorf("[kafka]: failed to look up topics: %v", err)
+			}
+			continue
+		}
+
+		if !reflect.DeepEqual(names, current) {
+			if log.V(log.InfoLevel, log.DefaultLogger) {
+				log.Infof("[kafka]: consuming topics %v", names)
+			}
+			cancel()
+			return
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package kafka
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/micro/go-micro/v2/broker"
+)
+
+var (
+	// DefaultTransactionTimeout is how long a transaction may be in progress
+	// before the coordinator aborts it
+	DefaultTransactionTimeout = time.Minute
+
+	// ErrNoTransactionalID is returned by BeginTransaction without the TransactionalID option
+	ErrNoTransactionalID = errors.New("kafka: transactions require a transactional id")
+	// ErrTransactionInProgress is returned by BeginTransaction until the transaction
+	// in progress is committed or aborted, the transactional id allows only one
+	ErrTransactionInProgress = errors.New("kafka: transaction already in progress")
+	// ErrTransactionDone is returned by a transaction once it's committed or aborted
+	ErrTransactionDone = errors.New("kafka: transaction already committed or aborted")
+	// ErrTransactionAborted is returned by Commit if a publish of the transaction
+	// failed, it's aborted instead so none of its messages are written
+	ErrTransactionAborted = errors.New("kafka: transaction aborted after a failed publish")
+)
+
+// Transaction groups publishes to any topics which are written atomically,
+// consumers reading committed messages see all of them once it's committed and
+// none if it's aborted
+type Transaction interface {
+	Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error
+	Commit() error
+	Abort() error
+}
+
+// Transactional is implemented by the broker returned by NewBroker
+type Transactional interface {
+	// BeginTransaction starts a transaction, the broker must be connected
+	// and have the TransactionalID option
+	BeginTransaction() (Transaction, error)
+}
+
+// txnProducer is the transactional producer of the broker, it's started by the
+// first transaction and again after one fails with a fatal error such as
+// sarama.ErrProducerFenced
+type txnProducer struct {
+	sync.Mutex
+	p      sarama.SyncProducer
+	active bool
+}
+
+type transaction struct {
+	p *txnProducer
+	k *kBroker
+
+	failed bool
+	done   bool
+}
+
+// getTransactionConfig returns the config of the transactional producer, a copy
+// of the broker config with the settings transactions require
+func (k *kBroker) getTransactionConfig(id string) (*sarama.Config, error) {
+	c, err := k.getBrokerConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, ok := k.opts.Context.Value(transactionTimeoutKey{}).(time.Duration)
+	if !ok {
+		timeout = DefaultTransactionTimeout
+	}
+
+	c.Producer.Transaction.ID = id
+	c.Producer.Transaction.Timeout = timeout
+	c.Producer.Idempotent = true
+	c.Producer.RequiredAcks = sarama.WaitForAll
+	c.Producer.Return.Successes = true
+	c.Producer.Return.Errors = true
+	c.Net.MaxOpenRequests = 1
+	if !c.Version.IsAtLeast(sarama.V0_11_0_0) {
+		c.Version = sarama.V0_11_0_0
+	}
+	return c, nil
+}
+
+// fatal reports if the producer can't be used again
+func (p *txnProducer) fatal() bool {
+	return p.p.TxnStatus()&sarama.ProducerTxnFlagFatalError != 0
+}
+
+// reset closes the producer so the next transaction starts a new one, which
+// aborts the unfinished transactions of the transactional id
+func (p *txnProducer) reset() {
+	if p.p != nil {
+		p.p.Close()
+		p.p = nil
+	}
+}
+
+// BeginTransaction starts a transaction of the transactional producer
+func (k *kBroker) BeginTransaction() (Transaction, error) {
+	id, _ := k.opts.Context.Value(transactionalIDKey{}).(string)
+	if len(id) == 0 {
+		return nil, ErrNoTransactionalID
+	}
+
+	k.scMutex.Lock()
+	if !k.connected {
+		k.scMutex.Unlock()
+		return nil, errors.New("kafka: broker not connected")
+	}
+	if k.txn == nil {
+		k.txn = &txnProducer{}
+	}
+	p := k.txn
+	k.scMutex.Unlock()
+
+	p.Lock()
+	defer p.Unlock()
+
+	if p.active {
+		return nil, ErrTransactionInProgress
+	}
+	if p.p == nil {
+		c, err := k.getTransactionConfig(id)
+		if err != nil {
+			return nil, err
+		}
+		sp, err := sarama.NewSyncProducer(k.addrs, c)
+		if err != nil {
+			return nil, err
+		}
+		p.p = sp
+	}
+
+	if err := p.p.BeginTxn(); err != nil {
+		if p.fatal() {
+			p.reset()
+		}
+		return nil, err
+	}
+	p.active = true
+
+	return &transaction{p: p, k: k}, nil
+}
+
+// Publish writes the message as part of the transaction, it's only read by
+// consumers of committed messages once the transaction is committed
+func (t *transaction) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	p := t.p
+	p.Lock()
+	defer p.Unlock()
+
+	if t.done {
+		return ErrTransactionDone
+	}
+
+	m, err := t.k.producerMessage(topic, msg, opts...)
+	if err == nil {
+		_, _, err = p.p.SendMessage(m)
+	}
+	if err != nil {
+		t.failed = true
+	}
+	return err
+}
+
+// end commits or aborts the transaction, it's aborted if a publish failed
+func (t *transaction) end(commit bool) error {
+	p := t.p
+	p.Lock()
+	defer p.Unlock()
+
+	if t.done {
+		return ErrTransactionDone
+	}
+	t.done = true
+	p.active = false
+
+	var err error
+	if commit && !t.failed {
+		if err = p.p.CommitTxn(); err == nil {
+			return nil
+		}
+	}
+
+	// a failed commit, or one of errors such as sarama.ErrOutOfOrderSequenceNumber,
+	// leave the transaction to be aborted. After a fatal error the producer is
+	// closed instead and the next one aborts it.
+	if !p.fatal() {
+		if aerr := p.p.AbortTxn(); aerr != nil && err == nil {
+			err = aerr
+		}
+	}
+	if p.fatal() {
+		p.reset()
+	}
+
+	if err != nil {
+		return err
+	}
+	if commit {
+		return ErrTransactionAborted
+	}
+	return nil
+}
+
+// Commit writes the messages of the transaction atomically
+func (t *transaction) Commit() error {
+	return t.end(true)
+}
+
+// Abort discards the messages of the transaction
+func (t *transaction) Abort() error {
+	return t.end(false)
+}
@@ -14,6 +14,7 @@ package mqtt
 */
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -114,8 +115,28 @@ func setAddrs(addrs []string) []string {
 func newClient(addrs []string, opts broker.Options) mqtt.Client {
 	// create opts
 	cOpts := mqtt.NewClientOptions()
-	cOpts.SetClientID(fmt.Sprintf("%d%d", time.Now().UnixNano(), rand.Intn(10)))
-	cOpts.SetCleanSession(false)
+
+	// a persistent session is only resumed with the same client id
+	if id, ok := opts.Context.Value(clientIDKey{}).(string); ok && len(id) > 0 {
+		cOpts.SetClientID(id)
+		cOpts.SetCleanSession(false)
+	} else {
+		cOpts.SetClientID(fmt.Sprintf("%d%d", time.Now().UnixNano(), rand.Intn(10)))
+		cOpts.SetCleanSession(true)
+	}
+
+	if clean, ok := opts.Context.Value(cleanSessionKey{}).(bool); ok {
+		cOpts.SetCleanSession(clean)
+	}
+
+	// setup last will
+	if w, ok := opts.Context.Value(willKey{}).(*will); ok {
+		if b, err := opts.Codec.Marshal(w.msg); err != nil {
+			log.Errorf("Error encoding will message: %v", err)
+		} else {
+			cOpts.SetBinaryWill(w.topic, b, w.qos, w.retained)
+		}
+	}
 
 	// setup tls
 	if opts.TLSConfig != nil {
@@ -133,7 +154,8 @@ func newClient(addrs []string, opts broker.Options) mqtt.Client {
 func newBroker(opts ...broker.Option) broker.Broker {
 	options := broker.Options{
 		// Default codec
-		Codec: json.Marshaler{},
+		Codec:   json.Marshaler{},
+		Context: context.Background(),
 	}
 
 	for _, o := range opts {
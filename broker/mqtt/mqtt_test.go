@@ -87,3 +87,48 @@ func TestMQTT(t *testing.T) {
 
 	b.(*mqttBroker).client.Disconnect(0)
 }
+
+func TestMQTTSession(t *testing.T) {
+	b := NewBroker()
+
+	r := b.(*mqttBroker).client.OptionsReader()
+	if !r.CleanSession() {
+		t.Fatal("Expected clean session without a client id")
+	}
+	if r.WillEnabled() {
+		t.Fatal("Expected no will by default")
+	}
+
+	if err := b.Init(
+		ClientID("device-1"),
+		Will("devices/offline", &broker.Message{Body: []byte(`device-1`)}, 1, true),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	r = b.(*mqttBroker).client.OptionsReader()
+	if r.ClientID() != "device-1" {
+		t.Fatalf("Expected client id device-1 got %s", r.ClientID())
+	}
+	if r.CleanSession() {
+		t.Fatal("Expected persistent session with a client id")
+	}
+	if !r.WillEnabled() || r.WillTopic() != "devices/offline" || r.WillQos() != 1 || !r.WillRetained() {
+		t.Fatalf("Unexpected will topic %s qos %d retained %v", r.WillTopic(), r.WillQos(), r.WillRetained())
+	}
+
+	var msg broker.Message
+	if err := b.Options().Codec.Unmarshal(r.WillPayload(), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Body) != "device-1" {
+		t.Fatalf("Expected will body device-1 got %s", string(msg.Body))
+	}
+
+	if err := b.Init(CleanSession(true)); err != nil {
+		t.Fatal(err)
+	}
+	if r := b.(*mqttBroker).client.OptionsReader(); !r.CleanSession() {
+		t.Fatal("Expected clean session to be set")
+	}
+}
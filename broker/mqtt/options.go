@@ -0,0 +1,61 @@
+package mqtt
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/broker"
+)
+
+type clientIDKey struct{}
+
+type cleanSessionKey struct{}
+
+type willKey struct{}
+
+// will is the last will and testament of the client
+type will struct {
+	topic    string
+	msg      *broker.Message
+	qos      byte
+	retained bool
+}
+
+// ClientID sets the client id of the connection. Set a stable id per instance,
+// the broker only resumes a persistent session for the same client id.
+func ClientID(id string) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, clientIDKey{}, id)
+	}
+}
+
+// CleanSession sets whether the broker discards the session on disconnect. It
+// defaults to false when a client id is set so subscriptions and messages
+// published to them are kept while the client is disconnected, and to true otherwise.
+func CleanSession(clean bool) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, cleanSessionKey{}, clean)
+	}
+}
+
+// Will sets the message the broker publishes to the topic when the client
+// disconnects without calling Disconnect e.g on network failure. The message
+// is encoded with the broker codec like any other message.
+func Will(topic string, msg *broker.Message, qos byte, retained bool) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, willKey{}, &will{
+			topic:    topic,
+			msg:      msg,
+			qos:      qos,
+			retained: retained,
+		})
+	}
+}
@@ -2,4 +2,9 @@ module github.com/micro/go-plugins/broker/nats/v2
 
 go 1.13
 
-require github.com/micro/go-micro/v2 v2.9.1
+require (
+	github.com/micro/go-micro/v2 v2.9.1
+	github.com/micro/go-plugins/broker/tests/v2 v2.9.1
+)
+
+replace github.com/micro/go-plugins/broker/tests/v2 => ../tests
@@ -0,0 +1,135 @@
+package nsq
+
+import (
+	"errors"
+
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/nsqio/go-nsq"
+)
+
+// AddrUpdater is implemented by the nsq broker, assert the broker to it to
+// replace the nsqds while it's connected e.g when they're autoscaled
+type AddrUpdater interface {
+	// UpdateAddrs replaces the nsqd addresses. Producers are created for the new
+	// nsqds and those of the removed ones are stopped, so publishes in progress on
+	// them may fail. Subscribers connected to the nsqds rather than lookupd
+	// follow the addresses.
+	UpdateAddrs(addrs []string) error
+}
+
+func (n *nsqBroker) UpdateAddrs(addrs []string) error {
+	seen := make(map[string]bool, len(addrs))
+	var update []string
+	for _, addr := range addrs {
+		if len(addr) > 0 && !seen[addr] {
+			seen[addr] = true
+			update = append(update, addr)
+		}
+	}
+	if len(update) == 0 {
+		return errors.New("no nsqd addresses")
+	}
+
+	// updates are serialised, the producers of the new nsqds are created and
+	// pinged without the broker lock so publishes aren't held up meanwhile
+	n.updating.Lock()
+	defer n.updating.Unlock()
+
+	n.Lock()
+	if !n.running {
+		n.addrs = update
+		n.Unlock()
+		return nil
+	}
+	current := make(map[string]bool, len(n.p))
+	for _, p := range n.p {
+		current[p.addr] = true
+	}
+	n.Unlock()
+
+	var added []*producer
+	for _, addr := range update {
+		if current[addr] {
+			continue
+		}
+		p, err := n.newProducer(addr)
+		if err != nil {
+			for _, p := range added {
+				p.Stop()
+			}
+			return err
+		}
+		added = append(added, p)
+	}
+
+	// nsqds which aren't up yet are pinged until they are
+	for _, p := range added {
+		if err := p.Ping(); err != nil {
+			n.setState(p, err)
+			continue
+		}
+		n.emit(&Event{Type: EventConnect, Addr: p.addr})
+	}
+
+	n.Lock()
+	defer n.Unlock()
+
+	n.addrs = update
+
+	// the producers are created again on connecting
+	if !n.running {
+		for _, p := range added {
+			p.Stop()
+		}
+		return nil
+	}
+
+	// the producers may have been replaced by reconnecting meanwhile
+	removed := make(map[string]*producer, len(n.p))
+	for _, p := range n.p {
+		removed[p.addr] = p
+	}
+	var connect []*producer
+	for _, p := range added {
+		if _, ok := removed[p.addr]; ok {
+			p.Stop()
+			continue
+		}
+		removed[p.addr] = p
+		connect = append(connect, p)
+	}
+
+	producers := make([]*producer, 0, len(update))
+	for _, addr := range update {
+		producers = append(producers, removed[addr])
+		delete(removed, addr)
+	}
+	n.p = producers
+
+	// the producers left are of the removed nsqds
+	for _, p := range removed {
+		p.Stop()
+	}
+
+	// subscribers polling lookupd find the nsqds themselves
+	if len(n.lookupdAddrs) > 0 {
+		return nil
+	}
+	for _, s := range n.c {
+		if s.c == nil {
+			continue
+		}
+		for _, p := range connect {
+			if err := s.c.ConnectToNSQD(p.addr); err != nil && err != nsq.ErrAlreadyConnected {
+				if log.V(log.ErrorLevel, log.DefaultLogger) {
+					log.Errorf("Error connecting to nsqd %s: %v", p.addr, authError(p.addr, err))
+				}
+			}
+		}
+		for addr := range removed {
+			s.c.DisconnectFromNSQD(addr)
+		}
+	}
+
+	return nil
+}
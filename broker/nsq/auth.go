@@ -0,0 +1,45 @@
+package nsq
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nsqio/go-nsq"
+)
+
+// AuthError is returned by Connect, Subscribe and Publish when nsqd requires
+// an auth secret and it's missing, rejected or lacks the permissions needed
+type AuthError struct {
+	// Addr of the nsqd
+	Addr string
+	Err  error
+}
+
+func (e *AuthError) Error() string {
+	return fmt.Sprintf("nsqd %s auth failed: %v", e.Addr, e.Err)
+}
+
+// Unwrap returns the go-nsq error
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// authError returns the error as an AuthError if nsqd rejected the connection or
+// command for its auth, go-nsq only reports it in the error message
+func authError(addr string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	if e, ok := err.(nsq.ErrProtocol); ok {
+		msg = e.Reason
+	}
+
+	for _, s := range []string{"Auth Required", "Error authenticating", "E_AUTH_", "E_UNAUTHORIZED"} {
+		if strings.Contains(msg, s) {
+			return &AuthError{Addr: addr, Err: err}
+		}
+	}
+	return err
+}
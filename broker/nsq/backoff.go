@@ -0,0 +1,45 @@
+package nsq
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	nsq "github.com/nsqio/go-nsq"
+)
+
+// fullJitter backs off for a random duration up to the exponential backoff,
+// so consumers failing at once don't resume at once
+type fullJitter struct {
+	multiplier time.Duration
+	max        time.Duration
+
+	sync.Mutex
+	rng *rand.Rand
+}
+
+// NewFullJitterStrategy returns a backoff strategy backing off for a random duration
+// between zero and multiplier * 2 ^ attempt, up to the max. Unlike the full jitter
+// strategy of go-nsq it's capped before the jitter so long failures keep it spread.
+func NewFullJitterStrategy(multiplier, max time.Duration) nsq.BackoffStrategy {
+	return &fullJitter{
+		multiplier: multiplier,
+		max:        max,
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (f *fullJitter) Calculate(attempt int) time.Duration {
+	d := float64(f.multiplier) * math.Pow(2, float64(attempt))
+	if f.max > 0 && d > float64(f.max) {
+		d = float64(f.max)
+	}
+	if d < 1 {
+		return 0
+	}
+
+	f.Lock()
+	defer f.Unlock()
+	return time.Duration(f.rng.Int63n(int64(d)))
+}
@@ -0,0 +1,106 @@
+package nsq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/codec"
+)
+
+// Envelope is how messages are encoded in the body of nsq messages
+type Envelope int
+
+const (
+	// EnvelopeCodec marshals the message and its header with the broker codec, the default
+	EnvelopeCodec Envelope = iota
+	// EnvelopeHeader prefixes the body with the header, other consumers skip
+	// the prefix to read the body. Messages without one are read as the body.
+	EnvelopeHeader
+	// EnvelopeBody publishes the body only, the header is dropped
+	EnvelopeBody
+)
+
+var (
+	// headerMagic starts the header prefix of EnvelopeHeader messages. It's
+	// followed by the length of the url encoded header as a big endian uint16.
+	headerMagic = []byte("\x00MH")
+
+	maxHeaderLength = 1<<16 - 1
+)
+
+func (e Envelope) String() string {
+	switch e {
+	case EnvelopeCodec:
+		return "codec"
+	case EnvelopeHeader:
+		return "header"
+	case EnvelopeBody:
+		return "body"
+	default:
+		return "unknown"
+	}
+}
+
+// encode returns the nsq message body of the message
+func (e Envelope) encode(c codec.Marshaler, m *broker.Message) ([]byte, error) {
+	switch e {
+	case EnvelopeHeader:
+		v := make(url.Values, len(m.Header))
+		for k, val := range m.Header {
+			v.Set(k, val)
+		}
+		h := v.Encode()
+		if len(h) > maxHeaderLength {
+			return nil, fmt.Errorf("header of %d bytes exceeds the %d of the envelope", len(h), maxHeaderLength)
+		}
+
+		var buf bytes.Buffer
+		buf.Grow(len(headerMagic) + 2 + len(h) + len(m.Body))
+		buf.Write(headerMagic)
+		binary.Write(&buf, binary.BigEndian, uint16(len(h)))
+		buf.WriteString(h)
+		buf.Write(m.Body)
+		return buf.Bytes(), nil
+	case EnvelopeBody:
+		return m.Body, nil
+	default:
+		return c.Marshal(m)
+	}
+}
+
+// decode reads the message from the nsq message body
+func (e Envelope) decode(c codec.Marshaler, b []byte, m *broker.Message) error {
+	switch e {
+	case EnvelopeHeader:
+		m.Header = make(map[string]string)
+		m.Body = b
+
+		n := len(headerMagic)
+		if len(b) < n+2 || !bytes.HasPrefix(b, headerMagic) {
+			return nil
+		}
+		size := int(binary.BigEndian.Uint16(b[n:]))
+		if len(b) < n+2+size {
+			return nil
+		}
+
+		v, err := url.ParseQuery(string(b[n+2 : n+2+size]))
+		if err != nil {
+			return err
+		}
+		for k := range v {
+			m.Header[k] = v.Get(k)
+		}
+		m.Body = b[n+2+size:]
+		return nil
+	case EnvelopeBody:
+		m.Header = make(map[string]string)
+		m.Body = b
+		return nil
+	default:
+		return c.Unmarshal(b, m)
+	}
+}
@@ -0,0 +1,190 @@
+package nsq
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nsqio/go-nsq"
+)
+
+// EventType is the type of a broker event
+type EventType int
+
+const (
+	// EventConnect is emitted when a producer or consumer first connects
+	EventConnect EventType = iota
+	// EventDisconnect is emitted when a producer or consumer loses a connection
+	EventDisconnect
+	// EventReconnect is emitted when a producer or consumer connects again after a disconnect
+	EventReconnect
+	// EventError is emitted when handling a message fails
+	EventError
+	// EventLookupdError is emitted when querying lookupd fails
+	EventLookupdError
+)
+
+var (
+	// DefaultEventInterval consumer connections are checked at
+	DefaultEventInterval = time.Second
+	// DefaultHealthInterval producers which are down are pinged at
+	DefaultHealthInterval = 5 * time.Second
+)
+
+// Event is a change of connection state or an error of the broker
+type Event struct {
+	Type EventType
+	// Topic of the consumer, or empty for producers
+	Topic string
+	// Channel of the consumer
+	Channel string
+	// Addr of the nsqd or lookupd, it's empty for consumer connections
+	// as go-nsq only reports how many there are
+	Addr  string
+	Error error
+}
+
+// EventHandler is called with the events of the broker. It's called from the
+// broker goroutines so it mustn't block.
+type EventHandler func(*Event)
+
+// producer tracks the connection state and stats of a producer from its publish
+// errors, go-nsq doesn't report them otherwise
+type producer struct {
+	// accessed atomically, first so they're aligned on 32 bit platforms
+	published uint64
+	errors    uint64
+
+	*nsq.Producer
+	addr string
+
+	sync.Mutex
+	down bool
+}
+
+func (e EventType) String() string {
+	switch e {
+	case EventConnect:
+		return "connect"
+	case EventDisconnect:
+		return "disconnect"
+	case EventReconnect:
+		return "reconnect"
+	case EventError:
+		return "error"
+	case EventLookupdError:
+		return "lookupd error"
+	default:
+		return "unknown"
+	}
+}
+
+// emit calls the event handler if there is one
+func (n *nsqBroker) emit(e *Event) {
+	if n.events != nil {
+		n.events(e)
+	}
+}
+
+// published records the result of a publish and emits a change of the producer state
+func (n *nsqBroker) published(p *producer, err error) {
+	if err != nil {
+		atomic.AddUint64(&p.errors, 1)
+	} else {
+		atomic.AddUint64(&p.published, 1)
+	}
+
+	n.setState(p, err)
+}
+
+// isDown returns true if the last publish or ping of the producer failed
+func (p *producer) isDown() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.down
+}
+
+// setState records whether the producer is down after a command and emits
+// a change of its state
+func (n *nsqBroker) setState(p *producer, err error) {
+	// protocol errors are returned by nsqd so it's still connected
+	if _, ok := err.(nsq.ErrProtocol); ok {
+		return
+	}
+
+	p.Lock()
+	down := p.down
+	p.down = err != nil
+	p.Unlock()
+
+	switch {
+	case err != nil && !down:
+		n.emit(&Event{Type: EventDisconnect, Addr: p.addr, Error: err})
+	case err == nil && down:
+		n.emit(&Event{Type: EventReconnect, Addr: p.addr})
+	}
+}
+
+// check pings the producers which are down at the interval until exit is
+// closed, so they're selected again once nsqd is back
+func (n *nsqBroker) check(exit chan bool) {
+	t := time.NewTicker(DefaultHealthInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-exit:
+			return
+		case <-t.C:
+			n.Lock()
+			producers := n.p
+			n.Unlock()
+
+			for _, p := range producers {
+				if !p.isDown() {
+					continue
+				}
+				// stopped by disconnecting or updating the addrs while pinging the others
+				if err := p.Ping(); err != nsq.ErrStopped {
+					n.setState(p, err)
+				}
+			}
+		}
+	}
+}
+
+// monitor emits the changes of the consumer connections until done is closed
+func (n *nsqBroker) monitor(c *nsq.Consumer, topic, channel string, done chan bool) {
+	var conns int
+	var lost bool
+
+	check := func() {
+		cur := c.Stats().Connections
+
+		switch {
+		case cur > conns && lost:
+			n.emit(&Event{Type: EventReconnect, Topic: topic, Channel: channel})
+		case cur > conns:
+			n.emit(&Event{Type: EventConnect, Topic: topic, Channel: channel})
+		case cur < conns:
+			lost = true
+			n.emit(&Event{Type: EventDisconnect, Topic: topic, Channel: channel})
+		}
+
+		conns = cur
+	}
+
+	check()
+
+	t := time.NewTicker(DefaultEventInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+			check()
+		}
+	}
+}
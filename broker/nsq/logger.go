@@ -0,0 +1,71 @@
+package nsq
+
+import (
+	"strings"
+
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/nsqio/go-nsq"
+)
+
+var (
+	// DefaultLogLevels map the go-nsq log levels to logger levels
+	DefaultLogLevels = map[nsq.LogLevel]log.Level{
+		nsq.LogLevelDebug:   log.DebugLevel,
+		nsq.LogLevelInfo:    log.InfoLevel,
+		nsq.LogLevelWarning: log.WarnLevel,
+		nsq.LogLevelError:   log.ErrorLevel,
+	}
+)
+
+// nsqLogger writes the go-nsq log lines to a logger instead of stderr
+type nsqLogger struct {
+	// the default logger when nil, which may be replaced after the broker is created
+	logger log.Logger
+	levels map[nsq.LogLevel]log.Level
+}
+
+// newLogger returns the logger of the producers and consumers
+func (n *nsqBroker) newLogger() *nsqLogger {
+	levels := make(map[nsq.LogLevel]log.Level, len(DefaultLogLevels))
+	for k, v := range DefaultLogLevels {
+		levels[k] = v
+	}
+	for k, v := range n.logLevels {
+		levels[k] = v
+	}
+
+	return &nsqLogger{
+		logger: n.logger,
+		levels: levels,
+	}
+}
+
+// level returns the go-nsq level a log line starts with and the rest of the line
+func level(s string) (nsq.LogLevel, string) {
+	for _, lvl := range []nsq.LogLevel{nsq.LogLevelDebug, nsq.LogLevelInfo, nsq.LogLevelWarning, nsq.LogLevelError} {
+		if strings.HasPrefix(s, lvl.String()) {
+			return lvl, strings.TrimSpace(strings.TrimPrefix(s, lvl.String()))
+		}
+	}
+	return nsq.LogLevelInfo, s
+}
+
+// Output is called by go-nsq with each log line
+func (l *nsqLogger) Output(calldepth int, s string) error {
+	logger := l.logger
+	if logger == nil {
+		logger = log.DefaultLogger
+	}
+
+	lvl, msg := level(s)
+
+	ll, ok := l.levels[lvl]
+	if !ok {
+		ll = log.InfoLevel
+	}
+
+	if log.V(ll, logger) {
+		logger.Log(ll, msg)
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package nsq
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/nsqio/go-nsq"
+)
+
+var (
+	// DefaultLookupdTimeout of requests to lookupd, the same as go-nsq
+	DefaultLookupdTimeout = 2 * time.Second
+)
+
+type lookupResp struct {
+	Producers []*peerInfo `json:"producers"`
+}
+
+type peerInfo struct {
+	BroadcastAddress string `json:"broadcast_address"`
+	TCPPort          int    `json:"tcp_port"`
+}
+
+// wrappedResp is the lookupd response before version 1.0 negotiation
+type wrappedResp struct {
+	Data interface{} `json:"data"`
+}
+
+// newLookupdClient returns the http client lookupd is queried with
+func newLookupdClient(config *tls.Config, timeout time.Duration, maxConns int) *http.Client {
+	if timeout == 0 {
+		timeout = DefaultLookupdTimeout
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout: timeout,
+			}).DialContext,
+			TLSClientConfig:     config,
+			TLSHandshakeTimeout: timeout,
+			MaxConnsPerHost:     maxConns,
+			MaxIdleConnsPerHost: maxConns,
+		},
+	}
+}
+
+// lookupdURL returns the lookup url of the topic at a lookupd address
+func (n *nsqBroker) lookupdURL(addr, topic string) (string, error) {
+	if !strings.Contains(addr, "://") {
+		scheme := "http://"
+		if n.lookupdTLS {
+			scheme = "https://"
+		}
+		addr = scheme + addr
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return "", err
+	}
+	if u.Path == "/" || u.Path == "" {
+		u.Path = "/lookup"
+	}
+
+	v := u.Query()
+	v.Set("topic", topic)
+	u.RawQuery = v.Encode()
+
+	return u.String(), nil
+}
+
+// nodesURL returns the nodes url of a lookupd address, next to its lookup url
+func (n *nsqBroker) nodesURL(addr string) (string, error) {
+	endpoint, err := n.lookupdURL(addr, "")
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/lookup") + "/nodes"
+	u.RawQuery = ""
+
+	return u.String(), nil
+}
+
+// lookup returns the nsqd addresses of the topic from a lookupd
+func (n *nsqBroker) lookup(addr, topic string) ([]string, error) {
+	endpoint, err := n.lookupdURL(addr, topic)
+	if err != nil {
+		return nil, err
+	}
+	return n.query(endpoint)
+}
+
+// query returns the nsqd addresses of the producers in the response of a lookupd endpoint
+func (n *nsqBroker) query(endpoint string) ([]string, error) {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Accept", "application/vnd.nsq; version=1.0")
+
+	rsp, err := n.lookupdClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// the topic hasn't been created yet
+	if rsp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got response %s %q", rsp.Status, b)
+	}
+
+	var data lookupResp
+	if rsp.Header.Get("X-NSQ-Content-Type") == "nsq; version=1.0" {
+		err = json.Unmarshal(b, &data)
+	} else {
+		err = json.Unmarshal(b, &wrappedResp{Data: &data})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]string, 0, len(data.Producers))
+	for _, p := range data.Producers {
+		addrs = append(addrs, net.JoinHostPort(p.BroadcastAddress, strconv.Itoa(p.TCPPort)))
+	}
+	return addrs, nil
+}
+
+// discover connects the consumer to the nsqds of the topic, trying each lookupd
+// until one answers. The nsqds of connected which are no longer listed, such as
+// ones which left lookupd to be drained, are disconnected.
+func (n *nsqBroker) discover(c *nsq.Consumer, topic string, connected map[string]bool) {
+	for _, addr := range n.lookupdAddrs {
+		addrs, err := n.lookup(addr, topic)
+		if err != nil {
+			if log.V(log.ErrorLevel, log.DefaultLogger) {
+				log.Errorf("Error querying nsqlookupd %s: %v", addr, err)
+			}
+			n.emit(&Event{Type: EventLookupdError, Addr: addr, Error: err})
+			continue
+		}
+
+		listed := make(map[string]bool, len(addrs))
+		for _, a := range addrs {
+			listed[a] = true
+			if err := c.ConnectToNSQD(a); err != nil && err != nsq.ErrAlreadyConnected {
+				if log.V(log.ErrorLevel, log.DefaultLogger) {
+					log.Errorf("Error connecting to nsqd %s: %v", a, authError(a, err))
+				}
+				continue
+			}
+			connected[a] = true
+		}
+
+		for a := range connected {
+			if listed[a] {
+				continue
+			}
+			if err := c.DisconnectFromNSQD(a); err != nil && err != nsq.ErrNotConnected {
+				if log.V(log.ErrorLevel, log.DefaultLogger) {
+					log.Errorf("Error disconnecting from nsqd %s: %v", a, err)
+				}
+			}
+			delete(connected, a)
+		}
+		return
+	}
+}
+
+// nodes returns the addresses of the nsqds registered with the first lookupd which answers
+func (n *nsqBroker) nodes() ([]string, error) {
+	var err error
+
+	for _, addr := range n.lookupdAddrs {
+		var endpoint string
+		var addrs []string
+
+		endpoint, err = n.nodesURL(addr)
+		if err == nil {
+			addrs, err = n.query(endpoint)
+		}
+		if err != nil {
+			if log.V(log.ErrorLevel, log.DefaultLogger) {
+				log.Errorf("Error querying nsqlookupd %s: %v", addr, err)
+			}
+			n.emit(&Event{Type: EventLookupdError, Addr: addr, Error: err})
+			continue
+		}
+		return addrs, nil
+	}
+
+	if err == nil {
+		err = errors.New("no lookupd addresses")
+	}
+	return nil, err
+}
+
+// watch updates the nsqd addresses to the nodes of lookupd at the lookupd poll
+// interval until exit is closed
+func (n *nsqBroker) watch(exit chan bool) {
+	t := time.NewTicker(n.config.LookupdPollInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-exit:
+			return
+		case <-t.C:
+			// the addresses are kept while no nsqd is registered
+			addrs, err := n.nodes()
+			if err != nil || len(addrs) == 0 {
+				continue
+			}
+			if err := n.UpdateAddrs(addrs); err != nil {
+				if log.V(log.ErrorLevel, log.DefaultLogger) {
+					log.Errorf("Error updating nsqd addresses: %v", err)
+				}
+			}
+		}
+	}
+}
+
+// poll discovers the nsqds of the topic at the lookupd poll interval until exit is closed
+func (n *nsqBroker) poll(c *nsq.Consumer, topic string, exit chan bool) {
+	connected := make(map[string]bool)
+	n.discover(c, topic, connected)
+
+	t := time.NewTicker(n.config.LookupdPollInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-exit:
+			return
+		case <-t.C:
+			n.discover(c, topic, connected)
+		}
+	}
+}
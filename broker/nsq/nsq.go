@@ -3,7 +3,13 @@ package nsq
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"math/rand"
+	"net/http"
+	"regexp"
+	"strconv"
 	"sync"
 	"time"
 
@@ -11,6 +17,7 @@ import (
 	"github.com/micro/go-micro/v2/broker"
 	"github.com/micro/go-micro/v2/codec/json"
 	"github.com/micro/go-micro/v2/config/cmd"
+	log "github.com/micro/go-micro/v2/logger"
 	"github.com/nsqio/go-nsq"
 )
 
@@ -20,10 +27,74 @@ type nsqBroker struct {
 	opts         broker.Options
 	config       *nsq.Config
 
+	// resubscribe subscribers when reconnecting
+	resubscribe bool
+
+	// client lookupd is queried with instead of go-nsq
+	lookupdClient *http.Client
+	lookupdTLS    bool
+
+	// topics created on connecting, and those of subscribers, if it's set
+	createTopics  []string
+	nsqdClient    *http.Client
+	nsqdHTTPAddrs []string
+
+	// naming of channels for subscriptions without a queue
+	channelPrefix  string
+	channelService string
+	durable        bool
+
+	// called with connection state changes and errors
+	events EventHandler
+	// called with the latency of publishes and handled messages
+	metrics Metrics
+	// selects the nsqd messages are published to
+	selector Selector
+	// other nsqds a failed publish is retried on
+	retries int
+	// encoding of messages in nsq message bodies
+	envelope Envelope
+	// the nsqds are the nodes of lookupd rather than the addrs
+	discovery bool
+
+	// go-nsq logs are written to the logger
+	logger    log.Logger
+	logLevels map[nsq.LogLevel]log.Level
+
+	// serialises UpdateAddrs
+	updating sync.Mutex
+
 	sync.Mutex
 	running bool
-	p       []*nsq.Producer
+	p       []*producer
 	c       []*subscriber
+	// closed to stop pinging the producers which are down
+	exit chan bool
+}
+
+// Metadata is implemented by the events of subscribers, assert the event to it
+// in a handler for the metadata of the nsq message, it's also set in the header
+type Metadata interface {
+	// ID of the nsq message, the same for each delivery of it
+	ID() string
+	// Attempts to deliver the message, including this one
+	Attempts() uint16
+	// Timestamp nsqd got the message at
+	Timestamp() time.Time
+}
+
+// Requeuer is implemented by the events of subscribers, assert the event to it
+// in a handler to redeliver the message after a delay e.g on a transient failure.
+// Requeueing is a noop once the message is acknowledged or requeued.
+type Requeuer interface {
+	Requeue(delay time.Duration)
+	RequeueWithoutBackoff(delay time.Duration)
+}
+
+// BatchPublisher is implemented by the nsq broker, assert the broker to it to
+// publish many messages in one round trip
+type BatchPublisher interface {
+	PublishBatch(topic string, messages []*broker.Message, opts ...broker.PublishOption) error
 }
 
 type publication struct {
@@ -35,10 +106,18 @@ type publication struct {
 }
 
 type subscriber struct {
-	topic string
-	opts  broker.SubscribeOptions
+	topic   string
+	channel string
+	opts    broker.SubscribeOptions
+	config  *nsq.Config
+	b       *nsqBroker
 
+	// nil while disconnected
 	c *nsq.Consumer
+	// stops polling lookupd
+	exit chan bool
+	// stops monitoring the connections
+	done chan bool
 
 	// handler so we can resubcribe
 	h nsq.HandlerFunc
@@ -48,6 +127,27 @@ type subscriber struct {
 
 var (
 	DefaultConcurrentHandlers = 1
+	// DefaultPublishRetries is how many other nsqds a failed publish is retried on
+	DefaultPublishRetries = 1
+
+	// ErrorHeader is the dead-lettered message header set to the last handler error
+	ErrorHeader = "Micro-Dead-Letter-Error"
+	// TopicHeader is the dead-lettered message header set to the topic it was consumed from
+	TopicHeader = "Micro-Dead-Letter-Topic"
+	// AttemptsHeader is the dead-lettered message header set to the attempts made
+	AttemptsHeader = "Micro-Dead-Letter-Attempts"
+
+	// IDHeader is the header of handled messages set to the nsq message id
+	IDHeader = "nsq-id"
+	// DeliveryAttemptsHeader is the header of handled messages set to the delivery attempts
+	DeliveryAttemptsHeader = "nsq-attempts"
+	// TimestampHeader is the header of handled messages set to when nsqd got
+	// the message, in nanoseconds since the unix epoch
+	TimestampHeader = "nsq-timestamp"
+
+	ephemeralSuffix  = "#ephemeral"
+	maxChannelLength = 64
+	invalidChannel   = regexp.MustCompile(`[^.a-zA-Z0-9_-]`)
 )
 
 func init() {
@@ -82,12 +182,110 @@ func (n *nsqBroker) configure(ctx context.Context) {
 		n.lookupdAddrs = v
 	}
 
+	if v, ok := ctx.Value(resubscribeKey{}).(bool); ok {
+		n.resubscribe = v
+	}
+
+	if v, ok := ctx.Value(channelPrefixKey{}).(string); ok {
+		n.channelPrefix = v
+	}
+	if v, ok := ctx.Value(serviceChannelKey{}).(string); ok {
+		n.channelService = v
+	}
+	if v, ok := ctx.Value(durableChannelsKey{}).(bool); ok {
+		n.durable = v
+	}
+	if v, ok := ctx.Value(eventHandlerKey{}).(EventHandler); ok {
+		n.events = v
+	}
+	if v, ok := ctx.Value(selectorKey{}).(Selector); ok {
+		n.selector = v
+	}
+	if v, ok := ctx.Value(envelopeKey{}).(Envelope); ok {
+		n.envelope = v
+	}
+	if v, ok := ctx.Value(publishRetriesKey{}).(int); ok {
+		n.retries = v
+	}
+	if v, ok := ctx.Value(metricsKey{}).(Metrics); ok {
+		n.metrics = v
+	}
+	if v, ok := ctx.Value(loggerKey{}).(log.Logger); ok {
+		n.logger = v
+	}
+	if v, ok := ctx.Value(logLevelsKey{}).(map[nsq.LogLevel]log.Level); ok {
+		n.logLevels = v
+	}
+
+	// lookupd is queried with our own client when it's tuned
+	lookupdTLS, _ := ctx.Value(lookupdTLSConfigKey{}).(*tls.Config)
+	timeout, _ := ctx.Value(lookupdTimeoutKey{}).(time.Duration)
+	maxConns, _ := ctx.Value(lookupdMaxConnsKey{}).(int)
+
+	if v, ok := ctx.Value(lookupdHTTPClientKey{}).(*http.Client); ok {
+		n.lookupdClient = v
+	} else if lookupdTLS != nil || timeout > 0 || maxConns > 0 || n.events != nil {
+		// go-nsq only logs lookupd errors so they're emitted by querying it ourselves
+		n.lookupdClient = newLookupdClient(lookupdTLS, timeout, maxConns)
+	}
+	n.lookupdTLS = lookupdTLS != nil
+
+	if v, ok := ctx.Value(createTopicsKey{}).([]string); ok {
+		n.createTopics = v
+		// the http api of nsqd is served with the tls config of the tcp connections
+		var config *tls.Config
+		if n.opts.Secure || n.opts.TLSConfig != nil {
+			config = n.opts.TLSConfig
+		}
+		n.nsqdClient = newLookupdClient(config, timeout, 0)
+	}
+	if v, ok := ctx.Value(nsqdHTTPAddrsKey{}).([]string); ok {
+		n.nsqdHTTPAddrs = v
+	}
+	if v, ok := ctx.Value(nsqdDiscoveryKey{}).(bool); ok {
+		n.discovery = v
+		// the nodes are only listed by querying lookupd ourselves
+		if n.discovery && n.lookupdClient == nil {
+			n.lookupdClient = newLookupdClient(lookupdTLS, timeout, maxConns)
+		}
+	}
+
 	if v, ok := ctx.Value(consumerOptsKey{}).([]string); ok {
 		cfgFlag := &nsq.ConfigFlag{Config: n.config}
 		for _, opt := range v {
 			cfgFlag.Set(opt)
 		}
 	}
+
+	if v, ok := ctx.Value(authSecretKey{}).(string); ok {
+		n.config.AuthSecret = v
+	}
+
+	// nsqd only compresses connections with one of them
+	if v, ok := ctx.Value(compressionKey{}).(compression); ok {
+		n.config.Snappy = v.snappy
+		n.config.Deflate = !v.snappy
+		n.config.DeflateLevel = v.level
+	}
+
+	// the typed options override the consumer opts
+	if v, ok := ctx.Value(backoffStrategyKey{}).(nsq.BackoffStrategy); ok {
+		n.config.BackoffStrategy = v
+	}
+	if v, ok := ctx.Value(maxBackoffDurationKey{}).(time.Duration); ok {
+		n.config.MaxBackoffDuration = v
+	}
+	if v, ok := ctx.Value(backoffMultiplierKey{}).(time.Duration); ok {
+		n.config.BackoffMultiplier = v
+	}
+
+	// upgrade producer and consumer connections to tls
+	if n.opts.Secure || n.opts.TLSConfig != nil {
+		n.config.TlsV1 = true
+		if n.opts.TLSConfig != nil {
+			n.config.TlsConfig = n.opts.TLSConfig
+		}
+	}
 }
 
 func (n *nsqBroker) Options() broker.Options {
@@ -106,51 +304,73 @@ func (n *nsqBroker) Connect() error {
 		return nil
 	}
 
-	producers := make([]*nsq.Producer, 0, len(n.addrs))
+	// topics exist before they're first published to so consumers find them
+	if n.nsqdClient != nil {
+		for _, topic := range n.createTopics {
+			if err := n.create(topic, ""); err != nil {
+				return err
+			}
+		}
+	}
+
+	// the addrs are only used if no lookupd answers
+	if n.discovery {
+		if addrs, err := n.nodes(); err == nil && len(addrs) > 0 {
+			n.addrs = addrs
+		}
+	}
+
+	producers := make([]*producer, 0, len(n.addrs))
 
 	// create producers
 	for _, addr := range n.addrs {
-		p, err := nsq.NewProducer(addr, n.config)
+		p, err := n.newProducer(addr)
 		if err != nil {
 			return err
 		}
 		if err = p.Ping(); err != nil {
-			return err
+			return authError(addr, err)
 		}
 		producers = append(producers, p)
 	}
 
-	// create consumers
+	for _, p := range producers {
+		n.emit(&Event{Type: EventConnect, Addr: p.addr})
+	}
+
+	// resubscribe the consumers stopped by disconnecting
 	for _, c := range n.c {
-		channel := c.opts.Queue
-		if len(channel) == 0 {
-			channel = uuid.New().String() + "#ephemeral"
+		if c.c != nil {
+			continue
 		}
-
-		cm, err := nsq.NewConsumer(c.topic, channel, n.config)
-		if err != nil {
+		if err := n.connect(c); err != nil {
 			return err
 		}
-
-		cm.AddConcurrentHandlers(c.h, c.n)
-
-		c.c = cm
-
-		if len(n.lookupdAddrs) > 0 {
-			c.c.ConnectToNSQLookupds(n.lookupdAddrs)
-		} else {
-			err = c.c.ConnectToNSQDs(n.addrs)
-			if err != nil {
-				return err
-			}
-		}
 	}
 
 	n.p = producers
+	n.exit = make(chan bool)
 	n.running = true
+
+	go n.check(n.exit)
+	if n.discovery {
+		go n.watch(n.exit)
+	}
+
 	return nil
 }
 
+// newProducer creates the producer of an nsqd
+func (n *nsqBroker) newProducer(addr string) (*producer, error) {
+	p, err := nsq.NewProducer(addr, n.config)
+	if err != nil {
+		return nil, err
+	}
+	// lines are filtered by the logger level
+	p.SetLogger(n.newLogger(), nsq.LogLevelDebug)
+	return &producer{Producer: p, addr: addr}, nil
+}
+
 func (n *nsqBroker) Disconnect() error {
 	n.Lock()
 	defer n.Unlock()
@@ -159,6 +379,11 @@ func (n *nsqBroker) Disconnect() error {
 		return nil
 	}
 
+	if n.exit != nil {
+		close(n.exit)
+		n.exit = nil
+	}
+
 	// stop the producers
 	for _, p := range n.p {
 		p.Stop()
@@ -166,19 +391,12 @@ func (n *nsqBroker) Disconnect() error {
 
 	// stop the consumers
 	for _, c := range n.c {
-		c.c.Stop()
+		n.stop(c)
+	}
 
-		if len(n.lookupdAddrs) > 0 {
-			// disconnect from all lookupd
-			for _, addr := range n.lookupdAddrs {
-				c.c.DisconnectFromNSQLookupd(addr)
-			}
-		} else {
-			// disconnect from all nsq brokers
-			for _, addr := range n.addrs {
-				c.c.DisconnectFromNSQD(addr)
-			}
-		}
+	// subscribers are only kept to resubscribe when reconnecting
+	if !n.resubscribe {
+		n.c = nil
 	}
 
 	n.p = nil
@@ -186,8 +404,191 @@ func (n *nsqBroker) Disconnect() error {
 	return nil
 }
 
+// channel returns the channel name of a subscription without a queue
+func (n *nsqBroker) channel() string {
+	name := n.channelService
+	if len(name) == 0 {
+		name = uuid.New().String()
+	}
+
+	suffix := ephemeralSuffix
+	if n.durable {
+		suffix = ""
+	}
+
+	// replace the characters nsq doesn't allow and keep within the max length
+	name = invalidChannel.ReplaceAllString(n.channelPrefix+name, "_")
+	if limit := maxChannelLength - len(suffix); len(name) > limit {
+		name = name[:limit]
+	}
+
+	return name + suffix
+}
+
+// connect creates the consumer of a subscriber and connects it
+func (n *nsqBroker) connect(s *subscriber) error {
+	c, err := nsq.NewConsumer(s.topic, s.channel, s.config)
+	if err != nil {
+		return err
+	}
+
+	c.SetLogger(n.newLogger(), nsq.LogLevelDebug)
+	c.AddConcurrentHandlers(s.h, s.n)
+
+	if len(n.lookupdAddrs) > 0 && n.lookupdClient != nil {
+		s.exit = make(chan bool)
+		go n.poll(c, s.topic, s.exit)
+	} else if len(n.lookupdAddrs) > 0 {
+		err = c.ConnectToNSQLookupds(n.lookupdAddrs)
+	} else {
+		for _, addr := range n.addrs {
+			if err = c.ConnectToNSQD(addr); err != nil {
+				err = authError(addr, err)
+				break
+			}
+		}
+	}
+	if err != nil {
+		c.Stop()
+		return err
+	}
+
+	if n.events != nil {
+		s.done = make(chan bool)
+		go n.monitor(c, s.topic, s.channel, s.done)
+	}
+
+	s.c = c
+	return nil
+}
+
+// stop stops the consumer of a subscriber and disconnects it
+func (n *nsqBroker) stop(s *subscriber) {
+	if s.c == nil {
+		return
+	}
+
+	s.c.Stop()
+
+	if s.done != nil {
+		close(s.done)
+		s.done = nil
+	}
+
+	if s.exit != nil {
+		// stop polling lookupd, the nsqd connections are closed by stopping
+		close(s.exit)
+		s.exit = nil
+	} else if len(n.lookupdAddrs) > 0 {
+		// disconnect from all lookupd
+		for _, addr := range n.lookupdAddrs {
+			s.c.DisconnectFromNSQLookupd(addr)
+		}
+	} else {
+		// disconnect from all nsq brokers
+		for _, addr := range n.addrs {
+			s.c.DisconnectFromNSQD(addr)
+		}
+	}
+
+	s.c = nil
+}
+
+// producer returns the producer of the nsqd address, or the one selected for the topic
+// out of those not tried yet. Producers which are down are only selected if all are.
+func (n *nsqBroker) producer(producers []*producer, topic, addr string, tried map[string]bool) (*producer, error) {
+	if len(addr) == 0 {
+		var up, down []string
+		for _, p := range producers {
+			switch {
+			case tried[p.addr]:
+			case p.isDown():
+				down = append(down, p.addr)
+			default:
+				up = append(up, p.addr)
+			}
+		}
+		if len(up) == 0 {
+			up = down
+		}
+		if len(up) == 0 {
+			return nil, errors.New("no producer left to publish to")
+		}
+		addr = n.selector(topic, up)
+	}
+
+	for _, p := range producers {
+		if p.addr == addr {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("no producer for nsqd %s", addr)
+}
+
+// retriable returns true if a publish which failed with the error may succeed on
+// another nsqd, those rejecting the message or the secret would do the same
+func retriable(err error) bool {
+	switch err.(type) {
+	case nsq.ErrProtocol, *AuthError:
+		return false
+	}
+	return err != nsq.ErrStopped
+}
+
+// Publish publishes the message to the nsqd chosen by the selector, random by default, skipping those
+// which are down. A failed publish is retried on another nsqd, see WithPublishRetries. A publish context set with
+// broker.PublishContext, before the other publish options, stops waiting when it's
+// done and returns its error. Async publishes only wait until the message is queued.
 func (n *nsqBroker) Publish(topic string, message *broker.Message, opts ...broker.PublishOption) error {
-	p := n.p[rand.Intn(len(n.p))]
+	b, err := n.publishEnvelope(opts).encode(n.opts.Codec, message)
+	if err != nil {
+		return err
+	}
+	return n.publish(topic, [][]byte{b}, opts...)
+}
+
+// PublishBatch publishes the messages to one nsqd in a single MPUB command, they're
+// all written or none are. It takes the same options as Publish except WithDeferredPublish.
+func (n *nsqBroker) PublishBatch(topic string, messages []*broker.Message, opts ...broker.PublishOption) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	e := n.publishEnvelope(opts)
+	bodies := make([][]byte, 0, len(messages))
+	for _, m := range messages {
+		b, err := e.encode(n.opts.Codec, m)
+		if err != nil {
+			return err
+		}
+		bodies = append(bodies, b)
+	}
+	return n.publish(topic, bodies, opts...)
+}
+
+// publishEnvelope returns the envelope of WithPublishEnvelope or the broker's
+func (n *nsqBroker) publishEnvelope(opts []broker.PublishOption) Envelope {
+	var options broker.PublishOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.Context != nil {
+		if v, ok := options.Context.Value(envelopeKey{}).(Envelope); ok {
+			return v
+		}
+	}
+	return n.envelope
+}
+
+// publish publishes the message bodies, more than one is a multi publish
+func (n *nsqBroker) publish(topic string, bodies [][]byte, opts ...broker.PublishOption) error {
+	n.Lock()
+	producers := n.p
+	n.Unlock()
+
+	if len(producers) == 0 {
+		return errors.New("not connected")
+	}
 
 	options := broker.PublishOptions{}
 	for _, o := range opts {
@@ -197,6 +598,7 @@ func (n *nsqBroker) Publish(topic string, message *broker.Message, opts ...broke
 	var (
 		doneChan chan *nsq.ProducerTransaction
 		delay    time.Duration
+		addr     string
 	)
 	if options.Context != nil {
 		if v, ok := options.Context.Value(asyncPublishKey{}).(chan *nsq.ProducerTransaction); ok {
@@ -205,23 +607,84 @@ func (n *nsqBroker) Publish(topic string, message *broker.Message, opts ...broke
 		if v, ok := options.Context.Value(deferredPublishKey{}).(time.Duration); ok {
 			delay = v
 		}
+		if v, ok := options.Context.Value(publishAddrKey{}).(string); ok {
+			addr = v
+		}
+	}
+
+	multi := len(bodies) > 1
+	if multi && delay > 0 {
+		return errors.New("deferred publish of a batch isn't supported by nsq")
 	}
 
-	b, err := n.opts.Codec.Marshal(message)
+	p, err := n.producer(producers, topic, addr, nil)
 	if err != nil {
 		return err
 	}
 
-	if doneChan != nil {
-		if delay > 0 {
-			return p.DeferredPublishAsync(topic, delay, b, doneChan)
+	publishTo := func(p *producer) error {
+		start := time.Now()
+
+		var err error
+		switch {
+		case multi && doneChan != nil:
+			err = p.MultiPublishAsync(topic, bodies, doneChan)
+		case multi:
+			err = p.MultiPublish(topic, bodies)
+		case doneChan != nil && delay > 0:
+			err = p.DeferredPublishAsync(topic, delay, bodies[0], doneChan)
+		case doneChan != nil:
+			err = p.PublishAsync(topic, bodies[0], doneChan)
+		case delay > 0:
+			err = p.DeferredPublish(topic, delay, bodies[0])
+		default:
+			err = p.Publish(topic, bodies[0])
 		}
-		return p.PublishAsync(topic, b, doneChan)
-	} else {
-		if delay > 0 {
-			return p.DeferredPublish(topic, delay, b)
+
+		n.published(p, err)
+		if n.metrics != nil {
+			n.metrics.Published(topic, time.Since(start), err)
 		}
-		return p.Publish(topic, b)
+		return authError(p.addr, err)
+	}
+
+	// failed publishes are retried on the other nsqds unless the address is set
+	publish := func() error {
+		err := publishTo(p)
+
+		tried := map[string]bool{}
+		for i := 0; err != nil && len(addr) == 0 && i < n.retries && retriable(err); i++ {
+			tried[p.addr] = true
+			next, perr := n.producer(producers, topic, "", tried)
+			if perr != nil {
+				break
+			}
+			p = next
+			err = publishTo(p)
+		}
+		return err
+	}
+
+	// without a deadline or cancellation the publish is waited for
+	ctx := options.Context
+	if ctx == nil || ctx.Done() == nil {
+		return publish()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// the publish is abandoned when the context is done, so nsqd may still get the message
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- publish()
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
@@ -235,6 +698,11 @@ func (n *nsqBroker) Subscribe(topic string, handler broker.Handler, opts ...brok
 	}
 
 	concurrency, maxInFlight := DefaultConcurrentHandlers, DefaultConcurrentHandlers
+	var maxAttempts uint16
+	var sampleRate int32
+	var deadLetter, orderingKey string
+	var requeueDelay, touchInterval time.Duration
+	envelope := n.envelope
 	if options.Context != nil {
 		if v, ok := options.Context.Value(concurrentHandlerKey{}).(int); ok {
 			maxInFlight, concurrency = v, v
@@ -242,17 +710,118 @@ func (n *nsqBroker) Subscribe(topic string, handler broker.Handler, opts ...brok
 		if v, ok := options.Context.Value(maxInFlightKey{}).(int); ok {
 			maxInFlight = v
 		}
+		if v, ok := options.Context.Value(maxAttemptsKey{}).(uint16); ok {
+			maxAttempts = v
+		}
+		if v, ok := options.Context.Value(sampleRateKey{}).(int); ok {
+			if v < 0 || v > 99 {
+				return nil, fmt.Errorf("sample rate %d isn't between 0 and 99", v)
+			}
+			sampleRate = int32(v)
+		}
+		if v, ok := options.Context.Value(deadLetterTopicKey{}).(string); ok {
+			deadLetter = v
+		}
+		if v, ok := options.Context.Value(requeueDelayKey{}).(time.Duration); ok {
+			requeueDelay = v
+		}
+		if v, ok := options.Context.Value(touchIntervalKey{}).(time.Duration); ok {
+			touchInterval = v
+		}
+		if v, ok := options.Context.Value(envelopeKey{}).(Envelope); ok {
+			envelope = v
+		}
+		if v, ok := options.Context.Value(orderingKeyKey{}).(string); ok {
+			orderingKey = v
+		}
 	}
 	channel := options.Queue
 	if len(channel) == 0 {
-		channel = uuid.New().String() + "#ephemeral"
+		channel = n.channel()
+	}
+	if n.nsqdClient != nil {
+		if err := n.create(topic, channel); err != nil {
+			return nil, err
+		}
 	}
 	config := *n.config
 	config.MaxInFlight = maxInFlight
+	if sampleRate > 0 {
+		config.SampleRate = sampleRate
+	}
+	if maxAttempts > 0 {
+		config.MaxAttempts = maxAttempts
+	}
+	if len(deadLetter) > 0 {
+		// the attempts are counted by the handler so messages aren't dropped
+		if maxAttempts == 0 {
+			maxAttempts = config.MaxAttempts
+		}
+		config.MaxAttempts = 0
+	}
 
-	c, err := nsq.NewConsumer(topic, channel, &config)
-	if err != nil {
-		return nil, err
+	decode := func(nm *nsq.Message) (*broker.Message, error) {
+		var m broker.Message
+
+		if err := envelope.decode(n.opts.Codec, nm.Body, &m); err != nil {
+			n.emit(&Event{Type: EventError, Topic: topic, Channel: channel, Error: err})
+			return nil, err
+		}
+
+		// the metadata of the nsq message replaces any published in the header
+		if m.Header == nil {
+			m.Header = make(map[string]string, 3)
+		}
+		m.Header[IDHeader] = string(nm.ID[:])
+		m.Header[DeliveryAttemptsHeader] = strconv.Itoa(int(nm.Attempts))
+		m.Header[TimestampHeader] = strconv.FormatInt(nm.Timestamp, 10)
+		return &m, nil
+	}
+
+	handle := func(nm *nsq.Message, m *broker.Message) error {
+		p := &publication{topic: topic, m: m, nm: nm}
+
+		start := time.Now()
+		stop := touch(nm, touchInterval)
+		p.err = handler(p)
+		stop()
+		if n.metrics != nil {
+			n.metrics.Handled(topic, channel, time.Since(start), p.err)
+		}
+
+		if p.err != nil {
+			n.emit(&Event{Type: EventError, Topic: topic, Channel: channel, Error: p.err})
+		}
+
+		if p.err == nil {
+			return nil
+		}
+
+		if len(deadLetter) > 0 && nm.Attempts >= maxAttempts {
+			// the message is requeued to try again if it can't be dead-lettered
+			err := n.deadLetter(deadLetter, p, nm.Attempts)
+			if err == nil {
+				nm.Finish()
+				return nil
+			}
+			if log.V(log.ErrorLevel, log.DefaultLogger) {
+				log.Errorf("Error dead-lettering message from %s: %v", topic, err)
+			}
+		}
+
+		// the handler may have responded itself, its response takes precedence
+		if nm.HasResponded() {
+			return p.err
+		}
+		if requeueDelay > 0 {
+			nm.RequeueWithoutBackoff(requeueDelay)
+		} else if !options.AutoAck {
+			// go-nsq doesn't respond to errors once auto response is disabled,
+			// so the message would only be redelivered after the msg timeout.
+			// Handlers which return nil respond themselves, even later.
+			nm.Requeue(-1)
+		}
+		return p.err
 	}
 
 	h := nsq.HandlerFunc(func(nm *nsq.Message) error {
@@ -260,34 +829,60 @@ func (n *nsqBroker) Subscribe(topic string, handler broker.Handler, opts ...brok
 			nm.DisableAutoResponse()
 		}
 
-		var m broker.Message
-
-		if err := n.opts.Codec.Unmarshal(nm.Body, &m); err != nil {
+		m, err := decode(nm)
+		if err != nil {
 			return err
 		}
-
-		p := &publication{topic: topic, m: &m}
-		p.err = handler(p)
-		return p.err
+		return handle(nm, m)
 	})
 
-	c.AddConcurrentHandlers(h, concurrency)
+	if len(orderingKey) > 0 {
+		o := newOrdered(concurrency)
 
-	if len(n.lookupdAddrs) > 0 {
-		err = c.ConnectToNSQLookupds(n.lookupdAddrs)
-	} else {
-		err = c.ConnectToNSQDs(n.addrs)
-	}
-	if err != nil {
-		return nil, err
+		// one handler receives the messages in order and hands them to the
+		// worker of their key, which responds once the message is handled
+		h = nsq.HandlerFunc(func(nm *nsq.Message) error {
+			m, err := decode(nm)
+			if err != nil {
+				return err
+			}
+			nm.DisableAutoResponse()
+
+			// queued messages are touched so they aren't redelivered out of order
+			queued := touch(nm, touchInterval)
+			o.dispatch(m.Header[orderingKey], func() {
+				queued()
+				err := handle(nm, m)
+				if !options.AutoAck {
+					return
+				}
+				// a noop if the handler already responded
+				if err != nil {
+					nm.Requeue(-1)
+				} else {
+					nm.Finish()
+				}
+			})
+			return nil
+		})
+		concurrency = 1
 	}
 
 	sub := &subscriber{
-		c:     c,
-		opts:  options,
-		topic: topic,
-		h:     h,
-		n:     concurrency,
+		opts:    options,
+		topic:   topic,
+		channel: channel,
+		config:  &config,
+		b:       n,
+		h:       h,
+		n:       concurrency,
+	}
+
+	n.Lock()
+	defer n.Unlock()
+
+	if err := n.connect(sub); err != nil {
+		return nil, err
 	}
 
 	n.c = append(n.c, sub)
@@ -295,6 +890,77 @@ func (n *nsqBroker) Subscribe(topic string, handler broker.Handler, opts ...brok
 	return sub, nil
 }
 
+// touch touches the message at the interval until the returned func is called
+func touch(nm *nsq.Message, interval time.Duration) func() {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan bool)
+	exited := make(chan bool)
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		defer close(exited)
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				// a noop once the handler responded
+				nm.Touch()
+			}
+		}
+	}()
+
+	// waits for a touch in progress so none follow the response
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// deadLetter publishes the raw body of the message to the dead-letter topic,
+// so it's left as it was published, prefixed with the ErrorHeader, TopicHeader
+// and AttemptsHeader as EnvelopeHeader does. The nsq id, attempts and timestamp
+// headers of the handled message aren't kept. It's published to the nsqd the
+// message came from, or the first one, and isn't retried on the others.
+func (n *nsqBroker) deadLetter(topic string, p *publication, attempts uint16) error {
+	b, err := EnvelopeHeader.encode(n.opts.Codec, &broker.Message{
+		Header: map[string]string{
+			ErrorHeader:    p.err.Error(),
+			TopicHeader:    p.topic,
+			AttemptsHeader: strconv.Itoa(int(attempts)),
+		},
+		Body: p.nm.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	n.Lock()
+	producers := n.p
+	n.Unlock()
+
+	if len(producers) == 0 {
+		return errors.New("not connected")
+	}
+
+	pr, err := n.producer(producers, topic, p.nm.NSQDAddress, nil)
+	if err != nil {
+		pr = producers[0]
+	}
+
+	start := time.Now()
+	err = pr.Publish(topic, b)
+	n.published(pr, err)
+	if n.metrics != nil {
+		n.metrics.Published(topic, time.Since(start), err)
+	}
+	return authError(pr.addr, err)
+}
+
 func (n *nsqBroker) String() string {
 	return "nsq"
 }
@@ -307,6 +973,18 @@ func (p *publication) Message() *broker.Message {
 	return p.m
 }
 
+func (p *publication) ID() string {
+	return string(p.nm.ID[:])
+}
+
+func (p *publication) Attempts() uint16 {
+	return p.nm.Attempts
+}
+
+func (p *publication) Timestamp() time.Time {
+	return time.Unix(0, p.nm.Timestamp)
+}
+
 func (p *publication) Ack() error {
 	p.nm.Finish()
 	return nil
@@ -316,6 +994,18 @@ func (p *publication) Error() error {
 	return p.err
 }
 
+// Requeue requeues the message to be redelivered after the delay, backing off
+// the consumer as a handler error does. A delay of -1 lets nsq choose it.
+func (p *publication) Requeue(delay time.Duration) {
+	p.nm.Requeue(delay)
+}
+
+// RequeueWithoutBackoff requeues the message to be redelivered after the delay
+// without backing off the consumer
+func (p *publication) RequeueWithoutBackoff(delay time.Duration) {
+	p.nm.RequeueWithoutBackoff(delay)
+}
+
 func (s *subscriber) Options() broker.SubscribeOptions {
 	return s.opts
 }
@@ -324,8 +1014,23 @@ func (s *subscriber) Topic() string {
 	return s.topic
 }
 
+// Unsubscribe stops the consumer and removes the subscriber from the
+// broker so it isn't resubscribed when reconnecting
 func (s *subscriber) Unsubscribe() error {
-	s.c.Stop()
+	n := s.b
+
+	n.Lock()
+	defer n.Unlock()
+
+	n.stop(s)
+
+	for i, c := range n.c {
+		if c == s {
+			n.c = append(n.c[:i], n.c[i+1:]...)
+			break
+		}
+	}
+
 	return nil
 }
 
@@ -354,9 +1059,11 @@ func NewBroker(opts ...broker.Option) broker.Broker {
 	}
 
 	n := &nsqBroker{
-		addrs:  addrs,
-		opts:   options,
-		config: nsq.NewConfig(),
+		addrs:    addrs,
+		opts:     options,
+		config:   nsq.NewConfig(),
+		selector: RandomSelector,
+		retries:  DefaultPublishRetries,
 	}
 	n.configure(n.opts.Context)
 
@@ -0,0 +1,1886 @@
+package nsq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/codec/json"
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-plugins/broker/tests/v2"
+	"github.com/nsqio/go-nsq"
+)
+
+func TestSuite(t *testing.T) {
+	addr := os.Getenv("NSQD_ADDRESS")
+	if addr == "" {
+		t.Skip("NSQD_ADDRESS not defined")
+	}
+
+	// topics are created on the first publish
+	tests.Run(t, func() broker.Broker {
+		return NewBroker(broker.Addrs(addr))
+	}, tests.WithSettle(time.Second))
+}
+
+func TestTLSConfig(t *testing.T) {
+	config := &tls.Config{ServerName: "nsqd"}
+
+	b := NewBroker(WithTLSConfig(config)).(*nsqBroker)
+	if !b.config.TlsV1 || b.config.TlsConfig != config {
+		t.Fatalf("expected tls to be enabled with the config got %v %v", b.config.TlsV1, b.config.TlsConfig)
+	}
+
+	b = NewBroker().(*nsqBroker)
+	if b.config.TlsV1 {
+		t.Fatal("expected tls to be disabled by default")
+	}
+
+	if err := b.Init(broker.Secure(true)); err != nil {
+		t.Fatal(err)
+	}
+	if !b.config.TlsV1 {
+		t.Fatal("expected tls to be enabled by broker.Secure")
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	// lookupd is only queried in the background so no server is needed
+	b := NewBroker(WithLookupdAddrs([]string{"127.0.0.1:4161"})).(*nsqBroker)
+
+	handler := func(broker.Event) error { return nil }
+
+	s1, err := b.Subscribe("foo", handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := b.Subscribe("bar", handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s1.Unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.c) != 1 || b.c[0] != s2 {
+		t.Fatalf("expected only the bar subscriber to remain got %d", len(b.c))
+	}
+	if s1.(*subscriber).c != nil {
+		t.Fatal("expected the consumer to be stopped")
+	}
+
+	// unsubscribing again is a noop
+	if err := s1.Unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.c) != 1 {
+		t.Fatalf("expected 1 subscriber got %d", len(b.c))
+	}
+
+	// without resubscribing disconnecting unsubscribes
+	b.running = true
+	if err := b.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.c) != 0 {
+		t.Fatalf("expected no subscribers after disconnecting got %d", len(b.c))
+	}
+}
+
+func TestResubscribe(t *testing.T) {
+	b := NewBroker(
+		WithLookupdAddrs([]string{"127.0.0.1:4161"}),
+		WithResubscribe(),
+	).(*nsqBroker)
+
+	s, err := b.Subscribe("foo", func(broker.Event) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.running = true
+	if err := b.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.c) != 1 || b.c[0] != s {
+		t.Fatal("expected the subscriber to be kept to resubscribe")
+	}
+	if s.(*subscriber).c != nil {
+		t.Fatal("expected the consumer to be stopped")
+	}
+
+	// subscribers unsubscribed while disconnected aren't resubscribed
+	if err := s.Unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+	if len(b.c) != 0 {
+		t.Fatalf("expected no subscribers got %d", len(b.c))
+	}
+}
+
+type testDelegate struct {
+	finished bool
+	requeued bool
+	delay    time.Duration
+	backoff  bool
+	// accessed atomically, messages are touched from another goroutine
+	touched int32
+}
+
+func (d *testDelegate) OnFinish(*nsq.Message) { d.finished = true }
+
+func (d *testDelegate) OnRequeue(_ *nsq.Message, delay time.Duration, backoff bool) {
+	d.requeued, d.delay, d.backoff = true, delay, backoff
+}
+
+func (d *testDelegate) OnTouch(*nsq.Message) { atomic.AddInt32(&d.touched, 1) }
+
+func TestMaxAttempts(t *testing.T) {
+	b := NewBroker(WithLookupdAddrs([]string{"127.0.0.1:4161"})).(*nsqBroker)
+	handler := func(broker.Event) error { return errors.New("failed") }
+
+	s, err := b.Subscribe("foo", handler, WithMaxAttempts(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+
+	if a := s.(*subscriber).config.MaxAttempts; a != 3 {
+		t.Fatalf("expected 3 max attempts got %d", a)
+	}
+
+	d, err := b.Subscribe("foo", handler, WithMaxAttempts(3), WithDeadLetterTopic("foo.dlq"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Unsubscribe()
+
+	// attempts are counted by the handler so nsq doesn't drop messages
+	ds := d.(*subscriber)
+	if a := ds.config.MaxAttempts; a != 0 {
+		t.Fatalf("expected unlimited nsq attempts got %d", a)
+	}
+
+	body, _ := b.opts.Codec.Marshal(&broker.Message{Body: []byte("foo")})
+
+	for attempts, finished := range map[uint16]bool{1: false, 3: false} {
+		delegate := &testDelegate{}
+		nm := nsq.NewMessage(nsq.MessageID{}, body)
+		nm.Delegate = delegate
+		nm.Attempts = attempts
+
+		// the broker isn't connected so the message can't be dead-lettered
+		if err := ds.h(nm); err == nil {
+			t.Fatalf("expected the handler error to requeue attempt %d", attempts)
+		}
+		if delegate.finished != finished {
+			t.Fatalf("expected attempt %d not to be finished", attempts)
+		}
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	addr := os.Getenv("NSQD_ADDRESS")
+	if addr == "" {
+		t.Skip("NSQD_ADDRESS not defined")
+	}
+
+	b := NewBroker(broker.Addrs(addr))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	dead := make(chan *broker.Message, 1)
+	d, err := b.Subscribe("dlq.foo.dead", func(e broker.Event) error {
+		dead <- e.Message()
+		return nil
+	}, WithSubscribeEnvelope(EnvelopeHeader))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Unsubscribe()
+
+	s, err := b.Subscribe("dlq.foo", func(broker.Event) error {
+		return errors.New("failed")
+	}, WithMaxAttempts(1), WithDeadLetterTopic("dlq.foo.dead"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+
+	// subscribing creates the topics and channels
+	time.Sleep(time.Second)
+
+	if err := b.Publish("dlq.foo", &broker.Message{Header: map[string]string{"id": "1"}, Body: []byte("foo")}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case m := <-dead:
+		if m.Header[TopicHeader] != "dlq.foo" || m.Header[ErrorHeader] != "failed" || m.Header[AttemptsHeader] != "1" {
+			t.Fatalf("unexpected dead-lettered headers %v", m.Header)
+		}
+		// the body is the message as it was published
+		var orig broker.Message
+		if err := b.Options().Codec.Unmarshal(m.Body, &orig); err != nil || orig.Header["id"] != "1" {
+			t.Fatalf("expected the published message got %v %v", orig.Header, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected message to be dead-lettered")
+	}
+}
+
+func TestDeadLetterBody(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// the topic and body of the publishes to the nsqd
+	published := make(chan [2]string, 1)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				r := bufio.NewReader(c)
+				if _, err := r.Discard(len(nsq.MagicV2)); err != nil {
+					return
+				}
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					cmd := strings.Fields(line)
+					if len(cmd) == 0 || cmd[0] == "NOP" {
+						continue
+					}
+					var size int32
+					if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+						return
+					}
+					body := make([]byte, size)
+					if _, err := io.ReadFull(r, body); err != nil {
+						return
+					}
+					if cmd[0] == "PUB" {
+						published <- [2]string{cmd[1], string(body)}
+					}
+					frame(c, nsq.FrameTypeResponse, "OK")
+				}
+			}()
+		}
+	}()
+
+	b := NewBroker(broker.Addrs(l.Addr().String())).(*nsqBroker)
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	s, err := b.Subscribe("foo", func(broker.Event) error {
+		return errors.New("failed")
+	}, WithMaxAttempts(2), WithDeadLetterTopic("foo.dead"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+
+	body, _ := b.opts.Codec.Marshal(&broker.Message{Header: map[string]string{"id": "1"}, Body: []byte("foo")})
+	delegate := &testDelegate{}
+	nm := nsq.NewMessage(nsq.MessageID{}, body)
+	nm.Delegate = delegate
+	nm.Attempts = 2
+
+	if err := s.(*subscriber).h(nm); err != nil || !delegate.finished {
+		t.Fatalf("expected the message to be dead-lettered and finished got %v %v", err, delegate.finished)
+	}
+
+	var pub [2]string
+	select {
+	case pub = <-published:
+	case <-time.After(time.Second):
+		t.Fatal("expected the message to be dead-lettered")
+	}
+	if pub[0] != "foo.dead" {
+		t.Fatalf("expected the publish to foo.dead got %s", pub[0])
+	}
+
+	// the dead-letter headers are in front of the body as it was consumed
+	var m broker.Message
+	if err := EnvelopeHeader.decode(b.opts.Codec, []byte(pub[1]), &m); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{ErrorHeader: "failed", TopicHeader: "foo", AttemptsHeader: "2"}
+	if !reflect.DeepEqual(m.Header, expected) {
+		t.Fatalf("expected the dead-letter headers %v got %v", expected, m.Header)
+	}
+	if !bytes.Equal(m.Body, body) {
+		t.Fatalf("expected the body as it was consumed got %s", m.Body)
+	}
+}
+
+func TestLookupdTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("topic") {
+		case "foo":
+			// negotiated version 1.0 response
+			w.Header().Set("X-NSQ-Content-Type", "nsq; version=1.0")
+			w.Write([]byte(`{"producers":[{"broadcast_address":"10.0.0.1","tcp_port":4150}]}`))
+		case "bar":
+			// response before version negotiation
+			w.Write([]byte(`{"status_code":200,"data":{"producers":[{"broadcast_address":"10.0.0.2","tcp_port":4150}]}}`))
+		default:
+			http.Error(w, "TOPIC_NOT_FOUND", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ts.Certificate())
+
+	addr := strings.TrimPrefix(ts.URL, "https://")
+
+	b := NewBroker(
+		WithLookupdAddrs([]string{addr}),
+		WithLookupdTLSConfig(&tls.Config{RootCAs: pool}),
+		WithLookupdTimeout(time.Second),
+	).(*nsqBroker)
+
+	if b.lookupdClient == nil || b.lookupdClient.Timeout != time.Second {
+		t.Fatal("expected the lookupd client to be configured")
+	}
+
+	testData := map[string][]string{
+		"foo": {"10.0.0.1:4150"},
+		"bar": {"10.0.0.2:4150"},
+		"baz": nil,
+	}
+
+	for topic, expected := range testData {
+		addrs, err := b.lookup(addr, topic)
+		if err != nil {
+			t.Fatalf("unexpected error looking up %s: %v", topic, err)
+		}
+		if !reflect.DeepEqual(addrs, expected) && len(addrs)+len(expected) > 0 {
+			t.Fatalf("expected %v for %s got %v", expected, topic, addrs)
+		}
+	}
+
+	// the server certificate isn't trusted without the CA
+	nb := NewBroker(
+		WithLookupdAddrs([]string{addr}),
+		WithLookupdTimeout(time.Second),
+	).(*nsqBroker)
+	if _, err := nb.lookup("https://"+addr, "foo"); err == nil {
+		t.Fatal("expected lookup to fail without the CA")
+	}
+
+	// subscribers poll lookupd with the client
+	s, err := b.Subscribe("foo", func(broker.Event) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.(*subscriber).exit == nil {
+		t.Fatal("expected the subscriber to poll lookupd")
+	}
+	if err := s.Unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChannel(t *testing.T) {
+	testData := []struct {
+		opts     []broker.Option
+		expected string
+	}{
+		{[]broker.Option{WithServiceChannel("go.micro.srv.greeter")}, "go.micro.srv.greeter#ephemeral"},
+		{[]broker.Option{WithServiceChannel("greeter"), WithChannelPrefix("team-a.")}, "team-a.greeter#ephemeral"},
+		{[]broker.Option{WithServiceChannel("greeter"), WithDurableChannels()}, "greeter"},
+		{[]broker.Option{WithServiceChannel("greeter:v1/api")}, "greeter_v1_api#ephemeral"},
+		{[]broker.Option{WithServiceChannel(strings.Repeat("a", 70))}, strings.Repeat("a", 54) + "#ephemeral"},
+	}
+
+	for _, d := range testData {
+		b := NewBroker(d.opts...).(*nsqBroker)
+		if c := b.channel(); c != d.expected {
+			t.Fatalf("expected channel %s got %s", d.expected, c)
+		}
+		if !nsq.IsValidChannelName(b.channel()) {
+			t.Fatalf("expected channel %s to be valid", b.channel())
+		}
+	}
+
+	b := NewBroker(WithChannelPrefix("team-a.")).(*nsqBroker)
+	c := b.channel()
+	if !strings.HasPrefix(c, "team-a.") || !strings.HasSuffix(c, "#ephemeral") || c == b.channel() {
+		t.Fatalf("expected a random prefixed ephemeral channel got %s", c)
+	}
+
+	// the queue is used as the channel
+	b = NewBroker(WithLookupdAddrs([]string{"127.0.0.1:4161"}), WithChannelPrefix("team-a.")).(*nsqBroker)
+	s, err := b.Subscribe("foo", func(broker.Event) error { return nil }, broker.Queue("workers"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+	if c := s.(*subscriber).channel; c != "workers" {
+		t.Fatalf("expected the queue as the channel got %s", c)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "INTERNAL_ERROR", http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	events := make(chan *Event, 10)
+	addr := strings.TrimPrefix(ts.URL, "http://")
+
+	b := NewBroker(
+		WithLookupdAddrs([]string{addr}),
+		WithEventHandler(func(e *Event) { events <- e }),
+	).(*nsqBroker)
+
+	s, err := b.Subscribe("foo", func(broker.Event) error {
+		return errors.New("failed")
+	}, broker.Queue("bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+
+	// lookupd is queried with the broker client to emit its errors
+	select {
+	case e := <-events:
+		if e.Type != EventLookupdError || e.Addr != addr || e.Error == nil {
+			t.Fatalf("expected a lookupd error event got %s %v", e.Type, e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a lookupd error event")
+	}
+
+	body, _ := b.opts.Codec.Marshal(&broker.Message{Body: []byte("foo")})
+	nm := nsq.NewMessage(nsq.MessageID{}, body)
+	nm.Delegate = &testDelegate{}
+
+	if err := s.(*subscriber).h(nm); err == nil {
+		t.Fatal("expected the handler error")
+	}
+
+	select {
+	case e := <-events:
+		if e.Type != EventError || e.Topic != "foo" || e.Channel != "bar" || e.Error.Error() != "failed" {
+			t.Fatalf("expected a handler error event got %s %v", e.Type, e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a handler error event")
+	}
+
+	// the producer state follows the publish errors
+	p := &producer{addr: "127.0.0.1:4150"}
+
+	testData := []struct {
+		err      error
+		expected []EventType
+	}{
+		{nil, nil},
+		{nsq.ErrNotConnected, []EventType{EventDisconnect}},
+		{nsq.ErrNotConnected, nil},
+		{nsq.ErrProtocol{Reason: "E_BAD_TOPIC"}, nil},
+		{nil, []EventType{EventReconnect}},
+	}
+
+	for i, d := range testData {
+		for len(events) > 0 {
+			<-events
+		}
+
+		b.published(p, d.err)
+
+		var got []EventType
+		for len(events) > 0 {
+			e := <-events
+			if e.Type == EventLookupdError {
+				continue
+			}
+			if e.Addr != p.addr {
+				t.Fatalf("expected the producer address got %s", e.Addr)
+			}
+			got = append(got, e.Type)
+		}
+		if !reflect.DeepEqual(got, d.expected) {
+			t.Fatalf("%d: expected events %v got %v", i, d.expected, got)
+		}
+	}
+}
+
+func TestConnectEvents(t *testing.T) {
+	addr := os.Getenv("NSQD_ADDRESS")
+	if addr == "" {
+		t.Skip("NSQD_ADDRESS not defined")
+	}
+
+	events := make(chan *Event, 10)
+
+	b := NewBroker(
+		broker.Addrs(addr),
+		WithEventHandler(func(e *Event) { events <- e }),
+	)
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	s, err := b.Subscribe("events.foo", func(broker.Event) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+
+	for _, topic := range []string{"", "events.foo"} {
+		select {
+		case e := <-events:
+			if e.Type != EventConnect || e.Topic != topic {
+				t.Fatalf("expected a connect event for %q got %s %v", topic, e.Type, e)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected a connect event for %q", topic)
+		}
+	}
+}
+
+func TestPublishContext(t *testing.T) {
+	// nsqd which accepts connections but never responds
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	conns := make(chan net.Conn, 10)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conns <- c
+		}
+	}()
+
+	b := NewBroker(broker.Addrs(l.Addr().String())).(*nsqBroker)
+
+	p, err := nsq.NewProducer(l.Addr().String(), b.config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the abandoned publish only returns once the connection is closed
+	defer func() {
+		l.Close()
+		for len(conns) > 0 {
+			(<-conns).Close()
+		}
+		p.Stop()
+	}()
+	b.p = []*producer{{Producer: p, addr: l.Addr().String()}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err = b.Publish("foo", &broker.Message{Body: []byte("foo")}, broker.PublishContext(ctx))
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected the deadline to be exceeded got %v", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("expected the publish to be abandoned at the deadline got %v", d)
+	}
+
+	// cancelled contexts aren't published
+	cctx, ccancel := context.WithCancel(context.Background())
+	ccancel()
+
+	if err := b.Publish("foo", &broker.Message{}, broker.PublishContext(cctx), WithDeferredPublish(time.Second)); err != context.Canceled {
+		t.Fatalf("expected the publish to be cancelled got %v", err)
+	}
+}
+
+// testLogger records the entries logged at or above its level
+type testLogger struct {
+	level   log.Level
+	entries []string
+}
+
+func (l *testLogger) Init(...log.Option) error { return nil }
+
+func (l *testLogger) Options() log.Options { return log.Options{Level: l.level} }
+
+func (l *testLogger) Fields(map[string]interface{}) log.Logger { return l }
+
+func (l *testLogger) Log(level log.Level, v ...interface{}) {
+	l.entries = append(l.entries, level.String()+" "+fmt.Sprint(v...))
+}
+
+func (l *testLogger) Logf(level log.Level, format string, v ...interface{}) {
+	l.Log(level, fmt.Sprintf(format, v...))
+}
+
+func (l *testLogger) String() string { return "test" }
+
+func TestLogger(t *testing.T) {
+	logger := &testLogger{level: log.InfoLevel}
+
+	b := NewBroker(
+		WithLogger(logger),
+		WithLogLevels(map[nsq.LogLevel]log.Level{nsq.LogLevelInfo: log.DebugLevel}),
+	).(*nsqBroker)
+
+	l := b.newLogger()
+
+	testData := []struct {
+		line     string
+		expected string
+	}{
+		// info is overridden to debug which is below the logger level
+		{"INF    1 [foo/bar] connecting to nsqd", ""},
+		{"DBG    1 [foo/bar] heartbeat received", ""},
+		{"WRN    1 [foo/bar] backing off", "warn 1 [foo/bar] backing off"},
+		{"ERR    2 (127.0.0.1:4150) IO error - EOF", "error 2 (127.0.0.1:4150) IO error - EOF"},
+	}
+
+	for _, d := range testData {
+		logger.entries = nil
+
+		if err := l.Output(2, d.line); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(d.expected) == 0 {
+			if len(logger.entries) > 0 {
+				t.Fatalf("expected %q not to be logged got %v", d.line, logger.entries)
+			}
+			continue
+		}
+		if len(logger.entries) != 1 || logger.entries[0] != d.expected {
+			t.Fatalf("expected %q to be logged got %v", d.expected, logger.entries)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	b := NewBroker(
+		WithLookupdAddrs([]string{"127.0.0.1:4161"}),
+		WithResubscribe(),
+	).(*nsqBroker)
+
+	p := &producer{addr: "127.0.0.1:4150"}
+	b.p = []*producer{p}
+	b.published(p, nil)
+	b.published(p, nsq.ErrNotConnected)
+
+	s, err := b.Subscribe("foo", func(broker.Event) error { return nil }, broker.Queue("bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := b.Stats()
+	if len(stats.Producers) != 1 || stats.Producers[0].Published != 1 || stats.Producers[0].Errors != 1 {
+		t.Fatalf("unexpected producer stats %+v", stats.Producers)
+	}
+	if len(stats.Consumers) != 1 || stats.Consumers[0].Topic != "foo" || stats.Consumers[0].Channel != "bar" {
+		t.Fatalf("unexpected consumer stats %+v", stats.Consumers)
+	}
+
+	// stopped subscribers have no stats
+	b.stop(s.(*subscriber))
+	if stats := b.Stats(); len(stats.Consumers) != 0 {
+		t.Fatalf("expected no consumer stats got %+v", stats.Consumers)
+	}
+}
+
+func TestRequeue(t *testing.T) {
+	b := NewBroker(WithLookupdAddrs([]string{"127.0.0.1:4161"})).(*nsqBroker)
+	body, _ := b.opts.Codec.Marshal(&broker.Message{Body: []byte("foo")})
+
+	testData := []struct {
+		handler broker.Handler
+		opts    []broker.SubscribeOption
+		delay   time.Duration
+		backoff bool
+	}{
+		// the handler requeues the message itself
+		{
+			handler: func(e broker.Event) error {
+				e.(Requeuer).Requeue(time.Second)
+				return errors.New("failed")
+			},
+			delay:   time.Second,
+			backoff: true,
+		},
+		{
+			handler: func(e broker.Event) error {
+				e.(Requeuer).RequeueWithoutBackoff(2 * time.Second)
+				return nil
+			},
+			delay: 2 * time.Second,
+		},
+		// the handler response takes precedence over the default delay
+		{
+			handler: func(e broker.Event) error {
+				e.(Requeuer).RequeueWithoutBackoff(3 * time.Second)
+				return errors.New("failed")
+			},
+			opts:  []broker.SubscribeOption{WithRequeueDelay(time.Minute)},
+			delay: 3 * time.Second,
+		},
+		// handler errors are requeued after the default delay
+		{
+			handler: func(broker.Event) error { return errors.New("failed") },
+			opts:    []broker.SubscribeOption{WithRequeueDelay(time.Minute)},
+			delay:   time.Minute,
+		},
+		// handler errors are requeued with backoff without auto ack
+		{
+			handler: func(broker.Event) error { return errors.New("failed") },
+			opts:    []broker.SubscribeOption{broker.DisableAutoAck()},
+			delay:   -1,
+			backoff: true,
+		},
+	}
+
+	for i, d := range testData {
+		s, err := b.Subscribe("foo", d.handler, d.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		delegate := &testDelegate{}
+		nm := nsq.NewMessage(nsq.MessageID{}, body)
+		nm.Delegate = delegate
+
+		s.(*subscriber).h(nm)
+
+		if !delegate.requeued || delegate.delay != d.delay || delegate.backoff != d.backoff {
+			t.Fatalf("%d: expected requeue after %v with backoff %v got %v %v %v", i, d.delay, d.backoff, delegate.requeued, delegate.delay, delegate.backoff)
+		}
+
+		s.Unsubscribe()
+	}
+
+	// without auto ack a handler which returns nil acks the message itself,
+	// here once it's processed asynchronously
+	acked := make(chan bool)
+	s, err := b.Subscribe("foo", func(e broker.Event) error {
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			e.Ack()
+			close(acked)
+		}()
+		return nil
+	}, broker.DisableAutoAck())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delegate := &testDelegate{}
+	nm := nsq.NewMessage(nsq.MessageID{}, body)
+	nm.Delegate = delegate
+
+	if err := s.(*subscriber).h(nm); err != nil {
+		t.Fatal(err)
+	}
+	if nm.HasResponded() {
+		t.Fatal("expected the message to wait for the handler to ack it")
+	}
+	select {
+	case <-acked:
+	case <-time.After(time.Second):
+		t.Fatal("expected the message to be acked")
+	}
+	if !delegate.finished || delegate.requeued {
+		t.Fatalf("expected the message to be finished without requeueing got %v %v", delegate.finished, delegate.requeued)
+	}
+	s.Unsubscribe()
+
+	// without a default delay handler errors are requeued by go-nsq
+	s, err = b.Subscribe("foo", func(broker.Event) error { return errors.New("failed") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+
+	delegate = &testDelegate{}
+	nm = nsq.NewMessage(nsq.MessageID{}, body)
+	nm.Delegate = delegate
+
+	if err := s.(*subscriber).h(nm); err == nil || delegate.requeued {
+		t.Fatal("expected the handler error to be returned without requeueing")
+	}
+}
+
+func TestSelector(t *testing.T) {
+	addrs := []string{"10.0.0.1:4150", "10.0.0.2:4150", "10.0.0.3:4150"}
+
+	var producers []*producer
+	for _, addr := range addrs {
+		producers = append(producers, &producer{addr: addr})
+	}
+
+	b := NewBroker(WithSelector(HashSelector)).(*nsqBroker)
+
+	// topics are always published to the same nsqd
+	seen := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		topic := "topic." + strconv.Itoa(i)
+
+		p, err := b.producer(producers, topic, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 3; j++ {
+			if o, _ := b.producer(producers, topic, "", nil); o != p {
+				t.Fatalf("expected %s to be published to %s got %s", topic, p.addr, o.addr)
+			}
+		}
+		seen[p.addr] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("expected topics to be sharded across nsqds got %v", seen)
+	}
+
+	// the publish address overrides the selector
+	p, err := b.producer(producers, "topic.0", addrs[2], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.addr != addrs[2] {
+		t.Fatalf("expected %s got %s", addrs[2], p.addr)
+	}
+
+	b.p = producers
+	if err := b.Publish("foo", &broker.Message{}, WithPublishAddr("10.0.0.4:4150")); err == nil || !strings.Contains(err.Error(), "no producer") {
+		t.Fatalf("expected an error for an unknown nsqd got %v", err)
+	}
+}
+
+func TestTouch(t *testing.T) {
+	b := NewBroker(WithLookupdAddrs([]string{"127.0.0.1:4161"})).(*nsqBroker)
+	body, _ := b.opts.Codec.Marshal(&broker.Message{Body: []byte("foo")})
+
+	handler := func(broker.Event) error {
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	}
+
+	s, err := b.Subscribe("foo", handler, WithTouchInterval(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+
+	delegate := &testDelegate{}
+	nm := nsq.NewMessage(nsq.MessageID{}, body)
+	nm.Delegate = delegate
+
+	if err := s.(*subscriber).h(nm); err != nil {
+		t.Fatal(err)
+	}
+
+	touched := atomic.LoadInt32(&delegate.touched)
+	if touched < 2 {
+		t.Fatalf("expected the message to be touched while handled got %d touches", touched)
+	}
+
+	// touching stops once the handler returns
+	time.Sleep(60 * time.Millisecond)
+	if n := atomic.LoadInt32(&delegate.touched); n != touched {
+		t.Fatalf("expected no touches after the handler returned got %d", n-touched)
+	}
+}
+
+// frame writes a frame of the nsq protocol
+func frame(c net.Conn, frameType int32, data string) {
+	buf := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(4+len(data)))
+	binary.BigEndian.PutUint32(buf[4:], uint32(frameType))
+	copy(buf[8:], data)
+	c.Write(buf)
+}
+
+// command reads a command and its body
+func command(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSpace(line)
+	if line == "NOP" {
+		return line, nil
+	}
+	var size int32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return "", err
+	}
+	if _, err := r.Discard(int(size)); err != nil {
+		return "", err
+	}
+	return line, nil
+}
+
+// authNSQD accepts connections as nsqd requiring auth and rejects every secret
+func authNSQD(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				r := bufio.NewReader(c)
+				if _, err := r.Discard(len(nsq.MagicV2)); err != nil {
+					return
+				}
+				if cmd, err := command(r); err != nil || cmd != "IDENTIFY" {
+					return
+				}
+				frame(c, nsq.FrameTypeResponse, `{"auth_required": true}`)
+				if cmd, err := command(r); err != nil || cmd != "AUTH" {
+					return
+				}
+				frame(c, nsq.FrameTypeError, "E_AUTH_FAILED AUTH failed")
+			}()
+		}
+	}()
+
+	return l
+}
+
+// pubNSQD accepts publishes as nsqd and counts them, while failing is set it
+// closes the connection instead
+func pubNSQD(t *testing.T, published, failing *int32) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				r := bufio.NewReader(c)
+				if _, err := r.Discard(len(nsq.MagicV2)); err != nil {
+					return
+				}
+				for {
+					cmd, err := command(r)
+					if err != nil || atomic.LoadInt32(failing) == 1 {
+						return
+					}
+					switch {
+					case cmd == "NOP":
+					case strings.HasPrefix(cmd, "PUB"):
+						atomic.AddInt32(published, 1)
+						fallthrough
+					default:
+						frame(c, nsq.FrameTypeResponse, "OK")
+					}
+				}
+			}()
+		}
+	}()
+
+	return l
+}
+
+// subNSQD accepts subscriptions as nsqd and tracks the connected consumers
+func subNSQD(t *testing.T, connected *int32) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				r := bufio.NewReader(c)
+				if _, err := r.Discard(len(nsq.MagicV2)); err != nil {
+					return
+				}
+				if cmd, err := command(r); err != nil || cmd != "IDENTIFY" {
+					return
+				}
+				frame(c, nsq.FrameTypeResponse, "OK")
+
+				atomic.AddInt32(connected, 1)
+				defer atomic.AddInt32(connected, -1)
+
+				// the commands of consumers have no body
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch cmd := strings.TrimSpace(line); {
+					case strings.HasPrefix(cmd, "SUB"):
+						frame(c, nsq.FrameTypeResponse, "OK")
+					case cmd == "CLS":
+						frame(c, nsq.FrameTypeResponse, "CLOSE_WAIT")
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	return l
+}
+
+func TestLookupdPoll(t *testing.T) {
+	var connected [2]int32
+	var nodes []*peerInfo
+	for i := range connected {
+		l := subNSQD(t, &connected[i])
+		defer l.Close()
+		addr := l.Addr().(*net.TCPAddr)
+		nodes = append(nodes, &peerInfo{BroadcastAddress: addr.IP.String(), TCPPort: addr.Port})
+	}
+
+	var mu sync.Mutex
+	registered := nodes
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		b, _ := json.Marshaler{}.Marshal(&lookupResp{Producers: registered})
+		mu.Unlock()
+		w.Header().Set("X-NSQ-Content-Type", "nsq; version=1.0")
+		w.Write(b)
+	}))
+	defer ts.Close()
+
+	// the event handler has lookupd polled by the broker instead of go-nsq
+	b := NewBroker(
+		WithLookupdAddrs([]string{strings.TrimPrefix(ts.URL, "http://")}),
+		WithConsumerOpts([]string{"lookupd_poll_interval,20ms"}),
+		WithEventHandler(func(*Event) {}),
+	).(*nsqBroker)
+
+	s, err := b.Subscribe("foo", func(broker.Event) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+
+	waitConnected := func(expected [2]int32) {
+		for i := 0; ; i++ {
+			got := [2]int32{atomic.LoadInt32(&connected[0]), atomic.LoadInt32(&connected[1])}
+			if got == expected {
+				return
+			}
+			if i == 50 {
+				t.Fatalf("expected %v consumers connected to the nsqds got %v", expected, got)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	waitConnected([2]int32{1, 1})
+
+	// the nsqd leaving lookupd is disconnected
+	mu.Lock()
+	registered = nodes[1:]
+	mu.Unlock()
+	waitConnected([2]int32{0, 1})
+
+	// and connected again once it's back
+	mu.Lock()
+	registered = nodes
+	mu.Unlock()
+	waitConnected([2]int32{1, 1})
+}
+
+func TestFailover(t *testing.T) {
+	interval := DefaultHealthInterval
+	DefaultHealthInterval = 20 * time.Millisecond
+	defer func() { DefaultHealthInterval = interval }()
+
+	var published, failing [2]int32
+	var addrs []string
+	for i := range published {
+		l := pubNSQD(t, &published[i], &failing[i])
+		defer l.Close()
+		addrs = append(addrs, l.Addr().String())
+	}
+
+	// the first nsqd is selected while it's up
+	first := func(topic string, addrs []string) string {
+		return addrs[0]
+	}
+
+	b := NewBroker(broker.Addrs(addrs...), WithSelector(first)).(*nsqBroker)
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	// the publish is retried on the other nsqd
+	atomic.StoreInt32(&failing[0], 1)
+	if err := b.Publish("foo", &broker.Message{Body: []byte("foo")}); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&published[1]); n != 1 {
+		t.Fatalf("expected the publish to fail over got %d publishes", n)
+	}
+	if !b.p[0].isDown() {
+		t.Fatal("expected the failed nsqd to be down")
+	}
+
+	// nsqds which are down aren't selected
+	if p, err := b.producer(b.p, "foo", "", nil); err != nil || p.addr != addrs[1] {
+		t.Fatalf("expected %s to be selected got %v", addrs[1], p)
+	}
+
+	// publishes to an address aren't retried
+	if err := b.Publish("foo", &broker.Message{}, WithPublishAddr(addrs[0])); err == nil {
+		t.Fatal("expected the publish to the failed nsqd to fail")
+	}
+
+	// the nsqd is selected again once it's back
+	atomic.StoreInt32(&failing[0], 0)
+	for i := 0; b.p[0].isDown(); i++ {
+		if i == 50 {
+			t.Fatal("expected the nsqd to be up again")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err := b.Publish("foo", &broker.Message{Body: []byte("foo")}); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&published[0]); n != 1 {
+		t.Fatalf("expected the nsqd to be published to again got %d publishes", n)
+	}
+
+	// without retries the error is returned
+	b.retries = 0
+	atomic.StoreInt32(&failing[0], 1)
+	atomic.StoreInt32(&failing[1], 1)
+	if err := b.Publish("foo", &broker.Message{}); err == nil {
+		t.Fatal("expected the publish to fail")
+	}
+}
+
+// addrs returns the nsqd addresses of the producers
+func addrs(producers []*producer) []string {
+	var addrs []string
+	for _, p := range producers {
+		addrs = append(addrs, p.addr)
+	}
+	return addrs
+}
+
+func TestUpdateAddrs(t *testing.T) {
+	var published, failing [3]int32
+	var nsqds []string
+	for i := range published {
+		l := pubNSQD(t, &published[i], &failing[i])
+		defer l.Close()
+		nsqds = append(nsqds, l.Addr().String())
+	}
+
+	b := NewBroker(broker.Addrs(nsqds[:2]...)).(*nsqBroker)
+	if err := b.UpdateAddrs(nil); err == nil {
+		t.Fatal("expected updating to no addresses to fail")
+	}
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	kept := b.p[1]
+	if err := b.UpdateAddrs(nsqds[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if got := addrs(b.p); !reflect.DeepEqual(got, nsqds[1:]) {
+		t.Fatalf("expected producers of %v got %v", nsqds[1:], got)
+	}
+	if b.p[0] != kept {
+		t.Fatal("expected the producer of the kept nsqd to be reused")
+	}
+
+	// the removed nsqd isn't published to
+	if err := b.Publish("foo", &broker.Message{}, WithPublishAddr(nsqds[0])); err == nil {
+		t.Fatal("expected the publish to the removed nsqd to fail")
+	}
+	if err := b.Publish("foo", &broker.Message{Body: []byte("foo")}, WithPublishAddr(nsqds[2])); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&published[2]); n != 1 {
+		t.Fatalf("expected the added nsqd to be published to got %d publishes", n)
+	}
+
+	// the addresses are connected to once disconnected
+	b.Disconnect()
+	if err := b.UpdateAddrs(nsqds[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if got := addrs(b.p); !reflect.DeepEqual(got, nsqds[:1]) {
+		t.Fatalf("expected producers of %v got %v", nsqds[:1], got)
+	}
+}
+
+func TestUpdateAddrsPing(t *testing.T) {
+	var published, failing int32
+	l := pubNSQD(t, &published, &failing)
+	defer l.Close()
+
+	// the added nsqd accepts but never replies, holding up its ping
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := silent.Accept()
+		if err != nil {
+			return
+		}
+		accepted <- c
+	}()
+
+	b := NewBroker(broker.Addrs(l.Addr().String())).(*nsqBroker)
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- b.UpdateAddrs([]string{l.Addr().String(), silent.Addr().String()})
+	}()
+
+	var c net.Conn
+	select {
+	case c = <-accepted:
+	case <-time.After(time.Second):
+		t.Fatal("expected the added nsqd to be pinged")
+	}
+
+	// publishing isn't held up by the ping
+	errs := make(chan error, 1)
+	go func() {
+		errs <- b.Publish("foo", &broker.Message{}, WithPublishAddr(l.Addr().String()))
+	}()
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the publish not to wait for the ping")
+	}
+
+	c.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the update to return")
+	}
+	if got := addrs(b.p); len(got) != 2 {
+		t.Fatalf("expected producers of both nsqds got %v", got)
+	}
+}
+
+func TestNSQDDiscovery(t *testing.T) {
+	var published, failing [2]int32
+	var nodes []*peerInfo
+	for i := range published {
+		l := pubNSQD(t, &published[i], &failing[i])
+		defer l.Close()
+		addr := l.Addr().(*net.TCPAddr)
+		nodes = append(nodes, &peerInfo{BroadcastAddress: addr.IP.String(), TCPPort: addr.Port})
+	}
+
+	var mu sync.Mutex
+	registered := nodes[:1]
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/nodes" {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		b, _ := json.Marshaler{}.Marshal(&lookupResp{Producers: registered})
+		mu.Unlock()
+		w.Header().Set("X-NSQ-Content-Type", "nsq; version=1.0")
+		w.Write(b)
+	}))
+	defer ts.Close()
+
+	b := NewBroker(
+		// the broker address is only used if lookupd doesn't answer
+		broker.Addrs("127.0.0.1:1"),
+		WithLookupdAddrs([]string{strings.TrimPrefix(ts.URL, "http://")}),
+		WithConsumerOpts([]string{"lookupd_poll_interval,20ms"}),
+		WithNSQDDiscovery(),
+	).(*nsqBroker)
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	producers := func() []string {
+		b.Lock()
+		defer b.Unlock()
+		return addrs(b.p)
+	}
+
+	addr := func(p *peerInfo) string {
+		return net.JoinHostPort(p.BroadcastAddress, strconv.Itoa(p.TCPPort))
+	}
+	if got := producers(); !reflect.DeepEqual(got, []string{addr(nodes[0])}) {
+		t.Fatalf("expected the producer of the registered nsqd got %v", got)
+	}
+
+	// the nsqd is replaced
+	mu.Lock()
+	registered = nodes[1:]
+	mu.Unlock()
+
+	expected := []string{addr(nodes[1])}
+	for i := 0; !reflect.DeepEqual(producers(), expected); i++ {
+		if i == 50 {
+			t.Fatalf("expected the producer of the new nsqd got %v", producers())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err := b.Publish("foo", &broker.Message{Body: []byte("foo")}); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&published[1]); n != 1 {
+		t.Fatalf("expected the new nsqd to be published to got %d publishes", n)
+	}
+}
+
+func TestAuthSecret(t *testing.T) {
+	l := authNSQD(t)
+	defer l.Close()
+
+	b := NewBroker(broker.Addrs(l.Addr().String()), WithAuthSecret("secret")).(*nsqBroker)
+	if b.config.AuthSecret != "secret" {
+		t.Fatalf("expected the auth secret to be set got %q", b.config.AuthSecret)
+	}
+
+	for _, b := range []broker.Broker{b, NewBroker(broker.Addrs(l.Addr().String()))} {
+		err := b.Connect()
+		if err == nil {
+			b.Disconnect()
+			t.Fatal("expected the connection to be rejected")
+		}
+
+		var authErr *AuthError
+		if !errors.As(err, &authErr) || authErr.Addr != l.Addr().String() {
+			t.Fatalf("expected an auth error got %v", err)
+		}
+	}
+
+	if err := authError("127.0.0.1:4150", nsq.ErrProtocol{Reason: "E_UNAUTHORIZED AUTH no permissions"}); !errors.As(err, new(*AuthError)) {
+		t.Fatalf("expected an auth error for an unauthorized publish got %v", err)
+	}
+	if err := authError("127.0.0.1:4150", errors.New("connection refused")); errors.As(err, new(*AuthError)) {
+		t.Fatalf("expected other errors not to be auth errors got %v", err)
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	c := json.Marshaler{}
+	msg := &broker.Message{
+		Header: map[string]string{"Micro-Id": "1", "Content-Type": "application/json"},
+		Body:   []byte(`{"foo":"bar"}`),
+	}
+
+	for _, e := range []Envelope{EnvelopeCodec, EnvelopeHeader, EnvelopeBody} {
+		b, err := e.encode(c, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var m broker.Message
+		if err := e.decode(c, b, &m); err != nil {
+			t.Fatal(err)
+		}
+		if string(m.Body) != string(msg.Body) {
+			t.Fatalf("%s: expected body %s got %s", e, msg.Body, m.Body)
+		}
+
+		header := msg.Header
+		if e == EnvelopeBody {
+			header = map[string]string{}
+		}
+		if !reflect.DeepEqual(m.Header, header) {
+			t.Fatalf("%s: expected header %v got %v", e, header, m.Header)
+		}
+	}
+
+	// other consumers read the body after the prefix, or all of it
+	b, _ := EnvelopeHeader.encode(c, msg)
+	if !bytes.HasSuffix(b, msg.Body) {
+		t.Fatalf("expected the body after the header prefix got %q", b)
+	}
+	if b, _ := EnvelopeBody.encode(c, msg); string(b) != string(msg.Body) {
+		t.Fatalf("expected the raw body got %q", b)
+	}
+
+	// messages of other producers have no prefix
+	var m broker.Message
+	if err := EnvelopeHeader.decode(c, []byte("raw"), &m); err != nil || string(m.Body) != "raw" || len(m.Header) != 0 {
+		t.Fatalf("expected a message without a prefix to be read as the body got %+v %v", m, err)
+	}
+}
+
+func TestRawPayload(t *testing.T) {
+	b := NewBroker(WithLookupdAddrs([]string{"127.0.0.1:4161"})).(*nsqBroker)
+
+	if e := b.publishEnvelope([]broker.PublishOption{WithPublishEnvelope(EnvelopeBody)}); e != EnvelopeBody {
+		t.Fatalf("expected the publish envelope to override the broker's got %s", e)
+	}
+	if e := b.publishEnvelope(nil); e != EnvelopeCodec {
+		t.Fatalf("expected the broker envelope got %s", e)
+	}
+
+	var body []byte
+	handler := func(e broker.Event) error {
+		body = e.Message().Body
+		return nil
+	}
+
+	s, err := b.Subscribe("foo", handler, WithSubscribeEnvelope(EnvelopeBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+
+	// the body isn't decoded with the codec
+	nm := nsq.NewMessage(nsq.MessageID{}, []byte("raw"))
+	nm.Delegate = &testDelegate{}
+
+	if err := s.(*subscriber).h(nm); err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "raw" {
+		t.Fatalf("expected the raw body got %q", body)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	s := NewFullJitterStrategy(100*time.Millisecond, time.Second)
+
+	b := NewBroker(
+		WithConsumerOpts([]string{"max_backoff_duration=1m", "backoff_multiplier=2s"}),
+		WithBackoffStrategy(s),
+		WithMaxBackoffDuration(time.Second),
+		WithBackoffMultiplier(100*time.Millisecond),
+	).(*nsqBroker)
+
+	// the typed options override the consumer opts
+	if b.config.BackoffStrategy != s || b.config.MaxBackoffDuration != time.Second || b.config.BackoffMultiplier != 100*time.Millisecond {
+		t.Fatalf("expected the backoff options to be set got %v %v %v", b.config.BackoffStrategy, b.config.MaxBackoffDuration, b.config.BackoffMultiplier)
+	}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		limit := 100 * time.Millisecond << uint(attempt)
+		if limit > time.Second {
+			limit = time.Second
+		}
+		for i := 0; i < 100; i++ {
+			if d := s.Calculate(attempt); d < 0 || d >= limit {
+				t.Fatalf("expected attempt %d to back off less than %v got %v", attempt, limit, d)
+			}
+		}
+	}
+
+	if d := NewFullJitterStrategy(0, 0).Calculate(3); d != 0 {
+		t.Fatalf("expected no backoff without a multiplier got %v", d)
+	}
+}
+
+func TestSampleRate(t *testing.T) {
+	b := NewBroker(WithLookupdAddrs([]string{"127.0.0.1:4161"}))
+
+	s, err := b.Subscribe("foo", func(broker.Event) error { return nil }, WithSampleRate(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+
+	if r := s.(*subscriber).config.SampleRate; r != 10 {
+		t.Fatalf("expected a sample rate of 10 got %d", r)
+	}
+
+	if _, err := b.Subscribe("foo", func(broker.Event) error { return nil }, WithSampleRate(100)); err == nil {
+		t.Fatal("expected an invalid sample rate to be rejected")
+	}
+}
+
+func TestTopicCreation(t *testing.T) {
+	var mtx sync.Mutex
+	var created []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		mtx.Lock()
+		created = append(created, r.URL.Path+"?"+r.URL.RawQuery)
+		mtx.Unlock()
+	}))
+	defer srv.Close()
+
+	var published, failing int32
+	l := pubNSQD(t, &published, &failing)
+	defer l.Close()
+
+	b := NewBroker(
+		broker.Addrs(l.Addr().String()),
+		WithLookupdAddrs([]string{"127.0.0.1:4161"}),
+		WithTopicCreation("foo", "bar"),
+		WithNSQDHTTPAddrs(srv.URL),
+	)
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	// ephemeral channels aren't created
+	for _, queue := range []string{"", "baz"} {
+		s, err := b.Subscribe("qux", func(broker.Event) error { return nil }, broker.Queue(queue))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Unsubscribe()
+	}
+
+	expected := []string{
+		"/topic/create?topic=foo",
+		"/topic/create?topic=bar",
+		"/topic/create?topic=qux",
+		"/topic/create?topic=qux",
+		"/channel/create?channel=baz&topic=qux",
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	if !reflect.DeepEqual(created, expected) {
+		t.Fatalf("expected %v to be created got %v", expected, created)
+	}
+
+	// the http addresses default to the nsqd hosts
+	if addrs := NewBroker(broker.Addrs("10.0.0.1:4150")).(*nsqBroker).httpAddrs(); addrs[0] != "10.0.0.1:4151" {
+		t.Fatalf("expected the default http address got %v", addrs)
+	}
+
+	// errors creating topics are returned
+	srv.Close()
+	if _, err := b.Subscribe("qux", func(broker.Event) error { return nil }); err == nil {
+		t.Fatal("expected an error creating the topic")
+	}
+}
+
+func TestCompression(t *testing.T) {
+	b := NewBroker(WithSnappy()).(*nsqBroker)
+	if !b.config.Snappy || b.config.Deflate {
+		t.Fatal("expected snappy compression")
+	}
+
+	// the last option is used
+	for _, b := range []*nsqBroker{
+		NewBroker(WithDeflate(9)).(*nsqBroker),
+		NewBroker(WithSnappy(), WithDeflate(9)).(*nsqBroker),
+	} {
+		if b.config.Snappy || !b.config.Deflate || b.config.DeflateLevel != 9 {
+			t.Fatalf("expected deflate compression at level 9 got %+v", b.config)
+		}
+		if err := b.config.Validate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	b := NewBroker(WithLookupdAddrs([]string{"127.0.0.1:4161"})).(*nsqBroker)
+	body, _ := b.opts.Codec.Marshal(&broker.Message{Header: map[string]string{IDHeader: "published"}, Body: []byte("foo")})
+
+	var header map[string]string
+	var md Metadata
+	handler := func(e broker.Event) error {
+		header = e.Message().Header
+		md = e.(Metadata)
+		return nil
+	}
+
+	s, err := b.Subscribe("foo", handler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+
+	id := nsq.MessageID{'0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'a', 'b', 'c', 'd', 'e', 'f'}
+	nm := nsq.NewMessage(id, body)
+	nm.Delegate = &testDelegate{}
+	nm.Attempts = 3
+	nm.Timestamp = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()
+
+	if err := s.(*subscriber).h(nm); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		IDHeader:               "0123456789abcdef",
+		DeliveryAttemptsHeader: "3",
+		TimestampHeader:        strconv.FormatInt(nm.Timestamp, 10),
+	}
+	if !reflect.DeepEqual(header, expected) {
+		t.Fatalf("expected the header %v got %v", expected, header)
+	}
+	if md.ID() != "0123456789abcdef" || md.Attempts() != 3 || !md.Timestamp().Equal(time.Unix(0, nm.Timestamp)) {
+		t.Fatalf("expected the metadata of the message got %s %d %v", md.ID(), md.Attempts(), md.Timestamp())
+	}
+}
+
+// finishDelegate counts down the wait group when a message is finished
+type finishDelegate struct {
+	testDelegate
+	wg *sync.WaitGroup
+}
+
+func (d *finishDelegate) OnFinish(*nsq.Message) { d.wg.Done() }
+
+func TestOrderingKey(t *testing.T) {
+	b := NewBroker(WithLookupdAddrs([]string{"127.0.0.1:4161"})).(*nsqBroker)
+
+	var mtx sync.Mutex
+	var running, maxRunning int
+	handled := make(map[string][]string)
+
+	handler := func(e broker.Event) error {
+		mtx.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mtx.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mtx.Lock()
+		running--
+		key := e.Message().Header["Key"]
+		handled[key] = append(handled[key], string(e.Message().Body))
+		mtx.Unlock()
+		return nil
+	}
+
+	s, err := b.Subscribe("foo", handler, WithOrderingKey("Key"), WithConcurrentHandlers(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unsubscribe()
+
+	// one handler receives the messages so they're dispatched in order
+	sub := s.(*subscriber)
+	if sub.n != 1 {
+		t.Fatalf("expected 1 handler got %d", sub.n)
+	}
+
+	var wg sync.WaitGroup
+	keys := []string{"a", "b", "c", "d"}
+	for i := 0; i < 40; i++ {
+		key := keys[i%len(keys)]
+		body, _ := b.opts.Codec.Marshal(&broker.Message{
+			Header: map[string]string{"Key": key},
+			Body:   []byte(strconv.Itoa(i)),
+		})
+
+		wg.Add(1)
+		nm := nsq.NewMessage(nsq.MessageID{}, body)
+		nm.Delegate = &finishDelegate{wg: &wg}
+
+		if err := sub.h(nm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	for i, key := range keys {
+		var expected []string
+		for j := i; j < 40; j += len(keys) {
+			expected = append(expected, strconv.Itoa(j))
+		}
+		if !reflect.DeepEqual(handled[key], expected) {
+			t.Fatalf("expected the messages of key %s in order %v got %v", key, expected, handled[key])
+		}
+	}
+
+	if maxRunning < 2 || maxRunning > 4 {
+		t.Fatalf("expected keys to be handled concurrently by up to 4 handlers got %d", maxRunning)
+	}
+}
+
+func TestPublishBatch(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// the topic and bodies of the multi-publishes to the nsqd
+	published := make(chan []string, 1)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				r := bufio.NewReader(c)
+				if _, err := r.Discard(len(nsq.MagicV2)); err != nil {
+					return
+				}
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					cmd := strings.Fields(line)
+					if len(cmd) == 0 || cmd[0] == "NOP" {
+						continue
+					}
+					var size int32
+					if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+						return
+					}
+					body := make([]byte, size)
+					if _, err := io.ReadFull(r, body); err != nil {
+						return
+					}
+					if cmd[0] == "MPUB" {
+						// the number of messages followed by each size and body
+						pub := []string{cmd[1]}
+						buf := bytes.NewReader(body)
+						var count int32
+						binary.Read(buf, binary.BigEndian, &count)
+						for i := int32(0); i < count; i++ {
+							binary.Read(buf, binary.BigEndian, &size)
+							m := make([]byte, size)
+							io.ReadFull(buf, m)
+							pub = append(pub, string(m))
+						}
+						published <- pub
+					}
+					frame(c, nsq.FrameTypeResponse, "OK")
+				}
+			}()
+		}
+	}()
+
+	b := NewBroker(broker.Addrs(l.Addr().String())).(*nsqBroker)
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	var bp BatchPublisher = b
+
+	// an empty batch isn't published
+	if err := bp.PublishBatch("foo", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := []*broker.Message{
+		{Header: map[string]string{"id": "1"}, Body: []byte("foo")},
+		{Header: map[string]string{"id": "2"}, Body: []byte("bar")},
+		{Header: map[string]string{"id": "3"}, Body: []byte("baz")},
+	}
+
+	// nsq can't defer a multi-publish
+	if err := bp.PublishBatch("foo", messages, WithDeferredPublish(time.Second)); err == nil {
+		t.Fatal("expected the deferred batch to fail")
+	}
+
+	if err := bp.PublishBatch("foo", messages); err != nil {
+		t.Fatal(err)
+	}
+
+	var pub []string
+	select {
+	case pub = <-published:
+	case <-time.After(time.Second):
+		t.Fatal("expected the batch to be published")
+	}
+	if pub[0] != "foo" {
+		t.Fatalf("expected the publish to foo got %s", pub[0])
+	}
+	if len(pub)-1 != len(messages) {
+		t.Fatalf("expected %d messages in one MPUB got %d", len(messages), len(pub)-1)
+	}
+	for i, body := range pub[1:] {
+		var m broker.Message
+		if err := b.publishEnvelope(nil).decode(b.opts.Codec, []byte(body), &m); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m.Header, messages[i].Header) || !bytes.Equal(m.Body, messages[i].Body) {
+			t.Fatalf("expected message %d to be %v got %v", i, messages[i], m)
+		}
+	}
+
+	// neither the empty nor the deferred batch reached the nsqd
+	select {
+	case pub = <-published:
+		t.Fatalf("expected one MPUB got another with %d messages", len(pub)-1)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
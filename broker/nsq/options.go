@@ -2,9 +2,12 @@ package nsq
 
 import (
 	"context"
+	"crypto/tls"
+	"net/http"
 	"time"
 
 	"github.com/micro/go-micro/v2/broker"
+	log "github.com/micro/go-micro/v2/logger"
 	nsq "github.com/nsqio/go-nsq"
 )
 
@@ -14,6 +17,36 @@ type asyncPublishKey struct{}
 type deferredPublishKey struct{}
 type lookupdAddrsKey struct{}
 type consumerOptsKey struct{}
+type resubscribeKey struct{}
+type maxAttemptsKey struct{}
+type deadLetterTopicKey struct{}
+type requeueDelayKey struct{}
+type touchIntervalKey struct{}
+type sampleRateKey struct{}
+type lookupdTLSConfigKey struct{}
+type lookupdTimeoutKey struct{}
+type lookupdMaxConnsKey struct{}
+type lookupdHTTPClientKey struct{}
+type channelPrefixKey struct{}
+type serviceChannelKey struct{}
+type durableChannelsKey struct{}
+type eventHandlerKey struct{}
+type loggerKey struct{}
+type metricsKey struct{}
+type selectorKey struct{}
+type publishAddrKey struct{}
+type logLevelsKey struct{}
+type authSecretKey struct{}
+type envelopeKey struct{}
+type orderingKeyKey struct{}
+type publishRetriesKey struct{}
+type compressionKey struct{}
+type createTopicsKey struct{}
+type nsqdHTTPAddrsKey struct{}
+type nsqdDiscoveryKey struct{}
+type backoffStrategyKey struct{}
+type maxBackoffDurationKey struct{}
+type backoffMultiplierKey struct{}
 
 func WithConcurrentHandlers(n int) broker.SubscribeOption {
 	return func(o *broker.SubscribeOptions) {
@@ -33,6 +66,90 @@ func WithMaxInFlight(n int) broker.SubscribeOption {
 	}
 }
 
+// WithMaxAttempts sets the attempts to handle a message before it's dropped,
+// or dead-lettered if WithDeadLetterTopic is set
+func WithMaxAttempts(n uint16) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, maxAttemptsKey{}, n)
+	}
+}
+
+// WithDeadLetterTopic publishes messages which fail on their last attempt to the
+// topic instead of dropping them. The body consumed is published as it was with
+// the ErrorHeader, TopicHeader and AttemptsHeader in front of it, subscribers of
+// the topic read them with WithSubscribeEnvelope(EnvelopeHeader).
+func WithDeadLetterTopic(topic string) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, deadLetterTopicKey{}, topic)
+	}
+}
+
+// WithRequeueDelay requeues messages the handler returns an error for to be
+// redelivered after the delay, without backing off the consumer
+func WithRequeueDelay(d time.Duration) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, requeueDelayKey{}, d)
+	}
+}
+
+// WithTouchInterval touches messages at the interval while the handler runs, so
+// handlers taking longer than the msg timeout of nsqd don't have the message
+// redelivered. The interval should be well below the timeout, 60s by default.
+func WithTouchInterval(d time.Duration) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, touchIntervalKey{}, d)
+	}
+}
+
+// WithSampleRate has nsqd send the subscriber a sample of the messages of the channel,
+// the percentage from 1 to 99, e.g for a monitoring consumer on its own channel.
+// Zero sends every message.
+func WithSampleRate(pct int) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, sampleRateKey{}, pct)
+	}
+}
+
+// WithOrderingKey handles the messages with the same value of the header one at a
+// time in the order they're received, by a worker per value. The concurrent handlers
+// limit the messages handled at once and the max in flight the messages queued.
+// Messages are only received in order if they're published in order to one nsqd,
+// e.g with WithPublishAddr, and aren't requeued.
+func WithOrderingKey(header string) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, orderingKeyKey{}, header)
+	}
+}
+
+// WithSubscribeEnvelope overrides the envelope of the broker for the subscriber e.g
+// EnvelopeBody to consume the raw bytes published by services not using micro
+func WithSubscribeEnvelope(e Envelope) broker.SubscribeOption {
+	return func(o *broker.SubscribeOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, envelopeKey{}, e)
+	}
+}
+
 func WithAsyncPublish(doneChan chan *nsq.ProducerTransaction) broker.PublishOption {
 	return func(o *broker.PublishOptions) {
 		if o.Context == nil {
@@ -51,6 +168,28 @@ func WithDeferredPublish(delay time.Duration) broker.PublishOption {
 	}
 }
 
+// WithPublishAddr publishes the message to the nsqd address, which must be one of the
+// broker addresses, instead of the one chosen by the selector
+func WithPublishAddr(addr string) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, publishAddrKey{}, addr)
+	}
+}
+
+// WithPublishEnvelope overrides the envelope of the broker for the publish e.g
+// EnvelopeBody to publish the raw bytes of the body to services not using micro
+func WithPublishEnvelope(e Envelope) broker.PublishOption {
+	return func(o *broker.PublishOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, envelopeKey{}, e)
+	}
+}
+
 func WithLookupdAddrs(addrs []string) broker.Option {
 	return func(o *broker.Options) {
 		o.Context = context.WithValue(o.Context, lookupdAddrsKey{}, addrs)
@@ -62,3 +201,217 @@ func WithConsumerOpts(consumerOpts []string) broker.Option {
 		o.Context = context.WithValue(o.Context, consumerOptsKey{}, consumerOpts)
 	}
 }
+
+// WithTopicCreation creates the topics on every nsqd when connecting, and the topic
+// and durable channel of each subscriber when subscribing, with the http api of nsqd.
+// Messages published before a consumer discovers a topic then aren't missed. nsqd
+// doesn't authenticate the http api, only the tls config of the broker is used.
+func WithTopicCreation(topics ...string) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, createTopicsKey{}, topics)
+	}
+}
+
+// WithNSQDHTTPAddrs sets the http addresses of the nsqds topics are created with,
+// by default they're the hosts of the broker addresses on DefaultHTTPPort
+func WithNSQDHTTPAddrs(addrs ...string) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, nsqdHTTPAddrsKey{}, addrs)
+	}
+}
+
+// WithNSQDDiscovery publishes to the nsqds registered with lookupd instead of
+// the broker addresses, they're listed at the lookupd poll interval so nsqds
+// which are replaced are followed as with AddrUpdater. The addresses are only
+// used when connecting if no lookupd answers.
+func WithNSQDDiscovery() broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, nsqdDiscoveryKey{}, true)
+	}
+}
+
+// compression of the connections to nsqd, snappy or deflate at the level
+type compression struct {
+	snappy bool
+	level  int
+}
+
+// WithSnappy compresses the connections of producers and consumers to nsqd with
+// snappy, nsqd must be run with it enabled. It replaces WithDeflate.
+func WithSnappy() broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, compressionKey{}, compression{snappy: true, level: 6})
+	}
+}
+
+// WithDeflate compresses the connections of producers and consumers to nsqd with
+// deflate at the level from 1 to 9, nsqd must be run with it enabled and may lower
+// the level to its max. It replaces WithSnappy.
+func WithDeflate(level int) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, compressionKey{}, compression{level: level})
+	}
+}
+
+// WithBackoffStrategy sets the strategy consumers back off with after a handler
+// error, e.g NewFullJitterStrategy. The default is exponential.
+func WithBackoffStrategy(s nsq.BackoffStrategy) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, backoffStrategyKey{}, s)
+	}
+}
+
+// WithMaxBackoffDuration caps how long consumers back off for, the default is
+// 2 minutes. Zero resumes consuming right away.
+func WithMaxBackoffDuration(d time.Duration) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, maxBackoffDurationKey{}, d)
+	}
+}
+
+// WithBackoffMultiplier sets the unit of the exponential backoff strategy, the
+// default is 1 second
+func WithBackoffMultiplier(d time.Duration) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, backoffMultiplierKey{}, d)
+	}
+}
+
+// WithTLSConfig connects to nsqd with tls using the config, including any
+// client certificates. The http lookupd addresses must use the https scheme.
+func WithTLSConfig(c *tls.Config) broker.Option {
+	return func(o *broker.Options) {
+		o.Secure = true
+		o.TLSConfig = c
+	}
+}
+
+// WithAuthSecret sets the secret producers and consumers authenticate with to
+// nsqd run with --auth-http-address. Failures are returned as an *AuthError.
+func WithAuthSecret(secret string) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, authSecretKey{}, secret)
+	}
+}
+
+// WithEnvelope sets how messages are encoded in the body of nsq messages, the
+// default EnvelopeCodec is only read by micro consumers
+func WithEnvelope(e Envelope) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, envelopeKey{}, e)
+	}
+}
+
+// WithPublishRetries sets how many other nsqds a failed publish is retried on, the
+// default is DefaultPublishRetries. Publishes with WithPublishAddr aren't retried.
+func WithPublishRetries(n int) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, publishRetriesKey{}, n)
+	}
+}
+
+// WithResubscribe resubscribes the subscribers when the broker connects
+// again after Disconnect. Without it Disconnect unsubscribes them.
+func WithResubscribe() broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, resubscribeKey{}, true)
+	}
+}
+
+// WithLookupdTLSConfig queries lookupd over https with the config, such as a
+// custom CA for a TLS terminator in front of lookupd. Addresses without a scheme use https.
+func WithLookupdTLSConfig(c *tls.Config) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, lookupdTLSConfigKey{}, c)
+	}
+}
+
+// WithLookupdTimeout sets the timeout of requests to lookupd, the default is 2 seconds
+func WithLookupdTimeout(d time.Duration) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, lookupdTimeoutKey{}, d)
+	}
+}
+
+// WithLookupdMaxConns limits the concurrent connections to each lookupd
+// shared by all the subscribers
+func WithLookupdMaxConns(n int) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, lookupdMaxConnsKey{}, n)
+	}
+}
+
+// WithLookupdHTTPClient queries lookupd with the client, it overrides the
+// other lookupd options
+func WithLookupdHTTPClient(c *http.Client) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, lookupdHTTPClientKey{}, c)
+	}
+}
+
+// WithChannelPrefix prefixes the channels of subscriptions without a queue,
+// so they're easy to tell apart in nsqadmin
+func WithChannelPrefix(prefix string) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, channelPrefixKey{}, prefix)
+	}
+}
+
+// WithServiceChannel names the channels of subscriptions without a queue after the
+// service instead of a random id. Instances of the service share the channel, so
+// each message is handled by one of them, even after restarting if it's durable.
+func WithServiceChannel(service string) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, serviceChannelKey{}, service)
+	}
+}
+
+// WithDurableChannels doesn't add the #ephemeral suffix to the channels of
+// subscriptions without a queue, so nsqd keeps their messages while there are
+// no consumers. Durable random channels are left behind by restarts.
+func WithDurableChannels() broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, durableChannelsKey{}, true)
+	}
+}
+
+// WithEventHandler calls the handler when producers and consumers connect,
+// disconnect and reconnect, when handling a message fails and when querying
+// lookupd fails, so services can alert on them and report their health.
+func WithEventHandler(h EventHandler) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, eventHandlerKey{}, h)
+	}
+}
+
+// WithLogger writes the go-nsq logs of the producers and consumers to the
+// logger, the default logger is used without it
+func WithLogger(l log.Logger) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, loggerKey{}, l)
+	}
+}
+
+// WithLogLevels overrides the logger levels go-nsq log levels are written at e.g
+// map[nsq.LogLevel]logger.Level{nsq.LogLevelInfo: logger.DebugLevel} to quieten them
+func WithLogLevels(levels map[nsq.LogLevel]log.Level) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, logLevelsKey{}, levels)
+	}
+}
+
+// WithMetrics calls the metrics with the latency and result of each publish
+// and each message handled
+func WithMetrics(m Metrics) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, metricsKey{}, m)
+	}
+}
+
+// WithSelector sets the selector choosing the nsqd each message is published to
+// e.g HashSelector to shard topics across them
+func WithSelector(s Selector) broker.Option {
+	return func(o *broker.Options) {
+		o.Context = context.WithValue(o.Context, selectorKey{}, s)
+	}
+}
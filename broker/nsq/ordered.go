@@ -0,0 +1,69 @@
+package nsq
+
+import (
+	"sync"
+)
+
+// ordered runs the handling of messages by a worker per key, so messages with
+// the same key are handled one at a time in the order they're dispatched
+type ordered struct {
+	// limits the messages handled at once to the concurrency
+	sem chan struct{}
+
+	sync.Mutex
+	// messages of each key with a worker, waiting to be handled
+	queues map[string][]func()
+}
+
+func newOrdered(concurrency int) *ordered {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &ordered{
+		sem:    make(chan struct{}, concurrency),
+		queues: make(map[string][]func()),
+	}
+}
+
+// dispatch queues the handling of a message for the worker of its key, which is
+// started if the key has none. Messages without a key are handled in any order.
+func (o *ordered) dispatch(key string, fn func()) {
+	if len(key) == 0 {
+		go o.run(fn)
+		return
+	}
+
+	o.Lock()
+	q, ok := o.queues[key]
+	o.queues[key] = append(q, fn)
+	o.Unlock()
+
+	if !ok {
+		go o.work(key)
+	}
+}
+
+// work handles the messages of the key until its queue is empty
+func (o *ordered) work(key string) {
+	for {
+		o.Lock()
+		q := o.queues[key]
+		if len(q) == 0 {
+			delete(o.queues, key)
+			o.Unlock()
+			return
+		}
+		fn := q[0]
+		q[0] = nil
+		o.queues[key] = q[1:]
+		o.Unlock()
+
+		o.run(fn)
+	}
+}
+
+func (o *ordered) run(fn func()) {
+	o.sem <- struct{}{}
+	fn()
+	<-o.sem
+}
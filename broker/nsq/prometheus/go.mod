@@ -0,0 +1,13 @@
+module github.com/micro/go-plugins/broker/nsq/prometheus/v2
+
+go 1.13
+
+require (
+	github.com/micro/go-micro/v2 v2.9.1
+	github.com/micro/go-plugins/broker/nsq/v2 v2.9.1
+	github.com/prometheus/client_golang v1.3.0
+)
+
+replace github.com/micro/go-plugins/broker/nsq/v2 => ../
+
+replace github.com/micro/go-plugins/broker/tests/v2 => ../../tests
@@ -0,0 +1,26 @@
+package prometheus
+
+// Options of the collector
+type Options struct {
+	// Namespace of the metrics
+	Namespace string
+	// Buckets of the latency histograms in seconds
+	Buckets []float64
+}
+
+// Option sets an option of the collector
+type Option func(o *Options)
+
+// Namespace sets the namespace of the metrics
+func Namespace(n string) Option {
+	return func(o *Options) {
+		o.Namespace = n
+	}
+}
+
+// Buckets sets the latency histogram buckets in seconds
+func Buckets(b ...float64) Option {
+	return func(o *Options) {
+		o.Buckets = b
+	}
+}
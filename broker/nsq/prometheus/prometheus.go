@@ -0,0 +1,159 @@
+// Package prometheus exports the stats of the nsq broker, and the latency of
+// its publishes and handled messages, as prometheus metrics.
+package prometheus
+
+import (
+	"errors"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-plugins/broker/nsq/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// DefaultNamespace of the metrics
+	DefaultNamespace = "micro"
+	// DefaultBuckets of the latency histograms in seconds
+	DefaultBuckets = prometheus.DefBuckets
+
+	subsystem = "nsq"
+)
+
+// Collector collects the stats of an nsq broker. It's a prometheus.Collector
+// and the nsq.Metrics of the broker.
+type Collector struct {
+	stats func() *nsq.Stats
+
+	published *prometheus.HistogramVec
+	handled   *prometheus.HistogramVec
+
+	producerPublished   *prometheus.Desc
+	producerErrors      *prometheus.Desc
+	consumerReceived    *prometheus.Desc
+	consumerFinished    *prometheus.Desc
+	consumerRequeued    *prometheus.Desc
+	consumerConnections *prometheus.Desc
+}
+
+type consumerKey struct {
+	topic   string
+	channel string
+}
+
+func status(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// Published observes the latency of a publish
+func (c *Collector) Published(topic string, d time.Duration, err error) {
+	c.published.WithLabelValues(topic, status(err)).Observe(d.Seconds())
+}
+
+// Handled observes the latency of a handled message
+func (c *Collector) Handled(topic, channel string, d time.Duration, err error) {
+	c.handled.WithLabelValues(topic, channel, status(err)).Observe(d.Seconds())
+}
+
+// Describe sends the descriptions of the metrics
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.published.Describe(ch)
+	c.handled.Describe(ch)
+
+	for _, d := range []*prometheus.Desc{
+		c.producerPublished,
+		c.producerErrors,
+		c.consumerReceived,
+		c.consumerFinished,
+		c.consumerRequeued,
+		c.consumerConnections,
+	} {
+		ch <- d
+	}
+}
+
+// Collect sends the latency histograms and the current stats of the broker
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.published.Collect(ch)
+	c.handled.Collect(ch)
+
+	stats := c.stats()
+
+	for _, p := range stats.Producers {
+		ch <- prometheus.MustNewConstMetric(c.producerPublished, prometheus.CounterValue, float64(p.Published), p.Addr)
+		ch <- prometheus.MustNewConstMetric(c.producerErrors, prometheus.CounterValue, float64(p.Errors), p.Addr)
+	}
+
+	// subscribers of the same topic and channel are summed as their labels are the same
+	consumers := make(map[consumerKey]nsq.ConsumerStats)
+	for _, s := range stats.Consumers {
+		k := consumerKey{s.Topic, s.Channel}
+		cs := consumers[k]
+		cs.Received += s.Received
+		cs.Finished += s.Finished
+		cs.Requeued += s.Requeued
+		cs.Connections += s.Connections
+		consumers[k] = cs
+	}
+
+	for k, s := range consumers {
+		ch <- prometheus.MustNewConstMetric(c.consumerReceived, prometheus.CounterValue, float64(s.Received), k.topic, k.channel)
+		ch <- prometheus.MustNewConstMetric(c.consumerFinished, prometheus.CounterValue, float64(s.Finished), k.topic, k.channel)
+		ch <- prometheus.MustNewConstMetric(c.consumerRequeued, prometheus.CounterValue, float64(s.Requeued), k.topic, k.channel)
+		ch <- prometheus.MustNewConstMetric(c.consumerConnections, prometheus.GaugeValue, float64(s.Connections), k.topic, k.channel)
+	}
+}
+
+// NewCollector returns the collector of the nsq broker. It initialises the broker
+// with the collector as its metrics, register it with prometheus to export them.
+func NewCollector(b broker.Broker, opts ...Option) (*Collector, error) {
+	s, ok := b.(interface{ Stats() *nsq.Stats })
+	if !ok {
+		return nil, errors.New("not an nsq broker")
+	}
+
+	options := Options{
+		Namespace: DefaultNamespace,
+		Buckets:   DefaultBuckets,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(options.Namespace, subsystem, name), help, labels, nil)
+	}
+
+	c := &Collector{
+		stats: s.Stats,
+		published: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: options.Namespace,
+			Subsystem: subsystem,
+			Name:      "publish_duration_seconds",
+			Help:      "Publish latency in seconds, partitioned by topic and status",
+			Buckets:   options.Buckets,
+		}, []string{"topic", "status"}),
+		handled: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: options.Namespace,
+			Subsystem: subsystem,
+			Name:      "handle_duration_seconds",
+			Help:      "Message handling latency in seconds, partitioned by topic, channel and status",
+			Buckets:   options.Buckets,
+		}, []string{"topic", "channel", "status"}),
+		producerPublished:   desc("producer_published_total", "Messages published, partitioned by nsqd", "addr"),
+		producerErrors:      desc("producer_errors_total", "Publish errors, partitioned by nsqd", "addr"),
+		consumerReceived:    desc("consumer_received_total", "Messages received, partitioned by topic and channel", "topic", "channel"),
+		consumerFinished:    desc("consumer_finished_total", "Messages finished, partitioned by topic and channel", "topic", "channel"),
+		consumerRequeued:    desc("consumer_requeued_total", "Messages requeued, partitioned by topic and channel", "topic", "channel"),
+		consumerConnections: desc("consumer_connections", "Connections to nsqd, partitioned by topic and channel", "topic", "channel"),
+	}
+
+	if err := b.Init(nsq.WithMetrics(c)); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
@@ -0,0 +1,68 @@
+package prometheus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/broker/memory"
+	"github.com/micro/go-plugins/broker/nsq/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestCollector(t *testing.T) {
+	if _, err := NewCollector(memory.NewBroker()); err == nil {
+		t.Fatal("expected an error for a broker which isn't nsq")
+	}
+
+	// lookupd is only queried in the background so no server is needed
+	b := nsq.NewBroker(nsq.WithLookupdAddrs([]string{"127.0.0.1:4161"}))
+
+	c, err := NewCollector(b, Namespace("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		s, err := b.Subscribe("foo", func(broker.Event) error { return nil }, broker.Queue("bar"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Unsubscribe()
+	}
+
+	c.Published("foo", time.Millisecond, nil)
+	c.Published("foo", time.Millisecond, errors.New("failed"))
+	c.Handled("foo", "bar", time.Millisecond, nil)
+
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatal(err)
+	}
+
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	families := make(map[string]int)
+	for _, mf := range mfs {
+		families[mf.GetName()] = len(mf.GetMetric())
+	}
+
+	expected := map[string]int{
+		// partitioned by status
+		"test_nsq_publish_duration_seconds": 2,
+		"test_nsq_handle_duration_seconds":  1,
+		// the subscribers of the same channel are summed
+		"test_nsq_consumer_received_total": 1,
+		"test_nsq_consumer_connections":    1,
+	}
+
+	for name, n := range expected {
+		if families[name] != n {
+			t.Fatalf("expected %d %s metrics got %d", n, name, families[name])
+		}
+	}
+}
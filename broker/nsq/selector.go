@@ -0,0 +1,23 @@
+package nsq
+
+import (
+	"hash/fnv"
+	"math/rand"
+)
+
+// Selector returns the address of the nsqd a message to the topic is published
+// to, from the addresses of the producers
+type Selector func(topic string, addrs []string) string
+
+// RandomSelector publishes to a random nsqd, it's the default
+func RandomSelector(topic string, addrs []string) string {
+	return addrs[rand.Intn(len(addrs))]
+}
+
+// HashSelector publishes each topic to the same nsqd, sharding the topics across
+// them. Topics move between nsqds when the addresses change.
+func HashSelector(topic string, addrs []string) string {
+	h := fnv.New32a()
+	h.Write([]byte(topic))
+	return addrs[h.Sum32()%uint32(len(addrs))]
+}
@@ -0,0 +1,79 @@
+package nsq
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is called with the latency and result of each publish and each message
+// handled, e.g to export them as histograms. It's called from the broker goroutines
+// so it mustn't block.
+type Metrics interface {
+	// Published is called after each publish
+	Published(topic string, d time.Duration, err error)
+	// Handled is called after each message is handled
+	Handled(topic, channel string, d time.Duration, err error)
+}
+
+// Stats of the producers and consumers of the broker
+type Stats struct {
+	Producers []ProducerStats
+	Consumers []ConsumerStats
+}
+
+// ProducerStats are the publishes to an nsqd since the broker connected
+type ProducerStats struct {
+	Addr      string
+	Published uint64
+	Errors    uint64
+}
+
+// ConsumerStats are the messages of a subscriber since it connected
+type ConsumerStats struct {
+	Topic    string
+	Channel  string
+	Received uint64
+	Finished uint64
+	Requeued uint64
+	// Connections to nsqd
+	Connections int
+}
+
+// Stats returns the stats of the producers and consumers of the broker, assert
+// the broker to interface{ Stats() *nsq.Stats } to get them
+func (n *nsqBroker) Stats() *Stats {
+	n.Lock()
+	defer n.Unlock()
+
+	stats := &Stats{
+		Producers: make([]ProducerStats, 0, len(n.p)),
+		Consumers: make([]ConsumerStats, 0, len(n.c)),
+	}
+
+	for _, p := range n.p {
+		stats.Producers = append(stats.Producers, ProducerStats{
+			Addr:      p.addr,
+			Published: atomic.LoadUint64(&p.published),
+			Errors:    atomic.LoadUint64(&p.errors),
+		})
+	}
+
+	for _, s := range n.c {
+		// disconnected subscribers kept to resubscribe have no stats
+		if s.c == nil {
+			continue
+		}
+
+		cs := s.c.Stats()
+		stats.Consumers = append(stats.Consumers, ConsumerStats{
+			Topic:       s.topic,
+			Channel:     s.channel,
+			Received:    cs.MessagesReceived,
+			Finished:    cs.MessagesFinished,
+			Requeued:    cs.MessagesRequeued,
+			Connections: cs.Connections,
+		})
+	}
+
+	return stats
+}
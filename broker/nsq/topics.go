@@ -0,0 +1,84 @@
+package nsq
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	// DefaultHTTPPort of nsqd, topics are created at the host of each nsqd address
+	// on the port unless WithNSQDHTTPAddrs is set
+	DefaultHTTPPort = 4151
+)
+
+// httpAddrs returns the http addresses of the nsqds
+func (n *nsqBroker) httpAddrs() []string {
+	if len(n.nsqdHTTPAddrs) > 0 {
+		return n.nsqdHTTPAddrs
+	}
+
+	addrs := make([]string, 0, len(n.addrs))
+	for _, addr := range n.addrs {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(DefaultHTTPPort)))
+	}
+	return addrs
+}
+
+// post makes a request to the http api of a nsqd
+func (n *nsqBroker) post(addr, path string, v url.Values) error {
+	if !strings.Contains(addr, "://") {
+		scheme := "http://"
+		if n.opts.Secure || n.opts.TLSConfig != nil {
+			scheme = "https://"
+		}
+		addr = scheme + addr
+	}
+
+	u, err := url.Parse(addr)
+	if err != nil {
+		return err
+	}
+	u.Path = path
+	u.RawQuery = v.Encode()
+
+	rsp, err := n.nsqdClient.Post(u.String(), "", nil)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	if err != nil {
+		return err
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("got response %s %q", rsp.Status, b)
+	}
+	return nil
+}
+
+// create creates the topic on every nsqd, and the channel of it unless it's
+// empty or ephemeral, nsqd deletes them once their consumers disconnect
+func (n *nsqBroker) create(topic, channel string) error {
+	for _, addr := range n.httpAddrs() {
+		if err := n.post(addr, "/topic/create", url.Values{"topic": {topic}}); err != nil {
+			return fmt.Errorf("error creating topic %s on nsqd %s: %v", topic, addr, err)
+		}
+
+		if len(channel) == 0 || strings.HasSuffix(channel, ephemeralSuffix) {
+			continue
+		}
+		if err := n.post(addr, "/channel/create", url.Values{"topic": {topic}, "channel": {channel}}); err != nil {
+			return fmt.Errorf("error creating channel %s of topic %s on nsqd %s: %v", channel, topic, addr, err)
+		}
+	}
+	return nil
+}
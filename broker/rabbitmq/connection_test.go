@@ -4,7 +4,9 @@ import (
 	"crypto/tls"
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/micro/go-micro/v2/broker"
 	"github.com/streadway/amqp"
 )
 
@@ -104,3 +106,37 @@ func TestNewRabbitMQPrefetch(t *testing.T) {
 		}
 	}
 }
+
+func TestConnectionOptions(t *testing.T) {
+	var config amqp.Config
+	dialConfig = func(_ string, c amqp.Config) (*amqp.Connection, error) {
+		config = c
+		return nil, errors.New("stop connect here")
+	}
+
+	b := NewBroker(
+		broker.Addrs("amqp://example.com/one"),
+		Vhost("two"),
+		ConnectionName("my.service"),
+		Heartbeat(time.Minute),
+	)
+	b.Connect()
+
+	if config.Vhost != "two" {
+		t.Errorf("invalid vhost, want %q, have %q", "two", config.Vhost)
+	}
+	if config.Heartbeat != time.Minute {
+		t.Errorf("invalid heartbeat, want %v, have %v", time.Minute, config.Heartbeat)
+	}
+	if name := config.Properties["connection_name"]; name != "my.service" {
+		t.Errorf("invalid connection name, want %q, have %v", "my.service", name)
+	}
+
+	// the defaults aren't changed by the options
+	b = NewBroker(broker.Addrs("amqp://example.com/one"))
+	b.Connect()
+
+	if config.Vhost != "" || config.Heartbeat != defaultHeartbeat || config.Properties != nil {
+		t.Errorf("expected the default config, have %+v", config)
+	}
+}
@@ -5,5 +5,8 @@ go 1.13
 require (
 	github.com/google/uuid v1.1.1
 	github.com/micro/go-micro/v2 v2.9.1
+	github.com/micro/go-plugins/broker/tests/v2 v2.9.1
 	github.com/streadway/amqp v0.0.0-20200108173154-1c71cc93ed71
 )
+
+replace github.com/micro/go-plugins/broker/tests/v2 => ../tests
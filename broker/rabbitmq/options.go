@@ -2,6 +2,7 @@ package rabbitmq
 
 import (
 	"context"
+	"time"
 
 	"github.com/micro/go-micro/v2/broker"
 )
@@ -17,6 +18,9 @@ type deliveryMode struct{}
 type priorityKey struct{}
 type externalAuth struct{}
 type durableExchange struct{}
+type vhostKey struct{}
+type connectionNameKey struct{}
+type heartbeatKey struct{}
 
 // DurableQueue creates a durable queue when subscribing.
 func DurableQueue() broker.SubscribeOption {
@@ -72,6 +76,22 @@ func ExternalAuth() broker.Option {
 	return setBrokerOption(externalAuth{}, ExternalAuthentication{})
 }
 
+// Vhost sets the virtual host to connect to, overriding the vhost of the url
+func Vhost(v string) broker.Option {
+	return setBrokerOption(vhostKey{}, v)
+}
+
+// ConnectionName sets the name of the connection shown in the management UI
+func ConnectionName(name string) broker.Option {
+	return setBrokerOption(connectionNameKey{}, name)
+}
+
+// Heartbeat sets the interval of heartbeats negotiated with the server, the
+// default is 10 seconds
+func Heartbeat(d time.Duration) broker.Option {
+	return setBrokerOption(heartbeatKey{}, d)
+}
+
 type subscribeContextKey struct{}
 
 // SubscribeContext set the context for broker.SubscribeOption
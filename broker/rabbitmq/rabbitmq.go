@@ -281,6 +281,20 @@ func (r *rbroker) Connect() error {
 		conf.SASL = []amqp.Authentication{&auth}
 	}
 
+	if v, ok := r.opts.Context.Value(vhostKey{}).(string); ok {
+		conf.Vhost = v
+	}
+	if v, ok := r.opts.Context.Value(heartbeatKey{}).(time.Duration); ok {
+		conf.Heartbeat = v
+	}
+	if v, ok := r.opts.Context.Value(connectionNameKey{}).(string); ok {
+		// the default properties are only sent without any
+		conf.Properties = amqp.Table{
+			"product":         "go-micro",
+			"connection_name": v,
+		}
+	}
+
 	conf.TLSClientConfig = r.opts.TLSConfig
 
 	return r.conn.Connect(r.opts.Secure, &conf)
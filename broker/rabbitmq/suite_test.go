@@ -0,0 +1,20 @@
+package rabbitmq
+
+import (
+	"os"
+	"testing"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-plugins/broker/tests/v2"
+)
+
+func TestSuite(t *testing.T) {
+	url := os.Getenv("RABBITMQ_URL")
+	if url == "" {
+		t.Skip("RABBITMQ_URL not defined")
+	}
+
+	tests.Run(t, func() broker.Broker {
+		return NewBroker(broker.Addrs(url))
+	})
+}
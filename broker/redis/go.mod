@@ -5,4 +5,7 @@ go 1.13
 require (
 	github.com/gomodule/redigo v2.0.0+incompatible
 	github.com/micro/go-micro/v2 v2.9.1
+	github.com/micro/go-plugins/broker/tests/v2 v2.9.1
 )
+
+replace github.com/micro/go-plugins/broker/tests/v2 => ../tests
@@ -0,0 +1,21 @@
+package redis
+
+import (
+	"os"
+	"testing"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-plugins/broker/tests/v2"
+)
+
+func TestSuite(t *testing.T) {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		t.Skip("REDIS_URL not defined")
+	}
+
+	// redis pub/sub delivers every message to every subscriber
+	tests.Run(t, func() broker.Broker {
+		return NewBroker(broker.Addrs(url))
+	}, tests.WithoutQueue())
+}
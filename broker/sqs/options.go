@@ -2,6 +2,7 @@ package sqs
 
 import (
 	"context"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/micro/go-micro/v2/broker"
@@ -13,6 +14,10 @@ type groupIdFunctionKey struct{}
 type maxMessagesKey struct{}
 type visiblityTimeoutKey struct{}
 type waitTimeSecondsKey struct{}
+type createQueueKey struct{}
+type kmsKeyKey struct{}
+type kmsDataKeyReuseKey struct{}
+type headerAttributesKey struct{}
 
 type StringFromMessageFunc func(m *broker.Message) string
 
@@ -80,3 +85,47 @@ func Client(c *sqs.SQS) broker.Option {
 		o.Context = context.WithValue(o.Context, sqsClientKey{}, c)
 	}
 }
+
+// CreateQueue creates queues which don't exist when publishing or subscribing,
+// fifo queues are created for names ending in .fifo
+func CreateQueue() broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, createQueueKey{}, true)
+	}
+}
+
+// KMSKey enables server-side encryption of created queues with the KMS key id, arn
+// or alias e.g alias/aws/sqs. Existing queues are left as they are.
+func KMSKey(id string) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, kmsKeyKey{}, id)
+	}
+}
+
+// KMSDataKeyReuse sets how long created queues reuse a data key before calling
+// KMS again, between 1 minute and 24 hours
+func KMSDataKeyReuse(d time.Duration) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, kmsDataKeyReuseKey{}, d)
+	}
+}
+
+// HeaderAttributes maps message headers to message attribute names and back e.g
+// map[string]string{"Micro-Id": "id"}, for consumers which expect other names
+func HeaderAttributes(names map[string]string) broker.Option {
+	return func(o *broker.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, headerAttributesKey{}, names)
+	}
+}
@@ -3,8 +3,12 @@ package sqs
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -20,6 +24,17 @@ const (
 	defaultMaxMessages       = 1
 	defaultVisibilityTimeout = 3
 	defaultWaitSeconds       = 10
+
+	// maxAttributes of an SQS message
+	maxAttributes = 10
+	// maxAttributeName length of an SQS message attribute
+	maxAttributeName = 256
+)
+
+var (
+	// HeaderAttribute holds the headers which can't be sent as message attributes
+	// of their own, either because of their name or the limit of 10 attributes
+	HeaderAttribute = "Micro-Header"
 )
 
 // Amazon SQS Broker
@@ -35,6 +50,8 @@ type subscriber struct {
 	svc       *sqs.SQS
 	URL       string
 	exit      chan bool
+	// attributes maps headers to message attributes
+	attributes map[string]string
 }
 
 // A wrapper around a message published on an SQS queue and delivered via subscriber
@@ -122,7 +139,7 @@ func (s *subscriber) handleMessage(msg *sqs.Message, hdlr broker.Handler) {
 		log.Errorf("Failed to decode message body : %s", err.Error())
 	} else {
 		m := &broker.Message{
-			Header: buildMessageHeader(msg.MessageAttributes),
+			Header: buildMessageHeader(msg.MessageAttributes, s.attributes),
 			Body:   decodeBody,
 		}
 
@@ -237,7 +254,10 @@ func (b *sqsBroker) Publish(queueName string, msg *broker.Message, opts ...broke
 		MessageBody: &messageBody,
 		QueueUrl:    &queueURL,
 	}
-	input.MessageAttributes = copyMessageHeader(msg)
+	input.MessageAttributes, err = copyMessageHeader(msg, b.getHeaderAttributes())
+	if err != nil {
+		return err
+	}
 	input.MessageDeduplicationId = b.generateDedupID(msg)
 	input.MessageGroupId = b.generateGroupID(msg)
 
@@ -270,11 +290,12 @@ func (b *sqsBroker) Subscribe(queueName string, h broker.Handler, opts ...broker
 	}
 
 	subscriber := &subscriber{
-		options:   options,
-		URL:       queueURL,
-		queueName: queueName,
-		svc:       b.svc,
-		exit:      make(chan bool),
+		options:    options,
+		URL:        queueURL,
+		queueName:  queueName,
+		svc:        b.svc,
+		exit:       make(chan bool),
+		attributes: b.getHeaderAttributes(),
 	}
 	go subscriber.run(h)
 
@@ -287,6 +308,9 @@ func (b *sqsBroker) urlFromQueueName(queueName string) (string, error) {
 	})
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == sqs.ErrCodeQueueDoesNotExist {
+			if b.getCreateQueue() {
+				return b.createQueue(queueName)
+			}
 			return "", errors.New(fmt.Sprintf("Unable to find queue %s: %s", queueName, err.Error()))
 		}
 		return "", errors.New(fmt.Sprintf("Unable to determine URL for queue %s: %s", queueName, err.Error()))
@@ -294,31 +318,196 @@ func (b *sqsBroker) urlFromQueueName(queueName string) (string, error) {
 	return *resultURL.QueueUrl, nil
 }
 
+// createQueue creates the queue and returns its URL
+func (b *sqsBroker) createQueue(queueName string) (string, error) {
+	log.Infof("Creating SQS queue %s", queueName)
+
+	result, err := b.svc.CreateQueue(&sqs.CreateQueueInput{
+		QueueName:  aws.String(queueName),
+		Attributes: b.queueAttributes(queueName),
+	})
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Unable to create queue %s: %s", queueName, err.Error()))
+	}
+	return *result.QueueUrl, nil
+}
+
+// queueAttributes returns the attributes queues are created with
+func (b *sqsBroker) queueAttributes(queueName string) map[string]*string {
+	attribs := make(map[string]*string)
+
+	if strings.HasSuffix(queueName, ".fifo") {
+		attribs[sqs.QueueAttributeNameFifoQueue] = aws.String("true")
+	}
+
+	if v, ok := b.options.Context.Value(kmsKeyKey{}).(string); ok && len(v) > 0 {
+		attribs[sqs.QueueAttributeNameKmsMasterKeyId] = aws.String(v)
+
+		if d, ok := b.options.Context.Value(kmsDataKeyReuseKey{}).(time.Duration); ok && d > 0 {
+			attribs[sqs.QueueAttributeNameKmsDataKeyReusePeriodSeconds] = aws.String(strconv.Itoa(int(d / time.Second)))
+		}
+	}
+
+	return attribs
+}
+
 // String returns the name of the broker plugin
 func (b *sqsBroker) String() string {
 	return "sqs"
 }
 
-func copyMessageHeader(m *broker.Message) (attribs map[string]*sqs.MessageAttributeValue) {
-	attribs = make(map[string]*sqs.MessageAttributeValue)
-	for k, v := range m.Header {
-		attribs[k] = &sqs.MessageAttributeValue{
+// validAttribute returns whether the name is allowed as a message attribute name
+func validAttribute(name string) bool {
+	if len(name) == 0 || len(name) > maxAttributeName {
+		return false
+	}
+
+	lower := strings.ToLower(name)
+	if strings.HasPrefix(lower, "aws.") || strings.HasPrefix(lower, "amazon.") {
+		return false
+	}
+	if strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") || strings.Contains(name, "..") {
+		return false
+	}
+
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '_', c == '-', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// copyMessageHeader maps the message header to message attributes, so consumers which
+// don't use micro still see them. Headers are renamed by the attribute map. Headers
+// which aren't valid attributes, or don't fit in the attribute limit, are sent json
+// encoded in the HeaderAttribute instead of being dropped.
+func copyMessageHeader(m *broker.Message, names map[string]string) (map[string]*sqs.MessageAttributeValue, error) {
+	keys := make([]string, 0, len(m.Header))
+	for k := range m.Header {
+		keys = append(keys, k)
+	}
+
+	// mapped headers are sent as attributes first
+	sort.Slice(keys, func(i, j int) bool {
+		_, mi := names[keys[i]]
+		_, mj := names[keys[j]]
+		if mi != mj {
+			return mi
+		}
+		return keys[i] < keys[j]
+	})
+
+	var direct []string
+	packed := make(map[string]string)
+
+	for _, k := range keys {
+		name := k
+		if n, ok := names[k]; ok {
+			name = n
+		}
+		// empty string attributes are rejected by SQS
+		if name == HeaderAttribute || !validAttribute(name) || len(m.Header[k]) == 0 {
+			packed[k] = m.Header[k]
+			continue
+		}
+		direct = append(direct, k)
+	}
+
+	// keep an attribute free for the headers which don't fit
+	if len(packed) > 0 || len(direct) > maxAttributes {
+		for _, k := range direct[min(len(direct), maxAttributes-1):] {
+			packed[k] = m.Header[k]
+		}
+		direct = direct[:min(len(direct), maxAttributes-1)]
+	}
+
+	attribs := make(map[string]*sqs.MessageAttributeValue)
+	for _, k := range direct {
+		name := k
+		if n, ok := names[k]; ok {
+			name = n
+		}
+		attribs[name] = &sqs.MessageAttributeValue{
 			DataType:    aws.String("String"),
-			StringValue: aws.String(v),
+			StringValue: aws.String(m.Header[k]),
 		}
 	}
-	return attribs
+
+	if len(packed) > 0 {
+		b, err := json.Marshal(packed)
+		if err != nil {
+			return nil, err
+		}
+		attribs[HeaderAttribute] = &sqs.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(string(b)),
+		}
+	}
+
+	return attribs, nil
 }
 
-func buildMessageHeader(attribs map[string]*sqs.MessageAttributeValue) map[string]string {
+// buildMessageHeader maps message attributes back to the message header. Binary
+// attributes, e.g set by other producers, are base64 encoded.
+func buildMessageHeader(attribs map[string]*sqs.MessageAttributeValue, names map[string]string) map[string]string {
+	headers := make(map[string]string, len(names))
+	for k, v := range names {
+		headers[v] = k
+	}
+
 	res := make(map[string]string)
 
 	for k, v := range attribs {
-		res[k] = *v.StringValue
+		if v == nil {
+			continue
+		}
+
+		if k == HeaderAttribute {
+			packed := make(map[string]string)
+			if err := json.Unmarshal([]byte(aws.StringValue(v.StringValue)), &packed); err == nil {
+				for pk, pv := range packed {
+					res[pk] = pv
+				}
+				continue
+			}
+		}
+
+		if h, ok := headers[k]; ok {
+			k = h
+		}
+
+		if strings.HasPrefix(aws.StringValue(v.DataType), "Binary") {
+			res[k] = base64.StdEncoding.EncodeToString(v.BinaryValue)
+			continue
+		}
+		res[k] = aws.StringValue(v.StringValue)
 	}
 	return res
 }
 
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (b *sqsBroker) getCreateQueue() bool {
+	v, ok := b.options.Context.Value(createQueueKey{}).(bool)
+	return ok && v
+}
+
+func (b *sqsBroker) getHeaderAttributes() map[string]string {
+	if v, ok := b.options.Context.Value(headerAttributesKey{}).(map[string]string); ok {
+		return v
+	}
+	return nil
+}
+
 func (b *sqsBroker) getSQSClient() *sqs.SQS {
 	raw := b.options.Context.Value(sqsClientKey{})
 	if raw != nil {
@@ -0,0 +1,176 @@
+package sqs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/micro/go-micro/v2/broker"
+)
+
+func TestMessageHeader(t *testing.T) {
+	names := map[string]string{"Micro-Id": "id"}
+
+	header := map[string]string{
+		"Micro-Id":   "1",
+		"Micro-From": "go.micro.srv.foo",
+		"aws.header": "reserved",
+		"bad name":   "invalid",
+		"empty":      "",
+	}
+
+	attribs, err := copyMessageHeader(&broker.Message{Header: header}, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range []string{"id", "Micro-From", HeaderAttribute} {
+		if _, ok := attribs[k]; !ok {
+			t.Fatalf("Expected attribute %s in %v", k, attribs)
+		}
+	}
+	if len(attribs) != 3 {
+		t.Fatalf("Expected 3 attributes, got %d", len(attribs))
+	}
+
+	res := buildMessageHeader(attribs, names)
+	if len(res) != len(header) {
+		t.Fatalf("Expected header %v, got %v", header, res)
+	}
+	for k, v := range header {
+		if res[k] != v {
+			t.Fatalf("Expected header %s to be %q, got %q", k, v, res[k])
+		}
+	}
+}
+
+func TestMessageHeaderLimit(t *testing.T) {
+	header := make(map[string]string)
+	for i := 0; i < maxAttributes; i++ {
+		header[fmt.Sprintf("header-%d", i)] = "value"
+	}
+
+	attribs, err := copyMessageHeader(&broker.Message{Header: header}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attribs) != maxAttributes {
+		t.Fatalf("Expected %d attributes, got %d", maxAttributes, len(attribs))
+	}
+	if _, ok := attribs[HeaderAttribute]; ok {
+		t.Fatal("Expected headers within the limit not to be packed")
+	}
+
+	header["header-10"] = "value"
+
+	attribs, err = copyMessageHeader(&broker.Message{Header: header}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attribs) != maxAttributes {
+		t.Fatalf("Expected %d attributes, got %d", maxAttributes, len(attribs))
+	}
+	if _, ok := attribs[HeaderAttribute]; !ok {
+		t.Fatal("Expected headers over the limit to be packed")
+	}
+
+	if res := buildMessageHeader(attribs, nil); len(res) != len(header) {
+		t.Fatalf("Expected %d headers, got %d", len(header), len(res))
+	}
+}
+
+func TestMessageHeaderTypes(t *testing.T) {
+	res := buildMessageHeader(map[string]*sqs.MessageAttributeValue{
+		"number": {
+			DataType:    aws.String("Number"),
+			StringValue: aws.String("1.5"),
+		},
+		"binary": {
+			DataType:    aws.String("Binary.gzip"),
+			BinaryValue: []byte("foo"),
+		},
+		"nil": nil,
+	}, nil)
+
+	if res["number"] != "1.5" {
+		t.Fatalf("Expected number header 1.5, got %q", res["number"])
+	}
+	if res["binary"] != "Zm9v" {
+		t.Fatalf("Expected base64 binary header, got %q", res["binary"])
+	}
+	if _, ok := res["nil"]; ok {
+		t.Fatal("Expected nil attribute to be skipped")
+	}
+}
+
+func TestCreateQueue(t *testing.T) {
+	var attribs map[string]string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+
+		switch r.Form.Get("Action") {
+		case "GetQueueUrl":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `<ErrorResponse><Error><Type>Sender</Type><Code>AWS.SimpleQueueService.NonExistentQueue</Code><Message>not found</Message></Error><RequestId>1</RequestId></ErrorResponse>`)
+		case "CreateQueue":
+			attribs = make(map[string]string)
+			for i := 1; len(r.Form.Get(fmt.Sprintf("Attribute.%d.Name", i))) > 0; i++ {
+				attribs[r.Form.Get(fmt.Sprintf("Attribute.%d.Name", i))] = r.Form.Get(fmt.Sprintf("Attribute.%d.Value", i))
+			}
+			fmt.Fprintf(w, `<CreateQueueResponse><CreateQueueResult><QueueUrl>%s/1/%s</QueueUrl></CreateQueueResult><ResponseMetadata><RequestId>2</RequestId></ResponseMetadata></CreateQueueResponse>`, "http://"+r.Host, r.Form.Get("QueueName"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Endpoint:    aws.String(srv.URL),
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+	}))
+
+	b := NewBroker(
+		Client(sqs.New(sess)),
+		KMSKey("alias/aws/sqs"),
+		KMSDataKeyReuse(time.Hour),
+	)
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := b.(*sqsBroker).urlFromQueueName("test.fifo"); err == nil {
+		t.Fatal("Expected error for missing queue without CreateQueue")
+	}
+
+	b.Init(CreateQueue())
+
+	url, err := b.(*sqsBroker).urlFromQueueName("test.fifo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != srv.URL+"/1/test.fifo" {
+		t.Fatalf("Unexpected queue url %s", url)
+	}
+
+	expected := map[string]string{
+		sqs.QueueAttributeNameFifoQueue:                    "true",
+		sqs.QueueAttributeNameKmsMasterKeyId:               "alias/aws/sqs",
+		sqs.QueueAttributeNameKmsDataKeyReusePeriodSeconds: "3600",
+	}
+	if len(attribs) != len(expected) {
+		t.Fatalf("Expected queue attributes %v, got %v", expected, attribs)
+	}
+	for k, v := range expected {
+		if attribs[k] != v {
+			t.Fatalf("Expected queue attribute %s to be %q, got %q", k, v, attribs[k])
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 )
 
 // Context related keys and funcs
+type ackModeKey struct{}
 type authKey struct{}
 type connectHeaderKey struct{}
 type connectTimeoutKey struct{}
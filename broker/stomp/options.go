@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/go-stomp/stomp"
 	"github.com/micro/go-micro/v2/broker"
 )
 
@@ -31,7 +32,14 @@ func Durable() broker.SubscribeOption {
 	return setSubscribeOption(durableQueueKey{}, true)
 }
 
-// Receipt requests a receipt for the delivery should be received
+// AckMode sets the acknowledgement mode of the subscription. In stomp.AckClient
+// acknowledging a message acknowledges those before it too, in stomp.AckClientIndividual
+// only the message. Messages are acknowledged with Ack, or on success with AckOnSuccess.
+func AckMode(mode stomp.AckMode) broker.SubscribeOption {
+	return setSubscribeOption(ackModeKey{}, mode)
+}
+
+// Receipt waits for the server to confirm it received the message, the duration isn't used
 func Receipt(ct time.Duration) broker.PublishOption {
 	return setPublishOption(receiptKey{}, true)
 }
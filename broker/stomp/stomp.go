@@ -124,33 +124,38 @@ func (r *rbroker) Publish(topic string, msg *broker.Message, opts ...broker.Publ
 		return errors.New("not connected")
 	}
 
-	// Set options
+	// Send
+	if err := r.stompConn.Send(
+		topic,
+		"",
+		msg.Body,
+		r.sendOptions(msg, opts)...); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// sendOptions returns the options of sending the message
+func (r *rbroker) sendOptions(msg *broker.Message, opts []broker.PublishOption) []func(*frame.Frame) error {
 	stompOpt := make([]func(*frame.Frame) error, 0, len(msg.Header))
 	for k, v := range msg.Header {
 		stompOpt = append(stompOpt, stomp.SendOpt.Header(k, v))
 	}
 
-	bOpt := broker.PublishOptions{}
+	bOpt := broker.PublishOptions{
+		Context: r.Options().Context,
+	}
 	for _, o := range opts {
 		o(&bOpt)
 	}
-	if withReceipt, ok := r.Options().Context.Value(receiptKey{}).(bool); ok && withReceipt {
+	if withReceipt, ok := bOpt.Context.Value(receiptKey{}).(bool); ok && withReceipt {
 		stompOpt = append(stompOpt, stomp.SendOpt.Receipt)
 	}
-	if withoutContentLength, ok := r.Options().Context.Value(suppressContentLengthKey{}).(bool); ok && withoutContentLength {
+	if withoutContentLength, ok := bOpt.Context.Value(suppressContentLengthKey{}).(bool); ok && withoutContentLength {
 		stompOpt = append(stompOpt, stomp.SendOpt.NoContentLength)
 	}
-
-	// Send
-	if err := r.stompConn.Send(
-		topic,
-		"",
-		msg.Body,
-		stompOpt...); err != nil {
-		return err
-	}
-
-	return nil
+	return stompOpt
 }
 
 func (r *rbroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
@@ -200,6 +205,12 @@ func (r *rbroker) Subscribe(topic string, handler broker.Handler, opts ...broker
 		ackMode = stomp.AckClientIndividual
 	}
 
+	// messages are acknowledged by the handler or on success in the client modes
+	if mode, ok := ctx.Value(ackModeKey{}).(stomp.AckMode); ok {
+		ackMode = mode
+		bOpt.AutoAck = mode == stomp.AckAuto
+	}
+
 	// Subscribe now
 	sub, err := r.stompConn.Subscribe(topic, ackMode, stompOpt...)
 	if err != nil {
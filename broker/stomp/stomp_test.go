@@ -0,0 +1,121 @@
+package stomp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-stomp/stomp"
+	"github.com/go-stomp/stomp/server"
+	"github.com/micro/go-micro/v2/broker"
+)
+
+func testBroker(t *testing.T) (broker.Broker, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(l)
+
+	b := NewBroker(broker.Addrs("stomp://" + l.Addr().String()))
+	if err := b.Connect(); err != nil {
+		l.Close()
+		t.Fatal(err)
+	}
+
+	return b, func() {
+		b.Disconnect()
+		l.Close()
+	}
+}
+
+func receive(t *testing.T, c chan *broker.Message) *broker.Message {
+	select {
+	case m := <-c:
+		return m
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the message")
+	}
+	return nil
+}
+
+func TestTransaction(t *testing.T) {
+	b, cleanup := testBroker(t)
+	defer cleanup()
+
+	c := make(chan *broker.Message, 10)
+	if _, err := b.Subscribe("/queue/test", func(e broker.Event) error {
+		c <- e.Message()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tx, err := b.(Transactional).BeginTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{"1", "2"} {
+		if err := tx.Publish("/queue/test", &broker.Message{Body: []byte(body)}, Receipt(0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// nothing is delivered until the transaction is committed
+	select {
+	case m := <-c:
+		t.Fatalf("expected no message before committing got %s", m.Body)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	// messages are handled concurrently so they may be received in any order
+	received := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		received[string(receive(t, c).Body)] = true
+	}
+	if !received["1"] || !received["2"] {
+		t.Fatalf("expected the messages of the transaction got %v", received)
+	}
+	if err := tx.Abort(); err != ErrTransactionDone {
+		t.Fatalf("expected %v got %v", ErrTransactionDone, err)
+	}
+
+	// aborted messages are discarded
+	tx, err = b.(Transactional).BeginTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Publish("/queue/test", &broker.Message{Body: []byte("3")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Publish("/queue/test", &broker.Message{Body: []byte("4")}, Receipt(0)); err != nil {
+		t.Fatal(err)
+	}
+	if m := receive(t, c); string(m.Body) != "4" {
+		t.Fatalf("expected the aborted message to be discarded got %s", m.Body)
+	}
+}
+
+func TestAckMode(t *testing.T) {
+	b, cleanup := testBroker(t)
+	defer cleanup()
+
+	for _, mode := range []stomp.AckMode{stomp.AckClient, stomp.AckClientIndividual, stomp.AckAuto} {
+		s, err := b.Subscribe("/queue/"+mode.String(), func(broker.Event) error { return nil }, AckMode(mode))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m := s.(*subscriber).sub.AckMode(); m != mode {
+			t.Fatalf("expected ack mode %s got %s", mode, m)
+		}
+		if s.Options().AutoAck != (mode == stomp.AckAuto) {
+			t.Fatalf("expected messages to be acknowledged automatically only in %s", stomp.AckAuto)
+		}
+	}
+}
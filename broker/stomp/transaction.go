@@ -0,0 +1,80 @@
+package stomp
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/go-stomp/stomp"
+	"github.com/micro/go-micro/v2/broker"
+)
+
+var (
+	// ErrTransactionDone is returned by a transaction once it's committed or aborted
+	ErrTransactionDone = errors.New("stomp: transaction already committed or aborted")
+)
+
+// Transaction groups publishes which the server delivers once it's committed,
+// none of them are delivered if it's aborted
+type Transaction interface {
+	Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error
+	Commit() error
+	Abort() error
+}
+
+// Transactional is implemented by the broker returned by NewBroker
+type Transactional interface {
+	// BeginTransaction sends BEGIN, the broker must be connected
+	BeginTransaction() (Transaction, error)
+}
+
+type transaction struct {
+	r  *rbroker
+	tx *stomp.Transaction
+
+	sync.Mutex
+	done bool
+}
+
+// BeginTransaction starts a transaction on the connection of the broker
+func (r *rbroker) BeginTransaction() (Transaction, error) {
+	if r.stompConn == nil {
+		return nil, errors.New("not connected")
+	}
+	return &transaction{r: r, tx: r.stompConn.Begin()}, nil
+}
+
+// Publish sends the message as part of the transaction, with Receipt the server
+// confirms it got the message but only delivers it once the transaction is committed
+func (t *transaction) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.done {
+		return ErrTransactionDone
+	}
+	return t.tx.Send(topic, "", msg.Body, t.r.sendOptions(msg, opts)...)
+}
+
+// Commit sends COMMIT and waits for the server to confirm it with a receipt
+func (t *transaction) Commit() error {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.done {
+		return ErrTransactionDone
+	}
+	t.done = true
+	return t.tx.CommitWithReceipt()
+}
+
+// Abort sends ABORT and waits for the server to confirm it with a receipt
+func (t *transaction) Abort() error {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.done {
+		return ErrTransactionDone
+	}
+	t.done = true
+	return t.tx.AbortWithReceipt()
+}
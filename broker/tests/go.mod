@@ -0,0 +1,8 @@
+module github.com/micro/go-plugins/broker/tests/v2
+
+go 1.13
+
+require (
+	github.com/google/uuid v1.1.1
+	github.com/micro/go-micro/v2 v2.9.1
+)
@@ -0,0 +1,57 @@
+package tests
+
+import "time"
+
+// Options of the suite, describing what the broker supports
+type Options struct {
+	// Queue is true if subscribers in a queue share messages
+	Queue bool
+	// Redelivery is true if messages are redelivered when the handler errors
+	Redelivery bool
+	// Settle is how long subscriptions take to be ready
+	Settle time.Duration
+	// Timeout is how long to wait for messages
+	Timeout time.Duration
+}
+
+type Option func(o *Options)
+
+// WithoutQueue for brokers which deliver every message to every subscriber
+func WithoutQueue() Option {
+	return func(o *Options) {
+		o.Queue = false
+	}
+}
+
+// WithRedelivery for brokers which redeliver messages when the handler errors
+func WithRedelivery() Option {
+	return func(o *Options) {
+		o.Redelivery = true
+	}
+}
+
+// WithSettle sets how long subscriptions take to be ready, e.g. for consumer group rebalances
+func WithSettle(d time.Duration) Option {
+	return func(o *Options) {
+		o.Settle = d
+	}
+}
+
+// WithTimeout sets how long to wait for messages
+func WithTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Queue:   true,
+		Settle:  100 * time.Millisecond,
+		Timeout: 5 * time.Second,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
@@ -0,0 +1,466 @@
+// Package tests is a conformance suite and benchmarks for broker plugins
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2/broker"
+)
+
+// NewBroker returns a new broker to test, it's called for each test
+type NewBroker func() broker.Broker
+
+type suite struct {
+	newBroker NewBroker
+	opts      Options
+}
+
+// Run the conformance suite against the broker
+func Run(t *testing.T, newBroker NewBroker, opts ...Option) {
+	s := &suite{newBroker: newBroker, opts: newOptions(opts...)}
+
+	t.Run("ConnectDisconnect", s.testConnectDisconnect)
+	t.Run("PublishSubscribe", s.testPublishSubscribe)
+	t.Run("Fanout", s.testFanout)
+	t.Run("Queue", s.testQueue)
+	t.Run("Ack", s.testAck)
+	t.Run("ConcurrentPublish", s.testConcurrentPublish)
+	t.Run("HandlerError", s.testHandlerError)
+	t.Run("Unsubscribe", s.testUnsubscribe)
+	t.Run("Reconnect", s.testReconnect)
+}
+
+// topic returns a unique topic so tests don't see each other's messages
+func topic() string {
+	return "micro.test." + uuid.New().String()
+}
+
+func message(i int) *broker.Message {
+	return &broker.Message{
+		Header: map[string]string{"Id": strconv.Itoa(i), "Content-Type": "text/plain"},
+		Body:   []byte("message " + strconv.Itoa(i)),
+	}
+}
+
+func (s *suite) connect(t *testing.T) broker.Broker {
+	b := s.newBroker()
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	return b
+}
+
+func (s *suite) disconnect(t *testing.T, b broker.Broker) {
+	if err := b.Disconnect(); err != nil {
+		t.Errorf("Disconnect: %v", err)
+	}
+}
+
+func (s *suite) subscribe(t *testing.T, b broker.Broker, topic string, h broker.Handler, opts ...broker.SubscribeOption) broker.Subscriber {
+	sub, err := b.Subscribe(topic, h, opts...)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	return sub
+}
+
+func (s *suite) publish(t *testing.T, b broker.Broker, topic string, m *broker.Message) {
+	if err := b.Publish(topic, m); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+}
+
+// settle waits for subscriptions to be ready
+func (s *suite) settle() {
+	time.Sleep(s.opts.Settle)
+}
+
+// receive waits for n messages on the channel
+func (s *suite) receive(t *testing.T, ch <-chan *broker.Message, n int) []*broker.Message {
+	var msgs []*broker.Message
+	timeout := time.After(s.opts.Timeout)
+
+	for len(msgs) < n {
+		select {
+		case m := <-ch:
+			msgs = append(msgs, m)
+		case <-timeout:
+			t.Fatalf("Received %d of %d messages", len(msgs), n)
+		}
+	}
+
+	return msgs
+}
+
+// none fails if a message is received before the settle time
+func (s *suite) none(t *testing.T, ch <-chan *broker.Message) {
+	select {
+	case m := <-ch:
+		t.Fatalf("Unexpected message %s", m.Body)
+	case <-time.After(s.opts.Settle + 100*time.Millisecond):
+	}
+}
+
+func handler(ch chan<- *broker.Message) broker.Handler {
+	return func(e broker.Event) error {
+		ch <- e.Message()
+		return nil
+	}
+}
+
+func (s *suite) testConnectDisconnect(t *testing.T) {
+	b := s.newBroker()
+
+	for i := 0; i < 3; i++ {
+		if err := b.Connect(); err != nil {
+			t.Fatalf("Connect %d: %v", i, err)
+		}
+		if err := b.Disconnect(); err != nil {
+			t.Fatalf("Disconnect %d: %v", i, err)
+		}
+	}
+
+	if len(b.String()) == 0 {
+		t.Fatal("Expected the broker to have a name")
+	}
+}
+
+func (s *suite) testPublishSubscribe(t *testing.T) {
+	b := s.connect(t)
+	defer s.disconnect(t, b)
+
+	tp := topic()
+	ch := make(chan *broker.Message, 1)
+	topics := make(chan string, 1)
+
+	sub := s.subscribe(t, b, tp, func(e broker.Event) error {
+		topics <- e.Topic()
+		ch <- e.Message()
+		return nil
+	})
+	defer sub.Unsubscribe()
+
+	if sub.Topic() != tp {
+		t.Fatalf("Expected subscriber topic %s got %s", tp, sub.Topic())
+	}
+
+	s.settle()
+	s.publish(t, b, tp, message(1))
+
+	m := s.receive(t, ch, 1)[0]
+	if !bytes.Equal(m.Body, message(1).Body) {
+		t.Fatalf("Expected body %q got %q", message(1).Body, m.Body)
+	}
+	for k, v := range message(1).Header {
+		if m.Header[k] != v {
+			t.Fatalf("Expected header %s=%s got %q", k, v, m.Header[k])
+		}
+	}
+	if got := <-topics; got != tp {
+		t.Fatalf("Expected event topic %s got %s", tp, got)
+	}
+}
+
+func (s *suite) testFanout(t *testing.T) {
+	b := s.connect(t)
+	defer s.disconnect(t, b)
+
+	tp := topic()
+	ch1 := make(chan *broker.Message, 10)
+	ch2 := make(chan *broker.Message, 10)
+
+	sub1 := s.subscribe(t, b, tp, handler(ch1))
+	defer sub1.Unsubscribe()
+	sub2 := s.subscribe(t, b, tp, handler(ch2))
+	defer sub2.Unsubscribe()
+
+	s.settle()
+	s.publish(t, b, tp, message(1))
+
+	// every subscriber without a queue receives the message
+	s.receive(t, ch1, 1)
+	s.receive(t, ch2, 1)
+}
+
+func (s *suite) testQueue(t *testing.T) {
+	if !s.opts.Queue {
+		t.Skip("broker doesn't support queues")
+	}
+
+	b := s.connect(t)
+	defer s.disconnect(t, b)
+
+	tp := topic()
+	queue := "queue-" + uuid.New().String()
+	ch := make(chan *broker.Message, 100)
+
+	sub1 := s.subscribe(t, b, tp, handler(ch), broker.Queue(queue))
+	defer sub1.Unsubscribe()
+	sub2 := s.subscribe(t, b, tp, handler(ch), broker.Queue(queue))
+	defer sub2.Unsubscribe()
+
+	s.settle()
+
+	n := 10
+	for i := 0; i < n; i++ {
+		s.publish(t, b, tp, message(i))
+	}
+
+	// each message is delivered to one subscriber in the queue
+	msgs := s.receive(t, ch, n)
+	seen := map[string]bool{}
+	for _, m := range msgs {
+		if seen[m.Header["Id"]] {
+			t.Fatalf("Message %s delivered twice", m.Header["Id"])
+		}
+		seen[m.Header["Id"]] = true
+	}
+	s.none(t, ch)
+}
+
+func (s *suite) testAck(t *testing.T) {
+	b := s.connect(t)
+	defer s.disconnect(t, b)
+
+	tp := topic()
+	errs := make(chan error, 1)
+
+	sub := s.subscribe(t, b, tp, func(e broker.Event) error {
+		errs <- e.Ack()
+		return nil
+	}, broker.DisableAutoAck(), broker.Queue("ack-"+uuid.New().String()))
+	defer sub.Unsubscribe()
+
+	if sub.Options().AutoAck {
+		t.Fatal("Expected auto ack to be disabled")
+	}
+
+	s.settle()
+	s.publish(t, b, tp, message(1))
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Fatalf("Ack: %v", err)
+		}
+	case <-time.After(s.opts.Timeout):
+		t.Fatal("Expected a message to ack")
+	}
+}
+
+func (s *suite) testConcurrentPublish(t *testing.T) {
+	b := s.connect(t)
+	defer s.disconnect(t, b)
+
+	tp := topic()
+	publishers, messages := 10, 20
+	total := publishers * messages
+
+	var received int64
+	done := make(chan bool)
+
+	sub := s.subscribe(t, b, tp, func(e broker.Event) error {
+		if atomic.AddInt64(&received, 1) == int64(total) {
+			close(done)
+		}
+		return nil
+	})
+	defer sub.Unsubscribe()
+
+	s.settle()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, total)
+
+	for p := 0; p < publishers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < messages; i++ {
+				if err := b.Publish(tp, message(p*messages+i)); err != nil {
+					errs <- err
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(s.opts.Timeout):
+		t.Fatalf("Received %d of %d messages", atomic.LoadInt64(&received), total)
+	}
+}
+
+func (s *suite) testHandlerError(t *testing.T) {
+	b := s.connect(t)
+	defer s.disconnect(t, b)
+
+	tp := topic()
+	ch := make(chan *broker.Message, 10)
+	var calls int64
+
+	sub := s.subscribe(t, b, tp, func(e broker.Event) error {
+		ch <- e.Message()
+		if atomic.AddInt64(&calls, 1) == 1 {
+			return errors.New("handler error")
+		}
+		return nil
+	}, broker.Queue("error-"+uuid.New().String()))
+	defer sub.Unsubscribe()
+
+	s.settle()
+
+	// brokers may return the handler error from publish
+	b.Publish(tp, message(1))
+
+	first := s.receive(t, ch, 1)[0]
+	if first.Header["Id"] != "1" {
+		t.Fatalf("Expected message 1 got %s", first.Header["Id"])
+	}
+
+	if s.opts.Redelivery {
+		if m := s.receive(t, ch, 1)[0]; m.Header["Id"] != "1" {
+			t.Fatalf("Expected message 1 to be redelivered got %s", m.Header["Id"])
+		}
+	}
+
+	// the subscription keeps receiving after an error
+	s.publish(t, b, tp, message(2))
+
+	for {
+		m := s.receive(t, ch, 1)[0]
+		if m.Header["Id"] == "2" {
+			break
+		}
+		if !s.opts.Redelivery {
+			t.Fatalf("Unexpected redelivery of message %s", m.Header["Id"])
+		}
+	}
+}
+
+func (s *suite) testUnsubscribe(t *testing.T) {
+	b := s.connect(t)
+	defer s.disconnect(t, b)
+
+	tp := topic()
+	ch := make(chan *broker.Message, 10)
+
+	sub := s.subscribe(t, b, tp, handler(ch))
+
+	s.settle()
+	s.publish(t, b, tp, message(1))
+	s.receive(t, ch, 1)
+
+	if err := sub.Unsubscribe(); err != nil {
+		t.Fatalf("Unsubscribe: %v", err)
+	}
+
+	s.settle()
+	s.publish(t, b, tp, message(2))
+	s.none(t, ch)
+}
+
+func (s *suite) testReconnect(t *testing.T) {
+	b := s.connect(t)
+
+	tp := topic()
+	ch := make(chan *broker.Message, 10)
+
+	if err := b.Disconnect(); err != nil {
+		t.Fatalf("Disconnect: %v", err)
+	}
+	if err := b.Connect(); err != nil {
+		t.Fatalf("Reconnect: %v", err)
+	}
+	defer s.disconnect(t, b)
+
+	// the broker works again after reconnecting
+	sub := s.subscribe(t, b, tp, handler(ch))
+	defer sub.Unsubscribe()
+
+	s.settle()
+	s.publish(t, b, tp, message(1))
+	s.receive(t, ch, 1)
+}
+
+// Benchmark publishing and round trips through the broker
+func Benchmark(b *testing.B, newBroker NewBroker, opts ...Option) {
+	options := newOptions(opts...)
+
+	for _, size := range []int{64, 1024, 16384} {
+		body := bytes.Repeat([]byte("a"), size)
+
+		b.Run(fmt.Sprintf("Publish/%d", size), func(b *testing.B) {
+			br := newBroker()
+			if err := br.Connect(); err != nil {
+				b.Fatal(err)
+			}
+			defer br.Disconnect()
+
+			tp := topic()
+			m := &broker.Message{Header: map[string]string{"Id": "1"}, Body: body}
+
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if err := br.Publish(tp, m); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("RoundTrip/%d", size), func(b *testing.B) {
+			br := newBroker()
+			if err := br.Connect(); err != nil {
+				b.Fatal(err)
+			}
+			defer br.Disconnect()
+
+			tp := topic()
+			m := &broker.Message{Header: map[string]string{"Id": "1"}, Body: body}
+			ch := make(chan struct{}, 1)
+
+			sub, err := br.Subscribe(tp, func(broker.Event) error {
+				ch <- struct{}{}
+				return nil
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer sub.Unsubscribe()
+
+			time.Sleep(options.Settle)
+
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if err := br.Publish(tp, m); err != nil {
+					b.Fatal(err)
+				}
+				select {
+				case <-ch:
+				case <-time.After(options.Timeout):
+					b.Fatal("timed out waiting for message")
+				}
+			}
+		})
+	}
+}
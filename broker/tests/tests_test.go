@@ -0,0 +1,20 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/broker/memory"
+)
+
+func newMemory() broker.Broker {
+	return memory.NewBroker()
+}
+
+func TestMemory(t *testing.T) {
+	Run(t, newMemory, WithoutQueue())
+}
+
+func BenchmarkMemory(b *testing.B) {
+	Benchmark(b, newMemory)
+}
@@ -0,0 +1,17 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/client"
+)
+
+// setClientOption returns a function to setup a context with given value
+func setClientOption(k, v interface{}) client.Option {
+	return func(o *client.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
@@ -0,0 +1,84 @@
+// Package grpc provides the go-micro grpc client with flow control for streams
+package grpc
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/client"
+	mgrpc "github.com/micro/go-micro/v2/client/grpc"
+	"google.golang.org/grpc"
+)
+
+/*
+	The go-micro grpc client buffers as much of a stream as gRPC lets it, which
+	grows to 16MB per stream on fast connections, and Send blocks while the server
+	doesn't read. The window size options bound what's buffered for slow consumers
+	and SendNonBlock queues the messages sent up to a limit instead of blocking.
+*/
+
+type grpcClient struct {
+	client.Client
+
+	mode       SendMode
+	bufferSize int
+}
+
+func (g *grpcClient) configure(ctx context.Context) client.Option {
+	if v, ok := ctx.Value(sendModeKey{}).(SendMode); ok {
+		g.mode = v
+	}
+	if v, ok := ctx.Value(sendBufferSizeKey{}).(int); ok && v > 0 {
+		g.bufferSize = v
+	}
+
+	var opts []grpc.DialOption
+	if v, ok := ctx.Value(dialOptionsKey{}).([]grpc.DialOption); ok {
+		opts = append(opts, v...)
+	}
+	if v, ok := ctx.Value(streamWindowSizeKey{}).(int32); ok {
+		opts = append(opts, grpc.WithInitialWindowSize(v))
+	}
+	if v, ok := ctx.Value(connWindowSizeKey{}).(int32); ok {
+		opts = append(opts, grpc.WithInitialConnWindowSize(v))
+	}
+
+	// each stream is dialed with the dial options of the client call options
+	return func(o *client.Options) {
+		if len(opts) > 0 {
+			mgrpc.DialOptions(opts...)(&o.CallOptions)
+		}
+	}
+}
+
+func (g *grpcClient) Init(opts ...client.Option) error {
+	options := g.Client.Options()
+	for _, o := range opts {
+		o(&options)
+	}
+	return g.Client.Init(append(opts, g.configure(options.Context))...)
+}
+
+// Stream returns a stream sending with the send mode of the client
+func (g *grpcClient) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
+	s, err := g.Client.Stream(ctx, req, opts...)
+	if err != nil || g.mode != SendNonBlock {
+		return s, err
+	}
+	return newStream(s, g.bufferSize), nil
+}
+
+func (g *grpcClient) String() string {
+	return "grpc"
+}
+
+// NewClient returns the go-micro grpc client with the flow control options
+func NewClient(opts ...client.Option) client.Client {
+	g := &grpcClient{
+		bufferSize: DefaultSendBufferSize,
+	}
+
+	options := client.NewOptions(opts...)
+	g.Client = mgrpc.NewClient(append(opts, g.configure(options.Context))...)
+
+	return g
+}
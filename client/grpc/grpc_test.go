@@ -0,0 +1,180 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/codec/bytes"
+	"google.golang.org/grpc"
+)
+
+type frameCodec struct{}
+
+func (frameCodec) Marshal(v interface{}) ([]byte, error) {
+	return v.(*bytes.Frame).Data, nil
+}
+
+func (frameCodec) Unmarshal(data []byte, v interface{}) error {
+	v.(*bytes.Frame).Data = append([]byte(nil), data...)
+	return nil
+}
+
+func (frameCodec) String() string {
+	return "bytes"
+}
+
+// echo sends back what's received until the client closes the stream
+func echo(srv interface{}, s grpc.ServerStream) error {
+	for {
+		var f bytes.Frame
+		if err := s.RecvMsg(&f); err != nil {
+			return nil
+		}
+		if err := s.SendMsg(&f); err != nil {
+			return err
+		}
+	}
+}
+
+type testStream struct {
+	client.Stream
+
+	sent    chan interface{}
+	release chan bool
+	err     error
+}
+
+func (t *testStream) Send(msg interface{}) error {
+	<-t.release
+	if t.err != nil {
+		return t.err
+	}
+	t.sent <- msg
+	return nil
+}
+
+func (t *testStream) Error() error {
+	return nil
+}
+
+func (t *testStream) Close() error {
+	return nil
+}
+
+func TestStreamSendNonBlock(t *testing.T) {
+	ts := &testStream{
+		sent:    make(chan interface{}, 4),
+		release: make(chan bool),
+	}
+	s := newStream(ts, 2)
+
+	// the first message is held by the sender, the next two are queued
+	for i := 0; i < 3; i++ {
+		if err := s.Send(i); err == ErrSendBufferFull {
+			// the sender hasn't taken the first message yet
+			i--
+			continue
+		} else if err != nil {
+			t.Fatalf("Unexpected send error: %v", err)
+		}
+	}
+	if err := s.Send(3); err != ErrSendBufferFull {
+		t.Fatalf("Expected %v, got %v", ErrSendBufferFull, err)
+	}
+
+	close(ts.release)
+	if err := s.Close(); err != nil {
+		t.Fatalf("Unexpected close error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if v := <-ts.sent; v != i {
+			t.Fatalf("Expected message %d, got %v", i, v)
+		}
+	}
+	if err := s.Send(4); err == nil {
+		t.Fatal("Expected an error sending on a closed stream")
+	}
+}
+
+func TestStreamSendError(t *testing.T) {
+	ts := &testStream{
+		sent:    make(chan interface{}, 1),
+		release: make(chan bool),
+		err:     errors.New("send failed"),
+	}
+	close(ts.release)
+	s := newStream(ts, 2)
+
+	if err := s.Send(0); err != nil {
+		t.Fatalf("Unexpected send error: %v", err)
+	}
+	<-s.done
+
+	if err := s.Send(1); err != ts.err {
+		t.Fatalf("Expected %v, got %v", ts.err, err)
+	}
+	if err := s.Error(); err != ts.err {
+		t.Fatalf("Expected %v, got %v", ts.err, err)
+	}
+}
+
+func TestGRPCClientStream(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	srv := grpc.NewServer(grpc.CustomCodec(frameCodec{}), grpc.UnknownServiceHandler(echo))
+	go srv.Serve(l)
+	defer srv.Stop()
+
+	testCases := []struct {
+		name string
+		mode SendMode
+	}{
+		{"block", SendBlock},
+		{"non-block", SendNonBlock},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewClient(
+				StreamWindowSize(1<<16),
+				ConnWindowSize(1<<16),
+				WithSendMode(tc.mode),
+				SendBufferSize(4),
+			)
+
+			req := c.NewRequest("test.service", "Test.Echo", &bytes.Frame{},
+				client.WithContentType("application/grpc+bytes"), client.StreamingRequest())
+			s, err := c.Stream(context.TODO(), req, client.WithAddress(l.Addr().String()))
+			if err != nil {
+				t.Fatalf("Unexpected stream error: %v", err)
+			}
+			if _, ok := s.(*stream); ok != (tc.mode == SendNonBlock) {
+				t.Fatalf("Unexpected stream %T for send mode %d", s, tc.mode)
+			}
+
+			for _, msg := range []string{"foo", "bar"} {
+				if err := s.Send(&bytes.Frame{Data: []byte(msg)}); err != nil {
+					t.Fatalf("Unexpected send error: %v", err)
+				}
+				var rsp bytes.Frame
+				if err := s.Recv(&rsp); err != nil {
+					t.Fatalf("Unexpected recv error: %v", err)
+				}
+				if string(rsp.Data) != msg {
+					t.Fatalf("Expected %s, got %s", msg, rsp.Data)
+				}
+			}
+
+			if err := s.Close(); err != nil {
+				t.Fatalf("Unexpected close error: %v", err)
+			}
+		})
+	}
+}
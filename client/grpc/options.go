@@ -0,0 +1,44 @@
+package grpc
+
+import (
+	"github.com/micro/go-micro/v2/client"
+	"google.golang.org/grpc"
+)
+
+type streamWindowSizeKey struct{}
+type connWindowSizeKey struct{}
+type sendModeKey struct{}
+type sendBufferSizeKey struct{}
+type dialOptionsKey struct{}
+
+// StreamWindowSize limits the bytes received and not yet read of each stream,
+// once they're buffered the server is held back by gRPC flow control. It also
+// turns off the window gRPC otherwise grows up to 16MB for fast connections.
+// gRPC ignores sizes below 64KB.
+func StreamWindowSize(n int32) client.Option {
+	return setClientOption(streamWindowSizeKey{}, n)
+}
+
+// ConnWindowSize limits the bytes received and not yet read of all the
+// streams of a connection
+func ConnWindowSize(n int32) client.Option {
+	return setClientOption(connWindowSizeKey{}, n)
+}
+
+// WithSendMode sets how Send of streams behaves when the server doesn't keep up,
+// SendBlock by default
+func WithSendMode(m SendMode) client.Option {
+	return setClientOption(sendModeKey{}, m)
+}
+
+// SendBufferSize sets how many messages streams queue with SendNonBlock,
+// DefaultSendBufferSize by default
+func SendBufferSize(n int) client.Option {
+	return setClientOption(sendBufferSizeKey{}, n)
+}
+
+// DialOptions to be used to configure gRPC dial options. Use it instead of the
+// DialOptions of the go-micro grpc client, they would replace the window sizes.
+func DialOptions(opts ...grpc.DialOption) client.Option {
+	return setClientOption(dialOptionsKey{}, opts)
+}
@@ -0,0 +1,112 @@
+package grpc
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/micro/go-micro/v2/client"
+)
+
+// SendMode is how Send of a stream behaves when the server doesn't keep up
+type SendMode int
+
+const (
+	// SendBlock blocks Send until the message is written, gRPC flow control
+	// holds it back while the receive window of the server is full
+	SendBlock SendMode = iota
+	// SendNonBlock queues the messages up to the send buffer size, Send returns
+	// ErrSendBufferFull once it's full. The messages mustn't be changed after
+	// they're sent and the error of a failed send is returned by the next.
+	SendNonBlock
+)
+
+var (
+	// DefaultSendBufferSize is how many messages streams queue with SendNonBlock
+	DefaultSendBufferSize = 64
+
+	// ErrSendBufferFull is returned by Send with SendNonBlock while the queue is full
+	ErrSendBufferFull = errors.New("stream send buffer is full")
+)
+
+// stream queues the messages sent and sends them on its own goroutine
+type stream struct {
+	client.Stream
+
+	queue chan interface{}
+	// closed once the queue is sent or a send failed
+	done chan bool
+
+	sync.Mutex
+	closed bool
+	err    error
+}
+
+func newStream(s client.Stream, size int) *stream {
+	st := &stream{
+		Stream: s,
+		queue:  make(chan interface{}, size),
+		done:   make(chan bool),
+	}
+	go st.send()
+	return st
+}
+
+func (s *stream) send() {
+	defer close(s.done)
+
+	for msg := range s.queue {
+		if err := s.Stream.Send(msg); err != nil {
+			s.Lock()
+			s.err = err
+			s.Unlock()
+			return
+		}
+	}
+}
+
+// Send queues the message without waiting for it to be written
+func (s *stream) Send(msg interface{}) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	if s.closed {
+		return io.EOF
+	}
+
+	select {
+	case s.queue <- msg:
+		return nil
+	default:
+		return ErrSendBufferFull
+	}
+}
+
+func (s *stream) Error() error {
+	s.Lock()
+	err := s.err
+	s.Unlock()
+
+	if err != nil {
+		return err
+	}
+	return s.Stream.Error()
+}
+
+// Close sends the queued messages before closing the stream, cancel the
+// context of the stream to drop them
+func (s *stream) Close() error {
+	s.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.Unlock()
+
+	<-s.done
+
+	return s.Stream.Close()
+}
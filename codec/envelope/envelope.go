@@ -0,0 +1,187 @@
+// Package envelope provides a broker codec which wraps messages in a protobuf
+// envelope carrying the content type, schema version and compression of the
+// payload, so consumers can handle several versions of a message during
+// rolling upgrades.
+package envelope
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/codec/json"
+	pb "github.com/micro/go-plugins/codec/envelope/v2/proto"
+)
+
+var (
+	// ContentTypeHeader of messages is the content type of their payload
+	ContentTypeHeader = "Content-Type"
+	// VersionHeader of messages is the schema version of their payload
+	VersionHeader = "Micro-Schema-Version"
+
+	// ErrUnsupportedVersion is returned for messages of schema versions which aren't unmarshaled
+	ErrUnsupportedVersion = errors.New("unsupported schema version")
+
+	// version of the envelope format, it's the first field so envelopes
+	// start with its tag and value
+	version = uint32(1)
+	prefix  = []byte{0x08, 0x01}
+)
+
+// Marshaler is a broker codec which wraps messages in an envelope
+type Marshaler struct {
+	opts Options
+}
+
+func (m *Marshaler) compress(env *pb.Envelope) error {
+	if m.opts.Compress <= 0 || len(env.Payload) < m.opts.Compress {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(env.Payload); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	env.Payload = buf.Bytes()
+	env.Compression = pb.Compression_GZIP
+	return nil
+}
+
+func decompress(env *pb.Envelope) error {
+	switch env.Compression {
+	case pb.Compression_NONE:
+		return nil
+	case pb.Compression_GZIP:
+		r, err := gzip.NewReader(bytes.NewReader(env.Payload))
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		env.Payload = b
+		env.Compression = pb.Compression_NONE
+		return nil
+	default:
+		return fmt.Errorf("unsupported compression %v", env.Compression)
+	}
+}
+
+func (m *Marshaler) supported(v string) bool {
+	if len(m.opts.Versions) == 0 {
+		return true
+	}
+	for _, s := range m.opts.Versions {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Marshal wraps broker messages in an envelope with their header, other values
+// are marshaled with the codec and wrapped
+func (m *Marshaler) Marshal(v interface{}) ([]byte, error) {
+	env := &pb.Envelope{
+		Version:       version,
+		ContentType:   m.opts.ContentType,
+		SchemaVersion: m.opts.Version,
+	}
+
+	if msg, ok := v.(*broker.Message); ok {
+		env.Header = msg.Header
+		env.Payload = msg.Body
+
+		if ct, ok := msg.Header[ContentTypeHeader]; ok {
+			env.ContentType = ct
+		}
+		if sv, ok := msg.Header[VersionHeader]; ok {
+			env.SchemaVersion = sv
+		}
+	} else {
+		b, err := m.opts.Codec.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		env.Payload = b
+
+		if len(env.ContentType) == 0 {
+			env.ContentType = "application/" + m.opts.Codec.String()
+		}
+	}
+
+	if err := m.compress(env); err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(env)
+}
+
+// Unmarshal unwraps the envelope into a broker message, setting its content
+// type and version headers, or unmarshals its payload with the codec into other
+// values. Data which isn't in an envelope is unmarshaled with the codec.
+func (m *Marshaler) Unmarshal(d []byte, v interface{}) error {
+	if !bytes.HasPrefix(d, prefix) {
+		return m.opts.Codec.Unmarshal(d, v)
+	}
+
+	env := new(pb.Envelope)
+	if err := proto.Unmarshal(d, env); err != nil {
+		return err
+	}
+
+	if !m.supported(env.SchemaVersion) {
+		return fmt.Errorf("%w %q", ErrUnsupportedVersion, env.SchemaVersion)
+	}
+
+	if err := decompress(env); err != nil {
+		return err
+	}
+
+	msg, ok := v.(*broker.Message)
+	if !ok {
+		return m.opts.Codec.Unmarshal(env.Payload, v)
+	}
+
+	msg.Header = env.Header
+	if msg.Header == nil {
+		msg.Header = make(map[string]string)
+	}
+	if len(env.ContentType) > 0 {
+		msg.Header[ContentTypeHeader] = env.ContentType
+	}
+	if len(env.SchemaVersion) > 0 {
+		msg.Header[VersionHeader] = env.SchemaVersion
+	}
+	msg.Body = env.Payload
+
+	return nil
+}
+
+func (m *Marshaler) String() string {
+	return "envelope"
+}
+
+// NewMarshaler returns a broker codec which wraps messages in an envelope
+func NewMarshaler(opts ...Option) *Marshaler {
+	options := Options{
+		Codec: json.Marshaler{},
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &Marshaler{opts: options}
+}
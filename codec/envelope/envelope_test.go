@@ -0,0 +1,130 @@
+package envelope
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/codec/json"
+)
+
+func TestMarshaler(t *testing.T) {
+	m := NewMarshaler(WithVersion("1"))
+
+	d, err := m.Marshal(&broker.Message{
+		Header: map[string]string{"Micro-Id": "1", ContentTypeHeader: "application/protobuf"},
+		Body:   []byte("body"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg broker.Message
+	if err := m.Unmarshal(d, &msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(msg.Body) != "body" {
+		t.Fatalf("Expected body %q, got %q", "body", msg.Body)
+	}
+	expected := map[string]string{
+		"Micro-Id":        "1",
+		ContentTypeHeader: "application/protobuf",
+		VersionHeader:     "1",
+	}
+	for k, v := range expected {
+		if msg.Header[k] != v {
+			t.Fatalf("Expected header %s to be %q, got %q", k, v, msg.Header[k])
+		}
+	}
+}
+
+func TestMarshalerValue(t *testing.T) {
+	m := NewMarshaler()
+
+	d, err := m.Marshal(map[string]string{"foo": "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg broker.Message
+	if err := m.Unmarshal(d, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header[ContentTypeHeader] != "application/json" {
+		t.Fatalf("Expected content type of the codec, got %q", msg.Header[ContentTypeHeader])
+	}
+
+	var v map[string]string
+	if err := m.Unmarshal(d, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v["foo"] != "bar" {
+		t.Fatalf("Expected value bar, got %v", v)
+	}
+}
+
+func TestMarshalerCompress(t *testing.T) {
+	m := NewMarshaler(WithCompress(64))
+	body := bytes.Repeat([]byte("a"), 1024)
+
+	for _, b := range [][]byte{[]byte("small"), body} {
+		d, err := m.Marshal(&broker.Message{Body: b})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) >= 64 && len(d) >= len(b) {
+			t.Fatalf("Expected compressed message, got %d bytes", len(d))
+		}
+
+		var msg broker.Message
+		if err := m.Unmarshal(d, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(msg.Body, b) {
+			t.Fatalf("Expected body of %d bytes, got %d", len(b), len(msg.Body))
+		}
+	}
+}
+
+func TestMarshalerLegacy(t *testing.T) {
+	d, err := json.Marshaler{}.Marshal(&broker.Message{
+		Header: map[string]string{"Micro-Id": "1"},
+		Body:   []byte("body"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg broker.Message
+	if err := NewMarshaler(WithVersions("2")).Unmarshal(d, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Body) != "body" || msg.Header["Micro-Id"] != "1" {
+		t.Fatalf("Expected legacy message to be unmarshaled, got %+v", msg)
+	}
+}
+
+func TestMarshalerVersions(t *testing.T) {
+	m := NewMarshaler(WithVersions("2", "3"))
+
+	for v, ok := range map[string]bool{"1": false, "2": true, "": false} {
+		d, err := m.Marshal(&broker.Message{
+			Header: map[string]string{VersionHeader: v},
+			Body:   []byte("body"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var msg broker.Message
+		err = m.Unmarshal(d, &msg)
+		if ok && err != nil {
+			t.Fatalf("Expected version %q to be unmarshaled, got %v", v, err)
+		}
+		if !ok && !errors.Is(err, ErrUnsupportedVersion) {
+			t.Fatalf("Expected version %q to be unsupported, got %v", v, err)
+		}
+	}
+}
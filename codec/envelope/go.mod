@@ -0,0 +1,8 @@
+module github.com/micro/go-plugins/codec/envelope/v2
+
+go 1.13
+
+require (
+	github.com/golang/protobuf v1.4.0
+	github.com/micro/go-micro/v2 v2.9.1
+)
@@ -0,0 +1,61 @@
+package envelope
+
+import (
+	"github.com/micro/go-micro/v2/codec"
+)
+
+// Options of the envelope marshaler
+type Options struct {
+	// Codec marshals the payload of values which aren't broker messages, and
+	// unmarshals data which isn't in an envelope
+	Codec codec.Marshaler
+	// ContentType of payloads without a Content-Type header
+	ContentType string
+	// Version is the schema version of messages without a version header
+	Version string
+	// Versions are the schema versions unmarshaled, all are if empty
+	Versions []string
+	// Compress gzips payloads of at least the size in bytes, zero doesn't compress
+	Compress int
+}
+
+// Option sets an option of the envelope marshaler
+type Option func(o *Options)
+
+// WithCodec sets the codec of payloads which aren't broker messages and of data
+// which isn't in an envelope, such as messages published before upgrading
+func WithCodec(c codec.Marshaler) Option {
+	return func(o *Options) {
+		o.Codec = c
+	}
+}
+
+// WithContentType sets the content type of payloads without a Content-Type header
+func WithContentType(ct string) Option {
+	return func(o *Options) {
+		o.ContentType = ct
+	}
+}
+
+// WithVersion sets the schema version of messages without a version header
+func WithVersion(v string) Option {
+	return func(o *Options) {
+		o.Version = v
+	}
+}
+
+// WithVersions only unmarshals messages of the schema versions, others return
+// ErrUnsupportedVersion. Messages without a version are only unmarshaled if the
+// versions include the empty string.
+func WithVersions(v ...string) Option {
+	return func(o *Options) {
+		o.Versions = v
+	}
+}
+
+// WithCompress gzips payloads of at least the size in bytes
+func WithCompress(size int) Option {
+	return func(o *Options) {
+		o.Compress = size
+	}
+}
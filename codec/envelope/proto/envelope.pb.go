@@ -0,0 +1,166 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: github.com/micro/go-plugins/codec/envelope/v2/proto/envelope.proto
+
+package envelope
+
+import (
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// Compression of the payload
+type Compression int32
+
+const (
+	Compression_NONE Compression = 0
+	Compression_GZIP Compression = 1
+)
+
+var Compression_name = map[int32]string{
+	0: "NONE",
+	1: "GZIP",
+}
+
+var Compression_value = map[string]int32{
+	"NONE": 0,
+	"GZIP": 1,
+}
+
+func (x Compression) String() string {
+	return proto.EnumName(Compression_name, int32(x))
+}
+
+func (Compression) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_103ef613951e5b04, []int{0}
+}
+
+// Envelope wraps the payload of a message with its content type and schema version
+type Envelope struct {
+	// version of the envelope format
+	Version uint32 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// content type of the payload e.g application/json
+	ContentType string `protobuf:"bytes,2,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	// schema version of the payload
+	SchemaVersion string `protobuf:"bytes,3,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	// compression of the payload
+	Compression Compression `protobuf:"varint,4,opt,name=compression,proto3,enum=envelope.Compression" json:"compression,omitempty"`
+	// header of the message
+	Header map[string]string `protobuf:"bytes,5,rep,name=header,proto3" json:"header,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// payload of the message
+	Payload              []byte   `protobuf:"bytes,6,opt,name=payload,proto3" json:"payload,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Envelope) Reset()         { *m = Envelope{} }
+func (m *Envelope) String() string { return proto.CompactTextString(m) }
+func (*Envelope) ProtoMessage()    {}
+func (*Envelope) Descriptor() ([]byte, []int) {
+	return fileDescriptor_103ef613951e5b04, []int{0}
+}
+
+func (m *Envelope) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Envelope.Unmarshal(m, b)
+}
+func (m *Envelope) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Envelope.Marshal(b, m, deterministic)
+}
+func (m *Envelope) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Envelope.Merge(m, src)
+}
+func (m *Envelope) XXX_Size() int {
+	return xxx_messageInfo_Envelope.Size(m)
+}
+func (m *Envelope) XXX_DiscardUnknown() {
+	xxx_messageInfo_Envelope.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Envelope proto.InternalMessageInfo
+
+func (m *Envelope) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *Envelope) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+func (m *Envelope) GetSchemaVersion() string {
+	if m != nil {
+		return m.SchemaVersion
+	}
+	return ""
+}
+
+func (m *Envelope) GetCompression() Compression {
+	if m != nil {
+		return m.Compression
+	}
+	return Compression_NONE
+}
+
+func (m *Envelope) GetHeader() map[string]string {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *Envelope) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("envelope.Compression", Compression_name, Compression_value)
+	proto.RegisterType((*Envelope)(nil), "envelope.Envelope")
+	proto.RegisterMapType((map[string]string)(nil), "envelope.Envelope.HeaderEntry")
+}
+
+func init() {
+	proto.RegisterFile("github.com/micro/go-plugins/codec/envelope/v2/proto/envelope.proto", fileDescriptor_103ef613951e5b04)
+}
+
+var fileDescriptor_103ef613951e5b04 = []byte{
+	// 299 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x90, 0x5d, 0x4b, 0xf3, 0x30,
+	0x14, 0xc7, 0x9f, 0xec, 0xed, 0xd9, 0x4e, 0xb7, 0x31, 0x82, 0x42, 0xf1, 0x42, 0x3a, 0x41, 0x28,
+	0x82, 0x2d, 0x4c, 0xf0, 0xed, 0x52, 0x19, 0xea, 0xcd, 0x94, 0x20, 0x5e, 0x78, 0x33, 0xba, 0xec,
+	0xb0, 0x15, 0xdb, 0x24, 0xa4, 0x59, 0xa1, 0xdf, 0xcb, 0x0f, 0x28, 0xcb, 0x9a, 0x6d, 0x77, 0xe7,
+	0x77, 0xf2, 0x3b, 0x87, 0xf3, 0x0f, 0x3c, 0xad, 0x52, 0xb3, 0xde, 0x2c, 0x22, 0x2e, 0xf3, 0x38,
+	0x4f, 0xb9, 0x96, 0xf1, 0x4a, 0x5e, 0xab, 0x6c, 0xb3, 0x4a, 0x45, 0x11, 0x73, 0xb9, 0x44, 0x1e,
+	0xa3, 0x28, 0x31, 0x93, 0x0a, 0xe3, 0x72, 0x12, 0x2b, 0x2d, 0x8d, 0xdc, 0x77, 0x22, 0x8b, 0xb4,
+	0xeb, 0xf8, 0xe2, 0xb7, 0x01, 0xdd, 0x69, 0x0d, 0xd4, 0x87, 0xff, 0x25, 0xea, 0x22, 0x95, 0xc2,
+	0x27, 0x01, 0x09, 0x07, 0xcc, 0x21, 0x1d, 0x43, 0x9f, 0x4b, 0x61, 0x50, 0x98, 0xb9, 0xa9, 0x14,
+	0xfa, 0x8d, 0x80, 0x84, 0x3d, 0xe6, 0xd5, 0xbd, 0xcf, 0x4a, 0x21, 0xbd, 0x84, 0x61, 0xc1, 0xd7,
+	0x98, 0x27, 0x73, 0xb7, 0xa3, 0x69, 0xa5, 0xc1, 0xae, 0xfb, 0x55, 0x6f, 0xba, 0x03, 0x8f, 0xcb,
+	0x5c, 0x69, 0x2c, 0xac, 0xd3, 0x0a, 0x48, 0x38, 0x9c, 0x9c, 0x46, 0xfb, 0x03, 0x9f, 0x0f, 0x8f,
+	0xec, 0xd8, 0xa4, 0xb7, 0xd0, 0x59, 0x63, 0xb2, 0x44, 0xed, 0xb7, 0x83, 0x66, 0xe8, 0x4d, 0xce,
+	0x0f, 0x33, 0x2e, 0x40, 0xf4, 0x6a, 0x85, 0xa9, 0x30, 0xba, 0x62, 0xb5, 0xbd, 0x0d, 0xa5, 0x92,
+	0x2a, 0x93, 0xc9, 0xd2, 0xef, 0x04, 0x24, 0xec, 0x33, 0x87, 0x67, 0x0f, 0xe0, 0x1d, 0x0d, 0xd0,
+	0x11, 0x34, 0x7f, 0xb0, 0xb2, 0xc9, 0x7b, 0x6c, 0x5b, 0xd2, 0x13, 0x68, 0x97, 0x49, 0xb6, 0x71,
+	0x71, 0x77, 0xf0, 0xd8, 0xb8, 0x27, 0x57, 0x63, 0xf0, 0x8e, 0x0e, 0xa5, 0x5d, 0x68, 0xcd, 0xde,
+	0x67, 0xd3, 0xd1, 0xbf, 0x6d, 0xf5, 0xf2, 0xfd, 0xf6, 0x31, 0x22, 0x8b, 0x8e, 0xfd, 0xea, 0x9b,
+	0xbf, 0x01, 0x00, 0x29, 0xf1, 0xc5, 0x3b, 0xb0, 0x01, 0x00, 0x00,
+}
@@ -0,0 +1,38 @@
+package encrypted
+
+import (
+	"bytes"
+	"io/ioutil"
+
+	"filippo.io/age"
+)
+
+type ageDecrypter struct {
+	identities []age.Identity
+}
+
+func (a *ageDecrypter) Decrypt(ct []byte) ([]byte, error) {
+	r, err := age.Decrypt(bytes.NewReader(ct), a.identities...)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+func (a *ageDecrypter) String() string {
+	return "age"
+}
+
+// NewAgeDecrypter returns a decrypter for values encrypted with age
+// to one of the given identities e.g AGE-SECRET-KEY-1...
+func NewAgeDecrypter(identities ...string) (Decrypter, error) {
+	ids := make([]age.Identity, 0, len(identities))
+	for _, i := range identities {
+		id, err := age.ParseX25519Identity(i)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return &ageDecrypter{identities: ids}, nil
+}
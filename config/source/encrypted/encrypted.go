@@ -0,0 +1,191 @@
+// Package encrypted is a config source which decrypts values read from another source
+package encrypted
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/micro/go-micro/v2/config/encoder"
+	"github.com/micro/go-micro/v2/config/encoder/hcl"
+	"github.com/micro/go-micro/v2/config/encoder/json"
+	"github.com/micro/go-micro/v2/config/encoder/toml"
+	"github.com/micro/go-micro/v2/config/encoder/xml"
+	"github.com/micro/go-micro/v2/config/encoder/yaml"
+	"github.com/micro/go-micro/v2/config/source"
+)
+
+// Decrypter decrypts a single ciphertext value
+type Decrypter interface {
+	Decrypt(ciphertext []byte) ([]byte, error)
+	String() string
+}
+
+type encrypted struct {
+	opts      source.Options
+	source    source.Source
+	decrypter Decrypter
+}
+
+var (
+	// ErrNoSource is returned when no underlying source was set
+	ErrNoSource = errors.New("no source set")
+
+	// DefaultPrefix marks the start of an encrypted value e.g ENC[base64 ciphertext]
+	DefaultPrefix = "ENC["
+	// DefaultSuffix marks the end of an encrypted value
+	DefaultSuffix = "]"
+
+	encoders = map[string]encoder.Encoder{
+		"json": json.NewEncoder(),
+		"yaml": yaml.NewEncoder(),
+		"toml": toml.NewEncoder(),
+		"xml":  xml.NewEncoder(),
+		"hcl":  hcl.NewEncoder(),
+	}
+)
+
+func (e *encrypted) Read() (*source.ChangeSet, error) {
+	if e.source == nil {
+		return nil, ErrNoSource
+	}
+	cs, err := e.source.Read()
+	if err != nil {
+		return nil, err
+	}
+	return e.decrypt(cs)
+}
+
+// Write is unsupported
+func (e *encrypted) Write(cs *source.ChangeSet) error {
+	return nil
+}
+
+func (e *encrypted) Watch() (source.Watcher, error) {
+	if e.source == nil {
+		return nil, ErrNoSource
+	}
+	w, err := e.source.Watch()
+	if err != nil {
+		return nil, err
+	}
+	return &watcher{e: e, w: w}, nil
+}
+
+func (e *encrypted) String() string {
+	return "encrypted"
+}
+
+// decrypt decodes the change set, replaces every encrypted string value
+// with its plaintext and encodes the result back into the same format
+func (e *encrypted) decrypt(cs *source.ChangeSet) (*source.ChangeSet, error) {
+	enc, ok := encoders[cs.Format]
+	if !ok {
+		enc = e.opts.Encoder
+	}
+
+	var data interface{}
+	if err := enc.Decode(cs.Data, &data); err != nil {
+		return nil, fmt.Errorf("error decoding source: %v", err)
+	}
+
+	data, err := e.walk(data)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := enc.Encode(data)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding source: %v", err)
+	}
+
+	ncs := &source.ChangeSet{
+		Timestamp: time.Now(),
+		Format:    enc.String(),
+		Source:    cs.Source,
+		Data:      b,
+	}
+	ncs.Checksum = ncs.Sum()
+
+	return ncs, nil
+}
+
+func (e *encrypted) walk(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			nv, err := e.walk(val)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", k, err)
+			}
+			t[k] = nv
+		}
+		return t, nil
+	case map[interface{}]interface{}:
+		// yaml decodes nested maps with interface keys
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			nv, err := e.walk(val)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", k, err)
+			}
+			m[fmt.Sprintf("%v", k)] = nv
+		}
+		return m, nil
+	case []interface{}:
+		for i, val := range t {
+			nv, err := e.walk(val)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			t[i] = nv
+		}
+		return t, nil
+	case string:
+		if !strings.HasPrefix(t, DefaultPrefix) || !strings.HasSuffix(t, DefaultSuffix) {
+			return t, nil
+		}
+		ct, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(t, DefaultPrefix), DefaultSuffix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid encrypted value: %v", err)
+		}
+		pt, err := e.decrypter.Decrypt(ct)
+		if err != nil {
+			return nil, fmt.Errorf("%s decrypt failed: %v", e.decrypter.String(), err)
+		}
+		return string(pt), nil
+	default:
+		return v, nil
+	}
+}
+
+// NewSource returns a source which decrypts the values of the source set
+// with WithSource using the decrypter set with WithDecrypter
+func NewSource(opts ...source.Option) source.Source {
+	options := source.NewOptions(opts...)
+
+	src, _ := options.Context.Value(sourceKey{}).(source.Source)
+
+	d, ok := options.Context.Value(decrypterKey{}).(Decrypter)
+	if !ok {
+		d = noopDecrypter{}
+	}
+
+	return &encrypted{
+		opts:      options,
+		source:    src,
+		decrypter: d,
+	}
+}
+
+type noopDecrypter struct{}
+
+func (noopDecrypter) Decrypt([]byte) ([]byte, error) {
+	return nil, errors.New("no decrypter set")
+}
+
+func (noopDecrypter) String() string {
+	return "noop"
+}
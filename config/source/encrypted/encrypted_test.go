@@ -0,0 +1,91 @@
+package encrypted
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/config/source/memory"
+)
+
+func encrypt(t *testing.T, r age.Recipient, v string) string {
+	buf := bytes.NewBuffer(nil)
+	w, err := age.Encrypt(buf, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, v); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return DefaultPrefix + base64.StdEncoding.EncodeToString(buf.Bytes()) + DefaultSuffix
+}
+
+func TestAgeSource(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewAgeDecrypter(id.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte(`{
+		"database": {
+			"address": "10.0.0.1",
+			"password": "` + encrypt(t, id.Recipient(), "secret") + `"
+		},
+		"tokens": ["` + encrypt(t, id.Recipient(), "token") + `"]
+	}`)
+
+	src := NewSource(
+		WithSource(memory.NewSource(memory.WithJSON(data))),
+		WithDecrypter(d),
+	)
+
+	conf, err := config.NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.Load(src); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := conf.Get("database", "address").String(""); v != "10.0.0.1" {
+		t.Fatalf("expected 10.0.0.1 got %s", v)
+	}
+	if v := conf.Get("database", "password").String(""); v != "secret" {
+		t.Fatalf("expected secret got %s", v)
+	}
+	if v := conf.Get("tokens").StringSlice(nil); len(v) != 1 || v[0] != "token" {
+		t.Fatalf("expected [token] got %v", v)
+	}
+}
+
+func TestInvalidValue(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := NewAgeDecrypter(id.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := NewSource(
+		WithSource(memory.NewSource(memory.WithJSON([]byte(`{"password": "ENC[bm90IGFnZQ==]"}`)))),
+		WithDecrypter(d),
+	)
+
+	if _, err := src.Read(); err == nil {
+		t.Fatal("expected error reading invalid ciphertext")
+	}
+}
@@ -0,0 +1,9 @@
+module github.com/micro/go-plugins/config/source/encrypted/v2
+
+go 1.13
+
+require (
+	filippo.io/age v1.0.0
+	github.com/aws/aws-sdk-go v1.28.4
+	github.com/micro/go-micro/v2 v2.9.1
+)
@@ -0,0 +1,90 @@
+package encrypted
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+)
+
+type kmsDecrypter struct {
+	client   kmsiface.KMSAPI
+	context  map[string]*string
+	envelope bool
+}
+
+func (k *kmsDecrypter) Decrypt(ct []byte) ([]byte, error) {
+	if !k.envelope {
+		return k.decrypt(ct)
+	}
+
+	// envelope format: uint16 key length | encrypted data key | nonce | sealed data
+	if len(ct) < 2 {
+		return nil, errors.New("invalid envelope")
+	}
+	n := int(binary.BigEndian.Uint16(ct))
+	ct = ct[2:]
+	if len(ct) < n {
+		return nil, errors.New("invalid envelope")
+	}
+
+	key, err := k.decrypt(ct[:n])
+	if err != nil {
+		return nil, err
+	}
+	ct = ct[n:]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(ct) < gcm.NonceSize() {
+		return nil, errors.New("invalid envelope")
+	}
+	return gcm.Open(nil, ct[:gcm.NonceSize()], ct[gcm.NonceSize():], nil)
+}
+
+func (k *kmsDecrypter) decrypt(ct []byte) ([]byte, error) {
+	rsp, err := k.client.Decrypt(&kms.DecryptInput{
+		CiphertextBlob:    ct,
+		EncryptionContext: k.context,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rsp.Plaintext, nil
+}
+
+func (k *kmsDecrypter) String() string {
+	return "kms"
+}
+
+// NewKMSDecrypter returns a decrypter for values encrypted directly with
+// AWS KMS. The encryption context is optional.
+func NewKMSDecrypter(client kmsiface.KMSAPI, context map[string]string) Decrypter {
+	return &kmsDecrypter{
+		client:  client,
+		context: aws.StringMap(context),
+	}
+}
+
+// NewKMSEnvelopeDecrypter returns a decrypter for values using envelope
+// encryption. Each value holds a KMS encrypted AES-256 data key followed
+// by the AES-GCM nonce and sealed data:
+//
+//	uint16 key length | encrypted data key | nonce | sealed data
+func NewKMSEnvelopeDecrypter(client kmsiface.KMSAPI, context map[string]string) Decrypter {
+	return &kmsDecrypter{
+		client:   client,
+		context:  aws.StringMap(context),
+		envelope: true,
+	}
+}
@@ -0,0 +1,30 @@
+package encrypted
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/config/source"
+)
+
+type sourceKey struct{}
+type decrypterKey struct{}
+
+// WithSource sets the underlying source to read encrypted values from
+func WithSource(s source.Source) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, sourceKey{}, s)
+	}
+}
+
+// WithDecrypter sets the decrypter used for encrypted values
+func WithDecrypter(d Decrypter) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, decrypterKey{}, d)
+	}
+}
@@ -0,0 +1,22 @@
+package encrypted
+
+import (
+	"github.com/micro/go-micro/v2/config/source"
+)
+
+type watcher struct {
+	e *encrypted
+	w source.Watcher
+}
+
+func (w *watcher) Next() (*source.ChangeSet, error) {
+	cs, err := w.w.Next()
+	if err != nil {
+		return nil, err
+	}
+	return w.e.decrypt(cs)
+}
+
+func (w *watcher) Stop() error {
+	return w.w.Stop()
+}
@@ -0,0 +1,36 @@
+package priority
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/config/source"
+)
+
+type sourcesKey struct{}
+type handlersKey struct{}
+
+// WithSource adds a source with the given priority. Values from
+// sources with a higher priority override those with a lower one.
+func WithSource(s source.Source, priority int) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		sources, _ := o.Context.Value(sourcesKey{}).([]*prioritySource)
+		sources = append(append([]*prioritySource{}, sources...), &prioritySource{Source: s, priority: priority})
+		o.Context = context.WithValue(o.Context, sourcesKey{}, sources)
+	}
+}
+
+// WithChangeHandler adds a handler called with the value changes
+// detected each time the sources are merged
+func WithChangeHandler(h ChangeHandler) source.Option {
+	return func(o *source.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		handlers, _ := o.Context.Value(handlersKey{}).([]ChangeHandler)
+		handlers = append(append([]ChangeHandler{}, handlers...), h)
+		o.Context = context.WithValue(o.Context, handlersKey{}, handlers)
+	}
+}
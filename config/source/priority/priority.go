@@ -0,0 +1,176 @@
+// Package priority is a config source which merges other sources by precedence
+package priority
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/config/encoder"
+	"github.com/micro/go-micro/v2/config/encoder/hcl"
+	"github.com/micro/go-micro/v2/config/encoder/json"
+	"github.com/micro/go-micro/v2/config/encoder/toml"
+	"github.com/micro/go-micro/v2/config/encoder/xml"
+	"github.com/micro/go-micro/v2/config/encoder/yaml"
+	"github.com/micro/go-micro/v2/config/source"
+)
+
+// Change is a single value change between two merged reads
+type Change struct {
+	// Path of the changed value
+	Path []string
+	// Old is nil when the value was added
+	Old interface{}
+	// New is nil when the value was removed
+	New interface{}
+	// Source that now provides the value
+	Source string
+}
+
+// ChangeHandler is called with the changes detected on each update
+type ChangeHandler func([]*Change)
+
+type prioritySource struct {
+	source.Source
+	priority int
+}
+
+type priority struct {
+	opts     source.Options
+	sources  []*prioritySource
+	handlers []ChangeHandler
+
+	sync.Mutex
+	// last merged values used to compute changes
+	values map[string]interface{}
+	// source which provided each leaf value
+	origin map[string]string
+}
+
+var (
+	// ErrNoSources is returned when no sources were set
+	ErrNoSources = errors.New("no sources set")
+
+	encoders = map[string]encoder.Encoder{
+		"json": json.NewEncoder(),
+		"yaml": yaml.NewEncoder(),
+		"toml": toml.NewEncoder(),
+		"xml":  xml.NewEncoder(),
+		"hcl":  hcl.NewEncoder(),
+	}
+)
+
+func (p *priority) Read() (*source.ChangeSet, error) {
+	if len(p.sources) == 0 {
+		return nil, ErrNoSources
+	}
+
+	sets := make([]*source.ChangeSet, len(p.sources))
+	for i, s := range p.sources {
+		cs, err := s.Read()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", s.String(), err)
+		}
+		sets[i] = cs
+	}
+
+	return p.merge(sets)
+}
+
+// Write is unsupported
+func (p *priority) Write(cs *source.ChangeSet) error {
+	return nil
+}
+
+func (p *priority) Watch() (source.Watcher, error) {
+	if len(p.sources) == 0 {
+		return nil, ErrNoSources
+	}
+	return newWatcher(p)
+}
+
+func (p *priority) String() string {
+	return "priority"
+}
+
+// merge combines the change sets, ordered lowest to highest priority,
+// and emits the changes relative to the previous merge
+func (p *priority) merge(sets []*source.ChangeSet) (*source.ChangeSet, error) {
+	values := make(map[string]interface{})
+	origin := make(map[string]string)
+
+	for i, cs := range sets {
+		if cs == nil || len(cs.Data) == 0 {
+			continue
+		}
+
+		enc, ok := encoders[cs.Format]
+		if !ok {
+			enc = p.opts.Encoder
+		}
+
+		var data map[string]interface{}
+		if err := enc.Decode(cs.Data, &data); err != nil {
+			return nil, fmt.Errorf("error decoding %s: %v", p.sources[i].String(), err)
+		}
+
+		mergeMap(values, normalise(data).(map[string]interface{}), origin, nil, p.sources[i].String())
+	}
+
+	b, err := p.opts.Encoder.Encode(values)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding source: %v", err)
+	}
+
+	p.Lock()
+	var changes []*Change
+	if p.values != nil {
+		changes = diff(p.values, values, origin, nil)
+	}
+	p.values = values
+	p.origin = origin
+	handlers := p.handlers
+	p.Unlock()
+
+	if len(changes) > 0 {
+		for _, h := range handlers {
+			h(changes)
+		}
+	}
+
+	cs := &source.ChangeSet{
+		Timestamp: time.Now(),
+		Format:    p.opts.Encoder.String(),
+		Source:    p.String(),
+		Data:      b,
+	}
+	cs.Checksum = cs.Sum()
+
+	return cs, nil
+}
+
+// NewSource returns a source which merges the sources set with
+// WithSource. Values from sources with a higher priority override
+// those with a lower priority. Sources with equal priority are
+// applied in the order they are set.
+func NewSource(opts ...source.Option) source.Source {
+	options := source.NewOptions(opts...)
+
+	sources, _ := options.Context.Value(sourcesKey{}).([]*prioritySource)
+	handlers, _ := options.Context.Value(handlersKey{}).([]ChangeHandler)
+
+	// copy so the options context is not modified
+	sorted := make([]*prioritySource, len(sources))
+	copy(sorted, sources)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].priority < sorted[j].priority
+	})
+
+	return &priority{
+		opts:     options,
+		sources:  sorted,
+		handlers: handlers,
+	}
+}
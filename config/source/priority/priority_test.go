@@ -0,0 +1,88 @@
+package priority
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/config/source"
+	"github.com/micro/go-micro/v2/config/source/memory"
+)
+
+func TestPriority(t *testing.T) {
+	low := memory.NewSource(memory.WithJSON([]byte(`{"db": {"host": "localhost", "port": 3306}, "debug": false}`)))
+	high := memory.NewSource(memory.WithJSON([]byte(`{"db": {"host": "10.0.0.1"}}`)))
+
+	src := NewSource(
+		// added out of order to check sorting
+		WithSource(high, 10),
+		WithSource(low, 1),
+	)
+
+	conf, err := config.NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.Load(src); err != nil {
+		t.Fatal(err)
+	}
+
+	if v := conf.Get("db", "host").String(""); v != "10.0.0.1" {
+		t.Fatalf("expected 10.0.0.1 got %s", v)
+	}
+	if v := conf.Get("db", "port").Int(0); v != 3306 {
+		t.Fatalf("expected 3306 got %d", v)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	low := memory.NewSource(memory.WithJSON([]byte(`{"db": {"host": "localhost", "port": 3306}}`)))
+	high := memory.NewSource(memory.WithJSON([]byte(`{"db": {"host": "10.0.0.1"}}`)))
+
+	changes := make(chan []*Change, 1)
+
+	src := NewSource(
+		WithSource(low, 1),
+		WithSource(high, 2),
+		WithChangeHandler(func(c []*Change) {
+			changes <- c
+		}),
+	)
+
+	w, err := src.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	high.Write(&source.ChangeSet{
+		Data:   []byte(`{"db": {"host": "10.0.0.2"}, "debug": true}`),
+		Format: "json",
+	})
+
+	if _, err := w.Next(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := <-changes
+	if len(c) != 2 {
+		t.Fatalf("expected 2 changes got %d", len(c))
+	}
+
+	if p := strings.Join(c[0].Path, "."); p != "db.host" {
+		t.Fatalf("expected db.host got %s", p)
+	}
+	if c[0].Old != "10.0.0.1" || c[0].New != "10.0.0.2" {
+		t.Fatalf("unexpected change %v -> %v", c[0].Old, c[0].New)
+	}
+	if c[0].Source != "memory" {
+		t.Fatalf("expected source memory got %s", c[0].Source)
+	}
+
+	if p := strings.Join(c[1].Path, "."); p != "debug" {
+		t.Fatalf("expected debug got %s", p)
+	}
+	if c[1].Old != nil || c[1].New != true {
+		t.Fatalf("unexpected change %v -> %v", c[1].Old, c[1].New)
+	}
+}
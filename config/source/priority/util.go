@@ -0,0 +1,113 @@
+package priority
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// normalise converts the map[interface{}]interface{} values produced by
+// some decoders into map[string]interface{}
+func normalise(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[fmt.Sprintf("%v", k)] = normalise(val)
+		}
+		return m
+	case map[string]interface{}:
+		for k, val := range t {
+			t[k] = normalise(val)
+		}
+		return t
+	case []interface{}:
+		for i, val := range t {
+			t[i] = normalise(val)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// mergeMap deep merges src into dst recording the source of each leaf
+func mergeMap(dst, src map[string]interface{}, origin map[string]string, path []string, name string) {
+	for k, v := range src {
+		p := append(append([]string{}, path...), k)
+
+		sm, sok := v.(map[string]interface{})
+		dm, dok := dst[k].(map[string]interface{})
+
+		if sok && dok {
+			mergeMap(dm, sm, origin, p, name)
+			continue
+		}
+
+		// replace the whole value and drop the origin of any old leaves
+		prefix := strings.Join(p, ".")
+		for o := range origin {
+			if o == prefix || strings.HasPrefix(o, prefix+".") {
+				delete(origin, o)
+			}
+		}
+
+		if sok {
+			m := make(map[string]interface{})
+			mergeMap(m, sm, origin, p, name)
+			dst[k] = m
+			continue
+		}
+
+		dst[k] = v
+		origin[prefix] = name
+	}
+}
+
+// diff returns the leaf changes between the old and new values
+func diff(older, newer map[string]interface{}, origin map[string]string, path []string) []*Change {
+	var changes []*Change
+
+	keys := make(map[string]bool)
+	for k := range older {
+		keys[k] = true
+	}
+	for k := range newer {
+		keys[k] = true
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	for _, k := range sorted {
+		p := append(append([]string{}, path...), k)
+		ov, ook := older[k]
+		nv, nok := newer[k]
+
+		om, omap := ov.(map[string]interface{})
+		nm, nmap := nv.(map[string]interface{})
+
+		switch {
+		case omap && nmap:
+			changes = append(changes, diff(om, nm, origin, p)...)
+		case omap:
+			changes = append(changes, diff(om, nil, origin, p)...)
+			if nok {
+				changes = append(changes, &Change{Path: p, New: nv, Source: origin[strings.Join(p, ".")]})
+			}
+		case nmap:
+			if ook {
+				changes = append(changes, &Change{Path: p, Old: ov})
+			}
+			changes = append(changes, diff(nil, nm, origin, p)...)
+		case !reflect.DeepEqual(ov, nv):
+			changes = append(changes, &Change{Path: p, Old: ov, New: nv, Source: origin[strings.Join(p, ".")]})
+		}
+	}
+
+	return changes
+}
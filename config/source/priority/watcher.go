@@ -0,0 +1,124 @@
+package priority
+
+import (
+	"sync"
+
+	"github.com/micro/go-micro/v2/config/source"
+)
+
+type watcher struct {
+	p        *priority
+	watchers []source.Watcher
+
+	sync.Mutex
+	sets []*source.ChangeSet
+
+	ch   chan *source.ChangeSet
+	errs chan error
+	exit chan bool
+}
+
+func newWatcher(p *priority) (source.Watcher, error) {
+	w := &watcher{
+		p:    p,
+		sets: make([]*source.ChangeSet, len(p.sources)),
+		ch:   make(chan *source.ChangeSet),
+		errs: make(chan error, len(p.sources)),
+		exit: make(chan bool),
+	}
+
+	// the current state of every source is needed to merge an update
+	for i, s := range p.sources {
+		cs, err := s.Read()
+		if err != nil {
+			return nil, err
+		}
+		w.sets[i] = cs
+	}
+
+	// merge once so changes are relative to the current state
+	if _, err := p.merge(w.sets); err != nil {
+		return nil, err
+	}
+
+	for _, s := range p.sources {
+		sw, err := s.Watch()
+		if err != nil {
+			w.Stop()
+			return nil, err
+		}
+		w.watchers = append(w.watchers, sw)
+	}
+
+	for i, sw := range w.watchers {
+		go w.run(i, sw)
+	}
+
+	return w, nil
+}
+
+func (w *watcher) run(i int, sw source.Watcher) {
+	for {
+		cs, err := sw.Next()
+		if err != nil {
+			select {
+			case <-w.exit:
+			case w.errs <- err:
+			}
+			return
+		}
+
+		if !w.update(i, cs) {
+			return
+		}
+	}
+}
+
+// update merges the change of a source and sends it. It's done under the lock
+// so the changes of the sources are merged and sent in order, an older merge
+// sent after a newer one would revert it. It returns false once stopped.
+func (w *watcher) update(i int, cs *source.ChangeSet) bool {
+	w.Lock()
+	defer w.Unlock()
+
+	w.sets[i] = cs
+	merged, err := w.p.merge(w.sets)
+	if err != nil {
+		return true
+	}
+
+	select {
+	case w.ch <- merged:
+		return true
+	case <-w.exit:
+		return false
+	}
+}
+
+func (w *watcher) Next() (*source.ChangeSet, error) {
+	select {
+	case cs := <-w.ch:
+		return cs, nil
+	case err := <-w.errs:
+		return nil, err
+	case <-w.exit:
+		return nil, source.ErrWatcherStopped
+	}
+}
+
+func (w *watcher) Stop() error {
+	select {
+	case <-w.exit:
+		return nil
+	default:
+		close(w.exit)
+	}
+
+	var err error
+	for _, sw := range w.watchers {
+		if e := sw.Stop(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
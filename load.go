@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/micro/cli/v2"
+)
+
+// Flag sets the shared object plugins to load at startup e.g --plugin broker/kafka.so.
+// Add it to the service with micro.Flags so the flag is accepted when parsed.
+var Flag = &cli.StringSliceFlag{
+	Name:    "plugin",
+	Usage:   "Comma-separated list of plugins built with -buildmode=plugin to load e.g broker/kafka.so",
+	EnvVars: []string{"MICRO_PLUGIN"},
+}
+
+// LoadAll loads and initialises the plugins at the given paths
+func LoadAll(paths ...string) error {
+	for _, path := range paths {
+		p, err := Load(path)
+		if err != nil {
+			return fmt.Errorf("Failed to load plugin %s: %v", path, err)
+		}
+		if err := Init(p); err != nil {
+			return fmt.Errorf("Failed to init plugin %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// Setup loads the plugins set with the --plugin flag or MICRO_PLUGIN env var.
+// It must be called before the service is initialised because the broker,
+// registry and transport flags are resolved by name when they are parsed.
+func Setup() error {
+	return LoadAll(Paths(os.Args[1:])...)
+}
+
+// Paths returns the plugin paths set in the MICRO_PLUGIN env var followed by
+// those set with the plugin flag in args. Parsing stops at a "--" terminator.
+func Paths(args []string) []string {
+	paths := split(os.Getenv("MICRO_PLUGIN"))
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		if name == arg || len(arg)-len(name) > 2 {
+			continue
+		}
+
+		switch {
+		case name == Flag.Name:
+			if i+1 < len(args) {
+				i++
+				paths = append(paths, split(args[i])...)
+			}
+		case strings.HasPrefix(name, Flag.Name+"="):
+			paths = append(paths, split(strings.TrimPrefix(name, Flag.Name+"="))...)
+		}
+	}
+
+	return paths
+}
+
+func split(v string) []string {
+	var paths []string
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); len(p) > 0 {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestPaths(t *testing.T) {
+	testCases := []struct {
+		name  string
+		env   string
+		args  []string
+		paths []string
+	}{
+		{"none", "", []string{"--registry=mdns"}, nil},
+		{"flag", "", []string{"--plugin", "broker/kafka.so"}, []string{"broker/kafka.so"}},
+		{"single dash", "", []string{"-plugin", "broker/kafka.so"}, []string{"broker/kafka.so"}},
+		{"equals", "", []string{"--plugin=broker/kafka.so, registry/etcd.so"}, []string{"broker/kafka.so", "registry/etcd.so"}},
+		{"repeated", "", []string{"--plugin=broker/kafka.so", "--plugin", "registry/etcd.so"}, []string{"broker/kafka.so", "registry/etcd.so"}},
+		{"env first", "store/redis.so", []string{"--plugin=broker/kafka.so"}, []string{"store/redis.so", "broker/kafka.so"}},
+		{"terminator", "", []string{"--", "--plugin=broker/kafka.so"}, nil},
+		{"missing value", "", []string{"--plugin"}, nil},
+		{"not a flag", "", []string{"plugin", "broker/kafka.so", "---plugin=registry/etcd.so"}, nil},
+		{"other flag", "", []string{"--plugins=broker/kafka.so"}, nil},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			os.Setenv("MICRO_PLUGIN", c.env)
+			defer os.Unsetenv("MICRO_PLUGIN")
+
+			if paths := Paths(c.args); !reflect.DeepEqual(paths, c.paths) {
+				t.Fatalf("expected paths %v got %v", c.paths, paths)
+			}
+		})
+	}
+}
+
+func TestLoadAll(t *testing.T) {
+	if err := LoadAll(); err != nil {
+		t.Fatalf("expected no plugins to load got %v", err)
+	}
+	if err := LoadAll("does/not/exist.so"); err == nil {
+		t.Fatal("expected loading a missing plugin to fail")
+	}
+}
+
+func TestSetup(t *testing.T) {
+	args := os.Args
+	defer func() {
+		os.Args = args
+	}()
+
+	os.Args = []string{"service", "--registry=mdns"}
+	if err := Setup(); err != nil {
+		t.Fatalf("expected no plugins to load got %v", err)
+	}
+
+	os.Args = []string{"service", "--plugin=does/not/exist.so"}
+	if err := Setup(); err == nil {
+		t.Fatal("expected loading a missing plugin to fail")
+	}
+}
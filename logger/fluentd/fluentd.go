@@ -0,0 +1,284 @@
+// Package fluentd is a logger which forwards structured log events to fluentd or fluent bit
+package fluentd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fluent/fluent-logger-golang/fluent"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+var (
+	// DefaultTag of log events
+	DefaultTag = "micro"
+	// DefaultBufferSize is the number of events buffered
+	DefaultBufferSize = 8192
+	// MaxBackoff between reconnect attempts
+	MaxBackoff = 10 * time.Second
+)
+
+type entry struct {
+	time   time.Time
+	record map[string]interface{}
+}
+
+// forwarder sends buffered events to fluentd, it's shared by loggers created with Fields
+type forwarder struct {
+	client *fluent.Fluent
+	tag    string
+	block  bool
+
+	entries chan *entry
+	dropped uint64
+
+	sync.RWMutex
+	closed bool
+	once   sync.Once
+	exit   chan struct{}
+	done   chan struct{}
+}
+
+type fluentLogger struct {
+	opts   logger.Options
+	fields map[string]interface{}
+	fwd    *forwarder
+}
+
+func newForwarder(opts logger.Options) (*forwarder, error) {
+	cfg := fluent.Config{}
+	tag := DefaultTag
+	size := DefaultBufferSize
+	var block bool
+
+	if ctx := opts.Context; ctx != nil {
+		if v, ok := ctx.Value(configKey{}).(fluent.Config); ok {
+			cfg = v
+		}
+		if v, ok := ctx.Value(tagKey{}).(string); ok && len(v) > 0 {
+			tag = v
+		}
+		if v, ok := ctx.Value(bufferSizeKey{}).(int); ok && v > 0 {
+			size = v
+		}
+		if v, ok := ctx.Value(blockKey{}).(bool); ok {
+			block = v
+		}
+	}
+
+	// events are buffered and retried by the forwarder
+	cfg.Async = false
+	if cfg.MaxRetry == 0 {
+		cfg.MaxRetry = 1
+	}
+
+	// the connection is retried on the first post if fluentd is down
+	client, err := fluent.New(cfg)
+	if client == nil {
+		return nil, err
+	}
+
+	f := &forwarder{
+		client:  client,
+		tag:     tag,
+		block:   block,
+		entries: make(chan *entry, size),
+		exit:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go f.run()
+
+	return f, nil
+}
+
+func (f *forwarder) run() {
+	defer close(f.done)
+
+	for e := range f.entries {
+		f.post(e)
+
+		if n := atomic.SwapUint64(&f.dropped, 0); n > 0 {
+			f.post(&entry{
+				time: time.Now(),
+				record: map[string]interface{}{
+					"level":   logger.WarnLevel.String(),
+					"message": fmt.Sprintf("dropped %d log events while fluentd was unreachable", n),
+				},
+			})
+		}
+	}
+}
+
+// post sends an event, reconnecting with backoff until it succeeds or the logger is closed
+func (f *forwarder) post(e *entry) {
+	backoff := 100 * time.Millisecond
+
+	for {
+		err := f.client.PostWithTime(f.tag, e.time, e.record)
+		if err == nil {
+			return
+		}
+
+		// drain the buffer quickly once closed
+		select {
+		case <-f.exit:
+			fmt.Fprintf(os.Stderr, "[fluentd] dropping log event: %v\n", err)
+			return
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > MaxBackoff {
+			backoff = MaxBackoff
+		}
+	}
+}
+
+func (f *forwarder) enqueue(e *entry) {
+	f.RLock()
+	defer f.RUnlock()
+
+	if f.closed {
+		return
+	}
+
+	if f.block {
+		f.entries <- e
+		return
+	}
+
+	select {
+	case f.entries <- e:
+	default:
+		atomic.AddUint64(&f.dropped, 1)
+	}
+}
+
+// close sends the buffered events then closes the connection
+func (f *forwarder) close() error {
+	f.once.Do(func() {
+		// stop retrying so blocked loggers are released
+		close(f.exit)
+
+		f.Lock()
+		f.closed = true
+		close(f.entries)
+		f.Unlock()
+	})
+
+	<-f.done
+	return f.client.Close()
+}
+
+func (l *fluentLogger) Init(opts ...logger.Option) error {
+	for _, o := range opts {
+		o(&l.opts)
+	}
+
+	fwd, err := newForwarder(l.opts)
+	if err != nil {
+		return err
+	}
+
+	if l.fwd != nil {
+		l.fwd.close()
+	}
+
+	l.fwd = fwd
+	l.fields = copyFields(l.opts.Fields)
+
+	return nil
+}
+
+func copyFields(src map[string]interface{}) map[string]interface{} {
+	dst := make(map[string]interface{}, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+func (l *fluentLogger) Fields(fields map[string]interface{}) logger.Logger {
+	nfields := copyFields(l.fields)
+	for k, v := range fields {
+		nfields[k] = v
+	}
+
+	return &fluentLogger{
+		opts:   l.opts,
+		fields: nfields,
+		fwd:    l.fwd,
+	}
+}
+
+func (l *fluentLogger) Log(level logger.Level, args ...interface{}) {
+	if !l.opts.Level.Enabled(level) {
+		return
+	}
+	l.log(level, fmt.Sprint(args...))
+}
+
+func (l *fluentLogger) Logf(level logger.Level, format string, args ...interface{}) {
+	if !l.opts.Level.Enabled(level) {
+		return
+	}
+	l.log(level, fmt.Sprintf(format, args...))
+}
+
+func (l *fluentLogger) log(level logger.Level, msg string) {
+	record := make(map[string]interface{}, len(l.fields)+2)
+	for k, v := range l.fields {
+		// errors and stringers aren't encoded by msgpack
+		switch t := v.(type) {
+		case error:
+			v = t.Error()
+		case fmt.Stringer:
+			v = t.String()
+		}
+		record[k] = v
+	}
+	record["level"] = level.String()
+	record["message"] = msg
+
+	l.fwd.enqueue(&entry{time: time.Now(), record: record})
+
+	if level == logger.FatalLevel {
+		l.fwd.close()
+		os.Exit(1)
+	}
+}
+
+func (l *fluentLogger) Options() logger.Options {
+	return l.opts
+}
+
+// Close sends buffered events and closes the connection to fluentd
+func (l *fluentLogger) Close() error {
+	return l.fwd.close()
+}
+
+func (l *fluentLogger) String() string {
+	return "fluentd"
+}
+
+// NewLogger returns a logger which forwards events to fluentd, it implements
+// io.Closer to flush buffered events on shutdown
+func NewLogger(opts ...logger.Option) (logger.Logger, error) {
+	options := logger.Options{
+		Level:   logger.InfoLevel,
+		Fields:  make(map[string]interface{}),
+		Out:     os.Stderr,
+		Context: context.Background(),
+	}
+
+	l := &fluentLogger{opts: options}
+	if err := l.Init(opts...); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
@@ -0,0 +1,117 @@
+package fluentd
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fluent/fluent-logger-golang/fluent"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+// read the next forwarded event from the listener's first connection
+func read(t *testing.T, dec *json.Decoder) (string, map[string]interface{}) {
+	var msg []json.RawMessage
+	if err := dec.Decode(&msg); err != nil {
+		t.Fatal(err)
+	}
+	var tag string
+	var record map[string]interface{}
+	json.Unmarshal(msg[0], &tag)
+	json.Unmarshal(msg[2], &record)
+	return tag, record
+}
+
+func TestForward(t *testing.T) {
+	// reserve a port then log while fluentd is down
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().(*net.TCPAddr)
+	l.Close()
+
+	log, err := NewLogger(
+		WithTag("greeter"),
+		WithConfig(fluent.Config{
+			FluentHost:    "127.0.0.1",
+			FluentPort:    addr.Port,
+			MarshalAsJSON: true,
+		}),
+		logger.WithFields(map[string]interface{}{"service": "greeter"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log.Log(logger.DebugLevel, "filtered")
+	log.Fields(map[string]interface{}{"id": 1, "error": errors.New("failed")}).Logf(logger.ErrorLevel, "request %d", 1)
+
+	time.Sleep(200 * time.Millisecond)
+
+	// the event is sent once fluentd is reachable
+	l, err = net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(addr.Port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	tag, record := read(t, json.NewDecoder(conn))
+	if tag != "greeter" {
+		t.Fatalf("Expected tag greeter got %s", tag)
+	}
+	if record["message"] != "request 1" || record["level"] != "error" || record["error"] != "failed" || record["service"] != "greeter" || record["id"] != float64(1) {
+		t.Fatalf("Unexpected record %v", record)
+	}
+
+	if err := log.(*fluentLogger).Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDrop(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	log, err := NewLogger(
+		WithBufferSize(1),
+		WithConfig(fluent.Config{FluentHost: "127.0.0.1", FluentPort: port}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.(*fluentLogger).Close()
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 10; i++ {
+			log.Log(logger.InfoLevel, "dropped")
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected logging not to block")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if n := log.(*fluentLogger).fwd.dropped; n == 0 {
+		t.Fatal("Expected dropped events")
+	}
+}
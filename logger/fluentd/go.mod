@@ -0,0 +1,11 @@
+module github.com/micro/go-plugins/logger/fluentd/v2
+
+go 1.13
+
+require (
+	github.com/fluent/fluent-logger-golang v1.5.0
+	github.com/micro/go-micro/v2 v2.9.1
+	github.com/philhofer/fwd v1.2.0 // indirect
+	github.com/tinylib/msgp v1.1.2 // indirect
+	github.com/ttacon/chalk v0.0.0-20160626202418-22c06c80ed31 // indirect
+)
@@ -0,0 +1,35 @@
+package fluentd
+
+import (
+	"github.com/fluent/fluent-logger-golang/fluent"
+	"github.com/micro/go-micro/v2/logger"
+)
+
+type configKey struct{}
+
+// WithConfig sets the fluent client config, e.g. the host and port
+func WithConfig(c fluent.Config) logger.Option {
+	return logger.SetOption(configKey{}, c)
+}
+
+type tagKey struct{}
+
+// WithTag sets the tag of log events, defaults to micro
+func WithTag(tag string) logger.Option {
+	return logger.SetOption(tagKey{}, tag)
+}
+
+type bufferSizeKey struct{}
+
+// WithBufferSize sets how many events are buffered while fluentd is unreachable
+func WithBufferSize(n int) logger.Option {
+	return logger.SetOption(bufferSizeKey{}, n)
+}
+
+type blockKey struct{}
+
+// WithBlocking makes logging block when the buffer is full rather than
+// dropping events, applying backpressure to the service
+func WithBlocking() logger.Option {
+	return logger.SetOption(blockKey{}, true)
+}
@@ -0,0 +1,397 @@
+// Package loki is a logger which pushes log lines to grafana loki
+package loki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/logger"
+)
+
+var (
+	// DefaultURL of the loki push api
+	DefaultURL = "http://localhost:3100/loki/api/v1/push"
+	// DefaultBatchSize is the most lines pushed at once
+	DefaultBatchSize = 1000
+	// DefaultBatchWait is how long lines wait to be batched
+	DefaultBatchWait = time.Second
+	// DefaultBufferSize is the most lines buffered
+	DefaultBufferSize = 10000
+	// MaxBackoff between retries
+	MaxBackoff = 30 * time.Second
+	// MaxRetries of a batch before it's dropped
+	MaxRetries = 10
+	// FlushTimeout is how long closing waits for buffered lines to be pushed
+	FlushTimeout = 5 * time.Second
+)
+
+type line struct {
+	level logger.Level
+	time  time.Time
+	text  string
+}
+
+// pusher batches lines and pushes them to loki, it's shared by loggers created with Fields
+type pusher struct {
+	url       string
+	tenant    string
+	labels    map[string]string
+	client    *http.Client
+	batchSize int
+	batchWait time.Duration
+
+	lines chan *line
+
+	sync.Mutex
+	dropped int
+
+	once  sync.Once
+	exit  chan struct{}
+	abort chan struct{}
+	done  chan struct{}
+}
+
+type lokiLogger struct {
+	opts   logger.Options
+	fields map[string]interface{}
+	push   *pusher
+}
+
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type request struct {
+	Streams []stream `json:"streams"`
+}
+
+func newPusher(opts logger.Options) *pusher {
+	p := &pusher{
+		url:       DefaultURL,
+		labels:    map[string]string{},
+		client:    &http.Client{Timeout: 10 * time.Second},
+		batchSize: DefaultBatchSize,
+		batchWait: DefaultBatchWait,
+		exit:      make(chan struct{}),
+		abort:     make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	size := DefaultBufferSize
+
+	if ctx := opts.Context; ctx != nil {
+		if v, ok := ctx.Value(urlKey{}).(string); ok && len(v) > 0 {
+			p.url = v
+		}
+		if v, ok := ctx.Value(labelsKey{}).(map[string]string); ok {
+			p.labels = v
+		}
+		if v, ok := ctx.Value(batchSizeKey{}).(int); ok && v > 0 {
+			p.batchSize = v
+		}
+		if v, ok := ctx.Value(batchWaitKey{}).(time.Duration); ok && v > 0 {
+			p.batchWait = v
+		}
+		if v, ok := ctx.Value(bufferSizeKey{}).(int); ok && v > 0 {
+			size = v
+		}
+		if v, ok := ctx.Value(clientKey{}).(*http.Client); ok {
+			p.client = v
+		}
+		if v, ok := ctx.Value(tenantKey{}).(string); ok {
+			p.tenant = v
+		}
+	}
+
+	p.lines = make(chan *line, size)
+
+	go p.run()
+
+	return p
+}
+
+func (p *pusher) enqueue(l *line) {
+	select {
+	case <-p.exit:
+		return
+	default:
+	}
+
+	select {
+	case p.lines <- l:
+	default:
+		p.Lock()
+		p.dropped++
+		p.Unlock()
+	}
+}
+
+func (p *pusher) run() {
+	defer close(p.done)
+
+	t := time.NewTicker(p.batchWait)
+	defer t.Stop()
+
+	batch := make([]*line, 0, p.batchSize)
+
+	flush := func() {
+		p.Lock()
+		dropped := p.dropped
+		p.dropped = 0
+		p.Unlock()
+
+		if dropped > 0 {
+			batch = append(batch, &line{
+				level: logger.WarnLevel,
+				time:  time.Now(),
+				text:  fmt.Sprintf(`{"message":"dropped %d log lines while loki was unreachable"}`, dropped),
+			})
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if err := p.send(batch); err != nil {
+			fmt.Fprintf(os.Stderr, "[loki] dropping %d log lines: %v\n", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-p.exit:
+			// push the buffered lines until the flush timeout
+			for {
+				select {
+				case l := <-p.lines:
+					batch = append(batch, l)
+					if len(batch) >= p.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		case l := <-p.lines:
+			batch = append(batch, l)
+			if len(batch) >= p.batchSize {
+				flush()
+			}
+		case <-t.C:
+			flush()
+		}
+	}
+}
+
+// encode groups the lines into a stream per level
+func (p *pusher) encode(batch []*line) ([]byte, error) {
+	streams := map[logger.Level]*stream{}
+	var levels []logger.Level
+
+	for _, l := range batch {
+		s, ok := streams[l.level]
+		if !ok {
+			labels := make(map[string]string, len(p.labels)+1)
+			for k, v := range p.labels {
+				labels[k] = v
+			}
+			labels["level"] = l.level.String()
+			s = &stream{Stream: labels}
+			streams[l.level] = s
+			levels = append(levels, l.level)
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(l.time.UnixNano(), 10), l.text})
+	}
+
+	sort.Slice(levels, func(i, j int) bool { return levels[i] < levels[j] })
+
+	req := request{}
+	for _, lvl := range levels {
+		req.Streams = append(req.Streams, *streams[lvl])
+	}
+
+	return json.Marshal(req)
+}
+
+// send pushes the batch, retrying with backoff on network errors, 429s and 5xxs
+func (p *pusher) send(batch []*line) error {
+	b, err := p.encode(batch)
+	if err != nil {
+		return err
+	}
+
+	backoff := 500 * time.Millisecond
+
+	for i := 0; ; i++ {
+		retry, err := p.post(b)
+		if err == nil || !retry || i >= MaxRetries {
+			return err
+		}
+
+		select {
+		case <-p.abort:
+			// don't hold up shutdown
+			return err
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > MaxBackoff {
+			backoff = MaxBackoff
+		}
+	}
+}
+
+func (p *pusher) post(b []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(b))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(p.tenant) > 0 {
+		req.Header.Set("X-Scope-OrgID", p.tenant)
+	}
+
+	rsp, err := p.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode/100 == 2 {
+		io.Copy(ioutil.Discard, rsp.Body)
+		return false, nil
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(rsp.Body, 1024))
+	err = fmt.Errorf("push failed: %s %s", rsp.Status, bytes.TrimSpace(msg))
+
+	return rsp.StatusCode == http.StatusTooManyRequests || rsp.StatusCode >= 500, err
+}
+
+// close pushes the buffered lines and stops the pusher
+func (p *pusher) close() error {
+	p.once.Do(func() {
+		close(p.exit)
+
+		select {
+		case <-p.done:
+		case <-time.After(FlushTimeout):
+			close(p.abort)
+		}
+	})
+	<-p.done
+	return nil
+}
+
+func (l *lokiLogger) Init(opts ...logger.Option) error {
+	for _, o := range opts {
+		o(&l.opts)
+	}
+
+	if l.push != nil {
+		l.push.close()
+	}
+
+	l.push = newPusher(l.opts)
+	l.fields = copyFields(l.opts.Fields)
+
+	return nil
+}
+
+func copyFields(src map[string]interface{}) map[string]interface{} {
+	dst := make(map[string]interface{}, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+func (l *lokiLogger) Fields(fields map[string]interface{}) logger.Logger {
+	nfields := copyFields(l.fields)
+	for k, v := range fields {
+		nfields[k] = v
+	}
+
+	return &lokiLogger{
+		opts:   l.opts,
+		fields: nfields,
+		push:   l.push,
+	}
+}
+
+func (l *lokiLogger) Log(level logger.Level, args ...interface{}) {
+	if !l.opts.Level.Enabled(level) {
+		return
+	}
+	l.log(level, fmt.Sprint(args...))
+}
+
+func (l *lokiLogger) Logf(level logger.Level, format string, args ...interface{}) {
+	if !l.opts.Level.Enabled(level) {
+		return
+	}
+	l.log(level, fmt.Sprintf(format, args...))
+}
+
+// log encodes the fields and message as a json line
+func (l *lokiLogger) log(level logger.Level, msg string) {
+	data := make(map[string]interface{}, len(l.fields)+1)
+	for k, v := range l.fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		data[k] = v
+	}
+	data["message"] = msg
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		b, _ = json.Marshal(map[string]string{"message": msg})
+	}
+
+	l.push.enqueue(&line{level: level, time: time.Now(), text: string(b)})
+
+	if level == logger.FatalLevel {
+		l.push.close()
+		os.Exit(1)
+	}
+}
+
+func (l *lokiLogger) Options() logger.Options {
+	return l.opts
+}
+
+// Close pushes buffered lines to loki
+func (l *lokiLogger) Close() error {
+	return l.push.close()
+}
+
+func (l *lokiLogger) String() string {
+	return "loki"
+}
+
+// NewLogger returns a logger which pushes lines to loki, it implements
+// io.Closer to push buffered lines on shutdown
+func NewLogger(opts ...logger.Option) logger.Logger {
+	options := logger.Options{
+		Level:   logger.InfoLevel,
+		Fields:  make(map[string]interface{}),
+		Out:     os.Stderr,
+		Context: context.Background(),
+	}
+
+	l := &lokiLogger{opts: options}
+	l.Init(opts...)
+
+	return l
+}
@@ -0,0 +1,126 @@
+package loki
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/logger"
+)
+
+func TestPush(t *testing.T) {
+	var mtx sync.Mutex
+	var pushes []request
+	var attempts int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		attempts++
+		// the first push is retried
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("X-Scope-OrgID") != "tenant" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req request
+		json.NewDecoder(r.Body).Decode(&req)
+		pushes = append(pushes, req)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	l := NewLogger(
+		WithURL(srv.URL),
+		WithTenant("tenant"),
+		WithBatchWait(time.Hour),
+		WithLabels(map[string]string{"service": "greeter", "version": "latest"}),
+		logger.WithFields(map[string]interface{}{"node": "1"}),
+	)
+
+	l.Log(logger.DebugLevel, "filtered")
+	l.Log(logger.InfoLevel, "started")
+	l.Fields(map[string]interface{}{"error": errors.New("failed")}).Logf(logger.ErrorLevel, "request %d", 1)
+
+	// close pushes the buffered lines
+	if err := l.(*lokiLogger).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if attempts != 2 || len(pushes) != 1 {
+		t.Fatalf("Expected a retried push got %d attempts %d pushes", attempts, len(pushes))
+	}
+
+	streams := pushes[0].Streams
+	if len(streams) != 2 {
+		t.Fatalf("Expected a stream per level got %+v", streams)
+	}
+
+	info, errs := streams[0], streams[1]
+	if info.Stream["level"] != "info" || info.Stream["service"] != "greeter" || info.Stream["version"] != "latest" {
+		t.Fatalf("Unexpected labels %v", info.Stream)
+	}
+	if len(info.Values) != 1 || info.Values[0][1] != `{"message":"started","node":"1"}` {
+		t.Fatalf("Unexpected values %v", info.Values)
+	}
+	if errs.Stream["level"] != "error" || errs.Values[0][1] != `{"error":"failed","message":"request 1","node":"1"}` {
+		t.Fatalf("Unexpected error stream %+v", errs)
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	block := make(chan bool)
+	var mtx sync.Mutex
+	var lines []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+		var req request
+		json.NewDecoder(r.Body).Decode(&req)
+		mtx.Lock()
+		for _, s := range req.Streams {
+			for _, v := range s.Values {
+				lines = append(lines, v[1])
+			}
+		}
+		mtx.Unlock()
+	}))
+	defer srv.Close()
+
+	l := NewLogger(WithURL(srv.URL), WithBatchSize(1), WithBufferSize(2))
+
+	// the first push blocks so later lines overflow the buffer
+	for i := 0; i < 10; i++ {
+		l.Log(logger.InfoLevel, "line")
+	}
+	close(block)
+
+	l.(*lokiLogger).Close()
+
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if len(lines) >= 10 {
+		t.Fatalf("Expected lines to be dropped got %d", len(lines))
+	}
+	var warned bool
+	for _, line := range lines {
+		warned = warned || strings.HasPrefix(line, `{"message":"dropped `)
+	}
+	if !warned {
+		t.Fatalf("Expected a dropped warning got %v", lines)
+	}
+}
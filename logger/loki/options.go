@@ -0,0 +1,59 @@
+package loki
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/micro/go-micro/v2/logger"
+)
+
+type urlKey struct{}
+
+// WithURL sets the loki push url, defaults to http://localhost:3100/loki/api/v1/push
+func WithURL(url string) logger.Option {
+	return logger.SetOption(urlKey{}, url)
+}
+
+type labelsKey struct{}
+
+// WithLabels sets the stream labels e.g. service, version and node. Labels
+// should have few values, use fields for everything else
+func WithLabels(labels map[string]string) logger.Option {
+	return logger.SetOption(labelsKey{}, labels)
+}
+
+type batchSizeKey struct{}
+
+// WithBatchSize sets the most lines pushed at once
+func WithBatchSize(n int) logger.Option {
+	return logger.SetOption(batchSizeKey{}, n)
+}
+
+type batchWaitKey struct{}
+
+// WithBatchWait sets how long lines wait to be batched
+func WithBatchWait(d time.Duration) logger.Option {
+	return logger.SetOption(batchWaitKey{}, d)
+}
+
+type bufferSizeKey struct{}
+
+// WithBufferSize sets the most lines buffered while loki is unreachable,
+// further lines are dropped
+func WithBufferSize(n int) logger.Option {
+	return logger.SetOption(bufferSizeKey{}, n)
+}
+
+type clientKey struct{}
+
+// WithClient sets the http client, e.g. to add auth
+func WithClient(c *http.Client) logger.Option {
+	return logger.SetOption(clientKey{}, c)
+}
+
+type tenantKey struct{}
+
+// WithTenant sets the X-Scope-OrgID header of multi-tenant lokis
+func WithTenant(id string) logger.Option {
+	return logger.SetOption(tenantKey{}, id)
+}
@@ -0,0 +1,138 @@
+package syslog
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/micro/go-micro/v2/logger"
+)
+
+// Facility of syslog messages
+type Facility int
+
+const (
+	Kern Facility = iota
+	User
+	Mail
+	Daemon
+	Auth
+	Syslog
+	Lpr
+	News
+	Uucp
+	Cron
+	Authpriv
+	Ftp
+	Local0 Facility = iota + 4
+	Local1
+	Local2
+	Local3
+	Local4
+	Local5
+	Local6
+	Local7
+)
+
+// severity of a logger level
+func severity(level logger.Level) int {
+	switch level {
+	case logger.TraceLevel, logger.DebugLevel:
+		return 7
+	case logger.InfoLevel:
+		return 6
+	case logger.WarnLevel:
+		return 4
+	case logger.ErrorLevel:
+		return 3
+	case logger.FatalLevel:
+		return 2
+	default:
+		return 5
+	}
+}
+
+// header fields are printable ascii without spaces, empty is the nil value
+func header(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, s)
+	if len(s) == 0 {
+		return "-"
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	return s
+}
+
+// paramName removes the characters not allowed in sd names
+func paramName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	return s
+}
+
+var valueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// structuredData maps fields to an sd element sorted by name
+func structuredData(id string, fields map[string]interface{}) string {
+	if len(fields) == 0 {
+		return "-"
+	}
+
+	names := make([]string, 0, len(fields))
+	for k := range fields {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("[")
+	b.WriteString(id)
+	for _, k := range names {
+		b.WriteString(" ")
+		b.WriteString(paramName(k))
+		b.WriteString(`="`)
+		b.WriteString(valueEscaper.Replace(fmt.Sprint(fields[k])))
+		b.WriteString(`"`)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// format returns an rfc5424 message
+func format(facility Facility, level logger.Level, t time.Time, hostname, app string, pid int, sdID string, fields map[string]interface{}, msg string) string {
+	pri := int(facility)*8 + severity(level)
+
+	var b strings.Builder
+	b.WriteString("<")
+	b.WriteString(strconv.Itoa(pri))
+	b.WriteString(">1 ")
+	b.WriteString(t.UTC().Format("2006-01-02T15:04:05.000000Z07:00"))
+	b.WriteString(" ")
+	b.WriteString(header(hostname, 255))
+	b.WriteString(" ")
+	b.WriteString(header(app, 48))
+	b.WriteString(" ")
+	b.WriteString(strconv.Itoa(pid))
+	b.WriteString(" - ")
+	b.WriteString(structuredData(sdID, fields))
+	if len(msg) > 0 {
+		b.WriteString(" ")
+		b.WriteString(msg)
+	}
+	return b.String()
+}
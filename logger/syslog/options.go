@@ -0,0 +1,56 @@
+package syslog
+
+import (
+	"crypto/tls"
+
+	"github.com/micro/go-micro/v2/logger"
+)
+
+type networkKey struct{}
+
+// WithNetwork sets the transport, one of udp, tcp or tls. Defaults to udp
+func WithNetwork(network string) logger.Option {
+	return logger.SetOption(networkKey{}, network)
+}
+
+type addressKey struct{}
+
+// WithAddress sets the syslog server address, defaults to localhost:514
+func WithAddress(addr string) logger.Option {
+	return logger.SetOption(addressKey{}, addr)
+}
+
+type tlsConfigKey struct{}
+
+// WithTLSConfig sets the tls config of the tls network
+func WithTLSConfig(c *tls.Config) logger.Option {
+	return logger.SetOption(tlsConfigKey{}, c)
+}
+
+type facilityKey struct{}
+
+// WithFacility sets the facility of messages, defaults to local0
+func WithFacility(f Facility) logger.Option {
+	return logger.SetOption(facilityKey{}, f)
+}
+
+type appNameKey struct{}
+
+// WithAppName sets the APP-NAME of messages, defaults to the process name
+func WithAppName(name string) logger.Option {
+	return logger.SetOption(appNameKey{}, name)
+}
+
+type hostnameKey struct{}
+
+// WithHostname sets the HOSTNAME of messages, defaults to the os hostname
+func WithHostname(name string) logger.Option {
+	return logger.SetOption(hostnameKey{}, name)
+}
+
+type sdIDKey struct{}
+
+// WithStructuredDataID sets the SD-ID fields are sent in, defaults to fields@32473
+func WithStructuredDataID(id string) logger.Option {
+	return logger.SetOption(sdIDKey{}, id)
+}
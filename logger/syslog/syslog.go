@@ -0,0 +1,262 @@
+// Package syslog is a logger which sends rfc5424 messages to a syslog server
+package syslog
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/micro/go-micro/v2/logger"
+)
+
+var (
+	// DefaultAddress of the syslog server
+	DefaultAddress = "localhost:514"
+	// DefaultSDID is the structured data id fields are sent in
+	DefaultSDID = "fields@32473"
+	// DialTimeout of connections to the syslog server
+	DialTimeout = 5 * time.Second
+)
+
+// writer sends messages to the server, it's shared by loggers created with Fields
+type writer struct {
+	network  string
+	address  string
+	tls      *tls.Config
+	facility Facility
+	hostname string
+	app      string
+	sdID     string
+	pid      int
+
+	sync.Mutex
+	conn *conn
+}
+
+type conn struct {
+	net.Conn
+	closed int32
+}
+
+type syslogLogger struct {
+	opts   logger.Options
+	fields map[string]interface{}
+	w      *writer
+}
+
+func newWriter(opts logger.Options) *writer {
+	w := &writer{
+		network:  "udp",
+		address:  DefaultAddress,
+		facility: Local0,
+		app:      filepath.Base(os.Args[0]),
+		sdID:     DefaultSDID,
+		pid:      os.Getpid(),
+	}
+	w.hostname, _ = os.Hostname()
+
+	if ctx := opts.Context; ctx != nil {
+		if v, ok := ctx.Value(networkKey{}).(string); ok && len(v) > 0 {
+			w.network = v
+		}
+		if v, ok := ctx.Value(addressKey{}).(string); ok && len(v) > 0 {
+			w.address = v
+		}
+		if v, ok := ctx.Value(tlsConfigKey{}).(*tls.Config); ok {
+			w.tls = v
+		}
+		if v, ok := ctx.Value(facilityKey{}).(Facility); ok {
+			w.facility = v
+		}
+		if v, ok := ctx.Value(appNameKey{}).(string); ok && len(v) > 0 {
+			w.app = v
+		}
+		if v, ok := ctx.Value(hostnameKey{}).(string); ok && len(v) > 0 {
+			w.hostname = v
+		}
+		if v, ok := ctx.Value(sdIDKey{}).(string); ok && len(v) > 0 {
+			w.sdID = v
+		}
+	}
+
+	return w
+}
+
+func (w *writer) dial() (net.Conn, error) {
+	d := &net.Dialer{Timeout: DialTimeout}
+
+	switch w.network {
+	case "tls":
+		return tls.DialWithDialer(d, "tcp", w.address, w.tls)
+	case "tcp", "udp":
+		return d.Dial(w.network, w.address)
+	default:
+		return nil, fmt.Errorf("unsupported network %s", w.network)
+	}
+}
+
+// frame uses octet counting on streams and a datagram per message on udp
+func (w *writer) frame(msg string) []byte {
+	if w.network == "udp" {
+		return []byte(msg)
+	}
+	return []byte(strconv.Itoa(len(msg)) + " " + msg)
+}
+
+// watch marks the connection closed when the server closes the stream,
+// otherwise the first write after it's closed succeeds and the message is lost
+func watch(c *conn) {
+	// servers don't send anything so reads only return on close
+	io.Copy(ioutil.Discard, c)
+	atomic.StoreInt32(&c.closed, 1)
+}
+
+// write sends the message, reconnecting once if the connection was lost
+func (w *writer) write(msg string) error {
+	w.Lock()
+	defer w.Unlock()
+
+	b := w.frame(msg)
+
+	var err error
+	for i := 0; i < 2; i++ {
+		if w.conn != nil && atomic.LoadInt32(&w.conn.closed) == 1 {
+			w.conn.Close()
+			w.conn = nil
+		}
+
+		if w.conn == nil {
+			c, err := w.dial()
+			if err != nil {
+				return err
+			}
+			w.conn = &conn{Conn: c}
+			if w.network != "udp" {
+				go watch(w.conn)
+			}
+		}
+
+		if _, err = w.conn.Write(b); err == nil {
+			return nil
+		}
+
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	return err
+}
+
+func (w *writer) close() error {
+	w.Lock()
+	defer w.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+func (l *syslogLogger) Init(opts ...logger.Option) error {
+	for _, o := range opts {
+		o(&l.opts)
+	}
+
+	if l.w != nil {
+		l.w.close()
+	}
+
+	l.w = newWriter(l.opts)
+	l.fields = copyFields(l.opts.Fields)
+
+	return nil
+}
+
+func copyFields(src map[string]interface{}) map[string]interface{} {
+	dst := make(map[string]interface{}, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+func (l *syslogLogger) Fields(fields map[string]interface{}) logger.Logger {
+	nfields := copyFields(l.fields)
+	for k, v := range fields {
+		nfields[k] = v
+	}
+
+	return &syslogLogger{
+		opts:   l.opts,
+		fields: nfields,
+		w:      l.w,
+	}
+}
+
+func (l *syslogLogger) Log(level logger.Level, args ...interface{}) {
+	if !l.opts.Level.Enabled(level) {
+		return
+	}
+	l.log(level, fmt.Sprint(args...))
+}
+
+func (l *syslogLogger) Logf(level logger.Level, format string, args ...interface{}) {
+	if !l.opts.Level.Enabled(level) {
+		return
+	}
+	l.log(level, fmt.Sprintf(format, args...))
+}
+
+func (l *syslogLogger) log(level logger.Level, msg string) {
+	w := l.w
+	m := format(w.facility, level, time.Now(), w.hostname, w.app, w.pid, w.sdID, l.fields, msg)
+
+	if err := w.write(m); err != nil && l.opts.Out != nil {
+		// don't lose messages while the server is unreachable
+		fmt.Fprintln(l.opts.Out, m)
+	}
+
+	if level == logger.FatalLevel {
+		os.Exit(1)
+	}
+}
+
+func (l *syslogLogger) Options() logger.Options {
+	return l.opts
+}
+
+// Close closes the connection to the syslog server
+func (l *syslogLogger) Close() error {
+	return l.w.close()
+}
+
+func (l *syslogLogger) String() string {
+	return "syslog"
+}
+
+// NewLogger returns a logger which sends rfc5424 messages to a syslog server,
+// messages are written to the output if the server is unreachable
+func NewLogger(opts ...logger.Option) logger.Logger {
+	options := logger.Options{
+		Level:   logger.InfoLevel,
+		Fields:  make(map[string]interface{}),
+		Out:     os.Stderr,
+		Context: context.Background(),
+	}
+
+	l := &syslogLogger{opts: options}
+	l.Init(opts...)
+
+	return l
+}
@@ -0,0 +1,107 @@
+package syslog
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/logger"
+)
+
+func TestFormat(t *testing.T) {
+	ts := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	msg := format(Local0, logger.ErrorLevel, ts, "host name", "greeter", 10, DefaultSDID, map[string]interface{}{
+		"id":       1,
+		"path":     `/a"b]c\d`,
+		"bad name": true,
+	}, "failed")
+
+	expected := `<131>1 2020-06-01T12:00:00.000000Z hostname greeter 10 - [fields@32473 bad_name="true" id="1" path="/a\"b\]c\\d"] failed`
+	if msg != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s", expected, msg)
+	}
+
+	if msg := format(User, logger.InfoLevel, ts, "", "", 1, DefaultSDID, nil, ""); msg != "<14>1 2020-06-01T12:00:00.000000Z - - 1 - -" {
+		t.Fatalf("Unexpected nil values %s", msg)
+	}
+}
+
+func TestUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	l := NewLogger(WithAddress(pc.LocalAddr().String()), WithAppName("greeter"), WithHostname("node"))
+	defer l.(*syslogLogger).Close()
+
+	l.Fields(map[string]interface{}{"id": 1}).Log(logger.WarnLevel, "slow")
+
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := string(b[:n])
+	if !strings.HasPrefix(msg, "<132>1 ") || !strings.HasSuffix(msg, ` node greeter `+strconv.Itoa(l.(*syslogLogger).w.pid)+` - [fields@32473 id="1"] slow`) {
+		t.Fatalf("Unexpected message %s", msg)
+	}
+}
+
+func TestTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	msgs := make(chan string, 2)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			// octet counted frames
+			n, err := r.ReadString(' ')
+			if err != nil {
+				continue
+			}
+			size, _ := strconv.Atoi(strings.TrimSpace(n))
+			b := make([]byte, size)
+			if _, err := r.Read(b); err == nil {
+				msgs <- string(b)
+			}
+			// drop the connection to force a reconnect
+			conn.Close()
+		}
+	}()
+
+	l := NewLogger(WithNetwork("tcp"), WithAddress(ln.Addr().String()))
+	defer l.(*syslogLogger).Close()
+
+	for _, text := range []string{"first", "second"} {
+		l.Log(logger.InfoLevel, text)
+
+		select {
+		case msg := <-msgs:
+			if !strings.HasSuffix(msg, " - - "+text) {
+				t.Fatalf("Unexpected message %s", msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected %s message", text)
+		}
+
+		// wait for the server to close the connection
+		time.Sleep(50 * time.Millisecond)
+	}
+}
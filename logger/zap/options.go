@@ -1,6 +1,8 @@
 package zap
 
 import (
+	"math"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
@@ -36,3 +38,27 @@ type namespaceKey struct{}
 func WithNamespace(namespace string) logger.Option {
 	return logger.SetOption(namespaceKey{}, namespace)
 }
+
+type samplingKey struct{}
+
+// WithSampling logs the first entries with the same level and message each
+// second then every thereafter entry, zero initial disables sampling and zero
+// thereafter drops the rest
+func WithSampling(initial, thereafter int) logger.Option {
+	if thereafter < 1 {
+		thereafter = math.MaxInt32
+	}
+	var s *zap.SamplingConfig
+	if initial > 0 {
+		s = &zap.SamplingConfig{Initial: initial, Thereafter: thereafter}
+	}
+	return logger.SetOption(samplingKey{}, s)
+}
+
+type atomicLevelKey struct{}
+
+// WithAtomicLevel uses the level to change the log level at runtime, e.g. by
+// serving it over http. It's set to the logger's level on Init
+func WithAtomicLevel(level zap.AtomicLevel) logger.Option {
+	return logger.SetOption(atomicLevelKey{}, level)
+}
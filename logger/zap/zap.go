@@ -3,8 +3,11 @@ package zap
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -18,6 +21,8 @@ type zaplog struct {
 	opts logger.Options
 	sync.RWMutex
 	fields map[string]interface{}
+	// shared with loggers created by Fields
+	level zap.AtomicLevel
 }
 
 func (l *zaplog) Init(opts ...logger.Option) error {
@@ -37,18 +42,25 @@ func (l *zaplog) Init(opts ...logger.Option) error {
 
 	}
 
+	if sampling, ok := l.opts.Context.Value(samplingKey{}).(*zap.SamplingConfig); ok {
+		zapConfig.Sampling = sampling
+	}
+
 	skip, ok := l.opts.Context.Value(callerSkipKey{}).(int)
 	if !ok || skip < 1 {
 		skip = 1
 	}
 
-	// Set log Level if not default
-	zapConfig.Level = zap.NewAtomicLevel()
-	if l.opts.Level != logger.InfoLevel {
-		zapConfig.Level.SetLevel(loggerToZapLevel(l.opts.Level))
+	// Reuse the level so it changes for existing loggers
+	if level, ok := l.opts.Context.Value(atomicLevelKey{}).(zap.AtomicLevel); ok {
+		l.level = level
+	} else if l.zap == nil {
+		l.level = zap.NewAtomicLevel()
 	}
+	l.level.SetLevel(loggerToZapLevel(l.opts.Level))
+	zapConfig.Level = l.level
 
-	log, err := zapConfig.Build(zap.AddCallerSkip(skip))
+	log, err := build(zapConfig, l.opts.Out, zap.AddCallerSkip(skip))
 	if err != nil {
 		return err
 	}
@@ -76,18 +88,53 @@ func (l *zaplog) Init(opts ...logger.Option) error {
 	return nil
 }
 
+// build the logger writing to out, the config's output paths are used for stderr
+func build(cfg zap.Config, out io.Writer, opts ...zap.Option) (*zap.Logger, error) {
+	if out == nil || out == os.Stderr {
+		return cfg.Build(opts...)
+	}
+
+	var enc zapcore.Encoder
+	switch cfg.Encoding {
+	case "console":
+		enc = zapcore.NewConsoleEncoder(cfg.EncoderConfig)
+	default:
+		enc = zapcore.NewJSONEncoder(cfg.EncoderConfig)
+	}
+
+	sink := zapcore.AddSync(out)
+	core := zapcore.NewCore(enc, sink, cfg.Level)
+	if cfg.Sampling != nil {
+		core = zapcore.NewSampler(core, time.Second, cfg.Sampling.Initial, cfg.Sampling.Thereafter)
+	}
+
+	opts = append([]zap.Option{zap.ErrorOutput(sink)}, opts...)
+	if cfg.Development {
+		opts = append(opts, zap.Development())
+	}
+	if !cfg.DisableCaller {
+		opts = append(opts, zap.AddCaller())
+	}
+	if !cfg.DisableStacktrace {
+		opts = append(opts, zap.AddStacktrace(zap.ErrorLevel))
+	}
+
+	return zap.New(core, opts...), nil
+}
+
+// Fields returns a logger with the fields encoded once rather than on every entry
 func (l *zaplog) Fields(fields map[string]interface{}) logger.Logger {
-	l.Lock()
-	nfields := make(map[string]interface{}, len(l.fields))
+	l.RLock()
+	nfields := make(map[string]interface{}, len(l.fields)+len(fields))
 	for k, v := range l.fields {
 		nfields[k] = v
 	}
-	l.Unlock()
+	l.RUnlock()
 	for k, v := range fields {
 		nfields[k] = v
 	}
 
-	data := make([]zap.Field, 0, len(nfields))
+	data := make([]zap.Field, 0, len(fields))
 	for k, v := range fields {
 		data = append(data, zap.Any(k, v))
 	}
@@ -96,7 +143,8 @@ func (l *zaplog) Fields(fields map[string]interface{}) logger.Logger {
 		cfg:    l.cfg,
 		zap:    l.zap.With(data...),
 		opts:   l.opts,
-		fields: make(map[string]interface{}),
+		fields: nfields,
+		level:  l.level,
 	}
 
 	return zl
@@ -107,50 +155,23 @@ func (l *zaplog) Error(err error) logger.Logger {
 }
 
 func (l *zaplog) Log(level logger.Level, args ...interface{}) {
-	l.RLock()
-	data := make([]zap.Field, 0, len(l.fields))
-	for k, v := range l.fields {
-		data = append(data, zap.Any(k, v))
-	}
-	l.RUnlock()
-
+	// skip formatting disabled entries
 	lvl := loggerToZapLevel(level)
-	msg := fmt.Sprint(args...)
-	switch lvl {
-	case zap.DebugLevel:
-		l.zap.Debug(msg, data...)
-	case zap.InfoLevel:
-		l.zap.Info(msg, data...)
-	case zap.WarnLevel:
-		l.zap.Warn(msg, data...)
-	case zap.ErrorLevel:
-		l.zap.Error(msg, data...)
-	case zap.FatalLevel:
-		l.zap.Fatal(msg, data...)
+	if !l.level.Enabled(lvl) {
+		return
+	}
+	if ce := l.zap.Check(lvl, fmt.Sprint(args...)); ce != nil {
+		ce.Write()
 	}
 }
 
 func (l *zaplog) Logf(level logger.Level, format string, args ...interface{}) {
-	l.RLock()
-	data := make([]zap.Field, 0, len(l.fields))
-	for k, v := range l.fields {
-		data = append(data, zap.Any(k, v))
-	}
-	l.RUnlock()
-
 	lvl := loggerToZapLevel(level)
-	msg := fmt.Sprintf(format, args...)
-	switch lvl {
-	case zap.DebugLevel:
-		l.zap.Debug(msg, data...)
-	case zap.InfoLevel:
-		l.zap.Info(msg, data...)
-	case zap.WarnLevel:
-		l.zap.Warn(msg, data...)
-	case zap.ErrorLevel:
-		l.zap.Error(msg, data...)
-	case zap.FatalLevel:
-		l.zap.Fatal(msg, data...)
+	if !l.level.Enabled(lvl) {
+		return
+	}
+	if ce := l.zap.Check(lvl, fmt.Sprintf(format, args...)); ce != nil {
+		ce.Write()
 	}
 }
 
@@ -159,7 +180,10 @@ func (l *zaplog) String() string {
 }
 
 func (l *zaplog) Options() logger.Options {
-	return l.opts
+	// the level may have been changed at runtime
+	opts := l.opts
+	opts.Level = zapToLoggerLevel(l.level.Level())
+	return opts
 }
 
 // New builds a new logger based on options
@@ -180,6 +204,16 @@ func NewLogger(opts ...logger.Option) (logger.Logger, error) {
 	return l, nil
 }
 
+// LevelHandler returns a handler to get and change the level of a zap
+// logger at runtime, it returns nil for other loggers
+func LevelHandler(l logger.Logger) http.Handler {
+	zl, ok := l.(*zaplog)
+	if !ok {
+		return nil
+	}
+	return zl.level
+}
+
 func loggerToZapLevel(level logger.Level) zapcore.Level {
 	switch level {
 	case logger.TraceLevel, logger.DebugLevel:
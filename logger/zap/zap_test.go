@@ -1,9 +1,15 @@
 package zap
 
 import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/micro/go-micro/v2/logger"
+	"go.uber.org/zap"
 )
 
 func TestName(t *testing.T) {
@@ -42,3 +48,69 @@ func TestSetLevel(t *testing.T) {
 	logger.Init(logger.WithLevel(logger.InfoLevel))
 	l.Logf(logger.DebugLevel, "test non-show debug: %s", "debug msg")
 }
+
+func TestFields(t *testing.T) {
+	var buf bytes.Buffer
+
+	l, err := NewLogger(logger.WithOutput(&buf), logger.WithFields(map[string]interface{}{"service": "greeter"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Fields(map[string]interface{}{"id": 1}).Fields(map[string]interface{}{"name": "john"}).Log(logger.InfoLevel, "hello")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["msg"] != "hello" || entry["service"] != "greeter" || entry["id"] != float64(1) || entry["name"] != "john" {
+		t.Fatalf("Unexpected entry %v", entry)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+
+	l, err := NewLogger(logger.WithOutput(&buf), WithSampling(2, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		l.Log(logger.InfoLevel, "sampled")
+	}
+
+	if n := strings.Count(buf.String(), "sampled"); n != 2 {
+		t.Fatalf("Expected 2 entries got %d", n)
+	}
+}
+
+func TestAtomicLevel(t *testing.T) {
+	var buf bytes.Buffer
+
+	level := zap.NewAtomicLevel()
+	l, err := NewLogger(logger.WithOutput(&buf), WithAtomicLevel(level))
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := l.Fields(map[string]interface{}{"id": 1})
+
+	child.Log(logger.DebugLevel, "hidden")
+
+	// change the level of existing loggers at runtime
+	level.SetLevel(zap.DebugLevel)
+	child.Log(logger.DebugLevel, "shown")
+
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "shown") {
+		t.Fatalf("Unexpected output %s", buf.String())
+	}
+	if l.Options().Level != logger.DebugLevel {
+		t.Fatalf("Expected debug level got %v", l.Options().Level)
+	}
+
+	w := httptest.NewRecorder()
+	LevelHandler(l).ServeHTTP(w, httptest.NewRequest("PUT", "/", strings.NewReader(`{"level":"error"}`)))
+	if w.Code != http.StatusOK || level.Level() != zap.ErrorLevel {
+		t.Fatalf("Expected error level got %d %v", w.Code, level.Level())
+	}
+}
@@ -0,0 +1,21 @@
+package zerolog
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/logger"
+)
+
+// FromContext returns the logger in the context or the default logger
+func FromContext(ctx context.Context) logger.Logger {
+	if l, ok := logger.FromContext(ctx); ok {
+		return l
+	}
+	return logger.DefaultLogger
+}
+
+// ContextFields returns a context with the fields added to its logger, so
+// fields chain through the calls the context is passed to
+func ContextFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	return logger.NewContext(ctx, FromContext(ctx).Fields(fields))
+}
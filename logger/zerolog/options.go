@@ -19,6 +19,8 @@ type Options struct {
 	TimeFormat string
 	// Runtime mode. (Production by default)
 	Mode Mode
+	// Output format. (Pretty in development and JSON in production by default)
+	Format Format
 	// Exit Function to call when FatalLevel log
 	ExitFunc func(int)
 }
@@ -64,3 +66,10 @@ type exitKey struct{}
 func WithExitFunc(exit func(int)) logger.Option {
 	return logger.SetOption(exitKey{}, exit)
 }
+
+type formatKey struct{}
+
+// WithFormat sets the output format regardless of the mode
+func WithFormat(format Format) logger.Option {
+	return logger.SetOption(formatKey{}, format)
+}
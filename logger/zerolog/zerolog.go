@@ -21,6 +21,15 @@ const (
 	Development
 )
 
+type Format uint8
+
+const (
+	// DefaultFormat is pretty in development and JSON in production
+	DefaultFormat Format = iota
+	JSONFormat
+	PrettyFormat
+)
+
 type zeroLogger struct {
 	zLog zerolog.Logger
 	opts Options
@@ -52,19 +61,26 @@ func (l *zeroLogger) Init(opts ...logger.Option) error {
 	if prodMode, ok := l.opts.Context.Value(productionModeKey{}).(bool); ok && prodMode {
 		l.opts.Mode = Production
 	}
+	if format, ok := l.opts.Context.Value(formatKey{}).(Format); ok {
+		l.opts.Format = format
+	}
 
 	// RESET
 	zerolog.TimeFieldFormat = time.RFC3339
 	zerolog.ErrorStackMarshaler = nil
 	zerolog.CallerSkipFrameCount = 4
 
-	switch l.opts.Mode {
-	case Development:
-		zerolog.ErrorStackMarshaler = func(err error) interface{} {
-			fmt.Println(string(debug.Stack()))
-			return nil
+	format := l.opts.Format
+	if format == DefaultFormat {
+		format = JSONFormat
+		if l.opts.Mode == Development {
+			format = PrettyFormat
 		}
-		consOut := zerolog.NewConsoleWriter(
+	}
+
+	out := l.opts.Out
+	if format == PrettyFormat {
+		out = zerolog.NewConsoleWriter(
 			func(w *zerolog.ConsoleWriter) {
 				if len(l.opts.TimeFormat) > 0 {
 					w.TimeFormat = l.opts.TimeFormat
@@ -73,13 +89,21 @@ func (l *zeroLogger) Init(opts ...logger.Option) error {
 				w.NoColor = false
 			},
 		)
+	}
+
+	switch l.opts.Mode {
+	case Development:
+		zerolog.ErrorStackMarshaler = func(err error) interface{} {
+			fmt.Println(string(debug.Stack()))
+			return nil
+		}
 		//level = logger.DebugLevel
-		l.zLog = zerolog.New(consOut).
+		l.zLog = zerolog.New(out).
 			Level(zerolog.DebugLevel).
 			With().Timestamp().Stack().Logger()
 	default: // Production
 		zerolog.ErrorStackMarshaler = pkgerrors.MarshalStack
-		l.zLog = zerolog.New(l.opts.Out).
+		l.zLog = zerolog.New(out).
 			Level(zerolog.InfoLevel).
 			With().Timestamp().Stack().Logger()
 	}
@@ -94,7 +118,7 @@ func (l *zeroLogger) Init(opts ...logger.Option) error {
 	if l.opts.ReportCaller {
 		l.zLog = l.zLog.With().Caller().Logger()
 	}
-	
+
 	// Adding hooks if exist
 	for _, hook := range l.opts.Hooks {
 		l.zLog = l.zLog.Hook(hook)
@@ -118,14 +142,17 @@ func (l *zeroLogger) Init(opts ...logger.Option) error {
 	return nil
 }
 
+// Fields returns a new logger with the fields added, the logger isn't changed
+// so calls can be chained without fields leaking between them
 func (l *zeroLogger) Fields(fields map[string]interface{}) logger.Logger {
-	l.zLog = l.zLog.With().Fields(fields).Logger()
-	return l
+	return &zeroLogger{
+		zLog: l.zLog.With().Fields(fields).Logger(),
+		opts: l.opts,
+	}
 }
 
 func (l *zeroLogger) Error(err error) logger.Logger {
-	l.zLog = l.zLog.With().Fields(map[string]interface{}{zerolog.ErrorFieldName: err}).Logger()
-	return l
+	return l.Fields(map[string]interface{}{zerolog.ErrorFieldName: err})
 }
 
 func (l *zeroLogger) Log(level logger.Level, args ...interface{}) {
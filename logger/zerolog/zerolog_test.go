@@ -1,8 +1,11 @@
 package zerolog
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -80,3 +83,45 @@ func TestWithHooks(t *testing.T) {
 
 	logger.Logf(logger.InfoLevel, "testing: %s", "WithHooks")
 }
+
+func TestFieldsChaining(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(logger.WithOutput(&buf), logger.WithLevel(logger.InfoLevel))
+
+	l.Fields(map[string]interface{}{"request": 1}).Log(logger.InfoLevel, "first")
+	l.Log(logger.InfoLevel, "second")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"request":1`) || strings.Contains(lines[1], "request") {
+		t.Fatalf("Expected fields only on the first entry got %s", buf.String())
+	}
+}
+
+func TestContextFields(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(logger.WithOutput(&buf), logger.WithLevel(logger.InfoLevel))
+
+	ctx := logger.NewContext(context.Background(), l)
+	ctx = ContextFields(ctx, map[string]interface{}{"service": "greeter"})
+	ctx = ContextFields(ctx, map[string]interface{}{"id": "1"})
+
+	FromContext(ctx).Log(logger.InfoLevel, "chained")
+
+	if s := buf.String(); !strings.Contains(s, `"service":"greeter"`) || !strings.Contains(s, `"id":"1"`) {
+		t.Fatalf("Expected chained fields got %s", s)
+	}
+}
+
+func TestWithFormat(t *testing.T) {
+	var buf bytes.Buffer
+	NewLogger(logger.WithOutput(&buf), logger.WithLevel(logger.InfoLevel), WithFormat(PrettyFormat)).Log(logger.InfoLevel, "pretty")
+	if strings.HasPrefix(buf.String(), "{") || !strings.Contains(buf.String(), "pretty") {
+		t.Fatalf("Expected pretty output got %s", buf.String())
+	}
+
+	buf.Reset()
+	NewLogger(logger.WithOutput(&buf), WithDevelopmentMode(), WithFormat(JSONFormat)).Log(logger.InfoLevel, "json")
+	if !strings.HasPrefix(buf.String(), "{") {
+		t.Fatalf("Expected json output got %s", buf.String())
+	}
+}
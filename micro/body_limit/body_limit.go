@@ -0,0 +1,190 @@
+// Package body_limit is a micro plugin for limiting the size of request bodies
+package body_limit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/micro/cli/v2"
+	"github.com/micro/micro/v2/plugin"
+)
+
+var (
+	// DefaultLimit of request bodies is 4MB
+	DefaultLimit int64 = 4 << 20
+)
+
+type route struct {
+	prefix string
+	limit  int64
+}
+
+type bodyLimit struct {
+	opts Options
+	// sorted longest prefix first
+	routes []route
+}
+
+func (b *bodyLimit) Flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "body_limit",
+			Usage:   "Max request body size e.g 1MB, 0 is unlimited",
+			EnvVars: []string{"BODY_LIMIT"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "body_limit_route",
+			Usage:   "Max request body size of a path prefix e.g /upload=10MB",
+			EnvVars: []string{"BODY_LIMIT_ROUTE"},
+		},
+	}
+}
+
+func (b *bodyLimit) Commands() []*cli.Command {
+	return nil
+}
+
+// limit returns the max body size of the path
+func (b *bodyLimit) limit(path string) int64 {
+	for _, r := range b.routes {
+		if strings.HasPrefix(path, r.prefix) {
+			return r.limit
+		}
+	}
+	return b.opts.Limit
+}
+
+func tooLarge(w http.ResponseWriter) {
+	http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+}
+
+func (b *bodyLimit) Handler() plugin.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := b.limit(r.URL.Path)
+			if limit <= 0 || r.Body == nil || r.Body == http.NoBody {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			switch {
+			case r.ContentLength > limit:
+				tooLarge(w)
+				return
+			case r.ContentLength < 0:
+				// the size is unknown so read it up to the limit
+				buf, err := ioutil.ReadAll(io.LimitReader(r.Body, limit+1))
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				if int64(len(buf)) > limit {
+					tooLarge(w)
+					return
+				}
+				r.Body.Close()
+				r.Body = ioutil.NopCloser(bytes.NewReader(buf))
+				r.ContentLength = int64(len(buf))
+			default:
+				// never trust the content length
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (b *bodyLimit) Init(ctx *cli.Context) error {
+	if v := ctx.String("body_limit"); len(v) > 0 {
+		n, err := ParseSize(v)
+		if err != nil {
+			return err
+		}
+		b.opts.Limit = n
+	}
+
+	for _, v := range ctx.StringSlice("body_limit_route") {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid body limit route %s", v)
+		}
+		n, err := ParseSize(parts[1])
+		if err != nil {
+			return err
+		}
+		Route(parts[0], n)(&b.opts)
+	}
+
+	b.routes = sortRoutes(b.opts.Routes)
+
+	return nil
+}
+
+func (b *bodyLimit) String() string {
+	return "body_limit"
+}
+
+func sortRoutes(routes map[string]int64) []route {
+	var sorted []route
+	for prefix, limit := range routes {
+		sorted = append(sorted, route{prefix, limit})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return len(sorted[i].prefix) > len(sorted[j].prefix)
+	})
+	return sorted
+}
+
+// ParseSize parses a size in bytes with an optional KB, MB or GB suffix
+func ParseSize(s string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+
+	mult := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(v, u.suffix) {
+			v = strings.TrimSpace(strings.TrimSuffix(v, u.suffix))
+			mult = u.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %s", s)
+	}
+
+	return n * mult, nil
+}
+
+// NewPlugin returns a plugin which rejects requests with bodies over the limit
+func NewPlugin(opts ...Option) plugin.Plugin {
+	options := Options{
+		Limit: DefaultLimit,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &bodyLimit{
+		opts:   options,
+		routes: sortRoutes(options.Routes),
+	}
+}
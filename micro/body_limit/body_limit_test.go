@@ -0,0 +1,74 @@
+package body_limit
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	testData := map[string]int64{
+		"0":     0,
+		"512":   512,
+		"512B":  512,
+		"4KB":   4 << 10,
+		"10mb":  10 << 20,
+		"1 GB ": 1 << 30,
+	}
+
+	for s, expected := range testData {
+		n, err := ParseSize(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != expected {
+			t.Errorf("ParseSize(%q) = %d, expected %d", s, n, expected)
+		}
+	}
+
+	for _, s := range []string{"", "MB", "-1", "1TB"} {
+		if _, err := ParseSize(s); err == nil {
+			t.Errorf("Expected error parsing %q", s)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := NewPlugin(
+		Limit(8),
+		Route("/upload", 32),
+		Route("/upload/small", 4),
+	).Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	testData := []struct {
+		path    string
+		body    string
+		chunked bool
+		code    int
+	}{
+		{"/greeter", "1234", false, http.StatusOK},
+		{"/greeter", "123456789", false, http.StatusRequestEntityTooLarge},
+		{"/greeter", "123456789", true, http.StatusRequestEntityTooLarge},
+		{"/greeter", "12345678", true, http.StatusOK},
+		{"/upload", strings.Repeat("1", 32), false, http.StatusOK},
+		{"/upload/small", "12345", false, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, d := range testData {
+		r := httptest.NewRequest("POST", d.path, strings.NewReader(d.body))
+		if d.chunked {
+			r.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != d.code {
+			t.Errorf("%s %q: expected %d got %d", d.path, d.body, d.code, w.Code)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package body_limit
+
+type Options struct {
+	// Limit is the max body size in bytes of routes without their own limit, 0 is unlimited
+	Limit int64
+	// Routes are the max body sizes by path prefix, the longest prefix wins
+	Routes map[string]int64
+}
+
+type Option func(o *Options)
+
+// Limit sets the default max body size in bytes
+func Limit(n int64) Option {
+	return func(o *Options) {
+		o.Limit = n
+	}
+}
+
+// Route sets the max body size in bytes of paths with the prefix
+func Route(prefix string, n int64) Option {
+	return func(o *Options) {
+		if o.Routes == nil {
+			o.Routes = make(map[string]int64)
+		}
+		o.Routes[prefix] = n
+	}
+}
@@ -1,8 +1,10 @@
+// Package cors is a micro plugin for setting CORS headers
 package cors
 
 import (
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/micro/cli/v2"
 	"github.com/micro/micro/v2/plugin"
@@ -10,28 +12,43 @@ import (
 )
 
 type allowedCors struct {
-	allowedHeaders []string
-	allowedOrigins []string
-	allowedMethods []string
+	opts Options
+	cors *cors.Cors
 }
 
 func (ac *allowedCors) Flags() []cli.Flag {
 	return []cli.Flag{
 		&cli.StringFlag{
-			Name:   "cors-allowed-headers",
-			Usage:  "Comma-seperated list of allowed headers",
+			Name:    "cors-allowed-headers",
+			Usage:   "Comma-seperated list of allowed headers",
 			EnvVars: []string{"CORS_ALLOWED_HEADERS"},
 		},
 		&cli.StringFlag{
-			Name:   "cors-allowed-origins",
-			Usage:  "Comma-seperated list of allowed origins",
+			Name:    "cors-allowed-origins",
+			Usage:   "Comma-seperated list of allowed origins, each may contain a single * wildcard",
 			EnvVars: []string{"CORS_ALLOWED_ORIGINS"},
 		},
 		&cli.StringFlag{
-			Name:   "cors-allowed-methods",
-			Usage:  "Comma-seperated list of allowed methods",
+			Name:    "cors-allowed-methods",
+			Usage:   "Comma-seperated list of allowed methods",
 			EnvVars: []string{"CORS_ALLOWED_METHODS"},
 		},
+		&cli.StringFlag{
+			Name:    "cors-exposed-headers",
+			Usage:   "Comma-seperated list of response headers exposed to the browser",
+			EnvVars: []string{"CORS_EXPOSED_HEADERS"},
+		},
+		&cli.BoolFlag{
+			Name:    "cors-allow-credentials",
+			Usage:   "Allow requests with credentials",
+			EnvVars: []string{"CORS_ALLOW_CREDENTIALS"},
+			Value:   true,
+		},
+		&cli.IntFlag{
+			Name:    "cors-max-age",
+			Usage:   "Seconds browsers may cache a preflight response",
+			EnvVars: []string{"CORS_MAX_AGE"},
+		},
 	}
 }
 
@@ -41,30 +58,39 @@ func (ac *allowedCors) Commands() []*cli.Command {
 
 func (ac *allowedCors) Handler() plugin.Handler {
 	return func(ha http.Handler) http.Handler {
-		hf := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ha.ServeHTTP(w, r)
-		})
-
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			cors.New(cors.Options{
-				AllowedOrigins:   ac.allowedOrigins,
-				AllowedMethods:   ac.allowedMethods,
-				AllowedHeaders:   ac.allowedHeaders,
-				AllowCredentials: true,
-			}).ServeHTTP(w, r, hf)
+			// preflight requests are answered without calling the next handler
+			ac.cors.ServeHTTP(w, r, ha.ServeHTTP)
 		})
 	}
 }
 
 func (ac *allowedCors) Init(ctx *cli.Context) error {
-	ac.allowedHeaders = ac.parseAllowed(ctx, "cors-allowed-headers")
-	ac.allowedMethods = ac.parseAllowed(ctx, "cors-allowed-methods")
-	ac.allowedOrigins = ac.parseAllowed(ctx, "cors-allowed-origins")
+	if v := parseAllowed(ctx, "cors-allowed-headers"); v != nil {
+		ac.opts.AllowedHeaders = v
+	}
+	if v := parseAllowed(ctx, "cors-allowed-methods"); v != nil {
+		ac.opts.AllowedMethods = v
+	}
+	if v := parseAllowed(ctx, "cors-allowed-origins"); v != nil {
+		ac.opts.AllowedOrigins = v
+	}
+	if v := parseAllowed(ctx, "cors-exposed-headers"); v != nil {
+		ac.opts.ExposedHeaders = v
+	}
+	if ctx.IsSet("cors-allow-credentials") {
+		ac.opts.AllowCredentials = ctx.Bool("cors-allow-credentials")
+	}
+	if ctx.IsSet("cors-max-age") {
+		ac.opts.MaxAge = time.Duration(ctx.Int("cors-max-age")) * time.Second
+	}
+
+	ac.cors = newCors(ac.opts)
 
 	return nil
 }
 
-func (ac *allowedCors) parseAllowed(ctx *cli.Context, flagName string) []string {
+func parseAllowed(ctx *cli.Context, flagName string) []string {
 	fv := ctx.String(flagName)
 
 	// no op
@@ -72,7 +98,24 @@ func (ac *allowedCors) parseAllowed(ctx *cli.Context, flagName string) []string
 		return nil
 	}
 
-	return strings.Split(fv, ",")
+	var vals []string
+	for _, v := range strings.Split(fv, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
+
+func newCors(opts Options) *cors.Cors {
+	return cors.New(cors.Options{
+		AllowedOrigins:   opts.AllowedOrigins,
+		AllowedMethods:   opts.AllowedMethods,
+		AllowedHeaders:   opts.AllowedHeaders,
+		ExposedHeaders:   opts.ExposedHeaders,
+		AllowCredentials: opts.AllowCredentials,
+		MaxAge:           int(opts.MaxAge / time.Second),
+	})
 }
 
 func (ac *allowedCors) String() string {
@@ -80,10 +123,17 @@ func (ac *allowedCors) String() string {
 }
 
 // NewPlugin Creates the CORS Plugin
-func NewPlugin() plugin.Plugin {
+func NewPlugin(opts ...Option) plugin.Plugin {
+	options := Options{
+		AllowCredentials: true,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
 	return &allowedCors{
-		allowedHeaders: []string{},
-		allowedOrigins: []string{},
-		allowedMethods: []string{},
+		opts: options,
+		cors: newCors(options),
 	}
 }
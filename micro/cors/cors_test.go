@@ -0,0 +1,98 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	testData := []struct {
+		name        string
+		opts        []Option
+		method      string
+		origin      string
+		origins     string
+		credentials string
+		maxAge      string
+		next        bool
+	}{
+		{
+			name:        "exact origin",
+			opts:        []Option{AllowedOrigins("https://example.com")},
+			method:      "GET",
+			origin:      "https://example.com",
+			origins:     "https://example.com",
+			credentials: "true",
+			next:        true,
+		},
+		{
+			name:        "wildcard origin",
+			opts:        []Option{AllowedOrigins("https://*.example.com")},
+			method:      "GET",
+			origin:      "https://api.example.com",
+			origins:     "https://api.example.com",
+			credentials: "true",
+			next:        true,
+		},
+		{
+			name:   "rejected origin",
+			opts:   []Option{AllowedOrigins("https://*.example.com")},
+			method: "GET",
+			origin: "https://example.org",
+			next:   true,
+		},
+		{
+			name:    "without credentials",
+			opts:    []Option{AllowedOrigins("https://example.com"), AllowCredentials(false)},
+			method:  "GET",
+			origin:  "https://example.com",
+			origins: "https://example.com",
+			next:    true,
+		},
+		{
+			name:        "preflight",
+			opts:        []Option{AllowedOrigins("https://example.com"), MaxAge(time.Minute)},
+			method:      "OPTIONS",
+			origin:      "https://example.com",
+			origins:     "https://example.com",
+			credentials: "true",
+			maxAge:      "60",
+		},
+		{
+			name:   "rejected preflight",
+			opts:   []Option{AllowedOrigins("https://example.com")},
+			method: "OPTIONS",
+			origin: "https://example.org",
+		},
+	}
+
+	for _, d := range testData {
+		var next bool
+		h := NewPlugin(d.opts...).Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next = true
+		}))
+
+		r := httptest.NewRequest(d.method, "/greeter", nil)
+		r.Header.Set("Origin", d.origin)
+		if d.method == "OPTIONS" {
+			r.Header.Set("Access-Control-Request-Method", "POST")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if v := w.Header().Get("Access-Control-Allow-Origin"); v != d.origins {
+			t.Errorf("%s: expected allowed origin %q got %q", d.name, d.origins, v)
+		}
+		if v := w.Header().Get("Access-Control-Allow-Credentials"); v != d.credentials {
+			t.Errorf("%s: expected allow credentials %q got %q", d.name, d.credentials, v)
+		}
+		if v := w.Header().Get("Access-Control-Max-Age"); v != d.maxAge {
+			t.Errorf("%s: expected max age %q got %q", d.name, d.maxAge, v)
+		}
+		if next != d.next {
+			t.Errorf("%s: expected next handler called %v got %v", d.name, d.next, next)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package cors
+
+import (
+	"time"
+)
+
+// Options of the cors policy
+type Options struct {
+	// AllowedOrigins may contain a single * wildcard, e.g. https://*.example.com
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	ExposedHeaders []string
+	// AllowCredentials sets Access-Control-Allow-Credentials
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache preflight responses
+	MaxAge time.Duration
+}
+
+type Option func(o *Options)
+
+// AllowedOrigins sets the origins allowed to make cross-origin requests
+func AllowedOrigins(origins ...string) Option {
+	return func(o *Options) {
+		o.AllowedOrigins = origins
+	}
+}
+
+// AllowedMethods sets the methods allowed in cross-origin requests
+func AllowedMethods(methods ...string) Option {
+	return func(o *Options) {
+		o.AllowedMethods = methods
+	}
+}
+
+// AllowedHeaders sets the request headers allowed in cross-origin requests
+func AllowedHeaders(headers ...string) Option {
+	return func(o *Options) {
+		o.AllowedHeaders = headers
+	}
+}
+
+// ExposedHeaders sets the response headers exposed to the browser
+func ExposedHeaders(headers ...string) Option {
+	return func(o *Options) {
+		o.ExposedHeaders = headers
+	}
+}
+
+// AllowCredentials allows requests with cookies and auth headers
+func AllowCredentials(b bool) Option {
+	return func(o *Options) {
+		o.AllowCredentials = b
+	}
+}
+
+// MaxAge sets how long preflight responses may be cached
+func MaxAge(d time.Duration) Option {
+	return func(o *Options) {
+		o.MaxAge = d
+	}
+}
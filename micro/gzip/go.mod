@@ -3,6 +3,7 @@ module github.com/micro/go-plugins/micro/gzip/v2
 go 1.13
 
 require (
+	github.com/andybalholm/brotli v1.0.0
 	github.com/micro/cli/v2 v2.1.2
 	github.com/micro/micro/v2 v2.9.1
 )
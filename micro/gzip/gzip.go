@@ -1,26 +1,69 @@
-// Package gzip is a micro plugin for gzipping http response
+// Package gzip is a micro plugin for compressing http responses with gzip or brotli
 package gzip
 
 import (
+	"bufio"
 	"compress/gzip"
+	"errors"
 	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/andybalholm/brotli"
 	"github.com/micro/cli/v2"
 	"github.com/micro/micro/v2/plugin"
 )
 
+// encoder is implemented by the gzip and brotli writers
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+type gzipper struct {
+	opts  Options
+	pools map[string]*sync.Pool
+}
+
 type gzipWriter struct {
-	http.Hijacker
-	io.Writer
 	http.ResponseWriter
-}
+	g        *gzipper
+	encoding string
 
-type gzipper struct{}
+	status  int
+	buf     []byte
+	decided bool
+	enc     encoder
+}
 
 func (g *gzipper) Flags() []cli.Flag {
-	return nil
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:    "gzip-min-size",
+			Usage:   "Smallest response body in bytes which is compressed",
+			EnvVars: []string{"GZIP_MIN_SIZE"},
+		},
+		&cli.StringFlag{
+			Name:    "gzip-encodings",
+			Usage:   "Comma-seperated list of encodings in order of preference, br and gzip are supported",
+			EnvVars: []string{"GZIP_ENCODINGS"},
+		},
+		&cli.StringFlag{
+			Name:    "gzip-content-types",
+			Usage:   "Comma-seperated list of content types to compress, an entry ending in / matches all subtypes",
+			EnvVars: []string{"GZIP_CONTENT_TYPES"},
+		},
+		&cli.IntFlag{
+			Name:    "gzip-level",
+			Usage:   "Compression level, -1 uses the default of each encoding",
+			EnvVars: []string{"GZIP_LEVEL"},
+		},
+	}
 }
 
 func (g *gzipper) Commands() []*cli.Command {
@@ -30,30 +73,55 @@ func (g *gzipper) Commands() []*cli.Command {
 func (g *gzipper) Handler() plugin.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// has gzip accept-encoding?
-			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			// bodiless, partial and upgraded responses are never compressed
+			if r.Method == http.MethodHead || len(r.Header.Get("Range")) > 0 || len(r.Header.Get("Upgrade")) > 0 {
 				h.ServeHTTP(w, r)
 				return
 			}
 
-			// set the content-encoding
-			w.Header().Set("Content-Encoding", "gzip")
+			encoding := negotiate(r.Header.Get("Accept-Encoding"), g.opts.Encodings)
+			if len(encoding) == 0 {
+				h.ServeHTTP(w, r)
+				return
+			}
 
-			// create gzip writer
-			gz := gzip.NewWriter(w)
-			defer gz.Close()
+			// the response now depends on the accept-encoding
+			w.Header().Add("Vary", "Accept-Encoding")
 
-			// create http response writer
-			hj, _ := w.(http.Hijacker)
-			gzw := gzipWriter{hj, gz, w}
+			cw := &gzipWriter{
+				ResponseWriter: w,
+				g:              g,
+				encoding:       encoding,
+			}
+			defer cw.Close()
 
-			// serve the request
-			h.ServeHTTP(gzw, r)
+			h.ServeHTTP(cw, r)
 		})
 	}
 }
 
 func (g *gzipper) Init(ctx *cli.Context) error {
+	if ctx.IsSet("gzip-min-size") {
+		g.opts.MinSize = ctx.Int("gzip-min-size")
+	}
+	if v := split(ctx.String("gzip-encodings")); len(v) > 0 {
+		g.opts.Encodings = v
+	}
+	if v := split(ctx.String("gzip-content-types")); len(v) > 0 {
+		g.opts.ContentTypes = v
+	}
+	if ctx.IsSet("gzip-level") {
+		g.opts.Level = ctx.Int("gzip-level")
+	}
+
+	for _, e := range g.opts.Encodings {
+		if e != "br" && e != "gzip" {
+			return errors.New("gzip: unsupported encoding " + e)
+		}
+	}
+
+	g.pools = newPools(g.opts.Level)
+
 	return nil
 }
 
@@ -61,15 +129,239 @@ func (g *gzipper) String() string {
 	return "gzip"
 }
 
-func (w gzipWriter) WriteHeader(code int) {
-	w.Header().Del("Content-Length")
-	w.ResponseWriter.WriteHeader(code)
+// compressible returns true if responses of the content type should be compressed
+func (g *gzipper) compressible(ct string) bool {
+	if i := strings.Index(ct, ";"); i >= 0 {
+		ct = ct[:i]
+	}
+	ct = strings.ToLower(strings.TrimSpace(ct))
+
+	for _, t := range g.opts.ContentTypes {
+		if t == "*" || t == ct || (strings.HasSuffix(t, "/") && strings.HasPrefix(ct, t)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newPools(level int) map[string]*sync.Pool {
+	gzipLevel := level
+	if gzipLevel > gzip.BestCompression {
+		gzipLevel = gzip.BestCompression
+	}
+	if gzipLevel < gzip.HuffmanOnly {
+		gzipLevel = gzip.DefaultCompression
+	}
+
+	brotliLevel := level
+	if brotliLevel < brotli.BestSpeed {
+		brotliLevel = brotli.DefaultCompression
+	}
+	if brotliLevel > brotli.BestCompression {
+		brotliLevel = brotli.BestCompression
+	}
+
+	return map[string]*sync.Pool{
+		"gzip": {New: func() interface{} {
+			gz, _ := gzip.NewWriterLevel(ioutil.Discard, gzipLevel)
+			return gz
+		}},
+		"br": {New: func() interface{} {
+			return brotli.NewWriterLevel(ioutil.Discard, brotliLevel)
+		}},
+	}
+}
+
+// negotiate returns the most preferred encoding accepted by the client
+func negotiate(header string, encodings []string) string {
+	if len(header) == 0 {
+		return ""
+	}
+
+	accepted := make(map[string]float64)
+
+	for _, part := range strings.Split(header, ",") {
+		name, q := part, 1.0
+		if i := strings.Index(part, ";"); i >= 0 {
+			name = part[:i]
+			param := strings.TrimSpace(part[i+1:])
+			if strings.HasPrefix(param, "q=") {
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					v = 0
+				}
+				q = v
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+
+	var best string
+	var bestQ float64
+
+	for _, e := range encodings {
+		q, ok := accepted[e]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+
+	return best
+}
+
+func split(s string) []string {
+	var vals []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
 }
 
-func (w gzipWriter) Write(b []byte) (int, error) {
-	return w.Writer.Write(b)
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.enc != nil {
+			return w.enc.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	// buffer until we know if the body is big enough
+	w.buf = append(w.buf, b...)
+	if len(w.buf) < w.g.opts.MinSize {
+		return len(b), nil
+	}
+
+	if err := w.decide(true); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
 }
 
-func NewPlugin() plugin.Plugin {
-	return new(gzipper)
+// decide writes the header, compressing the body if it's big enough and of the right type
+func (w *gzipWriter) decide(big bool) error {
+	w.decided = true
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	h := w.Header()
+
+	// set the content type before it's sniffed from the compressed body
+	if len(h.Get("Content-Type")) == 0 && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if big && w.compress() {
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", w.encoding)
+		w.enc = w.g.pools[w.encoding].Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// compress returns true if the response should be compressed
+func (w *gzipWriter) compress() bool {
+	switch {
+	case w.status < http.StatusOK, w.status == http.StatusNoContent, w.status == http.StatusNotModified:
+		return false
+	case len(w.Header().Get("Content-Encoding")) > 0:
+		return false
+	}
+	return w.g.compressible(w.Header().Get("Content-Type"))
+}
+
+// Close writes any buffered body and finishes the compressed stream
+func (w *gzipWriter) Close() error {
+	if !w.decided {
+		if err := w.decide(len(w.buf) >= w.g.opts.MinSize); err != nil {
+			return err
+		}
+	}
+
+	if w.enc == nil {
+		return nil
+	}
+
+	err := w.enc.Close()
+	w.enc.Reset(ioutil.Discard)
+	w.g.pools[w.encoding].Put(w.enc)
+	w.enc = nil
+	return err
+}
+
+// Flush sends buffered data, streamed responses are compressed regardless of size
+func (w *gzipWriter) Flush() {
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *gzipWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("gzip: response does not implement http.Hijacker")
+	}
+	// the connection is no longer ours to write
+	w.decided = true
+	return hj.Hijack()
+}
+
+// NewPlugin returns a plugin which compresses responses
+func NewPlugin(opts ...Option) plugin.Plugin {
+	options := Options{
+		MinSize:      DefaultMinSize,
+		Encodings:    DefaultEncodings,
+		ContentTypes: DefaultContentTypes,
+		Level:        DefaultLevel,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &gzipper{
+		opts:  options,
+		pools: newPools(options.Level),
+	}
 }
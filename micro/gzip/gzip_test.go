@@ -0,0 +1,99 @@
+package gzip
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiate(t *testing.T) {
+	testData := []struct {
+		header   string
+		expected string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0, gzip;q=0", ""},
+		{"*", "br"},
+		{"identity", ""},
+	}
+
+	for _, d := range testData {
+		if e := negotiate(d.header, DefaultEncodings); e != d.expected {
+			t.Errorf("negotiate(%q) = %q, expected %q", d.header, e, d.expected)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	big := bytes.Repeat([]byte(`{"hello":"world"}`), 100)
+
+	testData := []struct {
+		name        string
+		accept      string
+		contentType string
+		body        []byte
+		encoding    string
+	}{
+		{"gzip", "gzip", "application/json", big, "gzip"},
+		{"brotli", "br, gzip", "application/json; charset=utf-8", big, "br"},
+		{"small", "gzip", "application/json", []byte(`{}`), ""},
+		{"content type", "gzip", "image/png", big, ""},
+		{"no accept", "", "application/json", big, ""},
+	}
+
+	for _, d := range testData {
+		t.Run(d.name, func(t *testing.T) {
+			h := NewPlugin().Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", d.contentType)
+				w.WriteHeader(http.StatusCreated)
+				// write in chunks to exercise buffering
+				w.Write(d.body[:len(d.body)/2])
+				w.Write(d.body[len(d.body)/2:])
+			}))
+
+			r := httptest.NewRequest("GET", "/", nil)
+			if len(d.accept) > 0 {
+				r.Header.Set("Accept-Encoding", d.accept)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Expected status %d got %d", http.StatusCreated, w.Code)
+			}
+			if e := w.Header().Get("Content-Encoding"); e != d.encoding {
+				t.Fatalf("Expected encoding %q got %q", d.encoding, e)
+			}
+
+			var body []byte
+			var err error
+
+			switch d.encoding {
+			case "gzip":
+				gz, gerr := gzip.NewReader(w.Body)
+				if gerr != nil {
+					t.Fatal(gerr)
+				}
+				body, err = ioutil.ReadAll(gz)
+			case "br":
+				body, err = ioutil.ReadAll(brotli.NewReader(w.Body))
+			default:
+				body = w.Body.Bytes()
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(body, d.body) {
+				t.Fatalf("Unexpected body %q", body)
+			}
+		})
+	}
+}
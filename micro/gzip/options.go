@@ -0,0 +1,63 @@
+package gzip
+
+import (
+	"compress/gzip"
+)
+
+var (
+	// DefaultMinSize is the smallest response body compressed
+	DefaultMinSize = 1024
+	// DefaultEncodings in order of preference
+	DefaultEncodings = []string{"br", "gzip"}
+	// DefaultContentTypes compressed, an entry ending in / matches all subtypes
+	DefaultContentTypes = []string{
+		"text/",
+		"application/json",
+		"application/javascript",
+		"application/xml",
+		"image/svg+xml",
+	}
+	// DefaultLevel of compression
+	DefaultLevel = gzip.DefaultCompression
+)
+
+type Options struct {
+	// Responses smaller than MinSize are sent uncompressed
+	MinSize int
+	// Encodings supported in order of preference, br and gzip are available
+	Encodings []string
+	// ContentTypes which are compressed
+	ContentTypes []string
+	// Level of compression, -1 uses the encoder's default
+	Level int
+}
+
+type Option func(o *Options)
+
+// MinSize sets the smallest response body compressed
+func MinSize(n int) Option {
+	return func(o *Options) {
+		o.MinSize = n
+	}
+}
+
+// Encodings sets the supported encodings in order of preference
+func Encodings(e ...string) Option {
+	return func(o *Options) {
+		o.Encodings = e
+	}
+}
+
+// ContentTypes sets the content types which are compressed
+func ContentTypes(ct ...string) Option {
+	return func(o *Options) {
+		o.ContentTypes = ct
+	}
+}
+
+// Level sets the compression level
+func Level(l int) Option {
+	return func(o *Options) {
+		o.Level = l
+	}
+}
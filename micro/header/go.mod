@@ -4,5 +4,6 @@ go 1.13
 
 require (
 	github.com/micro/cli/v2 v2.1.2
+	github.com/micro/go-micro/v2 v2.9.1
 	github.com/micro/micro/v2 v2.9.1
 )
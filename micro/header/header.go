@@ -1,24 +1,95 @@
+// Package header is a micro plugin for setting, removing and rewriting http headers
 package header
 
 import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/micro/cli/v2"
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/config/source/file"
+	log "github.com/micro/go-micro/v2/logger"
 	"github.com/micro/micro/v2/plugin"
 )
 
+var (
+	// DefaultPath of the rules in the config
+	DefaultPath = []string{"header"}
+)
+
 type header struct {
-	hd map[string]string
+	opts Options
+	hd   map[string]string
+
+	sync.RWMutex
+	rules []rule
+}
+
+// config of the rules
+type rules struct {
+	Rules []Rule `json:"rules"`
+}
+
+// responseWriter applies the response rules before the header is written
+type responseWriter struct {
+	http.ResponseWriter
+	rules   []rule
+	applied bool
+}
+
+func (w *responseWriter) apply() {
+	if w.applied {
+		return
+	}
+	w.applied = true
+	for _, r := range w.rules {
+		r.response.apply(w.Header())
+	}
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	w.apply()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.apply()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseWriter) Flush() {
+	w.apply()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("header: response does not implement http.Hijacker")
+	}
+	return hj.Hijack()
 }
 
 func (h *header) Flags() []cli.Flag {
 	return []cli.Flag{
 		&cli.StringSliceFlag{
-			Name:   "header",
-			Usage:  "Headers to be set in the http response",
+			Name:    "header",
+			Usage:   "Headers to be set in the http response",
 			EnvVars: []string{"HEADER"},
 		},
+		&cli.StringFlag{
+			Name:    "header_config",
+			Usage:   "File to load the header rules from, reloaded when it changes",
+			EnvVars: []string{"HEADER_CONFIG"},
+		},
 	}
 }
 
@@ -29,26 +100,88 @@ func (h *header) Commands() []*cli.Command {
 func (h *header) Handler() plugin.Handler {
 	return func(ha http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			for k, v := range h.hd {
-				// set header
-				r.Header.Set(k, v)
+			h.RLock()
+			rules := match(h.rules, r.URL.Path)
+			h.RUnlock()
+
+			if len(rules) == 0 {
+				ha.ServeHTTP(w, r)
+				return
 			}
+
+			for _, rl := range rules {
+				rl.request.apply(r.Header)
+			}
+
 			// exec handler
-			ha.ServeHTTP(w, r)
+			rw := &responseWriter{ResponseWriter: w, rules: rules}
+			ha.ServeHTTP(rw, r)
+
+			// nothing was written so the header is sent after we return
+			rw.apply()
 		})
 	}
 }
 
-func (h *header) Init(ctx *cli.Context) error {
-	header := ctx.StringSlice("header")
+// update compiles the rules, the flag headers are applied to every response first
+func (h *header) update(rs []Rule) error {
+	if len(h.hd) > 0 {
+		rs = append([]Rule{{Response: Actions{Set: h.hd}}}, rs...)
+	}
 
-	// no op
-	if len(header) == 0 {
-		return nil
+	compiled, err := compile(rs)
+	if err != nil {
+		return err
 	}
 
+	h.Lock()
+	h.rules = compiled
+	h.Unlock()
+
+	return nil
+}
+
+func (h *header) load(c config.Config) error {
+	var r rules
+	if err := c.Get(DefaultPath...).Scan(&r); err != nil {
+		return err
+	}
+	return h.update(r.Rules)
+}
+
+// run reloads the rules on change, rewatching the config if the watcher fails
+func (h *header) run(c config.Config, watcher config.Watcher) {
+	for {
+		v, err := watcher.Next()
+		if err != nil {
+			log.Errorf("[header] failed to get next config: %v", err)
+			watcher.Stop()
+
+			for {
+				time.Sleep(time.Second)
+				if watcher, err = c.Watch(DefaultPath...); err == nil {
+					break
+				}
+				log.Errorf("[header] failed to watch config: %v", err)
+			}
+			continue
+		}
+
+		var r rules
+		if err := v.Scan(&r); err != nil {
+			log.Errorf("[header] failed to scan rules, skipping update: %v", err)
+			continue
+		}
+
+		if err := h.update(r.Rules); err != nil {
+			log.Errorf("[header] invalid rules, skipping update: %v", err)
+		}
+	}
+}
+
+func (h *header) Init(ctx *cli.Context) error {
 	// iterate the string slice
-	for _, pair := range header {
+	for _, pair := range ctx.StringSlice("header") {
 		parts := strings.Split(pair, "=")
 		if len(parts) < 2 {
 			continue
@@ -57,6 +190,40 @@ func (h *header) Init(ctx *cli.Context) error {
 		h.hd[parts[0]] = strings.Join(parts[1:], "=")
 	}
 
+	if err := h.update(h.opts.Rules); err != nil {
+		return fmt.Errorf("[header] %v", err)
+	}
+
+	c := h.opts.Config
+	if path := ctx.String("header_config"); len(path) > 0 {
+		var err error
+		c, err = config.NewConfig()
+		if err != nil {
+			return err
+		}
+		if err := c.Load(file.NewSource(file.WithPath(path))); err != nil {
+			return err
+		}
+	}
+
+	if c == nil {
+		return nil
+	}
+
+	// watch before loading so no change is missed
+	watcher, err := c.Watch(DefaultPath...)
+	if err != nil {
+		return err
+	}
+
+	// the config replaces the rules once loaded
+	if err := h.load(c); err != nil {
+		watcher.Stop()
+		return fmt.Errorf("[header] failed to load rules: %v", err)
+	}
+
+	go h.run(c, watcher)
+
 	return nil
 }
 
@@ -64,8 +231,20 @@ func (h *header) String() string {
 	return "header"
 }
 
-func NewPlugin() plugin.Plugin {
-	return &header{
-		hd: make(map[string]string),
+func NewPlugin(opts ...Option) plugin.Plugin {
+	var options Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	h := &header{
+		opts: options,
+		hd:   make(map[string]string),
+	}
+
+	if err := h.update(options.Rules); err != nil {
+		log.Fatalf("[header] %v", err)
 	}
+
+	return h
 }
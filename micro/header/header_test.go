@@ -0,0 +1,122 @@
+package header
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/micro/cli/v2"
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/config/source"
+	"github.com/micro/go-micro/v2/config/source/memory"
+)
+
+func TestRules(t *testing.T) {
+	p := NewPlugin(Rules(
+		Rule{
+			Prefix: "/",
+			Request: Actions{
+				Remove: []string{"X-Internal"},
+			},
+			Response: Actions{
+				Set: map[string]string{"Strict-Transport-Security": "max-age=31536000"},
+			},
+		},
+		Rule{
+			Prefix: "/legacy",
+			Request: Actions{
+				Rename:  map[string]string{"X-Old-Token": "Authorization"},
+				Rewrite: []Rewrite{{Header: "Authorization", Match: "^Token (.*)$", Replace: "Bearer $1"}},
+			},
+			Response: Actions{
+				Remove: []string{"Server"},
+				Add:    map[string]string{"Warning": `299 - "deprecated"`},
+			},
+		},
+	))
+
+	h := p.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "micro")
+		w.Header().Set("X-Saw-Internal", r.Header.Get("X-Internal"))
+		w.Header().Set("X-Saw-Authorization", r.Header.Get("Authorization"))
+	}))
+
+	r := httptest.NewRequest("GET", "/legacy/greeter", nil)
+	r.Header.Set("X-Internal", "secret")
+	r.Header.Set("X-Old-Token", "Token abc")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	expected := map[string]string{
+		"X-Saw-Internal":            "",
+		"X-Saw-Authorization":       "Bearer abc",
+		"Strict-Transport-Security": "max-age=31536000",
+		"Server":                    "",
+		"Warning":                   `299 - "deprecated"`,
+	}
+	for k, v := range expected {
+		if got := w.Header().Get(k); got != v {
+			t.Errorf("Expected %s %q got %q", k, v, got)
+		}
+	}
+
+	// only the generic rule applies elsewhere
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/greeter", nil))
+	if w.Header().Get("Server") != "micro" || len(w.Header().Get("Warning")) > 0 {
+		t.Errorf("Unexpected headers %v", w.Header())
+	}
+}
+
+func TestReload(t *testing.T) {
+	src := memory.NewSource(memory.WithJSON([]byte(`{"header": {"rules": [{"prefix": "/", "response": {"set": {"X-Version": "1"}}}]}}`)))
+
+	c, err := config.NewConfig(config.WithSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPlugin(Config(c))
+
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, f := range p.Flags() {
+		f.Apply(set)
+	}
+	set.Parse([]string{"--header", "X-Frame-Options=DENY"})
+
+	if err := p.Init(cli.NewContext(cli.NewApp(), set, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	h := p.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	get := func() http.Header {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		return w.Header()
+	}
+
+	if v := get().Get("X-Version"); v != "1" {
+		t.Fatalf("Expected version 1 got %q", v)
+	}
+
+	// the config watches the source asynchronously so keep writing until it's seen
+	for i := 0; i < 100 && get().Get("X-Version") != "2"; i++ {
+		src.Write(&source.ChangeSet{
+			Data:      []byte(`{"header": {"rules": [{"prefix": "/", "response": {"set": {"X-Version": "2"}}}]}}`),
+			Format:    "json",
+			Timestamp: time.Now(),
+		})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	hd := get()
+	if v := hd.Get("X-Version"); v != "2" {
+		t.Fatalf("Expected version 2 after reload got %q", v)
+	}
+	if v := hd.Get("X-Frame-Options"); v != "DENY" {
+		t.Fatalf("Expected flag header to be kept got %q", v)
+	}
+}
@@ -0,0 +1,28 @@
+package header
+
+import (
+	"github.com/micro/go-micro/v2/config"
+)
+
+type Options struct {
+	// Rules applied until the config is loaded
+	Rules []Rule
+	// Config is watched for rules at DefaultPath
+	Config config.Config
+}
+
+type Option func(o *Options)
+
+// Rules sets the header rules
+func Rules(r ...Rule) Option {
+	return func(o *Options) {
+		o.Rules = r
+	}
+}
+
+// Config sets the config the rules are loaded from and reloaded on change
+func Config(c config.Config) Option {
+	return func(o *Options) {
+		o.Config = c
+	}
+}
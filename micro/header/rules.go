@@ -0,0 +1,132 @@
+package header
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Rule changes the headers of requests and responses with paths under the prefix
+type Rule struct {
+	Prefix   string  `json:"prefix"`
+	Request  Actions `json:"request"`
+	Response Actions `json:"response"`
+}
+
+// Actions are applied in the order remove, rename, rewrite, set then add
+type Actions struct {
+	// Remove the headers
+	Remove []string `json:"remove"`
+	// Rename headers from the key to the value
+	Rename map[string]string `json:"rename"`
+	// Rewrite header values matching a regexp
+	Rewrite []Rewrite `json:"rewrite"`
+	// Set the headers, replacing any values
+	Set map[string]string `json:"set"`
+	// Add values to the headers
+	Add map[string]string `json:"add"`
+}
+
+// Rewrite replaces matches of the regexp in the header's values, the
+// replacement may reference groups e.g $1
+type Rewrite struct {
+	Header  string `json:"header"`
+	Match   string `json:"match"`
+	Replace string `json:"replace"`
+}
+
+type rewrite struct {
+	header  string
+	match   *regexp.Regexp
+	replace string
+}
+
+type actions struct {
+	remove  []string
+	rename  map[string]string
+	rewrite []rewrite
+	set     map[string]string
+	add     map[string]string
+}
+
+type rule struct {
+	prefix   string
+	request  actions
+	response actions
+}
+
+func compileActions(a Actions) (actions, error) {
+	c := actions{
+		remove: a.Remove,
+		rename: a.Rename,
+		set:    a.Set,
+		add:    a.Add,
+	}
+	for _, rw := range a.Rewrite {
+		re, err := regexp.Compile(rw.Match)
+		if err != nil {
+			return c, err
+		}
+		c.rewrite = append(c.rewrite, rewrite{rw.Header, re, rw.Replace})
+	}
+	return c, nil
+}
+
+// compile validates the rules and sorts them so more specific prefixes are applied last
+func compile(rules []Rule) ([]rule, error) {
+	compiled := make([]rule, 0, len(rules))
+
+	for _, r := range rules {
+		req, err := compileActions(r.Request)
+		if err != nil {
+			return nil, err
+		}
+		rsp, err := compileActions(r.Response)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, rule{r.Prefix, req, rsp})
+	}
+
+	sort.SliceStable(compiled, func(i, j int) bool {
+		return len(compiled[i].prefix) < len(compiled[j].prefix)
+	})
+
+	return compiled, nil
+}
+
+func (a *actions) apply(h http.Header) {
+	for _, k := range a.remove {
+		h.Del(k)
+	}
+	for from, to := range a.rename {
+		if v, ok := h[http.CanonicalHeaderKey(from)]; ok {
+			h.Del(from)
+			h[http.CanonicalHeaderKey(to)] = v
+		}
+	}
+	for _, rw := range a.rewrite {
+		vals := h[http.CanonicalHeaderKey(rw.header)]
+		for i, v := range vals {
+			vals[i] = rw.match.ReplaceAllString(v, rw.replace)
+		}
+	}
+	for k, v := range a.set {
+		h.Set(k, v)
+	}
+	for k, v := range a.add {
+		h.Add(k, v)
+	}
+}
+
+// match returns the rules applying to the path
+func match(rules []rule, path string) []rule {
+	var matched []rule
+	for _, r := range rules {
+		if strings.HasPrefix(path, r.prefix) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
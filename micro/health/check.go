@@ -0,0 +1,60 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/registry"
+	"github.com/micro/go-micro/v2/store"
+)
+
+// Check is a named dependency check
+type Check struct {
+	Name string
+	// Timeout of the check, zero uses the plugin's timeout
+	Timeout time.Duration
+	// Check returns an error if the dependency is unhealthy
+	Check func(ctx context.Context) error
+}
+
+// NewCheck returns a check of the func
+func NewCheck(name string, fn func(ctx context.Context) error) Check {
+	return Check{Name: name, Check: fn}
+}
+
+// RegistryCheck lists services from the registry, nil uses the default registry
+func RegistryCheck(r registry.Registry) Check {
+	return NewCheck("registry", func(ctx context.Context) error {
+		reg := r
+		if reg == nil {
+			reg = registry.DefaultRegistry
+		}
+		_, err := reg.ListServices()
+		return err
+	})
+}
+
+// BrokerCheck connects to the broker, nil uses the default broker
+func BrokerCheck(b broker.Broker) Check {
+	return NewCheck("broker", func(ctx context.Context) error {
+		br := b
+		if br == nil {
+			br = broker.DefaultBroker
+		}
+		// connecting a connected broker is a no-op
+		return br.Connect()
+	})
+}
+
+// StoreCheck lists a key from the store, nil uses the default store
+func StoreCheck(s store.Store) Check {
+	return NewCheck("store", func(ctx context.Context) error {
+		st := s
+		if st == nil {
+			st = store.DefaultStore
+		}
+		_, err := st.List(store.ListLimit(1))
+		return err
+	})
+}
@@ -0,0 +1,203 @@
+// Package health is a micro plugin serving /healthz and /readyz for kubernetes probes
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micro/cli/v2"
+	"github.com/micro/micro/v2/plugin"
+)
+
+var (
+	// DefaultTimeout of a check
+	DefaultTimeout = 5 * time.Second
+
+	// builtin checks enabled by flag
+	checks = map[string]func() Check{
+		"registry": func() Check { return RegistryCheck(nil) },
+		"broker":   func() Check { return BrokerCheck(nil) },
+		"store":    func() Check { return StoreCheck(nil) },
+	}
+)
+
+// Status of a check
+type Status struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Response written by the endpoints
+type Response struct {
+	Status string            `json:"status"`
+	Checks map[string]Status `json:"checks,omitempty"`
+}
+
+type health struct {
+	opts Options
+}
+
+func (h *health) Flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "health_checks",
+			Usage:   "Comma separated list of readiness checks e.g registry,broker,store",
+			EnvVars: []string{"HEALTH_CHECKS"},
+		},
+		&cli.DurationFlag{
+			Name:    "health_timeout",
+			Usage:   "Timeout of each check",
+			EnvVars: []string{"HEALTH_TIMEOUT"},
+		},
+	}
+}
+
+func (h *health) Commands() []*cli.Command {
+	return nil
+}
+
+// run executes the check, giving up when the timeout is reached
+func (h *health) run(c Check) Status {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = h.opts.Timeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errc <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		errc <- c.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %v", timeout)
+	}
+
+	st := Status{
+		Status:   "ok",
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		st.Status = "error"
+		st.Error = err.Error()
+	}
+	return st
+}
+
+// check runs the checks concurrently
+func (h *health) check(checks []Check) (Response, bool) {
+	rsp := Response{
+		Status: "ok",
+		Checks: make(map[string]Status, len(checks)),
+	}
+
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c Check) {
+			defer wg.Done()
+			st := h.run(c)
+			mtx.Lock()
+			rsp.Checks[c.Name] = st
+			mtx.Unlock()
+		}(c)
+	}
+
+	wg.Wait()
+
+	ok := true
+	for _, st := range rsp.Checks {
+		if st.Status != "ok" {
+			ok = false
+			rsp.Status = "error"
+		}
+	}
+
+	return rsp, ok
+}
+
+func (h *health) serve(w http.ResponseWriter, checks []Check) {
+	rsp, ok := h.check(checks)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(w).Encode(rsp)
+}
+
+func (h *health) Handler() plugin.Handler {
+	return func(ha http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/healthz":
+				h.serve(w, h.opts.Live)
+			case "/readyz":
+				h.serve(w, h.opts.Ready)
+			default:
+				ha.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func (h *health) Init(ctx *cli.Context) error {
+	if ctx.IsSet("health_timeout") {
+		h.opts.Timeout = ctx.Duration("health_timeout")
+	}
+
+	for _, name := range strings.Split(ctx.String("health_checks"), ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		fn, ok := checks[name]
+		if !ok {
+			return fmt.Errorf("unknown health check %s", name)
+		}
+		h.opts.Ready = append(h.opts.Ready, fn())
+	}
+
+	return nil
+}
+
+func (h *health) String() string {
+	return "health"
+}
+
+// NewPlugin returns a plugin serving the liveness and readiness checks
+func NewPlugin(opts ...Option) plugin.Plugin {
+	options := Options{
+		Timeout: DefaultTimeout,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &health{
+		opts: options,
+	}
+}
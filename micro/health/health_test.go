@@ -0,0 +1,74 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/registry/memory"
+	smemory "github.com/micro/go-micro/v2/store/memory"
+)
+
+func TestHandler(t *testing.T) {
+	slow := NewCheck("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	slow.Timeout = 10 * time.Millisecond
+
+	h := NewPlugin(
+		Ready(
+			RegistryCheck(memory.NewRegistry()),
+			StoreCheck(smemory.NewStore()),
+		),
+	).Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	get := func(h http.Handler, path string) (int, Response) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		var rsp Response
+		json.Unmarshal(w.Body.Bytes(), &rsp)
+		return w.Code, rsp
+	}
+
+	if code, rsp := get(h, "/healthz"); code != 200 || rsp.Status != "ok" {
+		t.Fatalf("Expected healthy got %d %+v", code, rsp)
+	}
+
+	code, rsp := get(h, "/readyz")
+	if code != 200 || rsp.Status != "ok" || rsp.Checks["registry"].Status != "ok" || rsp.Checks["store"].Status != "ok" {
+		t.Fatalf("Expected ready got %d %+v", code, rsp)
+	}
+
+	if code, _ := get(h, "/greeter"); code != http.StatusTeapot {
+		t.Fatalf("Expected other paths to pass through got %d", code)
+	}
+
+	h = NewPlugin(
+		Ready(
+			slow,
+			NewCheck("broken", func(ctx context.Context) error { return errors.New("broken") }),
+			NewCheck("ok", func(ctx context.Context) error { return nil }),
+		),
+	).Handler()(http.NotFoundHandler())
+
+	code, rsp = get(h, "/readyz")
+	if code != http.StatusServiceUnavailable || rsp.Status != "error" {
+		t.Fatalf("Expected not ready got %d %+v", code, rsp)
+	}
+	if st := rsp.Checks["broken"]; st.Status != "error" || st.Error != "broken" {
+		t.Fatalf("Unexpected broken status %+v", st)
+	}
+	if st := rsp.Checks["slow"]; st.Status != "error" {
+		t.Fatalf("Expected slow check to time out %+v", st)
+	}
+	if st := rsp.Checks["ok"]; st.Status != "ok" {
+		t.Fatalf("Unexpected ok status %+v", st)
+	}
+}
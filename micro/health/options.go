@@ -0,0 +1,37 @@
+package health
+
+import (
+	"time"
+)
+
+type Options struct {
+	// Live checks run by /healthz
+	Live []Check
+	// Ready checks run by /readyz
+	Ready []Check
+	// Timeout of checks without their own
+	Timeout time.Duration
+}
+
+type Option func(o *Options)
+
+// Live adds checks to /healthz, failing them restarts the pod so keep them cheap
+func Live(c ...Check) Option {
+	return func(o *Options) {
+		o.Live = append(o.Live, c...)
+	}
+}
+
+// Ready adds checks to /readyz
+func Ready(c ...Check) Option {
+	return func(o *Options) {
+		o.Ready = append(o.Ready, c...)
+	}
+}
+
+// Timeout sets the default timeout of checks
+func Timeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
@@ -1,71 +1,230 @@
-// Package ip_allow is a micro plugin for allowing ip addresses
+// Package ip_allow is a micro plugin for allowing and denying ip addresses
 package ip_allow
 
 import (
+	"fmt"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/micro/cli/v2"
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/config/source/file"
 	log "github.com/micro/go-micro/v2/logger"
 	"github.com/micro/micro/v2/plugin"
 )
 
-type allow struct {
-	cidrs map[string]*net.IPNet
-	ips   map[string]bool
+var (
+	// DefaultPath of the rules in the config
+	DefaultPath = []string{"ip_allow"}
+)
+
+// Rules are the ips and cidrs allowed and denied
+type Rules struct {
+	Allow          []string `json:"allow"`
+	Deny           []string `json:"deny"`
+	TrustedProxies []string `json:"trusted_proxies"`
 }
 
-func (w *allow) Flags() []cli.Flag {
-	return []cli.Flag{
-		&cli.StringFlag{
-			Name:    "ip_allow",
-			Usage:   "Comma separated list of allowed IPs",
-			EnvVars: []string{"IP_ALLOW"},
-		},
+// nets is a parsed list of ips and cidrs
+type nets []*net.IPNet
+
+func (n nets) contains(ip net.IP) bool {
+	for _, cidr := range n {
+		if cidr.Contains(ip) {
+			return true
+		}
 	}
+	return false
+}
+
+type rules struct {
+	allow   nets
+	deny    nets
+	trusted nets
+}
+
+type allow struct {
+	opts Options
+
+	sync.RWMutex
+	rules *rules
 }
 
-func (w *allow) load(ips ...string) {
+// parse returns the ips and cidrs as networks, an ip is a network of one address
+func parse(ips ...string) (nets, error) {
+	var n nets
+
 	for _, ip := range ips {
-		parts := strings.Split(ip, "/")
+		ip = strings.TrimSpace(ip)
+		if len(ip) == 0 {
+			continue
+		}
 
-		switch len(parts) {
 		// assume just an ip
-		case 1:
-			w.ips[ip] = true
-		case 2:
-			// parse cidr
-			_, ipnet, err := net.ParseCIDR(ip)
-			if err != nil {
-				log.Fatalf("[ip_allow] failed to parse %v: %v", ip, err)
+		if !strings.Contains(ip, "/") {
+			nip := net.ParseIP(ip)
+			if nip == nil {
+				return nil, fmt.Errorf("failed to parse %v", ip)
 			}
-			w.cidrs[ipnet.String()] = ipnet
-		default:
-			log.Fatalf("[ip_allow] failed to parse %v", ip)
+			bits := 8 * net.IPv6len
+			if v4 := nip.To4(); v4 != nil {
+				nip = v4
+				bits = 8 * net.IPv4len
+			}
+			n = append(n, &net.IPNet{IP: nip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		// parse cidr
+		_, ipnet, err := net.ParseCIDR(ip)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %v: %v", ip, err)
 		}
+		n = append(n, ipnet)
 	}
 
+	return n, nil
 }
 
-func (w *allow) match(ip string) bool {
-	// make ip
-	nip := net.ParseIP(ip)
+func newRules(r Rules) (*rules, error) {
+	allow, err := parse(r.Allow...)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := parse(r.Deny...)
+	if err != nil {
+		return nil, err
+	}
+	trusted, err := parse(r.TrustedProxies...)
+	if err != nil {
+		return nil, err
+	}
+	return &rules{allow: allow, deny: deny, trusted: trusted}, nil
+}
 
-	// check ips
-	if ok := w.ips[nip.String()]; ok {
+// match returns true if the ip isn't denied and is allowed, no allow rules allows all
+func (r *rules) match(ip net.IP) bool {
+	if r.deny.contains(ip) {
+		return false
+	}
+	if len(r.allow) == 0 {
 		return true
 	}
+	return r.allow.contains(ip)
+}
 
-	// check cidrs
-	for _, cidr := range w.cidrs {
-		if cidr.Contains(nip) {
-			return true
+// clientIP returns the address of the client, reading X-Forwarded-For from
+// right to left while the request came through a trusted proxy
+func (r *rules) clientIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || len(r.trusted) == 0 || !r.trusted.contains(ip) {
+		return ip
+	}
+
+	var hops []string
+	for _, v := range req.Header[http.CanonicalHeaderKey("X-Forwarded-For")] {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// a proxy we trust wrote garbage, stop at the last good hop
+			break
+		}
+		ip = hop
+		if !r.trusted.contains(hop) {
+			break
 		}
 	}
 
-	// no match
-	return false
+	return ip
+}
+
+func (w *allow) Flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "ip_allow",
+			Usage:   "Comma separated list of allowed IPs",
+			EnvVars: []string{"IP_ALLOW"},
+		},
+		&cli.StringFlag{
+			Name:    "ip_deny",
+			Usage:   "Comma separated list of denied IPs",
+			EnvVars: []string{"IP_DENY"},
+		},
+		&cli.StringFlag{
+			Name:    "ip_trusted_proxies",
+			Usage:   "Comma separated list of proxies whose X-Forwarded-For header is trusted",
+			EnvVars: []string{"IP_TRUSTED_PROXIES"},
+		},
+		&cli.StringFlag{
+			Name:    "ip_allow_config",
+			Usage:   "File to load the rules from, reloaded when it changes",
+			EnvVars: []string{"IP_ALLOW_CONFIG"},
+		},
+	}
+}
+
+func (w *allow) update(r *rules) {
+	w.Lock()
+	w.rules = r
+	w.Unlock()
+}
+
+func (w *allow) load(c config.Config) error {
+	var r Rules
+	if err := c.Get(DefaultPath...).Scan(&r); err != nil {
+		return err
+	}
+	rs, err := newRules(r)
+	if err != nil {
+		return err
+	}
+	w.update(rs)
+	return nil
+}
+
+// run reloads the rules on change, rewatching the config if the watcher fails
+func (w *allow) run(c config.Config, watcher config.Watcher) {
+	for {
+		v, err := watcher.Next()
+		if err != nil {
+			log.Errorf("[ip_allow] failed to get next config: %v", err)
+			watcher.Stop()
+
+			for {
+				time.Sleep(time.Second)
+				if watcher, err = c.Watch(DefaultPath...); err == nil {
+					break
+				}
+				log.Errorf("[ip_allow] failed to watch config: %v", err)
+			}
+			continue
+		}
+
+		var r Rules
+		if err := v.Scan(&r); err != nil {
+			log.Errorf("[ip_allow] failed to scan rules, skipping update: %v", err)
+			continue
+		}
+
+		rs, err := newRules(r)
+		if err != nil {
+			log.Errorf("[ip_allow] invalid rules, skipping update: %v", err)
+			continue
+		}
+
+		w.update(rs)
+	}
 }
 
 func (w *allow) Commands() []*cli.Command {
@@ -75,13 +234,14 @@ func (w *allow) Commands() []*cli.Command {
 func (w *allow) Handler() plugin.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
-			// check remote addr; if we can't parse it passes through
-			if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
-				// reject if no match
-				if !w.match(ip) {
-					http.Error(rw, "forbidden", 403)
-					return
-				}
+			w.RLock()
+			rules := w.rules
+			w.RUnlock()
+
+			// if we can't parse the remote addr it passes through
+			if ip := rules.clientIP(r); ip != nil && !rules.match(ip) {
+				http.Error(rw, "forbidden", 403)
+				return
 			}
 
 			// serve the request
@@ -91,9 +251,53 @@ func (w *allow) Handler() plugin.Handler {
 }
 
 func (w *allow) Init(ctx *cli.Context) error {
-	if allow := ctx.String("ip_allow"); len(allow) > 0 {
-		w.load(strings.Split(allow, ",")...)
+	r := w.opts.Rules
+
+	if v := ctx.String("ip_allow"); len(v) > 0 {
+		r.Allow = strings.Split(v, ",")
+	}
+	if v := ctx.String("ip_deny"); len(v) > 0 {
+		r.Deny = strings.Split(v, ",")
+	}
+	if v := ctx.String("ip_trusted_proxies"); len(v) > 0 {
+		r.TrustedProxies = strings.Split(v, ",")
+	}
+
+	rs, err := newRules(r)
+	if err != nil {
+		return fmt.Errorf("[ip_allow] %v", err)
+	}
+	w.update(rs)
+
+	c := w.opts.Config
+	if path := ctx.String("ip_allow_config"); len(path) > 0 {
+		c, err = config.NewConfig()
+		if err != nil {
+			return err
+		}
+		if err := c.Load(file.NewSource(file.WithPath(path))); err != nil {
+			return err
+		}
+	}
+
+	if c == nil {
+		return nil
 	}
+
+	// watch before loading so no change is missed
+	watcher, err := c.Watch(DefaultPath...)
+	if err != nil {
+		return err
+	}
+
+	// the config replaces the rules once loaded
+	if err := w.load(c); err != nil {
+		watcher.Stop()
+		return fmt.Errorf("[ip_allow] failed to load rules: %v", err)
+	}
+
+	go w.run(c, watcher)
+
 	return nil
 }
 
@@ -101,19 +305,23 @@ func (w *allow) String() string {
 	return "ip_allow"
 }
 
-func NewPlugin() plugin.Plugin {
-	return NewIPAllow()
-}
+func NewPlugin(opts ...Option) plugin.Plugin {
+	var options Options
+	for _, o := range opts {
+		o(&options)
+	}
 
-func NewIPAllow(ips ...string) plugin.Plugin {
-	// create plugin
-	w := &allow{
-		cidrs: make(map[string]*net.IPNet),
-		ips:   make(map[string]bool),
+	rs, err := newRules(options.Rules)
+	if err != nil {
+		log.Fatalf("[ip_allow] %v", err)
 	}
 
-	// load ips
-	w.load(ips...)
+	return &allow{
+		opts:  options,
+		rules: rs,
+	}
+}
 
-	return w
+func NewIPAllow(ips ...string) plugin.Plugin {
+	return NewPlugin(Allow(ips...))
 }
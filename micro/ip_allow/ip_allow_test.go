@@ -0,0 +1,109 @@
+package ip_allow
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/micro/cli/v2"
+	"github.com/micro/go-micro/v2/config"
+	"github.com/micro/go-micro/v2/config/source"
+	"github.com/micro/go-micro/v2/config/source/memory"
+)
+
+func TestRules(t *testing.T) {
+	r, err := newRules(Rules{
+		Allow:          []string{"10.0.0.0/8", "192.168.1.1"},
+		Deny:           []string{"10.0.0.1"},
+		TrustedProxies: []string{"172.16.0.0/12"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testData := []struct {
+		remote    string
+		forwarded string
+		allowed   bool
+	}{
+		{"10.1.2.3:1234", "", true},
+		{"10.0.0.1:1234", "", false},
+		{"192.168.1.1:1234", "", true},
+		{"192.168.1.2:1234", "", false},
+		// untrusted proxies can't spoof the client
+		{"192.168.1.2:1234", "10.1.2.3", false},
+		{"172.16.0.1:1234", "10.1.2.3", true},
+		{"172.16.0.1:1234", "192.168.1.2, 10.1.2.3, 172.16.0.2", true},
+		{"172.16.0.1:1234", "10.1.2.3, 10.0.0.1", false},
+	}
+
+	for _, d := range testData {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = d.remote
+		if len(d.forwarded) > 0 {
+			req.Header.Set("X-Forwarded-For", d.forwarded)
+		}
+		if ok := r.match(r.clientIP(req)); ok != d.allowed {
+			t.Errorf("%s forwarded for %q: expected allowed %v got %v", d.remote, d.forwarded, d.allowed, ok)
+		}
+	}
+
+	if _, err := newRules(Rules{Allow: []string{"10.0.0.0/33"}}); err == nil {
+		t.Fatal("Expected error parsing invalid cidr")
+	}
+}
+
+func TestReload(t *testing.T) {
+	src := memory.NewSource(memory.WithJSON([]byte(`{"ip_allow": {"deny": ["10.0.0.1"]}}`)))
+
+	c, err := config.NewConfig(config.WithSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPlugin(Config(c))
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, f := range p.Flags() {
+		f.Apply(set)
+	}
+
+	if err := p.Init(cli.NewContext(cli.NewApp(), set, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	h := p.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	code := func(remote string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if c := code("10.0.0.1:1234"); c != 403 {
+		t.Fatalf("Expected 403 got %d", c)
+	}
+	if c := code("10.0.0.2:1234"); c != 200 {
+		t.Fatalf("Expected 200 got %d", c)
+	}
+
+	// the config watches the source asynchronously so keep writing until it's seen
+	for i := 0; i < 100 && code("10.0.0.2:1234") != 403; i++ {
+		src.Write(&source.ChangeSet{
+			Data:      []byte(`{"ip_allow": {"deny": ["10.0.0.2"]}}`),
+			Format:    "json",
+			Timestamp: time.Now(),
+		})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if c := code("10.0.0.2:1234"); c != 403 {
+		t.Fatalf("Expected 403 after reload got %d", c)
+	}
+	if c := code("10.0.0.1:1234"); c != 200 {
+		t.Fatalf("Expected 200 after reload got %d", c)
+	}
+}
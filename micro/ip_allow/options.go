@@ -0,0 +1,42 @@
+package ip_allow
+
+import (
+	"github.com/micro/go-micro/v2/config"
+)
+
+type Options struct {
+	// Rules applied until the config is loaded
+	Rules Rules
+	// Config is watched for rules at DefaultPath
+	Config config.Config
+}
+
+type Option func(o *Options)
+
+// Allow sets the allowed ips and cidrs
+func Allow(ips ...string) Option {
+	return func(o *Options) {
+		o.Rules.Allow = ips
+	}
+}
+
+// Deny sets the denied ips and cidrs, deny takes precedence over allow
+func Deny(ips ...string) Option {
+	return func(o *Options) {
+		o.Rules.Deny = ips
+	}
+}
+
+// TrustedProxies sets the proxies whose X-Forwarded-For header is trusted
+func TrustedProxies(ips ...string) Option {
+	return func(o *Options) {
+		o.Rules.TrustedProxies = ips
+	}
+}
+
+// Config sets the config the rules are loaded from and reloaded on change
+func Config(c config.Config) Option {
+	return func(o *Options) {
+		o.Config = c
+	}
+}
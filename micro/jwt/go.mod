@@ -0,0 +1,10 @@
+module github.com/micro/go-plugins/micro/jwt/v2
+
+go 1.13
+
+require (
+	github.com/dgrijalva/jwt-go v3.2.0+incompatible
+	github.com/micro/cli/v2 v2.1.2
+	github.com/micro/go-micro/v2 v2.9.1
+	github.com/micro/micro/v2 v2.9.1
+)
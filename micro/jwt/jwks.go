@@ -0,0 +1,206 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrKeyNotFound is returned if no key in the set matches the token
+	ErrKeyNotFound = errors.New("key not found")
+
+	// unknown keys refetch the set at most this often
+	minRefresh = 10 * time.Second
+)
+
+// jwk is a json web key, only the fields of RSA and EC public keys are decoded
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type keySet struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+
+	// serialises refreshes
+	refreshing sync.Mutex
+
+	sync.RWMutex
+	keys      map[string]interface{}
+	fetched   time.Time
+	attempted time.Time
+}
+
+func newKeySet(url string, interval time.Duration) *keySet {
+	return &keySet{
+		url:      url,
+		interval: interval,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		keys:     make(map[string]interface{}),
+	}
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// publicKey returns the rsa or ecdsa public key
+func (k *jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+// refresh fetches the key set, keys which can't be decoded are skipped
+func (s *keySet) refresh() error {
+	// record failed attempts too so they're rate limited
+	defer func() {
+		s.Lock()
+		s.attempted = time.Now()
+		s.Unlock()
+	}()
+
+	rsp, err := s.client.Get(s.url)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching key set: %s", rsp.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(rsp.Body).Decode(&set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]interface{})
+	for _, k := range set.Keys {
+		if len(k.Use) > 0 && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	s.Lock()
+	s.keys = keys
+	s.fetched = time.Now()
+	s.Unlock()
+
+	return nil
+}
+
+// cached returns the key with the id and whether the set shouldn't be refetched,
+// either because it's fresh or because it was fetched too recently
+func (s *keySet) cached(kid string) (interface{}, bool, bool) {
+	s.RLock()
+	defer s.RUnlock()
+
+	key, ok := s.keys[kid]
+	if ok && time.Since(s.fetched) < s.interval {
+		return key, ok, true
+	}
+
+	// keys may have been rotated, but don't let bad tokens or a failing server hammer it
+	return key, ok, time.Since(s.attempted) < minRefresh
+}
+
+// key returns the key with the id, refetching the set if it's stale or the key is unknown
+func (s *keySet) key(kid string) (interface{}, error) {
+	key, ok, done := s.cached(kid)
+	if !done {
+		var err error
+		if key, ok, err = s.refetch(kid); err != nil {
+			return nil, err
+		}
+	}
+
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+
+	return key, nil
+}
+
+// refetch refreshes the set once for concurrent callers and returns the key with the id
+func (s *keySet) refetch(kid string) (interface{}, bool, error) {
+	s.refreshing.Lock()
+	defer s.refreshing.Unlock()
+
+	// another caller may have refreshed the set while we waited
+	key, ok, done := s.cached(kid)
+	if done {
+		return key, ok, nil
+	}
+
+	if err := s.refresh(); err != nil {
+		// keep using the keys we have
+		if ok {
+			return key, ok, nil
+		}
+		return nil, false, err
+	}
+
+	key, ok, _ = s.cached(kid)
+	return key, ok, nil
+}
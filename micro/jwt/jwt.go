@@ -0,0 +1,273 @@
+// Package jwt is a micro plugin for validating json web tokens and forwarding their claims
+package jwt
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/micro/cli/v2"
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/micro/v2/plugin"
+)
+
+var (
+	// DefaultRefreshInterval of the key set
+	DefaultRefreshInterval = time.Hour
+
+	// only asymmetric algorithms, the key set never holds secrets
+	algorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+)
+
+type validator struct {
+	opts Options
+	keys *keySet
+}
+
+func (v *validator) Flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:    "jwt_jwks_url",
+			Usage:   "Url of the json web key set used to verify tokens",
+			EnvVars: []string{"JWT_JWKS_URL"},
+		},
+		&cli.StringFlag{
+			Name:    "jwt_issuer",
+			Usage:   "Required issuer of tokens",
+			EnvVars: []string{"JWT_ISSUER"},
+		},
+		&cli.StringFlag{
+			Name:    "jwt_audience",
+			Usage:   "Comma separated list of accepted audiences",
+			EnvVars: []string{"JWT_AUDIENCE"},
+		},
+		&cli.StringFlag{
+			Name:    "jwt_claims",
+			Usage:   "Comma separated list of claims forwarded as headers e.g sub=X-User-Id,email=X-User-Email",
+			EnvVars: []string{"JWT_CLAIMS"},
+		},
+		&cli.StringFlag{
+			Name:    "jwt_exclude",
+			Usage:   "Comma separated list of path prefixes which don't require a token",
+			EnvVars: []string{"JWT_EXCLUDE"},
+		},
+	}
+}
+
+func (v *validator) Commands() []*cli.Command {
+	return nil
+}
+
+func (v *validator) excluded(path string) bool {
+	for _, p := range v.opts.Exclude {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// keyFunc returns the key from the set matching the token's key id
+func (v *validator) keyFunc(t *jwtgo.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	return v.keys.key(kid)
+}
+
+// validate parses the token and checks its claims
+func (v *validator) validate(token string) (jwtgo.MapClaims, error) {
+	parser := &jwtgo.Parser{
+		ValidMethods: algorithms,
+		// times are checked below with leeway
+		SkipClaimsValidation: true,
+	}
+
+	claims := jwtgo.MapClaims{}
+	if _, err := parser.ParseWithClaims(token, claims, v.keyFunc); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(v.opts.Leeway)) {
+		return nil, errors.New("token is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.opts.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("token is not valid yet")
+	}
+
+	if len(v.opts.Issuer) > 0 && !claims.VerifyIssuer(v.opts.Issuer, true) {
+		return nil, errors.New("invalid issuer")
+	}
+
+	if len(v.opts.Audience) > 0 && !audience(claims["aud"], v.opts.Audience) {
+		return nil, errors.New("invalid audience")
+	}
+
+	return claims, nil
+}
+
+// audience returns true if the aud claim, a string or list, contains an accepted audience
+func audience(aud interface{}, accepted []string) bool {
+	var auds []string
+
+	switch a := aud.(type) {
+	case string:
+		auds = []string{a}
+	case []interface{}:
+		for _, v := range a {
+			if s, ok := v.(string); ok {
+				auds = append(auds, s)
+			}
+		}
+	}
+
+	for _, a := range auds {
+		for _, b := range accepted {
+			if a == b {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// value formats a claim as a header value, lists are comma separated
+func value(v interface{}) string {
+	switch c := v.(type) {
+	case string:
+		return c
+	case float64:
+		return fmt.Sprintf("%v", int64(c))
+	case []interface{}:
+		vals := make([]string, 0, len(c))
+		for _, i := range c {
+			vals = append(vals, value(i))
+		}
+		return strings.Join(vals, ",")
+	default:
+		return fmt.Sprintf("%v", c)
+	}
+}
+
+func unauthorized(w http.ResponseWriter, desc string) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, desc))
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+func (v *validator) Handler() plugin.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// never trust claim headers from the client
+			for _, header := range v.opts.Claims {
+				r.Header.Del(header)
+			}
+
+			if v.keys == nil || v.excluded(r.URL.Path) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			auth := r.Header.Get("Authorization")
+			if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := v.validate(strings.TrimSpace(auth[7:]))
+			if err != nil {
+				unauthorized(w, err.Error())
+				return
+			}
+
+			// the api passes headers on to services as metadata
+			for claim, header := range v.opts.Claims {
+				if c, ok := claims[claim]; ok {
+					r.Header.Set(header, value(c))
+				}
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func split(s string) []string {
+	var vals []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
+
+func (v *validator) Init(ctx *cli.Context) error {
+	if u := ctx.String("jwt_jwks_url"); len(u) > 0 {
+		v.opts.JWKS = u
+	}
+	if iss := ctx.String("jwt_issuer"); len(iss) > 0 {
+		v.opts.Issuer = iss
+	}
+	if aud := split(ctx.String("jwt_audience")); len(aud) > 0 {
+		v.opts.Audience = aud
+	}
+	if ex := split(ctx.String("jwt_exclude")); len(ex) > 0 {
+		v.opts.Exclude = ex
+	}
+	for _, c := range split(ctx.String("jwt_claims")) {
+		parts := strings.SplitN(c, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid jwt claim %s", c)
+		}
+		Claim(parts[0], parts[1])(&v.opts)
+	}
+
+	if len(v.opts.JWKS) == 0 {
+		log.Warn("[jwt] no key set url, tokens won't be validated")
+		v.keys = nil
+		return nil
+	}
+
+	v.keys = newKeySet(v.opts.JWKS, v.opts.RefreshInterval)
+
+	// fetch the set now so the first requests don't wait, it's refetched when needed
+	if err := v.keys.refresh(); err != nil {
+		log.Errorf("[jwt] failed to fetch key set: %v", err)
+	}
+
+	return nil
+}
+
+func (v *validator) String() string {
+	return "jwt"
+}
+
+// NewPlugin returns a plugin which rejects requests without a valid bearer token
+func NewPlugin(opts ...Option) plugin.Plugin {
+	options := Options{
+		RefreshInterval: DefaultRefreshInterval,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	v := &validator{
+		opts: options,
+	}
+
+	if len(options.JWKS) > 0 {
+		v.keys = newKeySet(options.JWKS, options.RefreshInterval)
+	}
+
+	return v
+}
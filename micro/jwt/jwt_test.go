@@ -0,0 +1,187 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+)
+
+func TestHandler(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "test",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	defer jwks.Close()
+
+	sign := func(method jwtgo.SigningMethod, k interface{}, claims jwtgo.MapClaims) string {
+		tok := jwtgo.NewWithClaims(method, claims)
+		tok.Header["kid"] = "test"
+		s, err := tok.SignedString(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	exp := time.Now().Add(time.Hour).Unix()
+
+	v := NewPlugin(
+		JWKS(jwks.URL),
+		Issuer("https://issuer"),
+		Audience("api"),
+		Claim("sub", "X-User-Id"),
+		Claim("roles", "X-User-Roles"),
+		Exclude("/public"),
+	)
+
+	h := v.Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-User-Id", r.Header.Get("X-User-Id"))
+		w.Header().Set("X-User-Roles", r.Header.Get("X-User-Roles"))
+	}))
+
+	testData := []struct {
+		name  string
+		path  string
+		token string
+		code  int
+		user  string
+	}{
+		{"valid", "/greeter", sign(jwtgo.SigningMethodRS256, key, jwtgo.MapClaims{
+			"iss": "https://issuer", "aud": []string{"other", "api"}, "exp": exp, "sub": "john", "roles": []string{"admin", "dev"},
+		}), 200, "john"},
+		{"excluded", "/public/index", "", 200, ""},
+		{"missing", "/greeter", "", 401, ""},
+		{"expired", "/greeter", sign(jwtgo.SigningMethodRS256, key, jwtgo.MapClaims{
+			"iss": "https://issuer", "aud": "api", "exp": time.Now().Add(-time.Hour).Unix(),
+		}), 401, ""},
+		{"no expiry", "/greeter", sign(jwtgo.SigningMethodRS256, key, jwtgo.MapClaims{
+			"iss": "https://issuer", "aud": "api",
+		}), 401, ""},
+		{"audience", "/greeter", sign(jwtgo.SigningMethodRS256, key, jwtgo.MapClaims{
+			"iss": "https://issuer", "aud": "other", "exp": exp,
+		}), 401, ""},
+		{"issuer", "/greeter", sign(jwtgo.SigningMethodRS256, key, jwtgo.MapClaims{
+			"iss": "https://other", "aud": "api", "exp": exp,
+		}), 401, ""},
+		{"symmetric", "/greeter", sign(jwtgo.SigningMethodHS256, []byte("secret"), jwtgo.MapClaims{
+			"iss": "https://issuer", "aud": "api", "exp": exp,
+		}), 401, ""},
+	}
+
+	for _, d := range testData {
+		r := httptest.NewRequest("GET", d.path, nil)
+		// spoofed claims are always removed
+		r.Header.Set("X-User-Id", "spoofed")
+		if len(d.token) > 0 {
+			r.Header.Set("Authorization", "Bearer "+d.token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != d.code {
+			t.Errorf("%s: expected %d got %d", d.name, d.code, w.Code)
+			continue
+		}
+		if d.code != 200 {
+			continue
+		}
+		if u := w.Header().Get("X-User-Id"); u != d.user {
+			t.Errorf("%s: expected user %q got %q", d.name, d.user, u)
+		}
+	}
+
+	r := httptest.NewRequest("GET", "/greeter", nil)
+	r.Header.Set("Authorization", "Bearer "+testData[0].token)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if roles := w.Header().Get("X-User-Roles"); roles != "admin,dev" {
+		t.Errorf("Expected roles admin,dev got %q", roles)
+	}
+}
+
+func TestKeySetRefresh(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		fetches int32
+		failing int32 = 1
+	)
+
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		// hold the request so concurrent lookups overlap
+		time.Sleep(time.Millisecond * 50)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "test",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	defer jwks.Close()
+
+	// a failed fetch counts towards the minimum refresh interval
+	s := newKeySet(jwks.URL, time.Hour)
+	if _, err := s.key("test"); err == nil || err == ErrKeyNotFound {
+		t.Fatalf("expected fetch error got %v", err)
+	}
+	if _, err := s.key("test"); err != ErrKeyNotFound {
+		t.Fatalf("expected %v got %v", ErrKeyNotFound, err)
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Fatalf("expected 1 fetch got %d", n)
+	}
+
+	// concurrent lookups of an unknown key share a fetch
+	atomic.StoreInt32(&failing, 0)
+	atomic.StoreInt32(&fetches, 0)
+
+	s = newKeySet(jwks.URL, time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.key("test"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Fatalf("expected 1 fetch got %d", n)
+	}
+}
@@ -0,0 +1,76 @@
+package jwt
+
+import (
+	"time"
+)
+
+type Options struct {
+	// JWKS is the url of the json web key set used to verify tokens
+	JWKS string
+	// Issuer of the tokens, empty allows any issuer
+	Issuer string
+	// Audience accepted, a token must contain one of them, empty allows any audience
+	Audience []string
+	// Claims forwarded to the backend, claim name to header
+	Claims map[string]string
+	// Exclude paths with these prefixes from validation
+	Exclude []string
+	// Leeway allowed when checking the expiry and not before times
+	Leeway time.Duration
+	// RefreshInterval of the key set
+	RefreshInterval time.Duration
+}
+
+type Option func(o *Options)
+
+// JWKS sets the url of the key set used to verify tokens
+func JWKS(url string) Option {
+	return func(o *Options) {
+		o.JWKS = url
+	}
+}
+
+// Issuer sets the required issuer
+func Issuer(iss string) Option {
+	return func(o *Options) {
+		o.Issuer = iss
+	}
+}
+
+// Audience sets the accepted audiences
+func Audience(aud ...string) Option {
+	return func(o *Options) {
+		o.Audience = aud
+	}
+}
+
+// Claim forwards a claim to the backend as the header
+func Claim(claim, header string) Option {
+	return func(o *Options) {
+		if o.Claims == nil {
+			o.Claims = make(map[string]string)
+		}
+		o.Claims[claim] = header
+	}
+}
+
+// Exclude paths with the prefixes from validation
+func Exclude(prefixes ...string) Option {
+	return func(o *Options) {
+		o.Exclude = prefixes
+	}
+}
+
+// Leeway sets the clock skew allowed when checking times
+func Leeway(d time.Duration) Option {
+	return func(o *Options) {
+		o.Leeway = d
+	}
+}
+
+// RefreshInterval sets how often the key set is refetched
+func RefreshInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.RefreshInterval = d
+	}
+}
@@ -8,3 +8,5 @@ require (
 	github.com/micro/go-plugins/micro/metrics/prometheus/v2 v2.0.1
 	github.com/micro/micro/v2 v2.9.1
 )
+
+replace github.com/micro/go-plugins/micro/metrics/prometheus/v2 => ./prometheus
@@ -24,6 +24,10 @@ type Provider interface {
 }
 
 func (m *Metrics) Handler(h http.Handler) http.Handler {
+	// no provider configured
+	if m.Provider == nil {
+		return h
+	}
 	return m.Provider.Handler(h)
 }
 
@@ -38,6 +42,28 @@ func NewPlugin() plugin.Plugin {
 				Name:  "metrics",
 				Usage: "Specify the type of metrics provider e.g prometheus",
 			},
+			&cli.StringFlag{
+				Name:    "metrics_path",
+				Usage:   "Path the metrics are served on",
+				EnvVars: []string{"METRICS_PATH"},
+				Value:   "/metrics",
+			},
+			&cli.StringFlag{
+				Name:    "metrics_auth_user",
+				Usage:   "Username used for basic auth of the metrics path",
+				EnvVars: []string{"METRICS_AUTH_USER"},
+			},
+			&cli.StringFlag{
+				Name:    "metrics_auth_pass",
+				Usage:   "Password used for basic auth of the metrics path",
+				EnvVars: []string{"METRICS_AUTH_PASS"},
+			},
+			&cli.IntFlag{
+				Name:    "metrics_route_depth",
+				Usage:   "Number of path segments used as the route label",
+				EnvVars: []string{"METRICS_ROUTE_DEPTH"},
+				Value:   prometheus.DefaultRouteDepth,
+			},
 		),
 		plugin.WithHandler(metrics.Handler),
 		plugin.WithInit(func(ctx *cli.Context) error {
@@ -45,8 +71,19 @@ func NewPlugin() plugin.Plugin {
 
 			switch provider {
 			case "prometheus":
-				metrics.Provider = prometheus.New()
-				log.Info("Loaded prometheus metrics at /metrics")
+				path := ctx.String("metrics_path")
+
+				opts := []prometheus.Option{
+					prometheus.Path(path),
+					prometheus.Route(prometheus.RouteDepth(ctx.Int("metrics_route_depth"))),
+				}
+				if user := ctx.String("metrics_auth_user"); len(user) > 0 {
+					opts = append(opts, prometheus.BasicAuth(user, ctx.String("metrics_auth_pass")))
+				}
+
+				metrics.Path = path
+				metrics.Provider = prometheus.New(opts...)
+				log.Info("Loaded prometheus metrics at " + path)
 			}
 
 			return nil
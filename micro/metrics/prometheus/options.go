@@ -0,0 +1,94 @@
+package prometheus
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// DefaultPath the metrics are served on
+	DefaultPath = "/metrics"
+	// DefaultNamespace of the metrics
+	DefaultNamespace = "micro"
+	// DefaultRouteDepth is the number of path segments used as the route e.g /greeter/say
+	DefaultRouteDepth = 2
+	// DefaultBuckets of the latency histogram in seconds
+	DefaultBuckets = prometheus.DefBuckets
+)
+
+type Options struct {
+	// Path the metrics are served on
+	Path string
+	// Namespace of the metrics
+	Namespace string
+	// Route returns the route label of a request, it should have a low cardinality
+	Route func(r *http.Request) string
+	// Buckets of the latency histogram
+	Buckets []float64
+	// Username and Password enable basic auth for the metrics path
+	Username string
+	Password string
+	// Registerer the metrics are registered with
+	Registerer prometheus.Registerer
+	// Gatherer the metrics are served from
+	Gatherer prometheus.Gatherer
+}
+
+type Option func(o *Options)
+
+// Path sets the path the metrics are served on
+func Path(p string) Option {
+	return func(o *Options) {
+		o.Path = p
+	}
+}
+
+// Namespace sets the namespace of the metrics
+func Namespace(n string) Option {
+	return func(o *Options) {
+		o.Namespace = n
+	}
+}
+
+// Route sets the func returning the route label of a request
+func Route(fn func(r *http.Request) string) Option {
+	return func(o *Options) {
+		o.Route = fn
+	}
+}
+
+// Buckets sets the latency histogram buckets in seconds
+func Buckets(b ...float64) Option {
+	return func(o *Options) {
+		o.Buckets = b
+	}
+}
+
+// BasicAuth protects the metrics path with a username and password
+func BasicAuth(username, password string) Option {
+	return func(o *Options) {
+		o.Username = username
+		o.Password = password
+	}
+}
+
+// Registry sets the registry the metrics are registered with and served from
+func Registry(r *prometheus.Registry) Option {
+	return func(o *Options) {
+		o.Registerer = r
+		o.Gatherer = r
+	}
+}
+
+// RouteDepth returns a route func using the first n segments of the path
+func RouteDepth(n int) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		parts := strings.SplitN(strings.Trim(r.URL.Path, "/"), "/", n+1)
+		if len(parts) > n {
+			parts = parts[:n]
+		}
+		return "/" + strings.Join(parts, "/")
+	}
+}
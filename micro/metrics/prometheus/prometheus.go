@@ -2,27 +2,153 @@
 package prometheus
 
 import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	p "github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-type Metrics struct{}
+type Metrics struct {
+	opts Options
 
-func (m *Metrics) Handler(h http.Handler) http.Handler {
-	ph := p.Handler()
+	handler  http.Handler
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+}
+
+// statusWriter records the status code of the response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("prometheus: response does not implement http.Hijacker")
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return hj.Hijack()
+}
+
+// class returns the status class of the code e.g 2xx
+func class(code int) string {
+	if code == 0 {
+		code = http.StatusOK
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
 
+// register registers the collector, reusing an identical one if already registered
+func register(r prometheus.Registerer, c prometheus.Collector) prometheus.Collector {
+	if err := r.Register(c); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector
+		}
+		panic(err)
+	}
+	return c
+}
+
+func (m *Metrics) authorized(r *http.Request) bool {
+	if len(m.opts.Username) == 0 {
+		return true
+	}
+	u, pass, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	uok := subtle.ConstantTimeCompare([]byte(u), []byte(m.opts.Username)) == 1
+	pok := subtle.ConstantTimeCompare([]byte(pass), []byte(m.opts.Password)) == 1
+	return uok && pok
+}
+
+func (m *Metrics) Handler(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// serve prometheus handler at /metrics
-		if r.URL.Path == "/metrics" {
-			ph.ServeHTTP(w, r)
+		if r.URL.Path == m.opts.Path {
+			if !m.authorized(r) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			m.handler.ServeHTTP(w, r)
 			return
 		}
+
 		// otherwise serve everything
-		h.ServeHTTP(w, r)
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+
+		h.ServeHTTP(sw, r)
+
+		route := m.opts.Route(r)
+		m.requests.WithLabelValues(r.Method, route, class(sw.status)).Inc()
+		m.latency.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
 	})
 }
 
-func New() *Metrics {
-	return new(Metrics)
+func New(opts ...Option) *Metrics {
+	options := Options{
+		Path:       DefaultPath,
+		Namespace:  DefaultNamespace,
+		Route:      RouteDepth(DefaultRouteDepth),
+		Buckets:    DefaultBuckets,
+		Registerer: prometheus.DefaultRegisterer,
+		Gatherer:   prometheus.DefaultGatherer,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: options.Namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Requests handled by route, method and status class",
+	}, []string{"method", "route", "status"})
+
+	latency := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: options.Namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Request latencies by route and method",
+		Buckets:   options.Buckets,
+	}, []string{"method", "route"})
+
+	return &Metrics{
+		opts:     options,
+		handler:  p.InstrumentMetricHandler(options.Registerer, p.HandlerFor(options.Gatherer, p.HandlerOpts{})),
+		requests: register(options.Registerer, requests).(*prometheus.CounterVec),
+		latency:  register(options.Registerer, latency).(*prometheus.HistogramVec),
+	}
 }
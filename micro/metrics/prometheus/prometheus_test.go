@@ -0,0 +1,72 @@
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRouteDepth(t *testing.T) {
+	testData := map[string]string{
+		"/":                  "/",
+		"/greeter":           "/greeter",
+		"/greeter/say":       "/greeter/say",
+		"/greeter/say/hello": "/greeter/say",
+	}
+
+	route := RouteDepth(2)
+
+	for path, expected := range testData {
+		if r := route(httptest.NewRequest("GET", path, nil)); r != expected {
+			t.Errorf("Expected route %s for %s got %s", expected, path, r)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := New(
+		Registry(prometheus.NewRegistry()),
+		BasicAuth("user", "pass"),
+	)
+
+	h := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/greeter/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+
+	for _, path := range []string{"/greeter/say/hello", "/greeter/say/hello", "/greeter/missing"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without auth got %d", w.Code)
+	}
+
+	r := httptest.NewRequest("GET", "/metrics", nil)
+	r.SetBasicAuth("user", "pass")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 got %d", w.Code)
+	}
+
+	body := w.Body.String()
+
+	for _, expected := range []string{
+		`micro_http_requests_total{method="GET",route="/greeter/say",status="2xx"} 2`,
+		`micro_http_requests_total{method="GET",route="/greeter/missing",status="4xx"} 1`,
+		`micro_http_request_duration_seconds_count{method="GET",route="/greeter/say"} 2`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected metrics to contain %s", expected)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package pprof is a micro plugin for serving pprof profiles and expvar runtime stats
+package pprof
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strings"
+
+	"github.com/micro/cli/v2"
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/micro/v2/plugin"
+)
+
+var (
+	// DefaultRealm of the basic auth
+	DefaultRealm = "pprof"
+)
+
+type profiler struct {
+	username string
+	password string
+	handler  http.Handler
+	// serving on the toolkit's own address
+	inline bool
+}
+
+func init() {
+	// expvar already publishes cmdline and memstats
+	if expvar.Get("goroutines") == nil {
+		expvar.Publish("goroutines", expvar.Func(func() interface{} {
+			return runtime.NumGoroutine()
+		}))
+	}
+}
+
+func newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	return mux
+}
+
+// debug returns true if the path is served by the profiler
+func debug(path string) bool {
+	return strings.HasPrefix(path, "/debug/pprof/") || path == "/debug/vars"
+}
+
+func (p *profiler) authorized(r *http.Request) bool {
+	if len(p.username) == 0 {
+		return true
+	}
+	u, pass, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+	uok := subtle.ConstantTimeCompare([]byte(u), []byte(p.username)) == 1
+	pok := subtle.ConstantTimeCompare([]byte(pass), []byte(p.password)) == 1
+	return uok && pok
+}
+
+func (p *profiler) serve(w http.ResponseWriter, r *http.Request) {
+	if !p.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="`+DefaultRealm+`"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	p.handler.ServeHTTP(w, r)
+}
+
+func (p *profiler) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p.inline && debug(r.URL.Path) {
+			p.serve(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func (p *profiler) Init(ctx *cli.Context) error {
+	p.username = ctx.String("pprof_auth_user")
+	p.password = ctx.String("pprof_auth_pass")
+
+	addr := ctx.String("pprof_address")
+	if len(addr) == 0 {
+		p.inline = true
+		return nil
+	}
+
+	// serve on a separate port so profiles aren't exposed with the api
+	p.inline = false
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := http.Serve(l, http.HandlerFunc(p.serve)); err != nil {
+			log.Errorf("[pprof] server error: %v", err)
+		}
+	}()
+
+	log.Infof("[pprof] serving profiles on %s", l.Addr())
+
+	return nil
+}
+
+// NewPlugin returns a plugin which serves /debug/pprof and /debug/vars
+func NewPlugin() plugin.Plugin {
+	p := &profiler{
+		handler: newMux(),
+		inline:  true,
+	}
+
+	return plugin.NewPlugin(
+		plugin.WithName("pprof"),
+		plugin.WithFlag(
+			&cli.StringFlag{
+				Name:    "pprof_address",
+				Usage:   "Address to serve profiles on e.g :6060, defaults to the address of the api",
+				EnvVars: []string{"PPROF_ADDRESS"},
+			},
+			&cli.StringFlag{
+				Name:    "pprof_auth_user",
+				Usage:   "Username used for basic auth of the profiles",
+				EnvVars: []string{"PPROF_AUTH_USER"},
+			},
+			&cli.StringFlag{
+				Name:    "pprof_auth_pass",
+				Usage:   "Password used for basic auth of the profiles",
+				EnvVars: []string{"PPROF_AUTH_PASS"},
+			},
+		),
+		plugin.WithHandler(p.Handler),
+		plugin.WithInit(p.Init),
+	)
+}
@@ -0,0 +1,54 @@
+package pprof
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	p := &profiler{
+		handler:  newMux(),
+		inline:   true,
+		username: "user",
+		password: "pass",
+	}
+
+	h := p.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	testData := []struct {
+		path string
+		auth bool
+		code int
+	}{
+		{"/greeter/say", false, http.StatusTeapot},
+		{"/debug/vars", false, http.StatusUnauthorized},
+		{"/debug/vars", true, http.StatusOK},
+		{"/debug/pprof/", true, http.StatusOK},
+		{"/debug/pprof/goroutine", true, http.StatusOK},
+	}
+
+	for _, d := range testData {
+		r := httptest.NewRequest("GET", d.path, nil)
+		if d.auth {
+			r.SetBasicAuth("user", "pass")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != d.code {
+			t.Errorf("%s: expected %d got %d", d.path, d.code, w.Code)
+		}
+	}
+
+	// profiles aren't served inline when on a separate address
+	p.inline = false
+	r := httptest.NewRequest("GET", "/debug/vars", nil)
+	r.SetBasicAuth("user", "pass")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusTeapot {
+		t.Errorf("Expected profiles to pass through, got %d", w.Code)
+	}
+}
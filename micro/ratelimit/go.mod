@@ -0,0 +1,10 @@
+module github.com/micro/go-plugins/micro/ratelimit/v2
+
+go 1.13
+
+require (
+	github.com/go-redis/redis/v7 v7.4.0
+	github.com/micro/cli/v2 v2.1.2
+	github.com/micro/go-micro/v2 v2.9.1
+	github.com/micro/micro/v2 v2.9.1
+)
@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Result of taking a token from a bucket
+type Result struct {
+	// Allowed is true if a token was taken
+	Allowed bool
+	// Limit is the size of the bucket
+	Limit int
+	// Remaining tokens in the bucket
+	Remaining int
+	// Reset is the time until the bucket is full
+	Reset time.Duration
+	// RetryAfter is the time until a token is available when not allowed
+	RetryAfter time.Duration
+}
+
+// Limiter is a token bucket per key
+type Limiter interface {
+	// Take a token from the key's bucket
+	Take(key string) (Result, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type memoryLimiter struct {
+	rate  float64
+	burst int
+
+	sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// take refills the bucket at the rate and takes a token if one is available
+func take(tokens float64, last, now time.Time, rate float64, burst int) (float64, Result) {
+	tokens = math.Min(float64(burst), tokens+now.Sub(last).Seconds()*rate)
+
+	res := Result{Limit: burst}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+
+	res.Remaining = int(tokens)
+	res.Reset = seconds((float64(burst) - tokens) / rate)
+
+	return tokens, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func (m *memoryLimiter) Take(key string) (Result, error) {
+	now := time.Now()
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(m.burst), last: now}
+		m.buckets[key] = b
+	}
+
+	tokens, res := take(b.tokens, b.last, now, m.rate, m.burst)
+	b.tokens = tokens
+	b.last = now
+
+	return res, nil
+}
+
+// sweep deletes full buckets since they're the same as new ones
+func (m *memoryLimiter) sweep(now time.Time) {
+	full := seconds(float64(m.burst) / m.rate)
+	if now.Sub(m.swept) < full {
+		return
+	}
+	m.swept = now
+
+	for k, b := range m.buckets {
+		if now.Sub(b.last) >= full {
+			delete(m.buckets, k)
+		}
+	}
+}
+
+// NewLimiter returns an in memory limiter refilling rate tokens a second up to burst
+func NewLimiter(rate float64, burst int) Limiter {
+	return &memoryLimiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+		swept:   time.Now(),
+	}
+}
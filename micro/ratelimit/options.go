@@ -0,0 +1,51 @@
+package ratelimit
+
+type Options struct {
+	// Rate of tokens added to each bucket a second
+	Rate float64
+	// Burst is the size of each bucket
+	Burst int
+	// KeyHeader identifies clients e.g X-Api-Key, which are limited by key as well as ip
+	KeyHeader string
+	// TrustedProxies whose X-Forwarded-For header is used for the client ip
+	TrustedProxies []string
+	// Limiter holding the buckets, defaults to in memory
+	Limiter Limiter
+}
+
+type Option func(o *Options)
+
+// Rate sets the tokens added a second
+func Rate(r float64) Option {
+	return func(o *Options) {
+		o.Rate = r
+	}
+}
+
+// Burst sets the size of each bucket
+func Burst(b int) Option {
+	return func(o *Options) {
+		o.Burst = b
+	}
+}
+
+// KeyHeader sets the header identifying clients
+func KeyHeader(h string) Option {
+	return func(o *Options) {
+		o.KeyHeader = h
+	}
+}
+
+// TrustedProxies sets the proxies whose X-Forwarded-For header is trusted
+func TrustedProxies(ips ...string) Option {
+	return func(o *Options) {
+		o.TrustedProxies = ips
+	}
+}
+
+// WithLimiter sets the limiter e.g NewRedisLimiter to share limits between gateways
+func WithLimiter(l Limiter) Option {
+	return func(o *Options) {
+		o.Limiter = l
+	}
+}
@@ -0,0 +1,294 @@
+// Package ratelimit is a micro plugin for rate limiting clients by ip and api key
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/micro/cli/v2"
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/micro/v2/plugin"
+)
+
+var (
+	// DefaultRate of requests a second
+	DefaultRate = 10.0
+	// DefaultBurst of requests
+	DefaultBurst = 20
+)
+
+type rateLimit struct {
+	opts    Options
+	trusted []*net.IPNet
+}
+
+func (rl *rateLimit) Flags() []cli.Flag {
+	return []cli.Flag{
+		&cli.Float64Flag{
+			Name:    "ratelimit_rate",
+			Usage:   "Requests a second allowed per client",
+			EnvVars: []string{"RATELIMIT_RATE"},
+		},
+		&cli.IntFlag{
+			Name:    "ratelimit_burst",
+			Usage:   "Requests a client can burst above the rate",
+			EnvVars: []string{"RATELIMIT_BURST"},
+		},
+		&cli.StringFlag{
+			Name:    "ratelimit_key_header",
+			Usage:   "Header identifying clients e.g X-Api-Key, clients are limited by it as well as by ip",
+			EnvVars: []string{"RATELIMIT_KEY_HEADER"},
+		},
+		&cli.StringFlag{
+			Name:    "ratelimit_trusted_proxies",
+			Usage:   "Comma separated list of proxies whose X-Forwarded-For header is trusted",
+			EnvVars: []string{"RATELIMIT_TRUSTED_PROXIES"},
+		},
+		&cli.StringFlag{
+			Name:    "ratelimit_redis_address",
+			Usage:   "Redis address to share limits between gateways",
+			EnvVars: []string{"RATELIMIT_REDIS_ADDRESS"},
+		},
+	}
+}
+
+func (rl *rateLimit) Commands() []*cli.Command {
+	return nil
+}
+
+// parse returns the ips and cidrs as networks, an ip is a network of one address
+func parse(ips ...string) ([]*net.IPNet, error) {
+	var n []*net.IPNet
+
+	for _, ip := range ips {
+		ip = strings.TrimSpace(ip)
+		if len(ip) == 0 {
+			continue
+		}
+
+		// assume just an ip
+		if !strings.Contains(ip, "/") {
+			nip := net.ParseIP(ip)
+			if nip == nil {
+				return nil, fmt.Errorf("failed to parse %v", ip)
+			}
+			bits := 8 * net.IPv6len
+			if v4 := nip.To4(); v4 != nil {
+				nip = v4
+				bits = 8 * net.IPv4len
+			}
+			n = append(n, &net.IPNet{IP: nip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		// parse cidr
+		_, ipnet, err := net.ParseCIDR(ip)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %v: %v", ip, err)
+		}
+		n = append(n, ipnet)
+	}
+
+	return n, nil
+}
+
+// trusts returns true if the ip is of a trusted proxy
+func (rl *rateLimit) trusts(ip net.IP) bool {
+	for _, cidr := range rl.trusted {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client, reading X-Forwarded-For from
+// right to left while the request came through a trusted proxy
+func (rl *rateLimit) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !rl.trusts(ip) {
+		return host
+	}
+
+	var hops []string
+	for _, v := range r.Header[http.CanonicalHeaderKey("X-Forwarded-For")] {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// a proxy we trust wrote garbage, stop at the last good hop
+			break
+		}
+		ip = hop
+		if !rl.trusts(hop) {
+			break
+		}
+	}
+
+	return ip.String()
+}
+
+// keys returns the buckets of the request. Every request takes from the
+// bucket of its ip so clients can't get fresh buckets by sending new keys.
+func (rl *rateLimit) keys(r *http.Request) []string {
+	keys := []string{"ip:" + rl.clientIP(r)}
+	if len(rl.opts.KeyHeader) > 0 {
+		if k := r.Header.Get(rl.opts.KeyHeader); len(k) > 0 {
+			keys = append(keys, "key:"+k)
+		}
+	}
+	return keys
+}
+
+// take takes a token from each bucket, stopping at the first which is empty.
+// The result is the most limiting of the buckets taken from.
+func (rl *rateLimit) take(keys []string) (Result, error) {
+	var res Result
+	for i, k := range keys {
+		r, err := rl.opts.Limiter.Take(k)
+		if err != nil {
+			return Result{}, err
+		}
+		if i == 0 || !r.Allowed || r.Remaining < res.Remaining {
+			res = r
+		}
+		if !r.Allowed {
+			break
+		}
+	}
+	return res, nil
+}
+
+// validate returns an error if the bucket can never be refilled or taken from
+func validate(rate float64, burst int) error {
+	if rate <= 0 {
+		return errors.New("rate must be greater than zero")
+	}
+	if burst < 1 {
+		return errors.New("burst must be at least one")
+	}
+	return nil
+}
+
+// ceil returns the duration in whole seconds rounded up
+func ceil(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+func (rl *rateLimit) Handler() plugin.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := rl.take(rl.keys(r))
+			if err != nil {
+				// don't take the gateway down with the limiter
+				log.Errorf("[ratelimit] failed to take token: %v", err)
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			hd := w.Header()
+			hd.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			hd.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			hd.Set("RateLimit-Reset", ceil(res.Reset))
+
+			if !res.Allowed {
+				hd.Set("Retry-After", ceil(res.RetryAfter))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (rl *rateLimit) Init(ctx *cli.Context) error {
+	changed := false
+
+	if ctx.IsSet("ratelimit_rate") {
+		rl.opts.Rate = ctx.Float64("ratelimit_rate")
+		changed = true
+	}
+	if ctx.IsSet("ratelimit_burst") {
+		rl.opts.Burst = ctx.Int("ratelimit_burst")
+		changed = true
+	}
+	if v := ctx.String("ratelimit_key_header"); len(v) > 0 {
+		rl.opts.KeyHeader = v
+	}
+	if v := ctx.String("ratelimit_trusted_proxies"); len(v) > 0 {
+		rl.opts.TrustedProxies = strings.Split(v, ",")
+	}
+
+	if err := validate(rl.opts.Rate, rl.opts.Burst); err != nil {
+		return fmt.Errorf("[ratelimit] %v", err)
+	}
+
+	trusted, err := parse(rl.opts.TrustedProxies...)
+	if err != nil {
+		return fmt.Errorf("[ratelimit] %v", err)
+	}
+	rl.trusted = trusted
+
+	if addr := ctx.String("ratelimit_redis_address"); len(addr) > 0 {
+		client := redis.NewUniversalClient(&redis.UniversalOptions{
+			Addrs: []string{addr},
+		})
+		if err := client.Ping().Err(); err != nil {
+			return err
+		}
+		rl.opts.Limiter = NewRedisLimiter(client, rl.opts.Rate, rl.opts.Burst)
+	} else if changed {
+		rl.opts.Limiter = NewLimiter(rl.opts.Rate, rl.opts.Burst)
+	}
+
+	return nil
+}
+
+func (rl *rateLimit) String() string {
+	return "ratelimit"
+}
+
+// NewPlugin returns a plugin which limits each client with a token bucket
+func NewPlugin(opts ...Option) plugin.Plugin {
+	options := Options{
+		Rate:  DefaultRate,
+		Burst: DefaultBurst,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if err := validate(options.Rate, options.Burst); err != nil {
+		log.Fatalf("[ratelimit] %v", err)
+	}
+
+	trusted, err := parse(options.TrustedProxies...)
+	if err != nil {
+		log.Fatalf("[ratelimit] %v", err)
+	}
+
+	if options.Limiter == nil {
+		options.Limiter = NewLimiter(options.Rate, options.Burst)
+	}
+
+	return &rateLimit{
+		opts:    options,
+		trusted: trusted,
+	}
+}
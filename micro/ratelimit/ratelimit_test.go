@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	now := time.Now()
+
+	// a full bucket of 2 refilling 1 a second
+	tokens, res := take(2, now, now, 1, 2)
+	if !res.Allowed || res.Remaining != 1 || tokens != 1 {
+		t.Fatalf("Unexpected result %+v with %v tokens", res, tokens)
+	}
+
+	tokens, res = take(tokens, now, now, 1, 2)
+	if !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("Unexpected result %+v with %v tokens", res, tokens)
+	}
+
+	tokens, res = take(tokens, now, now, 1, 2)
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 2*time.Second {
+		t.Fatalf("Unexpected result %+v with %v tokens", res, tokens)
+	}
+
+	// half a second later half a token has been added
+	_, res = take(tokens, now, now.Add(500*time.Millisecond), 1, 2)
+	if res.Allowed || res.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("Unexpected result %+v", res)
+	}
+
+	// the bucket never holds more than the burst
+	_, res = take(0, now, now.Add(time.Hour), 1, 2)
+	if !res.Allowed || res.Remaining != 1 {
+		t.Fatalf("Unexpected result %+v", res)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	h := NewPlugin(
+		Rate(0.001),
+		Burst(2),
+		KeyHeader("X-Api-Key"),
+	).Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(remote, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = remote
+		if len(key) > 0 {
+			r.Header.Set("X-Api-Key", key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := do("10.0.0.1:1234", ""); w.Code != 200 {
+			t.Fatalf("Expected 200 got %d", w.Code)
+		}
+	}
+
+	w := do("10.0.0.1:4321", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 got %d", w.Code)
+	}
+	if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != "0" || len(w.Header().Get("Retry-After")) == 0 {
+		t.Fatalf("Unexpected headers %v", w.Header())
+	}
+
+	// new api keys don't get around the limit of the ip
+	if w := do("10.0.0.1:1234", "abc"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 got %d", w.Code)
+	}
+
+	// other ips have their own buckets
+	if w := do("10.0.0.2:1234", ""); w.Code != 200 {
+		t.Fatalf("Expected 200 got %d", w.Code)
+	}
+
+	// api keys are limited across ips
+	for i, remote := range []string{"10.0.0.3:1234", "10.0.0.4:1234"} {
+		if w := do(remote, "def"); w.Code != 200 || w.Header().Get("RateLimit-Remaining") != strconv.Itoa(1-i) {
+			t.Fatalf("Expected 200 got %d with %v", w.Code, w.Header())
+		}
+	}
+	if w := do("10.0.0.5:1234", "def"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 got %d", w.Code)
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	rl := NewPlugin(TrustedProxies("172.16.0.0/12")).(*rateLimit)
+
+	testData := []struct {
+		remote  string
+		forward string
+		ip      string
+	}{
+		{"10.0.0.1:1234", "", "10.0.0.1"},
+		// untrusted proxies can't spoof the client
+		{"10.0.0.1:1234", "1.2.3.4", "10.0.0.1"},
+		{"172.16.0.1:1234", "1.2.3.4", "1.2.3.4"},
+		{"172.16.0.1:1234", "1.2.3.4, 172.16.0.2", "1.2.3.4"},
+		{"172.16.0.1:1234", "5.6.7.8, 1.2.3.4", "1.2.3.4"},
+		{"172.16.0.1:1234", "garbage", "172.16.0.1"},
+	}
+
+	for _, d := range testData {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = d.remote
+		if len(d.forward) > 0 {
+			r.Header.Set("X-Forwarded-For", d.forward)
+		}
+		if ip := rl.clientIP(r); ip != d.ip {
+			t.Fatalf("Expected ip %s for %s forwarded for %q got %s", d.ip, d.remote, d.forward, ip)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testData := []struct {
+		rate  float64
+		burst int
+		valid bool
+	}{
+		{1, 1, true},
+		{0.001, 20, true},
+		{0, 20, false},
+		{-1, 20, false},
+		{1, 0, false},
+	}
+
+	for _, d := range testData {
+		if err := validate(d.rate, d.burst); (err == nil) != d.valid {
+			t.Fatalf("Expected rate %v burst %d valid %v got %v", d.rate, d.burst, d.valid, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/go-redis/redis/v7"
+)
+
+// script takes a token from the bucket stored as a hash of tokens and the last
+// refill in microseconds, the time is redis' so replicas needn't agree on it
+var script = redis.NewScript(`
+-- replicate the writes rather than the script which reads the time
+redis.replicate_commands()
+
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = redis.call("TIME")
+local ts = tonumber(now[1]) * 1000000 + tonumber(now[2])
+
+local b = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(b[1]) or burst
+local last = tonumber(b[2]) or ts
+
+tokens = math.min(burst, tokens + (ts - last) / 1000000 * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", ts)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate * 1000))
+
+return {allowed, tostring(tokens)}
+`)
+
+type redisLimiter struct {
+	client redis.UniversalClient
+	prefix string
+	rate   float64
+	burst  int
+}
+
+func (r *redisLimiter) Take(key string) (Result, error) {
+	v, err := script.Run(r.client, []string{r.prefix + key}, r.rate, r.burst).Result()
+	if err != nil {
+		return Result{}, err
+	}
+
+	vals, _ := v.([]interface{})
+	if len(vals) != 2 {
+		return Result{}, redis.Nil
+	}
+
+	allowed, _ := vals[0].(int64)
+	s, _ := vals[1].(string)
+	tokens, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Result{}, err
+	}
+
+	res := Result{
+		Allowed:   allowed == 1,
+		Limit:     r.burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(r.burst) - tokens) / r.rate),
+	}
+	if !res.Allowed {
+		res.RetryAfter = seconds((1 - tokens) / r.rate)
+	}
+
+	return res, nil
+}
+
+// NewRedisLimiter returns a limiter sharing buckets between gateways through redis
+func NewRedisLimiter(client redis.UniversalClient, rate float64, burst int) Limiter {
+	return &redisLimiter{
+		client: client,
+		prefix: "micro:ratelimit:",
+		rate:   rate,
+		burst:  burst,
+	}
+}
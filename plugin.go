@@ -17,6 +17,7 @@ import (
 	"github.com/micro/go-micro/v2/config/cmd"
 	"github.com/micro/go-micro/v2/registry"
 	"github.com/micro/go-micro/v2/server"
+	"github.com/micro/go-micro/v2/store"
 	"github.com/micro/go-micro/v2/transport"
 	mp "github.com/micro/micro/v2/plugin"
 )
@@ -60,7 +61,6 @@ func Init(p *Plugin) error {
 			return fmt.Errorf("Invalid plugin %s", p.Name)
 		}
 		cmd.DefaultRegistries[p.Name] = pg
-
 	case "selector":
 		pg, ok := p.NewFunc.(func(...selector.Option) selector.Selector)
 		if !ok {
@@ -79,9 +79,17 @@ func Init(p *Plugin) error {
 			return fmt.Errorf("Invalid plugin %s", p.Name)
 		}
 		cmd.DefaultTransports[p.Name] = pg
+	case "store":
+		pg, ok := p.NewFunc.(func(...store.Option) store.Store)
+		if !ok {
+			return fmt.Errorf("Invalid plugin %s", p.Name)
+		}
+		cmd.DefaultStores[p.Name] = pg
+	default:
+		return fmt.Errorf("Unknown plugin type: %s for %s", p.Type, p.Name)
 	}
 
-	return fmt.Errorf("Unknown plugin type: %s for %s", p.Type, p.Name)
+	return nil
 }
 
 // Load loads a plugin created with `go build -buildmode=plugin`
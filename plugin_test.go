@@ -0,0 +1,35 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/micro/go-micro/v2/config/cmd"
+	"github.com/micro/go-micro/v2/store"
+)
+
+func TestInit(t *testing.T) {
+	newStore := func(...store.Option) store.Store { return nil }
+
+	testCases := []struct {
+		name   string
+		plugin *Plugin
+		err    bool
+	}{
+		{"store", &Plugin{Name: "test", Type: "store", NewFunc: newStore}, false},
+		{"invalid store", &Plugin{Name: "invalid", Type: "store", NewFunc: func() {}}, true},
+		{"unknown type", &Plugin{Name: "test", Type: "unknown", NewFunc: newStore}, true},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := Init(c.plugin); (err != nil) != c.err {
+				t.Fatalf("expected error %t got %v", c.err, err)
+			}
+		})
+	}
+
+	if _, ok := cmd.DefaultStores["test"]; !ok {
+		t.Fatal("expected the store to be registered")
+	}
+	delete(cmd.DefaultStores, "test")
+}
@@ -0,0 +1,297 @@
+// Package grpc is a grpc proxy which routes requests by their metadata.
+//
+// Rules match the service, endpoint and header values of a request, such as
+// a tenant or version header, and send it to a different service, version or
+// address. They can be loaded from a config source and are reloaded when it
+// changes, enabling canary releases and tenant isolation at the proxy.
+package grpc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/client/grpc"
+	"github.com/micro/go-micro/v2/client/selector"
+	"github.com/micro/go-micro/v2/codec"
+	"github.com/micro/go-micro/v2/config"
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/proxy"
+	"github.com/micro/go-micro/v2/server"
+)
+
+var (
+	// DefaultPath of the rules in the config source
+	DefaultPath = []string{"proxy", "rules"}
+)
+
+// Proxy transparently proxies grpc requests, routing them with its rules.
+// Requests matching no rule are sent to the fixed endpoint if one is set,
+// otherwise to the service called. It is a server.Router.
+type Proxy struct {
+	options Options
+
+	sync.RWMutex
+	rules []Rule
+
+	config  config.Config
+	watcher config.Watcher
+	exit    chan bool
+}
+
+// read client request and write to server
+func readLoop(r server.Request, s client.Stream) error {
+	// request to backend server
+	req := s.Request()
+
+	for {
+		// get data from client
+		//  no need to decode it
+		body, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// get the header from client
+		hdr := r.Header()
+		msg := &codec.Message{
+			Type:   codec.Request,
+			Header: hdr,
+			Body:   body,
+		}
+		// write the raw request
+		err = req.Codec().Write(msg, nil)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// header returns the request metadata with normalised keys
+func header(ctx context.Context, req server.Request) map[string]string {
+	hdr := make(map[string]string)
+	for k, v := range req.Header() {
+		hdr[normalise(k)] = v
+	}
+	if md, ok := metadata.FromContext(ctx); ok {
+		for k, v := range md {
+			hdr[normalise(k)] = v
+		}
+	}
+	return hdr
+}
+
+// Rules returns the current routing rules
+func (p *Proxy) Rules() []Rule {
+	p.RLock()
+	defer p.RUnlock()
+	return p.rules
+}
+
+// Update replaces the routing rules
+func (p *Proxy) Update(rules []Rule) {
+	p.Lock()
+	p.rules = rules
+	p.Unlock()
+}
+
+// call returns the service called and the call options of a request
+func (p *Proxy) call(ctx context.Context, req server.Request) (string, []client.CallOption) {
+	service := req.Service()
+	var opts []client.CallOption
+
+	if r, ok := route(p.Rules(), service, req.Endpoint(), header(ctx, req)); ok {
+		if len(r.Service) > 0 {
+			service = r.Service
+		}
+		if len(r.Version) > 0 {
+			opts = append(opts, client.WithSelectOption(selector.WithFilter(selector.FilterVersion(r.Version))))
+		}
+		if len(r.Address) > 0 {
+			opts = append(opts, client.WithAddress(r.Address))
+		}
+		return service, opts
+	}
+
+	// call a specific backend
+	if len(p.options.Endpoint) > 0 {
+		// address:port
+		if parts := strings.Split(p.options.Endpoint, ":"); len(parts) > 1 {
+			opts = append(opts, client.WithAddress(p.options.Endpoint))
+			// use as service name
+		} else {
+			service = p.options.Endpoint
+		}
+	}
+
+	return service, opts
+}
+
+// ProcessMessage publishes messages with the client
+func (p *Proxy) ProcessMessage(ctx context.Context, msg server.Message) error {
+	return p.options.Client.Publish(ctx, msg)
+}
+
+// ServeRequest honours the server.Router interface
+func (p *Proxy) ServeRequest(ctx context.Context, req server.Request, rsp server.Response) error {
+	service, opts := p.call(ctx, req)
+
+	// create new request with raw bytes body
+	creq := p.options.Client.NewRequest(service, req.Endpoint(), nil, client.WithContentType(req.ContentType()))
+
+	// create new stream
+	stream, err := p.options.Client.Stream(ctx, creq, opts...)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	// create client request read loop
+	go readLoop(req, stream)
+
+	// get raw response
+	resp := stream.Response()
+
+	// create server response write loop
+	for {
+		// read backend response body
+		body, err := resp.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// write raw response header to client
+		rsp.WriteHeader(resp.Header())
+
+		// write raw response body to client
+		err = rsp.Write(body)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// load reads the rules from the source and watches them for changes
+func (p *Proxy) load() error {
+	c, err := config.NewConfig()
+	if err != nil {
+		return err
+	}
+	if err := c.Load(p.options.Source); err != nil {
+		return err
+	}
+
+	var rules []Rule
+	if err := c.Get(p.options.Path...).Scan(&rules); err != nil {
+		return err
+	}
+
+	w, err := c.Watch(p.options.Path...)
+	if err != nil {
+		return err
+	}
+
+	p.Update(rules)
+	p.config = c
+	p.watcher = w
+	p.exit = make(chan bool)
+
+	go p.watch(w)
+
+	return nil
+}
+
+// watch reloads the rules when they change until the watcher is stopped
+func (p *Proxy) watch(w config.Watcher) {
+	for {
+		v, err := w.Next()
+		if err != nil {
+			select {
+			case <-p.exit:
+				return
+			default:
+			}
+			if log.V(log.ErrorLevel, log.DefaultLogger) {
+				log.Errorf("Error watching proxy rules: %v", err)
+			}
+			time.Sleep(time.Second)
+			continue
+		}
+
+		var rules []Rule
+		if err := v.Scan(&rules); err != nil {
+			if log.V(log.ErrorLevel, log.DefaultLogger) {
+				log.Errorf("Error reloading proxy rules: %v", err)
+			}
+			continue
+		}
+
+		p.Update(rules)
+
+		if log.V(log.InfoLevel, log.DefaultLogger) {
+			log.Infof("Reloaded %d proxy rules", len(rules))
+		}
+	}
+}
+
+// Close stops watching the config source
+func (p *Proxy) Close() error {
+	if p.watcher == nil {
+		return nil
+	}
+	close(p.exit)
+	p.watcher.Stop()
+	return p.config.Close()
+}
+
+func (p *Proxy) String() string {
+	return "grpc"
+}
+
+// NewProxy returns a new grpc proxy which routes requests with its rules.
+// If a source is set the rules are loaded from it before returning.
+func NewProxy(opts ...Option) (*Proxy, error) {
+	options := Options{
+		Path: DefaultPath,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Client == nil {
+		options.Client = grpc.NewClient()
+	}
+
+	p := &Proxy{
+		options: options,
+		rules:   options.Rules,
+	}
+
+	if options.Source != nil {
+		if err := p.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// NewSingleHostProxy returns a proxy which sends requests matching no rule to a single backend
+func NewSingleHostProxy(url string, opts ...Option) (*Proxy, error) {
+	return NewProxy(append([]Option{WithEndpoint(url)}, opts...)...)
+}
+
+var _ proxy.Proxy = (*Proxy)(nil)
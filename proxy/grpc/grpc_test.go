@@ -0,0 +1,169 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/config/source"
+	"github.com/micro/go-micro/v2/config/source/memory"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+)
+
+type testRequest struct {
+	server.Request
+	service  string
+	endpoint string
+	header   map[string]string
+}
+
+func (r *testRequest) Service() string {
+	return r.service
+}
+
+func (r *testRequest) Endpoint() string {
+	return r.endpoint
+}
+
+func (r *testRequest) Header() map[string]string {
+	return r.header
+}
+
+// callOptions returns the service and call options of a request
+func callOptions(p *Proxy, ctx context.Context, req server.Request) (string, client.CallOptions) {
+	service, opts := p.call(ctx, req)
+	var options client.CallOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	return service, options
+}
+
+func TestRoute(t *testing.T) {
+	rules := []Rule{
+		{Service: "greeter", Header: map[string]string{"X-Tenant": "acme"}, Route: Route{Service: "greeter-acme"}},
+		{Service: "greeter", Endpoint: "Greeter.Hello", Header: map[string]string{"x-canary": "*"}, Route: Route{Version: "v2"}},
+		{Service: "*", Header: map[string]string{"X-Debug": "true"}, Route: Route{Address: "10.0.0.1:9090"}},
+		{Service: "payments", Weight: 100, Route: Route{Version: "v1"}},
+	}
+
+	testCases := []struct {
+		service  string
+		endpoint string
+		header   map[string]string
+		route    Route
+		ok       bool
+	}{
+		{"greeter", "Greeter.Hello", map[string]string{"X-Tenant": "acme"}, Route{Service: "greeter-acme"}, true},
+		{"greeter", "Greeter.Hello", map[string]string{"X-Tenant": "other"}, Route{}, false},
+		{"greeter", "Greeter.Hello", map[string]string{"X-Canary": "1"}, Route{Version: "v2"}, true},
+		{"greeter", "Greeter.Bye", map[string]string{"X-Canary": "1"}, Route{}, false},
+		{"users", "Users.Read", map[string]string{"X-Debug": "true"}, Route{Address: "10.0.0.1:9090"}, true},
+		{"payments", "Payments.Charge", nil, Route{Version: "v1"}, true},
+		{"users", "Users.Read", nil, Route{}, false},
+	}
+
+	for _, tc := range testCases {
+		hdr := map[string]string{}
+		for k, v := range tc.header {
+			hdr[normalise(k)] = v
+		}
+		r, ok := route(rules, tc.service, tc.endpoint, hdr)
+		if ok != tc.ok || r != tc.route {
+			t.Fatalf("%s %s %v: expected %v %v got %v %v", tc.service, tc.endpoint, tc.header, tc.route, tc.ok, r, ok)
+		}
+	}
+}
+
+func TestWeight(t *testing.T) {
+	rule := Rule{Weight: 1}
+	var matched int
+	for i := 0; i < 1000; i++ {
+		if rule.Match("greeter", "Greeter.Hello", nil) {
+			matched++
+		}
+	}
+	if matched == 0 || matched > 100 {
+		t.Fatalf("expected about 1%% of requests to match, matched %d of 1000", matched)
+	}
+}
+
+func TestCall(t *testing.T) {
+	p, err := NewSingleHostProxy("localhost:9090", WithRules(
+		Rule{Header: map[string]string{"X-Tenant": "acme"}, Route: Route{Service: "greeter-acme", Version: "v2"}},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tenant header set in the metadata by the grpc server
+	ctx := metadata.NewContext(context.Background(), metadata.Metadata{"x-tenant": "acme"})
+	service, options := callOptions(p, ctx, &testRequest{service: "greeter", endpoint: "Greeter.Hello"})
+	if service != "greeter-acme" {
+		t.Fatalf("expected greeter-acme got %s", service)
+	}
+	if len(options.Address) != 0 || len(options.SelectOptions) != 1 {
+		t.Fatalf("expected a version filter got %+v", options)
+	}
+
+	// no match falls back to the endpoint
+	service, options = callOptions(p, context.Background(), &testRequest{service: "greeter", endpoint: "Greeter.Hello"})
+	if service != "greeter" {
+		t.Fatalf("expected greeter got %s", service)
+	}
+	if len(options.Address) != 1 || options.Address[0] != "localhost:9090" {
+		t.Fatalf("expected the fixed address got %v", options.Address)
+	}
+}
+
+func TestReload(t *testing.T) {
+	src := memory.NewSource(memory.WithJSON([]byte(`{"proxy": {"rules": [
+		{"header": {"x-tenant": "acme"}, "route": {"service": "greeter-acme"}}
+	]}}`)))
+
+	p, err := NewProxy(WithSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if rules := p.Rules(); len(rules) != 1 || rules[0].Route.Service != "greeter-acme" {
+		t.Fatalf("expected the loaded rule got %+v", rules)
+	}
+
+	update := &source.ChangeSet{
+		Format: "json",
+		Data: []byte(`{"proxy": {"rules": [
+			{"header": {"x-tenant": "acme"}, "route": {"version": "v2"}},
+			{"service": "payments", "route": {"address": "10.0.0.1:9090"}}
+		]}}`),
+	}
+
+	// the source is watched in the background so retry until the update is seen
+	deadline := time.Now().Add(5 * time.Second)
+	for len(p.Rules()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("rules not reloaded, got %+v", p.Rules())
+		}
+		src.Write(update)
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	if r := p.Rules()[0].Route; r.Version != "v2" || len(r.Service) > 0 {
+		t.Fatalf("expected the updated rule got %+v", r)
+	}
+}
+
+func TestEmptySource(t *testing.T) {
+	p, err := NewProxy(WithSource(memory.NewSource(memory.WithJSON([]byte(`{}`)))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if len(p.Rules()) != 0 {
+		t.Fatalf("expected no rules got %+v", p.Rules())
+	}
+}
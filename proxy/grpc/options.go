@@ -0,0 +1,56 @@
+package grpc
+
+import (
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/config/source"
+)
+
+// Options of the routing proxy
+type Options struct {
+	// Endpoint is a fixed service name or address:port to call
+	Endpoint string
+	// Client used for outbound requests
+	Client client.Client
+	// Rules applied in order, the first match routes the request
+	Rules []Rule
+	// Source the rules are loaded and watched from
+	Source source.Source
+	// Path of the rules in the source
+	Path []string
+}
+
+// Option sets an option of the routing proxy
+type Option func(o *Options)
+
+// WithEndpoint sets a fixed service name or address:port to call
+func WithEndpoint(e string) Option {
+	return func(o *Options) {
+		o.Endpoint = e
+	}
+}
+
+// WithClient sets the client used for outbound requests
+func WithClient(c client.Client) Option {
+	return func(o *Options) {
+		o.Client = c
+	}
+}
+
+// WithRules sets static routing rules. They are replaced by the rules in the
+// source if one is set.
+func WithRules(r ...Rule) Option {
+	return func(o *Options) {
+		o.Rules = r
+	}
+}
+
+// WithSource loads the routing rules from the config source and reloads them
+// when the value at path changes. The default path is proxy.rules.
+func WithSource(s source.Source, path ...string) Option {
+	return func(o *Options) {
+		o.Source = s
+		if len(path) > 0 {
+			o.Path = path
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package grpc
+
+import (
+	"math/rand"
+	"strings"
+)
+
+// Rule routes requests matching the service, endpoint and header values
+type Rule struct {
+	// Service to match, empty or * matches any service
+	Service string `json:"service"`
+	// Endpoint to match e.g Greeter.Hello, empty or * matches any endpoint
+	Endpoint string `json:"endpoint"`
+	// Header values which must all match. Keys are case insensitive
+	// and a * value matches any request with the header set.
+	Header map[string]string `json:"header"`
+	// Weight is the percentage of matching requests routed, zero routes all of them
+	Weight int `json:"weight"`
+	// Route sets where matching requests are sent
+	Route Route `json:"route"`
+}
+
+// Route is the backend a request is sent to
+type Route struct {
+	// Service replaces the service name called e.g greeter-acme
+	Service string `json:"service"`
+	// Version only calls nodes of the service version e.g v2
+	Version string `json:"version"`
+	// Address calls a fixed address:port
+	Address string `json:"address"`
+}
+
+// normalise header keys to the form used by metadata.FromContext
+func normalise(k string) string {
+	return strings.Title(strings.ToLower(k))
+}
+
+func matchValue(pattern, v string) bool {
+	return len(pattern) == 0 || pattern == "*" || pattern == v
+}
+
+// Match returns true if the rule matches the request. The header keys must be normalised.
+func (r *Rule) Match(service, endpoint string, header map[string]string) bool {
+	if !matchValue(r.Service, service) || !matchValue(r.Endpoint, endpoint) {
+		return false
+	}
+
+	for k, v := range r.Header {
+		val, ok := header[normalise(k)]
+		if !ok || (v != "*" && v != val) {
+			return false
+		}
+	}
+
+	if r.Weight > 0 && r.Weight < 100 {
+		return rand.Intn(100) < r.Weight
+	}
+
+	return true
+}
+
+// route returns the route of the first matching rule
+func route(rules []Rule, service, endpoint string, header map[string]string) (Route, bool) {
+	for i := range rules {
+		if rules[i].Match(service, endpoint, header) {
+			return rules[i].Route, true
+		}
+	}
+	return Route{}, false
+}
@@ -15,6 +15,8 @@ import (
 	"github.com/micro/go-micro/v2/config/cmd"
 	"github.com/micro/go-micro/v2/registry"
 	mnet "github.com/micro/go-micro/v2/util/net"
+	health "github.com/micro/go-plugins/registry/health/v2"
+	rewatch "github.com/micro/go-plugins/registry/rewatch/v2"
 	hash "github.com/mitchellh/hashstructure"
 )
 
@@ -27,6 +29,8 @@ type consulRegistry struct {
 
 	// connect enabled
 	connect bool
+	// register in the catalog instead of the agent
+	catalog bool
 
 	queryOptions *consul.QueryOptions
 
@@ -34,6 +38,10 @@ type consulRegistry struct {
 	register map[string]uint64
 	// lastChecked tracks when a node was last checked as existing in Consul
 	lastChecked map[string]time.Time
+	// expired registrations of each service and the indexes of the checks
+	// counted by them
+	expired       map[string]uint64
+	expiredChecks map[string]uint64
 }
 
 func init() {
@@ -92,6 +100,9 @@ func configure(c *consulRegistry, opts ...registry.Option) {
 		if cn, ok := c.opts.Context.Value("consul_connect").(bool); ok {
 			c.connect = cn
 		}
+		if ca, ok := c.opts.Context.Value("consul_catalog").(bool); ok {
+			c.catalog = ca
+		}
 
 		// Use the consul query options passed in the options, if available
 		if qo, ok := c.opts.Context.Value("consul_query_options").(*consul.QueryOptions); ok && qo != nil {
@@ -167,6 +178,15 @@ func (c *consulRegistry) Deregister(s *registry.Service, opts ...registry.Deregi
 	c.Unlock()
 
 	node := s.Nodes[0]
+
+	// catalog services are registered as their own node
+	if c.catalog {
+		_, err := c.Client().Catalog().Deregister(&consul.CatalogDeregistration{
+			Node: node.Id,
+		}, nil)
+		return err
+	}
+
 	return c.Client().Agent().ServiceDeregister(node.Id)
 }
 
@@ -220,7 +240,7 @@ func (c *consulRegistry) Register(s *registry.Service, opts ...registry.Register
 					}
 				}
 			}
-		} else {
+		} else if !c.catalog {
 			// if the err is nil we're all good, bail out
 			// if not, we don't know what the state is, so full re-register
 			if err := c.Client().Agent().PassTTL("service:"+node.Id, ""); err == nil {
@@ -261,6 +281,10 @@ func (c *consulRegistry) Register(s *registry.Service, opts ...registry.Register
 	}
 	port, _ := strconv.Atoi(pt)
 
+	if c.catalog {
+		return c.registerCatalog(s, host, port, tags, regTCPCheck, regInterval, h)
+	}
+
 	// register the service
 	asr := &consul.AgentServiceRegistration{
 		ID:      node.Id,
@@ -297,15 +321,76 @@ func (c *consulRegistry) Register(s *registry.Service, opts ...registry.Register
 	return c.Client().Agent().PassTTL("service:"+node.Id, "")
 }
 
+// registerCatalog registers the service node in the catalog of the servers as an
+// external node. There's no agent to run checks so the tcp check is only run by
+// consul-esm, and re-registering refreshes the service instead of a ttl check.
+func (c *consulRegistry) registerCatalog(s *registry.Service, host string, port int, tags []string, tcpCheck bool, interval time.Duration, h uint64) error {
+	node := s.Nodes[0]
+
+	reg := &consul.CatalogRegistration{
+		Node:    node.Id,
+		Address: host,
+		NodeMeta: map[string]string{
+			"external-node":  "true",
+			"external-probe": "true",
+		},
+		Service: &consul.AgentService{
+			ID:      node.Id,
+			Service: s.Name,
+			Tags:    tags,
+			Port:    port,
+			Address: host,
+		},
+	}
+
+	// Specify consul connect
+	if c.connect {
+		reg.Service.Connect = &consul.AgentServiceConnect{
+			Native: true,
+		}
+	}
+
+	if tcpCheck {
+		reg.Check = &consul.AgentCheck{
+			Node:        node.Id,
+			CheckID:     "service:" + node.Id,
+			Name:        "Service '" + s.Name + "' check",
+			Status:      consul.HealthPassing,
+			ServiceID:   node.Id,
+			ServiceName: s.Name,
+			Definition: consul.HealthCheckDefinition{
+				TCP:                                    node.Address,
+				IntervalDuration:                       interval,
+				DeregisterCriticalServiceAfterDuration: getDeregisterTTL(interval),
+			},
+		}
+	}
+
+	if _, err := c.Client().Catalog().Register(reg, nil); err != nil {
+		return err
+	}
+
+	// save our hash and time check of the service
+	c.Lock()
+	c.register[s.Name] = h
+	c.lastChecked[s.Name] = time.Now()
+	c.Unlock()
+
+	return nil
+}
+
 func (c *consulRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
 	var rsp []*consul.ServiceEntry
 	var err error
 
+	// consul only returns the nodes with all their checks passing
+	passing := health.IsPassing(opts...)
+
 	// if we're connect enabled only get connect services
 	if c.connect {
-		rsp, _, err = c.Client().Health().Connect(name, "", false, c.queryOptions)
+		rsp, _, err = c.Client().Health().Connect(name, "", passing, c.queryOptions)
 	} else {
-		rsp, _, err = c.Client().Health().Service(name, "", false, c.queryOptions)
+		rsp, _, err = c.Client().Health().Service(name, "", passing, c.queryOptions)
 	}
 	if err != nil {
 		return nil, err
@@ -346,8 +431,9 @@ func (c *consulRegistry) GetService(name string, opts ...registry.GetOption) ([]
 		var del bool
 
 		for _, check := range s.Checks {
-			// delete the node if the status is critical
-			if check.Status == "critical" {
+			// delete the node if the status is critical, or not passing if only
+			// the passing nodes are returned
+			if check.Status == "critical" || (passing && check.Status != "passing") {
 				del = true
 				break
 			}
@@ -388,7 +474,9 @@ func (c *consulRegistry) ListServices(opts ...registry.ListOption) ([]*registry.
 }
 
 func (c *consulRegistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
-	return newConsulWatcher(c, opts...)
+	return rewatch.NewWatcher(c.opts, func() (registry.Watcher, error) {
+		return newConsulWatcher(c, opts...)
+	}, rewatch.Services(c, opts...))
 }
 
 func (c *consulRegistry) String() string {
@@ -411,8 +499,13 @@ func (c *consulRegistry) Client() *consul.Client {
 		// create a new client
 		tmpClient, _ := consul.NewClient(c.config)
 
-		// test the client
-		_, err := tmpClient.Agent().Host()
+		// test the client, there's no local agent in catalog mode
+		var err error
+		if c.catalog {
+			_, err = tmpClient.Status().Leader()
+		} else {
+			_, err = tmpClient.Agent().Host()
+		}
 		if err != nil {
 			continue
 		}
@@ -431,9 +524,11 @@ func (c *consulRegistry) Client() *consul.Client {
 
 func NewRegistry(opts ...registry.Option) registry.Registry {
 	cr := &consulRegistry{
-		opts:        registry.Options{},
-		register:    make(map[string]uint64),
-		lastChecked: make(map[string]time.Time),
+		opts:          registry.Options{},
+		register:      make(map[string]uint64),
+		lastChecked:   make(map[string]time.Time),
+		expired:       make(map[string]uint64),
+		expiredChecks: make(map[string]uint64),
 		queryOptions: &consul.QueryOptions{
 			AllowStale: true,
 		},
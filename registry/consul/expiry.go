@@ -0,0 +1,37 @@
+package consul
+
+// ExpireAction is the action of the watch results of nodes whose TTL check
+// expired rather than being deregistered, they're only returned by watchers
+// with the WatchExpired option and are deletes otherwise
+const ExpireAction = "expire"
+
+// Expirations is implemented by the registry returned by NewRegistry
+type Expirations interface {
+	// Expired returns the number of registrations of each service its watchers
+	// saw expire, a service which keeps expiring is flapping
+	Expired() map[string]uint64
+}
+
+func (c *consulRegistry) Expired() map[string]uint64 {
+	c.Lock()
+	defer c.Unlock()
+
+	expired := make(map[string]uint64, len(c.expired))
+	for name, n := range c.expired {
+		expired[name] = n
+	}
+	return expired
+}
+
+// expire counts the expired registration of the node, the index its TTL check
+// became critical at is kept so it's counted once however many watchers see it
+func (c *consulRegistry) expire(name, id string, index uint64) {
+	c.Lock()
+	defer c.Unlock()
+
+	if i, ok := c.expiredChecks[id]; ok && i == index {
+		return
+	}
+	c.expiredChecks[id] = index
+	c.expired[name]++
+}
@@ -18,6 +18,20 @@ func Connect() registry.Option {
 	}
 }
 
+// Catalog specifies services should be registered directly in the catalog of the
+// Consul servers instead of a local agent, for environments where an agent can't
+// run on every node. Each service node is registered as an external node, which
+// consul-esm can health check when TCPCheck is also set. Without it nodes are
+// only removed when they deregister.
+func Catalog() registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, "consul_catalog", true)
+	}
+}
+
 func Config(c *consul.Config) registry.Option {
 	return func(o *registry.Options) {
 		if o.Context == nil {
@@ -79,3 +93,14 @@ func TCPCheck(t time.Duration) registry.Option {
 		o.Context = context.WithValue(o.Context, "consul_tcp_check", t)
 	}
 }
+
+// WatchExpired returns the results of nodes whose TTL check expired, rather than
+// being deregistered, with the ExpireAction instead of the delete action
+func WatchExpired() registry.WatchOption {
+	return func(o *registry.WatchOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, "consul_watch_expired", true)
+	}
+}
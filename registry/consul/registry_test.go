@@ -11,6 +11,7 @@ import (
 
 	consul "github.com/hashicorp/consul/api"
 	"github.com/micro/go-micro/v2/registry"
+	health "github.com/micro/go-plugins/registry/health/v2"
 )
 
 type mockRegistry struct {
@@ -206,3 +207,117 @@ func TestConsul_GetService_WithUnhealthyServiceNodes(t *testing.T) {
 		t.Fatalf("Expected len of nodes to be `%d`, got `%d`.", exp, act)
 	}
 }
+
+func TestConsul_GetService_Passing(t *testing.T) {
+	// nodes with a warning aren't passing
+	svcs := []*consul.ServiceEntry{
+		newServiceEntry(
+			"node-name-1", "node-address-1", "service-name", "v1.0.0",
+			[]*consul.HealthCheck{
+				newHealthCheck("node-name-1", "service-name", "passing"),
+				newHealthCheck("node-name-1", "service-name", "passing"),
+			},
+		),
+		newServiceEntry(
+			"node-name-2", "node-address-2", "service-name", "v1.0.0",
+			[]*consul.HealthCheck{
+				newHealthCheck("node-name-2", "service-name", "passing"),
+				newHealthCheck("node-name-2", "service-name", "warning"),
+			},
+		),
+	}
+
+	cr, cl := newConsulTestRegistry(&mockRegistry{
+		status: 200,
+		body:   newServiceList(svcs),
+		url:    "/v1/health/service/service-name",
+	})
+	defer cl()
+
+	svc, err := cr.GetService("service-name", health.Passing())
+	if err != nil {
+		t.Fatal("Unexpected error", err)
+	}
+
+	if exp, act := 1, len(svc); exp != act {
+		t.Fatalf("Expected len of svc to be `%d`, got `%d`.", exp, act)
+	}
+
+	if exp, act := 1, len(svc[0].Nodes); exp != act {
+		t.Fatalf("Expected len of nodes to be `%d`, got `%d`.", exp, act)
+	}
+}
+
+func TestConsul_Catalog_Register(t *testing.T) {
+	var reg consul.CatalogRegistration
+	var dereg consul.CatalogDeregistration
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/catalog/register", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&reg); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		w.Write([]byte("true"))
+	})
+	mux.HandleFunc("/v1/catalog/deregister", func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&dereg); err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		w.Write([]byte("true"))
+	})
+	mux.HandleFunc("/v1/agent/", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected agent request %s", r.URL.Path)
+		http.Error(w, "no agent", 500)
+	})
+
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go http.Serve(l, mux)
+
+	r := NewRegistry(
+		registry.Addrs(l.Addr().String()),
+		Catalog(),
+		TCPCheck(10*time.Second),
+	)
+
+	svc := &registry.Service{
+		Name:    "greeter",
+		Version: "1.0.0",
+		Nodes:   []*registry.Node{{Id: "greeter-1", Address: "10.0.0.1:8080"}},
+	}
+
+	if err := r.Register(svc, registry.RegisterTTL(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if reg.Node != "greeter-1" || reg.Address != "10.0.0.1" || reg.NodeMeta["external-node"] != "true" {
+		t.Fatalf("unexpected node registration %+v", reg)
+	}
+	if reg.Service == nil || reg.Service.Service != "greeter" || reg.Service.Port != 8080 {
+		t.Fatalf("unexpected service registration %+v", reg.Service)
+	}
+	if reg.Check == nil || reg.Check.Definition.TCP != "10.0.0.1:8080" || reg.Check.Status != consul.HealthPassing {
+		t.Fatalf("unexpected check registration %+v", reg.Check)
+	}
+
+	// registering again refreshes the registration instead of passing a ttl check
+	reg = consul.CatalogRegistration{}
+	if err := r.Register(svc, registry.RegisterTTL(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if reg.Node != "greeter-1" {
+		t.Fatalf("expected the service to be registered again got %+v", reg)
+	}
+
+	if err := r.Deregister(svc); err != nil {
+		t.Fatal(err)
+	}
+	if dereg.Node != "greeter-1" {
+		t.Fatalf("unexpected deregistration %+v", dereg)
+	}
+}
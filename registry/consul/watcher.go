@@ -20,6 +20,8 @@ type consulWatcher struct {
 
 	next chan *registry.Result
 	exit chan bool
+	// return the results of expired nodes with the ExpireAction
+	expire bool
 
 	sync.RWMutex
 	services map[string][]*registry.Service
@@ -39,6 +41,9 @@ func newConsulWatcher(cr *consulRegistry, opts ...registry.WatchOption) (registr
 		watchers: make(map[string]*watch.Plan),
 		services: make(map[string][]*registry.Service),
 	}
+	if wo.Context != nil {
+		cw.expire, _ = wo.Context.Value("consul_watch_expired").(bool)
+	}
 
 	wp, err := watch.Parse(map[string]interface{}{"type": "services"})
 	if err != nil {
@@ -60,6 +65,8 @@ func (cw *consulWatcher) serviceHandler(idx uint64, data interface{}) {
 
 	serviceMap := map[string]*registry.Service{}
 	serviceName := ""
+	// TTL checks of the nodes which expired
+	expired := map[string]*api.HealthCheck{}
 
 	for _, e := range entries {
 		serviceName = e.Service.Service
@@ -93,6 +100,10 @@ func (cw *consulWatcher) serviceHandler(idx uint64, data interface{}) {
 			// delete the node if the status is critical
 			if check.Status == "critical" {
 				del = true
+				// the node expired if it's the TTL check of the service
+				if check.Type == "ttl" && check.ServiceID == id {
+					expired[id] = check
+				}
 				break
 			}
 		}
@@ -145,7 +156,7 @@ func (cw *consulWatcher) serviceHandler(idx uint64, data interface{}) {
 			// yes? then it's an update
 			action = "update"
 
-			var nodes []*registry.Node
+			var nodes, expiredNodes []*registry.Node
 			// check the old nodes to see if they've been deleted
 			for _, oldNode := range oldService.Nodes {
 				var seen bool
@@ -158,6 +169,13 @@ func (cw *consulWatcher) serviceHandler(idx uint64, data interface{}) {
 				// does the old node exist in the new set of nodes
 				// no? then delete that shit
 				if !seen {
+					if check, ok := expired[oldNode.Id]; ok {
+						cw.r.expire(serviceName, oldNode.Id, check.ModifyIndex)
+						if cw.expire {
+							expiredNodes = append(expiredNodes, oldNode)
+							continue
+						}
+					}
 					nodes = append(nodes, oldNode)
 				}
 			}
@@ -168,6 +186,11 @@ func (cw *consulWatcher) serviceHandler(idx uint64, data interface{}) {
 				delService.Nodes = nodes
 				cw.next <- &registry.Result{Action: "delete", Service: delService}
 			}
+			if len(expiredNodes) > 0 {
+				expService := regutil.CopyService(oldService)
+				expService.Nodes = expiredNodes
+				cw.next <- &registry.Result{Action: ExpireAction, Service: expService}
+			}
 		}
 
 		cw.next <- &registry.Result{Action: action, Service: newService}
@@ -56,6 +56,66 @@ func TestUnhealthyNodeServiceHandler(t *testing.T) {
 	}
 }
 
+func TestExpiredServiceHandler(t *testing.T) {
+	entry := func(id, status string, index uint64) *api.ServiceEntry {
+		e := newServiceEntry(
+			"node-name", "node-address", "service-name", "v1.0.0",
+			[]*api.HealthCheck{{
+				Node:        "node-name",
+				CheckID:     "service:" + id,
+				Type:        "ttl",
+				ServiceID:   id,
+				ServiceName: "service-name",
+				Status:      status,
+				ModifyIndex: index,
+			}},
+		)
+		e.Service.ID = id
+		return e
+	}
+
+	r := NewRegistry().(*consulRegistry)
+
+	for _, expire := range []bool{false, true} {
+		watcher := newWatcher()
+		watcher.r = r
+		watcher.expire = expire
+
+		watcher.serviceHandler(1, []*api.ServiceEntry{
+			entry("node-1", "passing", 1),
+			entry("node-2", "passing", 1),
+		})
+		// node-1 expired and node-2 was deregistered
+		watcher.serviceHandler(2, []*api.ServiceEntry{
+			entry("node-1", "critical", 2),
+		})
+		close(watcher.next)
+
+		actions := make(map[string]string)
+		for res := range watcher.next {
+			for _, node := range res.Service.Nodes {
+				actions[node.Id] = res.Action
+			}
+		}
+
+		expected := map[string]string{"node-1": "delete", "node-2": "delete"}
+		if expire {
+			expected["node-1"] = ExpireAction
+		}
+		for id, action := range expected {
+			if actions[id] != action {
+				t.Fatalf("Expected %s of %s with expire %v, got %q", action, id, expire, actions[id])
+			}
+		}
+	}
+
+	// both watchers saw node-1 expire, it's counted once
+	expired := r.Expired()
+	if len(expired) != 1 || expired["service-name"] != 1 {
+		t.Fatalf("Expected service-name to have expired once, got %v", expired)
+	}
+}
+
 func newWatcher() *consulWatcher {
 	return &consulWatcher{
 		exit:     make(chan bool),
@@ -0,0 +1,313 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/micro/go-micro/v2/registry"
+)
+
+func TestPrefix(t *testing.T) {
+	testData := map[string]string{
+		"":        DefaultPrefix,
+		"team-a":  "/team-a/",
+		"/team-a": "/team-a/",
+		"team-a/": "/team-a/",
+		"/a/b/":   "/a/b/",
+	}
+
+	for p, want := range testData {
+		opts := []registry.Option{}
+		if len(p) > 0 {
+			opts = append(opts, Prefix(p))
+		}
+		r := NewRegistry(opts...).(*etcdRegistry)
+		if r.prefix != want {
+			t.Fatalf("Expected prefix %q for %q, got %q", want, p, r.prefix)
+		}
+	}
+
+	r := NewRegistry(Prefix("team-a")).(*etcdRegistry)
+	if p := r.servicePath("foo"); p != "/team-a/foo" {
+		t.Fatalf("Expected service path /team-a/foo, got %s", p)
+	}
+	if p := r.nodePath("foo", "foo-1"); p != "/team-a/foo/foo-1" {
+		t.Fatalf("Expected node path /team-a/foo/foo-1, got %s", p)
+	}
+}
+
+func TestIsolation(t *testing.T) {
+	addr := os.Getenv("ETCD_ADDRESS")
+	if len(addr) == 0 {
+		t.Skip("ETCD_ADDRESS not defined")
+	}
+
+	a := NewRegistry(registry.Addrs(addr), Prefix("team-a"))
+	b := NewRegistry(registry.Addrs(addr), Prefix("team-b"))
+
+	w, err := b.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	events := make(chan *registry.Result, 1)
+	go func() {
+		for {
+			r, err := w.Next()
+			if err != nil {
+				return
+			}
+			events <- r
+		}
+	}()
+
+	service := &registry.Service{
+		Name:    "foo",
+		Version: "1.0.0",
+		Nodes: []*registry.Node{
+			{Id: "foo-1", Address: "10.0.0.1:8080"},
+		},
+	}
+
+	if err := a.Register(service); err != nil {
+		t.Fatal(err)
+	}
+
+	services, err := a.GetService("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(services) != 1 || len(services[0].Nodes) != 1 {
+		t.Fatalf("Expected 1 service with 1 node, got %+v", services)
+	}
+
+	if _, err := b.GetService("foo"); err != registry.ErrNotFound {
+		t.Fatalf("Expected service to be isolated by prefix, got %v", err)
+	}
+	list, err := b.ListServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("Expected no services under other prefix, got %d", len(list))
+	}
+
+	select {
+	case r := <-events:
+		t.Fatalf("Expected no watch events under other prefix, got %+v", r)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if err := b.Register(service); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case r := <-events:
+		if r.Action != "create" || r.Service.Name != "foo" {
+			t.Fatalf("Expected create event for foo, got %+v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected watch event under own prefix")
+	}
+
+	if err := a.Deregister(service); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.GetService("foo"); err != registry.ErrNotFound {
+		t.Fatalf("Expected service to be deregistered, got %v", err)
+	}
+	if services, err := b.GetService("foo"); err != nil || len(services) != 1 {
+		t.Fatalf("Expected service under other prefix to remain, got %v %v", services, err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	kv := func(name string, rev int64) *mvccpb.KeyValue {
+		return &mvccpb.KeyValue{
+			Key:         []byte("/micro/registry/" + name),
+			Value:       []byte(encode(&registry.Service{Name: name})),
+			ModRevision: rev,
+		}
+	}
+
+	old := map[string]*mvccpb.KeyValue{
+		"/micro/registry/foo": kv("foo", 1),
+		"/micro/registry/bar": kv("bar", 2),
+		"/micro/registry/baz": kv("baz", 3),
+	}
+	keys := map[string]*mvccpb.KeyValue{
+		"/micro/registry/foo": kv("foo", 1),
+		"/micro/registry/bar": kv("bar", 5),
+		"/micro/registry/qux": kv("qux", 6),
+	}
+
+	actions := make(map[string]string)
+	deleted := func(*mvccpb.KeyValue, *registry.Service) string { return "delete" }
+	for _, r := range diff(old, keys, deleted) {
+		actions[r.Service.Name] = r.Action
+	}
+
+	expected := map[string]string{"bar": "update", "baz": "delete", "qux": "create"}
+	if len(actions) != len(expected) {
+		t.Fatalf("Expected results %v, got %v", expected, actions)
+	}
+	for name, action := range expected {
+		if actions[name] != action {
+			t.Fatalf("Expected %s of %s, got %q", action, name, actions[name])
+		}
+	}
+}
+
+func TestWatchCompacted(t *testing.T) {
+	addr := os.Getenv("ETCD_ADDRESS")
+	if len(addr) == 0 {
+		t.Skip("ETCD_ADDRESS not defined")
+	}
+
+	r := NewRegistry(registry.Addrs(addr), Prefix("compacted")).(*etcdRegistry)
+
+	foo := &registry.Service{Name: "foo", Nodes: []*registry.Node{{Id: "foo-1"}}}
+	bar := &registry.Service{Name: "bar", Nodes: []*registry.Node{{Id: "bar-1"}}}
+
+	if err := r.Register(foo); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Deregister(foo)
+
+	w, err := r.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	ew := w.(*etcdWatcher)
+
+	// the changes happen while the watcher is behind a compaction
+	_, rev, err := ew.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(bar); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Deregister(bar)
+	if err := r.Deregister(foo); err != nil {
+		t.Fatal(err)
+	}
+
+	_, cur, err := ew.list()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.client.Compact(context.Background(), cur); err != nil {
+		t.Fatal(err)
+	}
+	ew.watch(rev)
+
+	actions := make(map[string]string)
+	for len(actions) < 2 {
+		res, err := w.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		actions[res.Service.Name] = res.Action
+	}
+
+	if actions["bar"] != "create" || actions["foo"] != "delete" {
+		t.Fatalf("Expected create of bar and delete of foo, got %v", actions)
+	}
+}
+
+// leases fakes the leases of etcd, the leases which aren't alive expired
+type leases struct {
+	clientv3.Lease
+	alive map[clientv3.LeaseID]bool
+}
+
+func (l *leases) TimeToLive(ctx context.Context, id clientv3.LeaseID, opts ...clientv3.LeaseOption) (*clientv3.LeaseTimeToLiveResponse, error) {
+	if l.alive[id] {
+		return &clientv3.LeaseTimeToLiveResponse{ID: id, TTL: 10}, nil
+	}
+	return &clientv3.LeaseTimeToLiveResponse{ID: id, TTL: -1}, nil
+}
+
+func TestWatchExpired(t *testing.T) {
+	kv := func(name string, lease int64) *mvccpb.KeyValue {
+		return &mvccpb.KeyValue{
+			Key:         []byte("/micro/registry/" + name),
+			Value:       []byte(encode(&registry.Service{Name: name})),
+			ModRevision: 1,
+			Lease:       lease,
+		}
+	}
+	// foo expired, bar was deregistered and baz had no ttl
+	events := []*clientv3.Event{
+		{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte("/micro/registry/foo")}, PrevKv: kv("foo", 1)},
+		{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte("/micro/registry/bar")}, PrevKv: kv("bar", 2)},
+		{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte("/micro/registry/baz")}, PrevKv: kv("baz", 0)},
+	}
+
+	r := &etcdRegistry{
+		expired:     make(map[string]uint64),
+		expiredRevs: make(map[string]int64),
+	}
+
+	for _, expire := range []bool{false, true} {
+		w := make(chan clientv3.WatchResponse, 1)
+		w <- clientv3.WatchResponse{Events: events}
+
+		ew := &etcdWatcher{
+			r:       r,
+			ctx:     context.Background(),
+			w:       w,
+			lease:   &leases{alive: map[clientv3.LeaseID]bool{2: true}},
+			timeout: time.Second,
+			keys:    make(map[string]*mvccpb.KeyValue),
+			expire:  expire,
+		}
+
+		actions := make(map[string]string)
+		for len(actions) < len(events) {
+			res, err := ew.Next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			actions[res.Service.Name] = res.Action
+		}
+
+		expected := map[string]string{"foo": "delete", "bar": "delete", "baz": "delete"}
+		if expire {
+			expected["foo"] = ExpireAction
+		}
+		for name, action := range expected {
+			if actions[name] != action {
+				t.Fatalf("Expected %s of %s with expire %v, got %q", action, name, expire, actions[name])
+			}
+		}
+	}
+
+	// both watchers saw foo expire, it's counted once
+	expired := r.Expired()
+	if len(expired) != 1 || expired["foo"] != 1 {
+		t.Fatalf("Expected foo to have expired once, got %v", expired)
+	}
+
+	// only the revisions of the latest expired keys are kept
+	for i := 0; i < maxExpiredRevs+10; i++ {
+		r.expire("bar", &mvccpb.KeyValue{Key: []byte(fmt.Sprintf("/micro/registry/bar/%d", i)), ModRevision: 1})
+	}
+	if len(r.expiredRevs) != maxExpiredRevs || len(r.expiredKeys) != maxExpiredRevs {
+		t.Fatalf("Expected %d expired revisions, got %d", maxExpiredRevs, len(r.expiredRevs))
+	}
+	if _, ok := r.expiredRevs["/micro/registry/foo"]; ok {
+		t.Fatal("Expected the revision of foo to be forgotten")
+	}
+	if n := r.Expired()["bar"]; n != maxExpiredRevs+10 {
+		t.Fatalf("Expected bar to have expired %d times, got %d", maxExpiredRevs+10, n)
+	}
+}
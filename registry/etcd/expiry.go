@@ -0,0 +1,91 @@
+package etcd
+
+import (
+	"context"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/micro/go-micro/v2/registry"
+)
+
+// ExpireAction is the action of the watch results of registrations which
+// expired rather than being deregistered, they're only returned by watchers
+// with the WatchExpired option and are deletes otherwise
+const ExpireAction = "expire"
+
+// Expirations is implemented by the registry returned by NewRegistry
+type Expirations interface {
+	// Expired returns the number of registrations of each service its watchers
+	// saw expire, a service which keeps expiring is flapping
+	Expired() map[string]uint64
+}
+
+func (e *etcdRegistry) Expired() map[string]uint64 {
+	e.RLock()
+	defer e.RUnlock()
+
+	expired := make(map[string]uint64, len(e.expired))
+	for name, n := range e.expired {
+		expired[name] = n
+	}
+	return expired
+}
+
+// maxExpiredRevs is how many revisions of expired keys are kept, the watchers
+// see an expiry at about the same time so only the latest ones are needed
+const maxExpiredRevs = 1024
+
+// expire counts the expired registration of the key, the revision it was last
+// written at is kept so it's counted once however many watchers see it
+func (e *etcdRegistry) expire(name string, kv *mvccpb.KeyValue) {
+	e.Lock()
+	defer e.Unlock()
+
+	key := string(kv.Key)
+	if rev, ok := e.expiredRevs[key]; ok {
+		if rev == kv.ModRevision {
+			return
+		}
+	} else {
+		e.expiredKeys = append(e.expiredKeys, key)
+	}
+	e.expiredRevs[key] = kv.ModRevision
+	e.expired[name]++
+
+	// forget the oldest, each registration has a new key
+	if len(e.expiredKeys) > maxExpiredRevs {
+		delete(e.expiredRevs, e.expiredKeys[0])
+		e.expiredKeys = e.expiredKeys[1:]
+	}
+}
+
+// leaseExpired returns true if the lease doesn't exist anymore
+func (ew *etcdWatcher) leaseExpired(id clientv3.LeaseID) bool {
+	ctx, cancel := context.WithTimeout(ew.ctx, ew.timeout)
+	defer cancel()
+
+	rsp, err := ew.lease.TimeToLive(ctx, id)
+	if err == rpctypes.ErrLeaseNotFound {
+		return true
+	}
+	if err != nil {
+		return false
+	}
+	return rsp.TTL == -1
+}
+
+// deleted returns the action of the result of the deleted key. The lease of a
+// registration which expired is gone with its key, while Deregister deletes the
+// key and leaves the lease to expire later.
+func (ew *etcdWatcher) deleted(kv *mvccpb.KeyValue, service *registry.Service) string {
+	if kv.Lease == 0 || !ew.leaseExpired(clientv3.LeaseID(kv.Lease)) {
+		return "delete"
+	}
+
+	ew.r.expire(service.Name, kv)
+	if ew.expire {
+		return ExpireAction
+	}
+	return "delete"
+}
@@ -2,4 +2,12 @@ module github.com/micro/go-plugins/registry/etcd/v2
 
 go 1.13
 
-require github.com/micro/go-micro/v2 v2.9.1
+require (
+	github.com/coreos/etcd v3.3.18+incompatible
+	github.com/micro/go-micro/v2 v2.9.1
+	github.com/micro/go-plugins/registry/rewatch/v2 v2.9.1
+	github.com/mitchellh/hashstructure v1.0.0
+	go.uber.org/zap v1.13.0
+)
+
+replace github.com/micro/go-plugins/registry/rewatch/v2 => ../rewatch
@@ -1,11 +1,64 @@
 package etcd
 
 import (
+	"context"
+
 	"github.com/micro/go-micro/v2/registry"
-	"github.com/micro/go-micro/v2/registry/etcd"
+	"go.uber.org/zap"
 )
 
+type authKey struct{}
+
+type logConfigKey struct{}
+
+type prefixKey struct{}
+
+type watchExpiredKey struct{}
+
+type authCreds struct {
+	Username string
+	Password string
+}
+
 // Auth allows you to specify username/password
 func Auth(username, password string) registry.Option {
-	return etcd.Auth(username, password)
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, authKey{}, &authCreds{Username: username, Password: password})
+	}
+}
+
+// LogConfig allows you to set etcd log config
+func LogConfig(config *zap.Config) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, logConfigKey{}, config)
+	}
+}
+
+// Prefix sets the key prefix services are registered under, the default is
+// /micro/registry/. Deployments sharing an etcd cluster only see the services
+// under their own prefix, so prefixes shouldn't be nested within each other.
+func Prefix(p string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, prefixKey{}, p)
+	}
+}
+
+// WatchExpired returns the results of registrations which expired, rather than
+// being deregistered, with the ExpireAction instead of the delete action
+func WatchExpired() registry.WatchOption {
+	return func(o *registry.WatchOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, watchExpiredKey{}, true)
+	}
 }
@@ -0,0 +1,218 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3rpc/rpctypes"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/micro/go-micro/v2/registry"
+)
+
+type etcdWatcher struct {
+	r       *etcdRegistry
+	stop    chan bool
+	ctx     context.Context
+	w       clientv3.WatchChan
+	client  *clientv3.Client
+	lease   clientv3.Lease
+	timeout time.Duration
+	path    string
+	// return the results of expired registrations with the ExpireAction
+	expire bool
+
+	// keys of the watched path as last seen, to recover the events
+	// missed when the watched revision is compacted
+	keys map[string]*mvccpb.KeyValue
+	// results of the events not returned yet
+	results []*registry.Result
+}
+
+func newEtcdWatcher(r *etcdRegistry, timeout time.Duration, opts ...registry.WatchOption) (registry.Watcher, error) {
+	var wo registry.WatchOptions
+	for _, o := range opts {
+		o(&wo)
+	}
+
+	watchPath := r.prefix
+	if len(wo.Service) > 0 {
+		watchPath = r.servicePath(wo.Service) + "/"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := make(chan bool, 1)
+
+	ew := &etcdWatcher{
+		r:       r,
+		stop:    stop,
+		ctx:     ctx,
+		client:  r.client,
+		lease:   r.client,
+		timeout: timeout,
+		path:    watchPath,
+	}
+	if wo.Context != nil {
+		ew.expire, _ = wo.Context.Value(watchExpiredKey{}).(bool)
+	}
+
+	keys, rev, err := ew.list()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	ew.keys = keys
+	ew.watch(rev)
+
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	return ew, nil
+}
+
+// list returns the keys of the watched path and the revision of the store
+func (ew *etcdWatcher) list() (map[string]*mvccpb.KeyValue, int64, error) {
+	ctx, cancel := context.WithTimeout(ew.ctx, ew.timeout)
+	defer cancel()
+
+	rsp, err := ew.client.Get(ctx, ew.path, clientv3.WithPrefix())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	keys := make(map[string]*mvccpb.KeyValue, len(rsp.Kvs))
+	for _, kv := range rsp.Kvs {
+		keys[string(kv.Key)] = kv
+	}
+	return keys, rsp.Header.Revision, nil
+}
+
+// watch watches the path for the changes after the revision
+func (ew *etcdWatcher) watch(rev int64) {
+	ew.w = ew.client.Watch(ew.ctx, ew.path, clientv3.WithPrefix(), clientv3.WithPrevKV(), clientv3.WithRev(rev+1))
+}
+
+// recover lists the path again once the watched revision is compacted, queues
+// the results of the changes since the keys were last seen and watches from
+// the current revision
+func (ew *etcdWatcher) recover() error {
+	keys, rev, err := ew.list()
+	if err != nil {
+		return err
+	}
+
+	ew.results = append(ew.results, diff(ew.keys, keys, ew.deleted)...)
+	ew.keys = keys
+	ew.watch(rev)
+	return nil
+}
+
+// diff returns the results which change the old keys into the new ones, the
+// action of each deleted key is returned by deleted
+func diff(old, keys map[string]*mvccpb.KeyValue, deleted func(*mvccpb.KeyValue, *registry.Service) string) []*registry.Result {
+	var results []*registry.Result
+
+	for k, kv := range keys {
+		prev, ok := old[k]
+		if ok && prev.ModRevision == kv.ModRevision {
+			continue
+		}
+
+		action := "create"
+		if ok {
+			action = "update"
+		}
+		if service := decode(kv.Value); service != nil {
+			results = append(results, &registry.Result{Action: action, Service: service})
+		}
+	}
+
+	for k, kv := range old {
+		if _, ok := keys[k]; ok {
+			continue
+		}
+		if service := decode(kv.Value); service != nil {
+			results = append(results, &registry.Result{Action: deleted(kv, service), Service: service})
+		}
+	}
+
+	return results
+}
+
+func (ew *etcdWatcher) Next() (*registry.Result, error) {
+	for {
+		if len(ew.results) > 0 {
+			r := ew.results[0]
+			ew.results = ew.results[1:]
+			return r, nil
+		}
+
+		wresp, ok := <-ew.w
+		if !ok {
+			return nil, errors.New("could not get next")
+		}
+		if wresp.Err() == rpctypes.ErrCompacted {
+			if err := ew.recover(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if wresp.Err() != nil {
+			return nil, wresp.Err()
+		}
+		if wresp.Canceled {
+			return nil, errors.New("could not get next")
+		}
+
+		for _, ev := range wresp.Events {
+			service := decode(ev.Kv.Value)
+			var action string
+
+			switch ev.Type {
+			case clientv3.EventTypePut:
+				if ev.IsCreate() {
+					action = "create"
+				} else if ev.IsModify() {
+					action = "update"
+				}
+				ew.keys[string(ev.Kv.Key)] = ev.Kv
+			case clientv3.EventTypeDelete:
+				action = "delete"
+
+				// get service from prevKv, or the key as last seen if
+				// the previous revision is compacted
+				prev := ev.PrevKv
+				if prev == nil {
+					prev = ew.keys[string(ev.Kv.Key)]
+				}
+				if prev != nil {
+					service = decode(prev.Value)
+				}
+				if prev != nil && service != nil {
+					action = ew.deleted(prev, service)
+				}
+				delete(ew.keys, string(ev.Kv.Key))
+			}
+
+			if service == nil {
+				continue
+			}
+			ew.results = append(ew.results, &registry.Result{
+				Action:  action,
+				Service: service,
+			})
+		}
+	}
+}
+
+func (ew *etcdWatcher) Stop() {
+	select {
+	case <-ew.stop:
+		return
+	default:
+		close(ew.stop)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/hudl/fargo"
 	"github.com/micro/go-micro/v2/config/cmd"
 	"github.com/micro/go-micro/v2/registry"
+	health "github.com/micro/go-plugins/registry/health/v2"
 	"github.com/op/go-logging"
 )
 
@@ -102,11 +103,26 @@ func (e *eurekaRegistry) Deregister(s *registry.Service, opts ...registry.Deregi
 	return e.conn.DeregisterInstance(instance)
 }
 
+// upInstances returns a copy of the app with only the instances which are up
+func upInstances(app *fargo.Application) *fargo.Application {
+	up := *app
+	up.Instances = nil
+	for _, instance := range app.Instances {
+		if instance.Status == fargo.UP {
+			up.Instances = append(up.Instances, instance)
+		}
+	}
+	return &up
+}
+
 func (e *eurekaRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
 	app, err := e.conn.GetApp(name)
 	if err != nil {
 		return nil, err
 	}
+	if health.IsPassing(opts...) {
+		app = upInstances(app)
+	}
 	return appToService(app), nil
 }
 
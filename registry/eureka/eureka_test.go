@@ -3,12 +3,14 @@ package eureka
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"testing"
 
 	"github.com/hudl/fargo"
 	"github.com/micro/go-micro/v2/registry"
 	"github.com/micro/go-plugins/registry/eureka/v2/mock"
+	health "github.com/micro/go-plugins/registry/health/v2"
 )
 
 func TestRegistration(t *testing.T) {
@@ -72,3 +74,49 @@ func TestSwitchHttpClient(t *testing.T) {
 		t.Errorf("Unexpected fargo.HttpClient: got %v, want %v", fargo.HttpClient, expected)
 	}
 }
+
+func TestGetServicePassing(t *testing.T) {
+	app := new(fargo.Application)
+	for i, status := range []fargo.StatusType{fargo.UP, fargo.DOWN} {
+		instance, err := serviceToInstance(&registry.Service{
+			Name:    "service-name",
+			Version: "service-version",
+			Nodes: []*registry.Node{{
+				Id:      fmt.Sprintf("node%d", i),
+				Address: fmt.Sprintf("node%d.example.com:1234", i),
+			}},
+		})
+		if err != nil {
+			t.Fatal("Unexpected serviceToInstance error:", err)
+		}
+		instance.Status = status
+		app.Instances = append(app.Instances, instance)
+	}
+
+	eureka := NewRegistry().(*eurekaRegistry)
+	mockConn := new(mock.FargoConnection)
+	mockConn.GetAppReturns(app, nil)
+	eureka.conn = mockConn
+
+	testData := []struct {
+		opts  []registry.GetOption
+		nodes int
+	}{
+		{nil, 2},
+		{[]registry.GetOption{health.Passing()}, 1},
+	}
+
+	for _, test := range testData {
+		services, err := eureka.GetService("service-name", test.opts...)
+		if err != nil {
+			t.Fatal("Unexpected GetService error:", err)
+		}
+		if len(services) != 1 || len(services[0].Nodes) != test.nodes {
+			t.Errorf("Unexpected services: want 1 with %d nodes, got %+v", test.nodes, services)
+		}
+	}
+
+	if len(app.Instances) != 2 {
+		t.Errorf("Unexpected instances of the app: want 2, got %d", len(app.Instances))
+	}
+}
@@ -9,7 +9,10 @@ require (
 	github.com/franela/goreq v0.0.0-20171204163338-bcd34c9993f8 // indirect
 	github.com/hudl/fargo v1.3.0
 	github.com/micro/go-micro/v2 v2.9.1
+	github.com/micro/go-plugins/registry/health/v2 v2.9.1
 	github.com/op/go-logging v0.0.0-20160315200505-970db520ece7
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
 	gopkg.in/gcfg.v1 v1.2.3 // indirect
 )
+
+replace github.com/micro/go-plugins/registry/health/v2 => ../health
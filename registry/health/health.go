@@ -0,0 +1,50 @@
+// Package health provides the registry option getting only the nodes passing their
+// health checks, it's implemented by the consul, kubernetes and eureka registries
+package health
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/registry"
+)
+
+type passingKey struct{}
+
+// Passing gets only the nodes of the service passing their health checks, such
+// as the consul checks, kubernetes readiness or eureka status. Registries without
+// health checks ignore it.
+func Passing() registry.GetOption {
+	return func(o *registry.GetOptions) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, passingKey{}, true)
+	}
+}
+
+// IsPassing returns true if the options get only the nodes passing their health checks
+func IsPassing(opts ...registry.GetOption) bool {
+	var options registry.GetOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.Context == nil {
+		return false
+	}
+	passing, _ := options.Context.Value(passingKey{}).(bool)
+	return passing
+}
+
+type passingRegistry struct {
+	registry.Registry
+}
+
+func (p *passingRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	return p.Registry.GetService(name, append(opts, Passing())...)
+}
+
+// NewRegistry returns a registry getting only the nodes passing their health checks,
+// so selectors using it don't select the others
+func NewRegistry(r registry.Registry) registry.Registry {
+	return &passingRegistry{Registry: r}
+}
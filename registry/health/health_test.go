@@ -0,0 +1,43 @@
+package health
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micro/go-micro/v2/registry"
+)
+
+func TestPassing(t *testing.T) {
+	if IsPassing() {
+		t.Fatal("expected all nodes without options")
+	}
+	if !IsPassing(Passing()) {
+		t.Fatal("expected only the passing nodes")
+	}
+
+	// other options keep it
+	other := func(o *registry.GetOptions) {
+		o.Context = context.WithValue(o.Context, "foo", "bar")
+	}
+	if !IsPassing(Passing(), other) {
+		t.Fatal("expected only the passing nodes with other options")
+	}
+}
+
+type testRegistry struct {
+	registry.Registry
+	passing bool
+}
+
+func (t *testRegistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
+	t.passing = IsPassing(opts...)
+	return nil, nil
+}
+
+func TestNewRegistry(t *testing.T) {
+	r := &testRegistry{}
+	NewRegistry(r).GetService("foo")
+	if !r.passing {
+		t.Fatal("expected the registry to get only the passing nodes")
+	}
+}
@@ -49,6 +49,13 @@ var tests = []testcase{
 		Method: "GET",
 		URI:    "/api/v1/namespaces/default/pods/?labelSelector=foo%3Dbar",
 	},
+	testcase{
+		ReqFn: func(opts *Options) *Request {
+			return NewRequest(opts).Get().Resource("pods").Namespace("")
+		},
+		Method: "GET",
+		URI:    "/api/v1/pods/",
+	},
 	testcase{
 		ReqFn: func(opts *Options) *Request {
 			return NewRequest(opts).Post().Resource("services").Name("foo").Body(map[string]string{"foo": "bar"})
@@ -139,3 +146,23 @@ func TestRequest(t *testing.T) {
 		ts.Close()
 	}
 }
+
+func TestForbidden(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	opts := &Options{
+		Host:      ts.URL,
+		Client:    &http.Client{},
+		Namespace: "default",
+	}
+
+	if err := NewRequest(opts).Get().Resource("pods").Do().Error(); err != ErrForbidden {
+		t.Fatalf("Expected request to be forbidden, got %v", err)
+	}
+	if _, err := NewRequest(opts).Get().Resource("pods").Watch(); err != ErrForbidden {
+		t.Fatalf("Expected watch to be forbidden, got %v", err)
+	}
+}
@@ -124,6 +124,11 @@ func (r *Request) SetHeader(key, value string) *Request {
 func (r *Request) request() (*http.Request, error) {
 	url := fmt.Sprintf("%s/api/v1/namespaces/%s/%s/", r.host, r.namespace, r.resource)
 
+	// an empty namespace targets the resource in all namespaces
+	if len(r.namespace) == 0 {
+		url = fmt.Sprintf("%s/api/v1/%s/", r.host, r.resource)
+	}
+
 	// append resourceName if it is present
 	if r.resourceName != nil {
 		url += *r.resourceName
@@ -7,13 +7,16 @@ import (
 	"net/http"
 
 	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-plugins/registry/kubernetes/v2/client/watch"
 )
 
 // Errors ...
 var (
 	ErrNotFound = errors.New("K8s: not found")
-	ErrDecode   = errors.New("K8s: error decoding")
-	ErrOther    = errors.New("K8s: error")
+	// ErrForbidden is returned when RBAC doesn't allow the request
+	ErrForbidden = watch.ErrForbidden
+	ErrDecode    = errors.New("K8s: error decoding")
+	ErrOther     = errors.New("K8s: error")
 )
 
 // Status is an object that is returned when a request
@@ -82,6 +85,12 @@ func newResponse(res *http.Response, err error) *Response {
 		return r
 	}
 
+	if r.res.StatusCode == http.StatusForbidden {
+		r.res.Body.Close()
+		r.err = ErrForbidden
+		return r
+	}
+
 	log.Errorf("K8s: request failed with code %v", r.res.StatusCode)
 
 	b, err := ioutil.ReadAll(r.res.Body)
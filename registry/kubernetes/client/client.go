@@ -43,6 +43,13 @@ func (c *client) WatchPods(labels map[string]string) (watch.Watch, error) {
 	return api.NewRequest(c.opts).Get().Resource("pods").Params(&api.Params{LabelSelector: labels}).Watch()
 }
 
+// Namespace ...
+func (c *client) Namespace(namespace string) Kubernetes {
+	opts := *c.opts
+	opts.Namespace = namespace
+	return &client{opts: &opts}
+}
+
 func detectNamespace() (string, error) {
 	nsPath := path.Join(serviceAccountPath, "namespace")
 
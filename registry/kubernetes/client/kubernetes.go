@@ -7,6 +7,9 @@ type Kubernetes interface {
 	ListPods(labels map[string]string) (*PodList, error)
 	UpdatePod(podName string, pod *Pod) (*Pod, error)
 	WatchPods(labels map[string]string) (watch.Watch, error)
+	// Namespace returns a client for pods in the namespace,
+	// or in all namespaces if it's empty
+	Namespace(namespace string) Kubernetes
 }
 
 // PodList ...
@@ -23,12 +26,20 @@ type Pod struct {
 // Meta ...
 type Meta struct {
 	Name        string             `json:"name,omitempty"`
+	Namespace   string             `json:"namespace,omitempty"`
 	Labels      map[string]*string `json:"labels,omitempty"`
 	Annotations map[string]*string `json:"annotations,omitempty"`
 }
 
 // Status ...
 type Status struct {
-	PodIP string `json:"podIP"`
-	Phase string `json:"phase"`
+	PodIP      string      `json:"podIP"`
+	Phase      string      `json:"phase"`
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// Condition of a pod e.g whether it's ready
+type Condition struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
 }
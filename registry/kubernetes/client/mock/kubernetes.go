@@ -11,6 +11,10 @@ import (
 
 // Client ...
 type Client struct {
+	// ForbidAll makes listing and watching pods in all namespaces
+	// fail as if RBAC doesn't allow it
+	ForbidAll bool
+
 	sync.Mutex
 	Pods     map[string]*client.Pod
 	events   chan watch.Event
@@ -57,17 +61,103 @@ func (m *Client) WatchPods(labels map[string]string) (watch.Watch, error) {
 		stop:    make(chan bool),
 	}
 
-	i := len(m.watchers) // length of watchers is current index
+	m.Lock()
 	m.watchers = append(m.watchers, w)
+	m.Unlock()
 
 	go func() {
 		<-w.stop
-		m.watchers = append(m.watchers[:i], m.watchers[i+1:]...)
+		m.Lock()
+		for i, mw := range m.watchers {
+			if mw == w {
+				m.watchers = append(m.watchers[:i], m.watchers[i+1:]...)
+				break
+			}
+		}
+		m.Unlock()
 	}()
 
 	return w, nil
 }
 
+// Namespace returns a client for pods in the namespace
+func (m *Client) Namespace(namespace string) client.Kubernetes {
+	return &namespaced{Client: m, namespace: namespace}
+}
+
+// namespaced only sees pods in its namespace, or all pods if it's empty
+type namespaced struct {
+	*Client
+	namespace string
+}
+
+// ListPods ...
+func (n *namespaced) ListPods(labels map[string]string) (*client.PodList, error) {
+	if len(n.namespace) == 0 && n.ForbidAll {
+		return nil, api.ErrForbidden
+	}
+
+	pods, err := n.Client.ListPods(labels)
+	if err != nil || len(n.namespace) == 0 {
+		return pods, err
+	}
+
+	var items []client.Pod
+	for _, p := range pods.Items {
+		if p.Metadata.Namespace == n.namespace {
+			items = append(items, p)
+		}
+	}
+	return &client.PodList{
+		Items: items,
+	}, nil
+}
+
+// WatchPods ...
+func (n *namespaced) WatchPods(labels map[string]string) (watch.Watch, error) {
+	if len(n.namespace) == 0 {
+		if n.ForbidAll {
+			return nil, api.ErrForbidden
+		}
+		return n.Client.WatchPods(labels)
+	}
+
+	w, err := n.Client.WatchPods(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	nw := &namespacedWatcher{
+		results: make(chan watch.Event),
+		stop:    make(chan bool),
+	}
+
+	go func() {
+		defer close(nw.results)
+		defer w.Stop()
+		for {
+			var e watch.Event
+			select {
+			case e = <-w.ResultChan():
+			case <-nw.stop:
+				return
+			}
+
+			var pod client.Pod
+			if err := json.Unmarshal(e.Object, &pod); err != nil || pod.Metadata.Namespace != n.namespace {
+				continue
+			}
+			select {
+			case nw.results <- e:
+			case <-nw.stop:
+				return
+			}
+		}
+	}()
+
+	return nw, nil
+}
+
 // newClient ...
 func newClient() client.Kubernetes {
 	return &Client{}
@@ -83,8 +173,15 @@ func NewClient() *Client {
 	// broadcast events to watchers
 	go func() {
 		for e := range c.events {
-			for _, w := range c.watchers {
-				w.results <- e
+			c.Lock()
+			watchers := append([]*mockWatcher(nil), c.watchers...)
+			c.Unlock()
+
+			for _, w := range watchers {
+				select {
+				case w.results <- e:
+				case <-w.stop:
+				}
 			}
 		}
 	}()
@@ -15,14 +15,33 @@ func (w *mockWatcher) ResultChan() <-chan watch.Event {
 	return w.results
 }
 
-// Stop closes any channels
+// Stop closes the stop channel, results aren't closed
+// as events may still be broadcast to the watcher
 func (w *mockWatcher) Stop() {
 	select {
 	case <-w.stop:
 		return
 	default:
 		close(w.stop)
-		close(w.results)
+	}
+}
+
+type namespacedWatcher struct {
+	results chan watch.Event
+	stop    chan bool
+}
+
+// Changes returns the results channel
+func (w *namespacedWatcher) ResultChan() <-chan watch.Event {
+	return w.results
+}
+
+// Stop closes the stop channel, the results are closed once forwarding stops
+func (w *namespacedWatcher) Stop() {
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
 	}
 }
 
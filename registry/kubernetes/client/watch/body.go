@@ -3,6 +3,7 @@ package watch
 import (
 	"bufio"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 )
@@ -48,7 +49,7 @@ func (wr *bodyWatcher) stream() {
 			// read a line
 			b, err := reader.ReadBytes('\n')
 			if err != nil {
-				return
+				break
 			}
 
 			// ignore for the first second
@@ -80,6 +81,16 @@ func NewBodyWatcher(req *http.Request, client *http.Client) (Watch, error) {
 		return nil, err
 	}
 
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden:
+		res.Body.Close()
+		return nil, ErrForbidden
+	default:
+		res.Body.Close()
+		return nil, fmt.Errorf("K8s: watch failed with code %v", res.StatusCode)
+	}
+
 	wr := &bodyWatcher{
 		results: make(chan Event),
 		stop:    stop,
@@ -1,6 +1,12 @@
 package watch
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrForbidden is returned when RBAC doesn't allow the watch
+var ErrForbidden = errors.New("K8s: forbidden")
 
 // Watch ...
 type Watch interface {
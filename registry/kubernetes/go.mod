@@ -2,4 +2,12 @@ module github.com/micro/go-plugins/registry/kubernetes/v2
 
 go 1.13
 
-require github.com/micro/go-micro/v2 v2.9.1-0.20200706125517-97ae2979ad62
+require (
+	github.com/micro/go-micro/v2 v2.9.1-0.20200706125517-97ae2979ad62
+	github.com/micro/go-plugins/registry/health/v2 v2.9.1
+	github.com/micro/go-plugins/registry/rewatch/v2 v2.9.1
+)
+
+replace github.com/micro/go-plugins/registry/health/v2 => ../health
+
+replace github.com/micro/go-plugins/registry/rewatch/v2 => ../rewatch
@@ -8,18 +8,33 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/micro/go-plugins/registry/kubernetes/v2/client"
+	"github.com/micro/go-plugins/registry/kubernetes/v2/client/api"
+	"github.com/micro/go-plugins/registry/kubernetes/v2/client/watch"
 
 	"github.com/micro/go-micro/v2/config/cmd"
+	log "github.com/micro/go-micro/v2/logger"
 	"github.com/micro/go-micro/v2/registry"
+	health "github.com/micro/go-plugins/registry/health/v2"
+	rewatch "github.com/micro/go-plugins/registry/rewatch/v2"
 )
 
 type kregistry struct {
 	client  client.Kubernetes
 	timeout time.Duration
 	options registry.Options
+
+	sync.RWMutex
+	// clients of the namespaces services are discovered in,
+	// the namespace of the pod if empty
+	discover []client.Kubernetes
+	// discovering in all namespaces
+	all bool
+	// labels pods are selected with
+	selector map[string]string
 }
 
 var (
@@ -38,6 +53,10 @@ var (
 	// Pod status
 	podRunning = "Running"
 
+	// Pod condition
+	podReady      = "Ready"
+	conditionTrue = "True"
+
 	// label name regex
 	labelRe = regexp.MustCompilePOSIX("[-A-Za-z0-9_.]")
 )
@@ -77,9 +96,135 @@ func configure(k *kregistry, opts ...registry.Option) error {
 	k.client = c
 	k.timeout = k.options.Timeout
 
+	k.discovery()
+
 	return nil
 }
 
+// discovery sets up the namespaces and labels services are discovered with
+func (k *kregistry) discovery() {
+	k.Lock()
+	defer k.Unlock()
+
+	k.discover = nil
+	k.all = false
+	k.selector = nil
+
+	if k.options.Context == nil {
+		return
+	}
+
+	if all, ok := k.options.Context.Value(allNamespacesKey{}).(bool); ok && all {
+		k.all = true
+		k.discover = []client.Kubernetes{k.client.Namespace("")}
+	} else if ns, ok := k.options.Context.Value(namespacesKey{}).([]string); ok {
+		for _, n := range ns {
+			k.discover = append(k.discover, k.client.Namespace(n))
+		}
+	}
+
+	if labels, ok := k.options.Context.Value(selectorKey{}).(map[string]string); ok {
+		k.selector = labels
+	}
+}
+
+// namespaces returns the clients of the namespaces services are discovered in
+func (c *kregistry) namespaces() []client.Kubernetes {
+	c.RLock()
+	defer c.RUnlock()
+
+	if len(c.discover) == 0 {
+		return []client.Kubernetes{c.client}
+	}
+	return c.discover
+}
+
+// labels returns the labels to select pods with, including the selector
+func (c *kregistry) labels(labels map[string]string) map[string]string {
+	c.RLock()
+	defer c.RUnlock()
+
+	if len(c.selector) == 0 {
+		return labels
+	}
+
+	l := make(map[string]string, len(labels)+len(c.selector))
+	for k, v := range c.selector {
+		l[k] = v
+	}
+	for k, v := range labels {
+		l[k] = v
+	}
+	return l
+}
+
+// forbidden falls back to discovering services in the namespace of the pod
+// when the service account isn't allowed to access pods in all namespaces
+func (c *kregistry) forbidden(err error) bool {
+	if err != api.ErrForbidden {
+		return false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if !c.all {
+		return false
+	}
+
+	c.all = false
+	c.discover = nil
+
+	if log.V(log.WarnLevel, log.DefaultLogger) {
+		log.Warn("K8s: not allowed to access pods in all namespaces, falling back to the pod namespace")
+	}
+
+	return true
+}
+
+// listPods lists the pods with the labels in every namespace services are discovered in
+func (c *kregistry) listPods(labels map[string]string) (*client.PodList, error) {
+	list := &client.PodList{}
+
+	for _, k := range c.namespaces() {
+		pods, err := k.ListPods(c.labels(labels))
+		if c.forbidden(err) {
+			return c.listPods(labels)
+		}
+		if err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, pods.Items...)
+	}
+
+	return list, nil
+}
+
+// watchPods watches the pods with the labels in every namespace services are discovered in
+func (c *kregistry) watchPods(labels map[string]string) (watch.Watch, error) {
+	var watches []watch.Watch
+
+	for _, k := range c.namespaces() {
+		w, err := k.WatchPods(c.labels(labels))
+		if err != nil {
+			for _, w := range watches {
+				w.Stop()
+			}
+			if c.forbidden(err) {
+				return c.watchPods(labels)
+			}
+			return nil, err
+		}
+		watches = append(watches, w)
+	}
+
+	if len(watches) == 1 {
+		return watches[0], nil
+	}
+
+	return newMultiWatch(watches), nil
+}
+
 // serviceName generates a valid service name for k8s labels
 func serviceName(name string) string {
 	aname := make([]byte, len(name))
@@ -175,7 +320,10 @@ func (c *kregistry) Deregister(s *registry.Service, opts ...registry.DeregisterO
 // GetService will get all the pods with the given service selector,
 // and build services from the annotations.
 func (c *kregistry) GetService(name string, opts ...registry.GetOption) ([]*registry.Service, error) {
-	pods, err := c.client.ListPods(map[string]string{
+	// only ready pods pass their readiness probes
+	passing := health.IsPassing(opts...)
+
+	pods, err := c.listPods(map[string]string{
 		svcSelectorPrefix + serviceName(name): svcSelectorValue,
 	})
 	if err != nil {
@@ -194,6 +342,9 @@ func (c *kregistry) GetService(name string, opts ...registry.GetOption) ([]*regi
 		if pod.Status.Phase != podRunning {
 			continue
 		}
+		if passing && !ready(&pod) {
+			continue
+		}
 		// get serialised service from annotation
 		svcStr, ok := pod.Metadata.Annotations[annotationServiceKeyPrefix+serviceName(name)]
 		if !ok {
@@ -224,9 +375,19 @@ func (c *kregistry) GetService(name string, opts ...registry.GetOption) ([]*regi
 	return list, nil
 }
 
+// ready returns true if the pod passes its readiness probes
+func ready(pod *client.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == podReady {
+			return c.Status == conditionTrue
+		}
+	}
+	return false
+}
+
 // ListServices will list all the service names
 func (c *kregistry) ListServices(opts ...registry.ListOption) ([]*registry.Service, error) {
-	pods, err := c.client.ListPods(podSelector)
+	pods, err := c.listPods(podSelector)
 	if err != nil {
 		return nil, err
 	}
@@ -262,7 +423,9 @@ func (c *kregistry) ListServices(opts ...registry.ListOption) ([]*registry.Servi
 
 // Watch returns a kubernetes watcher
 func (c *kregistry) Watch(opts ...registry.WatchOption) (registry.Watcher, error) {
-	return newWatcher(c, opts...)
+	return rewatch.NewWatcher(c.options, func() (registry.Watcher, error) {
+		return newWatcher(c, opts...)
+	}, rewatch.Services(c, opts...))
 }
 
 func (c *kregistry) String() string {
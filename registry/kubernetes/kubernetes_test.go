@@ -12,6 +12,7 @@ import (
 	log "github.com/micro/go-micro/v2/logger"
 	"github.com/micro/go-micro/v2/registry"
 	"github.com/micro/go-micro/v2/router"
+	health "github.com/micro/go-plugins/registry/health/v2"
 	"github.com/micro/go-plugins/registry/kubernetes/v2/client"
 	"github.com/micro/go-plugins/registry/kubernetes/v2/client/mock"
 )
@@ -70,10 +71,15 @@ func teardownRegistry() {
 }
 
 func setupRegistry(opts ...registry.Option) registry.Registry {
-	return &kregistry{
+	k := &kregistry{
 		client:  mockClient,
 		timeout: time.Second * 1,
 	}
+	for _, o := range opts {
+		o(&k.options)
+	}
+	k.discovery()
+	return k
 }
 
 //
@@ -351,6 +357,40 @@ func TestGetServiceSameServiceTwoPods(t *testing.T) {
 	}
 }
 
+func TestGetServicePassing(t *testing.T) {
+	r := setupRegistry()
+	defer teardownRegistry()
+
+	svc1 := &registry.Service{Name: "foo.service", Version: "1"}
+	svc2 := &registry.Service{Name: "foo.service", Version: "1"}
+	register(r, "pod-1", svc1)
+	register(r, "pod-2", svc2)
+
+	// only pod-1 is ready
+	mockClient.Pods["pod-1"].Status.Conditions = []client.Condition{
+		{Type: podReady, Status: conditionTrue},
+	}
+	mockClient.Pods["pod-2"].Status.Conditions = []client.Condition{
+		{Type: podReady, Status: "False"},
+	}
+
+	service, err := r.GetService("foo.service")
+	if err != nil {
+		t.Fatalf("did not expect GetService to fail %v", err)
+	}
+	if len(service) != 1 || len(service[0].Nodes) != 2 {
+		t.Fatal("expected there to be 2 nodes")
+	}
+
+	service, err = r.GetService("foo.service", health.Passing())
+	if err != nil {
+		t.Fatalf("did not expect GetService to fail %v", err)
+	}
+	if len(service) != 1 || !hasNodes(service[0].Nodes, []*registry.Node{svc1.Nodes[0]}) {
+		t.Fatal("expected only the node of the ready pod")
+	}
+}
+
 func TestGetServiceTwoVersionsTwoPods(t *testing.T) {
 	r := setupRegistry()
 	defer teardownRegistry()
@@ -462,6 +502,120 @@ func TestWatcher(t *testing.T) {
 	}
 }
 
+func TestNamespaces(t *testing.T) {
+	r := setupRegistry(Namespaces("team-a", "team-b"))
+	defer teardownRegistry()
+
+	w, err := r.Watch()
+	if err != nil {
+		t.Fatalf("did not expect Watch() to fail: %v", err)
+	}
+	defer w.Stop()
+
+	results := make(chan *registry.Result, 10)
+	go func() {
+		for {
+			res, err := w.Next()
+			if err != nil {
+				return
+			}
+			results <- res
+		}
+	}()
+
+	for i, ns := range []string{"team-a", "team-b", "team-c"} {
+		name := fmt.Sprintf("pod-ns-%d", i)
+		setupPod(name).Metadata.Namespace = ns
+		register(r, name, &registry.Service{Name: ns + ".service", Version: "1"})
+	}
+
+	for _, name := range []string{"team-a.service", "team-b.service"} {
+		select {
+		case res := <-results:
+			if res.Action != "create" {
+				t.Fatalf("expected create result, got %s", res.Action)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected watch result for %s", name)
+		}
+
+		if _, err := r.GetService(name); err != nil {
+			t.Fatalf("did not expect GetService(%s) to fail: %v", name, err)
+		}
+	}
+
+	select {
+	case res := <-results:
+		t.Fatalf("did not expect result for other namespace, got %s", res.Service.Name)
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	if _, err := r.GetService("team-c.service"); err != registry.ErrNotFound {
+		t.Fatalf("expected service in other namespace not to be found, got %v", err)
+	}
+
+	services, err := r.ListServices()
+	if err != nil {
+		t.Fatalf("did not expect ListServices() to fail: %v", err)
+	}
+	if len(services) != 2 {
+		t.Fatalf("expected 2 services, got %d", len(services))
+	}
+}
+
+func TestAllNamespacesForbidden(t *testing.T) {
+	mockClient.ForbidAll = true
+	defer func() {
+		mockClient.ForbidAll = false
+	}()
+
+	r := setupRegistry(AllNamespaces())
+	defer teardownRegistry()
+
+	register(r, "pod-1", &registry.Service{Name: "foo.service", Version: "1"})
+
+	if _, err := r.GetService("foo.service"); err != nil {
+		t.Fatalf("expected discovery to fall back to the pod namespace, got %v", err)
+	}
+
+	k := r.(*kregistry)
+	if k.all || len(k.discover) != 0 {
+		t.Fatal("expected discovery in all namespaces to be disabled")
+	}
+
+	w, err := r.Watch()
+	if err != nil {
+		t.Fatalf("did not expect Watch() to fail: %v", err)
+	}
+	w.Stop()
+}
+
+func TestSelector(t *testing.T) {
+	team := "payments"
+
+	r := setupRegistry(Selector(map[string]string{"team": team}))
+	defer teardownRegistry()
+
+	setupPod("pod-1").Metadata.Labels["team"] = &team
+	register(r, "pod-1", &registry.Service{Name: "foo.service", Version: "1"})
+	register(r, "pod-2", &registry.Service{Name: "bar.service", Version: "1"})
+
+	if _, err := r.GetService("foo.service"); err != nil {
+		t.Fatalf("did not expect GetService() to fail: %v", err)
+	}
+	if _, err := r.GetService("bar.service"); err != registry.ErrNotFound {
+		t.Fatalf("expected service without the selector labels not to be found, got %v", err)
+	}
+
+	services, err := r.ListServices()
+	if err != nil {
+		t.Fatalf("did not expect ListServices() to fail: %v", err)
+	}
+	if len(services) != 1 || services[0].Name != "foo.service" {
+		t.Fatalf("expected only foo.service, got %v", services)
+	}
+}
+
 func hasNodes(a, b []*registry.Node) bool {
 	found := 0
 	for _, nodeA := range a {
@@ -0,0 +1,47 @@
+package kubernetes
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/registry"
+)
+
+type namespacesKey struct{}
+
+type allNamespacesKey struct{}
+
+type selectorKey struct{}
+
+// Namespaces sets the namespaces services are discovered in, instead of only
+// the namespace of the pod. Services are always registered on the pod itself.
+func Namespaces(ns ...string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, namespacesKey{}, ns)
+	}
+}
+
+// AllNamespaces discovers services in every namespace. The service account
+// needs a cluster role binding to list and watch pods in all namespaces,
+// without one discovery falls back to the namespace of the pod.
+func AllNamespaces() registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, allNamespacesKey{}, true)
+	}
+}
+
+// Selector only discovers services on pods with the given labels e.g
+// map[string]string{"team": "payments"}
+func Selector(labels map[string]string) registry.Option {
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, selectorKey{}, labels)
+	}
+}
@@ -12,10 +12,73 @@ import (
 	"github.com/micro/go-plugins/registry/kubernetes/v2/client/watch"
 )
 
+// multiWatch merges the pod watches of several namespaces,
+// it stops when any of them stops
+type multiWatch struct {
+	watches []watch.Watch
+	results chan watch.Event
+	stop    chan bool
+	once    sync.Once
+}
+
+// ResultChan returns the results channel
+func (m *multiWatch) ResultChan() <-chan watch.Event {
+	return m.results
+}
+
+// Stop stops all the watches
+func (m *multiWatch) Stop() {
+	m.once.Do(func() {
+		close(m.stop)
+		for _, w := range m.watches {
+			w.Stop()
+		}
+	})
+}
+
+func newMultiWatch(watches []watch.Watch) watch.Watch {
+	m := &multiWatch{
+		watches: watches,
+		results: make(chan watch.Event),
+		stop:    make(chan bool),
+	}
+
+	var wg sync.WaitGroup
+
+	for _, w := range watches {
+		wg.Add(1)
+		go func(w watch.Watch) {
+			defer wg.Done()
+			defer m.Stop()
+
+			for e := range w.ResultChan() {
+				select {
+				case m.results <- e:
+				case <-m.stop:
+					return
+				}
+			}
+		}(w)
+	}
+
+	go func() {
+		wg.Wait()
+		close(m.results)
+	}()
+
+	return m
+}
+
+// podKey returns the cache key of a pod, names are only unique in a namespace
+func podKey(pod *client.Pod) string {
+	return pod.Metadata.Namespace + "/" + pod.Metadata.Name
+}
+
 type k8sWatcher struct {
 	registry *kregistry
 	watcher  watch.Watch
 	next     chan *registry.Result
+	stop     chan bool
 
 	sync.RWMutex
 	pods map[string]*client.Pod
@@ -23,7 +86,7 @@ type k8sWatcher struct {
 
 // build a cache of pods when the watcher starts.
 func (k *k8sWatcher) updateCache() ([]*registry.Result, error) {
-	podList, err := k.registry.client.ListPods(podSelector)
+	podList, err := k.registry.listPods(podSelector)
 	if err != nil {
 		return nil, err
 	}
@@ -33,15 +96,16 @@ func (k *k8sWatcher) updateCache() ([]*registry.Result, error) {
 
 	var results []*registry.Result
 
-	for _, pod := range podList.Items {
-		rslts := k.buildPodResults(&pod, nil)
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		rslts := k.buildPodResults(pod, nil)
 
 		for _, r := range rslts {
 			results = append(results, r)
 		}
 
 		k.Lock()
-		k.pods[pod.Metadata.Name] = &pod
+		k.pods[podKey(pod)] = pod
 		k.Unlock()
 	}
 
@@ -140,7 +204,7 @@ func (k *k8sWatcher) handleEvent(event watch.Event) {
 		// Pod was modified
 
 		k.RLock()
-		cache := k.pods[pod.Metadata.Name]
+		cache := k.pods[podKey(&pod)]
 		k.RUnlock()
 
 		// service could have been added, edited or removed.
@@ -158,11 +222,15 @@ func (k *k8sWatcher) handleEvent(event watch.Event) {
 			if pod.Status.Phase != podRunning {
 				result.Action = "delete"
 			}
-			k.next <- result
+			select {
+			case k.next <- result:
+			case <-k.stop:
+				return
+			}
 		}
 
 		k.Lock()
-		k.pods[pod.Metadata.Name] = &pod
+		k.pods[podKey(&pod)] = &pod
 		k.Unlock()
 		return
 
@@ -173,11 +241,15 @@ func (k *k8sWatcher) handleEvent(event watch.Event) {
 
 		for _, result := range results {
 			result.Action = "delete"
-			k.next <- result
+			select {
+			case k.next <- result:
+			case <-k.stop:
+				return
+			}
 		}
 
 		k.Lock()
-		delete(k.pods, pod.Metadata.Name)
+		delete(k.pods, podKey(&pod))
 		k.Unlock()
 		return
 	}
@@ -186,23 +258,24 @@ func (k *k8sWatcher) handleEvent(event watch.Event) {
 
 // Next will block until a new result comes in
 func (k *k8sWatcher) Next() (*registry.Result, error) {
-	r, ok := <-k.next
-	if !ok {
-		return nil, errors.New("result chan closed")
+	select {
+	case r := <-k.next:
+		return r, nil
+	case <-k.stop:
+		return nil, errors.New("watcher stopped")
 	}
-	return r, nil
 }
 
 // Stop will cancel any requests, and close channels
 func (k *k8sWatcher) Stop() {
-	k.watcher.Stop()
-
 	select {
-	case <-k.next:
+	case <-k.stop:
 		return
 	default:
-		close(k.next)
+		close(k.stop)
 	}
+
+	k.watcher.Stop()
 }
 
 func newWatcher(kr *kregistry, opts ...registry.WatchOption) (registry.Watcher, error) {
@@ -219,7 +292,7 @@ func newWatcher(kr *kregistry, opts ...registry.WatchOption) (registry.Watcher,
 	}
 
 	// Create watch request
-	watcher, err := kr.client.WatchPods(selector)
+	watcher, err := kr.watchPods(selector)
 	if err != nil {
 		return nil, err
 	}
@@ -228,6 +301,7 @@ func newWatcher(kr *kregistry, opts ...registry.WatchOption) (registry.Watcher,
 		registry: kr,
 		watcher:  watcher,
 		next:     make(chan *registry.Result),
+		stop:     make(chan bool),
 		pods:     make(map[string]*client.Pod),
 	}
 
@@ -0,0 +1,65 @@
+package prometheus
+
+import (
+	"time"
+
+	"github.com/micro/go-micro/v2/registry"
+)
+
+// Options of the exporter
+type Options struct {
+	// Labels added to every target group
+	Labels map[string]string
+	// Filter returns true for the services exported
+	Filter func(*registry.Service) bool
+	// Target returns the address scraped for a node
+	Target func(*registry.Service, *registry.Node) string
+	// Refresh is how often the file is rewritten without registry changes
+	Refresh time.Duration
+}
+
+// Option sets an option of the exporter
+type Option func(o *Options)
+
+// WithLabels adds static labels to every target group e.g env=prod
+func WithLabels(labels map[string]string) Option {
+	return func(o *Options) {
+		for k, v := range labels {
+			o.Labels[k] = v
+		}
+	}
+}
+
+// WithFilter only exports the services the filter returns true for
+func WithFilter(fn func(*registry.Service) bool) Option {
+	return func(o *Options) {
+		o.Filter = fn
+	}
+}
+
+// WithServices only exports the named services
+func WithServices(names ...string) Option {
+	return WithFilter(func(s *registry.Service) bool {
+		for _, name := range names {
+			if s.Name == name {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// WithTarget sets the address scraped for a node. The default is the
+// metrics_address node metadata if set, otherwise the node address.
+func WithTarget(fn func(*registry.Service, *registry.Node) string) Option {
+	return func(o *Options) {
+		o.Target = fn
+	}
+}
+
+// WithRefresh sets how often the file is rewritten without registry changes
+func WithRefresh(d time.Duration) Option {
+	return func(o *Options) {
+		o.Refresh = d
+	}
+}
@@ -0,0 +1,306 @@
+// Package prometheus exports the services in a registry as prometheus targets.
+//
+// The exporter serves the targets as prometheus HTTP service discovery or
+// writes them to a file for file_sd_configs, so every registered service
+// is scraped without static configuration.
+package prometheus
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/registry"
+)
+
+var (
+	// DefaultRefresh is how often the file is rewritten without registry changes
+	DefaultRefresh = 30 * time.Second
+	// MetricsAddressKey is the node metadata key of an address to scrape instead of the node address
+	MetricsAddressKey = "metrics_address"
+
+	invalid = regexp.MustCompile("[^a-zA-Z0-9_]")
+)
+
+// TargetGroup is a prometheus static config of targets sharing labels
+type TargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// Exporter exports the services in a registry as prometheus targets
+type Exporter struct {
+	registry registry.Registry
+	options  Options
+
+	sync.Mutex
+	exit chan bool
+	done chan bool
+}
+
+// label returns a valid prometheus label name
+func label(name string) string {
+	return "__meta_micro_" + invalid.ReplaceAllString(name, "_")
+}
+
+func defaultTarget(s *registry.Service, n *registry.Node) string {
+	if addr, ok := n.Metadata[MetricsAddressKey]; ok && len(addr) > 0 {
+		return addr
+	}
+	return n.Address
+}
+
+// group returns the target group of a node
+func (e *Exporter) group(s *registry.Service, n *registry.Node) TargetGroup {
+	labels := map[string]string{}
+	for k, v := range s.Metadata {
+		labels[label("service_metadata_"+k)] = v
+	}
+	for k, v := range n.Metadata {
+		labels[label("node_metadata_"+k)] = v
+	}
+	labels[label("service")] = s.Name
+	labels[label("version")] = s.Version
+	labels[label("node_id")] = n.Id
+	labels[label("node_address")] = n.Address
+
+	for k, v := range e.options.Labels {
+		labels[k] = v
+	}
+
+	return TargetGroup{
+		Targets: []string{e.options.Target(s, n)},
+		Labels:  labels,
+	}
+}
+
+// TargetGroups returns a target group for every node in the registry
+func (e *Exporter) TargetGroups() ([]TargetGroup, error) {
+	list, err := e.registry.ListServices()
+	if err != nil {
+		return nil, err
+	}
+
+	// list may not return nodes so get each service once
+	names := map[string]bool{}
+	for _, s := range list {
+		names[s.Name] = true
+	}
+
+	groups := []TargetGroup{}
+
+	for name := range names {
+		services, err := e.registry.GetService(name)
+		if err == registry.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range services {
+			if e.options.Filter != nil && !e.options.Filter(s) {
+				continue
+			}
+			for _, n := range s.Nodes {
+				groups = append(groups, e.group(s, n))
+			}
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		li, lj := groups[i].Labels, groups[j].Labels
+		if li[label("service")] != lj[label("service")] {
+			return li[label("service")] < lj[label("service")]
+		}
+		return li[label("node_id")] < lj[label("node_id")]
+	})
+
+	return groups, nil
+}
+
+// ServeHTTP serves the targets as prometheus HTTP service discovery
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	groups, err := e.TargetGroups()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	b, err := json.Marshal(groups)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// WriteFile writes the targets to a file_sd file. The file is replaced
+// atomically so prometheus never reads a partial file.
+func (e *Exporter) WriteFile(path string) error {
+	groups, err := e.TargetGroups()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+func (e *Exporter) write(path string) {
+	if err := e.WriteFile(path); err != nil && log.V(log.ErrorLevel, log.DefaultLogger) {
+		log.Errorf("Error writing prometheus targets to %s: %v", path, err)
+	}
+}
+
+// watch sends on the channel when the registry changes until exit is closed
+func (e *Exporter) watch(changes chan<- bool, exit chan bool) {
+	for {
+		w, err := e.registry.Watch()
+		if err != nil {
+			if log.V(log.ErrorLevel, log.DefaultLogger) {
+				log.Errorf("Error watching registry: %v", err)
+			}
+			select {
+			case <-exit:
+				return
+			case <-time.After(time.Second):
+				continue
+			}
+		}
+
+		stop := make(chan bool)
+		go func() {
+			select {
+			case <-exit:
+			case <-stop:
+			}
+			w.Stop()
+		}()
+
+		for {
+			if _, err := w.Next(); err != nil {
+				break
+			}
+			select {
+			case changes <- true:
+			default:
+			}
+		}
+
+		close(stop)
+
+		select {
+		case <-exit:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// Start writes the targets to the file_sd file and rewrites it when the
+// registry changes, or at the refresh interval, until Stop is called.
+func (e *Exporter) Start(path string) error {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.exit != nil {
+		return nil
+	}
+
+	if err := e.WriteFile(path); err != nil {
+		return err
+	}
+
+	exit := make(chan bool)
+	done := make(chan bool)
+	changes := make(chan bool, 1)
+
+	go e.watch(changes, exit)
+
+	go func() {
+		defer close(done)
+
+		t := time.NewTicker(e.options.Refresh)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-exit:
+				return
+			case <-changes:
+			case <-t.C:
+			}
+			e.write(path)
+		}
+	}()
+
+	e.exit = exit
+	e.done = done
+
+	return nil
+}
+
+// Stop stops rewriting the file_sd file
+func (e *Exporter) Stop() error {
+	e.Lock()
+	defer e.Unlock()
+
+	if e.exit == nil {
+		return nil
+	}
+
+	close(e.exit)
+	<-e.done
+	e.exit = nil
+	e.done = nil
+
+	return nil
+}
+
+// NewExporter returns an exporter of the services in the registry
+func NewExporter(r registry.Registry, opts ...Option) *Exporter {
+	options := Options{
+		Labels:  map[string]string{},
+		Target:  defaultTarget,
+		Refresh: DefaultRefresh,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &Exporter{
+		registry: r,
+		options:  options,
+	}
+}
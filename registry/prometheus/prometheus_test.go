@@ -0,0 +1,179 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/registry"
+	"github.com/micro/go-micro/v2/registry/memory"
+)
+
+func testRegistry(t *testing.T) registry.Registry {
+	r := memory.NewRegistry()
+
+	services := []*registry.Service{
+		{
+			Name:    "greeter",
+			Version: "v1",
+			Nodes: []*registry.Node{
+				{Id: "greeter-1", Address: "10.0.0.1:8080", Metadata: map[string]string{"metrics_address": "10.0.0.1:9100"}},
+				{Id: "greeter-2", Address: "10.0.0.2:8080", Metadata: map[string]string{"server.type": "grpc"}},
+			},
+		},
+		{
+			Name:    "users",
+			Version: "v2",
+			Nodes:   []*registry.Node{{Id: "users-1", Address: "10.0.0.3:8080"}},
+		},
+	}
+
+	for _, s := range services {
+		if err := r.Register(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return r
+}
+
+func TestTargetGroups(t *testing.T) {
+	e := NewExporter(testRegistry(t), WithLabels(map[string]string{"env": "test"}))
+
+	groups, err := e.TargetGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []TargetGroup{
+		{
+			Targets: []string{"10.0.0.1:9100"},
+			Labels: map[string]string{
+				"__meta_micro_service":                       "greeter",
+				"__meta_micro_version":                       "v1",
+				"__meta_micro_node_id":                       "greeter-1",
+				"__meta_micro_node_address":                  "10.0.0.1:8080",
+				"__meta_micro_node_metadata_metrics_address": "10.0.0.1:9100",
+				"env": "test",
+			},
+		},
+		{
+			Targets: []string{"10.0.0.2:8080"},
+			Labels: map[string]string{
+				"__meta_micro_service":                   "greeter",
+				"__meta_micro_version":                   "v1",
+				"__meta_micro_node_id":                   "greeter-2",
+				"__meta_micro_node_address":              "10.0.0.2:8080",
+				"__meta_micro_node_metadata_server_type": "grpc",
+				"env":                                    "test",
+			},
+		},
+		{
+			Targets: []string{"10.0.0.3:8080"},
+			Labels: map[string]string{
+				"__meta_micro_service":      "users",
+				"__meta_micro_version":      "v2",
+				"__meta_micro_node_id":      "users-1",
+				"__meta_micro_node_address": "10.0.0.3:8080",
+				"env":                       "test",
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(groups, expected) {
+		t.Fatalf("expected %+v got %+v", expected, groups)
+	}
+
+	e = NewExporter(testRegistry(t), WithServices("users"))
+	groups, err = e.TargetGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Targets[0] != "10.0.0.3:8080" {
+		t.Fatalf("expected only users got %+v", groups)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	e := NewExporter(testRegistry(t))
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected application/json got %s", ct)
+	}
+
+	var groups []TargetGroup
+	if err := json.Unmarshal(w.Body.Bytes(), &groups); err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 target groups got %d", len(groups))
+	}
+}
+
+func readFile(t *testing.T, path string) []TargetGroup {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var groups []TargetGroup
+	if err := json.Unmarshal(b, &groups); err != nil {
+		t.Fatal(err)
+	}
+	return groups
+}
+
+func TestStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "prometheus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "micro.json")
+
+	r := testRegistry(t)
+	e := NewExporter(r)
+	if err := e.Start(path); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Stop()
+
+	if groups := readFile(t, path); len(groups) != 3 {
+		t.Fatalf("expected 3 target groups got %d", len(groups))
+	}
+
+	// give the watcher time to start
+	time.Sleep(100 * time.Millisecond)
+
+	if err := r.Register(&registry.Service{
+		Name:    "payments",
+		Version: "v1",
+		Nodes:   []*registry.Node{{Id: "payments-1", Address: "10.0.0.4:8080"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(readFile(t, path)) != 4 {
+		if time.Now().After(deadline) {
+			t.Fatal("file not rewritten after the registry changed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// no temporary files are left behind
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected only the targets file got %d files", len(files))
+	}
+}
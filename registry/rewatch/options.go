@@ -0,0 +1,46 @@
+package rewatch
+
+import (
+	"context"
+	"time"
+
+	"github.com/micro/go-micro/v2/registry"
+)
+
+type rewatchKey struct{}
+
+// Options of watching again once a watch fails
+type Options struct {
+	// Backoff returns how long to wait before the attempt to watch again,
+	// attempts is zero the first time after a watch fails
+	Backoff func(attempts int) time.Duration
+}
+
+// Option sets an option of watching again
+type Option func(o *Options)
+
+// WithBackoff sets how long to wait before each attempt to watch again
+func WithBackoff(fn func(attempts int) time.Duration) Option {
+	return func(o *Options) {
+		o.Backoff = fn
+	}
+}
+
+// Rewatch has the watchers of the registry watch again once a watch fails, instead
+// of returning the error. The changes missed meanwhile are returned once it's
+// watching again, from the difference of the services watched before and after.
+func Rewatch(opts ...Option) registry.Option {
+	options := Options{
+		Backoff: DefaultBackoff,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return func(o *registry.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, rewatchKey{}, options)
+	}
+}
@@ -0,0 +1,365 @@
+// Package rewatch provides the registry option watching again once a watch fails,
+// it's implemented by the consul, etcd and kubernetes registries. The changes
+// missed while the registry couldn't be watched are returned from the difference
+// of the services before and after, so caches of the registry don't go stale.
+package rewatch
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/registry"
+	util "github.com/micro/go-micro/v2/util/registry"
+)
+
+var (
+	// DefaultMaxBackoff is the longest DefaultBackoff waits between attempts
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+// Watch returns a new watcher of the registry
+type Watch func() (registry.Watcher, error)
+
+// Snapshot returns the services of the registry watched
+type Snapshot func() ([]*registry.Service, error)
+
+type watcher struct {
+	opts     Options
+	watch    Watch
+	snapshot Snapshot
+
+	// the services as of the results returned by name and version
+	services map[string]*registry.Service
+	// results of the changes missed which aren't returned yet
+	results []*registry.Result
+
+	sync.Mutex
+	w    registry.Watcher
+	exit chan bool
+}
+
+// DefaultBackoff watches again at once, then waits 100ms doubling with each
+// attempt up to DefaultMaxBackoff
+func DefaultBackoff(attempts int) time.Duration {
+	if attempts == 0 {
+		return 0
+	}
+	if attempts > 20 {
+		return DefaultMaxBackoff
+	}
+	d := 100 * time.Millisecond << uint(attempts-1)
+	if d > DefaultMaxBackoff {
+		return DefaultMaxBackoff
+	}
+	return d
+}
+
+// NewWatcher returns the watcher of watch if the registry options don't have
+// Rewatch, otherwise a watcher which watches again once it fails. The snapshot
+// returns the services watched, it's taken after each watch starts.
+func NewWatcher(opts registry.Options, watch Watch, snapshot Snapshot) (registry.Watcher, error) {
+	var options Options
+	var ok bool
+	if opts.Context != nil {
+		options, ok = opts.Context.Value(rewatchKey{}).(Options)
+	}
+	if !ok {
+		return watch()
+	}
+
+	w, err := watch()
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := snapshot()
+	if err != nil {
+		w.Stop()
+		return nil, err
+	}
+
+	return &watcher{
+		opts:     options,
+		watch:    watch,
+		snapshot: snapshot,
+		services: index(services),
+		w:        w,
+		exit:     make(chan bool),
+	}, nil
+}
+
+// Services returns the snapshot of the services of the registry the options watch
+func Services(r registry.Registry, opts ...registry.WatchOption) Snapshot {
+	var wo registry.WatchOptions
+	for _, o := range opts {
+		o(&wo)
+	}
+
+	get := func(name string) ([]*registry.Service, error) {
+		services, err := r.GetService(name)
+		if err == registry.ErrNotFound {
+			return nil, nil
+		}
+		return services, err
+	}
+
+	return func() ([]*registry.Service, error) {
+		if len(wo.Service) > 0 {
+			return get(wo.Service)
+		}
+
+		list, err := r.ListServices()
+		if err != nil {
+			return nil, err
+		}
+
+		var services []*registry.Service
+		seen := make(map[string]bool, len(list))
+		for _, s := range list {
+			if seen[s.Name] {
+				continue
+			}
+			seen[s.Name] = true
+
+			rsp, err := get(s.Name)
+			if err != nil {
+				return nil, err
+			}
+			services = append(services, rsp...)
+		}
+		return services, nil
+	}
+}
+
+func key(s *registry.Service) string {
+	return s.Name + "/" + s.Version
+}
+
+// index returns the services by name and version
+func index(services []*registry.Service) map[string]*registry.Service {
+	m := make(map[string]*registry.Service, len(services))
+	for _, s := range services {
+		if p, ok := m[key(s)]; ok {
+			s = merge(p, s.Nodes)
+		}
+		m[key(s)] = s
+	}
+	return m
+}
+
+// nodes returns the nodes of the service by id
+func nodes(s *registry.Service) map[string]*registry.Node {
+	m := make(map[string]*registry.Node, len(s.Nodes))
+	for _, n := range s.Nodes {
+		m[n.Id] = n
+	}
+	return m
+}
+
+// merge returns a copy of the service with the nodes added, replacing those with the same id
+func merge(s *registry.Service, add []*registry.Node) *registry.Service {
+	ns := util.CopyService(s)
+	for _, n := range add {
+		replaced := false
+		for i, o := range ns.Nodes {
+			if o.Id == n.Id {
+				ns.Nodes[i] = n
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			ns.Nodes = append(ns.Nodes, n)
+		}
+	}
+	return ns
+}
+
+// remove returns a copy of the service without the nodes
+func remove(s *registry.Service, del []*registry.Node) *registry.Service {
+	ids := nodes(&registry.Service{Nodes: del})
+	ns := util.CopyService(s)
+	ns.Nodes = nil
+	for _, n := range s.Nodes {
+		if _, ok := ids[n.Id]; !ok {
+			ns.Nodes = append(ns.Nodes, n)
+		}
+	}
+	return ns
+}
+
+// diff returns the results which change the old services into the new ones
+func diff(old, services map[string]*registry.Service) []*registry.Result {
+	result := func(action string, s *registry.Service, nodes []*registry.Node) *registry.Result {
+		rs := util.CopyService(s)
+		rs.Nodes = nodes
+		return &registry.Result{Action: action, Service: rs}
+	}
+
+	var results []*registry.Result
+
+	for k, s := range services {
+		var oldNodes map[string]*registry.Node
+		if p, ok := old[k]; ok {
+			oldNodes = nodes(p)
+		}
+		newNodes := nodes(s)
+
+		var created, updated, deleted []*registry.Node
+		for _, n := range s.Nodes {
+			o, ok := oldNodes[n.Id]
+			switch {
+			case !ok:
+				created = append(created, n)
+			case !reflect.DeepEqual(o, n):
+				updated = append(updated, n)
+			}
+		}
+		for id, n := range oldNodes {
+			if _, ok := newNodes[id]; !ok {
+				deleted = append(deleted, n)
+			}
+		}
+
+		if len(created) > 0 {
+			results = append(results, result("create", s, created))
+		}
+		if len(updated) > 0 {
+			results = append(results, result("update", s, updated))
+		}
+		if len(deleted) > 0 {
+			results = append(results, result("delete", s, deleted))
+		}
+	}
+
+	for k, s := range old {
+		if _, ok := services[k]; !ok && len(s.Nodes) > 0 {
+			results = append(results, result("delete", s, s.Nodes))
+		}
+	}
+
+	return results
+}
+
+// apply changes the services by the result
+func (w *watcher) apply(r *registry.Result) {
+	if r.Service == nil {
+		return
+	}
+	k := key(r.Service)
+	s, ok := w.services[k]
+
+	switch r.Action {
+	case "create", "update":
+		if !ok {
+			w.services[k] = util.CopyService(r.Service)
+			return
+		}
+		w.services[k] = merge(s, r.Service.Nodes)
+	default:
+		// deleted, or the expired registrations of the consul and etcd watchers
+		if !ok {
+			return
+		}
+		if s = remove(s, r.Service.Nodes); len(s.Nodes) == 0 {
+			delete(w.services, k)
+			return
+		}
+		w.services[k] = s
+	}
+}
+
+// rewatch watches again until it succeeds or the watcher is stopped, the
+// results of the changes missed are queued
+func (w *watcher) rewatch() error {
+	for attempts := 0; ; attempts++ {
+		select {
+		case <-w.exit:
+			return registry.ErrWatcherStopped
+		case <-time.After(w.opts.Backoff(attempts)):
+		}
+
+		nw, err := w.watch()
+		if err != nil {
+			if log.V(log.WarnLevel, log.DefaultLogger) {
+				log.Warnf("Registry watch error, watching again: %v", err)
+			}
+			continue
+		}
+
+		services, err := w.snapshot()
+		if err != nil {
+			nw.Stop()
+			if log.V(log.WarnLevel, log.DefaultLogger) {
+				log.Warnf("Registry snapshot error, watching again: %v", err)
+			}
+			continue
+		}
+
+		w.Lock()
+		select {
+		case <-w.exit:
+			w.Unlock()
+			nw.Stop()
+			return registry.ErrWatcherStopped
+		default:
+		}
+		w.w = nw
+		w.Unlock()
+
+		current := index(services)
+		w.results = append(w.results, diff(w.services, current)...)
+		w.services = current
+		return nil
+	}
+}
+
+func (w *watcher) Next() (*registry.Result, error) {
+	for {
+		if len(w.results) > 0 {
+			r := w.results[0]
+			w.results = w.results[1:]
+			return r, nil
+		}
+
+		w.Lock()
+		cur := w.w
+		w.Unlock()
+
+		r, err := cur.Next()
+		if err == nil {
+			w.apply(r)
+			return r, nil
+		}
+
+		select {
+		case <-w.exit:
+			return nil, registry.ErrWatcherStopped
+		default:
+		}
+
+		if log.V(log.WarnLevel, log.DefaultLogger) {
+			log.Warnf("Registry watcher error, watching again: %v", err)
+		}
+		cur.Stop()
+
+		if err := w.rewatch(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (w *watcher) Stop() {
+	w.Lock()
+	defer w.Unlock()
+
+	select {
+	case <-w.exit:
+		return
+	default:
+		close(w.exit)
+		w.w.Stop()
+	}
+}
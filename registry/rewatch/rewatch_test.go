@@ -0,0 +1,158 @@
+package rewatch
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/registry"
+	"github.com/micro/go-micro/v2/registry/memory"
+)
+
+// testWatcher returns the results, then fails
+type testWatcher struct {
+	results chan *registry.Result
+	stopped chan bool
+}
+
+func (t *testWatcher) Next() (*registry.Result, error) {
+	select {
+	case r, ok := <-t.results:
+		if !ok {
+			return nil, errors.New("registry unavailable")
+		}
+		return r, nil
+	case <-t.stopped:
+		return nil, registry.ErrWatcherStopped
+	}
+}
+
+func (t *testWatcher) Stop() {
+	select {
+	case <-t.stopped:
+	default:
+		close(t.stopped)
+	}
+}
+
+func newTestWatcher() *testWatcher {
+	return &testWatcher{
+		results: make(chan *registry.Result, 10),
+		stopped: make(chan bool),
+	}
+}
+
+func service(name string, ids ...string) *registry.Service {
+	s := &registry.Service{Name: name, Version: "latest"}
+	for _, id := range ids {
+		// the memory registry only adds nodes with metadata to a service
+		s.Nodes = append(s.Nodes, &registry.Node{
+			Id:       id,
+			Address:  id + ":8080",
+			Metadata: map[string]string{"region": "eu"},
+		})
+	}
+	return s
+}
+
+func TestRewatch(t *testing.T) {
+	r := memory.NewRegistry()
+	for _, s := range []*registry.Service{service("foo", "foo-1", "foo-2"), service("bar", "bar-1")} {
+		if err := r.Register(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	watchers := make(chan *testWatcher, 2)
+	watch := func() (registry.Watcher, error) {
+		w := newTestWatcher()
+		watchers <- w
+		return w, nil
+	}
+
+	opts := registry.Options{}
+	Rewatch(WithBackoff(func(int) time.Duration { return 0 }))(&opts)
+
+	w, err := NewWatcher(opts, watch, Services(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if err := r.Register(service("baz", "baz-1")); err != nil {
+		t.Fatal(err)
+	}
+	baz, err := r.GetService("baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := <-watchers
+	first.results <- &registry.Result{Action: "create", Service: baz[0]}
+	if res, err := w.Next(); err != nil || res.Service.Name != "baz" {
+		t.Fatalf("expected the result of the watch got %v %v", res, err)
+	}
+
+	// changes missed while the registry is unavailable
+	if err := r.Register(service("foo", "foo-3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Deregister(service("foo", "foo-1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Deregister(service("bar", "bar-1")); err != nil {
+		t.Fatal(err)
+	}
+	close(first.results)
+
+	var results []string
+	for {
+		res, err := w.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range res.Service.Nodes {
+			results = append(results, res.Action+" "+n.Id)
+		}
+		if len(w.(*watcher).results) == 0 {
+			break
+		}
+	}
+	sort.Strings(results)
+
+	expected := []string{"create foo-3", "delete bar-1", "delete foo-1"}
+	if len(results) != len(expected) {
+		t.Fatalf("expected the results %v got %v", expected, results)
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Fatalf("expected the results %v got %v", expected, results)
+		}
+	}
+
+	// the results of the new watch follow
+	second := <-watchers
+	second.results <- &registry.Result{Action: "delete", Service: service("baz", "baz-1")}
+	if res, err := w.Next(); err != nil || res.Action != "delete" {
+		t.Fatalf("expected the result of the new watch got %v %v", res, err)
+	}
+
+	w.Stop()
+	if _, err := w.Next(); err != registry.ErrWatcherStopped {
+		t.Fatalf("expected the watcher to be stopped got %v", err)
+	}
+}
+
+func TestNoRewatch(t *testing.T) {
+	tw := newTestWatcher()
+	w, err := NewWatcher(registry.Options{}, func() (registry.Watcher, error) {
+		return tw, nil
+	}, Services(memory.NewRegistry()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w != tw {
+		t.Fatal("expected the watcher of the registry without the rewatch option")
+	}
+}
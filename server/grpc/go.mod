@@ -0,0 +1,9 @@
+module github.com/micro/go-plugins/server/grpc/v2
+
+go 1.13
+
+require (
+	github.com/micro/go-micro/v2 v2.9.1
+	golang.org/x/net v0.0.0-20200520182314-0ba52f642ac2
+	google.golang.org/grpc v1.26.0
+)
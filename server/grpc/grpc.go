@@ -0,0 +1,89 @@
+// Package grpc is the go-micro grpc server which also serves a http handler on its port
+package grpc
+
+import (
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/micro/go-micro/v2/config/cmd"
+	"github.com/micro/go-micro/v2/server"
+	gserver "github.com/micro/go-micro/v2/server/grpc"
+)
+
+type grpcServer struct {
+	server.Server
+
+	sync.Mutex
+	// mux splits the connections of the port while started with a http handler
+	mux *mux
+}
+
+func init() {
+	cmd.DefaultServers["grpc"] = NewServer
+}
+
+func (g *grpcServer) Start() error {
+	opts := g.Options()
+
+	var h http.Handler
+	if opts.Context != nil {
+		h, _ = opts.Context.Value(httpHandlerKey{}).(http.Handler)
+	}
+	if h == nil {
+		return g.Server.Start()
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	if g.mux != nil {
+		return nil
+	}
+
+	l, err := net.Listen("tcp", opts.Address)
+	if err != nil {
+		return err
+	}
+
+	m := newMux(l, opts.TLSConfig, h)
+
+	// the grpc server accepts the connections the mux gives it
+	if err := g.Server.Init(gserver.Listener(m.grpc)); err != nil {
+		m.Close()
+		return err
+	}
+	if err := g.Server.Start(); err != nil {
+		m.Close()
+		return err
+	}
+
+	go m.serve()
+	g.mux = m
+
+	return nil
+}
+
+func (g *grpcServer) Stop() error {
+	err := g.Server.Stop()
+
+	g.Lock()
+	defer g.Unlock()
+
+	if g.mux != nil {
+		if cerr := g.mux.Close(); err == nil {
+			err = cerr
+		}
+		g.mux = nil
+	}
+
+	return err
+}
+
+// NewServer returns the go-micro grpc server, the HTTPHandler option serves
+// a http handler on the same port
+func NewServer(opts ...server.Option) server.Server {
+	return &grpcServer{
+		Server: gserver.NewServer(opts...),
+	}
+}
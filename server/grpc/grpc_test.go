@@ -0,0 +1,138 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/micro/go-micro/v2/registry/memory"
+	"github.com/micro/go-micro/v2/server"
+	pb "github.com/micro/go-micro/v2/server/grpc/proto"
+	mls "github.com/micro/go-micro/v2/util/tls"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type testHandler struct{}
+
+func (t *testHandler) Call(ctx context.Context, req *pb.Request, rsp *pb.Response) error {
+	rsp.Msg = "Hello " + req.Name
+	return nil
+}
+
+func (t *testHandler) CallPcre(ctx context.Context, req *pb.Request, rsp *pb.Response) error {
+	return t.Call(ctx, req, rsp)
+}
+
+func (t *testHandler) CallPcreInvalid(ctx context.Context, req *pb.Request, rsp *pb.Response) error {
+	return t.Call(ctx, req, rsp)
+}
+
+func testServer(t *testing.T, opts ...server.Option) server.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+
+	opts = append(opts,
+		server.Name("foo"),
+		server.Address("127.0.0.1:0"),
+		server.Registry(memory.NewRegistry()),
+		HTTPHandler(mux),
+	)
+
+	srv := NewServer(opts...)
+	if err := pb.RegisterTestHandler(srv, &testHandler{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func testCall(t *testing.T, addr string, opt grpc.DialOption) {
+	cc, err := grpc.Dial(addr, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	rsp := pb.Response{}
+	if err := cc.Invoke(context.Background(), "/test.Test/Call", &pb.Request{Name: "John"}, &rsp); err != nil {
+		t.Fatalf("error calling server: %v", err)
+	}
+	if rsp.Msg != "Hello John" {
+		t.Fatalf("Got unexpected response %v", rsp.Msg)
+	}
+}
+
+func testGet(t *testing.T, c *http.Client, url, proto string) {
+	rsp, err := c.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+
+	b, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != proto {
+		t.Fatalf("Expected response %s got %s", proto, string(b))
+	}
+}
+
+func TestHTTPHandler(t *testing.T) {
+	srv := testServer(t)
+	defer srv.Stop()
+
+	addr := srv.Options().Address
+
+	testCall(t, addr, grpc.WithInsecure())
+
+	testGet(t, http.DefaultClient, fmt.Sprintf("http://%s", addr), "HTTP/1.1")
+
+	// http/2 without TLS
+	h2c := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+				return net.Dial(network, addr)
+			},
+		},
+	}
+	testGet(t, h2c, fmt.Sprintf("http://%s", addr), "HTTP/2.0")
+}
+
+func TestHTTPHandlerTLS(t *testing.T) {
+	cert, err := mls.Certificate("127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := testServer(t, server.TLSConfig(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}))
+	defer srv.Stop()
+
+	addr := srv.Options().Address
+	config := &tls.Config{InsecureSkipVerify: true}
+
+	testCall(t, addr, grpc.WithTransportCredentials(credentials.NewTLS(config)))
+
+	h1 := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: config},
+	}
+	testGet(t, h1, fmt.Sprintf("https://%s", addr), "HTTP/1.1")
+
+	h2 := &http.Client{
+		Transport: &http2.Transport{TLSClientConfig: config},
+	}
+	testGet(t, h2, fmt.Sprintf("https://%s", addr), "HTTP/2.0")
+}
@@ -0,0 +1,302 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+var (
+	// DefaultSniffTimeout is how long a connection has to complete the TLS
+	// handshake and send its first request before it's given to grpc
+	DefaultSniffTimeout = time.Second
+
+	errListenerClosed = errors.New("grpc: listener closed")
+)
+
+// listener is a net.Listener of the connections given to it by the mux
+type listener struct {
+	addr  net.Addr
+	conns chan net.Conn
+	once  sync.Once
+	done  chan struct{}
+}
+
+// conn is a connection of the mux which replays the bytes read to tell it apart
+type conn struct {
+	net.Conn
+	r io.Reader
+}
+
+// tlsConn is a conn which was decrypted by the mux, the http/2 server
+// requires the TLS connection state
+type tlsConn struct {
+	*conn
+	tc *tls.Conn
+}
+
+// ackFilter drops the ack of the settings sent by the mux from the frames read
+// by the http/2 server, it didn't send them
+type ackFilter struct {
+	r io.Reader
+	// bytes of the frame header and the payload to return before the next frame
+	header  []byte
+	payload int
+	done    bool
+}
+
+// mux splits the connections of the port between the grpc server and the http handler
+type mux struct {
+	net.Listener
+	tls *tls.Config
+
+	grpc *listener
+	http *listener
+
+	srv  *http.Server
+	h2   *http2.Server
+	once sync.Once
+}
+
+func newListener(addr net.Addr) *listener {
+	return &listener{
+		addr:  addr,
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		return nil, errListenerClosed
+	}
+}
+
+func (l *listener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+	})
+	return nil
+}
+
+func (l *listener) Addr() net.Addr {
+	return l.addr
+}
+
+// put gives the connection to the listener, it's closed if the listener is
+func (l *listener) put(c net.Conn) {
+	select {
+	case l.conns <- c:
+	case <-l.done:
+		c.Close()
+	}
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func (c *tlsConn) ConnectionState() tls.ConnectionState {
+	return c.tc.ConnectionState()
+}
+
+func (f *ackFilter) Read(b []byte) (int, error) {
+	for !f.done {
+		if len(f.header) > 0 {
+			n := copy(b, f.header)
+			f.header = f.header[n:]
+			return n, nil
+		}
+		if f.payload > 0 {
+			if len(b) > f.payload {
+				b = b[:f.payload]
+			}
+			n, err := f.r.Read(b)
+			f.payload -= n
+			return n, err
+		}
+
+		header := make([]byte, 9)
+		if _, err := io.ReadFull(f.r, header); err != nil {
+			return 0, err
+		}
+		if http2.FrameType(header[3]) == http2.FrameSettings && http2.Flags(header[4]).Has(http2.FlagSettingsAck) {
+			f.done = true
+			continue
+		}
+		f.header = header
+		f.payload = int(header[0])<<16 | int(header[1])<<8 | int(header[2])
+	}
+	return f.r.Read(b)
+}
+
+func newMux(l net.Listener, config *tls.Config, h http.Handler) *mux {
+	m := &mux{
+		Listener: l,
+		grpc:     newListener(l.Addr()),
+		http:     newListener(l.Addr()),
+		srv:      &http.Server{Handler: h},
+		h2:       &http2.Server{},
+	}
+
+	// shutting down the http server also shuts down the http/2 connections
+	http2.ConfigureServer(m.srv, m.h2)
+
+	if config != nil {
+		m.tls = config.Clone()
+		m.tls.NextProtos = append([]string{http2.NextProtoTLS, "http/1.1"}, config.NextProtos...)
+	}
+
+	return m
+}
+
+// serve accepts the connections of the port until the mux is closed
+func (m *mux) serve() {
+	go m.srv.Serve(m.http)
+
+	for {
+		c, err := m.Listener.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return
+		}
+		go m.route(c)
+	}
+}
+
+// route gives the connection to the grpc server or the http handler, http/2
+// connections are grpc if their first request has the grpc content type
+func (m *mux) route(c net.Conn) {
+	c.SetDeadline(time.Now().Add(DefaultSniffTimeout))
+
+	var tc *tls.Conn
+	if m.tls != nil {
+		tc = tls.Server(c, m.tls)
+		if err := tc.Handshake(); err != nil {
+			c.Close()
+			return
+		}
+		c = tc
+	}
+
+	var buf bytes.Buffer
+	r := io.TeeReader(c, &buf)
+
+	// ALPN tells http/2 apart with TLS, the client preface does without
+	h2 := tc == nil || tc.ConnectionState().NegotiatedProtocol == http2.NextProtoTLS
+	if h2 {
+		h2 = preface(r)
+	}
+
+	isGRPC := h2 && sniff(c, r)
+
+	c.SetDeadline(time.Time{})
+
+	rc := &conn{Conn: c, r: io.MultiReader(&buf, c)}
+	if h2 && !isGRPC {
+		rc.r = &ackFilter{r: rc.r, payload: len(http2.ClientPreface)}
+	}
+	var mc net.Conn = rc
+	if tc != nil {
+		mc = &tlsConn{conn: rc, tc: tc}
+	}
+
+	switch {
+	case isGRPC:
+		m.grpc.put(mc)
+	case h2:
+		m.h2.ServeConn(mc, &http2.ServeConnOpts{
+			BaseConfig: m.srv,
+			Handler:    m.srv.Handler,
+		})
+	default:
+		m.http.put(mc)
+	}
+}
+
+// preface reads the http/2 client preface, it stops at the first byte which differs
+func preface(r io.Reader) bool {
+	b := make([]byte, len(http2.ClientPreface))
+	for n := 0; n < len(b); {
+		i, err := r.Read(b[n:])
+		n += i
+		if !strings.HasPrefix(http2.ClientPreface, string(b[:n])) {
+			return false
+		}
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// sniff reads the frames after the preface up to the headers of the first
+// request, it's grpc if they can't be read in time. Clients may wait for the
+// settings of the server before the first request so empty settings are sent,
+// grpc ignores the ack but the http/2 server reads the frames through an ackFilter.
+func sniff(w io.Writer, r io.Reader) bool {
+	fr := http2.NewFramer(w, r)
+	if err := fr.WriteSettings(); err != nil {
+		return true
+	}
+
+	var contentType string
+	dec := hpack.NewDecoder(4096, func(f hpack.HeaderField) {
+		if f.Name == "content-type" {
+			contentType = f.Value
+		}
+	})
+
+	for {
+		f, err := fr.ReadFrame()
+		if err != nil {
+			return true
+		}
+
+		var block []byte
+		var end bool
+		switch f := f.(type) {
+		case *http2.HeadersFrame:
+			block, end = f.HeaderBlockFragment(), f.HeadersEnded()
+		case *http2.ContinuationFrame:
+			block, end = f.HeaderBlockFragment(), f.HeadersEnded()
+		default:
+			continue
+		}
+
+		if _, err := dec.Write(block); err != nil {
+			return true
+		}
+		if end {
+			return strings.HasPrefix(contentType, "application/grpc")
+		}
+	}
+}
+
+// Close closes the port and shuts down the http server
+func (m *mux) Close() error {
+	var err error
+	m.once.Do(func() {
+		err = m.Listener.Close()
+		m.grpc.Close()
+		m.http.Close()
+		m.srv.Shutdown(context.Background())
+	})
+	return err
+}
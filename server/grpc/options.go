@@ -0,0 +1,25 @@
+package grpc
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/micro/go-micro/v2/server"
+)
+
+type httpHandlerKey struct{}
+
+// HTTPHandler serves the handler on the port of the grpc server, connections
+// are told apart by the ALPN protocol with server.TLSConfig and by the
+// content type of the http/2 requests, grpc requests are handled by the grpc
+// server and any other http/1.1 or http/2 requests by the handler. The TLS
+// config must be set with server.TLSConfig rather than grpc.AuthTLS so the
+// connections are decrypted before they're told apart.
+func HTTPHandler(h http.Handler) server.Option {
+	return func(o *server.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, httpHandlerKey{}, h)
+	}
+}
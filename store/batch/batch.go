@@ -0,0 +1,66 @@
+// Package batch provides batch reads, writes and deletes over any store,
+// using the store's own batch operations when it has them
+package batch
+
+import (
+	"github.com/micro/go-micro/v2/store"
+)
+
+// Batcher is implemented by store plugins which read, write and delete
+// many records in fewer round trips e.g MGET, BatchWriteItem or multi row inserts
+type Batcher interface {
+	// ReadMany returns the records of the keys which exist
+	ReadMany(keys []string, opts ...store.ReadOption) ([]*store.Record, error)
+	// WriteMany writes every record
+	WriteMany(records []*store.Record, opts ...store.WriteOption) error
+	// DeleteMany deletes every key
+	DeleteMany(keys []string, opts ...store.DeleteOption) error
+}
+
+// Read returns the records of the keys which exist in s
+func Read(s store.Store, keys []string, opts ...store.ReadOption) ([]*store.Record, error) {
+	if b, ok := s.(Batcher); ok {
+		return b.ReadMany(keys, opts...)
+	}
+
+	var records []*store.Record
+	for _, k := range keys {
+		rs, err := s.Read(k, opts...)
+		if err == store.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, rs...)
+	}
+	return records, nil
+}
+
+// Write writes every record to s
+func Write(s store.Store, records []*store.Record, opts ...store.WriteOption) error {
+	if b, ok := s.(Batcher); ok {
+		return b.WriteMany(records, opts...)
+	}
+
+	for _, r := range records {
+		if err := s.Write(r, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete deletes every key from s
+func Delete(s store.Store, keys []string, opts ...store.DeleteOption) error {
+	if b, ok := s.(Batcher); ok {
+		return b.DeleteMany(keys, opts...)
+	}
+
+	for _, k := range keys {
+		if err := s.Delete(k, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package batch
+
+import (
+	"testing"
+
+	"github.com/micro/go-micro/v2/store"
+	"github.com/micro/go-micro/v2/store/memory"
+)
+
+type batchStore struct {
+	store.Store
+	calls int
+}
+
+func (b *batchStore) ReadMany(keys []string, opts ...store.ReadOption) ([]*store.Record, error) {
+	b.calls++
+	return nil, nil
+}
+
+func (b *batchStore) WriteMany(records []*store.Record, opts ...store.WriteOption) error {
+	b.calls++
+	return nil
+}
+
+func (b *batchStore) DeleteMany(keys []string, opts ...store.DeleteOption) error {
+	b.calls++
+	return nil
+}
+
+func TestFallback(t *testing.T) {
+	s := memory.NewStore()
+
+	records := []*store.Record{
+		{Key: "a", Value: []byte("1")},
+		{Key: "b", Value: []byte("2")},
+	}
+	if err := Write(s, records); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := Read(s, []string{"a", "b", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 {
+		t.Fatalf("expected 2 records got %d", len(rs))
+	}
+
+	if err := Delete(s, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if rs, _ := Read(s, []string{"a", "b"}); len(rs) != 0 {
+		t.Fatalf("expected records to be deleted got %d", len(rs))
+	}
+}
+
+func TestBatcher(t *testing.T) {
+	b := &batchStore{Store: memory.NewStore()}
+
+	Write(b, []*store.Record{{Key: "a"}, {Key: "b"}})
+	Read(b, []string{"a", "b"})
+	Delete(b, []string{"a", "b"})
+
+	if b.calls != 3 {
+		t.Fatalf("expected batch operations to be used once each got %d calls", b.calls)
+	}
+}
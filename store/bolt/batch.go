@@ -0,0 +1,98 @@
+package bolt
+
+import (
+	"encoding/json"
+
+	"github.com/micro/go-micro/v2/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+// ReadMany reads the records of the keys which exist in one transaction.
+// Keys missing locally aren't read through from the remote store.
+func (b *boltStore) ReadMany(keys []string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	database, table := b.getDB(options.Database, options.Table)
+
+	var records []*store.Record
+
+	err := b.handle().View(func(tx *bolt.Tx) error {
+		bk := bucket(tx, database, table)
+		if bk == nil {
+			return nil
+		}
+
+		for _, k := range keys {
+			v := bk.Get([]byte(k))
+			if v == nil {
+				continue
+			}
+
+			var r record
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			if !r.expired() {
+				records = append(records, r.record())
+			}
+		}
+		return nil
+	})
+
+	return records, err
+}
+
+// WriteMany writes the records atomically in one transaction
+func (b *boltStore) WriteMany(records []*store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	database, table := b.getDB(options.Database, options.Table)
+
+	return b.handle().Update(func(tx *bolt.Tx) error {
+		bk, err := createBucket(tx, database, table)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range records {
+			v, err := encode(r, options)
+			if err != nil {
+				return err
+			}
+			if err := bk.Put([]byte(r.Key), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteMany deletes the keys atomically in one transaction
+func (b *boltStore) DeleteMany(keys []string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	database, table := b.getDB(options.Database, options.Table)
+
+	return b.handle().Update(func(tx *bolt.Tx) error {
+		bk := bucket(tx, database, table)
+		if bk == nil {
+			return nil
+		}
+
+		for _, k := range keys {
+			if err := bk.Delete([]byte(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
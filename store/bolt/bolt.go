@@ -0,0 +1,434 @@
+// Package bolt implements an embedded store using bbolt
+package bolt
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// DefaultDatabase is the bucket used if no database is provided
+	DefaultDatabase = "micro"
+	// DefaultTable is the nested bucket used if no table is provided
+	DefaultTable = "micro"
+	// DefaultPath is the bolt database file
+	DefaultPath = filepath.Join(os.TempDir(), "micro", "store.db")
+	// DefaultCleanupInterval is how often expired records are deleted
+	DefaultCleanupInterval = time.Minute
+)
+
+type boltStore struct {
+	options store.Options
+	remote  store.Store
+
+	sync.RWMutex
+	db   *bolt.DB
+	exit chan bool
+}
+
+// record stored by us
+type record struct {
+	Key       string
+	Value     []byte
+	Metadata  map[string]interface{}
+	ExpiresAt time.Time
+}
+
+func (r *record) expired() bool {
+	return !r.ExpiresAt.IsZero() && time.Now().After(r.ExpiresAt)
+}
+
+func (r *record) record() *store.Record {
+	sr := &store.Record{
+		Key:      r.Key,
+		Value:    r.Value,
+		Metadata: r.Metadata,
+	}
+	if !r.ExpiresAt.IsZero() {
+		sr.Expiry = time.Until(r.ExpiresAt)
+	}
+	return sr
+}
+
+func (b *boltStore) getDB(database, table string) (string, string) {
+	if len(database) == 0 {
+		database = b.options.Database
+	}
+	if len(database) == 0 {
+		database = DefaultDatabase
+	}
+	if len(table) == 0 {
+		table = b.options.Table
+	}
+	if len(table) == 0 {
+		table = DefaultTable
+	}
+	return database, table
+}
+
+// bucket returns the table's bucket nested in the database's bucket, or nil if it doesn't exist
+func bucket(tx *bolt.Tx, database, table string) *bolt.Bucket {
+	db := tx.Bucket([]byte(database))
+	if db == nil {
+		return nil
+	}
+	return db.Bucket([]byte(table))
+}
+
+// createBucket returns the table's bucket, creating it if it doesn't exist
+func createBucket(tx *bolt.Tx, database, table string) (*bolt.Bucket, error) {
+	db, err := tx.CreateBucketIfNotExists([]byte(database))
+	if err != nil {
+		return nil, err
+	}
+	return db.CreateBucketIfNotExists([]byte(table))
+}
+
+func (b *boltStore) configure() error {
+	path := DefaultPath
+	interval := DefaultCleanupInterval
+	b.remote = nil
+
+	if ctx := b.options.Context; ctx != nil {
+		if v, ok := ctx.Value(pathKey{}).(string); ok && len(v) > 0 {
+			path = v
+		}
+		if v, ok := ctx.Value(cleanupIntervalKey{}).(time.Duration); ok && v > 0 {
+			interval = v
+		}
+		if v, ok := ctx.Value(remoteKey{}).(store.Store); ok {
+			b.remote = v
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	if b.db != nil {
+		close(b.exit)
+		b.db.Close()
+	}
+
+	// bolt only allows one process to open the file
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return err
+	}
+
+	b.db = db
+	b.exit = make(chan bool)
+
+	go b.cleanup(db, interval, b.exit)
+
+	return nil
+}
+
+// cleanup periodically deletes expired records from every bucket
+func (b *boltStore) cleanup(db *bolt.DB, interval time.Duration, exit chan bool) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-exit:
+			return
+		case <-t.C:
+		}
+
+		err := db.Update(func(tx *bolt.Tx) error {
+			return tx.ForEach(func(_ []byte, database *bolt.Bucket) error {
+				return database.ForEach(func(table, v []byte) error {
+					bk := database.Bucket(table)
+					if v != nil || bk == nil {
+						return nil
+					}
+
+					var expired [][]byte
+					bk.ForEach(func(k, v []byte) error {
+						var r record
+						if json.Unmarshal(v, &r) == nil && r.expired() {
+							expired = append(expired, k)
+						}
+						return nil
+					})
+
+					for _, k := range expired {
+						if err := bk.Delete(k); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+			})
+		})
+		if err != nil && log.V(log.ErrorLevel, log.DefaultLogger) {
+			log.Error("Error deleting expired records ", err)
+		}
+	}
+}
+
+// scan calls fn with every unexpired record with the prefix and suffix in key
+// order, skipping offset records and stopping after limit
+func scan(bk *bolt.Bucket, prefix, suffix string, limit, offset uint, fn func(*record) error) error {
+	if bk == nil {
+		return nil
+	}
+
+	var skipped, count uint
+	c := bk.Cursor()
+
+	for k, v := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = c.Next() {
+		if !strings.HasSuffix(string(k), suffix) {
+			continue
+		}
+
+		var r record
+		if err := json.Unmarshal(v, &r); err != nil {
+			return err
+		}
+		if r.expired() {
+			continue
+		}
+
+		if skipped < offset {
+			skipped++
+			continue
+		}
+
+		if err := fn(&r); err != nil {
+			return err
+		}
+
+		count++
+		if limit > 0 && count >= limit {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (b *boltStore) Init(opts ...store.Option) error {
+	for _, o := range opts {
+		o(&b.options)
+	}
+	return b.configure()
+}
+
+func (b *boltStore) Options() store.Options {
+	return b.options
+}
+
+func (b *boltStore) Close() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.db == nil {
+		return nil
+	}
+
+	close(b.exit)
+	err := b.db.Close()
+	b.db = nil
+	return err
+}
+
+func (b *boltStore) handle() *bolt.DB {
+	b.RLock()
+	defer b.RUnlock()
+	return b.db
+}
+
+func (b *boltStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	database, table := b.getDB(options.Database, options.Table)
+
+	var records []*store.Record
+
+	err := b.handle().View(func(tx *bolt.Tx) error {
+		bk := bucket(tx, database, table)
+
+		if options.Prefix || options.Suffix {
+			var prefix, suffix string
+			if options.Prefix {
+				prefix = key
+			}
+			if options.Suffix {
+				suffix = key
+			}
+			return scan(bk, prefix, suffix, options.Limit, options.Offset, func(r *record) error {
+				records = append(records, r.record())
+				return nil
+			})
+		}
+
+		if bk == nil {
+			return nil
+		}
+
+		v := bk.Get([]byte(key))
+		if v == nil {
+			return nil
+		}
+
+		var r record
+		if err := json.Unmarshal(v, &r); err != nil {
+			return err
+		}
+		if !r.expired() {
+			records = append(records, r.record())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) > 0 || options.Prefix || options.Suffix {
+		return records, nil
+	}
+
+	if b.remote == nil {
+		return nil, store.ErrNotFound
+	}
+
+	return b.readThrough(key, database, table)
+}
+
+// readThrough reads a missing record from the remote store and keeps a local copy
+func (b *boltStore) readThrough(key, database, table string) ([]*store.Record, error) {
+	records, err := b.remote.Read(key, store.ReadFrom(database, table))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range records {
+		if err := b.Write(r, store.WriteTo(database, table)); err != nil && log.V(log.WarnLevel, log.DefaultLogger) {
+			log.Warnf("Error caching record %s from remote store: %v", r.Key, err)
+		}
+	}
+
+	return records, nil
+}
+
+// encode returns the stored form of a record
+func encode(r *store.Record, options store.WriteOptions) ([]byte, error) {
+	rec := record{
+		Key:      r.Key,
+		Value:    r.Value,
+		Metadata: r.Metadata,
+	}
+
+	switch {
+	case !options.Expiry.IsZero():
+		rec.ExpiresAt = options.Expiry
+	case options.TTL > 0:
+		rec.ExpiresAt = time.Now().Add(options.TTL)
+	case r.Expiry > 0:
+		rec.ExpiresAt = time.Now().Add(r.Expiry)
+	}
+
+	return json.Marshal(rec)
+}
+
+func (b *boltStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	database, table := b.getDB(options.Database, options.Table)
+
+	v, err := encode(r, options)
+	if err != nil {
+		return err
+	}
+
+	return b.handle().Update(func(tx *bolt.Tx) error {
+		bk, err := createBucket(tx, database, table)
+		if err != nil {
+			return err
+		}
+		return bk.Put([]byte(r.Key), v)
+	})
+}
+
+func (b *boltStore) Delete(key string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	database, table := b.getDB(options.Database, options.Table)
+
+	return b.handle().Update(func(tx *bolt.Tx) error {
+		bk := bucket(tx, database, table)
+		if bk == nil {
+			return nil
+		}
+		return bk.Delete([]byte(key))
+	})
+}
+
+func (b *boltStore) List(opts ...store.ListOption) ([]string, error) {
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	database, table := b.getDB(options.Database, options.Table)
+
+	var keys []string
+
+	err := b.handle().View(func(tx *bolt.Tx) error {
+		return scan(bucket(tx, database, table), options.Prefix, options.Suffix, options.Limit, options.Offset, func(r *record) error {
+			keys = append(keys, r.Key)
+			return nil
+		})
+	})
+
+	return keys, err
+}
+
+func (b *boltStore) String() string {
+	return "bolt"
+}
+
+// NewStore returns a new micro Store backed by a local bolt database
+func NewStore(opts ...store.Option) store.Store {
+	options := store.Options{
+		Database: DefaultDatabase,
+		Table:    DefaultTable,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	b := &boltStore{
+		options: options,
+	}
+
+	if err := b.configure(); err != nil {
+		log.Fatal(err)
+	}
+
+	return b
+}
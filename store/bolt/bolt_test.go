@@ -0,0 +1,138 @@
+package bolt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+	"github.com/micro/go-micro/v2/store/memory"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := NewStore(Path(filepath.Join(dir, "store.db")), CleanupInterval(time.Millisecond*10))
+	defer s.Close()
+
+	records := []*store.Record{
+		{Key: "user/1", Value: []byte("alice")},
+		{Key: "user/2", Value: []byte("bob")},
+		{Key: "user/3", Value: []byte("carol")},
+		{Key: "session/1", Value: []byte("x"), Expiry: time.Millisecond},
+	}
+	for _, r := range records {
+		if err := s.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// tables are separate buckets
+	if err := s.Write(&store.Record{Key: "user/9"}, store.WriteTo("other", "users")); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond * 50)
+
+	if _, err := s.Read("session/1"); err != store.ErrNotFound {
+		t.Fatalf("expected expired record to be not found got %v", err)
+	}
+
+	rs, err := s.Read("user/", store.ReadPrefix(), store.ReadLimit(2), store.ReadOffset(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 || rs[0].Key != "user/2" || rs[1].Key != "user/3" {
+		t.Fatalf("expected user/2 and user/3 got %v", rs)
+	}
+
+	keys, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("expected 3 keys got %v", keys)
+	}
+
+	if err := s.Delete("user/1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Read("user/1"); err != store.ErrNotFound {
+		t.Fatalf("expected deleted record to be not found got %v", err)
+	}
+}
+
+func TestReadThrough(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	remote := memory.NewStore()
+	if err := remote.Write(&store.Record{Key: "config", Value: []byte("v1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewStore(Path(filepath.Join(dir, "store.db")), Remote(remote))
+	defer s.Close()
+
+	rs, err := s.Read("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rs[0].Value) != "v1" {
+		t.Fatalf("expected v1 got %s", rs[0].Value)
+	}
+
+	// the local copy is read once the remote no longer has it
+	remote.Delete("config")
+
+	rs, err = s.Read("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rs[0].Value) != "v1" {
+		t.Fatalf("expected cached v1 got %s", rs[0].Value)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := NewStore(Path(filepath.Join(dir, "store.db"))).(*boltStore)
+	defer s.Close()
+
+	records := []*store.Record{
+		{Key: "a", Value: []byte("1")},
+		{Key: "b", Value: []byte("2")},
+	}
+	if err := s.WriteMany(records); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := s.ReadMany([]string{"a", "b", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 {
+		t.Fatalf("expected 2 records got %d", len(rs))
+	}
+
+	if err := s.DeleteMany([]string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if rs, _ := s.ReadMany([]string{"a", "b"}); len(rs) != 0 {
+		t.Fatalf("expected records to be deleted got %d", len(rs))
+	}
+}
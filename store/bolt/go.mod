@@ -0,0 +1,8 @@
+module github.com/micro/go-plugins/store/bolt/v2
+
+go 1.13
+
+require (
+	github.com/micro/go-micro/v2 v2.9.1
+	go.etcd.io/bbolt v1.3.4
+)
@@ -0,0 +1,37 @@
+package bolt
+
+import (
+	"context"
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+)
+
+type pathKey struct{}
+type cleanupIntervalKey struct{}
+type remoteKey struct{}
+
+// Path sets the bolt database file
+func Path(p string) store.Option {
+	return setOption(pathKey{}, p)
+}
+
+// CleanupInterval sets how often expired records are deleted
+func CleanupInterval(d time.Duration) store.Option {
+	return setOption(cleanupIntervalKey{}, d)
+}
+
+// Remote reads records missing locally through from a remote store and
+// keeps a local copy, so they stay readable while the remote is unreachable
+func Remote(s store.Store) store.Option {
+	return setOption(remoteKey{}, s)
+}
+
+func setOption(k, v interface{}) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
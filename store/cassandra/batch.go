@@ -0,0 +1,90 @@
+package cassandra
+
+import (
+	"fmt"
+
+	"github.com/gocql/gocql"
+	"github.com/micro/go-micro/v2/store"
+	"github.com/pkg/errors"
+)
+
+// ReadMany reads the records of the keys which exist with a single IN query
+func (c *cassandraStore) ReadMany(keys []string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, err := c.table(options.Database, options.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := c.session.Query(fmt.Sprintf("SELECT key, value, metadata, TTL(value) FROM %s WHERE key IN ?;", name), keys).
+		Consistency(c.readConsistency).Iter()
+
+	var records []*store.Record
+	var k string
+	var value, md []byte
+	var ttl int
+
+	for iter.Scan(&k, &value, &md, &ttl) {
+		r, err := scan(k, value, md, ttl)
+		if err != nil {
+			iter.Close()
+			return nil, err
+		}
+		records = append(records, r)
+		value, md, ttl = nil, nil, 0
+	}
+
+	return records, iter.Close()
+}
+
+// WriteMany writes the records in an unlogged batch. Records of different
+// keys are in different partitions so the batch isn't atomic.
+func (c *cassandraStore) WriteMany(records []*store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, err := c.table(options.Database, options.Table)
+	if err != nil {
+		return err
+	}
+
+	b := c.session.NewBatch(gocql.UnloggedBatch)
+	b.SetConsistency(c.writeConsistency)
+
+	q := fmt.Sprintf("INSERT INTO %s (key, value, metadata) VALUES (?, ?, ?) USING TTL ?;", name)
+	for _, r := range records {
+		md, ttl, err := encode(r, options)
+		if err != nil {
+			return err
+		}
+		b.Query(q, r.Key, r.Value, md, ttl)
+	}
+
+	if err := c.session.ExecuteBatch(b); err != nil {
+		return errors.Wrap(err, "Couldn't write records")
+	}
+
+	return nil
+}
+
+// DeleteMany deletes the keys with a single IN query
+func (c *cassandraStore) DeleteMany(keys []string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, err := c.table(options.Database, options.Table)
+	if err != nil {
+		return err
+	}
+
+	return c.session.Query(fmt.Sprintf("DELETE FROM %s WHERE key IN ?;", name), keys).
+		Consistency(c.writeConsistency).Exec()
+}
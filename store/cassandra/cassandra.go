@@ -0,0 +1,402 @@
+// Package cassandra implements the store using cassandra or scylla
+package cassandra
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/store"
+	"github.com/pkg/errors"
+)
+
+var (
+	// DefaultDatabase is the keyspace used if no database is provided
+	DefaultDatabase = "micro"
+	// DefaultTable is the table used if no table is provided
+	DefaultTable = "micro"
+	// DefaultReplicationFactor of keyspaces created by the store
+	DefaultReplicationFactor = 1
+
+	re = regexp.MustCompile("[^a-zA-Z0-9]+")
+)
+
+type cassandraStore struct {
+	options store.Options
+	session *gocql.Session
+
+	readConsistency   gocql.Consistency
+	writeConsistency  gocql.Consistency
+	replicationFactor int
+
+	sync.RWMutex
+	// known tables
+	tables map[string]bool
+}
+
+// getDB returns the sanitised keyspace and table names
+func (c *cassandraStore) getDB(database, table string) (string, string) {
+	if len(database) == 0 {
+		database = c.options.Database
+	}
+	if len(database) == 0 {
+		database = DefaultDatabase
+	}
+
+	if len(table) == 0 {
+		table = c.options.Table
+	}
+	if len(table) == 0 {
+		table = DefaultTable
+	}
+
+	// names may only contain letters, numbers and underscores
+	database = re.ReplaceAllString(database, "_")
+	table = re.ReplaceAllString(table, "_")
+
+	return database, table
+}
+
+// table creates the keyspace and table if they don't exist and returns the qualified table name
+func (c *cassandraStore) table(database, table string) (string, error) {
+	database, table = c.getDB(database, table)
+	name := database + "." + table
+
+	c.RLock()
+	ok := c.tables[name]
+	c.RUnlock()
+	if ok {
+		return name, nil
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.tables[name] {
+		return name, nil
+	}
+
+	err := c.session.Query(fmt.Sprintf(
+		"CREATE KEYSPACE IF NOT EXISTS %s WITH replication = {'class': 'SimpleStrategy', 'replication_factor': %d};",
+		database, c.replicationFactor)).Exec()
+	if err != nil {
+		return "", errors.Wrap(err, "Couldn't create keyspace")
+	}
+
+	err = c.session.Query(fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (key text PRIMARY KEY, value blob, metadata blob);", name)).Exec()
+	if err != nil {
+		return "", errors.Wrap(err, "Couldn't create table")
+	}
+
+	c.tables[name] = true
+	return name, nil
+}
+
+func (c *cassandraStore) configure() error {
+	c.readConsistency = gocql.LocalQuorum
+	c.writeConsistency = gocql.LocalQuorum
+	c.replicationFactor = DefaultReplicationFactor
+
+	nodes := c.options.Nodes
+	if len(nodes) == 0 {
+		nodes = []string{"127.0.0.1"}
+	}
+
+	cluster := gocql.NewCluster(nodes...)
+
+	policy := gocql.RoundRobinHostPolicy()
+
+	if ctx := c.options.Context; ctx != nil {
+		if v, ok := ctx.Value(readConsistencyKey{}).(gocql.Consistency); ok {
+			c.readConsistency = v
+		}
+		if v, ok := ctx.Value(writeConsistencyKey{}).(gocql.Consistency); ok {
+			c.writeConsistency = v
+		}
+		if v, ok := ctx.Value(replicationFactorKey{}).(int); ok && v > 0 {
+			c.replicationFactor = v
+		}
+		if v, ok := ctx.Value(localDCKey{}).(string); ok && len(v) > 0 {
+			policy = gocql.DCAwareRoundRobinPolicy(v)
+		}
+		if v, ok := ctx.Value(authKey{}).(gocql.PasswordAuthenticator); ok {
+			cluster.Authenticator = v
+		}
+	}
+
+	// send queries straight to a replica of the key
+	cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(policy)
+
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return err
+	}
+
+	c.Lock()
+	if c.session != nil {
+		c.session.Close()
+	}
+	c.session = session
+	c.tables = make(map[string]bool)
+	c.Unlock()
+
+	// create the default table
+	_, err = c.table(c.options.Database, c.options.Table)
+	return err
+}
+
+func (c *cassandraStore) Init(opts ...store.Option) error {
+	for _, o := range opts {
+		o(&c.options)
+	}
+	return c.configure()
+}
+
+func (c *cassandraStore) Options() store.Options {
+	return c.options
+}
+
+func (c *cassandraStore) Close() error {
+	c.session.Close()
+	return nil
+}
+
+// scan decodes a row into a record
+func scan(key string, value, md []byte, ttl int) (*store.Record, error) {
+	r := &store.Record{
+		Key:   key,
+		Value: value,
+	}
+	if len(md) > 0 {
+		if err := json.Unmarshal(md, &r.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	if ttl > 0 {
+		r.Expiry = time.Duration(ttl) * time.Second
+	}
+	return r, nil
+}
+
+// match returns true if the key has the prefix and suffix
+func match(key, prefix, suffix string) bool {
+	return strings.HasPrefix(key, prefix) && strings.HasSuffix(key, suffix)
+}
+
+// paginate applies the offset and limit to keys sorted in order
+func paginate(n int, limit, offset uint) (int, int) {
+	start := int(offset)
+	if start > n {
+		start = n
+	}
+	end := n
+	if limit > 0 && start+int(limit) < end {
+		end = start + int(limit)
+	}
+	return start, end
+}
+
+func (c *cassandraStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, err := c.table(options.Database, options.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	var k string
+	var value, md []byte
+	var ttl int
+
+	if !options.Prefix && !options.Suffix {
+		q := c.session.Query(fmt.Sprintf("SELECT key, value, metadata, TTL(value) FROM %s WHERE key = ?;", name), key)
+		if err := q.Consistency(c.readConsistency).Scan(&k, &value, &md, &ttl); err != nil {
+			if err == gocql.ErrNotFound {
+				return nil, store.ErrNotFound
+			}
+			return nil, err
+		}
+
+		r, err := scan(k, value, md, ttl)
+		if err != nil {
+			return nil, err
+		}
+		return []*store.Record{r}, nil
+	}
+
+	var prefix, suffix string
+	if options.Prefix {
+		prefix = key
+	}
+	if options.Suffix {
+		suffix = key
+	}
+
+	// keys are partitioned by token so prefix and suffix reads scan the table
+	iter := c.session.Query(fmt.Sprintf("SELECT key, value, metadata, TTL(value) FROM %s;", name)).
+		Consistency(c.readConsistency).Iter()
+
+	var records []*store.Record
+	for iter.Scan(&k, &value, &md, &ttl) {
+		if !match(k, prefix, suffix) {
+			continue
+		}
+		r, err := scan(k, value, md, ttl)
+		if err != nil {
+			iter.Close()
+			return nil, err
+		}
+		records = append(records, r)
+		value, md, ttl = nil, nil, 0
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Key < records[j].Key
+	})
+
+	start, end := paginate(len(records), options.Limit, options.Offset)
+	return records[start:end], nil
+}
+
+// encode returns the metadata and ttl in seconds of a record, zero means no ttl
+func encode(r *store.Record, options store.WriteOptions) ([]byte, int, error) {
+	var md []byte
+	if len(r.Metadata) > 0 {
+		b, err := json.Marshal(r.Metadata)
+		if err != nil {
+			return nil, 0, err
+		}
+		md = b
+	}
+
+	var expiry time.Duration
+	switch {
+	case !options.Expiry.IsZero():
+		expiry = time.Until(options.Expiry)
+	case options.TTL > 0:
+		expiry = options.TTL
+	case r.Expiry > 0:
+		expiry = r.Expiry
+	}
+
+	// cassandra ttls are in seconds
+	ttl := int((expiry + time.Second - 1) / time.Second)
+	if ttl < 0 {
+		ttl = 1
+	}
+
+	return md, ttl, nil
+}
+
+func (c *cassandraStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, err := c.table(options.Database, options.Table)
+	if err != nil {
+		return err
+	}
+
+	md, ttl, err := encode(r, options)
+	if err != nil {
+		return err
+	}
+
+	q := c.session.Query(fmt.Sprintf("INSERT INTO %s (key, value, metadata) VALUES (?, ?, ?) USING TTL ?;", name), r.Key, r.Value, md, ttl)
+	if err := q.Consistency(c.writeConsistency).Exec(); err != nil {
+		return errors.Wrap(err, "Couldn't write record "+r.Key)
+	}
+
+	return nil
+}
+
+func (c *cassandraStore) Delete(key string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, err := c.table(options.Database, options.Table)
+	if err != nil {
+		return err
+	}
+
+	return c.session.Query(fmt.Sprintf("DELETE FROM %s WHERE key = ?;", name), key).
+		Consistency(c.writeConsistency).Exec()
+}
+
+func (c *cassandraStore) List(opts ...store.ListOption) ([]string, error) {
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, err := c.table(options.Database, options.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := c.session.Query(fmt.Sprintf("SELECT key FROM %s;", name)).
+		Consistency(c.readConsistency).Iter()
+
+	var keys []string
+	var k string
+	for iter.Scan(&k) {
+		if match(k, options.Prefix, options.Suffix) {
+			keys = append(keys, k)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+
+	start, end := paginate(len(keys), options.Limit, options.Offset)
+	return keys[start:end], nil
+}
+
+func (c *cassandraStore) String() string {
+	return "cassandra"
+}
+
+// NewStore returns a new micro Store backed by cassandra
+func NewStore(opts ...store.Option) store.Store {
+	options := store.Options{
+		Database: DefaultDatabase,
+		Table:    DefaultTable,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	c := &cassandraStore{
+		options: options,
+		tables:  make(map[string]bool),
+	}
+
+	// best-effort configure the store
+	if err := c.configure(); err != nil {
+		if log.V(log.ErrorLevel, log.DefaultLogger) {
+			log.Error("Error configuring store ", err)
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,35 @@
+package cassandra
+
+import (
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	testData := []struct {
+		n             int
+		limit, offset uint
+		start, end    int
+	}{
+		{10, 0, 0, 0, 10},
+		{10, 3, 0, 0, 3},
+		{10, 3, 8, 8, 10},
+		{10, 0, 4, 4, 10},
+		{10, 5, 20, 10, 10},
+	}
+
+	for _, d := range testData {
+		start, end := paginate(d.n, d.limit, d.offset)
+		if start != d.start || end != d.end {
+			t.Fatalf("paginate(%d, %d, %d) expected [%d:%d] got [%d:%d]", d.n, d.limit, d.offset, d.start, d.end, start, end)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	if !match("user/1.json", "user/", ".json") {
+		t.Fatal("expected match")
+	}
+	if match("session/1", "user/", "") {
+		t.Fatal("expected no match")
+	}
+}
@@ -0,0 +1,9 @@
+module github.com/micro/go-plugins/store/cassandra/v2
+
+go 1.13
+
+require (
+	github.com/gocql/gocql v0.0.0-20200526081602-cd04bd7f22a7
+	github.com/micro/go-micro/v2 v2.9.1
+	github.com/pkg/errors v0.9.1
+)
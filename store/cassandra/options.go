@@ -0,0 +1,51 @@
+package cassandra
+
+import (
+	"context"
+
+	"github.com/gocql/gocql"
+	"github.com/micro/go-micro/v2/store"
+)
+
+type readConsistencyKey struct{}
+type writeConsistencyKey struct{}
+type replicationFactorKey struct{}
+type localDCKey struct{}
+type authKey struct{}
+
+// ReadConsistency sets the consistency level of reads, the default is LocalQuorum
+func ReadConsistency(c gocql.Consistency) store.Option {
+	return setOption(readConsistencyKey{}, c)
+}
+
+// WriteConsistency sets the consistency level of writes and deletes, the default is LocalQuorum
+func WriteConsistency(c gocql.Consistency) store.Option {
+	return setOption(writeConsistencyKey{}, c)
+}
+
+// ReplicationFactor sets the replication factor of keyspaces created by the store
+func ReplicationFactor(n int) store.Option {
+	return setOption(replicationFactorKey{}, n)
+}
+
+// LocalDC routes queries to replicas in the datacenter before falling back to others
+func LocalDC(dc string) store.Option {
+	return setOption(localDCKey{}, dc)
+}
+
+// Auth sets the username and password used to connect
+func Auth(username, password string) store.Option {
+	return setOption(authKey{}, gocql.PasswordAuthenticator{
+		Username: username,
+		Password: password,
+	})
+}
+
+func setOption(k, v interface{}) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
@@ -0,0 +1,187 @@
+package dynamodb
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/micro/go-micro/v2/store"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxBatchGet is the most keys BatchGetItem accepts
+	maxBatchGet = 100
+	// maxBatchWrite is the most requests BatchWriteItem accepts
+	maxBatchWrite = 25
+)
+
+// DefaultBatchRetries is how many times unprocessed items of a batch are retried
+var DefaultBatchRetries = 5
+
+// backoff waits before retrying unprocessed items of a batch
+func backoff(attempt int) {
+	time.Sleep(time.Duration(50<<uint(attempt)) * time.Millisecond)
+}
+
+func (d *dynamoStore) key(table, key string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		partitionKey: {S: aws.String(table)},
+		sortKey:      {S: aws.String(key)},
+	}
+}
+
+// ReadMany reads the records of the keys which exist with BatchGetItem
+func (d *dynamoStore) ReadMany(keys []string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	database, table := d.getDB(options.Database, options.Table)
+	if err := d.table(database); err != nil {
+		return nil, err
+	}
+
+	var records []*store.Record
+
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > maxBatchGet {
+			n = maxBatchGet
+		}
+
+		// BatchGetItem rejects duplicate keys
+		seen := make(map[string]bool, n)
+		ka := &dynamodb.KeysAndAttributes{}
+		for _, k := range keys[:n] {
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			ka.Keys = append(ka.Keys, d.key(table, k))
+		}
+		keys = keys[n:]
+
+		req := map[string]*dynamodb.KeysAndAttributes{database: ka}
+
+		for attempt := 0; len(req) > 0; attempt++ {
+			if attempt > DefaultBatchRetries {
+				return records, errors.New("Couldn't read records: too many unprocessed keys")
+			}
+			if attempt > 0 {
+				backoff(attempt)
+			}
+
+			rsp, err := d.client.BatchGetItem(&dynamodb.BatchGetItemInput{RequestItems: req})
+			if err != nil {
+				return records, err
+			}
+
+			for _, av := range rsp.Responses[database] {
+				var i item
+				if err := dynamodbattribute.UnmarshalMap(av, &i); err != nil {
+					return records, err
+				}
+				if !i.expired() {
+					records = append(records, i.record())
+				}
+			}
+
+			req = rsp.UnprocessedKeys
+		}
+	}
+
+	return records, nil
+}
+
+// write sends the write requests with BatchWriteItem, retrying unprocessed ones
+func (d *dynamoStore) write(database string, reqs []*dynamodb.WriteRequest) error {
+	for len(reqs) > 0 {
+		n := len(reqs)
+		if n > maxBatchWrite {
+			n = maxBatchWrite
+		}
+
+		req := map[string][]*dynamodb.WriteRequest{database: reqs[:n]}
+		reqs = reqs[n:]
+
+		for attempt := 0; len(req) > 0; attempt++ {
+			if attempt > DefaultBatchRetries {
+				return errors.New("Couldn't write records: too many unprocessed items")
+			}
+			if attempt > 0 {
+				backoff(attempt)
+			}
+
+			rsp, err := d.client.BatchWriteItem(&dynamodb.BatchWriteItemInput{RequestItems: req})
+			if err != nil {
+				return err
+			}
+
+			req = rsp.UnprocessedItems
+		}
+	}
+
+	return nil
+}
+
+// WriteMany writes the records with BatchWriteItem. Batches aren't atomic,
+// records already written stay written if an error is returned.
+func (d *dynamoStore) WriteMany(records []*store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	database, table := d.getDB(options.Database, options.Table)
+	if err := d.table(database); err != nil {
+		return err
+	}
+
+	// a batch can't contain the same key twice, the last record wins
+	last := make(map[string]int, len(records))
+	for i, r := range records {
+		last[r.Key] = i
+	}
+
+	reqs := make([]*dynamodb.WriteRequest, 0, len(last))
+	for i, r := range records {
+		if last[r.Key] != i {
+			continue
+		}
+		av, err := newItem(table, r, options)
+		if err != nil {
+			return err
+		}
+		reqs = append(reqs, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: av}})
+	}
+
+	return d.write(database, reqs)
+}
+
+// DeleteMany deletes the keys with BatchWriteItem
+func (d *dynamoStore) DeleteMany(keys []string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	database, table := d.getDB(options.Database, options.Table)
+	if err := d.table(database); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(keys))
+	reqs := make([]*dynamodb.WriteRequest, 0, len(keys))
+	for _, k := range keys {
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		reqs = append(reqs, &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: d.key(table, k)}})
+	}
+
+	return d.write(database, reqs)
+}
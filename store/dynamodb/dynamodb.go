@@ -0,0 +1,443 @@
+// Package dynamodb implements the store using dynamodb
+package dynamodb
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/store"
+	"github.com/pkg/errors"
+)
+
+var (
+	// DefaultDatabase is the dynamodb table used if no database is provided
+	DefaultDatabase = "micro"
+	// DefaultTable is the partition used if no table is provided
+	DefaultTable = "micro"
+
+	re = regexp.MustCompile("[^a-zA-Z0-9_.-]+")
+)
+
+// Items are keyed by the store table as the partition key and the record
+// key as the sort key, so prefix reads are queries on a single partition.
+const (
+	partitionKey = "table"
+	sortKey      = "key"
+	ttlAttribute = "expiry"
+)
+
+type dynamoStore struct {
+	options store.Options
+	client  dynamodbiface.DynamoDBAPI
+
+	throughput *throughput
+
+	sync.RWMutex
+	// tables known to exist
+	tables map[string]bool
+}
+
+// getDB returns the dynamodb table and partition
+func (d *dynamoStore) getDB(database, table string) (string, string) {
+	if len(database) == 0 {
+		database = d.options.Database
+	}
+	if len(database) == 0 {
+		database = DefaultDatabase
+	}
+
+	if len(table) == 0 {
+		table = d.options.Table
+	}
+	if len(table) == 0 {
+		table = DefaultTable
+	}
+
+	// table names may only contain letters, numbers, underscores, dashes and dots
+	return re.ReplaceAllString(database, "_"), table
+}
+
+// table creates the dynamodb table with a ttl if it doesn't exist
+func (d *dynamoStore) table(database string) error {
+	d.RLock()
+	ok := d.tables[database]
+	d.RUnlock()
+	if ok {
+		return nil
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	if d.tables[database] {
+		return nil
+	}
+
+	_, err := d.client.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(database)})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeResourceNotFoundException {
+		err = d.createTable(database)
+	}
+	if err != nil {
+		return err
+	}
+
+	d.tables[database] = true
+	return nil
+}
+
+func (d *dynamoStore) createTable(name string) error {
+	in := &dynamodb.CreateTableInput{
+		TableName: aws.String(name),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String(partitionKey), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+			{AttributeName: aws.String(sortKey), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String(partitionKey), KeyType: aws.String(dynamodb.KeyTypeHash)},
+			{AttributeName: aws.String(sortKey), KeyType: aws.String(dynamodb.KeyTypeRange)},
+		},
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+	}
+
+	if t := d.throughput; t != nil {
+		in.BillingMode = aws.String(dynamodb.BillingModeProvisioned)
+		in.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(t.read),
+			WriteCapacityUnits: aws.Int64(t.write),
+		}
+	}
+
+	if _, err := d.client.CreateTable(in); err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != dynamodb.ErrCodeResourceInUseException {
+			return errors.Wrap(err, "Couldn't create table")
+		}
+	}
+
+	if err := d.client.WaitUntilTableExists(&dynamodb.DescribeTableInput{TableName: aws.String(name)}); err != nil {
+		return err
+	}
+
+	// dynamodb deletes expired items in the background, reads filter them until then
+	_, err := d.client.UpdateTimeToLive(&dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(name),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String(ttlAttribute),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "Couldn't enable ttl")
+	}
+
+	return nil
+}
+
+func (d *dynamoStore) configure() error {
+	d.throughput = nil
+
+	var client dynamodbiface.DynamoDBAPI
+	if ctx := d.options.Context; ctx != nil {
+		if c, ok := ctx.Value(clientKey{}).(dynamodbiface.DynamoDBAPI); ok {
+			client = c
+		}
+		if t, ok := ctx.Value(throughputKey{}).(throughput); ok {
+			d.throughput = &t
+		}
+	}
+
+	if client == nil {
+		cfg := aws.NewConfig()
+		// a node overrides the endpoint e.g for dynamodb local
+		if len(d.options.Nodes) > 0 {
+			cfg = cfg.WithEndpoint(d.options.Nodes[0])
+		}
+
+		sess, err := session.NewSession(cfg)
+		if err != nil {
+			return err
+		}
+		client = dynamodb.New(sess)
+	}
+
+	d.Lock()
+	d.client = client
+	d.tables = make(map[string]bool)
+	d.Unlock()
+
+	database, _ := d.getDB(d.options.Database, d.options.Table)
+	return d.table(database)
+}
+
+// item is a record as stored in dynamodb
+type item struct {
+	Table    string                 `dynamodbav:"table"`
+	Key      string                 `dynamodbav:"key"`
+	Value    []byte                 `dynamodbav:"value,omitempty"`
+	Metadata map[string]interface{} `dynamodbav:"metadata,omitempty"`
+	Expiry   int64                  `dynamodbav:"expiry,omitempty"`
+}
+
+func (i *item) expired() bool {
+	return i.Expiry > 0 && i.Expiry <= time.Now().Unix()
+}
+
+func (i *item) record() *store.Record {
+	r := &store.Record{
+		Key:      i.Key,
+		Value:    i.Value,
+		Metadata: i.Metadata,
+	}
+	if i.Expiry > 0 {
+		r.Expiry = time.Until(time.Unix(i.Expiry, 0))
+	}
+	return r
+}
+
+// query calls fn with every unexpired item of the partition with the prefix and suffix
+// in key order, skipping offset items and stopping after limit
+func (d *dynamoStore) query(database, table, prefix, suffix string, limit, offset uint, projection bool, fn func(*item)) error {
+	in := &dynamodb.QueryInput{
+		TableName:              aws.String(database),
+		KeyConditionExpression: aws.String("#t = :t"),
+		ExpressionAttributeNames: map[string]*string{
+			"#t": aws.String(partitionKey),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":t": {S: aws.String(table)},
+		},
+	}
+
+	if len(prefix) > 0 {
+		in.KeyConditionExpression = aws.String("#t = :t AND begins_with(#k, :p)")
+		in.ExpressionAttributeNames["#k"] = aws.String(sortKey)
+		in.ExpressionAttributeValues[":p"] = &dynamodb.AttributeValue{S: aws.String(prefix)}
+	}
+
+	if projection {
+		in.ProjectionExpression = aws.String("#t, #k, #e")
+		in.ExpressionAttributeNames["#k"] = aws.String(sortKey)
+		in.ExpressionAttributeNames["#e"] = aws.String(ttlAttribute)
+	}
+
+	var skipped, count uint
+	var ferr error
+
+	err := d.client.QueryPages(in, func(page *dynamodb.QueryOutput, last bool) bool {
+		for _, av := range page.Items {
+			var i item
+			if err := dynamodbattribute.UnmarshalMap(av, &i); err != nil {
+				ferr = err
+				return false
+			}
+			if i.expired() || !strings.HasSuffix(i.Key, suffix) {
+				continue
+			}
+			if skipped < offset {
+				skipped++
+				continue
+			}
+			fn(&i)
+			count++
+			if limit > 0 && count >= limit {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return ferr
+}
+
+func (d *dynamoStore) Init(opts ...store.Option) error {
+	for _, o := range opts {
+		o(&d.options)
+	}
+	return d.configure()
+}
+
+func (d *dynamoStore) Options() store.Options {
+	return d.options
+}
+
+func (d *dynamoStore) Close() error {
+	return nil
+}
+
+func (d *dynamoStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	database, table := d.getDB(options.Database, options.Table)
+	if err := d.table(database); err != nil {
+		return nil, err
+	}
+
+	if !options.Prefix && !options.Suffix {
+		rsp, err := d.client.GetItem(&dynamodb.GetItemInput{
+			TableName: aws.String(database),
+			Key: map[string]*dynamodb.AttributeValue{
+				partitionKey: {S: aws.String(table)},
+				sortKey:      {S: aws.String(key)},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if rsp.Item == nil {
+			return nil, store.ErrNotFound
+		}
+
+		var i item
+		if err := dynamodbattribute.UnmarshalMap(rsp.Item, &i); err != nil {
+			return nil, err
+		}
+		if i.expired() {
+			return nil, store.ErrNotFound
+		}
+		return []*store.Record{i.record()}, nil
+	}
+
+	var prefix, suffix string
+	if options.Prefix {
+		prefix = key
+	}
+	if options.Suffix {
+		suffix = key
+	}
+
+	var records []*store.Record
+	err := d.query(database, table, prefix, suffix, options.Limit, options.Offset, false, func(i *item) {
+		records = append(records, i.record())
+	})
+	return records, err
+}
+
+// newItem returns the attributes of a record in the table partition
+func newItem(table string, r *store.Record, options store.WriteOptions) (map[string]*dynamodb.AttributeValue, error) {
+	i := item{
+		Table:    table,
+		Key:      r.Key,
+		Value:    r.Value,
+		Metadata: r.Metadata,
+	}
+
+	switch {
+	case !options.Expiry.IsZero():
+		i.Expiry = options.Expiry.Unix()
+	case options.TTL > 0:
+		i.Expiry = time.Now().Add(options.TTL).Unix()
+	case r.Expiry > 0:
+		i.Expiry = time.Now().Add(r.Expiry).Unix()
+	}
+
+	return dynamodbattribute.MarshalMap(i)
+}
+
+func (d *dynamoStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	database, table := d.getDB(options.Database, options.Table)
+	if err := d.table(database); err != nil {
+		return err
+	}
+
+	av, err := newItem(table, r, options)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.client.PutItem(&dynamodb.PutItemInput{
+		TableName: aws.String(database),
+		Item:      av,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Couldn't write record "+r.Key)
+	}
+
+	return nil
+}
+
+func (d *dynamoStore) Delete(key string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	database, table := d.getDB(options.Database, options.Table)
+	if err := d.table(database); err != nil {
+		return err
+	}
+
+	_, err := d.client.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(database),
+		Key: map[string]*dynamodb.AttributeValue{
+			partitionKey: {S: aws.String(table)},
+			sortKey:      {S: aws.String(key)},
+		},
+	})
+	return err
+}
+
+func (d *dynamoStore) List(opts ...store.ListOption) ([]string, error) {
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	database, table := d.getDB(options.Database, options.Table)
+	if err := d.table(database); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	err := d.query(database, table, options.Prefix, options.Suffix, options.Limit, options.Offset, true, func(i *item) {
+		keys = append(keys, i.Key)
+	})
+	return keys, err
+}
+
+func (d *dynamoStore) String() string {
+	return "dynamodb"
+}
+
+// NewStore returns a new micro Store backed by dynamodb
+func NewStore(opts ...store.Option) store.Store {
+	options := store.Options{
+		Database: DefaultDatabase,
+		Table:    DefaultTable,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	d := &dynamoStore{
+		options: options,
+		tables:  make(map[string]bool),
+	}
+
+	// best-effort configure the store
+	if err := d.configure(); err != nil {
+		if log.V(log.ErrorLevel, log.DefaultLogger) {
+			log.Error("Error configuring store ", err)
+		}
+	}
+
+	return d
+}
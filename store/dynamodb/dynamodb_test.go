@@ -0,0 +1,259 @@
+package dynamodb
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/micro/go-micro/v2/store"
+)
+
+// fake is an in memory dynamodb supporting the operations used by the store
+type fake struct {
+	dynamodbiface.DynamoDBAPI
+
+	gosync.Mutex
+	tables map[string]map[string]map[string]*dynamodb.AttributeValue
+	ttl    map[string]string
+	// leave the first request of the next batch unprocessed
+	unprocessed bool
+}
+
+func id(av map[string]*dynamodb.AttributeValue) string {
+	return aws.StringValue(av[partitionKey].S) + "\x00" + aws.StringValue(av[sortKey].S)
+}
+
+func (f *fake) DescribeTable(in *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+	if _, ok := f.tables[*in.TableName]; !ok {
+		return nil, awserr.New(dynamodb.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	return &dynamodb.DescribeTableOutput{}, nil
+}
+
+func (f *fake) CreateTable(in *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.tables[*in.TableName] = make(map[string]map[string]*dynamodb.AttributeValue)
+	return &dynamodb.CreateTableOutput{}, nil
+}
+
+func (f *fake) WaitUntilTableExists(*dynamodb.DescribeTableInput) error {
+	return nil
+}
+
+func (f *fake) UpdateTimeToLive(in *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.ttl[*in.TableName] = *in.TimeToLiveSpecification.AttributeName
+	return &dynamodb.UpdateTimeToLiveOutput{}, nil
+}
+
+func (f *fake) GetItem(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+	return &dynamodb.GetItemOutput{Item: f.tables[*in.TableName][id(in.Key)]}, nil
+}
+
+func (f *fake) PutItem(in *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.tables[*in.TableName][id(in.Item)] = in.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fake) DeleteItem(in *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+	delete(f.tables[*in.TableName], id(in.Key))
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func (f *fake) BatchGetItem(in *dynamodb.BatchGetItemInput) (*dynamodb.BatchGetItemOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+	out := &dynamodb.BatchGetItemOutput{
+		Responses:       make(map[string][]map[string]*dynamodb.AttributeValue),
+		UnprocessedKeys: make(map[string]*dynamodb.KeysAndAttributes),
+	}
+	for name, ka := range in.RequestItems {
+		if len(ka.Keys) > maxBatchGet {
+			return nil, awserr.New("ValidationException", "too many keys", nil)
+		}
+		keys := ka.Keys
+		if f.unprocessed {
+			f.unprocessed = false
+			out.UnprocessedKeys[name] = &dynamodb.KeysAndAttributes{Keys: keys[:1]}
+			keys = keys[1:]
+		}
+		for _, k := range keys {
+			if av := f.tables[name][id(k)]; av != nil {
+				out.Responses[name] = append(out.Responses[name], av)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f *fake) BatchWriteItem(in *dynamodb.BatchWriteItemInput) (*dynamodb.BatchWriteItemOutput, error) {
+	f.Lock()
+	defer f.Unlock()
+	out := &dynamodb.BatchWriteItemOutput{
+		UnprocessedItems: make(map[string][]*dynamodb.WriteRequest),
+	}
+	for name, reqs := range in.RequestItems {
+		if len(reqs) > maxBatchWrite {
+			return nil, awserr.New("ValidationException", "too many items", nil)
+		}
+		if f.unprocessed {
+			f.unprocessed = false
+			out.UnprocessedItems[name] = reqs[:1]
+			reqs = reqs[1:]
+		}
+		for _, r := range reqs {
+			if r.PutRequest != nil {
+				f.tables[name][id(r.PutRequest.Item)] = r.PutRequest.Item
+			} else {
+				delete(f.tables[name], id(r.DeleteRequest.Key))
+			}
+		}
+	}
+	return out, nil
+}
+
+// QueryPages returns one item per page to exercise pagination
+func (f *fake) QueryPages(in *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool) error {
+	f.Lock()
+	var items []map[string]*dynamodb.AttributeValue
+	for _, av := range f.tables[*in.TableName] {
+		if aws.StringValue(av[partitionKey].S) != aws.StringValue(in.ExpressionAttributeValues[":t"].S) {
+			continue
+		}
+		if p := in.ExpressionAttributeValues[":p"]; p != nil && !strings.HasPrefix(aws.StringValue(av[sortKey].S), *p.S) {
+			continue
+		}
+		items = append(items, av)
+	}
+	f.Unlock()
+
+	sort.Slice(items, func(i, j int) bool {
+		return *items[i][sortKey].S < *items[j][sortKey].S
+	})
+
+	for i, av := range items {
+		if !fn(&dynamodb.QueryOutput{Items: []map[string]*dynamodb.AttributeValue{av}}, i == len(items)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestStore(t *testing.T) {
+	f := &fake{
+		tables: make(map[string]map[string]map[string]*dynamodb.AttributeValue),
+		ttl:    make(map[string]string),
+	}
+	s := NewStore(Client(f))
+
+	if f.ttl[DefaultDatabase] != ttlAttribute {
+		t.Fatalf("expected ttl to be enabled on %s", ttlAttribute)
+	}
+
+	records := []*store.Record{
+		{Key: "user/1", Value: []byte("alice")},
+		{Key: "user/2", Value: []byte("bob"), Metadata: map[string]interface{}{"admin": true}},
+		{Key: "user/3", Value: []byte("carol")},
+		{Key: "session/1", Value: []byte("x"), Expiry: time.Millisecond},
+	}
+	for _, r := range records {
+		if err := s.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// records in another table are in a different partition
+	if err := s.Write(&store.Record{Key: "user/9"}, store.WriteTo("", "other")); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := s.Read("user/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rs[0].Value) != "bob" || rs[0].Metadata["admin"] != true {
+		t.Fatalf("unexpected record %+v", rs[0])
+	}
+
+	time.Sleep(time.Second)
+	if _, err := s.Read("session/1"); err != store.ErrNotFound {
+		t.Fatalf("expected expired record to be not found got %v", err)
+	}
+
+	rs, err = s.Read("user/", store.ReadPrefix(), store.ReadLimit(2), store.ReadOffset(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 || rs[0].Key != "user/2" || rs[1].Key != "user/3" {
+		t.Fatalf("expected user/2 and user/3 got %v", rs)
+	}
+
+	keys, err := s.List(store.ListSuffix("/1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "user/1" {
+		t.Fatalf("expected [user/1] got %v", keys)
+	}
+
+	if err := s.Delete("user/1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Read("user/1"); err != store.ErrNotFound {
+		t.Fatalf("expected deleted record to be not found got %v", err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	f := &fake{
+		tables: make(map[string]map[string]map[string]*dynamodb.AttributeValue),
+		ttl:    make(map[string]string),
+	}
+	s := NewStore(Client(f)).(*dynamoStore)
+
+	var records []*store.Record
+	var keys []string
+	for i := 0; i < 30; i++ {
+		k := "key/" + strconv.Itoa(i)
+		records = append(records, &store.Record{Key: k, Value: []byte(k)})
+		keys = append(keys, k)
+	}
+
+	f.unprocessed = true
+	if err := s.WriteMany(records); err != nil {
+		t.Fatal(err)
+	}
+
+	f.unprocessed = true
+	rs, err := s.ReadMany(append(keys, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 30 {
+		t.Fatalf("expected 30 records got %d", len(rs))
+	}
+
+	if err := s.DeleteMany(keys); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := s.List(); len(keys) != 0 {
+		t.Fatalf("expected all keys to be deleted got %v", keys)
+	}
+}
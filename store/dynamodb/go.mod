@@ -0,0 +1,9 @@
+module github.com/micro/go-plugins/store/dynamodb/v2
+
+go 1.13
+
+require (
+	github.com/aws/aws-sdk-go v1.28.4
+	github.com/micro/go-micro/v2 v2.9.1
+	github.com/pkg/errors v0.9.1
+)
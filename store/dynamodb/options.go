@@ -0,0 +1,34 @@
+package dynamodb
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/micro/go-micro/v2/store"
+)
+
+type clientKey struct{}
+type throughputKey struct{}
+
+type throughput struct {
+	read, write int64
+}
+
+// Client sets the dynamodb client, by default one is created from the environment
+func Client(c dynamodbiface.DynamoDBAPI) store.Option {
+	return setOption(clientKey{}, c)
+}
+
+// ProvisionedThroughput creates tables with provisioned capacity rather than on demand
+func ProvisionedThroughput(read, write int64) store.Option {
+	return setOption(throughputKey{}, throughput{read, write})
+}
+
+func setOption(k, v interface{}) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
@@ -0,0 +1,50 @@
+package memcached
+
+import (
+	mc "github.com/bradfitz/gomemcache/memcache"
+	"github.com/micro/go-micro/v2/store"
+)
+
+// ReadMany reads the keys with one multi get per server
+func (m *mkv) ReadMany(keys []string, opts ...store.ReadOption) ([]*store.Record, error) {
+	items, err := m.Client.GetMulti(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*store.Record, 0, len(items))
+	// keep the order of the keys
+	for _, k := range keys {
+		item, ok := items[k]
+		if !ok {
+			continue
+		}
+		records = append(records, &store.Record{
+			Key:   item.Key,
+			Value: item.Value,
+		})
+		delete(items, k)
+	}
+
+	return records, nil
+}
+
+// WriteMany writes every record, memcached has no multi set
+func (m *mkv) WriteMany(records []*store.Record, opts ...store.WriteOption) error {
+	for _, r := range records {
+		if err := m.Write(r, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteMany deletes every key, keys which don't exist are ignored
+func (m *mkv) DeleteMany(keys []string, opts ...store.DeleteOption) error {
+	for _, k := range keys {
+		if err := m.Client.Delete(k); err != nil && err != mc.ErrCacheMiss {
+			return err
+		}
+	}
+	return nil
+}
@@ -16,7 +16,7 @@ import (
 
 type mkv struct {
 	options store.Options
-	Server  *mc.ServerList
+	Server  *Ring
 	Client  *mc.Client
 }
 
@@ -64,11 +64,45 @@ func (m *mkv) Delete(key string, opts ...store.DeleteOption) error {
 	return m.Client.Delete(key)
 }
 
+// maxRelativeExpiry is the longest expiration memcached treats as relative,
+// anything longer is interpreted as a unix timestamp
+const maxRelativeExpiry = 30 * 24 * time.Hour
+
+// expiration returns the memcached expiration of a ttl, zero never expires
+func expiration(ttl time.Duration) int32 {
+	switch {
+	case ttl <= 0:
+		return 0
+	case ttl > maxRelativeExpiry:
+		return int32(time.Now().Add(ttl).Unix())
+	case ttl < time.Second:
+		return 1
+	}
+	return int32(ttl / time.Second)
+}
+
 func (m *mkv) Write(record *store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	ttl := record.Expiry
+	switch {
+	case !options.Expiry.IsZero():
+		ttl = time.Until(options.Expiry)
+		// already expired
+		if ttl <= 0 {
+			return m.Delete(record.Key)
+		}
+	case options.TTL > 0:
+		ttl = options.TTL
+	}
+
 	return m.Client.Set(&mc.Item{
 		Key:        record.Key,
 		Value:      record.Value,
-		Expiration: int32(record.Expiry.Seconds()),
+		Expiration: expiration(ttl),
 	})
 }
 
@@ -180,11 +214,15 @@ func (m *mkv) configure() error {
 		nodes = []string{"127.0.0.1:11211"}
 	}
 
-	ss := new(mc.ServerList)
-	ss.SetServers(nodes...)
+	// keys are spread over the nodes with consistent hashing so
+	// changing the nodes only invalidates a fraction of the cache
+	ring, err := NewRing(nodes...)
+	if err != nil {
+		return err
+	}
 
-	m.Server = ss
-	m.Client = mc.New(nodes...)
+	m.Server = ring
+	m.Client = mc.NewFromSelector(ring)
 
 	return nil
 }
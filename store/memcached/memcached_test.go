@@ -0,0 +1,70 @@
+package memcached
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRing(t *testing.T) {
+	servers := []string{"127.0.0.1:11211", "127.0.0.1:11212", "127.0.0.1:11213"}
+
+	r, err := NewRing(servers...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := make(map[string]string)
+	counts := make(map[string]int)
+	for i := 0; i < 3000; i++ {
+		key := "key-" + strconv.Itoa(i)
+		addr, err := r.PickServer(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		before[key] = addr.String()
+		counts[addr.String()]++
+	}
+
+	for _, s := range servers {
+		if counts[s] < 500 {
+			t.Fatalf("expected keys to be spread over servers got %v", counts)
+		}
+	}
+
+	// adding a server only moves keys to the new server
+	if err := r.SetServers(append(servers, "127.0.0.1:11214")...); err != nil {
+		t.Fatal(err)
+	}
+
+	moved := 0
+	for key, old := range before {
+		addr, _ := r.PickServer(key)
+		if addr.String() == old {
+			continue
+		}
+		if addr.String() != "127.0.0.1:11214" {
+			t.Fatalf("key %s moved from %s to %s", key, old, addr)
+		}
+		moved++
+	}
+
+	if moved > 1500 {
+		t.Fatalf("expected around a quarter of keys to move got %d", moved)
+	}
+}
+
+func TestExpiration(t *testing.T) {
+	if e := expiration(0); e != 0 {
+		t.Fatalf("expected no expiry got %d", e)
+	}
+	if e := expiration(time.Millisecond); e != 1 {
+		t.Fatalf("expected 1 second got %d", e)
+	}
+	if e := expiration(time.Minute); e != 60 {
+		t.Fatalf("expected 60 seconds got %d", e)
+	}
+	if e := expiration(60 * 24 * time.Hour); int64(e) < time.Now().Unix() {
+		t.Fatalf("expected a unix timestamp got %d", e)
+	}
+}
@@ -0,0 +1,114 @@
+package memcached
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	mc "github.com/bradfitz/gomemcache/memcache"
+)
+
+// DefaultReplicas is the number of points each server has on the ring
+var DefaultReplicas = 160
+
+// Ring is a ketama style consistent hash ring selecting the server of a key.
+// Adding or removing a server only moves the keys of its neighbours on the
+// ring rather than reshuffling every key as modulo hashing does.
+type Ring struct {
+	mtx      sync.RWMutex
+	replicas int
+	points   []uint32
+	addrs    map[uint32]net.Addr
+	servers  []net.Addr
+}
+
+// NewRing returns a ring of the servers
+func NewRing(servers ...string) (*Ring, error) {
+	r := &Ring{replicas: DefaultReplicas}
+	if err := r.SetServers(servers...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func resolve(server string) (net.Addr, error) {
+	if strings.Contains(server, "/") {
+		return net.ResolveUnixAddr("unix", server)
+	}
+	return net.ResolveTCPAddr("tcp", server)
+}
+
+// SetServers replaces the servers of the ring
+func (r *Ring) SetServers(servers ...string) error {
+	var points []uint32
+	addrs := make(map[uint32]net.Addr)
+	naddrs := make([]net.Addr, 0, len(servers))
+
+	for _, server := range servers {
+		addr, err := resolve(server)
+		if err != nil {
+			return err
+		}
+		naddrs = append(naddrs, addr)
+
+		// each md5 sum gives four points
+		for i := 0; i < r.replicas/4; i++ {
+			sum := md5.Sum([]byte(server + "-" + strconv.Itoa(i)))
+			for j := 0; j < 4; j++ {
+				p := binary.LittleEndian.Uint32(sum[j*4:])
+				points = append(points, p)
+				addrs[p] = addr
+			}
+		}
+	}
+
+	sort.Slice(points, func(i, j int) bool { return points[i] < points[j] })
+
+	r.mtx.Lock()
+	r.points = points
+	r.addrs = addrs
+	r.servers = naddrs
+	r.mtx.Unlock()
+
+	return nil
+}
+
+func hash(key string) uint32 {
+	sum := md5.Sum([]byte(key))
+	return binary.LittleEndian.Uint32(sum[:4])
+}
+
+// PickServer returns the first server clockwise of the key on the ring
+func (r *Ring) PickServer(key string) (net.Addr, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	if len(r.points) == 0 {
+		return nil, mc.ErrNoServers
+	}
+
+	h := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+
+	return r.addrs[r.points[i]], nil
+}
+
+// Each calls f with every server
+func (r *Ring) Each(f func(net.Addr) error) error {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	for _, a := range r.servers {
+		if err := f(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReadMany reads the records of the keys which exist in a single find
+func (m *mongoStore) ReadMany(keys []string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	c, err := m.collection(ctx, options.Database, options.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	f := filter("", "")
+	f["_id"] = bson.M{"$in": keys}
+
+	cur, err := c.Find(ctx, f, find(0, 0))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var records []*store.Record
+	for cur.Next(ctx) {
+		var doc document
+		if err := cur.Decode(&doc); err != nil {
+			return records, err
+		}
+		records = append(records, doc.record())
+	}
+
+	return records, cur.Err()
+}
+
+// WriteMany upserts the records with a single unordered bulk write
+func (m *mongoStore) WriteMany(records []*store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	c, err := m.collection(ctx, options.Database, options.Table)
+	if err != nil {
+		return err
+	}
+
+	models := make([]mongo.WriteModel, 0, len(records))
+	for _, r := range records {
+		doc := newDocument(r, options)
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": r.Key}).
+			SetReplacement(doc).
+			SetUpsert(true))
+	}
+
+	if _, err := c.BulkWrite(ctx, models, mopts.BulkWrite().SetOrdered(false)); err != nil {
+		return errors.Wrap(err, "Couldn't write records")
+	}
+
+	return nil
+}
+
+// DeleteMany deletes the keys in a single delete
+func (m *mongoStore) DeleteMany(keys []string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	c, err := m.collection(ctx, options.Database, options.Table)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": keys}})
+	return err
+}
+
+// newDocument returns the document of a record
+func newDocument(r *store.Record, options store.WriteOptions) document {
+	doc := document{
+		Key:      r.Key,
+		Value:    r.Value,
+		Metadata: r.Metadata,
+	}
+
+	var expiry time.Time
+	switch {
+	case !options.Expiry.IsZero():
+		expiry = options.Expiry
+	case options.TTL > 0:
+		expiry = time.Now().Add(options.TTL)
+	case r.Expiry > 0:
+		expiry = time.Now().Add(r.Expiry)
+	}
+	if !expiry.IsZero() {
+		doc.Expiry = &expiry
+	}
+
+	return doc
+}
@@ -0,0 +1,9 @@
+module github.com/micro/go-plugins/store/mongo/v2
+
+go 1.13
+
+require (
+	github.com/micro/go-micro/v2 v2.9.1
+	github.com/pkg/errors v0.9.1
+	go.mongodb.org/mongo-driver v1.3.4
+)
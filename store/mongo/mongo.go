@@ -0,0 +1,357 @@
+// Package mongo implements the store using mongodb
+package mongo
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"time"
+
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/store"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// DefaultDatabase is the database used if no database is provided
+	DefaultDatabase = "micro"
+	// DefaultTable is the collection used if no table is provided
+	DefaultTable = "micro"
+	// DefaultTimeout is the timeout of each operation
+	DefaultTimeout = 10 * time.Second
+)
+
+// document is a record as stored in a collection
+type document struct {
+	Key      string                 `bson:"_id"`
+	Value    []byte                 `bson:"value"`
+	Metadata map[string]interface{} `bson:"metadata,omitempty"`
+	Expiry   *time.Time             `bson:"expiry,omitempty"`
+}
+
+func (d *document) record() *store.Record {
+	r := &store.Record{
+		Key:      d.Key,
+		Value:    d.Value,
+		Metadata: d.Metadata,
+	}
+	if d.Expiry != nil {
+		r.Expiry = time.Until(*d.Expiry)
+	}
+	return r
+}
+
+type mongoStore struct {
+	options store.Options
+	client  *mongo.Client
+	timeout time.Duration
+
+	sync.RWMutex
+	// collections with a ttl index
+	indexed map[string]bool
+}
+
+func (m *mongoStore) getDB(database, table string) (string, string) {
+	if len(database) == 0 {
+		database = m.options.Database
+	}
+	if len(database) == 0 {
+		database = DefaultDatabase
+	}
+
+	if len(table) == 0 {
+		table = m.options.Table
+	}
+	if len(table) == 0 {
+		table = DefaultTable
+	}
+
+	return database, table
+}
+
+// collection returns the collection, creating its ttl index on first use
+func (m *mongoStore) collection(ctx context.Context, database, table string) (*mongo.Collection, error) {
+	database, table = m.getDB(database, table)
+	c := m.client.Database(database).Collection(table)
+
+	m.RLock()
+	ok := m.indexed[database+"."+table]
+	m.RUnlock()
+	if ok {
+		return c, nil
+	}
+
+	// documents are removed by mongo once their expiry has passed
+	_, err := c.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiry", Value: 1}},
+		Options: mopts.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Couldn't create ttl index")
+	}
+
+	m.Lock()
+	m.indexed[database+"."+table] = true
+	m.Unlock()
+
+	return c, nil
+}
+
+// filter matches keys with the prefix and suffix which haven't expired.
+// The ttl monitor only runs periodically so expiry is checked on reads.
+func filter(prefix, suffix string) bson.M {
+	f := bson.M{
+		"$or": bson.A{
+			bson.M{"expiry": bson.M{"$exists": false}},
+			bson.M{"expiry": bson.M{"$gt": time.Now()}},
+		},
+	}
+	if len(prefix) > 0 || len(suffix) > 0 {
+		pattern := ""
+		if len(prefix) > 0 {
+			pattern = "^" + regexp.QuoteMeta(prefix)
+		}
+		if len(suffix) > 0 {
+			pattern += ".*" + regexp.QuoteMeta(suffix) + "$"
+		}
+		f["_id"] = bson.M{"$regex": pattern}
+	}
+	return f
+}
+
+func (m *mongoStore) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), m.timeout)
+}
+
+func (m *mongoStore) configure() error {
+	m.timeout = DefaultTimeout
+
+	uri := "mongodb://localhost:27017"
+	if len(m.options.Nodes) > 0 {
+		uri = m.options.Nodes[0]
+	}
+
+	opts := []*mopts.ClientOptions{mopts.Client().ApplyURI(uri)}
+
+	if m.options.Context != nil {
+		if v, ok := m.options.Context.Value(clientOptionsKey{}).([]*mopts.ClientOptions); ok {
+			opts = append(opts, v...)
+		}
+		if v, ok := m.options.Context.Value(timeoutKey{}).(time.Duration); ok && v > 0 {
+			m.timeout = v
+		}
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, opts...)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return err
+	}
+
+	m.Lock()
+	if m.client != nil {
+		m.client.Disconnect(ctx)
+	}
+	m.client = client
+	m.indexed = make(map[string]bool)
+	m.Unlock()
+
+	return nil
+}
+
+func (m *mongoStore) Init(opts ...store.Option) error {
+	for _, o := range opts {
+		o(&m.options)
+	}
+	return m.configure()
+}
+
+func (m *mongoStore) Options() store.Options {
+	return m.options
+}
+
+func (m *mongoStore) Close() error {
+	ctx, cancel := m.context()
+	defer cancel()
+	return m.client.Disconnect(ctx)
+}
+
+func (m *mongoStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	c, err := m.collection(ctx, options.Database, options.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	if !options.Prefix && !options.Suffix {
+		f := filter("", "")
+		f["_id"] = key
+
+		var doc document
+		if err := c.FindOne(ctx, f).Decode(&doc); err != nil {
+			if err == mongo.ErrNoDocuments {
+				return nil, store.ErrNotFound
+			}
+			return nil, err
+		}
+		return []*store.Record{doc.record()}, nil
+	}
+
+	var prefix, suffix string
+	if options.Prefix {
+		prefix = key
+	}
+	if options.Suffix {
+		suffix = key
+	}
+
+	cur, err := c.Find(ctx, filter(prefix, suffix), find(options.Limit, options.Offset))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var records []*store.Record
+	for cur.Next(ctx) {
+		var doc document
+		if err := cur.Decode(&doc); err != nil {
+			return records, err
+		}
+		records = append(records, doc.record())
+	}
+
+	return records, cur.Err()
+}
+
+// find returns the find options paginating by key
+func find(limit, offset uint) *mopts.FindOptions {
+	o := mopts.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if limit > 0 {
+		o.SetLimit(int64(limit))
+	}
+	if offset > 0 {
+		o.SetSkip(int64(offset))
+	}
+	return o
+}
+
+func (m *mongoStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	c, err := m.collection(ctx, options.Database, options.Table)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.ReplaceOne(ctx, bson.M{"_id": r.Key}, newDocument(r, options), mopts.Replace().SetUpsert(true))
+	if err != nil {
+		return errors.Wrap(err, "Couldn't write record "+r.Key)
+	}
+
+	return nil
+}
+
+func (m *mongoStore) Delete(key string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	c, err := m.collection(ctx, options.Database, options.Table)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
+
+func (m *mongoStore) List(opts ...store.ListOption) ([]string, error) {
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	ctx, cancel := m.context()
+	defer cancel()
+
+	c, err := m.collection(ctx, options.Database, options.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	fo := find(options.Limit, options.Offset).SetProjection(bson.M{"_id": 1})
+
+	cur, err := c.Find(ctx, filter(options.Prefix, options.Suffix), fo)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var keys []string
+	for cur.Next(ctx) {
+		var doc document
+		if err := cur.Decode(&doc); err != nil {
+			return keys, err
+		}
+		keys = append(keys, doc.Key)
+	}
+
+	return keys, cur.Err()
+}
+
+func (m *mongoStore) String() string {
+	return "mongo"
+}
+
+// NewStore returns a new micro Store backed by mongodb
+func NewStore(opts ...store.Option) store.Store {
+	options := store.Options{
+		Database: DefaultDatabase,
+		Table:    DefaultTable,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	m := &mongoStore{
+		options: options,
+		indexed: make(map[string]bool),
+	}
+
+	// best-effort configure the store
+	if err := m.configure(); err != nil {
+		if log.V(log.ErrorLevel, log.DefaultLogger) {
+			log.Error("Error configuring store ", err)
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,85 @@
+package mongo
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFilter(t *testing.T) {
+	testData := []struct {
+		prefix, suffix string
+		regex          string
+	}{
+		{"", "", ""},
+		{"user/", "", "^user/"},
+		{"", ".json", `.*\.json$`},
+		{"a.", "b", `^a\..*b$`},
+	}
+
+	for _, d := range testData {
+		f := filter(d.prefix, d.suffix)
+		id, ok := f["_id"].(bson.M)
+		if len(d.regex) == 0 {
+			if ok {
+				t.Fatalf("expected no key filter got %v", id)
+			}
+			continue
+		}
+		if !ok || id["$regex"] != d.regex {
+			t.Fatalf("expected regex %s got %v", d.regex, f["_id"])
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	addr := os.Getenv("MONGO_ADDR")
+	if len(addr) == 0 {
+		t.Skip("MONGO_ADDR not set")
+	}
+
+	s := NewStore(store.Nodes(addr), store.Table("test"))
+	defer s.Close()
+
+	records := []*store.Record{
+		{Key: "user/1", Value: []byte("alice")},
+		{Key: "user/2", Value: []byte("bob")},
+		{Key: "session/1", Value: []byte("x"), Expiry: time.Millisecond},
+	}
+	for _, r := range records {
+		if err := s.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	time.Sleep(time.Millisecond * 10)
+
+	if _, err := s.Read("session/1"); err != store.ErrNotFound {
+		t.Fatalf("expected expired record to be not found got %v", err)
+	}
+
+	rs, err := s.Read("user/", store.ReadPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 {
+		t.Fatalf("expected 2 records got %d", len(rs))
+	}
+
+	keys, err := s.List(store.ListPrefix("user/"), store.ListLimit(1), store.ListOffset(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "user/2" {
+		t.Fatalf("expected [user/2] got %v", keys)
+	}
+
+	for _, r := range records {
+		if err := s.Delete(r.Key); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type clientOptionsKey struct{}
+type timeoutKey struct{}
+
+// ClientOptions sets additional mongo client options applied after the uri
+func ClientOptions(opts ...*options.ClientOptions) store.Option {
+	return setOption(clientOptionsKey{}, opts)
+}
+
+// Timeout sets the timeout of each operation
+func Timeout(d time.Duration) store.Option {
+	return setOption(timeoutKey{}, d)
+}
+
+func setOption(k, v interface{}) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
+
+// WatchOptions select the changes a watcher receives
+type WatchOptions struct {
+	Database, Table string
+	// Prefix only watches keys with the prefix
+	Prefix string
+}
+
+type WatchOption func(o *WatchOptions)
+
+// WatchFrom sets the database and table to watch
+func WatchFrom(database, table string) WatchOption {
+	return func(o *WatchOptions) {
+		o.Database = database
+		o.Table = table
+	}
+}
+
+// WatchPrefix only watches keys with the prefix
+func WatchPrefix(p string) WatchOption {
+	return func(o *WatchOptions) {
+		o.Prefix = p
+	}
+}
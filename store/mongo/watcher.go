@@ -0,0 +1,119 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"github.com/micro/go-micro/v2/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mopts "go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EventType is the type of change made to a record
+type EventType string
+
+const (
+	Write  EventType = "write"
+	Delete EventType = "delete"
+)
+
+// Event is a change made to a record. The record of a delete event only has its key.
+type Event struct {
+	Type   EventType
+	Record *store.Record
+}
+
+// Watcher receives changes made to the store by any client
+type Watcher interface {
+	Next() (*Event, error)
+	Stop() error
+}
+
+// Watchable is implemented by the mongo store to watch changes using change
+// streams. Change streams require mongo to run as a replica set or sharded cluster.
+type Watchable interface {
+	Watch(opts ...WatchOption) (Watcher, error)
+}
+
+type watcher struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	stream *mongo.ChangeStream
+}
+
+type change struct {
+	OperationType string    `bson:"operationType"`
+	FullDocument  *document `bson:"fullDocument"`
+	DocumentKey   struct {
+		Key string `bson:"_id"`
+	} `bson:"documentKey"`
+}
+
+// ErrWatcherStopped is returned when a watcher has been stopped
+var ErrWatcherStopped = errors.New("watcher stopped")
+
+func (w *watcher) Next() (*Event, error) {
+	for w.stream.Next(w.ctx) {
+		var c change
+		if err := w.stream.Decode(&c); err != nil {
+			return nil, err
+		}
+
+		switch c.OperationType {
+		case "insert", "update", "replace":
+			// the document may have been deleted before it was looked up
+			if c.FullDocument == nil {
+				continue
+			}
+			return &Event{Type: Write, Record: c.FullDocument.record()}, nil
+		case "delete":
+			return &Event{Type: Delete, Record: &store.Record{Key: c.DocumentKey.Key}}, nil
+		}
+	}
+
+	if w.ctx.Err() != nil {
+		return nil, ErrWatcherStopped
+	}
+	return nil, w.stream.Err()
+}
+
+func (w *watcher) Stop() error {
+	w.cancel()
+	return w.stream.Close(context.Background())
+}
+
+func (m *mongoStore) Watch(opts ...WatchOption) (Watcher, error) {
+	var options WatchOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c, err := m.collection(ctx, options.Database, options.Table)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{}
+	if len(options.Prefix) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{
+			"documentKey._id": bson.M{"$regex": "^" + regexp.QuoteMeta(options.Prefix)},
+		}}})
+	}
+
+	stream, err := c.Watch(ctx, pipeline, mopts.ChangeStream().SetFullDocument(mopts.UpdateLookup))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &watcher{
+		ctx:    ctx,
+		cancel: cancel,
+		stream: stream,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+	"github.com/pkg/errors"
+)
+
+// placeholders returns n comma separated placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// chunk splits keys into slices of at most the batch size
+func (s *sqlStore) chunk(keys []string) [][]string {
+	var chunks [][]string
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > s.batchSize {
+			n = s.batchSize
+		}
+		chunks = append(chunks, keys[:n])
+		keys = keys[n:]
+	}
+	return chunks
+}
+
+func strArgs(keys []string) []interface{} {
+	args := make([]interface{}, len(keys))
+	for i, k := range keys {
+		args[i] = k
+	}
+	return args
+}
+
+// ReadMany reads the records of the keys which exist using IN queries of up to the batch size
+func (s *sqlStore) ReadMany(keys []string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	st, err := s.table(options.Database, options.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*store.Record
+
+	for _, c := range s.chunk(keys) {
+		q := fmt.Sprintf("SELECT `key`, value, metadata, expiry FROM %s WHERE `key` IN (%s);", st.name, placeholders(len(c)))
+		rows, err := s.db.Query(q, strArgs(c)...)
+		if err != nil {
+			return records, err
+		}
+
+		for rows.Next() {
+			record := &store.Record{}
+			var md metadata
+			var expiry sql.NullTime
+
+			if err := rows.Scan(&record.Key, &record.Value, &md, &expiry); err != nil {
+				rows.Close()
+				return records, err
+			}
+			if expired(expiry) {
+				continue
+			}
+
+			record.Metadata = md
+			if expiry.Valid {
+				record.Expiry = time.Until(expiry.Time)
+			}
+			records = append(records, record)
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return records, err
+		}
+	}
+
+	return records, nil
+}
+
+// WriteMany writes records using multi row inserts of up to the batch size.
+// The records of each batch are written atomically.
+func (s *sqlStore) WriteMany(records []*store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	st, err := s.table(options.Database, options.Table)
+	if err != nil {
+		return err
+	}
+
+	for len(records) > 0 {
+		n := len(records)
+		if n > s.batchSize {
+			n = s.batchSize
+		}
+
+		stmt, err := st.insert(s.db, n)
+		if err != nil {
+			return err
+		}
+
+		values := make([]interface{}, 0, n*4)
+		for _, r := range records[:n] {
+			values = append(values, args(r, options)...)
+		}
+
+		if _, err := stmt.Exec(values...); err != nil {
+			return errors.Wrap(err, "Couldn't insert records")
+		}
+
+		records = records[n:]
+	}
+
+	return nil
+}
+
+// DeleteMany deletes the keys using IN queries of up to the batch size
+func (s *sqlStore) DeleteMany(keys []string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	st, err := s.table(options.Database, options.Table)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range s.chunk(keys) {
+		q := fmt.Sprintf("DELETE FROM %s WHERE `key` IN (%s);", st.name, placeholders(len(c)))
+		if _, err := s.db.Exec(q, strArgs(c)...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
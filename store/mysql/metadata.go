@@ -0,0 +1,35 @@
+package mysql
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// metadata is a record's metadata stored as json
+type metadata map[string]interface{}
+
+// Scan satisfies the sql.Scanner interface
+func (m *metadata) Scan(src interface{}) error {
+	if src == nil {
+		*m = nil
+		return nil
+	}
+
+	source, ok := src.([]byte)
+	if !ok {
+		return errors.New("metadata must be []byte")
+	}
+
+	return json.Unmarshal(source, m)
+}
+
+// Value satisfies the driver.Valuer interface
+func (m metadata) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	// mysql rejects binary strings as json
+	b, err := json.Marshal(m)
+	return string(b), err
+}
@@ -3,6 +3,8 @@ package mysql
 import (
 	"database/sql"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 	"unicode"
 
@@ -16,17 +18,58 @@ var (
 	DefaultDatabase = "micro"
 	// DefaultTable is the table that the sql store will use if no table is provided.
 	DefaultTable = "micro"
+	// DefaultBatchSize is the maximum number of rows in a single batch statement
+	DefaultBatchSize = 100
+
+	// columns of the table, missing columns are added to existing tables
+	columns = []struct {
+		name, definition string
+	}{
+		{"key", "varchar(255) primary key"},
+		{"value", "longblob null"},
+		{"metadata", "json null"},
+		{"expiry", "timestamp null"},
+	}
+
+	// escapes the LIKE wildcards in keys
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
 )
 
+// maxLimit is used when no limit is set since mysql requires a limit with an offset
+const maxLimit = uint64(18446744073709551615)
+
 type sqlStore struct {
 	db *sql.DB
 
-	database string
-	table    string
+	options   store.Options
+	naming    NamingFunc
+	batchSize int
+
+	sync.RWMutex
+	// prepared statements of each known table
+	tables map[string]*statements
+}
+
+// statements are the prepared statements of a table
+type statements struct {
+	name string
 
-	options store.Options
+	read, readMany, list, write, delete *sql.Stmt
 
-	readPrepare, writePrepare, deletePrepare *sql.Stmt
+	sync.Mutex
+	// multi row inserts keyed by number of rows
+	writeMany map[int]*sql.Stmt
+}
+
+func (s *statements) close() {
+	for _, st := range []*sql.Stmt{s.read, s.readMany, s.list, s.write, s.delete} {
+		if st != nil {
+			st.Close()
+		}
+	}
+	for _, st := range s.writeMany {
+		st.Close()
+	}
 }
 
 func (s *sqlStore) Init(opts ...store.Option) error {
@@ -42,12 +85,223 @@ func (s *sqlStore) Options() store.Options {
 }
 
 func (s *sqlStore) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, st := range s.tables {
+		st.close()
+	}
+	s.tables = make(map[string]*statements)
+
 	return s.db.Close()
 }
 
+// getDB returns the mysql database and table for the requested ones
+func (s *sqlStore) getDB(database, table string) (string, string, error) {
+	if len(database) == 0 {
+		database = s.options.Database
+	}
+	if len(database) == 0 {
+		database = DefaultDatabase
+	}
+
+	if len(table) == 0 {
+		table = s.options.Table
+	}
+	if len(table) == 0 {
+		table = DefaultTable
+	}
+
+	if s.naming != nil {
+		database, table = s.naming(database, table)
+	}
+
+	for _, name := range []string{database, table} {
+		for _, r := range name {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+				return "", "", errors.New("store database and table must only contain letters, numbers and underscores")
+			}
+		}
+	}
+
+	return database, table, nil
+}
+
+// table returns the prepared statements of the table, creating and migrating it on first use
+func (s *sqlStore) table(database, table string) (*statements, error) {
+	database, table, err := s.getDB(database, table)
+	if err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("`%s`.`%s`", database, table)
+
+	s.RLock()
+	st, ok := s.tables[name]
+	s.RUnlock()
+	if ok {
+		return st, nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if st, ok := s.tables[name]; ok {
+		return st, nil
+	}
+
+	if err := s.initDB(database, table); err != nil {
+		return nil, err
+	}
+
+	st, err = s.prepare(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.tables[name] = st
+	return st, nil
+}
+
+func (s *sqlStore) initDB(database, table string) error {
+	// Create the namespace's database
+	_, err := s.db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`;", database))
+	if err != nil {
+		return err
+	}
+
+	defs := make([]string, 0, len(columns))
+	for _, c := range columns {
+		defs = append(defs, fmt.Sprintf("`%s` %s", c.name, c.definition))
+	}
+
+	// Create a table for the namespace's prefix
+	createSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s`.`%s` (%s);", database, table, strings.Join(defs, ", "))
+	if _, err := s.db.Exec(createSQL); err != nil {
+		return errors.Wrap(err, "Couldn't create table")
+	}
+
+	return s.migrate(database, table)
+}
+
+// migrate brings a table created by an earlier version up to date
+func (s *sqlStore) migrate(database, table string) error {
+	rows, err := s.db.Query("SELECT COLUMN_NAME, IS_NULLABLE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?;", database, table)
+	if err != nil {
+		return errors.Wrap(err, "Couldn't read table schema")
+	}
+	defer rows.Close()
+
+	nullable := make(map[string]bool)
+	for rows.Next() {
+		var name, isNullable string
+		if err := rows.Scan(&name, &isNullable); err != nil {
+			return err
+		}
+		nullable[strings.ToLower(name)] = isNullable == "YES"
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range columns {
+		n, ok := nullable[c.name]
+
+		var alter string
+		switch {
+		case !ok:
+			alter = fmt.Sprintf("ADD COLUMN `%s` %s", c.name, c.definition)
+		case !n && strings.HasSuffix(c.definition, " null"):
+			// records without an expiry used to be stored with a not null expiry
+			alter = fmt.Sprintf("MODIFY `%s` %s", c.name, c.definition)
+		default:
+			continue
+		}
+
+		if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE `%s`.`%s` %s;", database, table, alter)); err != nil {
+			return errors.Wrap(err, "Couldn't migrate table")
+		}
+	}
+
+	return nil
+}
+
+func (s *sqlStore) prepare(name string) (*statements, error) {
+	st := &statements{
+		name:      name,
+		writeMany: make(map[int]*sql.Stmt),
+	}
+
+	queries := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&st.read, "SELECT `key`, value, metadata, expiry FROM %s WHERE `key` = ?;"},
+		{&st.readMany, "SELECT `key`, value, metadata, expiry FROM %s WHERE `key` LIKE ? ORDER BY `key` LIMIT ? OFFSET ?;"},
+		{&st.list, "SELECT `key`, expiry FROM %s WHERE `key` LIKE ? ORDER BY `key` LIMIT ? OFFSET ?;"},
+		{&st.write, "INSERT INTO %s (`key`, value, metadata, expiry) VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE value = VALUES(value), metadata = VALUES(metadata), expiry = VALUES(expiry);"},
+		{&st.delete, "DELETE FROM %s WHERE `key` = ?;"},
+	}
+
+	for _, q := range queries {
+		stmt, err := s.db.Prepare(fmt.Sprintf(q.query, name))
+		if err != nil {
+			st.close()
+			return nil, errors.Wrap(err, "Couldn't prepare statement")
+		}
+		*q.stmt = stmt
+	}
+
+	return st, nil
+}
+
+// insert returns the prepared statement inserting n rows
+func (st *statements) insert(db *sql.DB, n int) (*sql.Stmt, error) {
+	st.Lock()
+	defer st.Unlock()
+
+	if stmt, ok := st.writeMany[n]; ok {
+		return stmt, nil
+	}
+
+	values := strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?), ", n), ", ")
+	stmt, err := db.Prepare(fmt.Sprintf("INSERT INTO %s (`key`, value, metadata, expiry) VALUES %s ON DUPLICATE KEY UPDATE value = VALUES(value), metadata = VALUES(metadata), expiry = VALUES(expiry);", st.name, values))
+	if err != nil {
+		return nil, errors.Wrap(err, "Couldn't prepare statement")
+	}
+
+	st.writeMany[n] = stmt
+	return stmt, nil
+}
+
+// pattern returns the LIKE pattern matching keys with the prefix and suffix
+func pattern(prefix, suffix string) string {
+	return likeEscaper.Replace(prefix) + "%" + likeEscaper.Replace(suffix)
+}
+
+func limit(l uint) uint64 {
+	if l == 0 {
+		return maxLimit
+	}
+	return uint64(l)
+}
+
+func expired(expiry sql.NullTime) bool {
+	return expiry.Valid && expiry.Time.Before(time.Now())
+}
+
 // List all the known records
 func (s *sqlStore) List(opts ...store.ListOption) ([]string, error) {
-	rows, err := s.db.Query(fmt.Sprintf("SELECT `key`, value, expiry FROM %s.%s;", s.database, s.table))
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	st, err := s.table(options.Database, options.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := st.list.Query(pattern(options.Prefix, options.Suffix), limit(options.Limit), options.Offset)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -56,31 +310,26 @@ func (s *sqlStore) List(opts ...store.ListOption) ([]string, error) {
 	}
 	defer rows.Close()
 
-	var records []string
-	var cachedTime time.Time
+	var keys []string
 
 	for rows.Next() {
-		record := &store.Record{}
-		if err := rows.Scan(&record.Key, &record.Value, &cachedTime); err != nil {
+		var key string
+		var expiry sql.NullTime
+		if err := rows.Scan(&key, &expiry); err != nil {
 			return nil, err
 		}
 
-		if cachedTime.Before(time.Now()) {
+		if expired(expiry) {
 			// record has expired
-			go s.Delete(record.Key)
+			go s.Delete(key, store.DeleteFrom(options.Database, options.Table))
 		} else {
-			records = append(records, record.Key)
+			keys = append(keys, key)
 		}
 	}
-	rowErr := rows.Close()
-	if rowErr != nil {
-		// transaction rollback or something
-		return records, rowErr
-	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	return records, nil
+	return keys, nil
 }
 
 // Read all records with keys
@@ -90,35 +339,92 @@ func (s *sqlStore) Read(key string, opts ...store.ReadOption) ([]*store.Record,
 		o(&options)
 	}
 
-	// TODO: make use of options.Prefix using WHERE key LIKE = ?
+	st, err := s.table(options.Database, options.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows *sql.Rows
+
+	if options.Prefix || options.Suffix {
+		var prefix, suffix string
+		if options.Prefix {
+			prefix = key
+		}
+		if options.Suffix {
+			suffix = key
+		}
+		rows, err = st.readMany.Query(pattern(prefix, suffix), limit(options.Limit), options.Offset)
+	} else {
+		rows, err = st.read.Query(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
 	var records []*store.Record
-	row := s.readPrepare.QueryRow(key)
-	record := &store.Record{}
-	var cachedTime time.Time
 
-	if err := row.Scan(&record.Key, &record.Value, &cachedTime); err != nil {
-		if err == sql.ErrNoRows {
-			return records, store.ErrNotFound
+	for rows.Next() {
+		record := &store.Record{}
+		var md metadata
+		var expiry sql.NullTime
+
+		if err := rows.Scan(&record.Key, &record.Value, &md, &expiry); err != nil {
+			return records, err
+		}
+
+		if expired(expiry) {
+			// record has expired
+			go s.Delete(record.Key, store.DeleteFrom(options.Database, options.Table))
+			continue
+		}
+
+		record.Metadata = md
+		if expiry.Valid {
+			record.Expiry = time.Until(expiry.Time)
 		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
 		return records, err
 	}
-	if cachedTime.Before(time.Now()) {
-		// record has expired
-		go s.Delete(key)
+
+	if len(records) == 0 && !options.Prefix && !options.Suffix {
 		return records, store.ErrNotFound
 	}
-	record.Expiry = time.Until(cachedTime)
-	records = append(records, record)
 
 	return records, nil
 }
 
+// args returns the insert arguments of a record
+func args(r *store.Record, options store.WriteOptions) []interface{} {
+	var expiry interface{}
+	switch {
+	case !options.Expiry.IsZero():
+		expiry = options.Expiry
+	case options.TTL > 0:
+		expiry = time.Now().Add(options.TTL)
+	case r.Expiry > 0:
+		expiry = time.Now().Add(r.Expiry)
+	}
+
+	return []interface{}{r.Key, r.Value, metadata(r.Metadata), expiry}
+}
+
 // Write records
 func (s *sqlStore) Write(r *store.Record, opts ...store.WriteOption) error {
-	timeCached := time.Now().Add(r.Expiry)
-	_, err := s.writePrepare.Exec(r.Key, r.Value, timeCached, r.Value, timeCached)
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	st, err := s.table(options.Database, options.Table)
 	if err != nil {
+		return err
+	}
+
+	if _, err := st.write.Exec(args(r, options)...); err != nil {
 		return errors.Wrap(err, "Couldn't insert record "+r.Key)
 	}
 
@@ -127,42 +433,25 @@ func (s *sqlStore) Write(r *store.Record, opts ...store.WriteOption) error {
 
 // Delete records with keys
 func (s *sqlStore) Delete(key string, opts ...store.DeleteOption) error {
-	result, err := s.deletePrepare.Exec(key)
-	if err != nil {
-		return err
-	}
-	_, err = result.RowsAffected()
-	if err != nil {
-		return err
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
 	}
 
-	return nil
-}
-
-func (s *sqlStore) initDB() error {
-	// Create the namespace's database
-	_, err := s.db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s ;", s.database))
+	st, err := s.table(options.Database, options.Table)
 	if err != nil {
 		return err
 	}
 
-	_, err = s.db.Exec(fmt.Sprintf("USE %s ;", s.database))
+	result, err := st.delete.Exec(key)
 	if err != nil {
-		return errors.Wrap(err, "Couldn't use database")
+		return err
 	}
-
-	// Create a table for the namespace's prefix
-	createSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (`key` varchar(255) primary key, value blob null, expiry timestamp not null);", s.table)
-	_, err = s.db.Exec(createSQL)
+	_, err = result.RowsAffected()
 	if err != nil {
-		return errors.Wrap(err, "Couldn't create table")
+		return err
 	}
 
-	// prepare
-	s.readPrepare, _ = s.db.Prepare(fmt.Sprintf("SELECT `key`, value, expiry FROM %s.%s WHERE `key` = ?;", s.database, s.table))
-	s.writePrepare, _ = s.db.Prepare(fmt.Sprintf("INSERT INTO %s.%s (`key`, value, expiry) VALUES(?, ?, ?) ON DUPLICATE KEY UPDATE `value`= ?, `expiry` = ?", s.database, s.table))
-	s.deletePrepare, _ = s.db.Prepare(fmt.Sprintf("DELETE FROM %s.%s WHERE `key` = ?;", s.database, s.table))
-
 	return nil
 }
 
@@ -172,19 +461,15 @@ func (s *sqlStore) configure() error {
 		nodes = []string{"localhost:3306"}
 	}
 
-	database := s.options.Database
-	if len(database) == 0 {
-		database = DefaultDatabase
-	}
+	s.naming = nil
+	s.batchSize = DefaultBatchSize
 
-	table := s.options.Table
-	if len(table) == 0 {
-		table = DefaultTable
-	}
-
-	for _, r := range database {
-		if !unicode.IsLetter(r) {
-			return errors.New("store.namespace must only contain letters")
+	if s.options.Context != nil {
+		if fn, ok := s.options.Context.Value(namingKey{}).(NamingFunc); ok {
+			s.naming = fn
+		}
+		if n, ok := s.options.Context.Value(batchSizeKey{}).(int); ok && n > 0 {
+			s.batchSize = n
 		}
 	}
 
@@ -199,17 +484,22 @@ func (s *sqlStore) configure() error {
 		return err
 	}
 
+	s.Lock()
 	if s.db != nil {
+		for _, st := range s.tables {
+			st.close()
+		}
 		s.db.Close()
 	}
 
 	// save the values
 	s.db = db
-	s.database = database
-	s.table = table
+	s.tables = make(map[string]*statements)
+	s.Unlock()
 
-	// initialise the database
-	return s.initDB()
+	// initialise the default table
+	_, err = s.table(s.options.Database, s.options.Table)
+	return err
 }
 
 func (s *sqlStore) String() string {
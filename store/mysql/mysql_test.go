@@ -3,6 +3,7 @@ package mysql
 import (
 	"encoding/json"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,19 +15,27 @@ var (
 	sqlStoreT store.Store
 )
 
+const testNode = "root:123@(127.0.0.1:3306)/test?charset=utf8&parseTime=true&loc=Asia%2FShanghai"
+
 func TestMain(m *testing.M) {
-	if tr := os.Getenv("TRAVIS"); len(tr) > 0 {
-		os.Exit(0)
+	// tests needing mysql are skipped without it
+	if tr := os.Getenv("TRAVIS"); len(tr) == 0 {
+		sqlStoreT = NewStore(
+			store.Database("testMicro"),
+			store.Nodes(testNode),
+		)
 	}
-
-	sqlStoreT = NewStore(
-		store.Database("testMicro"),
-		store.Nodes("root:123@(127.0.0.1:3306)/test?charset=utf8&parseTime=true&loc=Asia%2FShanghai"),
-	)
 	os.Exit(m.Run())
 }
 
+func requireMySQL(t *testing.T) {
+	if sqlStoreT == nil {
+		t.Skip("mysql not available")
+	}
+}
+
 func TestWrite(t *testing.T) {
+	requireMySQL(t)
 	err := sqlStoreT.Write(
 		&store.Record{
 			Key:    "test",
@@ -40,6 +49,7 @@ func TestWrite(t *testing.T) {
 }
 
 func TestDelete(t *testing.T) {
+	requireMySQL(t)
 	err := sqlStoreT.Delete("test")
 	if err != nil {
 		t.Error(err)
@@ -47,6 +57,7 @@ func TestDelete(t *testing.T) {
 }
 
 func TestRead(t *testing.T) {
+	requireMySQL(t)
 	records, err := sqlStoreT.Read("test")
 	if err != nil {
 		t.Error(err)
@@ -56,6 +67,7 @@ func TestRead(t *testing.T) {
 }
 
 func TestList(t *testing.T) {
+	requireMySQL(t)
 	records, err := sqlStoreT.List()
 	if err != nil {
 		t.Error(err)
@@ -64,3 +76,184 @@ func TestList(t *testing.T) {
 		t.Log(string(beauty))
 	}
 }
+
+func TestMigrate(t *testing.T) {
+	requireMySQL(t)
+
+	s := sqlStoreT.(*sqlStore)
+
+	// the table as created before metadata and optional expiries
+	if _, err := s.db.Exec("CREATE DATABASE IF NOT EXISTS `testMicro`;"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.Exec("DROP TABLE IF EXISTS `testMicro`.`migrate`;"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.Exec("CREATE TABLE `testMicro`.`migrate` (`key` varchar(255) primary key, value blob null, expiry timestamp not null);"); err != nil {
+		t.Fatal(err)
+	}
+	defer s.db.Exec("DROP TABLE IF EXISTS `testMicro`.`migrate`;")
+
+	// migrating twice leaves the table as it is
+	for i := 0; i < 2; i++ {
+		if err := s.migrate("testMicro", "migrate"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows, err := s.db.Query("SELECT COLUMN_NAME, IS_NULLABLE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?;", "testMicro", "migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	nullable := make(map[string]string)
+	for rows.Next() {
+		var name, isNullable string
+		if err := rows.Scan(&name, &isNullable); err != nil {
+			t.Fatal(err)
+		}
+		nullable[strings.ToLower(name)] = isNullable
+	}
+	if n := nullable["metadata"]; n != "YES" {
+		t.Fatalf("expected the metadata column to be added got nullable %q", n)
+	}
+	if n := nullable["expiry"]; n != "YES" {
+		t.Fatalf("expected the expiry to be made nullable got %q", n)
+	}
+
+	// records without an expiry can now be written
+	if err := sqlStoreT.Write(&store.Record{Key: "foo", Value: []byte("bar")}, store.WriteTo("testMicro", "migrate")); err != nil {
+		t.Fatal(err)
+	}
+	records, err := sqlStoreT.Read("foo", store.ReadFrom("testMicro", "migrate"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || string(records[0].Value) != "bar" || records[0].Expiry != 0 {
+		t.Fatalf("unexpected records %+v", records)
+	}
+}
+
+func TestGetDB(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     []store.Option
+		database string
+		table    string
+		dbName   string
+		tblName  string
+		err      bool
+	}{
+		{name: "defaults", dbName: DefaultDatabase, tblName: DefaultTable},
+		{name: "store options", opts: []store.Option{store.Database("foo"), store.Table("bar")}, dbName: "foo", tblName: "bar"},
+		{name: "requested", opts: []store.Option{store.Database("foo")}, database: "baz", table: "qux", dbName: "baz", tblName: "qux"},
+		{name: "table prefix", opts: []store.Option{TablePrefix("micro", "store")}, database: "foo", table: "bar", dbName: "micro", tblName: "store_foo_bar"},
+		{name: "table prefix defaults", opts: []store.Option{TablePrefix("micro", "store")}, dbName: "micro", tblName: "store_" + DefaultDatabase + "_" + DefaultTable},
+		{name: "naming", opts: []store.Option{Naming(func(db, table string) (string, string) {
+			return "all", db + table
+		})}, database: "foo", table: "bar", dbName: "all", tblName: "foobar"},
+		{name: "invalid database", database: "foo-bar", err: true},
+		{name: "invalid table", table: "foo`bar", err: true},
+		{name: "invalid name", opts: []store.Option{TablePrefix("micro", "store.")}, err: true},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			s := &sqlStore{}
+			for _, o := range c.opts {
+				o(&s.options)
+			}
+			if s.options.Context != nil {
+				s.naming, _ = s.options.Context.Value(namingKey{}).(NamingFunc)
+			}
+
+			database, table, err := s.getDB(c.database, c.table)
+			if c.err {
+				if err == nil {
+					t.Fatalf("expected an error got %s.%s", database, table)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if database != c.dbName || table != c.tblName {
+				t.Fatalf("expected %s.%s got %s.%s", c.dbName, c.tblName, database, table)
+			}
+		})
+	}
+}
+
+func TestPattern(t *testing.T) {
+	testCases := []struct {
+		prefix, suffix, pattern string
+	}{
+		{"", "", "%"},
+		{"foo", "", "foo%"},
+		{"", "bar", "%bar"},
+		{"foo", "bar", "foo%bar"},
+		{"100%", "_x", `100\%%\_x`},
+		{`a\b`, "", `a\\b%`},
+	}
+
+	for _, c := range testCases {
+		if p := pattern(c.prefix, c.suffix); p != c.pattern {
+			t.Fatalf("expected pattern %s for %q and %q got %s", c.pattern, c.prefix, c.suffix, p)
+		}
+	}
+}
+
+func TestLimit(t *testing.T) {
+	if l := limit(0); l != maxLimit {
+		t.Fatalf("expected no limit to be the max limit got %d", l)
+	}
+	if l := limit(10); l != 10 {
+		t.Fatalf("expected a limit of 10 got %d", l)
+	}
+}
+
+func TestArgs(t *testing.T) {
+	at := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	testCases := []struct {
+		name    string
+		record  *store.Record
+		options store.WriteOptions
+		expiry  time.Duration
+		at      time.Time
+	}{
+		{name: "no expiry", record: &store.Record{Key: "foo"}},
+		{name: "record expiry", record: &store.Record{Key: "foo", Expiry: time.Minute}, expiry: time.Minute},
+		{name: "ttl over record", record: &store.Record{Key: "foo", Expiry: time.Minute}, options: store.WriteOptions{TTL: time.Hour}, expiry: time.Hour},
+		{name: "expiry over ttl", record: &store.Record{Key: "foo", Expiry: time.Minute}, options: store.WriteOptions{TTL: time.Minute, Expiry: at}, at: at},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			a := args(c.record, c.options)
+			if len(a) != 4 || a[0] != c.record.Key {
+				t.Fatalf("unexpected args %v", a)
+			}
+
+			switch {
+			case !c.at.IsZero():
+				if e, ok := a[3].(time.Time); !ok || !e.Equal(c.at) {
+					t.Fatalf("expected an expiry of %v got %v", c.at, a[3])
+				}
+			case c.expiry > 0:
+				e, ok := a[3].(time.Time)
+				if !ok {
+					t.Fatalf("expected an expiry got %v", a[3])
+				}
+				if d := time.Until(e); d > c.expiry || d < c.expiry-time.Minute {
+					t.Fatalf("expected an expiry in %v got %v", c.expiry, d)
+				}
+			default:
+				if a[3] != nil {
+					t.Fatalf("expected no expiry got %v", a[3])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/store"
+)
+
+type namingKey struct{}
+type batchSizeKey struct{}
+
+// NamingFunc maps the database and table of a request, e.g. a namespace,
+// to the mysql database and table it's stored in
+type NamingFunc func(database, table string) (string, string)
+
+// Naming sets how databases and tables are named. By default each store
+// database is a mysql database and each store table a table within it.
+func Naming(fn NamingFunc) store.Option {
+	return setOption(namingKey{}, fn)
+}
+
+// TablePrefix keeps every namespace in the one mysql database, naming
+// the table for a store database and table prefix_database_table
+func TablePrefix(database, prefix string) store.Option {
+	return Naming(func(db, table string) (string, string) {
+		return database, prefix + "_" + db + "_" + table
+	})
+}
+
+// BatchSize sets the maximum number of rows in a single statement of WriteMany, ReadMany and DeleteMany
+func BatchSize(n int) store.Option {
+	return setOption(batchSizeKey{}, n)
+}
+
+func setOption(k, v interface{}) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
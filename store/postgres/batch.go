@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/micro/go-micro/v2/store"
+	"github.com/pkg/errors"
+)
+
+// DefaultBatchSize is the maximum number of rows inserted by a single statement
+var DefaultBatchSize = 100
+
+// ReadMany reads the records of the keys which exist in a single query
+func (s *sqlStore) ReadMany(keys []string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, err := s.table(options.Database, options.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	q := fmt.Sprintf(`SELECT key, value, metadata, expiry FROM %s WHERE key = ANY($1) AND (expiry IS NULL OR expiry > now()) ORDER BY key;`, name)
+
+	rows, err := s.db.Query(q, pq.Array(keys))
+	if err != nil {
+		return nil, errors.Wrap(err, "Couldn't read records")
+	}
+	defer rows.Close()
+
+	var records []*store.Record
+
+	for rows.Next() {
+		var expiry pq.NullTime
+		var md metadata
+		record := &store.Record{}
+
+		if err := rows.Scan(&record.Key, &record.Value, &md, &expiry); err != nil {
+			return records, err
+		}
+
+		record.Metadata = md
+		if expiry.Valid {
+			record.Expiry = time.Until(expiry.Time)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// WriteMany writes the records in one transaction using multi row inserts
+func (s *sqlStore) WriteMany(records []*store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, err := s.table(options.Database, options.Table)
+	if err != nil {
+		return err
+	}
+
+	// a key can only be inserted once per statement, the last record wins
+	last := make(map[string]int, len(records))
+	for i, r := range records {
+		last[r.Key] = i
+	}
+	unique := make([]*store.Record, 0, len(last))
+	for i, r := range records {
+		if last[r.Key] == i {
+			unique = append(unique, r)
+		}
+	}
+	records = unique
+
+	cast := "bytea"
+	if s.jsonValues {
+		cast = "jsonb"
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for len(records) > 0 {
+		n := len(records)
+		if n > DefaultBatchSize {
+			n = DefaultBatchSize
+		}
+
+		values := make([]string, 0, n)
+		args := make([]interface{}, 0, n*4)
+
+		for i, r := range records[:n] {
+			var value interface{} = r.Value
+			if s.jsonValues {
+				if !json.Valid(r.Value) {
+					return errors.New("Couldn't insert record " + r.Key + ": value is not valid json")
+				}
+				value = string(r.Value)
+			}
+
+			if s.advisoryLock {
+				if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1));", name+"/"+r.Key); err != nil {
+					return errors.Wrap(err, "Couldn't lock record "+r.Key)
+				}
+			}
+
+			p := i * 4
+			values = append(values, fmt.Sprintf("($%d, $%d::%s, $%d, $%d)", p+1, p+2, cast, p+3, p+4))
+			args = append(args, r.Key, value, metadata(r.Metadata), expiry(r, options))
+		}
+
+		q := fmt.Sprintf(`INSERT INTO %s (key, value, metadata, expiry) VALUES %s
+	ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, metadata = EXCLUDED.metadata, expiry = EXCLUDED.expiry;`, name, strings.Join(values, ", "))
+
+		if _, err := tx.Exec(q, args...); err != nil {
+			return errors.Wrap(err, "Couldn't insert records")
+		}
+
+		records = records[n:]
+	}
+
+	return tx.Commit()
+}
+
+// DeleteMany deletes the keys in a single query
+func (s *sqlStore) DeleteMany(keys []string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, err := s.table(options.Database, options.Table)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE key = ANY($1);", name), pq.Array(keys))
+	return err
+}
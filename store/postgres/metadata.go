@@ -0,0 +1,33 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// metadata is a record's metadata stored as jsonb
+type metadata map[string]interface{}
+
+// Scan satisfies the sql.Scanner interface
+func (m *metadata) Scan(src interface{}) error {
+	if src == nil {
+		*m = nil
+		return nil
+	}
+
+	source, ok := src.([]byte)
+	if !ok {
+		return errors.New("metadata must be []byte")
+	}
+
+	return json.Unmarshal(source, m)
+}
+
+// Value satisfies the driver.Valuer interface
+func (m metadata) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return json.Marshal(m)
+}
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+)
+
+type jsonValuesKey struct{}
+type advisoryLockKey struct{}
+type reapIntervalKey struct{}
+
+// JSONValues stores record values in a jsonb column so they can be queried
+// and indexed in postgres. Writing a value which isn't valid json fails.
+func JSONValues() store.Option {
+	return setOption(jsonValuesKey{}, true)
+}
+
+// AdvisoryLock serialises writes to the same key across processes by
+// taking a transaction scoped advisory lock on the key
+func AdvisoryLock() store.Option {
+	return setOption(advisoryLockKey{}, true)
+}
+
+// ReapInterval sets how often expired records are deleted. Expired records
+// are never returned, the reaper only reclaims their space.
+func ReapInterval(d time.Duration) store.Option {
+	return setOption(reapIntervalKey{}, d)
+}
+
+func setOption(k, v interface{}) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, k, v)
+	}
+}
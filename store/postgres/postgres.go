@@ -0,0 +1,467 @@
+// Package postgres implements the store using postgres
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/store"
+	"github.com/pkg/errors"
+)
+
+var (
+	// DefaultDatabase is the schema used if no database is provided
+	DefaultDatabase = "micro"
+	// DefaultTable is the table used if no table is provided
+	DefaultTable = "micro"
+	// DefaultReapInterval is how often expired records are deleted
+	DefaultReapInterval = time.Minute
+
+	re = regexp.MustCompile("[^a-zA-Z0-9]+")
+
+	// escapes the LIKE wildcards in keys
+	likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+)
+
+type sqlStore struct {
+	options store.Options
+	db      *sql.DB
+
+	jsonValues   bool
+	advisoryLock bool
+	reapInterval time.Duration
+
+	sync.RWMutex
+	// known tables
+	tables map[string]bool
+	exit   chan bool
+}
+
+// getDB returns the sanitised schema and table names
+func (s *sqlStore) getDB(database, table string) (string, string) {
+	if len(database) == 0 {
+		database = s.options.Database
+	}
+	if len(database) == 0 {
+		database = DefaultDatabase
+	}
+
+	if len(table) == 0 {
+		table = s.options.Table
+	}
+	if len(table) == 0 {
+		table = DefaultTable
+	}
+
+	// names may only contain letters, numbers and underscores
+	database = re.ReplaceAllString(database, "_")
+	table = re.ReplaceAllString(table, "_")
+
+	return database, table
+}
+
+// table creates the schema and table if they don't exist and returns the qualified table name
+func (s *sqlStore) table(database, table string) (string, error) {
+	database, table = s.getDB(database, table)
+	name := database + "." + table
+
+	s.RLock()
+	ok := s.tables[name]
+	s.RUnlock()
+	if ok {
+		return name, nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.tables[name] {
+		return name, nil
+	}
+
+	if err := s.initDB(database, table); err != nil {
+		return "", err
+	}
+
+	s.tables[name] = true
+	return name, nil
+}
+
+func (s *sqlStore) initDB(database, table string) error {
+	if s.db == nil {
+		return errors.New("database connection not initialised")
+	}
+
+	if _, err := s.db.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s;", database)); err != nil {
+		return errors.Wrap(err, "Couldn't create schema")
+	}
+
+	valueType := "bytea"
+	if s.jsonValues {
+		valueType = "jsonb"
+	}
+
+	_, err := s.db.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.%s
+	(
+		key text NOT NULL,
+		value %s,
+		metadata jsonb,
+		expiry timestamp with time zone,
+		CONSTRAINT %s_pkey PRIMARY KEY (key)
+	);`, database, table, valueType, table))
+	if err != nil {
+		return errors.Wrap(err, "Couldn't create table")
+	}
+
+	// the reaper looks records up by expiry
+	_, err = s.db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_expiry_index ON %s.%s (expiry) WHERE expiry IS NOT NULL;`, table, database, table))
+	if err != nil {
+		return errors.Wrap(err, "Couldn't create expiry index")
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_metadata_index ON %s.%s USING GIN (metadata);`, table, database, table))
+	if err != nil {
+		return errors.Wrap(err, "Couldn't create metadata index")
+	}
+
+	return nil
+}
+
+func (s *sqlStore) configure() error {
+	if s.options.Context != nil {
+		if v, ok := s.options.Context.Value(jsonValuesKey{}).(bool); ok {
+			s.jsonValues = v
+		}
+		if v, ok := s.options.Context.Value(advisoryLockKey{}).(bool); ok {
+			s.advisoryLock = v
+		}
+		if v, ok := s.options.Context.Value(reapIntervalKey{}).(time.Duration); ok && v > 0 {
+			s.reapInterval = v
+		}
+	}
+
+	nodes := s.options.Nodes
+	if len(nodes) == 0 {
+		nodes = []string{"postgres://postgres@localhost:5432/postgres?sslmode=disable"}
+	}
+
+	source := nodes[0]
+	// accept urls as well as key=value connection strings e.g host=%s port=%d user=%s
+	if _, err := url.Parse(source); err != nil && !strings.Contains(source, " ") {
+		source = fmt.Sprintf("host=%s", source)
+	}
+
+	db, err := sql.Open("postgres", source)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Ping(); err != nil {
+		return err
+	}
+
+	s.Lock()
+	if s.db != nil {
+		s.db.Close()
+	}
+	if s.exit != nil {
+		close(s.exit)
+	}
+	s.db = db
+	s.tables = make(map[string]bool)
+	s.exit = make(chan bool)
+	s.Unlock()
+
+	go s.reap(s.exit)
+
+	// create the default table
+	_, err = s.table(s.options.Database, s.options.Table)
+	return err
+}
+
+// reap periodically deletes expired records from every known table
+func (s *sqlStore) reap(exit chan bool) {
+	t := time.NewTicker(s.reapInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-exit:
+			return
+		case <-t.C:
+		}
+
+		s.RLock()
+		tables := make([]string, 0, len(s.tables))
+		for name := range s.tables {
+			tables = append(tables, name)
+		}
+		s.RUnlock()
+
+		for _, name := range tables {
+			_, err := s.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE expiry < now();", name))
+			if err != nil && log.V(log.ErrorLevel, log.DefaultLogger) {
+				log.Errorf("Error reaping expired records from %s: %v", name, err)
+			}
+		}
+	}
+}
+
+// pattern returns the LIKE pattern matching keys with the prefix and suffix
+func pattern(prefix, suffix string) string {
+	return likeEscaper.Replace(prefix) + "%" + likeEscaper.Replace(suffix)
+}
+
+// paginate returns the LIMIT and OFFSET clause
+func paginate(limit, offset uint) string {
+	if limit == 0 {
+		if offset == 0 {
+			return ""
+		}
+		return fmt.Sprintf(" OFFSET %d", offset)
+	}
+	return fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+}
+
+func (s *sqlStore) Init(opts ...store.Option) error {
+	for _, o := range opts {
+		o(&s.options)
+	}
+	// reconfigure
+	return s.configure()
+}
+
+func (s *sqlStore) Options() store.Options {
+	return s.options
+}
+
+func (s *sqlStore) Close() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.exit != nil {
+		close(s.exit)
+		s.exit = nil
+	}
+	if s.db != nil {
+		return s.db.Close()
+	}
+	return nil
+}
+
+// List all the known keys
+func (s *sqlStore) List(opts ...store.ListOption) ([]string, error) {
+	var options store.ListOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, err := s.table(options.Database, options.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	q := fmt.Sprintf(`SELECT key FROM %s WHERE key LIKE $1 AND (expiry IS NULL OR expiry > now()) ORDER BY key%s;`,
+		name, paginate(options.Limit, options.Offset))
+
+	rows, err := s.db.Query(q, pattern(options.Prefix, options.Suffix))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return keys, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Read a single key, or every key with the prefix or suffix
+func (s *sqlStore) Read(key string, opts ...store.ReadOption) ([]*store.Record, error) {
+	var options store.ReadOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, err := s.table(options.Database, options.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	where := "key = $1"
+	arg := key
+
+	if options.Prefix || options.Suffix {
+		var prefix, suffix string
+		if options.Prefix {
+			prefix = key
+		}
+		if options.Suffix {
+			suffix = key
+		}
+		where = "key LIKE $1"
+		arg = pattern(prefix, suffix)
+	}
+
+	q := fmt.Sprintf(`SELECT key, value, metadata, expiry FROM %s WHERE %s AND (expiry IS NULL OR expiry > now()) ORDER BY key%s;`,
+		name, where, paginate(options.Limit, options.Offset))
+
+	rows, err := s.db.Query(q, arg)
+	if err != nil {
+		return nil, errors.Wrap(err, "Couldn't read records")
+	}
+	defer rows.Close()
+
+	var records []*store.Record
+
+	for rows.Next() {
+		var expiry pq.NullTime
+		var md metadata
+		record := &store.Record{}
+
+		if err := rows.Scan(&record.Key, &record.Value, &md, &expiry); err != nil {
+			return records, err
+		}
+
+		record.Metadata = md
+		if expiry.Valid {
+			record.Expiry = time.Until(expiry.Time)
+		}
+
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return records, err
+	}
+
+	if len(records) == 0 && !options.Prefix && !options.Suffix {
+		return records, store.ErrNotFound
+	}
+
+	return records, nil
+}
+
+// expiry returns the expiry time of a record or nil if it doesn't expire
+func expiry(r *store.Record, options store.WriteOptions) interface{} {
+	switch {
+	case !options.Expiry.IsZero():
+		return options.Expiry
+	case options.TTL > 0:
+		return time.Now().Add(options.TTL)
+	case r.Expiry > 0:
+		return time.Now().Add(r.Expiry)
+	}
+	return nil
+}
+
+// Write a record
+func (s *sqlStore) Write(r *store.Record, opts ...store.WriteOption) error {
+	var options store.WriteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, err := s.table(options.Database, options.Table)
+	if err != nil {
+		return err
+	}
+
+	var value interface{} = r.Value
+	cast := "bytea"
+	if s.jsonValues {
+		if !json.Valid(r.Value) {
+			return errors.New("Couldn't insert record " + r.Key + ": value is not valid json")
+		}
+		value = string(r.Value)
+		cast = "jsonb"
+	}
+
+	q := fmt.Sprintf(`INSERT INTO %s (key, value, metadata, expiry) VALUES ($1, $2::%s, $3, $4)
+	ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, metadata = EXCLUDED.metadata, expiry = EXCLUDED.expiry;`, name, cast)
+	args := []interface{}{r.Key, value, metadata(r.Metadata), expiry(r, options)}
+
+	if !s.advisoryLock {
+		if _, err := s.db.Exec(q, args...); err != nil {
+			return errors.Wrap(err, "Couldn't insert record "+r.Key)
+		}
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// held until the transaction ends
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1));", name+"/"+r.Key); err != nil {
+		return errors.Wrap(err, "Couldn't lock record "+r.Key)
+	}
+
+	if _, err := tx.Exec(q, args...); err != nil {
+		return errors.Wrap(err, "Couldn't insert record "+r.Key)
+	}
+
+	return tx.Commit()
+}
+
+// Delete a record
+func (s *sqlStore) Delete(key string, opts ...store.DeleteOption) error {
+	var options store.DeleteOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name, err := s.table(options.Database, options.Table)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE key = $1;", name), key)
+	return err
+}
+
+func (s *sqlStore) String() string {
+	return "postgres"
+}
+
+// NewStore returns a new micro Store backed by postgres
+func NewStore(opts ...store.Option) store.Store {
+	options := store.Options{
+		Database: DefaultDatabase,
+		Table:    DefaultTable,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	s := &sqlStore{
+		options:      options,
+		reapInterval: DefaultReapInterval,
+		tables:       make(map[string]bool),
+	}
+
+	// best-effort configure the store
+	if err := s.configure(); err != nil {
+		if log.V(log.ErrorLevel, log.DefaultLogger) {
+			log.Error("Error configuring store ", err)
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+)
+
+func TestPattern(t *testing.T) {
+	testData := []struct {
+		prefix, suffix string
+		pattern        string
+	}{
+		{"", "", "%"},
+		{"user/", "", "user/%"},
+		{"", ".json", "%.json"},
+		{"100%_", "", `100\%\_%`},
+	}
+
+	for _, d := range testData {
+		if p := pattern(d.prefix, d.suffix); p != d.pattern {
+			t.Fatalf("expected %s got %s", d.pattern, p)
+		}
+	}
+}
+
+func TestStore(t *testing.T) {
+	addr := os.Getenv("POSTGRES_ADDR")
+	if len(addr) == 0 {
+		t.Skip("POSTGRES_ADDR not set")
+	}
+
+	s := NewStore(store.Nodes(addr), store.Table("test"))
+	defer s.Close()
+
+	records := []*store.Record{
+		{Key: "user/1", Value: []byte(`{"name":"alice"}`)},
+		{Key: "user/2", Value: []byte(`{"name":"bob"}`), Metadata: map[string]interface{}{"admin": true}},
+		{Key: "session/1", Value: []byte("x"), Expiry: time.Millisecond},
+	}
+	for _, r := range records {
+		if err := s.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	time.Sleep(time.Millisecond * 10)
+
+	if _, err := s.Read("session/1"); err != store.ErrNotFound {
+		t.Fatalf("expected expired record to be not found got %v", err)
+	}
+
+	rs, err := s.Read("user/", store.ReadPrefix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 2 {
+		t.Fatalf("expected 2 records got %d", len(rs))
+	}
+	if rs[1].Metadata["admin"] != true {
+		t.Fatalf("expected metadata to be returned got %v", rs[1].Metadata)
+	}
+
+	keys, err := s.List(store.ListPrefix("user/"), store.ListLimit(1), store.ListOffset(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "user/2" {
+		t.Fatalf("expected [user/2] got %v", keys)
+	}
+
+	for _, r := range records {
+		if err := s.Delete(r.Key); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"github.com/go-redis/redis/v7"
+	"github.com/micro/go-micro/v2/store"
+)
+
+// ReadMany reads the keys with a single MGET and their ttls in one pipeline
+func (r *rkv) ReadMany(keys []string, opts ...store.ReadOption) ([]*store.Record, error) {
+	options := store.ReadOptions{}
+	options.Table = r.options.Table
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	rkeys := make([]string, len(keys))
+	for i, k := range keys {
+		rkeys[i] = r.key(options.Table, k)
+	}
+
+	vals, err := r.Client.MGet(rkeys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	pipe := r.Client.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(rkeys))
+	for i, rkey := range rkeys {
+		if vals[i] != nil {
+			ttls[i] = pipe.TTL(rkey)
+		}
+	}
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	records := make([]*store.Record, 0, len(keys))
+	for i, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+
+		record := &store.Record{
+			Key:   keys[i],
+			Value: []byte(s),
+		}
+		if d := ttls[i].Val(); d > 0 {
+			record.Expiry = d
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// WriteMany writes the records in one pipeline
+func (r *rkv) WriteMany(records []*store.Record, opts ...store.WriteOption) error {
+	options := store.WriteOptions{}
+	options.Table = r.options.Table
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	pipe := r.Client.TxPipeline()
+	for _, record := range records {
+		rkey := r.key(options.Table, record.Key)
+		pipe.Set(rkey, record.Value, record.Expiry)
+	}
+
+	_, err := pipe.Exec()
+	return err
+}
+
+// DeleteMany deletes the keys with a single DEL
+func (r *rkv) DeleteMany(keys []string, opts ...store.DeleteOption) error {
+	options := store.DeleteOptions{}
+	options.Table = r.options.Table
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	rkeys := make([]string, len(keys))
+	for i, k := range keys {
+		rkeys[i] = r.key(options.Table, k)
+	}
+
+	return r.Client.Del(rkeys...).Err()
+}
@@ -0,0 +1,21 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/store"
+)
+
+type tableSeparatorKey struct{}
+
+// TableSeparator sets a separator between the table and the key, e.g ":",
+// so tables sharing a name prefix such as foo and foobar don't list each
+// others keys. By default keys are stored as the table followed by the key.
+func TableSeparator(sep string) store.Option {
+	return func(o *store.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, tableSeparatorKey{}, sep)
+	}
+}
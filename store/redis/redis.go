@@ -1,7 +1,8 @@
 package redis
 
 import (
-	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/go-redis/redis/v7"
 	log "github.com/micro/go-micro/v2/logger"
@@ -11,6 +12,8 @@ import (
 type rkv struct {
 	options store.Options
 	Client  *redis.Client
+	// separator between the table and key, none by default
+	separator string
 }
 
 func (r *rkv) Init(opts ...store.Option) error {
@@ -35,26 +38,24 @@ func (r *rkv) Read(key string, opts ...store.ReadOption) ([]*store.Record, error
 
 	var keys []string
 
-	rkey := fmt.Sprintf("%s%s", options.Table, key)
 	// Handle Prefix
 	// TODO suffix
 	if options.Prefix {
-		prefixKey := fmt.Sprintf("%s*", rkey)
-		fkeys, err := r.Client.Keys(prefixKey).Result()
+		fkeys, err := r.scan(options.Table, key, "")
 		if err != nil {
 			return nil, err
 		}
-		// TODO Limit Offset
 
-		keys = append(keys, fkeys...)
+		keys = paginate(fkeys, options.Offset, options.Limit)
 
 	} else {
-		keys = []string{rkey}
+		keys = []string{key}
 	}
 
 	records := make([]*store.Record, 0, len(keys))
 
-	for _, rkey = range keys {
+	for _, key = range keys {
+		rkey := r.key(options.Table, key)
 		val, err := r.Client.Get(rkey).Bytes()
 
 		if err != nil && err == redis.Nil {
@@ -90,7 +91,7 @@ func (r *rkv) Delete(key string, opts ...store.DeleteOption) error {
 		o(&options)
 	}
 
-	rkey := fmt.Sprintf("%s%s", options.Table, key)
+	rkey := r.key(options.Table, key)
 	return r.Client.Del(rkey).Err()
 }
 
@@ -102,10 +103,58 @@ func (r *rkv) Write(record *store.Record, opts ...store.WriteOption) error {
 		o(&options)
 	}
 
-	rkey := fmt.Sprintf("%s%s", options.Table, record.Key)
+	rkey := r.key(options.Table, record.Key)
 	return r.Client.Set(rkey, record.Value, record.Expiry).Err()
 }
 
+// globEscaper escapes the glob characters of keys matched with SCAN
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// key returns the redis key of a key in the table
+func (r *rkv) key(table, key string) string {
+	if len(table) == 0 {
+		return key
+	}
+	return table + r.separator + key
+}
+
+// match returns the SCAN pattern of the table keys with the prefix and suffix
+func (r *rkv) match(table, prefix, suffix string) string {
+	return globEscaper.Replace(r.key(table, prefix)) + "*" + globEscaper.Replace(suffix)
+}
+
+// scan returns the keys of the table with the prefix and suffix in order.
+// Keys are found with SCAN rather than KEYS so searching large keyspaces
+// doesn't block the server.
+func (r *rkv) scan(table, prefix, suffix string) ([]string, error) {
+	var keys []string
+	iter := r.Client.Scan(0, r.match(table, prefix, suffix), 1000).Iterator()
+	for iter.Next() {
+		keys = append(keys, strings.TrimPrefix(iter.Val(), r.key(table, "")))
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// paginate returns the keys from the offset, at most limit of them if set
+func paginate(keys []string, offset, limit uint) []string {
+	start := int(offset)
+	if start > len(keys) {
+		start = len(keys)
+	}
+	end := len(keys)
+	if limit > 0 && start+int(limit) < end {
+		end = start + int(limit)
+	}
+	return keys[start:end]
+}
+
+// List the keys of the table in order
 func (r *rkv) List(opts ...store.ListOption) ([]string, error) {
 	options := store.ListOptions{}
 	options.Table = r.options.Table
@@ -114,12 +163,12 @@ func (r *rkv) List(opts ...store.ListOption) ([]string, error) {
 		o(&options)
 	}
 
-	keys, err := r.Client.Keys("*").Result()
+	keys, err := r.scan(options.Table, options.Prefix, options.Suffix)
 	if err != nil {
 		return nil, err
 	}
 
-	return keys, nil
+	return paginate(keys, options.Offset, options.Limit), nil
 }
 
 func (r *rkv) Options() store.Options {
@@ -166,5 +215,12 @@ func (r *rkv) configure() error {
 
 	r.Client = redis.NewClient(redisOptions)
 
+	r.separator = ""
+	if r.options.Context != nil {
+		if sep, ok := r.options.Context.Value(tableSeparatorKey{}).(string); ok {
+			r.separator = sep
+		}
+	}
+
 	return nil
 }
@@ -4,6 +4,8 @@ import (
 	"github.com/go-redis/redis/v7"
 	"github.com/micro/go-micro/v2/store"
 	"os"
+	"path"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -81,6 +83,84 @@ func Test_rkv_configure(t *testing.T) {
 	}
 }
 
+func Test_match(t *testing.T) {
+	tests := []struct {
+		name      string
+		separator string
+		table     string
+		prefix    string
+		suffix    string
+		key       string
+		matched   bool
+	}{
+		{name: "table key", table: "foo", key: "foobar", matched: true},
+		{name: "key of no table", table: "foo", key: "bar", matched: false},
+		{name: "separated table key", separator: ":", table: "foo", key: "foo:bar", matched: true},
+		{name: "table sharing the name prefix", separator: ":", table: "foo", key: "foobar:baz", matched: false},
+		{name: "no table", separator: ":", key: "foo:bar", matched: true},
+		{name: "prefix and suffix", separator: ":", table: "foo", prefix: "a", suffix: "z", key: "foo:abcz", matched: true},
+		{name: "other suffix", separator: ":", table: "foo", prefix: "a", suffix: "z", key: "foo:abcy", matched: false},
+		{name: "glob table", table: "f*", key: "fbarbaz", matched: false},
+		{name: "escaped glob table", separator: ":", table: "f*[o]?", key: "f*[o]?:bar", matched: true},
+		{name: "glob prefix", prefix: "[ab]", key: "abc", matched: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &rkv{separator: tt.separator}
+			m := r.match(tt.table, tt.prefix, tt.suffix)
+			matched, err := path.Match(m, tt.key)
+			if err != nil {
+				t.Fatalf("match() pattern %q error = %v", m, err)
+			}
+			if matched != tt.matched {
+				t.Errorf("match() pattern %q matched %q = %v, want %v", m, tt.key, matched, tt.matched)
+			}
+		})
+	}
+}
+
+func Test_configure_separator(t *testing.T) {
+	r := &rkv{}
+	if err := r.configure(); err != nil {
+		t.Fatal(err)
+	}
+	if k := r.key("foo", "bar"); k != "foobar" {
+		t.Errorf("key() = %v, want foobar", k)
+	}
+
+	TableSeparator(":")(&r.options)
+	if err := r.configure(); err != nil {
+		t.Fatal(err)
+	}
+	if k := r.key("foo", "bar"); k != "foo:bar" {
+		t.Errorf("key() = %v, want foo:bar", k)
+	}
+}
+
+func Test_paginate(t *testing.T) {
+	keys := []string{"a", "b", "c", "d"}
+	tests := []struct {
+		name   string
+		offset uint
+		limit  uint
+		want   []string
+	}{
+		{name: "all", want: keys},
+		{name: "limit", limit: 2, want: []string{"a", "b"}},
+		{name: "offset", offset: 1, want: []string{"b", "c", "d"}},
+		{name: "offset and limit", offset: 1, limit: 2, want: []string{"b", "c"}},
+		{name: "limit past the end", offset: 3, limit: 2, want: []string{"d"}},
+		{name: "offset past the end", offset: 5, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := paginate(keys, tt.offset, tt.limit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paginate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_Store(t *testing.T) {
 	if tr := os.Getenv("TRAVIS"); len(tr) > 0 {
 		t.Skip()
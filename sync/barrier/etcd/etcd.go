@@ -0,0 +1,204 @@
+// Package etcd is an etcd backed distributed barrier
+package etcd
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	client "github.com/coreos/etcd/clientv3"
+	cc "github.com/coreos/etcd/clientv3/concurrency"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/google/uuid"
+)
+
+// Barrier blocks participants until all of them have arrived
+type Barrier interface {
+	// Enter blocks until the expected number of participants have entered
+	Enter(ctx context.Context) error
+	// Leave blocks until every participant has left
+	Leave(ctx context.Context) error
+}
+
+type etcdBarrier struct {
+	opts   Options
+	client *client.Client
+	key    string
+	count  int
+
+	session *cc.Session
+	myKey   string
+}
+
+var (
+	// DefaultPrefix of barrier keys
+	DefaultPrefix = "/micro/sync/barrier"
+	// DefaultTTL of the participant session in seconds
+	DefaultTTL = 15
+
+	// ErrTooManyParticipants is returned when more than the expected participants enter
+	ErrTooManyParticipants = errors.New("too many participants")
+)
+
+func (e *etcdBarrier) waiters() string {
+	return e.key + "/waiters/"
+}
+
+func (e *etcdBarrier) ready() string {
+	return e.key + "/ready"
+}
+
+// wait blocks until an event of type t happens for the key after rev
+func (e *etcdBarrier) wait(ctx context.Context, key string, rev int64, t mvccpb.Event_EventType, opts ...client.OpOption) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	opts = append(opts, client.WithRev(rev))
+	for rsp := range e.client.Watch(ctx, key, opts...) {
+		if err := rsp.Err(); err != nil {
+			return err
+		}
+		for _, ev := range rsp.Events {
+			if ev.Type == t {
+				return nil
+			}
+		}
+	}
+	return ctx.Err()
+}
+
+func (e *etcdBarrier) Enter(ctx context.Context) error {
+	if e.session != nil {
+		return errors.New("already entered")
+	}
+
+	s, err := cc.NewSession(e.client, cc.WithTTL(e.opts.TTL), cc.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	key := e.waiters() + uuid.New().String()
+	put, err := e.client.Put(ctx, key, "", client.WithLease(s.Lease()))
+	if err != nil {
+		s.Close()
+		return err
+	}
+
+	e.session = s
+	e.myKey = key
+
+	// participants failing to enter leave so they can enter again
+	if err := e.enter(ctx, put.Header.Revision); err != nil {
+		e.leave()
+		return err
+	}
+
+	return nil
+}
+
+// enter blocks until the barrier is released after the participant key was put at rev
+func (e *etcdBarrier) enter(ctx context.Context, rev int64) error {
+	rsp, err := e.client.Get(ctx, e.waiters(), client.WithPrefix(), client.WithCountOnly())
+	if err != nil {
+		return err
+	}
+
+	if rsp.Count > int64(e.count) {
+		return ErrTooManyParticipants
+	}
+
+	// the last to arrive releases everyone, the ready key is removed with
+	// its session so a crash before leaving doesn't release later rounds
+	if rsp.Count == int64(e.count) {
+		_, err = e.client.Put(ctx, e.ready(), "", client.WithLease(e.session.Lease()))
+		return err
+	}
+
+	// the barrier may have been released before we started watching
+	ready, err := e.client.Get(ctx, e.ready())
+	if err != nil {
+		return err
+	}
+	if ready.Count > 0 {
+		return nil
+	}
+
+	return e.wait(ctx, e.ready(), rev, mvccpb.PUT)
+}
+
+func (e *etcdBarrier) leave() {
+	if e.session == nil {
+		return
+	}
+	e.client.Delete(context.Background(), e.myKey)
+	e.session.Close()
+	e.session = nil
+}
+
+func (e *etcdBarrier) Leave(ctx context.Context) error {
+	if e.session == nil {
+		return errors.New("not entered")
+	}
+
+	e.leave()
+
+	for {
+		rsp, err := e.client.Get(ctx, e.waiters(), client.WithPrefix(), client.WithCountOnly())
+		if err != nil {
+			return err
+		}
+
+		// the last to leave resets the barrier
+		if rsp.Count == 0 {
+			_, err = e.client.Delete(ctx, e.ready())
+			return err
+		}
+
+		if err := e.wait(ctx, e.waiters(), rsp.Header.Revision+1, mvccpb.DELETE, client.WithPrefix()); err != nil {
+			return err
+		}
+	}
+}
+
+// NewBarrier returns a barrier for name which releases once count participants have entered
+func NewBarrier(name string, count int, opts ...Option) (Barrier, error) {
+	options := Options{
+		Prefix: DefaultPrefix,
+		TTL:    DefaultTTL,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if count < 1 {
+		return nil, errors.New("count must be at least 1")
+	}
+
+	var endpoints []string
+	for _, addr := range options.Nodes {
+		if len(addr) > 0 {
+			endpoints = append(endpoints, addr)
+		}
+	}
+
+	if len(endpoints) == 0 {
+		endpoints = []string{"http://127.0.0.1:2379"}
+	}
+
+	c, err := client.New(client.Config{
+		Endpoints:   endpoints,
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &etcdBarrier{
+		opts:   options,
+		client: c,
+		key:    path.Join(options.Prefix, strings.Replace(name, "/", "-", -1)),
+		count:  count,
+	}, nil
+}
@@ -0,0 +1,173 @@
+package etcd
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	client "github.com/coreos/etcd/clientv3"
+)
+
+func newTestBarrier(t *testing.T, count int) *etcdBarrier {
+	addrs := os.Getenv("ETCD_ADDRS")
+	if len(addrs) == 0 {
+		t.Skip("ETCD_ADDRS not set")
+	}
+	b, err := NewBarrier("barrier", count, Nodes(strings.Split(addrs, ",")...), Prefix("/"+t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.(*etcdBarrier)
+}
+
+func TestBarrier(t *testing.T) {
+	var entered sync.WaitGroup
+	var done sync.WaitGroup
+	released := make(chan int, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	barriers := make([]*etcdBarrier, 3)
+	for i := range barriers {
+		barriers[i] = newTestBarrier(t, 3)
+	}
+
+	for i, b := range barriers[:2] {
+		entered.Add(1)
+		go func(i int, b *etcdBarrier) {
+			defer entered.Done()
+			if err := b.Enter(ctx); err != nil {
+				t.Error(err)
+			}
+			released <- i
+		}(i, b)
+	}
+
+	select {
+	case i := <-released:
+		t.Fatalf("expected participant %d to wait for the others", i)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	if err := barriers[2].Enter(ctx); err != nil {
+		t.Fatal(err)
+	}
+	entered.Wait()
+
+	for _, b := range barriers {
+		done.Add(1)
+		go func(b *etcdBarrier) {
+			defer done.Done()
+			if err := b.Leave(ctx); err != nil {
+				t.Error(err)
+			}
+		}(b)
+	}
+	done.Wait()
+
+	rsp, err := barriers[0].client.Get(ctx, barriers[0].ready())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Count != 0 {
+		t.Fatal("expected the barrier to be reset once everyone left")
+	}
+}
+
+func TestTooManyParticipants(t *testing.T) {
+	ctx := context.Background()
+	first := newTestBarrier(t, 1)
+	second := newTestBarrier(t, 1)
+
+	if err := first.Enter(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Enter(ctx); err != ErrTooManyParticipants {
+		t.Fatalf("expected too many participants got %v", err)
+	}
+	if err := first.Leave(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the participant turned away left and can enter again
+	if err := second.Enter(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Leave(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnterCancelled(t *testing.T) {
+	b := newTestBarrier(t, 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := b.Enter(ctx); err == nil {
+		t.Fatal("expected entering to fail once cancelled")
+	}
+
+	// the participant left so it isn't counted and can enter again
+	rsp, err := b.client.Get(context.Background(), b.waiters(), client.WithPrefix(), client.WithCountOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Count != 0 {
+		t.Fatalf("expected no participants got %d", rsp.Count)
+	}
+
+	other := newTestBarrier(t, 2)
+	ctx = context.Background()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- other.Enter(ctx)
+	}()
+	if err := b.Enter(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		errs <- other.Leave(ctx)
+	}()
+	if err := b.Leave(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadyLease(t *testing.T) {
+	ctx := context.Background()
+	b := newTestBarrier(t, 1)
+
+	if err := b.Enter(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the last participant crashes without leaving
+	b.session.Close()
+
+	rsp, err := b.client.Get(ctx, b.ready())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Count != 0 {
+		t.Fatal("expected the ready key to be removed with the session")
+	}
+
+	// the next round waits for its participants
+	next := newTestBarrier(t, 2)
+	ctx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	if err := next.Enter(ctx); err == nil {
+		t.Fatal("expected the next round not to be released")
+	}
+}
@@ -0,0 +1,34 @@
+package etcd
+
+type Options struct {
+	// Nodes are the etcd addresses
+	Nodes []string
+	// Prefix of barrier keys
+	Prefix string
+	// TTL of the participant session, a participant which stops
+	// renewing it is removed from the barrier
+	TTL int
+}
+
+type Option func(o *Options)
+
+// Nodes sets the etcd addresses
+func Nodes(a ...string) Option {
+	return func(o *Options) {
+		o.Nodes = a
+	}
+}
+
+// Prefix sets the prefix of barrier keys
+func Prefix(p string) Option {
+	return func(o *Options) {
+		o.Prefix = p
+	}
+}
+
+// TTL sets the participant session ttl in seconds
+func TTL(t int) Option {
+	return func(o *Options) {
+		o.TTL = t
+	}
+}
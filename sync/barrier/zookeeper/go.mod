@@ -0,0 +1,5 @@
+module github.com/micro/go-plugins/sync/barrier/zookeeper/v2
+
+go 1.13
+
+require github.com/samuel/go-zookeeper v0.0.0-20190923202752-2cc03de413da
@@ -0,0 +1,36 @@
+package zookeeper
+
+import "time"
+
+type Options struct {
+	// Nodes are the zookeeper addresses
+	Nodes []string
+	// Prefix is the znode under which barriers are kept
+	Prefix string
+	// Timeout is the session timeout, a participant whose session
+	// expires is removed from the barrier
+	Timeout time.Duration
+}
+
+type Option func(o *Options)
+
+// Nodes sets the zookeeper addresses
+func Nodes(a ...string) Option {
+	return func(o *Options) {
+		o.Nodes = a
+	}
+}
+
+// Prefix sets the znode under which barriers are kept
+func Prefix(p string) Option {
+	return func(o *Options) {
+		o.Prefix = p
+	}
+}
+
+// Timeout sets the session timeout
+func Timeout(t time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = t
+	}
+}
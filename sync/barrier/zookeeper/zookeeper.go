@@ -0,0 +1,198 @@
+// Package zookeeper is a zookeeper backed distributed barrier
+package zookeeper
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// Barrier blocks participants until all of them have arrived
+type Barrier interface {
+	// Enter blocks until the expected number of participants have entered
+	Enter(ctx context.Context) error
+	// Leave blocks until every participant has left
+	Leave(ctx context.Context) error
+}
+
+type zkBarrier struct {
+	opts  Options
+	conn  *zk.Conn
+	dir   string
+	count int
+
+	node string
+}
+
+var (
+	// DefaultPrefix is the znode under which barriers are kept
+	DefaultPrefix = "/micro/sync/barrier"
+	// DefaultTimeout is the zookeeper session timeout
+	DefaultTimeout = 10 * time.Second
+
+	// ErrTooManyParticipants is returned when more than the expected participants enter
+	ErrTooManyParticipants = errors.New("too many participants")
+)
+
+func (z *zkBarrier) waiters() string {
+	return path.Join(z.dir, "waiters")
+}
+
+func (z *zkBarrier) ready() string {
+	return path.Join(z.dir, "ready")
+}
+
+// createParents creates every node of dir which doesn't exist
+func (z *zkBarrier) createParents(dir string) error {
+	p := ""
+	for _, part := range strings.Split(strings.Trim(dir, "/"), "/") {
+		p += "/" + part
+		_, err := z.conn.Create(p, []byte{}, 0, zk.WorldACL(zk.PermAll))
+		if err != nil && err != zk.ErrNodeExists {
+			return err
+		}
+	}
+	return nil
+}
+
+// wait blocks until the watch fires or the context is done
+func wait(ctx context.Context, ch <-chan zk.Event) error {
+	select {
+	case ev := <-ch:
+		return ev.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (z *zkBarrier) Enter(ctx context.Context) error {
+	if len(z.node) > 0 {
+		return errors.New("already entered")
+	}
+
+	if err := z.createParents(z.waiters()); err != nil {
+		return err
+	}
+
+	node, err := z.conn.CreateProtectedEphemeralSequential(path.Join(z.waiters(), "p-"), []byte{}, zk.WorldACL(zk.PermAll))
+	if err != nil {
+		return err
+	}
+	z.node = node
+
+	// participants failing to enter leave so they can enter again
+	if err := z.enter(ctx); err != nil {
+		z.conn.Delete(z.node, -1)
+		z.node = ""
+		return err
+	}
+
+	return nil
+}
+
+// enter blocks until the barrier is released
+func (z *zkBarrier) enter(ctx context.Context) error {
+	children, _, err := z.conn.Children(z.waiters())
+	if err != nil {
+		return err
+	}
+
+	if len(children) > z.count {
+		return ErrTooManyParticipants
+	}
+
+	// the last to arrive releases everyone, the ready node is ephemeral so
+	// a crash before leaving doesn't release later rounds
+	if len(children) == z.count {
+		_, err := z.conn.Create(z.ready(), []byte{}, zk.FlagEphemeral, zk.WorldACL(zk.PermAll))
+		if err != nil && err != zk.ErrNodeExists {
+			return err
+		}
+		return nil
+	}
+
+	for {
+		ok, _, ch, err := z.conn.ExistsW(z.ready())
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if err := wait(ctx, ch); err != nil {
+			return err
+		}
+	}
+}
+
+func (z *zkBarrier) Leave(ctx context.Context) error {
+	if len(z.node) == 0 {
+		return errors.New("not entered")
+	}
+
+	if err := z.conn.Delete(z.node, -1); err != nil && err != zk.ErrNoNode {
+		return err
+	}
+	z.node = ""
+
+	for {
+		children, _, ch, err := z.conn.ChildrenW(z.waiters())
+		if err != nil {
+			return err
+		}
+
+		// the last to leave resets the barrier
+		if len(children) == 0 {
+			if err := z.conn.Delete(z.ready(), -1); err != nil && err != zk.ErrNoNode {
+				return err
+			}
+			return nil
+		}
+
+		if err := wait(ctx, ch); err != nil {
+			return err
+		}
+	}
+}
+
+// NewBarrier returns a barrier for name which releases once count participants have entered
+func NewBarrier(name string, count int, opts ...Option) (Barrier, error) {
+	options := Options{
+		Prefix:  DefaultPrefix,
+		Timeout: DefaultTimeout,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if count < 1 {
+		return nil, errors.New("count must be at least 1")
+	}
+
+	var addrs []string
+	for _, addr := range options.Nodes {
+		if len(addr) > 0 {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	if len(addrs) == 0 {
+		addrs = []string{"127.0.0.1:2181"}
+	}
+
+	c, _, err := zk.Connect(addrs, options.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &zkBarrier{
+		opts:  options,
+		conn:  c,
+		dir:   path.Join(options.Prefix, strings.Replace(name, "/", "-", -1)),
+		count: count,
+	}, nil
+}
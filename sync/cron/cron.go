@@ -0,0 +1,370 @@
+// Package cron schedules recurring jobs across service instances so each
+// run happens exactly once cluster-wide
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	gosync "sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/store"
+	memstore "github.com/micro/go-micro/v2/store/memory"
+	"github.com/micro/go-micro/v2/sync"
+	"github.com/micro/go-micro/v2/sync/memory"
+	"github.com/robfig/cron/v3"
+)
+
+// MisfirePolicy decides what happens to runs missed while no instance was scheduling
+type MisfirePolicy int
+
+const (
+	// MisfireSkip drops missed runs and waits for the next scheduled time
+	MisfireSkip MisfirePolicy = iota
+	// MisfireRunOnce runs the job once as soon as possible for all missed runs
+	MisfireRunOnce
+	// MisfireRunAll runs the job once for every missed run
+	MisfireRunAll
+)
+
+// Handler is executed for each scheduled run
+type Handler func(ctx context.Context) error
+
+// Job is a recurring job
+type Job struct {
+	// Name uniquely identifies the job across instances
+	Name string
+	// Schedule is a standard cron expression or a descriptor e.g @every 1m
+	Schedule string
+	// Misfire policy applied when the scheduler starts
+	Misfire MisfirePolicy
+	// Timeout of a single run, zero means no timeout
+	Timeout time.Duration
+	// Handler to run
+	Handler Handler
+}
+
+// Status of a job recorded in the store
+type Status struct {
+	Name string `json:"name"`
+	// LastScheduled is the scheduled time of the last run
+	LastScheduled time.Time `json:"last_scheduled"`
+	// LastRun is when the last run started
+	LastRun time.Time `json:"last_run"`
+	// LastDuration is how long the last run took
+	LastDuration time.Duration `json:"last_duration"`
+	// LastError of the last run if it failed
+	LastError string `json:"last_error,omitempty"`
+	// Node which executed the last run
+	Node     string `json:"node"`
+	Runs     int64  `json:"runs"`
+	Failures int64  `json:"failures"`
+}
+
+// Scheduler runs jobs on their schedule
+type Scheduler interface {
+	// Add a job, jobs can be added before or after Start
+	Add(*Job) error
+	// Remove a job by name
+	Remove(name string) error
+	// Status returns the recorded status of a job
+	Status(name string) (*Status, error)
+	Start() error
+	Stop() error
+}
+
+type job struct {
+	*Job
+	schedule cron.Schedule
+	exit     chan bool
+}
+
+type scheduler struct {
+	opts Options
+
+	gosync.Mutex
+	running bool
+	jobs    map[string]*job
+	wg      gosync.WaitGroup
+}
+
+var (
+	// DefaultPrefix of lock ids and status keys
+	DefaultPrefix = "micro-cron-"
+	// DefaultLockTTL is the ttl of the job lock
+	DefaultLockTTL = 30 * time.Second
+	// DefaultLockWait is how long to wait for the job lock
+	DefaultLockWait = 10 * time.Second
+
+	parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+)
+
+func (s *scheduler) key(name string) string {
+	return s.opts.Prefix + name
+}
+
+func (s *scheduler) Add(j *Job) error {
+	if len(j.Name) == 0 {
+		return errors.New("job name required")
+	}
+	if j.Handler == nil {
+		return errors.New("job handler required")
+	}
+
+	sched, err := parser.Parse(j.Schedule)
+	if err != nil {
+		return fmt.Errorf("invalid schedule %q: %v", j.Schedule, err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.jobs[j.Name]; ok {
+		return fmt.Errorf("job %s already exists", j.Name)
+	}
+
+	jb := &job{
+		Job:      j,
+		schedule: sched,
+		exit:     make(chan bool),
+	}
+	s.jobs[j.Name] = jb
+
+	if s.running {
+		s.start(jb)
+	}
+
+	return nil
+}
+
+func (s *scheduler) Remove(name string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	jb, ok := s.jobs[name]
+	if !ok {
+		return errors.New("job not found")
+	}
+	delete(s.jobs, name)
+
+	if s.running {
+		close(jb.exit)
+	}
+
+	return nil
+}
+
+func (s *scheduler) Status(name string) (*Status, error) {
+	recs, err := s.opts.Store.Read(s.key(name))
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return nil, store.ErrNotFound
+	}
+	var st *Status
+	if err := json.Unmarshal(recs[0].Value, &st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (s *scheduler) Start() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.running {
+		return nil
+	}
+
+	for _, jb := range s.jobs {
+		jb.exit = make(chan bool)
+		s.start(jb)
+	}
+
+	s.running = true
+	return nil
+}
+
+func (s *scheduler) Stop() error {
+	s.Lock()
+	if !s.running {
+		s.Unlock()
+		return nil
+	}
+	for _, jb := range s.jobs {
+		close(jb.exit)
+	}
+	s.running = false
+	s.Unlock()
+
+	// wait for runs in progress
+	s.wg.Wait()
+	return nil
+}
+
+func (s *scheduler) start(jb *job) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.misfire(jb)
+		s.loop(jb)
+	}()
+}
+
+// loop fires the job at each scheduled time until stopped
+func (s *scheduler) loop(jb *job) {
+	for {
+		next := jb.schedule.Next(time.Now())
+
+		t := time.NewTimer(time.Until(next))
+		select {
+		case <-jb.exit:
+			t.Stop()
+			return
+		case <-t.C:
+		}
+
+		s.fire(jb, next)
+	}
+}
+
+// misfire applies the misfire policy to runs missed since the last recorded run
+func (s *scheduler) misfire(jb *job) {
+	if jb.Misfire == MisfireSkip {
+		return
+	}
+
+	st, err := s.Status(jb.Name)
+	if err != nil || st.LastScheduled.IsZero() {
+		// never run before, nothing was missed
+		return
+	}
+
+	var missed []time.Time
+	now := time.Now()
+	for t := jb.schedule.Next(st.LastScheduled); !t.After(now); t = jb.schedule.Next(t) {
+		missed = append(missed, t)
+		if jb.Misfire == MisfireRunOnce {
+			// only the latest missed run matters
+			missed = missed[len(missed)-1:]
+		}
+	}
+
+	for _, t := range missed {
+		select {
+		case <-jb.exit:
+			return
+		default:
+		}
+		s.fire(jb, t)
+	}
+}
+
+// fire runs the job for the scheduled time unless another instance already has
+func (s *scheduler) fire(jb *job, scheduled time.Time) {
+	id := s.key(jb.Name)
+
+	if err := s.opts.Sync.Lock(id, sync.LockTTL(s.opts.LockTTL), sync.LockWait(s.opts.LockWait)); err != nil {
+		if err != sync.ErrLockTimeout && logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Errorf("cron: failed to lock job %s: %v", jb.Name, err)
+		}
+		return
+	}
+	defer s.opts.Sync.Unlock(id)
+
+	st, err := s.Status(jb.Name)
+	if err == store.ErrNotFound {
+		st = &Status{Name: jb.Name}
+	} else if err != nil {
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Errorf("cron: failed to read status of job %s: %v", jb.Name, err)
+		}
+		return
+	}
+
+	// another instance already ran this or a later time
+	if !st.LastScheduled.Before(scheduled) {
+		return
+	}
+
+	ctx := context.Background()
+	if jb.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, jb.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err = run(ctx, jb.Handler)
+
+	st.LastScheduled = scheduled
+	st.LastRun = start
+	st.LastDuration = time.Since(start)
+	st.Node = s.opts.Node
+	st.Runs++
+	st.LastError = ""
+	if err != nil {
+		st.Failures++
+		st.LastError = err.Error()
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Errorf("cron: job %s failed: %v", jb.Name, err)
+		}
+	}
+
+	b, err := json.Marshal(st)
+	if err != nil {
+		return
+	}
+
+	if err := s.opts.Store.Write(&store.Record{Key: id, Value: b}); err != nil {
+		if logger.V(logger.ErrorLevel, logger.DefaultLogger) {
+			logger.Errorf("cron: failed to write status of job %s: %v", jb.Name, err)
+		}
+	}
+}
+
+// run executes the handler recovering any panic as an error
+func run(ctx context.Context, h Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return h(ctx)
+}
+
+// NewScheduler returns a scheduler. The sync and store should be shared by
+// every instance, they default to in memory implementations for local use.
+func NewScheduler(opts ...Option) Scheduler {
+	options := Options{
+		Prefix:   DefaultPrefix,
+		LockTTL:  DefaultLockTTL,
+		LockWait: DefaultLockWait,
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if options.Sync == nil {
+		options.Sync = memory.NewSync()
+	}
+
+	if options.Store == nil {
+		options.Store = memstore.NewStore()
+	}
+
+	if len(options.Node) == 0 {
+		options.Node, _ = os.Hostname()
+	}
+
+	return &scheduler{
+		opts: options,
+		jobs: make(map[string]*job),
+	}
+}
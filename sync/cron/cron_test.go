@@ -0,0 +1,127 @@
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+	"github.com/micro/go-micro/v2/store/memory"
+	msync "github.com/micro/go-micro/v2/sync/memory"
+)
+
+func TestExactlyOnce(t *testing.T) {
+	sy := msync.NewSync()
+	st := memory.NewStore()
+
+	var runs int32
+
+	// two instances sharing the sync and store
+	var schedulers []Scheduler
+	for _, node := range []string{"a", "b"} {
+		s := NewScheduler(WithSync(sy), WithStore(st), Node(node), LockWait(time.Millisecond*500))
+		if err := s.Add(&Job{
+			Name:     "report",
+			Schedule: "@every 1s",
+			Handler: func(ctx context.Context) error {
+				atomic.AddInt32(&runs, 1)
+				return nil
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Start(); err != nil {
+			t.Fatal(err)
+		}
+		schedulers = append(schedulers, s)
+	}
+
+	time.Sleep(time.Millisecond * 2500)
+
+	for _, s := range schedulers {
+		s.Stop()
+	}
+
+	// the job fires 2 or 3 times depending on when the second starts
+	n := atomic.LoadInt32(&runs)
+	if n < 2 || n > 3 {
+		t.Fatalf("expected 2 or 3 runs got %d", n)
+	}
+
+	status, err := schedulers[0].Status("report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Runs != int64(n) {
+		t.Fatalf("expected %d recorded runs got %d", n, status.Runs)
+	}
+}
+
+func TestFailureStatus(t *testing.T) {
+	s := NewScheduler()
+	if err := s.Add(&Job{
+		Name:     "fail",
+		Schedule: "@every 1s",
+		Handler: func(ctx context.Context) error {
+			return errors.New("downstream unavailable")
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Start()
+	time.Sleep(time.Millisecond * 1500)
+	s.Stop()
+
+	status, err := s.Status("fail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Failures == 0 || status.Failures != status.Runs || status.LastError != "downstream unavailable" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
+
+func TestMisfireRunOnce(t *testing.T) {
+	st := memory.NewStore()
+
+	// the job last ran a minute ago
+	b, _ := json.Marshal(&Status{Name: "missed", LastScheduled: time.Now().Add(-time.Minute)})
+	st.Write(&store.Record{Key: DefaultPrefix + "missed", Value: b})
+
+	var runs int32
+
+	s := NewScheduler(WithStore(st))
+	s.Add(&Job{
+		Name:     "missed",
+		Schedule: "@every 10s",
+		Misfire:  MisfireRunOnce,
+		Handler: func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return nil
+		},
+	})
+
+	s.Start()
+	time.Sleep(time.Millisecond * 100)
+	s.Stop()
+
+	if n := atomic.LoadInt32(&runs); n != 1 {
+		t.Fatalf("expected 1 run got %d", n)
+	}
+}
+
+func TestInvalidSchedule(t *testing.T) {
+	s := NewScheduler()
+	err := s.Add(&Job{
+		Name:     "invalid",
+		Schedule: "every minute",
+		Handler:  func(ctx context.Context) error { return nil },
+	})
+	if err == nil {
+		t.Fatal("expected invalid schedule error")
+	}
+}
@@ -0,0 +1,8 @@
+module github.com/micro/go-plugins/sync/cron/v2
+
+go 1.13
+
+require (
+	github.com/micro/go-micro/v2 v2.9.1
+	github.com/robfig/cron/v3 v3.0.1
+)
@@ -0,0 +1,67 @@
+package cron
+
+import (
+	"time"
+
+	"github.com/micro/go-micro/v2/store"
+	"github.com/micro/go-micro/v2/sync"
+)
+
+type Options struct {
+	// Sync is used to ensure a job runs on a single instance
+	Sync sync.Sync
+	// Store is used to record the status of jobs
+	Store store.Store
+	// Prefix of the lock ids and status keys
+	Prefix string
+	// Node identifies this instance in job status
+	Node string
+	// LockTTL bounds how long a crashed instance holds a job's lock
+	LockTTL time.Duration
+	// LockWait is how long to wait for another instance running the job
+	LockWait time.Duration
+}
+
+type Option func(o *Options)
+
+// WithSync sets the sync used to coordinate instances
+func WithSync(s sync.Sync) Option {
+	return func(o *Options) {
+		o.Sync = s
+	}
+}
+
+// WithStore sets the store used to record job status
+func WithStore(s store.Store) Option {
+	return func(o *Options) {
+		o.Store = s
+	}
+}
+
+// Prefix sets the prefix of lock ids and status keys
+func Prefix(p string) Option {
+	return func(o *Options) {
+		o.Prefix = p
+	}
+}
+
+// Node sets the name of this instance reported in job status
+func Node(n string) Option {
+	return func(o *Options) {
+		o.Node = n
+	}
+}
+
+// LockTTL sets the ttl of the job lock
+func LockTTL(t time.Duration) Option {
+	return func(o *Options) {
+		o.LockTTL = t
+	}
+}
+
+// LockWait sets how long to wait for the job lock
+func LockWait(t time.Duration) Option {
+	return func(o *Options) {
+		o.LockWait = t
+	}
+}
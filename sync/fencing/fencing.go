@@ -0,0 +1,87 @@
+// Package fencing provides fencing tokens for distributed locks so writes
+// from stale lock holders can be rejected
+package fencing
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	gosync "sync"
+
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/sync"
+)
+
+var (
+	// ErrStaleToken is returned when a token is lower than one already seen
+	ErrStaleToken = errors.New("stale fencing token")
+	// ErrNotSupported is returned when the sync doesn't issue fencing tokens
+	ErrNotSupported = errors.New("fencing tokens not supported")
+
+	// MetadataKey is the metadata key used to propagate tokens
+	MetadataKey = "Micro-Fencing-Token"
+)
+
+// Fencer is implemented by sync plugins which issue a monotonically
+// increasing token each time a lock is acquired
+type Fencer interface {
+	// Token returns the fencing token of a held lock
+	Token(id string) (uint64, error)
+}
+
+// Verifier checks tokens against the highest token seen per resource
+type Verifier interface {
+	// Verify returns ErrStaleToken if a higher token was already seen
+	// for the resource, otherwise the token becomes the highest seen
+	Verify(resource string, token uint64) error
+}
+
+type verifier struct {
+	gosync.Mutex
+	tokens map[string]uint64
+}
+
+func (v *verifier) Verify(resource string, token uint64) error {
+	v.Lock()
+	defer v.Unlock()
+	if token < v.tokens[resource] {
+		return ErrStaleToken
+	}
+	v.tokens[resource] = token
+	return nil
+}
+
+// Token returns the fencing token of the lock id held with s
+func Token(s sync.Sync, id string) (uint64, error) {
+	f, ok := s.(Fencer)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	return f.Token(id)
+}
+
+// NewVerifier returns an in memory verifier
+func NewVerifier() Verifier {
+	return &verifier{
+		tokens: make(map[string]uint64),
+	}
+}
+
+// NewContext returns a context carrying the token in its metadata
+// so it's sent with outgoing requests
+func NewContext(ctx context.Context, token uint64) context.Context {
+	return metadata.Set(ctx, MetadataKey, strconv.FormatUint(token, 10))
+}
+
+// FromContext returns the token from the context metadata
+func FromContext(ctx context.Context) (uint64, bool) {
+	v, ok := metadata.Get(ctx, MetadataKey)
+	if !ok {
+		return 0, false
+	}
+	token, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return token, true
+}
@@ -0,0 +1,44 @@
+package fencing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micro/go-micro/v2/sync/memory"
+)
+
+func TestVerifier(t *testing.T) {
+	v := NewVerifier()
+
+	if err := v.Verify("account", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Verify("account", 10); err != nil {
+		t.Fatalf("expected the same token to be accepted got %v", err)
+	}
+	if err := v.Verify("account", 9); err != ErrStaleToken {
+		t.Fatalf("expected stale token got %v", err)
+	}
+	if err := v.Verify("other", 1); err != nil {
+		t.Fatalf("expected tokens per resource got %v", err)
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := NewContext(context.Background(), 42)
+
+	token, ok := FromContext(ctx)
+	if !ok || token != 42 {
+		t.Fatalf("expected token 42 got %d", token)
+	}
+
+	if _, ok := FromContext(context.Background()); ok {
+		t.Fatal("expected no token")
+	}
+}
+
+func TestNotSupported(t *testing.T) {
+	if _, err := Token(memory.NewSync(), "lock"); err != ErrNotSupported {
+		t.Fatalf("expected not supported got %v", err)
+	}
+}
@@ -0,0 +1,250 @@
+// Package etcd is an etcd implementation of sync with leader election
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	gosync "sync"
+	"time"
+
+	client "github.com/coreos/etcd/clientv3"
+	cc "github.com/coreos/etcd/clientv3/concurrency"
+	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/sync"
+)
+
+// Observer is implemented by the etcd sync to watch an election
+// without taking part in it
+type Observer interface {
+	// Observe sends the candidate of the current leader each time it changes.
+	// The channel is closed when the context is done.
+	Observe(ctx context.Context, id string) (<-chan string, error)
+}
+
+type etcdSync struct {
+	options sync.Options
+	path    string
+	client  *client.Client
+
+	mtx   gosync.Mutex
+	locks map[string]*etcdLock
+	// session used to observe elections
+	observer *cc.Session
+}
+
+type etcdLock struct {
+	s *cc.Session
+	m *cc.Mutex
+	// create revision of the lock key used as the fencing token
+	token uint64
+}
+
+var (
+	// DefaultPath is the etcd prefix under which elections and locks are kept
+	DefaultPath = "/micro/sync"
+	// DefaultLeaderTTL is the session ttl of a leader. Leadership is lost
+	// if the session can't be renewed within the ttl.
+	DefaultLeaderTTL = 15 * time.Second
+	// Candidate is the value used to campaign, it defaults to the hostname and a unique id
+	Candidate = candidate()
+)
+
+func candidate() string {
+	host, _ := os.Hostname()
+	if len(host) == 0 {
+		return uuid.New().String()
+	}
+	return host + "-" + uuid.New().String()
+}
+
+func (e *etcdSync) key(id string) string {
+	return path.Join(e.path, strings.Replace(e.options.Prefix+id, "/", "-", -1))
+}
+
+func (e *etcdSync) Init(opts ...sync.Option) error {
+	for _, o := range opts {
+		o(&e.options)
+	}
+	return nil
+}
+
+func (e *etcdSync) Options() sync.Options {
+	return e.options
+}
+
+func (e *etcdSync) Leader(id string, opts ...sync.LeaderOption) (sync.Leader, error) {
+	var options sync.LeaderOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	s, err := cc.NewSession(e.client, cc.WithTTL(int(DefaultLeaderTTL.Seconds())))
+	if err != nil {
+		return nil, err
+	}
+
+	el := cc.NewElection(s, path.Join(e.key(id), "leader"))
+
+	// campaign blocks until elected
+	if err := el.Campaign(context.Background(), Candidate); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return newLeader(id, s, el), nil
+}
+
+func (e *etcdSync) Observe(ctx context.Context, id string) (<-chan string, error) {
+	e.mtx.Lock()
+	if e.observer == nil {
+		s, err := cc.NewSession(e.client)
+		if err != nil {
+			e.mtx.Unlock()
+			return nil, err
+		}
+		e.observer = s
+	}
+	s := e.observer
+	e.mtx.Unlock()
+
+	el := cc.NewElection(s, path.Join(e.key(id), "leader"))
+	ch := make(chan string)
+
+	go func() {
+		defer close(ch)
+		for rsp := range el.Observe(ctx) {
+			if len(rsp.Kvs) == 0 {
+				continue
+			}
+			select {
+			case ch <- string(rsp.Kvs[0].Value):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+func (e *etcdSync) Lock(id string, opts ...sync.LockOption) error {
+	var options sync.LockOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	var sopts []cc.SessionOption
+	if options.TTL > 0 {
+		sopts = append(sopts, cc.WithTTL(int(options.TTL.Seconds())))
+	}
+
+	s, err := cc.NewSession(e.client, sopts...)
+	if err != nil {
+		return err
+	}
+
+	m := cc.NewMutex(s, path.Join(e.key(id), "lock"))
+
+	ctx := context.Background()
+	if options.Wait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Wait)
+		defer cancel()
+	}
+
+	if err := m.Lock(ctx); err != nil {
+		s.Close()
+		if err == context.DeadlineExceeded {
+			return sync.ErrLockTimeout
+		}
+		return err
+	}
+
+	// the create revision of our key increases with every acquisition
+	rsp, err := e.client.Get(context.Background(), m.Key())
+	if err != nil || len(rsp.Kvs) == 0 {
+		m.Unlock(context.Background())
+		s.Close()
+		if err == nil {
+			err = errors.New("lock key not found")
+		}
+		return err
+	}
+
+	e.mtx.Lock()
+	e.locks[id] = &etcdLock{
+		s:     s,
+		m:     m,
+		token: uint64(rsp.Kvs[0].CreateRevision),
+	}
+	e.mtx.Unlock()
+	return nil
+}
+
+// Token returns the fencing token of a held lock
+func (e *etcdSync) Token(id string) (uint64, error) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	v, ok := e.locks[id]
+	if !ok {
+		return 0, errors.New("lock not found")
+	}
+	return v.token, nil
+}
+
+func (e *etcdSync) Unlock(id string) error {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	v, ok := e.locks[id]
+	if !ok {
+		return errors.New("lock not found")
+	}
+	err := v.m.Unlock(context.Background())
+	v.s.Close()
+	delete(e.locks, id)
+	return err
+}
+
+func (e *etcdSync) String() string {
+	return "etcd"
+}
+
+// NewSync returns an etcd sync
+func NewSync(opts ...sync.Option) sync.Sync {
+	var options sync.Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	var endpoints []string
+
+	for _, addr := range options.Nodes {
+		if len(addr) > 0 {
+			endpoints = append(endpoints, addr)
+		}
+	}
+
+	if len(endpoints) == 0 {
+		endpoints = []string{"http://127.0.0.1:2379"}
+	}
+
+	c, err := client.New(client.Config{
+		Endpoints:   endpoints,
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("etcd sync: %v", err))
+	}
+
+	return &etcdSync{
+		path:    DefaultPath,
+		client:  c,
+		options: options,
+		locks:   make(map[string]*etcdLock),
+	}
+}
@@ -0,0 +1,69 @@
+package etcd
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/sync"
+)
+
+func newTestSync(t *testing.T) sync.Sync {
+	addrs := os.Getenv("ETCD_ADDRS")
+	if len(addrs) == 0 {
+		t.Skip("ETCD_ADDRS not set")
+	}
+	return NewSync(sync.Nodes(strings.Split(addrs, ",")...), sync.Prefix(t.Name()))
+}
+
+func TestLeader(t *testing.T) {
+	s := newTestSync(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ch, err := s.(Observer).Observe(ctx, "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := s.Leader("job")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case v := <-ch:
+		if v != Candidate {
+			t.Fatalf("expected leader %s got %s", Candidate, v)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out observing leader")
+	}
+
+	if err := l.Resign(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-l.Status():
+	case <-time.After(time.Second):
+		t.Fatal("expected leadership lost after resign")
+	}
+}
+
+func TestLockWait(t *testing.T) {
+	s := newTestSync(t)
+
+	if err := s.Lock("lock"); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Unlock("lock")
+
+	other := NewSync(sync.Nodes(s.Options().Nodes...), sync.Prefix(t.Name()))
+	if err := other.Lock("lock", sync.LockWait(time.Second)); err != sync.ErrLockTimeout {
+		t.Fatalf("expected lock timeout got %v", err)
+	}
+}
@@ -0,0 +1,9 @@
+module github.com/micro/go-plugins/sync/leader/etcd/v2
+
+go 1.13
+
+require (
+	github.com/coreos/etcd v3.3.18+incompatible
+	github.com/google/uuid v1.1.1
+	github.com/micro/go-micro/v2 v2.9.1
+)
@@ -0,0 +1,81 @@
+package etcd
+
+import (
+	"context"
+	gosync "sync"
+
+	cc "github.com/coreos/etcd/clientv3/concurrency"
+)
+
+type etcdLeader struct {
+	id string
+	s  *cc.Session
+	e  *cc.Election
+
+	cancel context.CancelFunc
+	once   gosync.Once
+	status chan bool
+}
+
+func newLeader(id string, s *cc.Session, e *cc.Election) *etcdLeader {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	l := &etcdLeader{
+		id:     id,
+		s:      s,
+		e:      e,
+		cancel: cancel,
+		status: make(chan bool, 1),
+	}
+
+	go l.run(ctx)
+
+	return l
+}
+
+// run watches the election and the session, notifying once leadership is lost
+func (l *etcdLeader) run(ctx context.Context) {
+	ch := l.e.Observe(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-l.s.Done():
+			// the lease expired or could not be kept alive
+			l.lost()
+			return
+		case rsp, ok := <-ch:
+			if !ok {
+				if ctx.Err() == nil {
+					l.lost()
+				}
+				return
+			}
+			if len(rsp.Kvs) == 0 || string(rsp.Kvs[0].Key) != l.e.Key() {
+				l.lost()
+				return
+			}
+		}
+	}
+}
+
+func (l *etcdLeader) lost() {
+	l.once.Do(func() {
+		l.status <- true
+		close(l.status)
+	})
+}
+
+// Status returns a channel which receives once leadership is lost
+func (l *etcdLeader) Status() chan bool {
+	return l.status
+}
+
+func (l *etcdLeader) Resign() error {
+	l.cancel()
+	err := l.e.Resign(context.Background())
+	l.s.Close()
+	l.lost()
+	return err
+}
@@ -0,0 +1,24 @@
+module github.com/micro/go-plugins/sync/leader/kubernetes/v2
+
+go 1.13
+
+require (
+	github.com/evanphx/json-patch v0.5.2 // indirect
+	github.com/google/uuid v1.1.1
+	github.com/googleapis/gnostic v0.4.0 // indirect
+	github.com/micro/go-micro/v2 v2.9.1
+	github.com/spf13/pflag v1.0.5 // indirect
+	golang.org/x/oauth2 v0.0.0-20191202225959-858c2ad4c8b6 // indirect
+	gopkg.in/inf.v0 v0.9.1 // indirect
+	k8s.io/api v0.0.0-00010101000000-000000000000 // indirect
+	k8s.io/apimachinery v0.0.0-00010101000000-000000000000
+	k8s.io/client-go v0.0.0-00010101000000-000000000000
+	k8s.io/kube-openapi v0.0.0-20190228160746-b3a7cee44a30 // indirect
+	k8s.io/utils v0.0.0-20200109141947-94aeca20bf09 // indirect
+)
+
+replace (
+	k8s.io/api => k8s.io/api v0.0.0-20190708174958-539a33f6e817
+	k8s.io/apimachinery => k8s.io/apimachinery v0.0.0-20190404173353-6a84e37a896d
+	k8s.io/client-go => k8s.io/client-go v11.0.0+incompatible
+)
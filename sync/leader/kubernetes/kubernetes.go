@@ -0,0 +1,304 @@
+// Package kubernetes implements sync using coordination.k8s.io leases
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	gosync "sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/sync"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+type kubernetesSync struct {
+	options   sync.Options
+	client    kubernetes.Interface
+	namespace string
+	identity  string
+
+	mtx   gosync.Mutex
+	locks map[string]*kubernetesLeader
+}
+
+type kubernetesLeader struct {
+	cancel context.CancelFunc
+	done   chan bool
+	// lease transitions when acquired used as the fencing token,
+	// written by the elector so accessed atomically
+	token uint64
+
+	once   gosync.Once
+	status chan bool
+}
+
+var (
+	// DefaultNamespace is used when not running in a pod
+	DefaultNamespace = "default"
+	// DefaultLeaseDuration is how long other candidates wait before taking over a lease
+	DefaultLeaseDuration = 15 * time.Second
+	// DefaultRenewDeadline is how long the leader retries renewing before giving up
+	DefaultRenewDeadline = 10 * time.Second
+	// DefaultRetryPeriod is the interval between attempts to acquire or renew
+	DefaultRetryPeriod = 2 * time.Second
+
+	serviceAccountNamespace = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// name returns a valid lease name for the id
+func (k *kubernetesSync) name(id string) string {
+	n := strings.ToLower(k.options.Prefix + id)
+	return strings.NewReplacer("/", "-", "_", "-", ":", "-").Replace(n)
+}
+
+// elect campaigns for the lease, returning once elected or the wait passed
+func (k *kubernetesSync) elect(name string, wait time.Duration) (*kubernetesLeader, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	l := &kubernetesLeader{
+		cancel: cancel,
+		done:   make(chan bool),
+		status: make(chan bool, 1),
+	}
+
+	lock := &fencedLock{
+		LeaseLock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: k.namespace,
+			},
+			Client: k.client.CoordinationV1(),
+			// client-go treats a lease held by its own identity as acquired
+			// so every elector has its own to exclude others in the process
+			LockConfig: resourcelock.ResourceLockConfig{
+				Identity: k.identity + "-" + uuid.New().String(),
+			},
+		},
+		leader: l,
+	}
+
+	elected := make(chan bool)
+
+	le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   DefaultLeaseDuration,
+		RenewDeadline:   DefaultRenewDeadline,
+		RetryPeriod:     DefaultRetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				close(elected)
+			},
+			OnStoppedLeading: l.lost,
+		},
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		le.Run(ctx)
+		close(l.done)
+	}()
+
+	var timeout <-chan time.Time
+	if wait > 0 {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case <-elected:
+		return l, nil
+	case <-timeout:
+		cancel()
+		<-l.done
+		return nil, sync.ErrLockTimeout
+	}
+}
+
+func (k *kubernetesSync) Init(opts ...sync.Option) error {
+	for _, o := range opts {
+		o(&k.options)
+	}
+	return nil
+}
+
+func (k *kubernetesSync) Options() sync.Options {
+	return k.options
+}
+
+func (k *kubernetesSync) Leader(id string, opts ...sync.LeaderOption) (sync.Leader, error) {
+	var options sync.LeaderOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	return k.elect(k.name(id), 0)
+}
+
+// Lock holds a lease for the id. The lease is renewed until unlocked
+// so the lock ttl does not apply.
+func (k *kubernetesSync) Lock(id string, opts ...sync.LockOption) error {
+	var options sync.LockOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	name := k.name(id) + "-lock"
+
+	l, err := k.elect(name, options.Wait)
+	if err != nil {
+		return err
+	}
+
+	k.mtx.Lock()
+	k.locks[id] = l
+	k.mtx.Unlock()
+	return nil
+}
+
+func (k *kubernetesSync) Unlock(id string) error {
+	k.mtx.Lock()
+	l, ok := k.locks[id]
+	delete(k.locks, id)
+	k.mtx.Unlock()
+
+	if !ok {
+		return errors.New("lock not found")
+	}
+	return l.Resign()
+}
+
+// Token returns the fencing token of a held lock
+func (k *kubernetesSync) Token(id string) (uint64, error) {
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+	l, ok := k.locks[id]
+	if !ok {
+		return 0, errors.New("lock not found")
+	}
+	return atomic.LoadUint64(&l.token), nil
+}
+
+func (k *kubernetesSync) String() string {
+	return "kubernetes"
+}
+
+// fencedLock records the lease transitions written by the elector
+type fencedLock struct {
+	*resourcelock.LeaseLock
+	leader *kubernetesLeader
+}
+
+// Create records the transitions of the lease once created
+func (f *fencedLock) Create(ler resourcelock.LeaderElectionRecord) error {
+	if err := f.LeaseLock.Create(ler); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&f.leader.token, uint64(ler.LeaderTransitions))
+	return nil
+}
+
+// Update records the transitions of the lease once updated. Renewing
+// leaves them unchanged so they're those of the update acquiring it.
+func (f *fencedLock) Update(ler resourcelock.LeaderElectionRecord) error {
+	if err := f.LeaseLock.Update(ler); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&f.leader.token, uint64(ler.LeaderTransitions))
+	return nil
+}
+
+func (l *kubernetesLeader) lost() {
+	l.once.Do(func() {
+		l.status <- true
+		close(l.status)
+	})
+}
+
+// Status returns a channel which receives once leadership is lost
+func (l *kubernetesLeader) Status() chan bool {
+	return l.status
+}
+
+// Resign releases the lease
+func (l *kubernetesLeader) Resign() error {
+	l.cancel()
+	<-l.done
+	return nil
+}
+
+func config() (*rest.Config, error) {
+	if c, err := rest.InClusterConfig(); err == nil {
+		return c, nil
+	}
+	path := os.Getenv("KUBECONFIG")
+	if len(path) == 0 {
+		path = os.Getenv("HOME") + "/.kube/config"
+	}
+	return clientcmd.BuildConfigFromFlags("", path)
+}
+
+func namespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); len(ns) > 0 {
+		return ns
+	}
+	if b, err := ioutil.ReadFile(serviceAccountNamespace); err == nil && len(b) > 0 {
+		return strings.TrimSpace(string(b))
+	}
+	return DefaultNamespace
+}
+
+// NewSync returns a kubernetes sync using the in cluster config,
+// falling back to the KUBECONFIG file
+func NewSync(opts ...sync.Option) sync.Sync {
+	c, err := config()
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("kubernetes sync: %v", err))
+	}
+
+	client, err := kubernetes.NewForConfig(c)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("kubernetes sync: %v", err))
+	}
+
+	return NewSyncWithClient(client, opts...)
+}
+
+// NewSyncWithClient returns a kubernetes sync using the given client.
+// The lease holder identity is the pod name, or hostname, followed by a
+// unique id per lock and leader.
+func NewSyncWithClient(client kubernetes.Interface, opts ...sync.Option) sync.Sync {
+	var options sync.Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	identity := os.Getenv("POD_NAME")
+	if len(identity) == 0 {
+		identity, _ = os.Hostname()
+	}
+
+	return &kubernetesSync{
+		options:   options,
+		client:    client,
+		namespace: namespace(),
+		identity:  identity,
+		locks:     make(map[string]*kubernetesLeader),
+	}
+}
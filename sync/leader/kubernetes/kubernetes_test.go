@@ -0,0 +1,144 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/sync"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func init() {
+	DefaultLeaseDuration = 2 * time.Second
+	DefaultRenewDeadline = time.Second
+	DefaultRetryPeriod = 100 * time.Millisecond
+}
+
+func TestLock(t *testing.T) {
+	s := NewSyncWithClient(fake.NewSimpleClientset())
+
+	if err := s.Lock("foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	// holders in the same process are excluded too
+	if err := s.Lock("foo", sync.LockWait(time.Second)); err != sync.ErrLockTimeout {
+		t.Fatalf("expected the lock to time out got %v", err)
+	}
+
+	locked := make(chan error, 1)
+	go func() {
+		locked <- s.Lock("foo", sync.LockWait(5*time.Second))
+	}()
+
+	select {
+	case err := <-locked:
+		t.Fatalf("expected the lock to be held got %v", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	if err := s.Unlock("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-locked; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Unlock("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Unlock("foo"); err == nil {
+		t.Fatal("expected unlocking twice to fail")
+	}
+}
+
+func TestToken(t *testing.T) {
+	s := NewSyncWithClient(fake.NewSimpleClientset())
+	k := s.(*kubernetesSync)
+
+	if _, err := k.Token("foo"); err == nil {
+		t.Fatal("expected no token before locking")
+	}
+
+	var last uint64
+	for i := 0; i < 3; i++ {
+		if err := s.Lock("foo", sync.LockWait(5*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+
+		// a holder in the same process must not renew the lease as its own
+		locked := make(chan error, 1)
+		go func() {
+			locked <- s.Lock("foo", sync.LockWait(5*time.Second))
+		}()
+
+		token, err := k.Token("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 && token <= last {
+			t.Fatalf("expected the token to increase from %d got %d", last, token)
+		}
+		last = token
+
+		time.Sleep(200 * time.Millisecond)
+		if err := s.Unlock("foo"); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-locked; err != nil {
+			t.Fatal(err)
+		}
+
+		token, err = k.Token("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token <= last {
+			t.Fatalf("expected the token of the waiting holder to increase from %d got %d", last, token)
+		}
+		last = token
+
+		if err := s.Unlock("foo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLeader(t *testing.T) {
+	s := NewSyncWithClient(fake.NewSimpleClientset())
+
+	l, err := s.Leader("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	elected := make(chan sync.Leader, 1)
+	go func() {
+		l, err := s.Leader("foo")
+		if err != nil {
+			return
+		}
+		elected <- l
+	}()
+
+	select {
+	case <-elected:
+		t.Fatal("expected a single leader")
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	if err := l.Resign(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-l.Status():
+	case <-time.After(time.Second):
+		t.Fatal("expected the status once resigned")
+	}
+
+	select {
+	case l := <-elected:
+		l.Resign()
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the candidate to be elected once resigned")
+	}
+}
@@ -0,0 +1,293 @@
+// Package consul is a consul session based implementation of sync
+package consul
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	gosync "sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/sync"
+)
+
+// LostHandler is called with the id of a lock which was lost while held
+// e.g the session was invalidated or the key was modified
+type LostHandler func(id string)
+
+// Notifier is implemented by the consul sync to be told about lost locks
+type Notifier interface {
+	OnLost(LostHandler)
+}
+
+type consulSync struct {
+	options sync.Options
+	path    string
+	client  *api.Client
+
+	mtx      gosync.Mutex
+	locks    map[string]*consulLock
+	handlers []LostHandler
+}
+
+type consulLock struct {
+	l    *api.Lock
+	exit chan struct{}
+	// modify index of the lock key used as the fencing token
+	token uint64
+}
+
+type consulLeader struct {
+	l      *api.Lock
+	once   gosync.Once
+	exit   chan struct{}
+	status chan bool
+}
+
+var (
+	// DefaultPath is the key prefix under which locks are kept
+	DefaultPath = "micro/sync"
+	// DefaultSessionTTL is the ttl of the session when no lock ttl is set.
+	// Sessions are renewed automatically while the lock is held.
+	DefaultSessionTTL = 15 * time.Second
+	// DefaultLockDelay is the time consul prevents a lost lock from being acquired again
+	DefaultLockDelay = 15 * time.Second
+)
+
+func (c *consulSync) key(id string) string {
+	return path.Join(c.path, strings.Replace(c.options.Prefix+id, "/", "-", -1))
+}
+
+func (c *consulSync) lock(key string, ttl, wait time.Duration) (*api.Lock, error) {
+	if ttl <= 0 {
+		ttl = DefaultSessionTTL
+	}
+
+	// consul requires a minimum session ttl of 10 seconds
+	if ttl < 10*time.Second {
+		ttl = 10 * time.Second
+	}
+
+	opts := &api.LockOptions{
+		Key:        key,
+		SessionTTL: ttl.String(),
+		SessionOpts: &api.SessionEntry{
+			Name:      "micro-sync: " + key,
+			TTL:       ttl.String(),
+			LockDelay: DefaultLockDelay,
+			Behavior:  api.SessionBehaviorRelease,
+		},
+	}
+
+	if wait > 0 {
+		opts.LockWaitTime = wait
+		opts.LockTryOnce = true
+	}
+
+	return c.client.LockOpts(opts)
+}
+
+func (c *consulSync) Init(opts ...sync.Option) error {
+	for _, o := range opts {
+		o(&c.options)
+	}
+	return nil
+}
+
+func (c *consulSync) Options() sync.Options {
+	return c.options
+}
+
+func (c *consulSync) OnLost(h LostHandler) {
+	c.mtx.Lock()
+	c.handlers = append(c.handlers, h)
+	c.mtx.Unlock()
+}
+
+func (c *consulSync) Leader(id string, opts ...sync.LeaderOption) (sync.Leader, error) {
+	var options sync.LeaderOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	l, err := c.lock(path.Join(c.key(id), "leader"), 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := l.Lock(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	leader := &consulLeader{
+		l:      l,
+		exit:   make(chan struct{}),
+		status: make(chan bool, 1),
+	}
+
+	go func() {
+		select {
+		case <-ch:
+			leader.lost()
+		case <-leader.exit:
+		}
+	}()
+
+	return leader, nil
+}
+
+func (c *consulSync) Lock(id string, opts ...sync.LockOption) error {
+	var options sync.LockOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	key := path.Join(c.key(id), "lock")
+
+	l, err := c.lock(key, options.TTL, options.Wait)
+	if err != nil {
+		return err
+	}
+
+	ch, err := l.Lock(nil)
+	if err != nil {
+		return err
+	}
+
+	// a nil channel without error means the wait time passed
+	if ch == nil {
+		return sync.ErrLockTimeout
+	}
+
+	// acquiring the lock writes the key so its modify index increases
+	// with every acquisition
+	pair, _, err := c.client.KV().Get(key, &api.QueryOptions{RequireConsistent: true})
+	if err != nil || pair == nil {
+		l.Unlock()
+		if err == nil {
+			err = errors.New("lock key not found")
+		}
+		return err
+	}
+
+	cl := &consulLock{
+		l:     l,
+		exit:  make(chan struct{}),
+		token: pair.ModifyIndex,
+	}
+
+	c.mtx.Lock()
+	c.locks[id] = cl
+	c.mtx.Unlock()
+
+	go c.monitor(id, cl, ch)
+
+	return nil
+}
+
+// monitor calls the lost handlers if the lock is lost before being unlocked
+func (c *consulSync) monitor(id string, cl *consulLock, ch <-chan struct{}) {
+	select {
+	case <-cl.exit:
+		return
+	case <-ch:
+	}
+
+	c.mtx.Lock()
+	if c.locks[id] == cl {
+		delete(c.locks, id)
+	}
+	handlers := c.handlers
+	c.mtx.Unlock()
+
+	if logger.V(logger.WarnLevel, logger.DefaultLogger) {
+		logger.Warnf("consul sync: lock %s lost", id)
+	}
+
+	for _, h := range handlers {
+		h(id)
+	}
+}
+
+func (c *consulSync) Unlock(id string) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	v, ok := c.locks[id]
+	if !ok {
+		return errors.New("lock not found")
+	}
+	close(v.exit)
+	delete(c.locks, id)
+	return v.l.Unlock()
+}
+
+// Token returns the fencing token of a held lock
+func (c *consulSync) Token(id string) (uint64, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	v, ok := c.locks[id]
+	if !ok {
+		return 0, errors.New("lock not found")
+	}
+	return v.token, nil
+}
+
+func (c *consulSync) String() string {
+	return "consul"
+}
+
+func (l *consulLeader) lost() {
+	l.once.Do(func() {
+		l.status <- true
+		close(l.status)
+	})
+}
+
+// Status returns a channel which receives once leadership is lost
+func (l *consulLeader) Status() chan bool {
+	return l.status
+}
+
+func (l *consulLeader) Resign() error {
+	select {
+	case <-l.exit:
+		return nil
+	default:
+		close(l.exit)
+	}
+	err := l.l.Unlock()
+	l.lost()
+	return err
+}
+
+// NewSync returns a consul sync
+func NewSync(opts ...sync.Option) sync.Sync {
+	var options sync.Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	config := api.DefaultConfig()
+	for _, addr := range options.Nodes {
+		if len(addr) > 0 {
+			config.Address = addr
+			break
+		}
+	}
+
+	client, err := api.NewClient(config)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("consul sync: %v", err))
+	}
+
+	return &consulSync{
+		path:    DefaultPath,
+		client:  client,
+		options: options,
+		locks:   make(map[string]*consulLock),
+	}
+}
@@ -0,0 +1,107 @@
+package consul
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/sync"
+)
+
+func init() {
+	// let lost locks be acquired again straight away
+	DefaultLockDelay = time.Millisecond
+}
+
+func newTestSync(t *testing.T) *consulSync {
+	addr := os.Getenv("CONSUL_HTTP_ADDR")
+	if len(addr) == 0 {
+		t.Skip("CONSUL_HTTP_ADDR not set")
+	}
+	return NewSync(sync.Nodes(addr), sync.Prefix(t.Name()+"-")).(*consulSync)
+}
+
+func TestLockTimeout(t *testing.T) {
+	a := newTestSync(t)
+	b := newTestSync(t)
+
+	if err := a.Lock("job"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Lock("job", sync.LockWait(time.Second)); err != sync.ErrLockTimeout {
+		t.Fatalf("expected lock timeout got %v", err)
+	}
+
+	if err := a.Unlock("job"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Lock("job", sync.LockWait(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Unlock("job"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestToken(t *testing.T) {
+	s := newTestSync(t)
+
+	var last uint64
+	for i := 0; i < 3; i++ {
+		if err := s.Lock("job"); err != nil {
+			t.Fatal(err)
+		}
+		token, err := s.Token("job")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token <= last {
+			t.Fatalf("expected token greater than %d got %d", last, token)
+		}
+		last = token
+		if err := s.Unlock("job"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLost(t *testing.T) {
+	s := newTestSync(t)
+
+	lost := make(chan string, 1)
+	s.OnLost(func(id string) {
+		lost <- id
+	})
+
+	if err := s.Lock("job"); err != nil {
+		t.Fatal(err)
+	}
+
+	// invalidate the session holding the lock
+	pair, _, err := s.client.KV().Get(path.Join(s.key("job"), "lock"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair == nil || len(pair.Session) == 0 {
+		t.Fatal("expected lock key held by a session")
+	}
+	if _, err := s.client.Session().Destroy(pair.Session, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case id := <-lost:
+		if id != "job" {
+			t.Fatalf("expected job lost got %s", id)
+		}
+	case <-time.After(time.Second * 10):
+		t.Fatal("expected lost lock notification")
+	}
+
+	if _, err := s.Token("job"); err == nil {
+		t.Fatal("expected lost lock to be forgotten")
+	}
+}
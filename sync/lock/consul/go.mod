@@ -0,0 +1,8 @@
+module github.com/micro/go-plugins/sync/lock/consul/v2
+
+go 1.13
+
+require (
+	github.com/hashicorp/consul/api v1.3.0
+	github.com/micro/go-micro/v2 v2.9.1
+)
@@ -0,0 +1,422 @@
+// Package dynamodb implements sync using conditional writes on a dynamodb table
+package dynamodb
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	gosync "sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/google/uuid"
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/sync"
+)
+
+// LostHandler is called with the id of a lock which was lost while held
+// because another client took over the lease or it couldn't be renewed in time
+type LostHandler func(id string)
+
+// Notifier is implemented by the dynamodb sync to be told about lost locks
+type Notifier interface {
+	OnLost(LostHandler)
+}
+
+type dynamoSync struct {
+	options sync.Options
+	client  dynamodbiface.DynamoDBAPI
+	table   string
+	owner   string
+
+	mtx      gosync.Mutex
+	locks    map[string]*dynamoLock
+	handlers []LostHandler
+}
+
+type dynamoLock struct {
+	key   string
+	rvn   string
+	fence uint64
+	lease time.Duration
+	exit  chan bool
+	lost  chan bool
+	// when the lease was last renewed
+	renewed time.Time
+}
+
+var (
+	// DefaultTable is the table holding locks. It must have a string partition key named "id".
+	// Items are kept when a lock is released so fencing tokens keep increasing.
+	DefaultTable = "micro-sync"
+	// DefaultLease is the lease duration of a lock when no ttl is set.
+	// Lock holders heartbeat every third of the lease.
+	DefaultLease = 20 * time.Second
+	// DefaultRetryInterval is how often a waiting client checks the lock
+	DefaultRetryInterval = time.Second
+
+	errLockHeld = errors.New("lock held")
+)
+
+func (d *dynamoSync) key(id string) string {
+	return strings.Replace(d.options.Prefix+id, "/", "-", -1)
+}
+
+func isConditionFailed(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+	}
+	return false
+}
+
+// put takes the lock if the condition holds, incrementing the fence.
+// The item is kept on release so the fence keeps increasing.
+func (d *dynamoSync) put(key string, rvn string, lease time.Duration, cond string, values map[string]*dynamodb.AttributeValue) (uint64, error) {
+	if values == nil {
+		values = make(map[string]*dynamodb.AttributeValue)
+	}
+	values[":owner"] = &dynamodb.AttributeValue{S: aws.String(d.owner)}
+	values[":new"] = &dynamodb.AttributeValue{S: aws.String(rvn)}
+	values[":lease"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(lease.Milliseconds(), 10))}
+	values[":one"] = &dynamodb.AttributeValue{N: aws.String("1")}
+
+	rsp, err := d.client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:        aws.String(d.table),
+		Key:              map[string]*dynamodb.AttributeValue{"id": {S: aws.String(key)}},
+		UpdateExpression: aws.String("SET #owner = :owner, rvn = :new, lease = :lease ADD fence :one"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner": aws.String("owner"),
+		},
+		ConditionExpression:       aws.String(cond),
+		ExpressionAttributeValues: values,
+		ReturnValues:              aws.String(dynamodb.ReturnValueUpdatedNew),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var fence uint64
+	if v := rsp.Attributes["fence"]; v != nil && v.N != nil {
+		fence, _ = strconv.ParseUint(*v.N, 10, 64)
+	}
+	return fence, nil
+}
+
+// acquire implements the lease based lock. A client waiting on a held lock
+// records its version and takes the lock over if the version is unchanged
+// after a full lease, meaning the holder stopped heartbeating.
+func (d *dynamoSync) acquire(key string, lease, wait time.Duration) (*dynamoLock, error) {
+	var deadline time.Time
+	if wait > 0 {
+		deadline = time.Now().Add(wait)
+	}
+
+	var (
+		observed    string
+		since       time.Time
+		holderLease time.Duration
+	)
+
+	for {
+		rvn := uuid.New().String()
+		start := time.Now()
+
+		var (
+			fence uint64
+			err   error
+		)
+		switch {
+		case len(observed) == 0:
+			fence, err = d.put(key, rvn, lease, "attribute_not_exists(rvn)", nil)
+		case time.Since(since) >= holderLease:
+			// the holder stopped heartbeating, take over
+			fence, err = d.put(key, rvn, lease, "rvn = :rvn", map[string]*dynamodb.AttributeValue{
+				":rvn": {S: aws.String(observed)},
+			})
+		default:
+			err = errLockHeld
+		}
+
+		if err == nil {
+			return &dynamoLock{
+				key:     key,
+				rvn:     rvn,
+				fence:   fence,
+				lease:   lease,
+				exit:    make(chan bool),
+				lost:    make(chan bool),
+				renewed: start,
+			}, nil
+		}
+		if err != errLockHeld && !isConditionFailed(err) {
+			return nil, err
+		}
+
+		// the lock is held, observe its version
+		rsp, err := d.client.GetItem(&dynamodb.GetItemInput{
+			TableName:      aws.String(d.table),
+			Key:            map[string]*dynamodb.AttributeValue{"id": {S: aws.String(key)}},
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if rsp.Item == nil || rsp.Item["rvn"] == nil {
+			// released in the meantime
+			observed = ""
+			continue
+		}
+
+		if current := aws.StringValue(rsp.Item["rvn"].S); current != observed {
+			observed = current
+			since = time.Now()
+			holderLease = lease
+			if v := rsp.Item["lease"]; v != nil && v.N != nil {
+				if ms, err := strconv.ParseInt(*v.N, 10, 64); err == nil {
+					holderLease = time.Duration(ms) * time.Millisecond
+				}
+			}
+		}
+
+		sleep := DefaultRetryInterval
+		if left := holderLease - time.Since(since); left < sleep {
+			sleep = left
+		}
+
+		if !deadline.IsZero() && time.Now().Add(sleep).After(deadline) {
+			return nil, sync.ErrLockTimeout
+		}
+
+		if sleep > 0 {
+			time.Sleep(sleep)
+		}
+	}
+}
+
+// heartbeat renews the lease by changing the version until released or lost.
+// The lock is lost when another client took over or when no renewal
+// succeeded for a full lease, since another client may take over by then.
+func (d *dynamoSync) heartbeat(id string, l *dynamoLock) {
+	t := time.NewTicker(l.lease / 3)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-l.exit:
+			return
+		case <-t.C:
+		}
+
+		rvn := uuid.New().String()
+		start := time.Now()
+		_, err := d.client.UpdateItem(&dynamodb.UpdateItemInput{
+			TableName:           aws.String(d.table),
+			Key:                 map[string]*dynamodb.AttributeValue{"id": {S: aws.String(l.key)}},
+			UpdateExpression:    aws.String("SET rvn = :new"),
+			ConditionExpression: aws.String("rvn = :rvn AND #owner = :owner"),
+			ExpressionAttributeNames: map[string]*string{
+				"#owner": aws.String("owner"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":new":   {S: aws.String(rvn)},
+				":rvn":   {S: aws.String(l.rvn)},
+				":owner": {S: aws.String(d.owner)},
+			},
+		})
+
+		if err == nil {
+			d.mtx.Lock()
+			l.rvn = rvn
+			l.renewed = start
+			d.mtx.Unlock()
+			continue
+		}
+
+		if !isConditionFailed(err) {
+			if logger.V(logger.WarnLevel, logger.DefaultLogger) {
+				logger.Warnf("dynamodb sync: heartbeat of %s failed: %v", l.key, err)
+			}
+			// transient errors are retried until the lease runs out
+			d.mtx.Lock()
+			expired := time.Since(l.renewed) >= l.lease
+			d.mtx.Unlock()
+			if !expired {
+				continue
+			}
+		}
+
+		// another client took over or the lease expired
+		d.mtx.Lock()
+		if d.locks[id] == l {
+			delete(d.locks, id)
+		}
+		handlers := d.handlers
+		d.mtx.Unlock()
+
+		close(l.lost)
+
+		if logger.V(logger.WarnLevel, logger.DefaultLogger) {
+			logger.Warnf("dynamodb sync: lock %s lost", id)
+		}
+
+		for _, h := range handlers {
+			h(id)
+		}
+		return
+	}
+}
+
+// release deletes the lock item if still owned
+func (d *dynamoSync) release(l *dynamoLock) error {
+	d.mtx.Lock()
+	rvn := l.rvn
+	d.mtx.Unlock()
+
+	select {
+	case <-l.exit:
+	default:
+		close(l.exit)
+	}
+
+	_, err := d.client.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:           aws.String(d.table),
+		Key:                 map[string]*dynamodb.AttributeValue{"id": {S: aws.String(l.key)}},
+		UpdateExpression:    aws.String("REMOVE rvn, #owner"),
+		ConditionExpression: aws.String("rvn = :rvn"),
+		ExpressionAttributeNames: map[string]*string{
+			"#owner": aws.String("owner"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":rvn": {S: aws.String(rvn)},
+		},
+	})
+	if isConditionFailed(err) {
+		return errors.New("lock not held")
+	}
+	return err
+}
+
+func (d *dynamoSync) Init(opts ...sync.Option) error {
+	for _, o := range opts {
+		o(&d.options)
+	}
+	return nil
+}
+
+func (d *dynamoSync) Options() sync.Options {
+	return d.options
+}
+
+func (d *dynamoSync) OnLost(h LostHandler) {
+	d.mtx.Lock()
+	d.handlers = append(d.handlers, h)
+	d.mtx.Unlock()
+}
+
+func (d *dynamoSync) Leader(id string, opts ...sync.LeaderOption) (sync.Leader, error) {
+	var options sync.LeaderOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	l, err := d.acquire(d.key(id)+"/leader", DefaultLease, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	leader := &dynamoLeader{
+		d:      d,
+		l:      l,
+		status: make(chan bool, 1),
+	}
+
+	go d.heartbeat(id, l)
+	go leader.run()
+
+	return leader, nil
+}
+
+func (d *dynamoSync) Lock(id string, opts ...sync.LockOption) error {
+	var options sync.LockOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	lease := options.TTL
+	if lease <= 0 {
+		lease = DefaultLease
+	}
+
+	l, err := d.acquire(d.key(id), lease, options.Wait)
+	if err != nil {
+		return err
+	}
+
+	d.mtx.Lock()
+	d.locks[id] = l
+	d.mtx.Unlock()
+
+	go d.heartbeat(id, l)
+
+	return nil
+}
+
+func (d *dynamoSync) Unlock(id string) error {
+	d.mtx.Lock()
+	l, ok := d.locks[id]
+	if !ok {
+		d.mtx.Unlock()
+		return errors.New("lock not found")
+	}
+	delete(d.locks, id)
+	d.mtx.Unlock()
+
+	return d.release(l)
+}
+
+// Token returns the fencing token of a held lock. The fence attribute
+// of the lock item is incremented with every acquisition.
+func (d *dynamoSync) Token(id string) (uint64, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	l, ok := d.locks[id]
+	if !ok {
+		return 0, errors.New("lock not found")
+	}
+	return l.fence, nil
+}
+
+func (d *dynamoSync) String() string {
+	return "dynamodb"
+}
+
+// NewSync returns a dynamodb sync using the default aws session
+func NewSync(opts ...sync.Option) sync.Sync {
+	sess, err := session.NewSession()
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("dynamodb sync: %v", err))
+	}
+	return NewSyncWithClient(dynamodb.New(sess), opts...)
+}
+
+// NewSyncWithClient returns a dynamodb sync using the given client
+func NewSyncWithClient(client dynamodbiface.DynamoDBAPI, opts ...sync.Option) sync.Sync {
+	var options sync.Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &dynamoSync{
+		options: options,
+		client:  client,
+		table:   DefaultTable,
+		owner:   uuid.New().String(),
+		locks:   make(map[string]*dynamoLock),
+	}
+}
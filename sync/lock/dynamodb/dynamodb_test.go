@@ -0,0 +1,218 @@
+package dynamodb
+
+import (
+	"strconv"
+	"strings"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/micro/go-micro/v2/sync"
+)
+
+// table is a fake dynamodb supporting the condition expressions used by the sync
+type table struct {
+	dynamodbiface.DynamoDBAPI
+
+	gosync.Mutex
+	items map[string]map[string]*dynamodb.AttributeValue
+	// stop accepting heartbeats to simulate a dead holder
+	frozen bool
+	// fail all updates to simulate an unreachable table
+	down bool
+}
+
+var (
+	errCondition   = awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "condition failed", nil)
+	errUnavailable = awserr.New(dynamodb.ErrCodeInternalServerError, "internal server error", nil)
+)
+
+func (t *table) check(item map[string]*dynamodb.AttributeValue, cond *string, values map[string]*dynamodb.AttributeValue) bool {
+	switch aws.StringValue(cond) {
+	case "attribute_not_exists(rvn)":
+		return item == nil || item["rvn"] == nil
+	case "rvn = :rvn AND #owner = :owner":
+		if t.frozen {
+			return false
+		}
+		fallthrough
+	default:
+		return item != nil && item["rvn"] != nil && aws.StringValue(item["rvn"].S) == aws.StringValue(values[":rvn"].S)
+	}
+}
+
+func (t *table) GetItem(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	t.Lock()
+	defer t.Unlock()
+	return &dynamodb.GetItemOutput{Item: t.items[aws.StringValue(in.Key["id"].S)]}, nil
+}
+
+func (t *table) UpdateItem(in *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	t.Lock()
+	defer t.Unlock()
+	if t.down {
+		return nil, errUnavailable
+	}
+	id := aws.StringValue(in.Key["id"].S)
+	item := t.items[id]
+	if !t.check(item, in.ConditionExpression, in.ExpressionAttributeValues) {
+		return nil, errCondition
+	}
+	if item == nil {
+		item = map[string]*dynamodb.AttributeValue{"id": in.Key["id"]}
+		t.items[id] = item
+	}
+
+	values := in.ExpressionAttributeValues
+	expr := aws.StringValue(in.UpdateExpression)
+
+	switch {
+	case strings.HasPrefix(expr, "REMOVE"):
+		delete(item, "rvn")
+		delete(item, "owner")
+	case strings.Contains(expr, "ADD fence"):
+		var fence int
+		if v := item["fence"]; v != nil {
+			fence, _ = strconv.Atoi(*v.N)
+		}
+		item["owner"] = values[":owner"]
+		item["rvn"] = values[":new"]
+		item["lease"] = values[":lease"]
+		item["fence"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(fence + 1))}
+	default:
+		item["rvn"] = values[":new"]
+	}
+
+	return &dynamodb.UpdateItemOutput{
+		Attributes: map[string]*dynamodb.AttributeValue{"fence": item["fence"]},
+	}, nil
+}
+
+func TestLock(t *testing.T) {
+	DefaultRetryInterval = time.Millisecond * 50
+
+	tb := &table{items: make(map[string]map[string]*dynamodb.AttributeValue)}
+	a := NewSyncWithClient(tb)
+	b := NewSyncWithClient(tb)
+
+	if err := a.Lock("job", sync.LockTTL(time.Millisecond*300)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the holder heartbeats so the lock can't be taken
+	if err := b.Lock("job", sync.LockWait(time.Millisecond*600)); err != sync.ErrLockTimeout {
+		t.Fatalf("expected lock timeout got %v", err)
+	}
+
+	if err := a.Unlock("job"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Lock("job", sync.LockWait(time.Millisecond*100)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTakeover(t *testing.T) {
+	DefaultRetryInterval = time.Millisecond * 50
+
+	tb := &table{items: make(map[string]map[string]*dynamodb.AttributeValue)}
+	a := NewSyncWithClient(tb)
+	b := NewSyncWithClient(tb)
+
+	lost := make(chan string, 1)
+	a.(Notifier).OnLost(func(id string) {
+		lost <- id
+	})
+
+	if err := a.Lock("job", sync.LockTTL(time.Millisecond*300)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the holder stops heartbeating
+	tb.Lock()
+	tb.frozen = true
+	tb.Unlock()
+
+	if err := b.Lock("job", sync.LockWait(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case id := <-lost:
+		if id != "job" {
+			t.Fatalf("expected job lost got %s", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected lost lock notification")
+	}
+}
+
+func TestExpired(t *testing.T) {
+	tb := &table{items: make(map[string]map[string]*dynamodb.AttributeValue)}
+	s := NewSyncWithClient(tb)
+
+	lost := make(chan string, 1)
+	s.(Notifier).OnLost(func(id string) {
+		lost <- id
+	})
+
+	if err := s.Lock("job", sync.LockTTL(time.Millisecond*300)); err != nil {
+		t.Fatal(err)
+	}
+
+	// heartbeats fail without the condition failing
+	tb.Lock()
+	tb.down = true
+	tb.Unlock()
+
+	select {
+	case <-lost:
+		t.Fatal("expected lock to be held within the lease")
+	case <-time.After(time.Millisecond * 150):
+	}
+
+	select {
+	case id := <-lost:
+		if id != "job" {
+			t.Fatalf("expected job lost got %s", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected lost lock notification once the lease passed")
+	}
+
+	if _, err := s.(interface {
+		Token(string) (uint64, error)
+	}).Token("job"); err == nil {
+		t.Fatal("expected expired lock to be forgotten")
+	}
+}
+
+func TestToken(t *testing.T) {
+	tb := &table{items: make(map[string]map[string]*dynamodb.AttributeValue)}
+	s := NewSyncWithClient(tb)
+
+	var last uint64
+	for i := 0; i < 3; i++ {
+		if err := s.Lock("job"); err != nil {
+			t.Fatal(err)
+		}
+		token, err := s.(interface {
+			Token(string) (uint64, error)
+		}).Token("job")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token <= last {
+			t.Fatalf("expected token greater than %d got %d", last, token)
+		}
+		last = token
+		if err := s.Unlock("job"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
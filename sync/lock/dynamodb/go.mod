@@ -0,0 +1,9 @@
+module github.com/micro/go-plugins/sync/lock/dynamodb/v2
+
+go 1.13
+
+require (
+	github.com/aws/aws-sdk-go v1.28.4
+	github.com/google/uuid v1.1.1
+	github.com/micro/go-micro/v2 v2.9.1
+)
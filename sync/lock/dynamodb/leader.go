@@ -0,0 +1,39 @@
+package dynamodb
+
+import (
+	gosync "sync"
+)
+
+type dynamoLeader struct {
+	d *dynamoSync
+	l *dynamoLock
+
+	once   gosync.Once
+	status chan bool
+}
+
+func (l *dynamoLeader) run() {
+	select {
+	case <-l.l.lost:
+		l.lost()
+	case <-l.l.exit:
+	}
+}
+
+func (l *dynamoLeader) lost() {
+	l.once.Do(func() {
+		l.status <- true
+		close(l.status)
+	})
+}
+
+// Status returns a channel which receives once leadership is lost
+func (l *dynamoLeader) Status() chan bool {
+	return l.status
+}
+
+func (l *dynamoLeader) Resign() error {
+	err := l.d.release(l.l)
+	l.lost()
+	return err
+}
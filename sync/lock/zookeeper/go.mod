@@ -0,0 +1,8 @@
+module github.com/micro/go-plugins/sync/lock/zookeeper/v2
+
+go 1.13
+
+require (
+	github.com/micro/go-micro/v2 v2.9.1
+	github.com/samuel/go-zookeeper v0.0.0-20190923202752-2cc03de413da
+)
@@ -0,0 +1,58 @@
+package zookeeper
+
+import (
+	gosync "sync"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+type zkLeader struct {
+	z    *zkSync
+	node string
+
+	once   gosync.Once
+	status chan bool
+}
+
+// run watches the leader node which is removed when the session expires
+// or the node is deleted by an operator
+func (l *zkLeader) run() {
+	for {
+		exists, _, ch, err := l.z.conn.ExistsW(l.node)
+		if err != nil || !exists {
+			l.lost()
+			return
+		}
+
+		e := <-ch
+		if e.Type == zk.EventNodeDeleted || e.State == zk.StateExpired {
+			l.lost()
+			return
+		}
+	}
+}
+
+func (l *zkLeader) lost() {
+	l.once.Do(func() {
+		l.z.mtx.Lock()
+		delete(l.z.leaders, l)
+		l.z.mtx.Unlock()
+
+		l.status <- true
+		close(l.status)
+	})
+}
+
+// Status returns a channel which receives once leadership is lost
+func (l *zkLeader) Status() chan bool {
+	return l.status
+}
+
+func (l *zkLeader) Resign() error {
+	err := l.z.conn.Delete(l.node, -1)
+	if err == zk.ErrNoNode {
+		err = nil
+	}
+	l.lost()
+	return err
+}
@@ -0,0 +1,107 @@
+package zookeeper
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/micro/go-micro/v2/sync"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// sequence returns the sequence number zookeeper appended to a node
+func sequence(node string) int {
+	i := strings.LastIndex(node, "-")
+	if i < 0 {
+		return -1
+	}
+	seq, err := strconv.Atoi(node[i+1:])
+	if err != nil {
+		return -1
+	}
+	return seq
+}
+
+// createParents creates every node of dir which doesn't exist
+func (z *zkSync) createParents(dir string) error {
+	p := ""
+	for _, part := range strings.Split(strings.Trim(dir, "/"), "/") {
+		p += "/" + part
+		_, err := z.conn.Create(p, []byte{}, 0, zk.WorldACL(zk.PermAll))
+		if err != nil && err != zk.ErrNodeExists {
+			return err
+		}
+	}
+	return nil
+}
+
+// acquire implements the ephemeral sequential node recipe. It creates a
+// node under dir and blocks until it has the lowest sequence, watching
+// only its predecessor to avoid the herd effect. A zero wait blocks forever.
+func (z *zkSync) acquire(dir string, wait time.Duration) (string, error) {
+	var timeout <-chan time.Time
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	acl := zk.WorldACL(zk.PermAll)
+	prefix := path.Join(dir, "n-")
+
+	node, err := z.conn.CreateProtectedEphemeralSequential(prefix, []byte{}, acl)
+	if err == zk.ErrNoNode {
+		if err = z.createParents(dir); err != nil {
+			return "", err
+		}
+		node, err = z.conn.CreateProtectedEphemeralSequential(prefix, []byte{}, acl)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	seq := sequence(node)
+
+	for {
+		children, _, err := z.conn.Children(dir)
+		if err != nil {
+			z.conn.Delete(node, -1)
+			return "", err
+		}
+
+		sort.Slice(children, func(i, j int) bool {
+			return sequence(children[i]) < sequence(children[j])
+		})
+
+		// find the node immediately before ours
+		prev := ""
+		for _, c := range children {
+			if sequence(c) >= seq {
+				break
+			}
+			prev = c
+		}
+
+		if len(prev) == 0 {
+			return node, nil
+		}
+
+		exists, _, ch, err := z.conn.ExistsW(path.Join(dir, prev))
+		if err != nil {
+			z.conn.Delete(node, -1)
+			return "", err
+		}
+		if !exists {
+			continue
+		}
+
+		select {
+		case <-ch:
+		case <-timeout:
+			z.conn.Delete(node, -1)
+			return "", sync.ErrLockTimeout
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package zookeeper
+
+import (
+	"testing"
+)
+
+func TestSequence(t *testing.T) {
+	testCases := []struct {
+		node string
+		seq  int
+	}{
+		{"_c_2e7f5b4a9a1c8d6e0f3b2a1c9d8e7f6a-n-0000000001", 1},
+		{"/micro/sync/job/lock/_c_2e7f5b4a9a1c8d6e0f3b2a1c9d8e7f6a-n-0000000042", 42},
+		{"invalid", -1},
+	}
+
+	for _, c := range testCases {
+		if seq := sequence(c.node); seq != c.seq {
+			t.Fatalf("expected sequence %d for %s got %d", c.seq, c.node, seq)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Package zookeeper implements sync using the zookeeper lock and leader latch recipes
+package zookeeper
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	gosync "sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/sync"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// LostHandler is called with the id of a lock which was lost while held
+// because the zookeeper session expired
+type LostHandler func(id string)
+
+// Notifier is implemented by the zookeeper sync to be told about lost locks
+type Notifier interface {
+	OnLost(LostHandler)
+}
+
+type zkSync struct {
+	options sync.Options
+	path    string
+	conn    *zk.Conn
+
+	mtx      gosync.Mutex
+	locks    map[string]string
+	leaders  map[*zkLeader]bool
+	handlers []LostHandler
+}
+
+var (
+	// DefaultPath is the znode under which locks and elections are kept
+	DefaultPath = "/micro/sync"
+	// DefaultSessionTimeout is the zookeeper session timeout. Ephemeral
+	// nodes, and so locks and leadership, are lost when the session expires.
+	DefaultSessionTimeout = 10 * time.Second
+)
+
+func (z *zkSync) key(id string) string {
+	return path.Join(z.path, strings.Replace(z.options.Prefix+id, "/", "-", -1))
+}
+
+// watch handles session events, expiring every lock and leader held
+func (z *zkSync) watch(events <-chan zk.Event) {
+	for e := range events {
+		if e.Type != zk.EventSession || e.State != zk.StateExpired {
+			continue
+		}
+
+		z.mtx.Lock()
+		var ids []string
+		for id := range z.locks {
+			ids = append(ids, id)
+		}
+		leaders := z.leaders
+		z.locks = make(map[string]string)
+		z.leaders = make(map[*zkLeader]bool)
+		handlers := z.handlers
+		z.mtx.Unlock()
+
+		if logger.V(logger.WarnLevel, logger.DefaultLogger) {
+			logger.Warnf("zookeeper sync: session expired, lost %d locks and %d leaders", len(ids), len(leaders))
+		}
+
+		for l := range leaders {
+			l.lost()
+		}
+
+		for _, id := range ids {
+			for _, h := range handlers {
+				h(id)
+			}
+		}
+	}
+}
+
+func (z *zkSync) Init(opts ...sync.Option) error {
+	for _, o := range opts {
+		o(&z.options)
+	}
+	return nil
+}
+
+func (z *zkSync) Options() sync.Options {
+	return z.options
+}
+
+func (z *zkSync) OnLost(h LostHandler) {
+	z.mtx.Lock()
+	z.handlers = append(z.handlers, h)
+	z.mtx.Unlock()
+}
+
+func (z *zkSync) Leader(id string, opts ...sync.LeaderOption) (sync.Leader, error) {
+	var options sync.LeaderOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	node, err := z.acquire(path.Join(z.key(id), "leader"), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &zkLeader{
+		z:      z,
+		node:   node,
+		status: make(chan bool, 1),
+	}
+
+	z.mtx.Lock()
+	z.leaders[l] = true
+	z.mtx.Unlock()
+
+	go l.run()
+
+	return l, nil
+}
+
+func (z *zkSync) Lock(id string, opts ...sync.LockOption) error {
+	var options sync.LockOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	node, err := z.acquire(path.Join(z.key(id), "lock"), options.Wait)
+	if err != nil {
+		return err
+	}
+
+	z.mtx.Lock()
+	z.locks[id] = node
+	z.mtx.Unlock()
+
+	return nil
+}
+
+func (z *zkSync) Unlock(id string) error {
+	z.mtx.Lock()
+	defer z.mtx.Unlock()
+	node, ok := z.locks[id]
+	if !ok {
+		return errors.New("lock not found")
+	}
+	delete(z.locks, id)
+	return z.conn.Delete(node, -1)
+}
+
+// Token returns the fencing token of a held lock. It is the zxid which
+// created the lock node so it increases with every acquisition.
+func (z *zkSync) Token(id string) (uint64, error) {
+	z.mtx.Lock()
+	node, ok := z.locks[id]
+	z.mtx.Unlock()
+	if !ok {
+		return 0, errors.New("lock not found")
+	}
+
+	exists, stat, err := z.conn.Exists(node)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, errors.New("lock node not found")
+	}
+	return uint64(stat.Czxid), nil
+}
+
+func (z *zkSync) String() string {
+	return "zookeeper"
+}
+
+// NewSync returns a zookeeper sync
+func NewSync(opts ...sync.Option) sync.Sync {
+	var options sync.Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	var addrs []string
+	for _, addr := range options.Nodes {
+		if len(addr) > 0 {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	if len(addrs) == 0 {
+		addrs = []string{"127.0.0.1:2181"}
+	}
+
+	c, events, err := zk.Connect(addrs, DefaultSessionTimeout)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("zookeeper sync: %v", err))
+	}
+
+	z := &zkSync{
+		options: options,
+		path:    DefaultPath,
+		conn:    c,
+		locks:   make(map[string]string),
+		leaders: make(map[*zkLeader]bool),
+	}
+
+	go z.watch(events)
+
+	return z
+}
@@ -0,0 +1,349 @@
+// Package etcd is a replicated key-value map kept in etcd with a local read cache
+package etcd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+	gosync "sync"
+	"time"
+
+	client "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+)
+
+// Map is a key-value map shared between instances. Reads are served from
+// a local cache which is kept up to date by watching etcd.
+type Map interface {
+	// Get decodes the value of key into v
+	Get(key string, v interface{}) error
+	// Put encodes v and stores it under key
+	Put(key string, v interface{}) error
+	// Delete removes key
+	Delete(key string) error
+	// Keys lists the keys in the map
+	Keys() []string
+	// Watch returns a watcher of changes to the map
+	Watch() (Watcher, error)
+	// Close stops replication
+	Close() error
+}
+
+// Watcher receives changes made to the map by any instance
+type Watcher interface {
+	Next() (*Event, error)
+	Stop() error
+}
+
+// EventType is the type of a change
+type EventType int
+
+const (
+	Create EventType = iota
+	Update
+	Delete
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Create:
+		return "create"
+	case Update:
+		return "update"
+	case Delete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// Event is a change to a key of the map
+type Event struct {
+	Type  EventType
+	Key   string
+	Value []byte
+}
+
+// Decode decodes the value of the event into v
+func (e *Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Value, v)
+}
+
+type etcdMap struct {
+	opts   Options
+	client *client.Client
+	prefix string
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mtx      gosync.RWMutex
+	cache    map[string][]byte
+	watchers map[*watcher]bool
+}
+
+type watcher struct {
+	m    *etcdMap
+	next chan *Event
+	exit chan bool
+	once gosync.Once
+}
+
+var (
+	// DefaultPrefix of map keys
+	DefaultPrefix = "/micro/sync/map"
+
+	// ErrNotFound is returned when a key is not in the map
+	ErrNotFound = errors.New("not found")
+	// ErrWatcherStopped is returned when a watcher has been stopped
+	ErrWatcherStopped = errors.New("watcher stopped")
+)
+
+func (w *watcher) Next() (*Event, error) {
+	select {
+	case ev := <-w.next:
+		return ev, nil
+	case <-w.exit:
+		return nil, ErrWatcherStopped
+	}
+}
+
+func (w *watcher) Stop() error {
+	w.once.Do(func() {
+		w.m.mtx.Lock()
+		delete(w.m.watchers, w)
+		w.m.mtx.Unlock()
+		close(w.exit)
+	})
+	return nil
+}
+
+func (e *etcdMap) key(k string) string {
+	return e.prefix + k
+}
+
+// apply updates the cache with an etcd event and returns the map event
+func (e *etcdMap) apply(ev *client.Event) *Event {
+	key := strings.TrimPrefix(string(ev.Kv.Key), e.prefix)
+
+	if ev.Type == mvccpb.DELETE {
+		if _, ok := e.cache[key]; !ok {
+			return nil
+		}
+		delete(e.cache, key)
+		return &Event{Type: Delete, Key: key}
+	}
+
+	typ := Update
+	if _, ok := e.cache[key]; !ok {
+		typ = Create
+	}
+	e.cache[key] = ev.Kv.Value
+
+	return &Event{Type: typ, Key: key, Value: ev.Kv.Value}
+}
+
+// notify sends an event to every watcher without blocking
+func (e *etcdMap) notify(ev *Event) {
+	for w := range e.watchers {
+		select {
+		case w.next <- ev:
+		case <-w.exit:
+		default:
+		}
+	}
+}
+
+// diff returns the events changing the old cache into the new one in key order
+func diff(old, cache map[string][]byte) []*Event {
+	var events []*Event
+
+	for k, v := range cache {
+		ov, ok := old[k]
+		switch {
+		case !ok:
+			events = append(events, &Event{Type: Create, Key: k, Value: v})
+		case !bytes.Equal(ov, v):
+			events = append(events, &Event{Type: Update, Key: k, Value: v})
+		}
+	}
+	for k := range old {
+		if _, ok := cache[k]; !ok {
+			events = append(events, &Event{Type: Delete, Key: k})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Key < events[j].Key
+	})
+
+	return events
+}
+
+// load fills the cache from etcd and returns the revision it was read at.
+// Watchers are sent the changes since the cache was last updated, so none
+// are lost when the cache is reloaded after the watch failed.
+func (e *etcdMap) load() (int64, error) {
+	rsp, err := e.client.Get(e.ctx, e.prefix, client.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+
+	cache := make(map[string][]byte, len(rsp.Kvs))
+	for _, kv := range rsp.Kvs {
+		cache[strings.TrimPrefix(string(kv.Key), e.prefix)] = kv.Value
+	}
+
+	e.mtx.Lock()
+	for _, ev := range diff(e.cache, cache) {
+		e.notify(ev)
+	}
+	e.cache = cache
+	e.mtx.Unlock()
+
+	return rsp.Header.Revision, nil
+}
+
+// run keeps the cache in sync, reloading when the watch is compacted or fails
+func (e *etcdMap) run(rev int64) {
+	for {
+		for rsp := range e.client.Watch(e.ctx, e.prefix, client.WithPrefix(), client.WithRev(rev+1)) {
+			if rsp.Err() != nil {
+				break
+			}
+
+			e.mtx.Lock()
+			for _, ev := range rsp.Events {
+				if mev := e.apply(ev); mev != nil {
+					e.notify(mev)
+				}
+			}
+			e.mtx.Unlock()
+
+			rev = rsp.Header.Revision
+		}
+
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+
+		r, err := e.load()
+		if err != nil {
+			continue
+		}
+		rev = r
+	}
+}
+
+func (e *etcdMap) Get(key string, v interface{}) error {
+	e.mtx.RLock()
+	b, ok := e.cache[key]
+	e.mtx.RUnlock()
+
+	if !ok {
+		return ErrNotFound
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+func (e *etcdMap) Put(key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.client.Put(e.ctx, e.key(key), string(b))
+	return err
+}
+
+func (e *etcdMap) Delete(key string) error {
+	_, err := e.client.Delete(e.ctx, e.key(key))
+	return err
+}
+
+func (e *etcdMap) Keys() []string {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+
+	keys := make([]string, 0, len(e.cache))
+	for k := range e.cache {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (e *etcdMap) Watch() (Watcher, error) {
+	w := &watcher{
+		m:    e,
+		next: make(chan *Event, 100),
+		exit: make(chan bool),
+	}
+
+	e.mtx.Lock()
+	e.watchers[w] = true
+	e.mtx.Unlock()
+
+	return w, nil
+}
+
+func (e *etcdMap) Close() error {
+	e.cancel()
+	return e.client.Close()
+}
+
+// NewMap returns the map called name, loading its current contents
+func NewMap(name string, opts ...Option) (Map, error) {
+	options := Options{
+		Prefix: DefaultPrefix,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	var endpoints []string
+	for _, addr := range options.Nodes {
+		if len(addr) > 0 {
+			endpoints = append(endpoints, addr)
+		}
+	}
+
+	if len(endpoints) == 0 {
+		endpoints = []string{"http://127.0.0.1:2379"}
+	}
+
+	c, err := client.New(client.Config{
+		Endpoints:   endpoints,
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	e := &etcdMap{
+		opts:     options,
+		client:   c,
+		prefix:   path.Join(options.Prefix, strings.Replace(name, "/", "-", -1)) + "/",
+		ctx:      ctx,
+		cancel:   cancel,
+		cache:    make(map[string][]byte),
+		watchers: make(map[*watcher]bool),
+	}
+
+	rev, err := e.load()
+	if err != nil {
+		cancel()
+		c.Close()
+		return nil, err
+	}
+
+	go e.run(rev)
+
+	return e, nil
+}
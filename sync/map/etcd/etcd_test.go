@@ -0,0 +1,153 @@
+package etcd
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	client "github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+)
+
+func TestApply(t *testing.T) {
+	e := &etcdMap{
+		prefix: "/micro/sync/map/flags/",
+		cache:  make(map[string][]byte),
+	}
+
+	put := func(k, v string) *client.Event {
+		return &client.Event{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte(e.prefix + k), Value: []byte(v)}}
+	}
+	del := func(k string) *client.Event {
+		return &client.Event{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte(e.prefix + k)}}
+	}
+
+	testData := []struct {
+		event *client.Event
+		typ   EventType
+		none  bool
+	}{
+		{put("beta", "true"), Create, false},
+		{put("beta", "false"), Update, false},
+		{del("beta"), Delete, false},
+		{del("beta"), 0, true},
+	}
+
+	for i, d := range testData {
+		ev := e.apply(d.event)
+		if d.none {
+			if ev != nil {
+				t.Fatalf("%d: expected no event got %v", i, ev.Type)
+			}
+			continue
+		}
+		if ev == nil {
+			t.Fatalf("%d: expected %v event got none", i, d.typ)
+		}
+		if ev.Type != d.typ {
+			t.Fatalf("%d: expected %v event got %v", i, d.typ, ev.Type)
+		}
+		if ev.Key != "beta" {
+			t.Fatalf("%d: expected key beta got %s", i, ev.Key)
+		}
+	}
+
+	e.apply(put("beta", "true"))
+
+	var v bool
+	if err := e.Get("beta", &v); err != nil || !v {
+		t.Fatalf("expected beta true got %v %v", v, err)
+	}
+	if err := e.Get("gamma", &v); err != ErrNotFound {
+		t.Fatalf("expected not found got %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := map[string][]byte{
+		"alpha": []byte("1"),
+		"beta":  []byte("2"),
+		"gamma": []byte("3"),
+	}
+	cache := map[string][]byte{
+		"alpha": []byte("1"),
+		"beta":  []byte("4"),
+		"delta": []byte("5"),
+	}
+
+	expected := []Event{
+		{Type: Update, Key: "beta", Value: []byte("4")},
+		{Type: Create, Key: "delta", Value: []byte("5")},
+		{Type: Delete, Key: "gamma"},
+	}
+
+	events := diff(old, cache)
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events got %d", len(expected), len(events))
+	}
+	for i, ev := range events {
+		if ev.Type != expected[i].Type || ev.Key != expected[i].Key || string(ev.Value) != string(expected[i].Value) {
+			t.Fatalf("%d: expected %v %s got %v %s", i, expected[i].Type, expected[i].Key, ev.Type, ev.Key)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	addrs := os.Getenv("ETCD_ADDRS")
+	if len(addrs) == 0 {
+		t.Skip("ETCD_ADDRS not set")
+	}
+
+	c, err := client.New(client.Config{Endpoints: strings.Split(addrs, ",")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a map which isn't watching etcd misses every change until reloaded
+	e := &etcdMap{
+		client:   c,
+		prefix:   "/" + t.Name() + "/",
+		ctx:      ctx,
+		cache:    make(map[string][]byte),
+		watchers: make(map[*watcher]bool),
+	}
+	defer c.Delete(ctx, e.prefix, client.WithPrefix())
+
+	c.Put(ctx, e.key("alpha"), "1")
+	c.Put(ctx, e.key("beta"), "2")
+	if _, err := e.load(); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := e.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	c.Put(ctx, e.key("alpha"), "3")
+	c.Delete(ctx, e.key("beta"))
+	c.Put(ctx, e.key("gamma"), "4")
+	if _, err := e.load(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []Event{
+		{Type: Update, Key: "alpha"},
+		{Type: Delete, Key: "beta"},
+		{Type: Create, Key: "gamma"},
+	} {
+		ev, err := w.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Type != expected.Type || ev.Key != expected.Key {
+			t.Fatalf("expected %v %s got %v %s", expected.Type, expected.Key, ev.Type, ev.Key)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package etcd
+
+type Options struct {
+	// Nodes are the etcd addresses
+	Nodes []string
+	// Prefix of map keys
+	Prefix string
+}
+
+type Option func(o *Options)
+
+// Nodes sets the etcd addresses
+func Nodes(a ...string) Option {
+	return func(o *Options) {
+		o.Nodes = a
+	}
+}
+
+// Prefix sets the prefix of map keys
+func Prefix(p string) Option {
+	return func(o *Options) {
+		o.Prefix = p
+	}
+}
@@ -0,0 +1,186 @@
+// Package etcd is an etcd backed distributed counting semaphore
+package etcd
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	client "github.com/coreos/etcd/clientv3"
+	cc "github.com/coreos/etcd/clientv3/concurrency"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/google/uuid"
+)
+
+// Semaphore limits how many holders may run at once across processes
+type Semaphore interface {
+	// Acquire blocks until a permit is available or the context is done
+	Acquire(ctx context.Context) (Permit, error)
+	// Close releases every permit held and the session
+	Close() error
+}
+
+// Permit is a single acquired slot of a semaphore
+type Permit interface {
+	// Release returns the permit to the semaphore
+	Release() error
+}
+
+type etcdSemaphore struct {
+	opts    Options
+	client  *client.Client
+	session *cc.Session
+	key     string
+	limit   int
+}
+
+type etcdPermit struct {
+	client *client.Client
+	key    string
+}
+
+var (
+	// DefaultPrefix of semaphore keys
+	DefaultPrefix = "/micro/sync/semaphore"
+	// DefaultTTL of the session in seconds
+	DefaultTTL = 15
+
+	// ErrSessionExpired is returned when the session holding permits has expired
+	ErrSessionExpired = errors.New("session expired")
+)
+
+func (p *etcdPermit) Release() error {
+	_, err := p.client.Delete(context.Background(), p.key)
+	return err
+}
+
+// holds returns true if key is among the first limit waiters
+func (e *etcdSemaphore) holds(ctx context.Context, key string) (bool, int64, error) {
+	rsp, err := e.client.Get(ctx, e.key+"/",
+		client.WithPrefix(),
+		client.WithSort(client.SortByCreateRevision, client.SortAscend),
+		client.WithLimit(int64(e.limit)),
+		client.WithKeysOnly(),
+	)
+	if err != nil {
+		return false, 0, err
+	}
+
+	for _, kv := range rsp.Kvs {
+		if string(kv.Key) == key {
+			return true, rsp.Header.Revision, nil
+		}
+	}
+
+	return false, rsp.Header.Revision, nil
+}
+
+func (e *etcdSemaphore) Acquire(ctx context.Context) (Permit, error) {
+	select {
+	case <-e.session.Done():
+		return nil, ErrSessionExpired
+	default:
+	}
+
+	// waiters queue in order of creation, the first limit hold a permit
+	key := e.key + "/" + uuid.New().String()
+	if _, err := e.client.Put(ctx, key, "", client.WithLease(e.session.Lease())); err != nil {
+		return nil, err
+	}
+
+	permit := &etcdPermit{client: e.client, key: key}
+
+	for {
+		ok, rev, err := e.holds(ctx, key)
+		if err != nil {
+			permit.Release()
+			return nil, err
+		}
+		if ok {
+			return permit, nil
+		}
+
+		if err := e.wait(ctx, rev+1); err != nil {
+			permit.Release()
+			return nil, err
+		}
+	}
+}
+
+// wait blocks until a key of the semaphore is deleted after rev
+func (e *etcdSemaphore) wait(ctx context.Context, rev int64) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for rsp := range e.client.Watch(ctx, e.key+"/", client.WithPrefix(), client.WithRev(rev)) {
+		if err := rsp.Err(); err != nil {
+			return err
+		}
+		for _, ev := range rsp.Events {
+			if ev.Type == mvccpb.DELETE {
+				return nil
+			}
+		}
+	}
+
+	select {
+	case <-e.session.Done():
+		return ErrSessionExpired
+	default:
+		return ctx.Err()
+	}
+}
+
+func (e *etcdSemaphore) Close() error {
+	return e.session.Close()
+}
+
+// NewSemaphore returns a semaphore for name allowing limit concurrent holders
+func NewSemaphore(name string, limit int, opts ...Option) (Semaphore, error) {
+	options := Options{
+		Prefix: DefaultPrefix,
+		TTL:    DefaultTTL,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if limit < 1 {
+		return nil, errors.New("limit must be at least 1")
+	}
+
+	var endpoints []string
+	for _, addr := range options.Nodes {
+		if len(addr) > 0 {
+			endpoints = append(endpoints, addr)
+		}
+	}
+
+	if len(endpoints) == 0 {
+		endpoints = []string{"http://127.0.0.1:2379"}
+	}
+
+	c, err := client.New(client.Config{
+		Endpoints:   endpoints,
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := cc.NewSession(c, cc.WithTTL(options.TTL))
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return &etcdSemaphore{
+		opts:    options,
+		client:  c,
+		session: s,
+		key:     path.Join(options.Prefix, strings.Replace(name, "/", "-", -1)),
+		limit:   limit,
+	}, nil
+}
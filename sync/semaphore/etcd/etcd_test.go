@@ -0,0 +1,162 @@
+package etcd
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	client "github.com/coreos/etcd/clientv3"
+)
+
+func newTestSemaphore(t *testing.T, limit int) *etcdSemaphore {
+	addrs := os.Getenv("ETCD_ADDRS")
+	if len(addrs) == 0 {
+		t.Skip("ETCD_ADDRS not set")
+	}
+
+	s, err := NewSemaphore("test", limit, Nodes(strings.Split(addrs, ",")...), Prefix("/"+t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.(*etcdSemaphore)
+}
+
+func TestLimit(t *testing.T) {
+	s := newTestSemaphore(t, 2)
+	defer s.Close()
+
+	var permits []Permit
+	for i := 0; i < 2; i++ {
+		p, err := s.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		permits = append(permits, p)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*200)
+	defer cancel()
+	if _, err := s.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v got %v", context.DeadlineExceeded, err)
+	}
+
+	if err := permits[0].Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := s.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	s := newTestSemaphore(t, 1)
+	defer s.Close()
+
+	holder, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type acquired struct {
+		id     int
+		permit Permit
+	}
+
+	ch := make(chan acquired, 3)
+	for i := 0; i < 3; i++ {
+		go func(id int) {
+			p, err := s.Acquire(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ch <- acquired{id, p}
+		}(i)
+
+		// wait for the waiter to queue before starting the next
+		waitWaiters(t, s, i+2)
+	}
+
+	if err := holder.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case a := <-ch:
+			if a.id != i {
+				t.Fatalf("expected waiter %d got %d", i, a.id)
+			}
+			if err := a.permit.Release(); err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("expected waiter %d to acquire", i)
+		}
+	}
+}
+
+func TestReleaseOnCancel(t *testing.T) {
+	s := newTestSemaphore(t, 1)
+	defer s.Close()
+
+	holder, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := s.Acquire(ctx)
+		errCh <- err
+	}()
+
+	waitWaiters(t, s, 2)
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err != context.Canceled {
+			t.Fatalf("expected %v got %v", context.Canceled, err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("expected cancelled acquire to return")
+	}
+
+	// the cancelled waiter leaves the queue
+	waitWaiters(t, s, 1)
+
+	if err := holder.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := s.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitWaiters waits until n keys are queued on the semaphore
+func waitWaiters(t *testing.T, s *etcdSemaphore, n int) {
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		rsp, err := s.client.Get(context.Background(), s.key+"/", client.WithPrefix(), client.WithCountOnly())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rsp.Count == int64(n) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiters got %d", n, rsp.Count)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
@@ -0,0 +1,34 @@
+package etcd
+
+type Options struct {
+	// Nodes are the etcd addresses
+	Nodes []string
+	// Prefix of semaphore keys
+	Prefix string
+	// TTL of the session holding permits, permits of a process which stops
+	// renewing it are released
+	TTL int
+}
+
+type Option func(o *Options)
+
+// Nodes sets the etcd addresses
+func Nodes(a ...string) Option {
+	return func(o *Options) {
+		o.Nodes = a
+	}
+}
+
+// Prefix sets the prefix of semaphore keys
+func Prefix(p string) Option {
+	return func(o *Options) {
+		o.Prefix = p
+	}
+}
+
+// TTL sets the session ttl in seconds
+func TTL(t int) Option {
+	return func(o *Options) {
+		o.TTL = t
+	}
+}
@@ -11,6 +11,55 @@ func init() {
 	cmd.DefaultTransports["grpc"] = NewTransport
 }
 
+// grpcTransport reports and records the load of servers when the options are set
+type grpcTransport struct {
+	transport.Transport
+
+	reporter *loadReporter
+	loads    *Loads
+}
+
+func (g *grpcTransport) Init(opts ...transport.Option) error {
+	if err := g.Transport.Init(opts...); err != nil {
+		return err
+	}
+	g.configure()
+	return nil
+}
+
+func (g *grpcTransport) configure() {
+	ctx := g.Transport.Options().Context
+	if ctx == nil {
+		return
+	}
+	if f, ok := ctx.Value(reportLoadKey{}).(func() float64); ok {
+		g.reporter = &loadReporter{utilization: f}
+	}
+	if l, ok := ctx.Value(loadsKey{}).(*Loads); ok {
+		g.loads = l
+	}
+}
+
+func (g *grpcTransport) Dial(addr string, opts ...transport.DialOption) (transport.Client, error) {
+	c, err := g.Transport.Dial(addr, opts...)
+	if err != nil || g.loads == nil {
+		return c, err
+	}
+	return &loadClient{Client: c, loads: g.loads}, nil
+}
+
+func (g *grpcTransport) Listen(addr string, opts ...transport.ListenOption) (transport.Listener, error) {
+	l, err := g.Transport.Listen(addr, opts...)
+	if err != nil || g.reporter == nil {
+		return l, err
+	}
+	return &loadListener{Listener: l, r: g.reporter}, nil
+}
+
+// NewTransport returns a grpc transport, servers report their load in responses
+// with ReportLoad and clients record it with WithLoads
 func NewTransport(opts ...transport.Option) transport.Transport {
-	return grpc.NewTransport(opts...)
+	g := &grpcTransport{Transport: grpc.NewTransport(opts...)}
+	g.configure()
+	return g
 }
@@ -0,0 +1,133 @@
+package grpc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/client/selector"
+	"github.com/micro/go-micro/v2/registry"
+	"github.com/micro/go-micro/v2/transport"
+)
+
+func TestLoadReporting(t *testing.T) {
+	server := NewTransport(ReportLoad(func() float64 { return 0.5 }))
+	l, err := server.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// the server holds a request in flight before responding to the next
+	hold := make(chan bool)
+	go l.Accept(func(sock transport.Socket) {
+		defer sock.Close()
+		for {
+			var m transport.Message
+			if err := sock.Recv(&m); err != nil {
+				return
+			}
+			if m.Header["hold"] == "true" {
+				<-hold
+			}
+			if err := sock.Send(&transport.Message{Header: map[string]string{"foo": "bar"}, Body: m.Body}); err != nil {
+				return
+			}
+		}
+	})
+
+	loads := NewLoads()
+	client := NewTransport()
+	if err := client.Init(WithLoads(loads)); err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(c transport.Client, header map[string]string) *transport.Message {
+		if err := c.Send(&transport.Message{Header: header, Body: []byte("foo")}); err != nil {
+			t.Fatal(err)
+		}
+		var m transport.Message
+		if err := c.Recv(&m); err != nil {
+			t.Fatal(err)
+		}
+		return &m
+	}
+
+	held, err := client.Dial(l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Close()
+	done := make(chan *transport.Message)
+	go func() {
+		done <- call(held, map[string]string{"hold": "true"})
+	}()
+
+	c, err := client.Dial(l.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// wait for the held request to be in flight
+	var m *transport.Message
+	for i := 0; ; i++ {
+		m = call(c, nil)
+		if m.Header[InFlightHeader] == "1" {
+			break
+		}
+		if i == 50 {
+			t.Fatalf("expected a request in flight got %v", m.Header)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if m.Header["foo"] != "bar" || m.Header[UtilizationHeader] != "0.5" {
+		t.Fatalf("expected the load to be added to the header got %v", m.Header)
+	}
+
+	load, ok := loads.Get(l.Addr())
+	if !ok || load.InFlight != 1 || load.Utilization != 0.5 {
+		t.Fatalf("expected the load to be recorded got %+v", load)
+	}
+
+	close(hold)
+	if m := <-done; m.Header[InFlightHeader] != "0" {
+		t.Fatalf("expected no requests in flight got %v", m.Header)
+	}
+}
+
+func TestLoadStrategy(t *testing.T) {
+	loads := NewLoads()
+	loads.record("10.0.0.1:8080", map[string]string{InFlightHeader: "5", UtilizationHeader: "0.1"})
+	loads.record("10.0.0.2:8080", map[string]string{InFlightHeader: "1", UtilizationHeader: "0.9"})
+	loads.record("10.0.0.3:8080", map[string]string{InFlightHeader: "1", UtilizationHeader: "0.2"})
+
+	services := []*registry.Service{{
+		Name: "foo",
+		Nodes: []*registry.Node{
+			{Id: "1", Address: "10.0.0.1:8080"},
+			{Id: "2", Address: "10.0.0.2:8080"},
+			{Id: "3", Address: "10.0.0.3:8080"},
+		},
+	}}
+
+	next := LoadStrategy(loads)(services)
+	for i := 0; i < 10; i++ {
+		node, err := next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node.Id != "3" {
+			t.Fatalf("expected the least loaded node got %s", node.Id)
+		}
+	}
+
+	// nodes without a load are selected as if they're idle
+	services[0].Nodes = append(services[0].Nodes, &registry.Node{Id: "4", Address: "10.0.0.4:8080"})
+	if node, _ := LoadStrategy(loads)(services)(); node.Id != "4" {
+		t.Fatalf("expected the node without a load got %s", node.Id)
+	}
+
+	if _, err := LoadStrategy(loads)(nil)(); err != selector.ErrNoneAvailable {
+		t.Fatalf("expected %v got %v", selector.ErrNoneAvailable, err)
+	}
+}
@@ -0,0 +1,197 @@
+package grpc
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/micro/go-micro/v2/client/selector"
+	"github.com/micro/go-micro/v2/registry"
+	"github.com/micro/go-micro/v2/transport"
+)
+
+const (
+	// UtilizationHeader is the utilization of the server reported in responses
+	UtilizationHeader = "Micro-Load-Utilization"
+	// InFlightHeader is the requests in flight of the server reported in responses
+	InFlightHeader = "Micro-Load-Inflight"
+)
+
+var (
+	// DefaultLoadTTL is how long a reported load is used for, nodes which haven't
+	// reported one since are selected as if they're idle
+	DefaultLoadTTL = 10 * time.Second
+)
+
+// Load is the load reported by a server
+type Load struct {
+	// Utilization of the server e.g its CPU from 0 to 1
+	Utilization float64
+	// InFlight is the requests the server was handling
+	InFlight int64
+	// Time the load was reported at
+	Time time.Time
+}
+
+// Loads are the loads last reported by the servers, by address
+type Loads struct {
+	sync.RWMutex
+	loads map[string]Load
+}
+
+// NewLoads returns the loads to record with WithLoads
+func NewLoads() *Loads {
+	return &Loads{loads: make(map[string]Load)}
+}
+
+// Get returns the load last reported by the server at the address, if it's recent
+func (l *Loads) Get(addr string) (Load, bool) {
+	l.RLock()
+	defer l.RUnlock()
+
+	load, ok := l.loads[addr]
+	if !ok || time.Since(load.Time) > DefaultLoadTTL {
+		return Load{}, false
+	}
+	return load, true
+}
+
+// record records the load reported in the header of a response from the address
+func (l *Loads) record(addr string, header map[string]string) {
+	v, ok := header[InFlightHeader]
+	if !ok {
+		return
+	}
+	inflight, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return
+	}
+	utilization, _ := strconv.ParseFloat(header[UtilizationHeader], 64)
+
+	l.Lock()
+	l.loads[addr] = Load{Utilization: utilization, InFlight: inflight, Time: time.Now()}
+	l.Unlock()
+}
+
+// less returns true if the load a is lower than b
+func less(a, b Load) bool {
+	if a.InFlight != b.InFlight {
+		return a.InFlight < b.InFlight
+	}
+	return a.Utilization < b.Utilization
+}
+
+// LoadStrategy selects the node which reported the fewest requests in flight,
+// ties are broken by the lowest utilization then at random
+func LoadStrategy(l *Loads) selector.Strategy {
+	return func(services []*registry.Service) selector.Next {
+		var nodes []*registry.Node
+		for _, service := range services {
+			nodes = append(nodes, service.Nodes...)
+		}
+
+		return func() (*registry.Node, error) {
+			if len(nodes) == 0 {
+				return nil, selector.ErrNoneAvailable
+			}
+
+			var least []*registry.Node
+			var min Load
+			for _, node := range nodes {
+				load, _ := l.Get(node.Address)
+				switch {
+				case len(least) == 0 || less(load, min):
+					least, min = []*registry.Node{node}, load
+				case !less(min, load):
+					least = append(least, node)
+				}
+			}
+
+			return least[rand.Intn(len(least))], nil
+		}
+	}
+}
+
+// loadReporter counts the requests in flight of the server sockets
+type loadReporter struct {
+	utilization func() float64
+	inflight    int64
+}
+
+type loadListener struct {
+	transport.Listener
+	r *loadReporter
+}
+
+// loadSocket reports the load in the responses to the requests it receives
+type loadSocket struct {
+	transport.Socket
+	r *loadReporter
+
+	mtx     sync.Mutex
+	pending int
+}
+
+func (l *loadListener) Accept(fn func(transport.Socket)) error {
+	return l.Listener.Accept(func(sock transport.Socket) {
+		fn(&loadSocket{Socket: sock, r: l.r})
+	})
+}
+
+func (s *loadSocket) Recv(m *transport.Message) error {
+	if err := s.Socket.Recv(m); err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	s.pending++
+	s.mtx.Unlock()
+	atomic.AddInt64(&s.r.inflight, 1)
+
+	return nil
+}
+
+func (s *loadSocket) Send(m *transport.Message) error {
+	// a response completes a request in flight, streams send several
+	s.mtx.Lock()
+	if s.pending > 0 {
+		s.pending--
+		atomic.AddInt64(&s.r.inflight, -1)
+	}
+	s.mtx.Unlock()
+
+	header := make(map[string]string, len(m.Header)+2)
+	for k, v := range m.Header {
+		header[k] = v
+	}
+	header[InFlightHeader] = strconv.FormatInt(atomic.LoadInt64(&s.r.inflight), 10)
+	header[UtilizationHeader] = strconv.FormatFloat(s.r.utilization(), 'f', -1, 64)
+
+	return s.Socket.Send(&transport.Message{Header: header, Body: m.Body})
+}
+
+func (s *loadSocket) Close() error {
+	// requests which weren't responded to aren't in flight anymore
+	s.mtx.Lock()
+	atomic.AddInt64(&s.r.inflight, -int64(s.pending))
+	s.pending = 0
+	s.mtx.Unlock()
+
+	return s.Socket.Close()
+}
+
+// loadClient records the load reported in the responses it receives
+type loadClient struct {
+	transport.Client
+	loads *Loads
+}
+
+func (c *loadClient) Recv(m *transport.Message) error {
+	if err := c.Client.Recv(m); err != nil {
+		return err
+	}
+	c.loads.record(c.Remote(), m.Header)
+	return nil
+}
@@ -0,0 +1,36 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/transport"
+)
+
+type reportLoadKey struct{}
+type loadsKey struct{}
+
+// ReportLoad reports the load of the server in the headers of its responses. The
+// utilization func returns the utilization of the server e.g its CPU from 0 to 1,
+// it may be nil to only report the requests in flight.
+func ReportLoad(utilization func() float64) transport.Option {
+	return func(o *transport.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		if utilization == nil {
+			utilization = func() float64 { return 0 }
+		}
+		o.Context = context.WithValue(o.Context, reportLoadKey{}, utilization)
+	}
+}
+
+// WithLoads records the load reported in the responses of servers in the loads,
+// pass them to LoadStrategy to select the least loaded nodes
+func WithLoads(l *Loads) transport.Option {
+	return func(o *transport.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, loadsKey{}, l)
+	}
+}
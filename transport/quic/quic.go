@@ -7,10 +7,47 @@ import (
 	"github.com/micro/go-micro/v2/transport/quic"
 )
 
+type quicTransport struct {
+	transport.Transport
+	// buckets shared by the connections
+	global buckets
+}
+
 func init() {
 	cmd.DefaultTransports["quic"] = NewTransport
 }
 
+func (t *quicTransport) Dial(addr string, opts ...transport.DialOption) (transport.Client, error) {
+	c, err := t.Transport.Dial(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if s := t.throttle(c); s != nil {
+		return &throttledClient{Client: c, s: s}, nil
+	}
+	return c, nil
+}
+
+func (t *quicTransport) Listen(addr string, opts ...transport.ListenOption) (transport.Listener, error) {
+	l, err := t.Transport.Listen(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &throttledListener{Listener: l, t: t}, nil
+}
+
+func (t *quicTransport) Init(opts ...transport.Option) error {
+	if err := t.Transport.Init(opts...); err != nil {
+		return err
+	}
+	t.global = newBuckets(t.Options(), globalThrottleKey{})
+	return nil
+}
+
 func NewTransport(opts ...transport.Option) transport.Transport {
-	return quic.NewTransport(opts...)
+	t := quic.NewTransport(opts...)
+	return &quicTransport{
+		Transport: t,
+		global:    newBuckets(t.Options(), globalThrottleKey{}),
+	}
 }
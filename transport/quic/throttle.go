@@ -0,0 +1,116 @@
+package quic
+
+import (
+	"github.com/juju/ratelimit"
+	"github.com/micro/go-micro/v2/transport"
+)
+
+// buckets are the token buckets of the bytes sent and received, a nil bucket
+// doesn't limit the direction
+type buckets struct {
+	send *ratelimit.Bucket
+	recv *ratelimit.Bucket
+}
+
+// throttledSocket sends and receives the messages of the socket within the
+// bandwidth of its buckets
+type throttledSocket struct {
+	transport.Socket
+	send []*ratelimit.Bucket
+	recv []*ratelimit.Bucket
+}
+
+type throttledClient struct {
+	transport.Client
+	s *throttledSocket
+}
+
+type throttledListener struct {
+	transport.Listener
+	t *quicTransport
+}
+
+func newBucket(rate int64) *ratelimit.Bucket {
+	if rate <= 0 {
+		return nil
+	}
+	return ratelimit.NewBucketWithRate(float64(rate), rate)
+}
+
+// newBuckets returns the buckets of the throttle option in the options
+func newBuckets(opts transport.Options, key interface{}) buckets {
+	if opts.Context == nil {
+		return buckets{}
+	}
+	t, _ := opts.Context.Value(key).(throttle)
+	return buckets{send: newBucket(t.send), recv: newBucket(t.recv)}
+}
+
+// size is the number of bytes of the message
+func size(m *transport.Message) int64 {
+	n := len(m.Body)
+	for k, v := range m.Header {
+		n += len(k) + len(v)
+	}
+	return int64(n)
+}
+
+func wait(bs []*ratelimit.Bucket, m *transport.Message) {
+	if len(bs) == 0 {
+		return
+	}
+	n := size(m)
+	for _, b := range bs {
+		b.Wait(n)
+	}
+}
+
+func (s *throttledSocket) Send(m *transport.Message) error {
+	wait(s.send, m)
+	return s.Socket.Send(m)
+}
+
+func (s *throttledSocket) Recv(m *transport.Message) error {
+	if err := s.Socket.Recv(m); err != nil {
+		return err
+	}
+	wait(s.recv, m)
+	return nil
+}
+
+func (c *throttledClient) Send(m *transport.Message) error {
+	return c.s.Send(m)
+}
+
+func (c *throttledClient) Recv(m *transport.Message) error {
+	return c.s.Recv(m)
+}
+
+func (l *throttledListener) Accept(fn func(transport.Socket)) error {
+	return l.Listener.Accept(func(sock transport.Socket) {
+		if s := l.t.throttle(sock); s != nil {
+			sock = s
+		}
+		fn(sock)
+	})
+}
+
+// throttle returns the socket throttled by buckets of its own and the global
+// buckets of the transport, or nil if it isn't throttled
+func (t *quicTransport) throttle(sock transport.Socket) *throttledSocket {
+	s := &throttledSocket{Socket: sock}
+
+	for _, b := range []buckets{newBuckets(t.Options(), throttleKey{}), t.global} {
+		if b.send != nil {
+			s.send = append(s.send, b.send)
+		}
+		if b.recv != nil {
+			s.recv = append(s.recv, b.recv)
+		}
+	}
+
+	if len(s.send) == 0 && len(s.recv) == 0 {
+		return nil
+	}
+	return s
+}
@@ -2,4 +2,7 @@ module github.com/micro/go-plugins/transport/tcp/v2
 
 go 1.13
 
-require github.com/micro/go-micro/v2 v2.9.1
+require (
+	github.com/juju/ratelimit v1.0.2-0.20191002062651-f60b32039441
+	github.com/micro/go-micro/v2 v2.9.1
+)
@@ -0,0 +1,42 @@
+package tcp
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/transport"
+)
+
+type throttleKey struct{}
+
+type globalThrottleKey struct{}
+
+// throttle is the bandwidth of each direction in bytes per second
+type throttle struct {
+	send int64
+	recv int64
+}
+
+// Throttle limits the bandwidth of each connection of the transport to send and
+// recv bytes per second, a rate of 0 doesn't limit the direction. Connections
+// can burst up to a second of their rate.
+func Throttle(send, recv int64) transport.Option {
+	return func(o *transport.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, throttleKey{}, throttle{send: send, recv: recv})
+	}
+}
+
+// GlobalThrottle limits the bandwidth of all the connections of the transport
+// together to send and recv bytes per second, on top of the limit of each
+// connection set by Throttle. A transport throttled for background traffic
+// leaves the bandwidth to the transports of interactive calls.
+func GlobalThrottle(send, recv int64) transport.Option {
+	return func(o *transport.Options) {
+		if o.Context == nil {
+			o.Context = context.Background()
+		}
+		o.Context = context.WithValue(o.Context, globalThrottleKey{}, throttle{send: send, recv: recv})
+	}
+}
@@ -19,6 +19,8 @@ import (
 
 type tcpTransport struct {
 	opts transport.Options
+	// buckets shared by the connections
+	global buckets
 }
 
 type tcpTransportClient struct {
@@ -41,6 +43,7 @@ type tcpTransportSocket struct {
 type tcpTransportListener struct {
 	listener net.Listener
 	timeout  time.Duration
+	throttle func(net.Conn) net.Conn
 }
 
 func init() {
@@ -143,6 +146,7 @@ func (t *tcpTransportListener) Accept(fn func(transport.Socket)) error {
 			}
 			return err
 		}
+		c = t.throttle(c)
 
 		encBuf := bufio.NewWriter(c)
 		sock := &tcpTransportSocket{
@@ -194,6 +198,7 @@ func (t *tcpTransport) Dial(addr string, opts ...transport.DialOption) (transpor
 	if err != nil {
 		return nil, err
 	}
+	conn = t.throttle(conn)
 
 	encBuf := bufio.NewWriter(conn)
 
@@ -259,6 +264,7 @@ func (t *tcpTransport) Listen(addr string, opts ...transport.ListenOption) (tran
 	return &tcpTransportListener{
 		timeout:  t.opts.Timeout,
 		listener: l,
+		throttle: t.throttle,
 	}, nil
 }
 
@@ -266,6 +272,7 @@ func (t *tcpTransport) Init(opts ...transport.Option) error {
 	for _, o := range opts {
 		o(&t.opts)
 	}
+	t.global = newBuckets(t.opts, globalThrottleKey{})
 	return nil
 }
 
@@ -282,5 +289,8 @@ func NewTransport(opts ...transport.Option) transport.Transport {
 	for _, o := range opts {
 		o(&options)
 	}
-	return &tcpTransport{opts: options}
+	return &tcpTransport{
+		opts:   options,
+		global: newBuckets(options, globalThrottleKey{}),
+	}
 }
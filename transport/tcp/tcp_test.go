@@ -229,3 +229,82 @@ func TestTCPTransportTimeout(t *testing.T) {
 
 	<-done
 }
+
+func TestTCPTransportThrottle(t *testing.T) {
+	const rate = 1 << 20
+
+	testData := []struct {
+		name    string
+		opt     transport.Option
+		clients int
+		size    int
+	}{
+		// the second after the burst of a connection
+		{"send", Throttle(rate, 0), 1, rate * 3 / 2},
+		{"recv", Throttle(0, rate), 1, rate * 3 / 2},
+		// the connections take turns with the global burst
+		{"global", GlobalThrottle(rate, 0), 2, rate * 3 / 4},
+	}
+
+	l, err := NewTransport().Listen(":0")
+	if err != nil {
+		t.Fatalf("Unexpected listen err: %v", err)
+	}
+	defer l.Close()
+
+	go l.Accept(func(sock transport.Socket) {
+		defer sock.Close()
+
+		for {
+			var m transport.Message
+			if err := sock.Recv(&m); err != nil {
+				return
+			}
+			if err := sock.Send(&m); err != nil {
+				return
+			}
+		}
+	})
+
+	for _, test := range testData {
+		tr := NewTransport(test.opt)
+		m := transport.Message{Body: make([]byte, test.size)}
+
+		start := time.Now()
+		errs := make(chan error, test.clients)
+		for i := 0; i < test.clients; i++ {
+			go func() {
+				c, err := tr.Dial(l.Addr())
+				if err != nil {
+					errs <- err
+					return
+				}
+				defer c.Close()
+
+				if err := c.Send(&m); err != nil {
+					errs <- err
+					return
+				}
+				var rm transport.Message
+				if err := c.Recv(&rm); err != nil {
+					errs <- err
+					return
+				}
+				if len(rm.Body) != test.size {
+					errs <- io.ErrShortBuffer
+					return
+				}
+				errs <- nil
+			}()
+		}
+		for i := 0; i < test.clients; i++ {
+			if err := <-errs; err != nil {
+				t.Fatalf("Unexpected %s err: %v", test.name, err)
+			}
+		}
+
+		if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+			t.Errorf("Expected %s to be throttled for 500ms, took %v", test.name, elapsed)
+		}
+	}
+}
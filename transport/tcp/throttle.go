@@ -0,0 +1,71 @@
+package tcp
+
+import (
+	"io"
+	"net"
+
+	"github.com/juju/ratelimit"
+	"github.com/micro/go-micro/v2/transport"
+)
+
+// buckets are the token buckets of the bytes sent and received, a nil bucket
+// doesn't limit the direction
+type buckets struct {
+	send *ratelimit.Bucket
+	recv *ratelimit.Bucket
+}
+
+// throttledConn reads and writes the conn within the bandwidth of its buckets
+type throttledConn struct {
+	net.Conn
+	r io.Reader
+	w io.Writer
+}
+
+func newBucket(rate int64) *ratelimit.Bucket {
+	if rate <= 0 {
+		return nil
+	}
+	return ratelimit.NewBucketWithRate(float64(rate), rate)
+}
+
+// newBuckets returns the buckets of the throttle option in the options
+func newBuckets(opts transport.Options, key interface{}) buckets {
+	if opts.Context == nil {
+		return buckets{}
+	}
+	t, _ := opts.Context.Value(key).(throttle)
+	return buckets{send: newBucket(t.send), recv: newBucket(t.recv)}
+}
+
+func (c *throttledConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func (c *throttledConn) Write(b []byte) (int, error) {
+	return c.w.Write(b)
+}
+
+// throttle returns the conn throttled by buckets of its own and the global
+// buckets of the transport
+func (t *tcpTransport) throttle(c net.Conn) net.Conn {
+	var r io.Reader = c
+	var w io.Writer = c
+	var throttled bool
+
+	for _, b := range []buckets{newBuckets(t.opts, throttleKey{}), t.global} {
+		if b.recv != nil {
+			r = ratelimit.Reader(r, b.recv)
+			throttled = true
+		}
+		if b.send != nil {
+			w = ratelimit.Writer(w, b.send)
+			throttled = true
+		}
+	}
+
+	if !throttled {
+		return c
+	}
+	return &throttledConn{Conn: c, r: r, w: w}
+}
@@ -0,0 +1,185 @@
+// Package budget provides a client wrapper which limits retries to a budget
+// shared by every service and endpoint called.
+//
+// Per call retry options multiply the load on services which are already
+// failing, so an incident can turn into a retry storm. The budget only allows
+// retries up to a ratio of the requests made in a sliding window, so retries
+// stop once most requests fail instead of adding to the load.
+package budget
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/client"
+	log "github.com/micro/go-micro/v2/logger"
+)
+
+var (
+	// DefaultRatio of retries to requests
+	DefaultRatio = 0.2
+	// DefaultMinRetries allowed in the window
+	DefaultMinRetries = 10
+	// DefaultWindow requests and retries are counted over
+	DefaultWindow = 10 * time.Second
+
+	// buckets the window is split into
+	buckets = 10
+)
+
+type bucket struct {
+	// index of the interval counted
+	index    int64
+	requests int
+	retries  int
+}
+
+// Budget counts requests and retries in a sliding window. It's safe for
+// concurrent use so one budget can be shared by several clients.
+type Budget struct {
+	opts Options
+
+	sync.Mutex
+	buckets []bucket
+}
+
+type budgetWrapper struct {
+	client.Client
+	budget *Budget
+}
+
+// current returns the bucket of the current interval, it must be called with the lock held
+func (b *Budget) current() *bucket {
+	width := int64(b.opts.Window) / int64(len(b.buckets))
+	if width <= 0 {
+		width = 1
+	}
+	index := time.Now().UnixNano() / width
+
+	bk := &b.buckets[index%int64(len(b.buckets))]
+	if bk.index != index {
+		*bk = bucket{index: index}
+	}
+	return bk
+}
+
+// counts returns the requests and retries in the window, it must be called with the lock held
+func (b *Budget) counts() (int, int) {
+	cur := b.current()
+
+	var requests, retries int
+	for _, bk := range b.buckets {
+		if cur.index-bk.index >= int64(len(b.buckets)) {
+			continue
+		}
+		requests += bk.requests
+		retries += bk.retries
+	}
+	return requests, retries
+}
+
+// Request records a request
+func (b *Budget) Request() {
+	b.Lock()
+	b.current().requests++
+	b.Unlock()
+}
+
+// Retry records a retry if the budget allows it and returns whether it does
+func (b *Budget) Retry() bool {
+	b.Lock()
+	defer b.Unlock()
+
+	requests, retries := b.counts()
+
+	limit := int(b.opts.Ratio * float64(requests))
+	if limit < b.opts.MinRetries {
+		limit = b.opts.MinRetries
+	}
+	if retries >= limit {
+		return false
+	}
+
+	b.current().retries++
+	return true
+}
+
+// Counts returns the requests and retries in the window
+func (b *Budget) Counts() (requests int, retries int) {
+	b.Lock()
+	defer b.Unlock()
+	return b.counts()
+}
+
+// retry returns the retry func of the call options which only
+// retries if the budget allows it
+func (w *budgetWrapper) retry(opts []client.CallOption) client.RetryFunc {
+	options := w.Client.Options().CallOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	fn := options.Retry
+
+	return func(ctx context.Context, req client.Request, retryCount int, err error) (bool, error) {
+		if fn == nil {
+			return false, nil
+		}
+
+		retry, rerr := fn(ctx, req, retryCount, err)
+		if rerr != nil || !retry {
+			return retry, rerr
+		}
+
+		if !w.budget.Retry() {
+			if log.V(log.DebugLevel, log.DefaultLogger) {
+				log.Debugf("Retry budget exhausted, not retrying %s.%s", req.Service(), req.Endpoint())
+			}
+			return false, nil
+		}
+
+		return true, nil
+	}
+}
+
+func (w *budgetWrapper) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	w.budget.Request()
+	opts = append(opts, client.WithRetry(w.retry(opts)))
+	return w.Client.Call(ctx, req, rsp, opts...)
+}
+
+func (w *budgetWrapper) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
+	w.budget.Request()
+	opts = append(opts, client.WithRetry(w.retry(opts)))
+	return w.Client.Stream(ctx, req, opts...)
+}
+
+// NewBudget returns a retry budget
+func NewBudget(opts ...Option) *Budget {
+	options := Options{
+		Ratio:      DefaultRatio,
+		MinRetries: DefaultMinRetries,
+		Window:     DefaultWindow,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &Budget{
+		opts:    options,
+		buckets: make([]bucket, buckets),
+	}
+}
+
+// NewClientWrapper returns a client wrapper which only retries calls if the budget
+// allows it. Share the budget between clients to limit retries across all of them.
+// Calls which aren't retried return their error, so a breaker wrapping the client
+// still sees the failures.
+func NewClientWrapper(b *Budget) client.Wrapper {
+	return func(c client.Client) client.Client {
+		return &budgetWrapper{
+			Client: c,
+			budget: b,
+		}
+	}
+}
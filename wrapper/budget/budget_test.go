@@ -0,0 +1,142 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/client"
+)
+
+// testClient fails every call and retries like the rpc client
+type testClient struct {
+	client.Client
+	calls int
+}
+
+func (t *testClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	options := t.Options().CallOptions
+	for _, o := range opts {
+		o(&options)
+	}
+
+	var err error
+	for i := 0; i <= options.Retries; i++ {
+		t.calls++
+		err = errors.New("failed")
+
+		retry, rerr := options.Retry(ctx, req, i, err)
+		if rerr != nil {
+			return rerr
+		}
+		if !retry {
+			return err
+		}
+	}
+	return err
+}
+
+func newClient(b *Budget) (*testClient, client.Client) {
+	tc := &testClient{Client: client.NewClient(client.Retries(3), client.Retry(client.RetryAlways))}
+	return tc, NewClientWrapper(b)(tc)
+}
+
+func TestBudget(t *testing.T) {
+	b := NewBudget(WithRatio(0.5), WithMinRetries(0))
+
+	for i := 0; i < 10; i++ {
+		b.Request()
+	}
+	for i := 0; i < 5; i++ {
+		if !b.Retry() {
+			t.Fatalf("expected retry %d to be allowed", i)
+		}
+	}
+	if b.Retry() {
+		t.Fatal("expected retry to exceed the budget")
+	}
+
+	if requests, retries := b.Counts(); requests != 10 || retries != 5 {
+		t.Fatalf("expected 10 requests and 5 retries got %d %d", requests, retries)
+	}
+}
+
+func TestMinRetries(t *testing.T) {
+	b := NewBudget(WithRatio(0.1), WithMinRetries(2))
+
+	b.Request()
+	if !b.Retry() || !b.Retry() {
+		t.Fatal("expected the min retries to be allowed")
+	}
+	if b.Retry() {
+		t.Fatal("expected retry to exceed the budget")
+	}
+}
+
+func TestWindow(t *testing.T) {
+	b := NewBudget(WithRatio(1), WithMinRetries(0), WithWindow(100*time.Millisecond))
+
+	b.Request()
+	if !b.Retry() {
+		t.Fatal("expected retry to be allowed")
+	}
+	if b.Retry() {
+		t.Fatal("expected retry to exceed the budget")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if requests, retries := b.Counts(); requests != 0 || retries != 0 {
+		t.Fatalf("expected the window to be empty got %d %d", requests, retries)
+	}
+
+	b.Request()
+	if !b.Retry() {
+		t.Fatal("expected retry to be allowed in the new window")
+	}
+}
+
+func TestClientWrapper(t *testing.T) {
+	b := NewBudget(WithRatio(0.2), WithMinRetries(0))
+
+	// two clients share the budget
+	tc1, c1 := newClient(b)
+	tc2, c2 := newClient(b)
+
+	req := c1.NewRequest("foo", "Foo.Bar", nil)
+
+	for i := 0; i < 10; i++ {
+		c1.Call(context.TODO(), req, nil)
+		c2.Call(context.TODO(), req, nil)
+	}
+
+	// 20 requests allow 4 retries across both clients
+	if calls := tc1.calls + tc2.calls; calls != 24 {
+		t.Fatalf("expected 24 calls got %d", calls)
+	}
+	if _, retries := b.Counts(); retries != 4 {
+		t.Fatalf("expected 4 retries got %d", retries)
+	}
+}
+
+func TestRetryFunc(t *testing.T) {
+	b := NewBudget()
+	tc, c := newClient(b)
+
+	req := c.NewRequest("foo", "Foo.Bar", nil)
+
+	// the call retry func still decides whether to retry
+	never := func(context.Context, client.Request, int, error) (bool, error) {
+		return false, nil
+	}
+	if err := c.Call(context.TODO(), req, nil, client.WithRetry(never)); err == nil {
+		t.Fatal("expected call to fail")
+	}
+	if tc.calls != 1 {
+		t.Fatalf("expected 1 call got %d", tc.calls)
+	}
+	if _, retries := b.Counts(); retries != 0 {
+		t.Fatalf("expected no retries got %d", retries)
+	}
+}
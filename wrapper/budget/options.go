@@ -0,0 +1,40 @@
+package budget
+
+import (
+	"time"
+)
+
+// Options of a retry budget
+type Options struct {
+	// Ratio is the maximum retries as a fraction of requests
+	Ratio float64
+	// MinRetries are always allowed in the window, so services
+	// with little traffic can still retry
+	MinRetries int
+	// Window requests and retries are counted over
+	Window time.Duration
+}
+
+// Option sets an option of a retry budget
+type Option func(o *Options)
+
+// WithRatio sets the maximum retries as a fraction of requests e.g 0.2 for 20%
+func WithRatio(r float64) Option {
+	return func(o *Options) {
+		o.Ratio = r
+	}
+}
+
+// WithMinRetries sets the retries always allowed in the window
+func WithMinRetries(n int) Option {
+	return func(o *Options) {
+		o.MinRetries = n
+	}
+}
+
+// WithWindow sets the window requests and retries are counted over
+func WithWindow(d time.Duration) Option {
+	return func(o *Options) {
+		o.Window = d
+	}
+}
@@ -0,0 +1,100 @@
+// Package schema provides a broker wrapper which validates message bodies
+// against a JSON schema or protobuf message of their topic. Invalid messages
+// are rejected on publish, and returned as an error or dead-lettered on consume,
+// to stop schema drift between teams sharing topics.
+package schema
+
+import (
+	"github.com/micro/go-micro/v2/broker"
+	log "github.com/micro/go-micro/v2/logger"
+)
+
+var (
+	// ErrorHeader is the dead-lettered message header set to the validation error
+	ErrorHeader = "Micro-Schema-Error"
+	// TopicHeader is the dead-lettered message header set to the topic it was consumed from
+	TopicHeader = "Micro-Schema-Topic"
+)
+
+type schemaBroker struct {
+	broker.Broker
+	opts Options
+}
+
+func (s *schemaBroker) validate(topic string, msg *broker.Message) error {
+	sc, ok := s.opts.Schemas[topic]
+	if !ok || msg == nil {
+		return nil
+	}
+	if err := sc.Validate(msg.Body); err != nil {
+		return &Error{Topic: topic, Err: err}
+	}
+	return nil
+}
+
+func (s *schemaBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	if err := s.validate(topic, msg); err != nil {
+		return err
+	}
+	return s.Broker.Publish(topic, msg, opts...)
+}
+
+// deadLetter publishes the invalid message to the dead letter topic
+func (s *schemaBroker) deadLetter(topic string, msg *broker.Message, verr error) error {
+	header := make(map[string]string, len(msg.Header)+2)
+	for k, v := range msg.Header {
+		header[k] = v
+	}
+	header[ErrorHeader] = verr.Error()
+	header[TopicHeader] = topic
+
+	return s.Broker.Publish(s.opts.DeadLetter, &broker.Message{
+		Header: header,
+		Body:   msg.Body,
+	})
+}
+
+func (s *schemaBroker) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	if _, ok := s.opts.Schemas[topic]; !ok {
+		return s.Broker.Subscribe(topic, h, opts...)
+	}
+
+	return s.Broker.Subscribe(topic, func(e broker.Event) error {
+		err := s.validate(topic, e.Message())
+		if err == nil {
+			return h(e)
+		}
+
+		if len(s.opts.DeadLetter) == 0 {
+			return err
+		}
+
+		if derr := s.deadLetter(topic, e.Message(), err); derr != nil {
+			if log.V(log.ErrorLevel, log.DefaultLogger) {
+				log.Errorf("Error dead-lettering message from %s: %v", topic, derr)
+			}
+			return err
+		}
+
+		if log.V(log.WarnLevel, log.DefaultLogger) {
+			log.Warnf("Dead-lettered %v", err)
+		}
+
+		return nil
+	}, opts...)
+}
+
+// NewBroker returns a broker which validates messages with the schemas of their topics
+func NewBroker(b broker.Broker, opts ...Option) broker.Broker {
+	options := Options{
+		Schemas: make(map[string]Schema),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &schemaBroker{
+		Broker: b,
+		opts:   options,
+	}
+}
@@ -0,0 +1,9 @@
+module github.com/micro/go-plugins/wrapper/schema/v2
+
+go 1.13
+
+require (
+	github.com/golang/protobuf v1.4.0
+	github.com/micro/go-micro/v2 v2.9.1
+	github.com/xeipuuv/gojsonschema v1.1.0
+)
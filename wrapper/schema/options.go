@@ -0,0 +1,27 @@
+package schema
+
+// Options of the schema broker
+type Options struct {
+	// Schemas of each topic, topics without a schema aren't validated
+	Schemas map[string]Schema
+	// DeadLetter is the topic invalid consumed messages are published to
+	DeadLetter string
+}
+
+// Option sets an option of the schema broker
+type Option func(o *Options)
+
+// WithSchema validates messages published and consumed on the topic with the schema
+func WithSchema(topic string, s Schema) Option {
+	return func(o *Options) {
+		o.Schemas[topic] = s
+	}
+}
+
+// WithDeadLetter publishes invalid consumed messages to the topic and acknowledges
+// them instead of returning an error from the handler
+func WithDeadLetter(topic string) Option {
+	return func(o *Options) {
+		o.DeadLetter = topic
+	}
+}
@@ -0,0 +1,94 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Schema validates message bodies
+type Schema interface {
+	Validate(body []byte) error
+}
+
+// Validator is implemented by generated messages with validation rules
+type Validator interface {
+	Validate() error
+}
+
+type jsonSchema struct {
+	schema *gojsonschema.Schema
+}
+
+func (j *jsonSchema) Validate(body []byte) error {
+	res, err := j.schema.Validate(gojsonschema.NewBytesLoader(body))
+	if err != nil {
+		return err
+	}
+	if res.Valid() {
+		return nil
+	}
+
+	var errs []string
+	for _, e := range res.Errors() {
+		errs = append(errs, e.String())
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
+
+// NewJSONSchema returns a schema validating json bodies against the json schema document
+func NewJSONSchema(schema string) (Schema, error) {
+	return loadJSONSchema(gojsonschema.NewStringLoader(schema))
+}
+
+// NewJSONSchemaFile returns a schema validating json bodies against the json schema file
+func NewJSONSchemaFile(path string) (Schema, error) {
+	return loadJSONSchema(gojsonschema.NewReferenceLoader("file://" + path))
+}
+
+func loadJSONSchema(l gojsonschema.JSONLoader) (Schema, error) {
+	s, err := gojsonschema.NewSchema(l)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonSchema{schema: s}, nil
+}
+
+type protoSchema struct {
+	msg proto.Message
+}
+
+func (p *protoSchema) Validate(body []byte) error {
+	m := proto.Clone(p.msg)
+	m.Reset()
+
+	if err := proto.Unmarshal(body, m); err != nil {
+		return err
+	}
+
+	if v, ok := m.(Validator); ok {
+		return v.Validate()
+	}
+
+	return nil
+}
+
+// NewProtoSchema returns a schema validating protobuf bodies decode as the message.
+// Messages implementing Validator, such as those generated by protoc-gen-validate,
+// are also validated with their rules.
+func NewProtoSchema(m proto.Message) Schema {
+	return &protoSchema{msg: m}
+}
+
+// Error is returned for messages which don't match the schema of their topic
+type Error struct {
+	Topic string
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid message for topic %s: %v", e.Topic, e.Err)
+}
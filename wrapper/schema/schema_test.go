@@ -0,0 +1,167 @@
+package schema
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/broker/memory"
+)
+
+var testSchema = `{
+	"type": "object",
+	"properties": {
+		"id": {"type": "string"},
+		"amount": {"type": "number", "minimum": 0}
+	},
+	"required": ["id", "amount"]
+}`
+
+func TestJSONSchema(t *testing.T) {
+	s, err := NewJSONSchema(testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		body  string
+		valid bool
+	}{
+		{`{"id": "1", "amount": 10}`, true},
+		{`{"id": "1"}`, false},
+		{`{"id": "1", "amount": -1}`, false},
+		{`not json`, false},
+	}
+
+	for _, tc := range testCases {
+		if err := s.Validate([]byte(tc.body)); (err == nil) != tc.valid {
+			t.Fatalf("%s: expected valid %v got %v", tc.body, tc.valid, err)
+		}
+	}
+
+	if _, err := NewJSONSchema(`{"type": 1}`); err == nil {
+		t.Fatal("expected an error for an invalid schema")
+	}
+}
+
+func TestProtoSchema(t *testing.T) {
+	s := NewProtoSchema(&wrappers.StringValue{})
+
+	b, err := proto.Marshal(&wrappers.StringValue{Value: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Validate(b); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Validate([]byte{0xff, 0xff}); err == nil {
+		t.Fatal("expected an error for an invalid message")
+	}
+}
+
+func TestBroker(t *testing.T) {
+	s, err := NewJSONSchema(testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mb := memory.NewBroker()
+	b := NewBroker(mb, WithSchema("payments", s), WithDeadLetter("payments.dead"))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	received := make(chan *broker.Message, 10)
+	dead := make(chan *broker.Message, 10)
+
+	if _, err := b.Subscribe("payments", func(e broker.Event) error {
+		received <- e.Message()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Subscribe("payments.dead", func(e broker.Event) error {
+		dead <- e.Message()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// invalid messages are rejected on publish
+	err = b.Publish("payments", &broker.Message{Body: []byte(`{"id": "1"}`)})
+	if _, ok := err.(*Error); !ok {
+		t.Fatalf("expected a schema error got %v", err)
+	}
+
+	// topics without a schema aren't validated
+	if err := b.Publish("other", &broker.Message{Body: []byte(`not json`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Publish("payments", &broker.Message{Body: []byte(`{"id": "1", "amount": 10}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// messages published without the wrapper are validated on consume
+	if err := mb.Publish("payments", &broker.Message{
+		Header: map[string]string{"Id": "2"},
+		Body:   []byte(`{"id": "2", "amount": -1}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case m := <-received:
+		if string(m.Body) != `{"id": "1", "amount": 10}` {
+			t.Fatalf("unexpected message %s", m.Body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("valid message not received")
+	}
+
+	select {
+	case m := <-dead:
+		if m.Header["Id"] != "2" || m.Header[TopicHeader] != "payments" || len(m.Header[ErrorHeader]) == 0 {
+			t.Fatalf("unexpected dead letter headers %v", m.Header)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("invalid message not dead-lettered")
+	}
+
+	select {
+	case m := <-received:
+		t.Fatalf("invalid message received %s", m.Body)
+	default:
+	}
+}
+
+func TestReject(t *testing.T) {
+	s, err := NewJSONSchema(testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mb := memory.NewBroker()
+	b := NewBroker(mb, WithSchema("payments", s))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	if _, err := b.Subscribe("payments", func(e broker.Event) error {
+		t.Errorf("invalid message received %s", e.Message().Body)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// without a dead letter topic the handler error is returned to the broker
+	err = mb.Publish("payments", &broker.Message{Body: []byte(`{}`)})
+	if _, ok := err.(*Error); !ok {
+		t.Fatalf("expected a schema error got %v", err)
+	}
+}
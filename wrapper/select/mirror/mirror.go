@@ -0,0 +1,147 @@
+// Package mirror provides a client wrapper which mirrors a percentage of calls
+// to a shadow version of the services, to validate a release against production traffic.
+package mirror
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"time"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/client/selector"
+	log "github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/registry"
+)
+
+var (
+	// DefaultTimeout of mirrored calls
+	DefaultTimeout = 5 * time.Second
+	// DefaultMaxInFlight is the maximum mirrored calls in progress
+	DefaultMaxInFlight = 100
+)
+
+type mirrorWrapper struct {
+	client.Client
+	opts Options
+
+	services map[string]bool
+	// a slot is taken by each mirrored call in progress
+	inflight chan struct{}
+}
+
+// withoutVersion filters out the services of the version
+func withoutVersion(version string) selector.Filter {
+	return func(old []*registry.Service) []*registry.Service {
+		var services []*registry.Service
+		for _, service := range old {
+			if service.Version != version {
+				services = append(services, service)
+			}
+		}
+		return services
+	}
+}
+
+func (m *mirrorWrapper) sampled(req client.Request, opts []client.CallOption) bool {
+	if len(m.services) > 0 && !m.services[req.Service()] {
+		return false
+	}
+
+	// calls to an address skip selecting so they can't be mirrored to the version
+	var options client.CallOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	if len(options.Address) > 0 {
+		return false
+	}
+	return rand.Float64()*100 < m.opts.Percentage
+}
+
+// mirror calls the shadow version with a copy of the request, the response is discarded
+func (m *mirrorWrapper) mirror(ctx context.Context, req client.Request, rsp interface{}, opts []client.CallOption) {
+	select {
+	case m.inflight <- struct{}{}:
+	default:
+		if log.V(log.DebugLevel, log.DefaultLogger) {
+			log.Debugf("Not mirroring %s.%s: %d mirrored calls in progress", req.Service(), req.Endpoint(), m.opts.MaxInFlight)
+		}
+		return
+	}
+
+	// the copy keeps the metadata but isn't cancelled with the call
+	mctx := context.Background()
+	if md, ok := metadata.FromContext(ctx); ok {
+		mctx = metadata.NewContext(mctx, md)
+	}
+
+	mreq := m.Client.NewRequest(req.Service(), req.Endpoint(), req.Body(), client.WithContentType(req.ContentType()))
+
+	// a zero value of the response type is decoded into
+	var mrsp interface{}
+	if t := reflect.TypeOf(rsp); t != nil && t.Kind() == reflect.Ptr {
+		mrsp = reflect.New(t.Elem()).Interface()
+	}
+
+	mopts := append(append([]client.CallOption(nil), opts...),
+		client.WithSelectOption(selector.WithFilter(selector.FilterVersion(m.opts.Version))),
+		client.WithRequestTimeout(m.opts.Timeout),
+	)
+
+	go func() {
+		defer func() { <-m.inflight }()
+
+		mctx, cancel := context.WithTimeout(mctx, m.opts.Timeout)
+		defer cancel()
+
+		if err := m.Client.Call(mctx, mreq, mrsp, mopts...); err != nil {
+			m.opts.ErrorHandler(mreq, err)
+		}
+	}()
+}
+
+// Call calls the services other than the shadow version and mirrors the sampled calls to it
+func (m *mirrorWrapper) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	if m.sampled(req, opts) {
+		m.mirror(ctx, req, rsp, opts)
+	}
+
+	nOpts := append(opts, client.WithSelectOption(selector.WithFilter(withoutVersion(m.opts.Version))))
+	return m.Client.Call(ctx, req, rsp, nOpts...)
+}
+
+func logError(req client.Request, err error) {
+	if log.V(log.WarnLevel, log.DefaultLogger) {
+		log.Warnf("Error mirroring %s.%s: %v", req.Service(), req.Endpoint(), err)
+	}
+}
+
+// NewClientWrapper returns a wrapper which mirrors calls to the version of the services.
+// Calls aren't selected to the version, only the mirrored copies are.
+func NewClientWrapper(version string, opts ...Option) client.Wrapper {
+	options := Options{
+		Version:      version,
+		Timeout:      DefaultTimeout,
+		MaxInFlight:  DefaultMaxInFlight,
+		ErrorHandler: logError,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	services := make(map[string]bool, len(options.Services))
+	for _, s := range options.Services {
+		services[s] = true
+	}
+
+	return func(c client.Client) client.Client {
+		return &mirrorWrapper{
+			Client:   c,
+			opts:     options,
+			services: services,
+			inflight: make(chan struct{}, options.MaxInFlight),
+		}
+	}
+}
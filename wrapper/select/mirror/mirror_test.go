@@ -0,0 +1,162 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/client/selector"
+	"github.com/micro/go-micro/v2/registry"
+)
+
+// testClient records the versions each call is selected to
+type testClient struct {
+	client.Client
+
+	sync.Mutex
+	calls map[string]int
+	err   error
+	wait  chan bool
+}
+
+func (c *testClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	var options client.CallOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	var so selector.SelectOptions
+	for _, o := range options.SelectOptions {
+		o(&so)
+	}
+
+	services := []*registry.Service{{Name: req.Service(), Version: "v1"}, {Name: req.Service(), Version: "v2"}}
+	for _, f := range so.Filters {
+		services = f(services)
+	}
+	if len(services) != 1 {
+		return selector.ErrNoneAvailable
+	}
+	version := services[0].Version
+
+	if version == "v2" && c.wait != nil {
+		<-c.wait
+	}
+
+	c.Lock()
+	c.calls[version]++
+	c.Unlock()
+
+	if version == "v2" {
+		return c.err
+	}
+	return nil
+}
+
+func (c *testClient) count(version string) int {
+	c.Lock()
+	defer c.Unlock()
+	return c.calls[version]
+}
+
+func newTestClient() *testClient {
+	return &testClient{Client: client.NewClient(), calls: make(map[string]int)}
+}
+
+// eventually waits for the condition of the mirrored calls
+func eventually(t *testing.T, cond func() bool) {
+	for i := 0; !cond(); i++ {
+		if i == 100 {
+			t.Fatal("timed out waiting for the mirrored calls")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMirror(t *testing.T) {
+	tc := newTestClient()
+	tc.err = errors.New("shadow error")
+
+	var mtx sync.Mutex
+	var errs []error
+
+	c := NewClientWrapper("v2",
+		WithPercentage(100),
+		WithErrorHandler(func(req client.Request, err error) {
+			mtx.Lock()
+			errs = append(errs, err)
+			mtx.Unlock()
+		}),
+	)(tc)
+
+	req := c.NewRequest("foo", "Foo.Bar", map[string]string{"foo": "bar"})
+	for i := 0; i < 10; i++ {
+		rsp := make(map[string]string)
+		// the shadow error isn't returned to the caller
+		if err := c.Call(context.Background(), req, &rsp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// calls are only selected to the other versions
+	if n := tc.count("v1"); n != 10 {
+		t.Fatalf("expected 10 calls to v1 got %d", n)
+	}
+	eventually(t, func() bool { return tc.count("v2") == 10 })
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if len(errs) != 10 || errs[0] != tc.err {
+		t.Fatalf("expected the errors of the mirrored calls to be recorded got %v", errs)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	tc := newTestClient()
+	req := tc.NewRequest("foo", "Foo.Bar", nil)
+
+	// nothing is mirrored at zero percent, to other services or with an address
+	for _, c := range []client.Client{
+		NewClientWrapper("v2")(tc),
+		NewClientWrapper("v2", WithPercentage(100), WithServices("bar"))(tc),
+	} {
+		if err := c.Call(context.Background(), req, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	NewClientWrapper("v2", WithPercentage(100))(tc).Call(context.Background(), req, nil, client.WithAddress("10.0.0.1:8080"))
+
+	c := NewClientWrapper("v2", WithPercentage(50), WithMaxInFlight(1000))(tc)
+	for i := 0; i < 1000; i++ {
+		c.Call(context.Background(), req, nil)
+	}
+
+	eventually(t, func() bool { return tc.count("v2") > 0 })
+	time.Sleep(20 * time.Millisecond)
+	if n := tc.count("v2"); n < 400 || n > 600 {
+		t.Fatalf("expected about half the calls to be mirrored got %d", n)
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	tc := newTestClient()
+	tc.wait = make(chan bool)
+
+	c := NewClientWrapper("v2", WithPercentage(100), WithMaxInFlight(2))(tc)
+	req := c.NewRequest("foo", "Foo.Bar", nil)
+	for i := 0; i < 5; i++ {
+		if err := c.Call(context.Background(), req, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the calls beyond the limit aren't mirrored
+	close(tc.wait)
+	eventually(t, func() bool { return tc.count("v2") == 2 })
+	time.Sleep(20 * time.Millisecond)
+	if n := tc.count("v2"); n != 2 {
+		t.Fatalf("expected 2 mirrored calls got %d", n)
+	}
+}
@@ -0,0 +1,66 @@
+package mirror
+
+import (
+	"time"
+
+	"github.com/micro/go-micro/v2/client"
+)
+
+// ErrorHandler is called with the error of a mirrored call
+type ErrorHandler func(req client.Request, err error)
+
+// Options of the mirror wrapper
+type Options struct {
+	// Version of the shadow services calls are mirrored to
+	Version string
+	// Percentage of calls mirrored, from 0 to 100
+	Percentage float64
+	// Timeout of mirrored calls
+	Timeout time.Duration
+	// MaxInFlight is the maximum mirrored calls in progress, calls beyond it aren't mirrored
+	MaxInFlight int
+	// Services which are mirrored, all of them if empty
+	Services []string
+	// ErrorHandler is called with the errors of mirrored calls
+	ErrorHandler ErrorHandler
+}
+
+// Option sets an option of the mirror wrapper
+type Option func(o *Options)
+
+// WithPercentage sets the percentage of calls mirrored, from 0 to 100
+func WithPercentage(p float64) Option {
+	return func(o *Options) {
+		o.Percentage = p
+	}
+}
+
+// WithTimeout sets the timeout of mirrored calls, they aren't cancelled with the call
+func WithTimeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+// WithMaxInFlight limits the mirrored calls in progress, calls beyond it aren't
+// mirrored so a slow shadow doesn't pile up goroutines
+func WithMaxInFlight(n int) Option {
+	return func(o *Options) {
+		o.MaxInFlight = n
+	}
+}
+
+// WithServices only mirrors calls to the services
+func WithServices(services ...string) Option {
+	return func(o *Options) {
+		o.Services = services
+	}
+}
+
+// WithErrorHandler calls the handler with the errors of mirrored calls, they're
+// logged as warnings without it
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(o *Options) {
+		o.ErrorHandler = h
+	}
+}
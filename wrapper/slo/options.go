@@ -0,0 +1,84 @@
+package slo
+
+import (
+	"time"
+)
+
+// Objective of the calls to an endpoint
+type Objective struct {
+	// Percentile of calls which must meet the objective e.g 0.99
+	Percentile float64
+	// Latency of calls at the percentile, zero isn't checked
+	Latency time.Duration
+	// Size of responses in bytes at the percentile, zero isn't checked
+	Size int
+}
+
+// Options of the slo wrapper
+type Options struct {
+	// Objectives of each endpoint by service and endpoint, or by service for all its endpoints
+	Objectives map[string]Objective
+	// Default objective of endpoints without one, the zero value isn't checked
+	Default Objective
+	// Samples are the most recent calls of an endpoint the percentiles are calculated from
+	Samples int
+	// MinSamples needed before an endpoint is checked
+	MinSamples int
+	// Interval endpoints are checked at, violations are reported at most once an interval
+	Interval time.Duration
+	// Alert is called with each violation
+	Alert func(*Violation)
+}
+
+// Option sets an option of the slo wrapper
+type Option func(o *Options)
+
+// key of the objective of an endpoint, the endpoint is empty for all the endpoints of the service
+func key(service, endpoint string) string {
+	return service + "/" + endpoint
+}
+
+// WithObjective sets the objective of the endpoint of the service e.g
+// ("go.micro.srv.greeter", "Say.Hello"). An empty endpoint sets it for all
+// the endpoints of the service without their own.
+func WithObjective(service, endpoint string, obj Objective) Option {
+	return func(o *Options) {
+		o.Objectives[key(service, endpoint)] = obj
+	}
+}
+
+// WithDefaultObjective sets the objective of endpoints without one
+func WithDefaultObjective(obj Objective) Option {
+	return func(o *Options) {
+		o.Default = obj
+	}
+}
+
+// WithSamples sets the most recent calls of an endpoint the percentiles are calculated from
+func WithSamples(n int) Option {
+	return func(o *Options) {
+		o.Samples = n
+	}
+}
+
+// WithMinSamples sets the calls needed before an endpoint is checked
+func WithMinSamples(n int) Option {
+	return func(o *Options) {
+		o.MinSamples = n
+	}
+}
+
+// WithInterval sets the interval endpoints are checked at
+func WithInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.Interval = d
+	}
+}
+
+// WithAlert calls the func with each violation e.g to alert or increment a metric,
+// it's called from the call goroutine so it mustn't block. Violations are logged without it.
+func WithAlert(fn func(*Violation)) Option {
+	return func(o *Options) {
+		o.Alert = fn
+	}
+}
@@ -0,0 +1,230 @@
+// Package slo provides a client wrapper which checks the latency and response
+// size of the calls to each endpoint against service level objectives, and
+// calls an alert func when they're violated. It detects a service burning its
+// error budget in process, without waiting for a monitoring system to notice.
+package slo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/codec/bytes"
+	log "github.com/micro/go-micro/v2/logger"
+)
+
+var (
+	// DefaultPercentile of objectives without one
+	DefaultPercentile = 0.99
+	// DefaultSamples the percentiles are calculated from
+	DefaultSamples = 1000
+	// DefaultMinSamples needed before an endpoint is checked
+	DefaultMinSamples = 100
+	// DefaultInterval endpoints are checked at
+	DefaultInterval = 10 * time.Second
+)
+
+// Violation of the objective of an endpoint
+type Violation struct {
+	Service  string
+	Endpoint string
+	// Objective which was violated
+	Objective Objective
+	// Latency of calls at the percentile of the objective
+	Latency time.Duration
+	// Size of responses at the percentile of the objective, or -1 if
+	// the size of none of them is known
+	Size int
+	// Samples the percentiles were calculated from
+	Samples int
+}
+
+// tracker records the latency and response size of the recent calls to an endpoint
+type tracker struct {
+	obj Objective
+
+	sync.Mutex
+	latencies []time.Duration
+	// -1 for responses of unknown size
+	sizes []int
+	// next sample to overwrite once the samples are full
+	next    int
+	checked time.Time
+}
+
+type sloWrapper struct {
+	client.Client
+	opts Options
+
+	sync.Mutex
+	trackers map[string]*tracker
+}
+
+func (v *Violation) String() string {
+	return fmt.Sprintf("%s %s violated its objective: p%g latency %v size %d of %d calls",
+		v.Service, v.Endpoint, v.Objective.Percentile*100, v.Latency, v.Size, v.Samples)
+}
+
+// percentile returns the index of the percentile of n sorted values
+func percentile(n int, p float64) int {
+	i := int(math.Ceil(p*float64(n))) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= n {
+		i = n - 1
+	}
+	return i
+}
+
+// record records a call and returns a violation if the endpoint is due
+// to be checked and violates its objective
+func (t *tracker) record(d time.Duration, size int, opts Options) *Violation {
+	t.Lock()
+	defer t.Unlock()
+
+	if len(t.latencies) < opts.Samples {
+		t.latencies = append(t.latencies, d)
+		t.sizes = append(t.sizes, size)
+	} else {
+		t.latencies[t.next] = d
+		t.sizes[t.next] = size
+		t.next = (t.next + 1) % len(t.latencies)
+	}
+
+	n := len(t.latencies)
+	if n < opts.MinSamples || time.Since(t.checked) < opts.Interval {
+		return nil
+	}
+	t.checked = time.Now()
+
+	latencies := make([]time.Duration, n)
+	copy(latencies, t.latencies)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var sizes []int
+	for _, s := range t.sizes {
+		if s >= 0 {
+			sizes = append(sizes, s)
+		}
+	}
+	sort.Ints(sizes)
+
+	v := &Violation{
+		Objective: t.obj,
+		Latency:   latencies[percentile(n, t.obj.Percentile)],
+		Size:      -1,
+		Samples:   n,
+	}
+	if len(sizes) > 0 {
+		v.Size = sizes[percentile(len(sizes), t.obj.Percentile)]
+	}
+
+	if (t.obj.Latency > 0 && v.Latency > t.obj.Latency) || (t.obj.Size > 0 && v.Size > t.obj.Size) {
+		return v
+	}
+	return nil
+}
+
+// tracker returns the tracker of the endpoint, or nil if it has no objective
+func (w *sloWrapper) tracker(service, endpoint string) *tracker {
+	k := key(service, endpoint)
+
+	w.Lock()
+	defer w.Unlock()
+
+	if t, ok := w.trackers[k]; ok {
+		return t
+	}
+
+	obj, ok := w.opts.Objectives[k]
+	if !ok {
+		obj, ok = w.opts.Objectives[key(service, "")]
+	}
+	if !ok {
+		obj = w.opts.Default
+	}
+	if obj.Percentile <= 0 || obj.Percentile > 1 {
+		obj.Percentile = DefaultPercentile
+	}
+
+	var t *tracker
+	if obj.Latency > 0 || obj.Size > 0 {
+		t = &tracker{obj: obj}
+	}
+
+	w.trackers[k] = t
+	return t
+}
+
+// size returns the size of the response in bytes, or -1 if it's unknown
+func size(rsp interface{}) int {
+	switch r := rsp.(type) {
+	case proto.Message:
+		return proto.Size(r)
+	case *bytes.Frame:
+		return len(r.Data)
+	default:
+		return -1
+	}
+}
+
+func (w *sloWrapper) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	t := w.tracker(req.Service(), req.Endpoint())
+	if t == nil {
+		return w.Client.Call(ctx, req, rsp, opts...)
+	}
+
+	start := time.Now()
+	err := w.Client.Call(ctx, req, rsp, opts...)
+	d := time.Since(start)
+
+	// failed calls have no response
+	s := -1
+	if err == nil {
+		s = size(rsp)
+	}
+
+	v := t.record(d, s, w.opts)
+	if v == nil {
+		return err
+	}
+
+	v.Service = req.Service()
+	v.Endpoint = req.Endpoint()
+
+	if w.opts.Alert != nil {
+		w.opts.Alert(v)
+	} else if log.V(log.WarnLevel, log.DefaultLogger) {
+		log.Warn(v.String())
+	}
+
+	return err
+}
+
+// NewClientWrapper returns a client wrapper which checks the calls to each endpoint
+// against its objective
+func NewClientWrapper(opts ...Option) client.Wrapper {
+	options := Options{
+		Objectives: make(map[string]Objective),
+		Samples:    DefaultSamples,
+		MinSamples: DefaultMinSamples,
+		Interval:   DefaultInterval,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return func(c client.Client) client.Client {
+		return &sloWrapper{
+			Client:   c,
+			opts:     options,
+			trackers: make(map[string]*tracker),
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package slo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/codec/bytes"
+)
+
+// testClient responds with a frame of the size after the delay
+type testClient struct {
+	client.Client
+	delay time.Duration
+	size  int
+}
+
+func (t *testClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	time.Sleep(t.delay)
+	if f, ok := rsp.(*bytes.Frame); ok {
+		f.Data = make([]byte, t.size)
+	}
+	return nil
+}
+
+func call(t *testing.T, c client.Client, endpoint string, n int) {
+	req := c.NewRequest("go.micro.srv.test", endpoint, &bytes.Frame{})
+	for i := 0; i < n; i++ {
+		if err := c.Call(context.TODO(), req, &bytes.Frame{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLatency(t *testing.T) {
+	var violations []*Violation
+
+	tc := &testClient{Client: client.NewClient()}
+	c := NewClientWrapper(
+		WithObjective("go.micro.srv.test", "Test.Slow", Objective{Percentile: 0.9, Latency: 5 * time.Millisecond}),
+		WithMinSamples(10),
+		WithInterval(0),
+		WithAlert(func(v *Violation) { violations = append(violations, v) }),
+	)(tc)
+
+	// within the objective
+	call(t, c, "Test.Slow", 10)
+	if len(violations) > 0 {
+		t.Fatalf("expected no violations got %v", violations[0])
+	}
+
+	// 2 of 12 calls are slow so the p90 is over the objective
+	tc.delay = 10 * time.Millisecond
+	call(t, c, "Test.Slow", 2)
+
+	if len(violations) != 1 {
+		t.Fatalf("expected 1 violation got %d", len(violations))
+	}
+	v := violations[0]
+	if v.Service != "go.micro.srv.test" || v.Endpoint != "Test.Slow" || v.Samples != 12 || v.Latency < 10*time.Millisecond {
+		t.Fatalf("unexpected violation %v", v)
+	}
+
+	// endpoints without an objective aren't tracked
+	call(t, c, "Test.Other", 20)
+	if len(violations) != 1 {
+		t.Fatalf("expected endpoints without an objective not to be checked got %d violations", len(violations))
+	}
+}
+
+func TestSize(t *testing.T) {
+	var violations []*Violation
+
+	tc := &testClient{Client: client.NewClient(), size: 1024}
+	c := NewClientWrapper(
+		WithObjective("go.micro.srv.test", "", Objective{Size: 512}),
+		WithSamples(10),
+		WithMinSamples(10),
+		WithInterval(time.Hour),
+		WithAlert(func(v *Violation) { violations = append(violations, v) }),
+	)(tc)
+
+	call(t, c, "Test.Big", 20)
+
+	// violations are reported at most once an interval
+	if len(violations) != 1 {
+		t.Fatalf("expected 1 violation got %d", len(violations))
+	}
+	if v := violations[0]; v.Size != 1024 || v.Samples != 10 || v.Objective.Percentile != DefaultPercentile {
+		t.Fatalf("unexpected violation %v", v)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	testData := []struct {
+		n        int
+		p        float64
+		expected int
+	}{
+		{100, 0.99, 98},
+		{100, 0.5, 49},
+		{10, 0.99, 9},
+		{1, 0.99, 0},
+	}
+
+	for _, d := range testData {
+		if i := percentile(d.n, d.p); i != d.expected {
+			t.Fatalf("expected p%g of %d at %d got %d", d.p*100, d.n, d.expected, i)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package spill
+
+import "time"
+
+// Options of the spill broker
+type Options struct {
+	// Path of the bolt database messages are queued in
+	Path string
+	// MaxSize is the maximum bytes of queued messages, zero is unlimited
+	MaxSize int64
+	// MaxAge of queued messages before they're dropped, zero keeps them until replayed
+	MaxAge time.Duration
+	// RetryInterval is how often replaying is retried while the broker is down
+	RetryInterval time.Duration
+}
+
+// Option sets an option of the spill broker
+type Option func(o *Options)
+
+// WithPath sets the bolt database file messages are queued in
+func WithPath(p string) Option {
+	return func(o *Options) {
+		o.Path = p
+	}
+}
+
+// WithMaxSize sets the maximum bytes of queued messages. Publishing
+// returns ErrFull once it's reached.
+func WithMaxSize(n int64) Option {
+	return func(o *Options) {
+		o.MaxSize = n
+	}
+}
+
+// WithMaxAge drops queued messages older than the age instead of replaying them
+func WithMaxAge(d time.Duration) Option {
+	return func(o *Options) {
+		o.MaxAge = d
+	}
+}
+
+// WithRetryInterval sets how often replaying is retried while the broker is down
+func WithRetryInterval(d time.Duration) Option {
+	return func(o *Options) {
+		o.RetryInterval = d
+	}
+}
@@ -0,0 +1,338 @@
+// Package spill provides a broker wrapper which queues messages on disk while
+// the broker is unreachable and replays them in order once it's back.
+package spill
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	log "github.com/micro/go-micro/v2/logger"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// DefaultPath is the bolt database file messages are queued in
+	DefaultPath = filepath.Join(os.TempDir(), "micro", "spill.db")
+	// DefaultRetryInterval is how often replaying is retried while the broker is down
+	DefaultRetryInterval = time.Second
+
+	// ErrFull is returned when the queue has reached its maximum size
+	ErrFull = errors.New("spill queue is full")
+
+	bucket = []byte("messages")
+)
+
+type spillBroker struct {
+	broker.Broker
+	opts Options
+
+	// serialises publishing so queued messages are replayed in order
+	sync.Mutex
+	db      *bolt.DB
+	pending int
+	size    int64
+
+	exit chan bool
+	done chan bool
+	wake chan bool
+}
+
+// record of a queued message
+type record struct {
+	Topic  string
+	Header map[string]string
+	Body   []byte
+	Time   time.Time
+}
+
+func (s *spillBroker) expired(r *record) bool {
+	return s.opts.MaxAge > 0 && time.Since(r.Time) > s.opts.MaxAge
+}
+
+// open opens the queue and starts replaying it, it must be called with the lock held
+func (s *spillBroker) open() error {
+	if s.db != nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.opts.Path), 0700); err != nil {
+		return err
+	}
+
+	// bolt only allows one process to open the file
+	db, err := bolt.Open(s.opts.Path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return err
+	}
+
+	var pending int
+	var size int64
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		bk, err := tx.CreateBucketIfNotExists(bucket)
+		if err != nil {
+			return err
+		}
+		return bk.ForEach(func(_, v []byte) error {
+			pending++
+			size += int64(len(v))
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return err
+	}
+
+	s.db = db
+	s.pending = pending
+	s.size = size
+	s.exit = make(chan bool)
+	s.done = make(chan bool)
+	s.wake = make(chan bool, 1)
+
+	go s.run(s.exit, s.done, s.wake)
+
+	return nil
+}
+
+// close stops replaying and closes the queue
+func (s *spillBroker) close() error {
+	s.Lock()
+	exit, done := s.exit, s.done
+	s.exit, s.done = nil, nil
+	s.Unlock()
+
+	if exit == nil {
+		return nil
+	}
+
+	close(exit)
+	<-done
+
+	s.Lock()
+	defer s.Unlock()
+
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
+
+// delete removes a queued message, it must be called with the lock held
+func (s *spillBroker) delete(k, v []byte) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete(k)
+	})
+	if err != nil {
+		return err
+	}
+	s.pending--
+	s.size -= int64(len(v))
+	return nil
+}
+
+// first returns the oldest queued message, it must be called with the lock held
+func (s *spillBroker) first() ([]byte, []byte, error) {
+	var k, v []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		ck, cv := tx.Bucket(bucket).Cursor().First()
+		// bolt values are only valid in the transaction
+		k = append([]byte(nil), ck...)
+		v = append([]byte(nil), cv...)
+		return nil
+	})
+	return k, v, err
+}
+
+// next returns the oldest queued message to replay, dropping any which
+// are expired or can't be decoded. It must be called with the lock held.
+func (s *spillBroker) next() ([]byte, []byte, *record, error) {
+	for s.pending > 0 {
+		k, v, err := s.first()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if k == nil {
+			s.pending, s.size = 0, 0
+			break
+		}
+
+		r := new(record)
+		if err := json.Unmarshal(v, r); err != nil || s.expired(r) {
+			if log.V(log.WarnLevel, log.DefaultLogger) {
+				log.Warnf("Dropping queued message to %s: expired or invalid", r.Topic)
+			}
+			if err := s.delete(k, v); err != nil {
+				return nil, nil, nil, err
+			}
+			continue
+		}
+
+		return k, v, r, nil
+	}
+
+	return nil, nil, nil, nil
+}
+
+// enqueue queues a message, it must be called with the lock held
+func (s *spillBroker) enqueue(topic string, msg *broker.Message) error {
+	v, err := json.Marshal(&record{
+		Topic:  topic,
+		Header: msg.Header,
+		Body:   msg.Body,
+		Time:   time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.opts.MaxSize > 0 && s.size+int64(len(v)) > s.opts.MaxSize {
+		// make room by dropping expired messages
+		if _, _, _, err := s.next(); err != nil {
+			return err
+		}
+		if s.size+int64(len(v)) > s.opts.MaxSize {
+			return ErrFull
+		}
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucket)
+		seq, err := bk.NextSequence()
+		if err != nil {
+			return err
+		}
+		// big endian keys are iterated in the order they were queued
+		k := make([]byte, 8)
+		binary.BigEndian.PutUint64(k, seq)
+		return bk.Put(k, v)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.pending++
+	s.size += int64(len(v))
+
+	select {
+	case s.wake <- true:
+	default:
+	}
+
+	return nil
+}
+
+// replay publishes queued messages in order until the queue is empty or publishing fails
+func (s *spillBroker) replay() {
+	for {
+		s.Lock()
+		if s.db == nil {
+			s.Unlock()
+			return
+		}
+
+		k, v, r, err := s.next()
+		if err != nil || r == nil {
+			s.Unlock()
+			if err != nil && log.V(log.ErrorLevel, log.DefaultLogger) {
+				log.Errorf("Error reading queued messages: %v", err)
+			}
+			return
+		}
+
+		if err := s.Broker.Publish(r.Topic, &broker.Message{Header: r.Header, Body: r.Body}); err != nil {
+			s.Unlock()
+			return
+		}
+
+		err = s.delete(k, v)
+		s.Unlock()
+
+		if err != nil {
+			if log.V(log.ErrorLevel, log.DefaultLogger) {
+				log.Errorf("Error deleting replayed message: %v", err)
+			}
+			return
+		}
+	}
+}
+
+func (s *spillBroker) run(exit, done, wake chan bool) {
+	defer close(done)
+
+	t := time.NewTicker(s.opts.RetryInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-exit:
+			return
+		case <-t.C:
+		case <-wake:
+		}
+		s.replay()
+	}
+}
+
+func (s *spillBroker) Connect() error {
+	s.Lock()
+	err := s.open()
+	s.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.Broker.Connect()
+}
+
+func (s *spillBroker) Disconnect() error {
+	if err := s.close(); err != nil {
+		return err
+	}
+	return s.Broker.Disconnect()
+}
+
+// Publish publishes the message, queueing it if the broker is unreachable.
+// Messages are also queued while others are waiting so they stay in order.
+// Publish options aren't kept for queued messages.
+func (s *spillBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	if s.pending == 0 {
+		err := s.Broker.Publish(topic, msg, opts...)
+		if err == nil {
+			return nil
+		}
+		if log.V(log.DebugLevel, log.DefaultLogger) {
+			log.Debugf("Queueing message to %s: %v", topic, err)
+		}
+	}
+
+	return s.enqueue(topic, msg)
+}
+
+// NewBroker returns a broker which queues messages on disk while the broker is unreachable
+func NewBroker(b broker.Broker, opts ...Option) broker.Broker {
+	options := Options{
+		Path:          DefaultPath,
+		RetryInterval: DefaultRetryInterval,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &spillBroker{
+		Broker: b,
+		opts:   options,
+	}
+}
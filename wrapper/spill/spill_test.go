@@ -0,0 +1,186 @@
+package spill
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/broker/memory"
+)
+
+// testBroker is a memory broker which can be taken down
+type testBroker struct {
+	broker.Broker
+
+	sync.Mutex
+	down bool
+}
+
+func (t *testBroker) setDown(down bool) {
+	t.Lock()
+	t.down = down
+	t.Unlock()
+}
+
+func (t *testBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	t.Lock()
+	down := t.down
+	t.Unlock()
+	if down {
+		return errors.New("broker down")
+	}
+	return t.Broker.Publish(topic, msg, opts...)
+}
+
+func testPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "spill")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "spill.db"), func() { os.RemoveAll(dir) }
+}
+
+func TestReplay(t *testing.T) {
+	path, cleanup := testPath(t)
+	defer cleanup()
+
+	tb := &testBroker{Broker: memory.NewBroker()}
+	b := NewBroker(tb, WithPath(path), WithRetryInterval(10*time.Millisecond))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	var mtx sync.Mutex
+	var received []string
+
+	if _, err := b.Subscribe("test", func(e broker.Event) error {
+		mtx.Lock()
+		received = append(received, string(e.Message().Body))
+		mtx.Unlock()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	publish := func(body string) {
+		if err := b.Publish("test", &broker.Message{Body: []byte(body)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	publish("1")
+	tb.setDown(true)
+	publish("2")
+	publish("3")
+
+	// messages published while others are queued are queued behind them
+	tb.setDown(false)
+	publish("4")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mtx.Lock()
+		n := len(received)
+		mtx.Unlock()
+		if n == 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 4 messages got %v", received)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	for i, body := range []string{"1", "2", "3", "4"} {
+		if received[i] != body {
+			t.Fatalf("expected messages in order got %v", received)
+		}
+	}
+}
+
+func TestPersist(t *testing.T) {
+	path, cleanup := testPath(t)
+	defer cleanup()
+
+	tb := &testBroker{Broker: memory.NewBroker(), down: true}
+	b := NewBroker(tb, WithPath(path))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Publish("test", &broker.Message{Header: map[string]string{"Id": "1"}, Body: []byte("queued")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// queued messages are replayed by the next process
+	tb = &testBroker{Broker: memory.NewBroker()}
+	b = NewBroker(tb, WithPath(path), WithRetryInterval(10*time.Millisecond))
+
+	if err := tb.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan *broker.Message, 1)
+	if _, err := tb.Subscribe("test", func(e broker.Event) error {
+		received <- e.Message()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	select {
+	case m := <-received:
+		if string(m.Body) != "queued" || m.Header["Id"] != "1" {
+			t.Fatalf("unexpected message %+v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued message not replayed")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	path, cleanup := testPath(t)
+	defer cleanup()
+
+	tb := &testBroker{Broker: memory.NewBroker(), down: true}
+	b := NewBroker(tb, WithPath(path), WithMaxSize(300), WithMaxAge(100*time.Millisecond), WithRetryInterval(time.Hour))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	msg := &broker.Message{Body: make([]byte, 100)}
+
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		err = b.Publish("test", msg)
+	}
+	if err != ErrFull {
+		t.Fatalf("expected ErrFull got %v", err)
+	}
+
+	// expired messages are dropped to make room
+	time.Sleep(150 * time.Millisecond)
+	if err := b.Publish("test", msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := b.(*spillBroker).pending; n != 1 {
+		t.Fatalf("expected 1 queued message got %d", n)
+	}
+}
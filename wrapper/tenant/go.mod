@@ -0,0 +1,8 @@
+module github.com/micro/go-plugins/wrapper/tenant/v2
+
+go 1.13
+
+require (
+	github.com/micro/go-micro/v2 v2.9.1
+	github.com/opentracing/opentracing-go v1.1.0
+)
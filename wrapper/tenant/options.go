@@ -0,0 +1,60 @@
+package tenant
+
+// Options of the tenant wrappers
+type Options struct {
+	// Header is the metadata key of the tenant id
+	Header string
+	// Required rejects requests without a tenant
+	Required bool
+	// Allowed are the tenants requests may have, any are if it's empty
+	Allowed map[string]bool
+	// Claim is the account metadata key of the tenant of the account,
+	// requests of an account must be of its tenant
+	Claim string
+	// Taggers tag the context of requests with their tenant
+	Taggers []Tagger
+}
+
+// Option sets an option of the tenant wrappers
+type Option func(o *Options)
+
+// WithHeader sets the metadata key of the tenant id
+func WithHeader(h string) Option {
+	return func(o *Options) {
+		o.Header = h
+	}
+}
+
+// Required rejects requests without a tenant
+func Required() Option {
+	return func(o *Options) {
+		o.Required = true
+	}
+}
+
+// WithAllowed sets the tenants requests may have
+func WithAllowed(ids ...string) Option {
+	return func(o *Options) {
+		if o.Allowed == nil {
+			o.Allowed = make(map[string]bool)
+		}
+		for _, id := range ids {
+			o.Allowed[id] = true
+		}
+	}
+}
+
+// WithClaim sets the account metadata key of the tenant of the account, requests of
+// an account must be of its tenant and requests without one are of the account's
+func WithClaim(key string) Option {
+	return func(o *Options) {
+		o.Claim = key
+	}
+}
+
+// WithTaggers sets the taggers of the context of requests, replacing the defaults
+func WithTaggers(t ...Tagger) Option {
+	return func(o *Options) {
+		o.Taggers = t
+	}
+}
@@ -0,0 +1,214 @@
+// Package tenant provides client and server wrappers which propagate the tenant
+// of a request in its metadata and reject requests of tenants which aren't allowed.
+//
+// The server wrappers read the tenant of a request from its metadata, validate
+// it and add it to the context of the handler, calls made with that context
+// have the tenant so it's propagated to every service downstream. The client
+// wrapper validates the tenant before calling so requests of a tenant which
+// isn't allowed never leave the service.
+package tenant
+
+import (
+	"context"
+
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/logger"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+	"github.com/opentracing/opentracing-go"
+)
+
+var (
+	// DefaultHeader is the metadata key of the tenant id
+	DefaultHeader = "Micro-Tenant"
+	// DefaultTaggers tag the logger and the span of the context with the tenant
+	DefaultTaggers = []Tagger{LogTagger, TraceTagger}
+)
+
+// Tagger tags the context of a request with its tenant e.g. with a field of its
+// logger, it's called by the wrappers for requests which have a tenant
+type Tagger func(ctx context.Context, id string) context.Context
+
+type tenantKey struct{}
+
+type tenantWrapper struct {
+	client.Client
+	opts Options
+}
+
+// NewContext returns a context of the tenant, calls made with it have its id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, id)
+}
+
+// FromContext returns the tenant of the context, which is the tenant of the
+// request in handlers wrapped by the server wrappers
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && len(id) > 0
+}
+
+// LogTagger adds the tenant field to the logger of the context, logger.FromContext
+// returns it in the handler
+func LogTagger(ctx context.Context, id string) context.Context {
+	l, ok := logger.FromContext(ctx)
+	if !ok {
+		l = logger.DefaultLogger
+	}
+	return logger.NewContext(ctx, l.Fields(map[string]interface{}{"tenant": id}))
+}
+
+// TraceTagger sets the tenant tag of the opentracing span of the context
+func TraceTagger(ctx context.Context, id string) context.Context {
+	if sp := opentracing.SpanFromContext(ctx); sp != nil {
+		sp.SetTag("tenant", id)
+	}
+	return ctx
+}
+
+func newOptions(opts ...Option) Options {
+	options := Options{
+		Header:  DefaultHeader,
+		Taggers: DefaultTaggers,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+	return options
+}
+
+// tenant returns the tenant of the request which may be the tenant of the account
+// of the context, it's an error if the request's tenant isn't allowed
+func (o Options) tenant(ctx context.Context, service, id string) (string, error) {
+	if len(o.Claim) > 0 {
+		if acc, ok := auth.AccountFromContext(ctx); ok {
+			claim := acc.Metadata[o.Claim]
+			if len(id) == 0 {
+				id = claim
+			}
+			if id != claim {
+				return "", errors.Forbidden(service, "tenant %s is not the tenant of account %s", id, acc.ID)
+			}
+		}
+	}
+
+	if len(id) == 0 {
+		if o.Required {
+			return "", errors.BadRequest(service, "tenant required")
+		}
+		return "", nil
+	}
+
+	if len(o.Allowed) > 0 && !o.Allowed[id] {
+		return "", errors.Forbidden(service, "tenant %s not allowed", id)
+	}
+
+	return id, nil
+}
+
+// context returns the context of the request with its tenant in the metadata,
+// it's tagged with the tenant
+func (o Options) context(ctx context.Context, service, id string) (context.Context, error) {
+	id, err := o.tenant(ctx, service, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(id) == 0 {
+		return ctx, nil
+	}
+
+	ctx = NewContext(ctx, id)
+	ctx = metadata.Set(ctx, o.Header, id)
+	for _, t := range o.Taggers {
+		ctx = t(ctx, id)
+	}
+	return ctx, nil
+}
+
+// incoming returns the context of a request received by the service
+func (o Options) incoming(ctx context.Context, service string) (context.Context, error) {
+	id, _ := metadata.Get(ctx, o.Header)
+	return o.context(ctx, service, id)
+}
+
+// outgoing returns the context of a request made by the service, the tenant of
+// the context is propagated unless the metadata has one
+func (w *tenantWrapper) outgoing(ctx context.Context, service string) (context.Context, error) {
+	id, ok := metadata.Get(ctx, w.opts.Header)
+	if !ok {
+		id, _ = FromContext(ctx)
+	}
+	return w.opts.context(ctx, service, id)
+}
+
+func (w *tenantWrapper) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	ctx, err := w.outgoing(ctx, req.Service())
+	if err != nil {
+		return err
+	}
+	return w.Client.Call(ctx, req, rsp, opts...)
+}
+
+func (w *tenantWrapper) Stream(ctx context.Context, req client.Request, opts ...client.CallOption) (client.Stream, error) {
+	ctx, err := w.outgoing(ctx, req.Service())
+	if err != nil {
+		return nil, err
+	}
+	return w.Client.Stream(ctx, req, opts...)
+}
+
+func (w *tenantWrapper) Publish(ctx context.Context, p client.Message, opts ...client.PublishOption) error {
+	ctx, err := w.outgoing(ctx, p.Topic())
+	if err != nil {
+		return err
+	}
+	return w.Client.Publish(ctx, p, opts...)
+}
+
+// NewClientWrapper returns a client wrapper which sets the tenant of the context in
+// the metadata of requests, requests of tenants which aren't allowed return an error
+func NewClientWrapper(opts ...Option) client.Wrapper {
+	options := newOptions(opts...)
+
+	return func(c client.Client) client.Client {
+		return &tenantWrapper{
+			Client: c,
+			opts:   options,
+		}
+	}
+}
+
+// NewHandlerWrapper returns a handler wrapper which adds the tenant of the request
+// to the context of the handler, requests of tenants which aren't allowed return an error
+func NewHandlerWrapper(opts ...Option) server.HandlerWrapper {
+	options := newOptions(opts...)
+
+	return func(fn server.HandlerFunc) server.HandlerFunc {
+		return func(ctx context.Context, req server.Request, rsp interface{}) error {
+			ctx, err := options.incoming(ctx, req.Service())
+			if err != nil {
+				return err
+			}
+			return fn(ctx, req, rsp)
+		}
+	}
+}
+
+// NewSubscriberWrapper returns a subscriber wrapper which adds the tenant of the
+// message to the context of the subscriber, messages of tenants which aren't
+// allowed aren't handled
+func NewSubscriberWrapper(opts ...Option) server.SubscriberWrapper {
+	options := newOptions(opts...)
+
+	return func(fn server.SubscriberFunc) server.SubscriberFunc {
+		return func(ctx context.Context, msg server.Message) error {
+			ctx, err := options.incoming(ctx, msg.Topic())
+			if err != nil {
+				return err
+			}
+			return fn(ctx, msg)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/micro/go-micro/v2/auth"
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/errors"
+	"github.com/micro/go-micro/v2/metadata"
+	"github.com/micro/go-micro/v2/server"
+)
+
+type testRequest struct {
+	server.Request
+}
+
+func (t *testRequest) Service() string {
+	return "test"
+}
+
+// testClient records the tenant of the metadata of calls
+type testClient struct {
+	client.Client
+	tenant string
+}
+
+func (t *testClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	t.tenant, _ = metadata.Get(ctx, DefaultHeader)
+	return nil
+}
+
+func call(ctx context.Context, w server.HandlerWrapper) (string, error) {
+	var tenant string
+	err := w(func(ctx context.Context, req server.Request, rsp interface{}) error {
+		tenant, _ = FromContext(ctx)
+		return nil
+	})(ctx, &testRequest{}, nil)
+	return tenant, err
+}
+
+func code(err error) int32 {
+	if err == nil {
+		return 0
+	}
+	return errors.Parse(err.Error()).Code
+}
+
+func TestHandlerWrapper(t *testing.T) {
+	w := NewHandlerWrapper(WithAllowed("foo", "bar"))
+
+	testData := []struct {
+		tenant string
+		code   int32
+	}{
+		{"foo", 0},
+		{"bar", 0},
+		{"baz", http.StatusForbidden},
+		{"", 0},
+	}
+
+	for _, d := range testData {
+		ctx := context.Background()
+		if len(d.tenant) > 0 {
+			ctx = metadata.NewContext(ctx, metadata.Metadata{DefaultHeader: d.tenant})
+		}
+
+		tenant, err := call(ctx, w)
+		if c := code(err); c != d.code {
+			t.Fatalf("Expected code %d for tenant %q got %d: %v", d.code, d.tenant, c, err)
+		}
+		if err == nil && tenant != d.tenant {
+			t.Fatalf("Expected tenant %q got %q", d.tenant, tenant)
+		}
+	}
+}
+
+func TestRequired(t *testing.T) {
+	_, err := call(context.Background(), NewHandlerWrapper(Required()))
+	if c := code(err); c != http.StatusBadRequest {
+		t.Fatalf("Expected code %d got %d: %v", http.StatusBadRequest, c, err)
+	}
+}
+
+func TestClaim(t *testing.T) {
+	w := NewHandlerWrapper(WithClaim("tenant"))
+	ctx := auth.ContextWithAccount(context.Background(), &auth.Account{
+		ID:       "john",
+		Metadata: map[string]string{"tenant": "foo"},
+	})
+
+	// the tenant of the account
+	tenant, err := call(ctx, w)
+	if err != nil || tenant != "foo" {
+		t.Fatalf("Expected tenant foo got %q: %v", tenant, err)
+	}
+
+	_, err = call(metadata.Set(ctx, DefaultHeader, "bar"), w)
+	if c := code(err); c != http.StatusForbidden {
+		t.Fatalf("Expected code %d got %d: %v", http.StatusForbidden, c, err)
+	}
+}
+
+func TestClientWrapper(t *testing.T) {
+	tc := &testClient{}
+	c := NewClientWrapper(WithAllowed("foo"))(tc)
+	req := client.NewRequest("test", "Test.Call", nil)
+
+	if err := c.Call(NewContext(context.Background(), "foo"), req, nil); err != nil {
+		t.Fatal(err)
+	}
+	if tc.tenant != "foo" {
+		t.Fatalf("Expected tenant foo to be propagated got %q", tc.tenant)
+	}
+
+	tc.tenant = ""
+	err := c.Call(NewContext(context.Background(), "bar"), req, nil)
+	if c := code(err); c != http.StatusForbidden {
+		t.Fatalf("Expected code %d got %d: %v", http.StatusForbidden, c, err)
+	}
+	if len(tc.tenant) > 0 {
+		t.Fatal("Expected the call of tenant bar not to be made")
+	}
+}
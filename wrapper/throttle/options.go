@@ -0,0 +1,49 @@
+package throttle
+
+// Options of the throttle broker
+type Options struct {
+	// Limits of each topic, topics without one use the default
+	Limits map[string]Limit
+	// Default limit of topics without one, the zero value is unlimited
+	Default Limit
+	// Requeue returns ErrThrottled for messages over the rate instead of dropping them
+	Requeue bool
+}
+
+// Limit of the messages handled on a topic
+type Limit struct {
+	// Rate is the most messages handled per second, zero is unlimited
+	Rate float64
+	// Burst is the most messages handled at once, it defaults to the rate
+	Burst int
+	// Sample is the fraction of messages handled e.g 0.1 for 10%, zero handles all
+	Sample float64
+}
+
+// Option sets an option of the throttle broker
+type Option func(o *Options)
+
+// WithLimit limits the messages handled on the topic. The subscribers of the
+// topic share the limit.
+func WithLimit(topic string, l Limit) Option {
+	return func(o *Options) {
+		o.Limits[topic] = l
+	}
+}
+
+// WithDefaultLimit limits the messages handled on each topic without a limit
+func WithDefaultLimit(l Limit) Option {
+	return func(o *Options) {
+		o.Default = l
+	}
+}
+
+// WithRequeue returns ErrThrottled from the handler for messages over the rate so
+// the broker redelivers them, instead of acknowledging and dropping them. Whether
+// and when they're redelivered depends on the broker. Messages which aren't
+// sampled are always dropped.
+func WithRequeue() Option {
+	return func(o *Options) {
+		o.Requeue = true
+	}
+}
@@ -0,0 +1,165 @@
+// Package throttle provides a broker wrapper which samples and rate limits the
+// messages handled on each topic, so a flood of messages, such as a backlog
+// consumed after an outage, doesn't overload the service. Messages over the
+// limit are dropped or returned to the broker to be redelivered.
+package throttle
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	log "github.com/micro/go-micro/v2/logger"
+)
+
+var (
+	// ErrThrottled is returned by the handler for messages over the rate when requeueing
+	ErrThrottled = errors.New("message throttled")
+)
+
+type throttleBroker struct {
+	broker.Broker
+	opts Options
+
+	sync.Mutex
+	limiters map[string]*limiter
+}
+
+// limiter is a token bucket shared by the subscribers of a topic
+type limiter struct {
+	limit Limit
+
+	sync.Mutex
+	tokens float64
+	burst  float64
+	last   time.Time
+}
+
+func newLimiter(l Limit) *limiter {
+	burst := float64(l.Burst)
+	if burst <= 0 {
+		burst = math.Max(math.Ceil(l.Rate), 1)
+	}
+
+	return &limiter{
+		limit:  l,
+		tokens: burst,
+		burst:  burst,
+		last:   time.Now(),
+	}
+}
+
+// sampled returns whether the message is in the sample to handle
+func (l *limiter) sampled() bool {
+	if l.limit.Sample <= 0 || l.limit.Sample >= 1 {
+		return true
+	}
+	return rand.Float64() < l.limit.Sample
+}
+
+// take returns whether the rate allows handling another message
+func (l *limiter) take() bool {
+	if l.limit.Rate <= 0 {
+		return true
+	}
+
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.limit.Rate)
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// limiter returns the limiter of the topic, or nil if it's unlimited
+func (t *throttleBroker) limiter(topic string) *limiter {
+	t.Lock()
+	defer t.Unlock()
+
+	if l, ok := t.limiters[topic]; ok {
+		return l
+	}
+
+	l, ok := t.opts.Limits[topic]
+	if !ok {
+		l = t.opts.Default
+	}
+
+	var lim *limiter
+	if l.Rate > 0 || (l.Sample > 0 && l.Sample < 1) {
+		lim = newLimiter(l)
+	}
+
+	t.limiters[topic] = lim
+	return lim
+}
+
+// handler returns the handler which only calls h with the messages within the limit
+func (t *throttleBroker) handler(topic string, h broker.Handler, autoAck bool) broker.Handler {
+	l := t.limiter(topic)
+	if l == nil {
+		return h
+	}
+
+	drop := func(e broker.Event, reason string) error {
+		if log.V(log.TraceLevel, log.DefaultLogger) {
+			log.Tracef("Dropping message from %s: %s", topic, reason)
+		}
+		// acknowledged so the broker doesn't redeliver it
+		if !autoAck {
+			return e.Ack()
+		}
+		return nil
+	}
+
+	return func(e broker.Event) error {
+		if !l.sampled() {
+			return drop(e, "not sampled")
+		}
+
+		if !l.take() {
+			if t.opts.Requeue {
+				return ErrThrottled
+			}
+			return drop(e, "over the rate")
+		}
+
+		return h(e)
+	}
+}
+
+func (t *throttleBroker) Subscribe(topic string, h broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	options := broker.SubscribeOptions{
+		AutoAck: true,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return t.Broker.Subscribe(topic, t.handler(topic, h, options.AutoAck), opts...)
+}
+
+// NewBroker returns a broker which samples and rate limits the messages handled on each topic
+func NewBroker(b broker.Broker, opts ...Option) broker.Broker {
+	options := Options{
+		Limits: make(map[string]Limit),
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &throttleBroker{
+		Broker:   b,
+		opts:     options,
+		limiters: make(map[string]*limiter),
+	}
+}
@@ -0,0 +1,114 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/broker"
+	"github.com/micro/go-micro/v2/broker/memory"
+)
+
+type testEvent struct {
+	acked bool
+}
+
+func (e *testEvent) Topic() string { return "foo" }
+
+func (e *testEvent) Message() *broker.Message { return &broker.Message{} }
+
+func (e *testEvent) Ack() error {
+	e.acked = true
+	return nil
+}
+
+func (e *testEvent) Error() error { return nil }
+
+func TestRate(t *testing.T) {
+	b := NewBroker(memory.NewBroker(), WithLimit("foo", Limit{Rate: 1, Burst: 3}))
+	if err := b.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer b.Disconnect()
+
+	var foo, bar int
+	if _, err := b.Subscribe("foo", func(broker.Event) error { foo++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Subscribe("bar", func(broker.Event) error { bar++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		// dropped messages are acknowledged without an error
+		if err := b.Publish("foo", &broker.Message{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Publish("bar", &broker.Message{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if foo != 3 {
+		t.Fatalf("expected the burst of 3 messages to be handled got %d", foo)
+	}
+	if bar != 10 {
+		t.Fatalf("expected topics without a limit to be handled got %d", bar)
+	}
+}
+
+func TestRequeue(t *testing.T) {
+	tb := NewBroker(memory.NewBroker(),
+		WithDefaultLimit(Limit{Rate: 100, Burst: 1}),
+		WithRequeue(),
+	).(*throttleBroker)
+
+	var handled int
+	h := tb.handler("foo", func(broker.Event) error { handled++; return nil }, false)
+
+	if err := h(&testEvent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	e := &testEvent{}
+	if err := h(e); err != ErrThrottled {
+		t.Fatalf("expected the message to be throttled got %v", err)
+	}
+	if e.acked {
+		t.Fatal("expected the throttled message not to be acknowledged")
+	}
+
+	// the bucket refills at the rate
+	time.Sleep(20 * time.Millisecond)
+
+	if err := h(&testEvent{}); err != nil {
+		t.Fatal(err)
+	}
+	if handled != 2 {
+		t.Fatalf("expected 2 messages to be handled got %d", handled)
+	}
+}
+
+func TestSample(t *testing.T) {
+	tb := NewBroker(memory.NewBroker(), WithLimit("foo", Limit{Sample: 0.1})).(*throttleBroker)
+
+	var handled, acked int
+	h := tb.handler("foo", func(broker.Event) error { handled++; return nil }, false)
+
+	for i := 0; i < 10000; i++ {
+		e := &testEvent{}
+		if err := h(e); err != nil {
+			t.Fatal(err)
+		}
+		if e.acked {
+			acked++
+		}
+	}
+
+	if handled < 800 || handled > 1200 {
+		t.Fatalf("expected about 10%% of messages to be handled got %d", handled)
+	}
+	// without auto ack the dropped messages are acknowledged by the wrapper
+	if handled+acked != 10000 {
+		t.Fatalf("expected the %d dropped messages to be acknowledged got %d", 10000-handled, acked)
+	}
+}
@@ -0,0 +1,49 @@
+package timeout
+
+import (
+	"time"
+
+	"github.com/micro/go-micro/v2/config/source"
+)
+
+// Options of the timeouts
+type Options struct {
+	// Timeouts of calls by service and endpoint, or by service for all its endpoints
+	Timeouts map[string]time.Duration
+	// Source the timeouts are loaded and watched from
+	Source source.Source
+	// Path of the timeouts in the source
+	Path []string
+}
+
+// Option sets an option of the timeouts
+type Option func(o *Options)
+
+// key of the timeout of an endpoint, the endpoint is empty for all the endpoints of the service
+func key(service, endpoint string) string {
+	if len(endpoint) == 0 {
+		return service
+	}
+	return service + "/" + endpoint
+}
+
+// WithTimeout sets the timeout of calls to the endpoint of the service e.g
+// ("go.micro.srv.greeter", "Say.Hello"). An empty endpoint sets it for all
+// the endpoints of the service without their own.
+func WithTimeout(service, endpoint string, d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeouts[key(service, endpoint)] = d
+	}
+}
+
+// WithSource loads the timeouts from the config source and reloads them when
+// the value at path changes, replacing the ones set with WithTimeout. The
+// default path is client.timeouts.
+func WithSource(s source.Source, path ...string) Option {
+	return func(o *Options) {
+		o.Source = s
+		if len(path) > 0 {
+			o.Path = path
+		}
+	}
+}
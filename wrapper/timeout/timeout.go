@@ -0,0 +1,215 @@
+// Package timeout provides a client wrapper which overrides the request timeout
+// of calls by service and endpoint.
+//
+// The timeouts can be loaded from a config source and are reloaded when it
+// changes, so operators can tighten or relax them without redeploying callers.
+// They're a map of durations by service, or by service and endpoint joined
+// with a slash, the endpoint taking precedence:
+//
+//	{"client": {"timeouts": {
+//		"go.micro.srv.greeter": "2s",
+//		"go.micro.srv.greeter/Say.Hello": "500ms"
+//	}}}
+package timeout
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/config"
+	log "github.com/micro/go-micro/v2/logger"
+)
+
+var (
+	// DefaultPath of the timeouts in the config source
+	DefaultPath = []string{"client", "timeouts"}
+)
+
+// Timeouts are the request timeouts of calls by service and endpoint
+type Timeouts struct {
+	options Options
+
+	sync.RWMutex
+	timeouts map[string]time.Duration
+
+	config  config.Config
+	watcher config.Watcher
+	exit    chan bool
+}
+
+type timeoutWrapper struct {
+	client.Client
+	t *Timeouts
+}
+
+// parse returns the timeouts of the durations in the config
+func parse(v map[string]string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration, len(v))
+	for k, s := range v {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout of %s: %v", k, err)
+		}
+		timeouts[k] = d
+	}
+	return timeouts, nil
+}
+
+// Get returns the timeout of calls to the endpoint of the service, or zero if there's none
+func (t *Timeouts) Get(service, endpoint string) time.Duration {
+	t.RLock()
+	defer t.RUnlock()
+
+	if d, ok := t.timeouts[key(service, endpoint)]; ok {
+		return d
+	}
+	return t.timeouts[key(service, "")]
+}
+
+// Update replaces the timeouts, they're keyed by service or by service and endpoint
+// joined with a slash
+func (t *Timeouts) Update(timeouts map[string]time.Duration) {
+	t.Lock()
+	t.timeouts = timeouts
+	t.Unlock()
+}
+
+// load reads the timeouts from the source and watches them for changes
+func (t *Timeouts) load() error {
+	c, err := config.NewConfig()
+	if err != nil {
+		return err
+	}
+	if err := c.Load(t.options.Source); err != nil {
+		return err
+	}
+
+	var v map[string]string
+	if err := c.Get(t.options.Path...).Scan(&v); err != nil {
+		return err
+	}
+	timeouts, err := parse(v)
+	if err != nil {
+		return err
+	}
+
+	w, err := c.Watch(t.options.Path...)
+	if err != nil {
+		return err
+	}
+
+	t.Update(timeouts)
+	t.config = c
+	t.watcher = w
+	t.exit = make(chan bool)
+
+	go t.watch(w)
+
+	return nil
+}
+
+// watch reloads the timeouts when they change until the watcher is stopped
+func (t *Timeouts) watch(w config.Watcher) {
+	for {
+		v, err := w.Next()
+		if err != nil {
+			select {
+			case <-t.exit:
+				return
+			default:
+			}
+			if log.V(log.ErrorLevel, log.DefaultLogger) {
+				log.Errorf("Error watching client timeouts: %v", err)
+			}
+			time.Sleep(time.Second)
+			continue
+		}
+
+		var m map[string]string
+		if err := v.Scan(&m); err != nil {
+			if log.V(log.ErrorLevel, log.DefaultLogger) {
+				log.Errorf("Error reloading client timeouts: %v", err)
+			}
+			continue
+		}
+		timeouts, err := parse(m)
+		if err != nil {
+			if log.V(log.ErrorLevel, log.DefaultLogger) {
+				log.Errorf("Error reloading client timeouts: %v", err)
+			}
+			continue
+		}
+
+		t.Update(timeouts)
+
+		if log.V(log.InfoLevel, log.DefaultLogger) {
+			log.Infof("Reloaded %d client timeouts", len(timeouts))
+		}
+	}
+}
+
+// Close stops watching the config source
+func (t *Timeouts) Close() error {
+	if t.watcher == nil {
+		return nil
+	}
+	close(t.exit)
+	t.watcher.Stop()
+	return t.config.Close()
+}
+
+// Call sets the request timeout of the endpoint. A deadline of the context
+// is shortened to it, but not extended.
+func (w *timeoutWrapper) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	d := w.t.Get(req.Service(), req.Endpoint())
+	if d <= 0 {
+		return w.Client.Call(ctx, req, rsp, opts...)
+	}
+
+	// the client uses the deadline of the context over the request timeout
+	if _, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
+	return w.Client.Call(ctx, req, rsp, append(opts, client.WithRequestTimeout(d))...)
+}
+
+// NewTimeouts returns the timeouts of the options. If a source is set they're
+// loaded from it before returning.
+func NewTimeouts(opts ...Option) (*Timeouts, error) {
+	options := Options{
+		Timeouts: make(map[string]time.Duration),
+		Path:     DefaultPath,
+	}
+	for _, o := range opts {
+		o(&options)
+	}
+
+	t := &Timeouts{
+		options:  options,
+		timeouts: options.Timeouts,
+	}
+
+	if options.Source != nil {
+		if err := t.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// NewClientWrapper returns a client wrapper which applies the timeouts to calls
+func NewClientWrapper(t *Timeouts) client.Wrapper {
+	return func(c client.Client) client.Client {
+		return &timeoutWrapper{
+			Client: c,
+			t:      t,
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package timeout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micro/go-micro/v2/client"
+	"github.com/micro/go-micro/v2/config/source"
+	"github.com/micro/go-micro/v2/config/source/memory"
+)
+
+// testClient records the request timeout and deadline calls are made with
+type testClient struct {
+	client.Client
+	timeout  time.Duration
+	deadline time.Duration
+}
+
+func (t *testClient) Call(ctx context.Context, req client.Request, rsp interface{}, opts ...client.CallOption) error {
+	var options client.CallOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	t.timeout = options.RequestTimeout
+
+	t.deadline = 0
+	if d, ok := ctx.Deadline(); ok {
+		t.deadline = time.Until(d)
+	}
+	return nil
+}
+
+func TestTimeouts(t *testing.T) {
+	to, err := NewTimeouts(
+		WithTimeout("greeter", "", 2*time.Second),
+		WithTimeout("greeter", "Say.Hello", 500*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tc := &testClient{Client: client.NewClient()}
+	c := NewClientWrapper(to)(tc)
+
+	testData := []struct {
+		service  string
+		endpoint string
+		timeout  time.Duration
+	}{
+		{"greeter", "Say.Hello", 500 * time.Millisecond},
+		{"greeter", "Say.Bye", 2 * time.Second},
+		{"payments", "Pay.Charge", 0},
+	}
+
+	for _, d := range testData {
+		req := c.NewRequest(d.service, d.endpoint, nil)
+		if err := c.Call(context.Background(), req, nil, client.WithRequestTimeout(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+
+		expected := d.timeout
+		if expected == 0 {
+			expected = time.Minute
+		}
+		if tc.timeout != expected {
+			t.Fatalf("%s %s: expected timeout %v got %v", d.service, d.endpoint, expected, tc.timeout)
+		}
+	}
+
+	// a deadline of the context is shortened but not extended
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	c.Call(ctx, c.NewRequest("greeter", "Say.Hello", nil), nil)
+	if tc.deadline > 500*time.Millisecond {
+		t.Fatalf("expected the deadline to be shortened got %v", tc.deadline)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	c.Call(ctx, c.NewRequest("greeter", "Say.Bye", nil), nil)
+	if tc.deadline > 100*time.Millisecond {
+		t.Fatalf("expected the deadline not to be extended got %v", tc.deadline)
+	}
+}
+
+func TestReload(t *testing.T) {
+	src := memory.NewSource(memory.WithJSON([]byte(`{"client": {"timeouts": {
+		"greeter": "2s"
+	}}}`)))
+
+	to, err := NewTimeouts(WithTimeout("payments", "", time.Second), WithSource(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer to.Close()
+
+	if d := to.Get("greeter", "Say.Hello"); d != 2*time.Second {
+		t.Fatalf("expected the loaded timeout got %v", d)
+	}
+	if d := to.Get("payments", "Pay.Charge"); d != 0 {
+		t.Fatalf("expected the source to replace the timeouts got %v", d)
+	}
+
+	update := &source.ChangeSet{
+		Format: "json",
+		Data: []byte(`{"client": {"timeouts": {
+			"greeter": "2s",
+			"greeter/Say.Hello": "250ms"
+		}}}`),
+	}
+
+	// the source is watched in the background so retry until the update is seen
+	deadline := time.Now().Add(5 * time.Second)
+	for to.Get("greeter", "Say.Hello") != 250*time.Millisecond {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeouts not reloaded, got %v", to.Get("greeter", "Say.Hello"))
+		}
+		src.Write(update)
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestInvalidTimeout(t *testing.T) {
+	src := memory.NewSource(memory.WithJSON([]byte(`{"client": {"timeouts": {"greeter": "soon"}}}`)))
+	if _, err := NewTimeouts(WithSource(src)); err == nil {
+		t.Fatal("expected an error for an invalid timeout")
+	}
+}